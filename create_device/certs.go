@@ -0,0 +1,170 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// 设备证书配置
+var (
+	certDir      = flag.String("cert-dir", "", "设备证书目录，设置后用测试CA为每个设备签发客户端证书(<token>.crt/<token>.key)，可直接作为MQTT测试工具的 --cert-dir")
+	certManifest = flag.String("cert-manifest", "device_certs.csv", "设备证书清单文件名(设备ID、Token、证书和私钥路径)")
+)
+
+// 测试CA文件名(与MQTT测试工具一致)及证书有效期
+const (
+	testCACertFile     = "ca.crt"
+	testCAKeyFile      = "ca.key"
+	testCAValidity     = 10 * 365 * 24 * time.Hour
+	deviceCertValidity = 365 * 24 * time.Hour
+)
+
+// 测试CA，未启用证书时为nil
+var (
+	caCert *x509.Certificate
+	caKey  crypto.Signer
+)
+
+// DeviceCert 签发给设备的证书
+type DeviceCert struct {
+	CertFile    string // 证书路径
+	KeyFile     string // 私钥路径
+	Fingerprint string // 证书DER的SHA-256(十六进制)
+}
+
+// loadOrCreateCA 加载证书目录中的测试CA，不存在时生成
+func loadOrCreateCA(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	certFile, keyFile := filepath.Join(dir, testCACertFile), filepath.Join(dir, testCAKeyFile)
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		if err := generateCA(certFile, keyFile); err != nil {
+			return fmt.Errorf(T("生成测试CA失败: %w"), err)
+		}
+		log.Printf(T("已生成测试CA: %s (需在平台上配置为设备证书的签发CA)"), certFile)
+		pair, err = tls.LoadX509KeyPair(certFile, keyFile)
+	}
+	if err != nil {
+		return fmt.Errorf(T("加载测试CA失败: %w"), err)
+	}
+
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return fmt.Errorf(T("加载测试CA失败: %w"), err)
+	}
+	signer, ok := pair.PrivateKey.(crypto.Signer)
+	if !ok || !cert.IsCA {
+		return fmt.Errorf(T("%s 不是有效的CA证书"), certFile)
+	}
+	caCert, caKey = cert, signer
+	return nil
+}
+
+// generateCA 生成自签名的测试CA并写入文件
+func generateCA(certFile, keyFile string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	template := &x509.Certificate{
+		SerialNumber:          newSerialNumber(),
+		Subject:               pkix.Name{CommonName: "ThingsPanel Test CA", Organization: []string{"ThingsPanel Test"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(testCAValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return err
+	}
+	return os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+}
+
+// newSerialNumber 生成128位随机证书序列号
+func newSerialNumber() *big.Int {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return big.NewInt(time.Now().UnixNano())
+	}
+	return serial
+}
+
+// issueDeviceCert 用测试CA为设备签发客户端证书(CN为设备Token)，写入证书目录
+func issueDeviceCert(token string) (*DeviceCert, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: newSerialNumber(),
+		Subject:      pkix.Name{CommonName: token, Organization: []string{"ThingsPanel Test"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(deviceCertValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, fmt.Errorf(T("签发设备证书失败: %w"), err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	base := filepath.Join(*certDir, token)
+	cert := &DeviceCert{CertFile: base + ".crt", KeyFile: base + ".key"}
+	if err := os.WriteFile(cert.KeyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return nil, fmt.Errorf(T("保存设备证书失败: %w"), err)
+	}
+	if err := os.WriteFile(cert.CertFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		return nil, fmt.Errorf(T("保存设备证书失败: %w"), err)
+	}
+	sum := sha256.Sum256(der)
+	cert.Fingerprint = hex.EncodeToString(sum[:])
+	return cert, nil
+}
+
+// certManifestLines 生成证书清单的CSV行(首行为表头)
+func certManifestLines(devices []Device, header bool) []string {
+	var lines []string
+	if header {
+		lines = append(lines, "device_id,token,cert_file,key_file,fingerprint")
+	}
+	for _, device := range devices {
+		if device.Cert == nil {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s,%s,%s,%s,%s",
+			device.ID, device.Token, device.Cert.CertFile, device.Cert.KeyFile, device.Cert.Fingerprint))
+	}
+	return lines
+}
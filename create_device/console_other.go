@@ -0,0 +1,6 @@
+//go:build !windows
+
+package main
+
+// setupConsole 其他平台的终端按UTF-8输出，无需设置
+func setupConsole() {}
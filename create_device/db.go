@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"gopkg.in/yaml.v3"
+
+	"test/internal/pgdsn"
+)
+
+// 数据库配置
+var (
+	dbConfigFile = flag.String("config", "", "压测工具的配置文件(如 ../mqtt/config.yml)，从其中的database部分读取数据库配置，命令行参数优先")
+
+	dbHost      = flag.String("db-host", "127.0.0.1:5432", "数据库服务器地址和端口，或Unix域套接字目录(如 /var/run/postgresql，可带 :端口)")
+	dbUser      = flag.String("db-user", "postgres", "数据库用户名")
+	dbPassword  = flag.String("db-pass", "ThingsPanel2023", "数据库密码")
+	dbName      = flag.String("db-name", "thingspanel", "数据库名称")
+	dbSSLMode   = flag.String("db-ssl", "disable", "数据库SSL模式")
+	dbPgBouncer = flag.Bool("db-pgbouncer", false, "通过pgbouncer连接数据库(事务池模式)：不使用预备语句，不设置SQL超时")
+	dbDSN       = flag.String("db-dsn", "", "完整的数据库连接字符串(postgres://... 或 host=... user=... 关键字格式)，设置后忽略 --db-host、--db-user、--db-pass、--db-name 和 --db-ssl")
+
+	dbMaxConns         = flag.Int("db-max-conns", 0, "数据库连接池最大连接数 (默认: 25)")
+	dbMaxIdleConns     = flag.Int("db-max-idle-conns", 0, "数据库连接池最大空闲连接数 (默认: 5)")
+	dbConnMaxLifetime  = flag.Duration("db-conn-max-lifetime", 0, "数据库连接最长使用时间，到期后重新建立 (默认: 5m)")
+	dbConnectTimeout   = flag.Duration("db-connect-timeout", 0, "建立数据库连接的超时时间 (默认: 5s)")
+	dbStatementTimeout = flag.Duration("db-statement-timeout", 0, "单条SQL的服务端执行超时，表被锁时插入超时失败而不是无限期等待，负数为不限制 (默认: 5m)")
+	dbStatementCache   = flag.Int("db-statement-cache", 0, "每个数据库连接缓存的预备语句数，负数为不缓存 (默认: 512)")
+)
+
+// DatabaseConfig 数据库连接配置，YAML键与压测工具配置文件的database部分相同
+type DatabaseConfig struct {
+	Host      string `yaml:"host"`      // 数据库服务器地址和端口，或Unix域套接字目录
+	User      string `yaml:"user"`      // 数据库用户名
+	Password  string `yaml:"password"`  // 数据库密码
+	Name      string `yaml:"name"`      // 数据库名称
+	SSLMode   string `yaml:"ssl_mode"`  // 数据库SSL模式
+	DSN       string `yaml:"dsn"`       // 完整连接字符串，设置后忽略以上各项
+	PgBouncer bool   `yaml:"pgbouncer"` // 通过pgbouncer(事务池模式)连接
+
+	MaxConns         int           `yaml:"max_conns"`         // 连接池最大连接数
+	MaxIdleConns     int           `yaml:"max_idle_conns"`    // 连接池最大空闲连接数
+	ConnMaxLifetime  time.Duration `yaml:"conn_max_lifetime"` // 连接最长使用时间，到期后重新建立
+	ConnectTimeout   time.Duration `yaml:"connect_timeout"`   // 建立连接的超时时间
+	StatementTimeout time.Duration `yaml:"statement_timeout"` // 单条SQL的服务端执行超时，负数为不限制
+	StatementCache   int           `yaml:"statement_cache"`   // 每个连接缓存的预备语句数，负数为不缓存
+}
+
+// dbConfig 本次运行使用的数据库配置，由loadDatabaseConfig确定
+var dbConfig DatabaseConfig
+
+// loadDatabaseConfig 合并配置文件和命令行参数，确定数据库配置
+//
+// 优先级为 显式指定的命令行参数 > 配置文件 > 默认值。数值参数为0时使用默认值，SQL超时
+// 和语句缓存为负数时不限制、不缓存，与压测工具的规则相同。
+func loadDatabaseConfig() error {
+	if *dbConfigFile != "" {
+		data, err := os.ReadFile(*dbConfigFile)
+		if err != nil {
+			return fmt.Errorf(T("读取配置文件失败: %w"), err)
+		}
+		var file struct {
+			Database DatabaseConfig `yaml:"database"`
+		}
+		if err := yaml.Unmarshal(data, &file); err != nil {
+			return fmt.Errorf(T("解析配置文件失败: %w"), err)
+		}
+		dbConfig = file.Database
+	}
+
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	for name, field := range map[string]*string{
+		"db-host": &dbConfig.Host, "db-user": &dbConfig.User, "db-pass": &dbConfig.Password,
+		"db-name": &dbConfig.Name, "db-ssl": &dbConfig.SSLMode, "db-dsn": &dbConfig.DSN,
+	} {
+		// 未显式指定且配置文件中没有时使用参数的默认值
+		if set[name] || *field == "" {
+			*field = flag.Lookup(name).Value.String()
+		}
+	}
+	if set["db-pgbouncer"] {
+		dbConfig.PgBouncer = *dbPgBouncer
+	}
+	if *dbMaxConns > 0 {
+		dbConfig.MaxConns = *dbMaxConns
+	}
+	if *dbMaxIdleConns > 0 {
+		dbConfig.MaxIdleConns = *dbMaxIdleConns
+	}
+	if *dbConnMaxLifetime > 0 {
+		dbConfig.ConnMaxLifetime = *dbConnMaxLifetime
+	}
+	if *dbConnectTimeout > 0 {
+		dbConfig.ConnectTimeout = *dbConnectTimeout
+	}
+	if *dbStatementTimeout != 0 {
+		dbConfig.StatementTimeout = *dbStatementTimeout
+	}
+	if *dbStatementCache != 0 {
+		dbConfig.StatementCache = *dbStatementCache
+	}
+
+	if dbConfig.MaxConns <= 0 {
+		dbConfig.MaxConns = 25
+	}
+	if dbConfig.MaxIdleConns <= 0 {
+		dbConfig.MaxIdleConns = 5
+	}
+	if dbConfig.ConnMaxLifetime <= 0 {
+		dbConfig.ConnMaxLifetime = 5 * time.Minute
+	}
+	if dbConfig.ConnectTimeout <= 0 {
+		dbConfig.ConnectTimeout = 5 * time.Second
+	}
+	if dbConfig.StatementTimeout == 0 {
+		dbConfig.StatementTimeout = 5 * time.Minute
+	}
+	if dbConfig.StatementCache == 0 {
+		dbConfig.StatementCache = 512
+	}
+	return nil
+}
+
+// connectDB 连接PostgreSQL数据库
+func connectDB() (*sql.DB, error) {
+	// 构建连接字符串
+	connStr, err := databaseDSN()
+	if err != nil {
+		return nil, err
+	}
+
+	// 连接数据库
+	db, err := sql.Open("pgx", connStr)
+	if err != nil {
+		return nil, fmt.Errorf(T("无法连接数据库: %w"), err)
+	}
+
+	// 测试连接
+	ctx, cancel := context.WithTimeout(context.Background(), dbConfig.ConnectTimeout)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf(T("数据库连接测试失败: %w"), err)
+	}
+
+	// 设置连接池参数
+	db.SetMaxOpenConns(dbConfig.MaxConns)
+	db.SetMaxIdleConns(dbConfig.MaxIdleConns)
+	db.SetConnMaxLifetime(dbConfig.ConnMaxLifetime)
+
+	return db, nil
+}
+
+// databaseDSN 构建数据库连接字符串
+//
+// 与压测工具使用同一套规则(见pgdsn包)：设置了 --db-dsn 时以其为准，其中没有的连接超时、
+// SQL超时和语句缓存参数仍按本工具的配置补充。
+func databaseDSN() (string, error) {
+	dsn := pgdsn.Build(pgdsn.Options{
+		Host:             dbConfig.Host,
+		User:             dbConfig.User,
+		Password:         dbConfig.Password,
+		Name:             dbConfig.Name,
+		SSLMode:          dbConfig.SSLMode,
+		DSN:              dbConfig.DSN,
+		PgBouncer:        dbConfig.PgBouncer,
+		ConnectTimeout:   dbConfig.ConnectTimeout,
+		StatementTimeout: dbConfig.StatementTimeout,
+		StatementCache:   dbConfig.StatementCache,
+	})
+	if dbConfig.DSN != "" {
+		if err := pgdsn.Check(dsn); err != nil {
+			return "", fmt.Errorf(T("--db-dsn 格式错误: %w"), err)
+		}
+	}
+	return dsn, nil
+}
+
+// txStmt 事务中重复执行的语句，通过pgbouncer连接时不使用命名预备语句
+//
+// 事务池模式下预备语句留在服务端连接上，之后分给其他客户端时会与其同名语句冲突。
+type txStmt struct {
+	tx    *sql.Tx
+	query string
+	stmt  *sql.Stmt
+}
+
+// prepareTx 在事务中准备重复执行的语句
+func prepareTx(tx *sql.Tx, query string) (*txStmt, error) {
+	s := &txStmt{tx: tx, query: query}
+	if dbConfig.PgBouncer {
+		return s, nil
+	}
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	s.stmt = stmt
+	return s, nil
+}
+
+// Exec 执行语句
+func (s *txStmt) Exec(args ...any) (sql.Result, error) {
+	if s.stmt == nil {
+		return s.tx.Exec(s.query, args...)
+	}
+	return s.stmt.Exec(args...)
+}
+
+// Close 关闭预备语句
+func (s *txStmt) Close() error {
+	if s.stmt == nil {
+		return nil
+	}
+	return s.stmt.Close()
+}
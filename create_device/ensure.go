@@ -0,0 +1,74 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 补足设备数量配置
+var ensureCount = flag.Int("ensure-count", 0, "补足到目标设备数：查询租户下已有的同名称前缀设备(<prefix>_<number>_<序号>)，只创建差额，设备ID/Token文件重写为全部设备；0为不启用(按 --count 创建)")
+
+// likeEscaper 转义LIKE模式中的通配符
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// deviceNamePrefix 设备名称中序号之前的部分
+func deviceNamePrefix() string {
+	return fmt.Sprintf("%s_%s_", *devicePrefix, *deviceNumber)
+}
+
+// existingDevices 查询租户下名称为 <prefix><序号> 的已有设备，按序号排序，并返回下一个可用的序号
+//
+// 名称后缀不是序号的设备不计入；设备凭证中记录了证书指纹且启用了证书目录时，按签发时的
+// 文件名还原证书路径，以便重写证书清单。
+func existingDevices(db *sql.DB, tenant, prefix string) ([]Device, int, error) {
+	rows, err := db.Query(`SELECT id, "name", voucher FROM devices WHERE tenant_id = $1 AND "name" LIKE $2`,
+		tenant, likeEscaper.Replace(prefix)+"%")
+	if err != nil {
+		return nil, 0, fmt.Errorf(T("查询已有设备失败: %w"), err)
+	}
+	defer rows.Close()
+
+	type indexed struct {
+		index  int
+		device Device
+	}
+	var found []indexed
+	for rows.Next() {
+		var device Device
+		if err := rows.Scan(&device.ID, &device.Name, &device.VoucherJSON); err != nil {
+			return nil, 0, fmt.Errorf(T("查询已有设备失败: %w"), err)
+		}
+		index, err := strconv.Atoi(strings.TrimPrefix(device.Name, prefix))
+		if err != nil || index < 0 {
+			continue
+		}
+		var voucher DeviceVoucher
+		if err := json.Unmarshal([]byte(device.VoucherJSON), &voucher); err != nil || voucher.Username == "" {
+			return nil, 0, fmt.Errorf(T("设备 %s 的凭证无法解析: %s"), device.Name, device.VoucherJSON)
+		}
+		device.Token = voucher.Username
+		if *certDir != "" && voucher.CertFingerprint != "" {
+			base := filepath.Join(*certDir, device.Token)
+			device.Cert = &DeviceCert{CertFile: base + ".crt", KeyFile: base + ".key", Fingerprint: voucher.CertFingerprint}
+		}
+		found = append(found, indexed{index, device})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf(T("查询已有设备失败: %w"), err)
+	}
+
+	sort.Slice(found, func(i, j int) bool { return found[i].index < found[j].index })
+	devices := make([]Device, len(found))
+	next := 0
+	for i, f := range found {
+		devices[i] = f.device
+		next = f.index + 1
+	}
+	return devices, next, nil
+}
@@ -0,0 +1,465 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-basic/uuid"
+)
+
+// 设备清单导出/导入配置
+var fleetArchivePath = flag.String("archive", "fleet.json.gz", "export/import: 设备清单归档文件，以.gz结尾时压缩(包含设备凭证和证书私钥，注意保管)")
+
+// fleetArchiveVersion 归档格式版本，格式不兼容地变化时递增
+const fleetArchiveVersion = 1
+
+// fleetArchive 一组测试设备的可移植归档：设备、设备配置(含物模型)、分组和凭证
+type fleetArchive struct {
+	Version    int           `json:"version"`
+	ExportedAt time.Time     `json:"exported_at"`
+	Tenant     string        `json:"tenant"`            // 导出时的租户
+	NamePrefix string        `json:"name_prefix"`       // 设备名称中序号之前的部分
+	CACert     string        `json:"ca_cert,omitempty"` // 签发设备证书的测试CA(PEM)
+	CAKey      string        `json:"ca_key,omitempty"`
+	Configs    []fleetConfig `json:"configs,omitempty"`
+	Groups     []fleetGroup  `json:"groups,omitempty"`
+	Devices    []fleetDevice `json:"devices"`
+}
+
+// fleetConfig 设备配置及其物模型中的标识符，导入时按本工具创建物模型的方式重建
+type fleetConfig struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Telemetry  []string `json:"telemetry,omitempty"`
+	Attributes []string `json:"attributes,omitempty"`
+	Commands   []string `json:"commands,omitempty"`
+}
+
+// fleetGroup 设备分组，Devices为组内设备在归档中的ID
+type fleetGroup struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Devices []string `json:"devices"`
+}
+
+// fleetDevice 设备及其凭证
+type fleetDevice struct {
+	ID      string          `json:"id"`
+	Name    string          `json:"name"`
+	Voucher json.RawMessage `json:"voucher"`
+	Config  string          `json:"config,omitempty"` // 绑定的设备配置在归档中的ID
+	Cert    string          `json:"cert,omitempty"`   // 设备证书(PEM)
+	Key     string          `json:"key,omitempty"`    // 设备私钥(PEM)
+}
+
+// subcommand 识别并移除命令行中的子命令，返回子命令名称（无则为空）
+func subcommand() string {
+	if len(os.Args) < 2 {
+		return ""
+	}
+	switch os.Args[1] {
+	case "export", "import":
+		cmd := os.Args[1]
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+		return cmd
+	}
+	return ""
+}
+
+// runFleetCommand 执行export/import子命令
+func runFleetCommand(cmd string) {
+	db, err := connectDB()
+	if err != nil {
+		log.Fatalf(T("连接数据库失败: %v"), err)
+	}
+	defer db.Close()
+
+	if cmd == "export" {
+		err = exportFleet(db, *fleetArchivePath)
+	} else {
+		err = importFleet(db, *fleetArchivePath)
+	}
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+}
+
+// exportFleet 导出租户下名称为 <prefix>_<number>_<序号> 的设备及其设备配置、分组和凭证
+//
+// 设置了 --cert-dir 时同时导出测试CA和设备证书。
+func exportFleet(db *sql.DB, path string) error {
+	prefix := deviceNamePrefix()
+	devices, _, err := existingDevices(db, *tenantID, prefix)
+	if err != nil {
+		return err
+	}
+	if len(devices) == 0 {
+		return fmt.Errorf(T("租户 %s 下没有名称前缀为 %s 的设备"), *tenantID, prefix)
+	}
+	ids := make([]string, len(devices))
+	for i, device := range devices {
+		ids[i] = device.ID
+	}
+
+	archive := fleetArchive{Version: fleetArchiveVersion, ExportedAt: time.Now(), Tenant: *tenantID, NamePrefix: prefix}
+	configOf, err := deviceConfigIDs(db, ids)
+	if err != nil {
+		return err
+	}
+	if archive.Configs, err = loadFleetConfigs(db, configOf); err != nil {
+		return err
+	}
+	if archive.Groups, err = loadFleetGroups(db, ids); err != nil {
+		return err
+	}
+	if *certDir != "" {
+		if archive.CACert, archive.CAKey, err = readPEMPair(filepath.Join(*certDir, testCACertFile), filepath.Join(*certDir, testCAKeyFile)); err != nil {
+			return err
+		}
+	}
+
+	certs := 0
+	for _, device := range devices {
+		d := fleetDevice{ID: device.ID, Name: device.Name, Voucher: json.RawMessage(device.VoucherJSON), Config: configOf[device.ID]}
+		if device.Cert != nil {
+			if d.Cert, d.Key, err = readPEMPair(device.Cert.CertFile, device.Cert.KeyFile); err != nil {
+				return err
+			}
+			certs++
+		}
+		archive.Devices = append(archive.Devices, d)
+	}
+
+	if err := writeFleetArchive(path, &archive); err != nil {
+		return err
+	}
+	log.Printf(T("已导出 %d 个设备(设备配置 %d 个, 分组 %d 个, 设备证书 %d 个)到 %s"),
+		len(archive.Devices), len(archive.Configs), len(archive.Groups), certs, path)
+	return nil
+}
+
+// deviceConfigIDs 查询设备绑定的设备配置，返回设备ID到配置ID的映射(未绑定的设备不在其中)
+func deviceConfigIDs(db *sql.DB, ids []string) (map[string]string, error) {
+	rows, err := db.Query(`SELECT id, device_config_id FROM devices WHERE id = ANY($1) AND device_config_id IS NOT NULL`, ids)
+	if err != nil {
+		return nil, fmt.Errorf(T("查询设备配置失败: %w"), err)
+	}
+	defer rows.Close()
+	configOf := make(map[string]string)
+	for rows.Next() {
+		var id, config string
+		if err := rows.Scan(&id, &config); err != nil {
+			return nil, fmt.Errorf(T("查询设备配置失败: %w"), err)
+		}
+		configOf[id] = config
+	}
+	return configOf, rows.Err()
+}
+
+// loadFleetConfigs 读取设备配置的名称和物模型中的遥测、属性、命令标识符
+func loadFleetConfigs(db *sql.DB, configOf map[string]string) ([]fleetConfig, error) {
+	seen := make(map[string]bool)
+	var configs []fleetConfig
+	for _, id := range configOf {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		c := fleetConfig{ID: id}
+		var template sql.NullString
+		if err := db.QueryRow(`SELECT "name", device_template_id FROM device_configs WHERE id = $1`, id).Scan(&c.Name, &template); err != nil {
+			return nil, fmt.Errorf(T("查询设备配置 %s 失败: %w"), id, err)
+		}
+		if template.Valid {
+			for _, list := range []struct {
+				table string
+				ids   *[]string
+			}{
+				{"device_model_telemetry", &c.Telemetry},
+				{"device_model_attributes", &c.Attributes},
+				{"device_model_commands", &c.Commands},
+			} {
+				var err error
+				if *list.ids, err = modelIdentifiers(db, list.table, template.String); err != nil {
+					return nil, err
+				}
+			}
+		}
+		configs = append(configs, c)
+	}
+	return configs, nil
+}
+
+// modelIdentifiers 查询物模型表中模板的标识符
+func modelIdentifiers(db *sql.DB, table, template string) ([]string, error) {
+	rows, err := db.Query(fmt.Sprintf(`SELECT data_identifier FROM %s WHERE device_template_id = $1 ORDER BY created_at, data_identifier`, table), template)
+	if err != nil {
+		return nil, fmt.Errorf(T("查询物模型 %s 失败: %w"), template, err)
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf(T("查询物模型 %s 失败: %w"), template, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// loadFleetGroups 查询设备所在的分组
+func loadFleetGroups(db *sql.DB, ids []string) ([]fleetGroup, error) {
+	rows, err := db.Query(`SELECT g.id, g."name", r.device_id FROM r_group_device r JOIN groups g ON g.id = r.group_id
+		WHERE r.device_id = ANY($1) ORDER BY g."name"`, ids)
+	if err != nil {
+		return nil, fmt.Errorf(T("查询设备分组失败: %w"), err)
+	}
+	defer rows.Close()
+	var groups []fleetGroup
+	index := make(map[string]int)
+	for rows.Next() {
+		var id, name, device string
+		if err := rows.Scan(&id, &name, &device); err != nil {
+			return nil, fmt.Errorf(T("查询设备分组失败: %w"), err)
+		}
+		i, ok := index[id]
+		if !ok {
+			i = len(groups)
+			index[id] = i
+			groups = append(groups, fleetGroup{ID: id, Name: name})
+		}
+		groups[i].Devices = append(groups[i].Devices, device)
+	}
+	return groups, rows.Err()
+}
+
+// readPEMPair 读取证书和私钥文件
+func readPEMPair(certFile, keyFile string) (string, string, error) {
+	cert, err := os.ReadFile(certFile)
+	if err != nil {
+		return "", "", fmt.Errorf(T("读取证书失败: %w"), err)
+	}
+	key, err := os.ReadFile(keyFile)
+	if err != nil {
+		return "", "", fmt.Errorf(T("读取私钥失败: %w"), err)
+	}
+	return string(cert), string(key), nil
+}
+
+// writeFleetArchive 写入归档文件，只有所有者可读
+func writeFleetArchive(path string, archive *fleetArchive) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf(T("创建归档文件失败: %w"), err)
+	}
+	defer f.Close()
+
+	var w io.Writer = f
+	var gz *gzip.Writer
+	if strings.HasSuffix(path, ".gz") {
+		gz = gzip.NewWriter(f)
+		w = gz
+	}
+	if err := json.NewEncoder(w).Encode(archive); err != nil {
+		return fmt.Errorf(T("写入归档文件失败: %w"), err)
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return fmt.Errorf(T("写入归档文件失败: %w"), err)
+		}
+	}
+	return f.Close()
+}
+
+// readFleetArchive 读取归档文件，按内容识别是否压缩
+func readFleetArchive(path string) (*fleetArchive, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf(T("读取归档文件失败: %w"), err)
+	}
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf(T("读取归档文件失败: %w"), err)
+		}
+		if data, err = io.ReadAll(gz); err != nil {
+			return nil, fmt.Errorf(T("读取归档文件失败: %w"), err)
+		}
+	}
+	var archive fleetArchive
+	if err := json.Unmarshal(data, &archive); err != nil {
+		return nil, fmt.Errorf(T("解析归档文件失败: %w"), err)
+	}
+	if archive.Version != fleetArchiveVersion {
+		return nil, fmt.Errorf(T("不支持的归档格式版本 %d (当前为 %d)"), archive.Version, fleetArchiveVersion)
+	}
+	return &archive, nil
+}
+
+// importFleet 在 --tenant 指定的租户下重建归档中的设备，设备、设备配置和分组使用新的ID，
+// 设备凭证(token和证书)保持不变
+//
+// 所有记录在一个事务中插入，失败时不会留下部分设备。目标租户已有同名称前缀的设备时拒绝导入，
+// 避免重复。导入后按 --output 等参数写入设备ID/Token文件，可直接用于MQTT测试。
+func importFleet(db *sql.DB, path string) error {
+	archive, err := readFleetArchive(path)
+	if err != nil {
+		return err
+	}
+	if err := detectDeviceSchema(db); err != nil {
+		return err
+	}
+	existing, _, err := existingDevices(db, *tenantID, archive.NamePrefix)
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		return fmt.Errorf(T("租户 %s 下已有 %d 个名称前缀为 %s 的设备，请先清理或导入到其他租户"), *tenantID, len(existing), archive.NamePrefix)
+	}
+	log.Printf(T("导入 %d 个设备: 租户 %s -> %s"), len(archive.Devices), archive.Tenant, *tenantID)
+
+	if err := restoreFleetCerts(archive); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(*outputDir, 0755); err != nil {
+		return fmt.Errorf(T("创建输出目录失败: %w"), err)
+	}
+
+	now := time.Now()
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf(T("开始事务失败: %w"), err)
+	}
+	defer tx.Rollback()
+
+	configIDs := make(map[string]string, len(archive.Configs))
+	for _, c := range archive.Configs {
+		model := &deviceModel{TemplateID: uuid.New(), ConfigID: uuid.New(), Telemetry: c.Telemetry, Attributes: c.Attributes, Commands: c.Commands}
+		if err := insertDeviceModel(tx, model, c.Name, *tenantID, now); err != nil {
+			return err
+		}
+		configIDs[c.ID] = model.ConfigID
+	}
+
+	stmt, err := prepareTx(tx, deviceInsert.sql)
+	if err != nil {
+		return fmt.Errorf(T("准备SQL语句失败: %w"), explainInsertError(err))
+	}
+	defer stmt.Close()
+	deviceIDs := make(map[string]string, len(archive.Devices))
+	devices := make([]Device, 0, len(archive.Devices))
+	for _, d := range archive.Devices {
+		var voucher DeviceVoucher
+		if err := json.Unmarshal(d.Voucher, &voucher); err != nil || voucher.Username == "" {
+			return fmt.Errorf(T("设备 %s 的凭证无法解析: %s"), d.Name, d.Voucher)
+		}
+		var config sql.NullString
+		if d.Config != "" {
+			id, ok := configIDs[d.Config]
+			if !ok {
+				return fmt.Errorf(T("设备 %s 绑定的设备配置 %s 不在归档中"), d.Name, d.Config)
+			}
+			config = sql.NullString{String: id, Valid: true}
+		}
+
+		device := Device{ID: uuid.New(), Name: d.Name, Token: voucher.Username, VoucherJSON: string(d.Voucher), CreationTime: now}
+		if d.Cert != "" {
+			base := filepath.Join(*certDir, device.Token)
+			device.Cert = &DeviceCert{CertFile: base + ".crt", KeyFile: base + ".key", Fingerprint: voucher.CertFingerprint}
+		}
+		if _, err := stmt.Exec(deviceInsert.values(deviceRow{Device: device, Tenant: *tenantID, Config: config})...); err != nil {
+			return fmt.Errorf(T("插入设备 %s 失败: %w"), d.Name, explainInsertError(err))
+		}
+		deviceIDs[d.ID] = device.ID
+		devices = append(devices, device)
+	}
+
+	for _, g := range archive.Groups {
+		id := uuid.New()
+		if _, err := tx.Exec(insertGroupSQL, id, g.Name, T("性能测试设备分组"), now, *tenantID); err != nil {
+			return fmt.Errorf(T("创建设备分组 %s 失败: %w"), g.Name, err)
+		}
+		for _, member := range g.Devices {
+			if _, err := tx.Exec(insertGroupDeviceSQL, id, deviceIDs[member], *tenantID); err != nil {
+				return fmt.Errorf(T("将设备 %s 加入分组 %s 失败: %w"), deviceIDs[member], g.Name, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf(T("提交事务失败: %w"), err)
+	}
+	log.Printf(T("已导入 %d 个设备(设备配置 %d 个, 分组 %d 个)"), len(devices), len(archive.Configs), len(archive.Groups))
+	return saveDeviceInfo(devices, nil)
+}
+
+// restoreFleetCerts 把归档中的测试CA和设备证书写入 --cert-dir 并加载CA
+//
+// 证书目录中已有不同的测试CA时拒绝覆盖，以免之前签发的设备证书失效。
+func restoreFleetCerts(archive *fleetArchive) error {
+	hasCerts := archive.CACert != ""
+	for _, d := range archive.Devices {
+		hasCerts = hasCerts || d.Cert != ""
+	}
+	if !hasCerts {
+		return nil
+	}
+	if *certDir == "" {
+		return errors.New(T("归档中包含证书，需要用 --cert-dir 指定写入的证书目录"))
+	}
+	if err := os.MkdirAll(*certDir, 0755); err != nil {
+		return err
+	}
+
+	if archive.CACert != "" {
+		certFile, keyFile := filepath.Join(*certDir, testCACertFile), filepath.Join(*certDir, testCAKeyFile)
+		current, err := os.ReadFile(certFile)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			if err := os.WriteFile(keyFile, []byte(archive.CAKey), 0600); err != nil {
+				return err
+			}
+			if err := os.WriteFile(certFile, []byte(archive.CACert), 0644); err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		case string(current) != archive.CACert:
+			return fmt.Errorf(T("证书目录 %s 中已有不同的测试CA，请使用新的证书目录"), *certDir)
+		}
+		if err := loadOrCreateCA(*certDir); err != nil {
+			return err
+		}
+	}
+
+	for _, d := range archive.Devices {
+		if d.Cert == "" {
+			continue
+		}
+		var voucher DeviceVoucher
+		json.Unmarshal(d.Voucher, &voucher)
+		base := filepath.Join(*certDir, voucher.Username)
+		if err := os.WriteFile(base+".key", []byte(d.Key), 0600); err != nil {
+			return err
+		}
+		if err := os.WriteFile(base+".crt", []byte(d.Cert), 0644); err != nil {
+			return err
+		}
+	}
+	log.Printf(T("设备证书已写入: %s"), *certDir)
+	return nil
+}
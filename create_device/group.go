@@ -0,0 +1,155 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-basic/uuid"
+)
+
+// 设备分组配置
+var (
+	groupCount        = flag.Int("groups", 0, "创建的设备分组数量，新设备按 --group-distribution 分配到各分组，0为不分组")
+	groupDistribution = flag.String("group-distribution", "even", "设备在分组间的分布(even: 平均分配, zipf: 少数大分组和大量小分组, 或逗号分隔的权重如 60,30,10)")
+)
+
+// deviceGroup 本次创建的设备分组
+type deviceGroup struct {
+	ID      string
+	Name    string
+	Devices int
+}
+
+// groupWeights 按分布方式返回各分组的权重
+func groupWeights() ([]float64, error) {
+	switch *groupDistribution {
+	case "even", "zipf":
+		if *groupCount <= 0 {
+			return nil, nil
+		}
+		weights := make([]float64, *groupCount)
+		for i := range weights {
+			weights[i] = 1
+			if *groupDistribution == "zipf" {
+				// 第k个分组的大小与1/k成正比，接近真实项目中按区域、楼宇划分的分组规模
+				weights[i] = 1 / float64(i+1)
+			}
+		}
+		return weights, nil
+	}
+	var weights []float64
+	for _, part := range strings.Split(*groupDistribution, ",") {
+		w, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || w <= 0 || math.IsInf(w, 0) {
+			return nil, fmt.Errorf(T("无效的分组权重 %q (可选: even, zipf, 或逗号分隔的正数权重)"), part)
+		}
+		weights = append(weights, w)
+	}
+	if *groupCount > 0 && *groupCount != len(weights) {
+		return nil, fmt.Errorf(T("分组权重有 %d 个，与 --groups %d 不一致"), len(weights), *groupCount)
+	}
+	return weights, nil
+}
+
+// assignGroups 按权重把count个设备分配到各分组，返回每个设备的分组序号
+//
+// 按累计权重划分连续的区间，分配结果是确定的，各分组的设备数与权重成比例。
+func assignGroups(count int, weights []float64) []int {
+	var total float64
+	for _, w := range weights {
+		total += w
+	}
+	assigned := make([]int, count)
+	group, cumulative := 0, weights[0]/total
+	for i := range assigned {
+		position := (float64(i) + 0.5) / float64(count)
+		for position > cumulative && group < len(weights)-1 {
+			group++
+			cumulative += weights[group] / total
+		}
+		assigned[i] = group
+	}
+	return assigned
+}
+
+// 创建分组和把设备加入分组的SQL语句
+const (
+	insertGroupSQL = `INSERT INTO groups (id, parent_id, tier, "name", description, created_at, updated_at, tenant_id)
+		VALUES ($1, '0', 1, $2, $3, $4, $4, $5)`
+	insertGroupDeviceSQL = `INSERT INTO r_group_device (group_id, device_id, tenant_id) VALUES ($1, $2, $3)`
+)
+
+// createDeviceGroups 创建设备分组并把设备加入分组
+//
+// 分组维度的看板和按分组筛选的查询，其代价取决于分组的规模分布，平均分配和长尾分布
+// 下的表现可能相差很大。
+func createDeviceGroups(db *sql.DB, devices []Device) ([]deviceGroup, error) {
+	weights, err := groupWeights()
+	if err != nil || len(weights) == 0 {
+		return nil, err
+	}
+	groups := make([]deviceGroup, len(weights))
+	for i := range groups {
+		groups[i] = deviceGroup{
+			ID:   uuid.New(),
+			Name: fmt.Sprintf("%s_%s_group%d", *devicePrefix, *deviceNumber, i+1),
+		}
+	}
+	assigned := assignGroups(len(devices), weights)
+	now := time.Now()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf(T("开始事务失败: %w"), err)
+	}
+	defer tx.Rollback()
+
+	for _, g := range groups {
+		if _, err := tx.Exec(insertGroupSQL, g.ID, g.Name, T("性能测试设备分组"), now, *tenantID); err != nil {
+			return nil, fmt.Errorf(T("创建设备分组 %s 失败: %w"), g.Name, err)
+		}
+	}
+
+	stmt, err := prepareTx(tx, insertGroupDeviceSQL)
+	if err != nil {
+		return nil, fmt.Errorf(T("准备SQL语句失败: %w"), err)
+	}
+	defer stmt.Close()
+	for i, device := range devices {
+		g := &groups[assigned[i]]
+		if _, err := stmt.Exec(g.ID, device.ID, *tenantID); err != nil {
+			return nil, fmt.Errorf(T("将设备 %s 加入分组 %s 失败: %w"), device.ID, g.Name, err)
+		}
+		g.Devices++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf(T("提交事务失败: %w"), err)
+	}
+	return groups, nil
+}
+
+// reportGroups 输出各分组的设备数分布
+func reportGroups(groups []deviceGroup) {
+	if len(groups) == 0 {
+		return
+	}
+	smallest, largest := groups[0].Devices, groups[0].Devices
+	for _, g := range groups {
+		smallest, largest = min(smallest, g.Devices), max(largest, g.Devices)
+	}
+	log.Printf(T("已创建 %d 个设备分组(分布: %s)，每组设备数 %d~%d"), len(groups), *groupDistribution, smallest, largest)
+	for i, g := range groups {
+		if i == 10 {
+			log.Printf(T("... 另有 %d 个分组未列出"), len(groups)-10)
+			break
+		}
+		log.Printf(T("  分组 %s (%s): %d 个设备"), g.Name, g.ID, g.Devices)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"strings"
+)
+
+// 输出语言命令行参数(在flag解析之前已由detectLang预先读取)
+var langFlag = flag.String("lang", "", "输出语言(zh/en)，也可通过环境变量TP_LANG设置")
+
+// currentLang 当前输出语言，启动时根据命令行和环境变量确定，便于帮助信息和早期日志也能翻译
+var currentLang = detectLang()
+
+// detectLang 从命令行参数(--lang)或环境变量TP_LANG确定输出语言，默认中文
+func detectLang() string {
+	args := os.Args[1:]
+	for i, arg := range args {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "lang" {
+			continue
+		}
+		if !hasValue && i+1 < len(args) {
+			value = args[i+1]
+		}
+		return normalizeLang(value)
+	}
+	return normalizeLang(os.Getenv("TP_LANG"))
+}
+
+// normalizeLang 将语言代码规范为zh或en
+func normalizeLang(lang string) string {
+	if strings.HasPrefix(strings.ToLower(lang), "en") {
+		return "en"
+	}
+	return "zh"
+}
+
+// T 返回消息在当前语言下的文本，未收录的消息原样返回
+func T(msg string) string {
+	if currentLang == "en" {
+		if translated, ok := messagesEN[msg]; ok {
+			return translated
+		}
+	}
+	return msg
+}
+
+// translateFlagUsage 翻译所有命令行参数的帮助文本，需在flag.Parse之前调用
+func translateFlagUsage() {
+	flag.VisitAll(func(f *flag.Flag) {
+		f.Usage = T(f.Usage)
+	})
+}
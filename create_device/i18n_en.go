@@ -0,0 +1,143 @@
+package main
+
+// messagesEN 英文消息表，键为源码中的中文消息
+var messagesEN = map[string]string{
+	"设备证书目录，设置后用测试CA为每个设备签发客户端证书(<token>.crt/<token>.key)，可直接作为MQTT测试工具的 --cert-dir": "Device certificate directory; when set, a client certificate (<token>.crt/<token>.key) is issued for each device with the test CA, usable directly as --cert-dir of the MQTT tester",
+	"设备证书清单文件名(设备ID、Token、证书和私钥路径)":                                                  "Device certificate manifest file name (device ID, token, certificate and key paths)",
+	"生成测试CA失败: %w": "failed to generate test CA: %w",
+	"已生成测试CA: %s (需在平台上配置为设备证书的签发CA)": "Generated test CA: %s (configure it on the platform as the device certificate issuer)",
+	"加载测试CA失败: %w": "failed to load test CA: %w",
+	"%s 不是有效的CA证书": "%s is not a valid CA certificate",
+	"签发设备证书失败: %w": "failed to issue device certificate: %w",
+	"保存设备证书失败: %w": "failed to save device certificate: %w",
+	"补足到目标设备数：查询租户下已有的同名称前缀设备(<prefix>_<number>_<序号>)，只创建差额，设备ID/Token文件重写为全部设备；0为不启用(按 --count 创建)": "Top up to a target fleet size: look up existing tenant devices with the same name prefix (<prefix>_<number>_<index>), create only the difference and rewrite the device ID/token files with the whole fleet; 0 disables (create --count devices)",
+	"查询已有设备失败: %w":      "failed to query existing devices: %w",
+	"设备 %s 的凭证无法解析: %s": "cannot parse voucher of device %s: %s",
+	"export/import: 设备清单归档文件，以.gz结尾时压缩(包含设备凭证和证书私钥，注意保管)": "export/import: fleet archive file, compressed when the name ends with .gz (contains device credentials and certificate private keys, keep it safe)",
+	"连接数据库失败: %v":                                   "failed to connect to database: %v",
+	"租户 %s 下没有名称前缀为 %s 的设备":                         "tenant %s has no devices with name prefix %s",
+	"已导出 %d 个设备(设备配置 %d 个, 分组 %d 个, 设备证书 %d 个)到 %s": "Exported %d devices (%d device configs, %d groups, %d device certificates) to %s",
+	"查询设备配置失败: %w":                                  "failed to query device configs: %w",
+	"查询设备配置 %s 失败: %w":                              "failed to query device config %s: %w",
+	"查询物模型 %s 失败: %w":                               "failed to query device model %s: %w",
+	"查询设备分组失败: %w":                                  "failed to query device groups: %w",
+	"读取证书失败: %w":                                    "failed to read certificate: %w",
+	"读取私钥失败: %w":                                    "failed to read private key: %w",
+	"创建归档文件失败: %w":                                  "failed to create archive: %w",
+	"写入归档文件失败: %w":                                  "failed to write archive: %w",
+	"读取归档文件失败: %w":                                  "failed to read archive: %w",
+	"解析归档文件失败: %w":                                  "failed to parse archive: %w",
+	"不支持的归档格式版本 %d (当前为 %d)":                        "unsupported archive version %d (current is %d)",
+	"租户 %s 下已有 %d 个名称前缀为 %s 的设备，请先清理或导入到其他租户": "tenant %s already has %d devices with name prefix %s, clean them up or import into another tenant",
+	"导入 %d 个设备: 租户 %s -> %s":           "Importing %d devices: tenant %s -> %s",
+	"创建输出目录失败: %w":                     "failed to create output directory: %w",
+	"开始事务失败: %w":                       "failed to begin transaction: %w",
+	"准备SQL语句失败: %w":                    "failed to prepare SQL statement: %w",
+	"设备 %s 绑定的设备配置 %s 不在归档中":           "device %s is bound to device config %s, which is not in the archive",
+	"插入设备 %s 失败: %w":                   "failed to insert device %s: %w",
+	"性能测试设备分组":                         "performance test device group",
+	"创建设备分组 %s 失败: %w":                 "failed to create device group %s: %w",
+	"将设备 %s 加入分组 %s 失败: %w":            "failed to add device %s to group %s: %w",
+	"提交事务失败: %w":                       "failed to commit transaction: %w",
+	"已导入 %d 个设备(设备配置 %d 个, 分组 %d 个)":   "Imported %d devices (%d device configs, %d groups)",
+	"归档中包含证书，需要用 --cert-dir 指定写入的证书目录": "the archive contains certificates, use --cert-dir to choose where to write them",
+	"证书目录 %s 中已有不同的测试CA，请使用新的证书目录":     "certificate directory %s already holds a different test CA, use a new directory",
+	"设备证书已写入: %s":                      "Device certificates written to: %s",
+	"创建的设备分组数量，新设备按 --group-distribution 分配到各分组，0为不分组":             "number of device groups to create, new devices are assigned per --group-distribution, 0 for no groups",
+	"设备在分组间的分布(even: 平均分配, zipf: 少数大分组和大量小分组, 或逗号分隔的权重如 60,30,10)": "distribution of devices across groups (even: equal sizes, zipf: a few large groups and many small ones, or comma separated weights such as 60,30,10)",
+	"无效的分组权重 %q (可选: even, zipf, 或逗号分隔的正数权重)":                      "invalid group weight %q (options: even, zipf, or comma separated positive weights)",
+	"分组权重有 %d 个，与 --groups %d 不一致":                                 "%d group weights given, which does not match --groups %d",
+	"已创建 %d 个设备分组(分布: %s)，每组设备数 %d~%d":                             "created %d device groups (distribution: %s), %d~%d devices per group",
+	"... 另有 %d 个分组未列出":                                             "... %d more groups not listed",
+	"  分组 %s (%s): %d 个设备":                                         "  group %s (%s): %d devices",
+	"输出语言(zh/en)，也可通过环境变量TP_LANG设置":                                "output language (zh/en), can also be set with the TP_LANG environment variable",
+	"数据库服务器地址和端口，或Unix域套接字目录(如 /var/run/postgresql，可带 :端口)":        "Database server address and port, or a unix socket directory (e.g. /var/run/postgresql, optionally with :port)",
+	"数据库用户名":   "database user name",
+	"数据库密码":    "database password",
+	"数据库名称":    "database name",
+	"数据库SSL模式": "database SSL mode",
+	"通过pgbouncer连接数据库(事务池模式)：不使用预备语句，不设置SQL超时":                                                                       "Connect through pgbouncer (transaction pooling): no prepared statements, no SQL timeout",
+	"完整的数据库连接字符串(postgres://... 或 host=... user=... 关键字格式)，设置后忽略 --db-host、--db-user、--db-pass、--db-name 和 --db-ssl": "Full database connection string (postgres://... or host=... user=... keyword format), overrides --db-host, --db-user, --db-pass, --db-name and --db-ssl",
+	"压测工具的配置文件(如 ../mqtt/config.yml)，从其中的database部分读取数据库配置，命令行参数优先":                                                  "load-test config file (e.g. ../mqtt/config.yml) whose database section provides the database settings, command-line flags take precedence",
+	"数据库连接池最大连接数 (默认: 25)":                             "maximum connections in the database pool (default: 25)",
+	"数据库连接池最大空闲连接数 (默认: 5)":                            "maximum idle connections in the database pool (default: 5)",
+	"数据库连接最长使用时间，到期后重新建立 (默认: 5m)":                     "maximum lifetime of a database connection before it is replaced (default: 5m)",
+	"建立数据库连接的超时时间 (默认: 5s)":                            "timeout for establishing a database connection (default: 5s)",
+	"单条SQL的服务端执行超时，表被锁时插入超时失败而不是无限期等待，负数为不限制 (默认: 5m)": "server-side timeout for each SQL statement so inserts fail instead of waiting forever on a locked table, negative for no limit (default: 5m)",
+	"每个数据库连接缓存的预备语句数，负数为不缓存 (默认: 512)":                 "prepared statements cached per database connection, negative to disable the cache (default: 512)",
+	"租户ID":       "tenant ID",
+	"设备名称前缀":     "device name prefix",
+	"设备名称后缀数字":   "device name suffix number",
+	"要创建的设备数量":   "number of devices to create",
+	"批量插入的大小":    "batch insert size",
+	"输出文件目录":     "output directory",
+	"设备ID文件名":    "device ID file name",
+	"设备Token文件名": "device token file name",
+	"是否追加写入文件":   "append to output files instead of overwriting",
+	"运行记录目录，设置后每次运行在 <时间>-<运行ID> 子目录中保存本次创建的设备凭证快照和日志": "run record directory; when set, each run saves a snapshot of the created device credentials and the log in a <time>-<run id> subdirectory",
+	"开始创建测试设备...":  "Creating test devices...",
+	"创建输出目录失败: %v": "failed to create output directory: %v",
+	"创建运行目录失败: %v": "failed to create run directory: %v",
+	"运行目录: %s":     "run directory: %s",
+	"创建物模型失败: %v":  "Failed to create thing model: %v",
+	"租户 %s 下已有 %d 个名称前缀为 %s 的设备，目标 %d 个，需要创建 %d 个": "Tenant %s already has %d devices with name prefix %s, target %d, creating %d",
+	"警告: 已有设备数超过目标 %d 个，不会删除多出的设备":                 "Warning: existing devices exceed the target by %d, extra devices are not deleted",
+	"创建设备失败: %v":                "failed to create devices: %v",
+	"成功创建 %d 个设备":               "created %d devices",
+	"创建设备分组失败: %v":              "failed to create device groups: %v",
+	"保存设备信息到文件失败: %v":           "failed to save device info to files: %v",
+	"设备创建完成":                    "device creation finished",
+	"无法连接数据库: %w":               "cannot connect to database: %w",
+	"数据库连接测试失败: %w":             "database ping failed: %w",
+	"--db-dsn 格式错误: %w":         "Invalid --db-dsn: %w",
+	"读取配置文件失败: %w":              "failed to read config file: %w",
+	"解析配置文件失败: %w":              "failed to parse config file: %w",
+	"开始创建 %d 个设备...":            "creating %d devices...",
+	"生成设备失败(序号 %d): %w":         "failed to generate device (index %d): %w",
+	"插入设备数据失败(序号 %d): %w":       "failed to insert device (index %d): %w",
+	"进度: %.1f%% (%d/%d)":        "progress: %.1f%% (%d/%d)",
+	"开始新事务失败: %w":               "failed to begin new transaction: %w",
+	"准备新SQL语句失败: %w":            "failed to prepare new SQL statement: %w",
+	"创建完成，耗时: %v，平均: %.2f 设备/秒": "creation finished in %v, average %.2f devices/s",
+	"警告: 序列化设备凭证失败: %v":         "warning: failed to serialize device voucher: %v",
+	"写入ID文件失败: %w":              "failed to write ID file: %w",
+	"设备ID已保存到: %s":              "device IDs saved to: %s",
+	"写入Token文件失败: %w":           "failed to write token file: %w",
+	"设备Token已保存到: %s":           "device tokens saved to: %s",
+	"写入证书清单失败: %w":              "failed to write certificate manifest: %w",
+	"设备证书已保存到: %s，清单: %s":       "Device certificates saved to: %s, manifest: %s",
+	"本次创建的设备快照已保存到: %s":         "snapshot of the created devices saved to: %s",
+	"警告: 更新 %s 失败: %v":          "warning: failed to update %s: %v",
+	"创建文件失败: %w":                "failed to create file: %w",
+	"打开文件失败: %w":                "failed to open file: %w",
+	"读取文件内容失败: %w":              "failed to read file content: %w",
+	"同时创建设备模板(物模型)和设备配置，并将新设备绑定到该设备配置":                            "Also create a device template (thing model) and device config, and bind the new devices to that config",
+	"物模型中的遥测数量，与MQTT测试工具的 --data-points 一致":                       "Number of telemetry keys in the thing model, same as --data-points of the MQTT test tool",
+	"遥测标识符模板，{i}为从1开始的序号，与MQTT测试工具的 --key-template 一致":            "Telemetry identifier template, {i} is a 1-based index, same as --key-template of the MQTT test tool",
+	"物模型中的属性标识符，逗号分隔":                                             "Attribute identifiers in the thing model, comma separated",
+	"物模型中的命令标识符，逗号分隔":                                             "Command identifiers in the thing model, comma separated",
+	"遥测标识符模板不能包含 {device}，物模型在同一设备配置的所有设备间共享: %s":                 "The telemetry identifier template cannot contain {device}, the thing model is shared by all devices of the config: %s",
+	"遥测标识符模板 %s 缺少 {i}，%d 个遥测会重名":                                 "Telemetry identifier template %s lacks {i}, the %d telemetry keys would share a name",
+	"已创建设备模板 %s (遥测 %d 个, 属性 %d 个, 命令 %d 个) 和设备配置 %s":             "Created device template %s (%d telemetry, %d attributes, %d commands) and device config %s",
+	"MQTT测试工具使用 --data-points %d --key-template %s 时上报的数据点与物模型一致": "The MQTT test tool reports data points matching the thing model with --data-points %d --key-template %s",
+	"性能测试物模型":          "Load test thing model",
+	"创建设备模板失败: %w":     "failed to create device template: %w",
+	"创建遥测定义 %s 失败: %w": "failed to create telemetry definition %s: %w",
+	"创建属性定义 %s 失败: %w": "failed to create attribute definition %s: %w",
+	"创建命令定义 %s 失败: %w": "failed to create command definition %s: %w",
+	"创建设备配置失败: %w":     "failed to create device config: %w",
+	"devices表结构对应的平台版本(auto: 根据information_schema自动识别; 可选: 1.1, 1.0, 0.5)": "Platform version of the devices table schema (auto: detect from information_schema; options: 1.1, 1.0, 0.5)",
+	"数据库中不存在devices表，请确认连接的是ThingsPanel数据库":                                "devices table not found, make sure the database is a ThingsPanel database",
+	"devices表结构: 平台版本 %s (%s)":                                             "devices table schema: platform version %s (%s)",
+	"警告: devices表中没有以下列，插入时跳过: %s":                                         "Warning: the devices table has no such columns, skipped on insert: %s",
+	"devices表结构与平台版本 %s (%s) 不一致:":                                         "devices table schema does not match platform version %s (%s):",
+	"\n  + %s: 平台新增的必填列(NOT NULL且没有默认值)，版本 %s 不插入该列":                       "\n  + %s: required column added by the platform (NOT NULL without default), not inserted by version %s",
+	"\n  - %s: 版本 %s 按设备写入该列，但表中不存在":                                       "\n  - %s: version %s writes this column per device, but it does not exist in the table",
+	"\n建议: 使用 --schema=%s":                                                 "\nHint: use --schema=%s",
+	"\n建议: 平台版本比本工具已知的版本更新，需要在 schema.go 的 schemaAdapters 中添加该版本的表结构":      "\nHint: the platform is newer than any version known to this tool, add its schema to schemaAdapters in schema.go",
+	"%w\n建议: devices表结构与平台版本 %s 不一致(可能在运行期间被迁移)，重新运行以重新检测表结构，或用 --schema 指定版本": "%w\nHint: the devices table schema does not match platform version %s (it may have been migrated during the run), rerun to detect the schema again or set the version with --schema",
+	"自动识别":        "auto-detected",
+	"--schema 指定": "set by --schema",
+	"不支持的表结构版本: %s (可选: auto, %s)": "Unsupported schema version: %s (options: auto, %s)",
+	"查询表结构失败: %w":                  "Failed to query table schema: %w",
+}
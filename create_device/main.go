@@ -2,29 +2,22 @@ package main
 
 import (
 	"bufio"
-	"context"
 	"database/sql"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/go-basic/uuid"
-	_ "github.com/lib/pq"
 )
 
 // 配置选项，支持命令行参数覆盖
 var (
-	// 数据库配置
-	dbHost     = flag.String("db-host", "127.0.0.1:5432", "数据库服务器地址和端口")
-	dbUser     = flag.String("db-user", "postgres", "数据库用户名")
-	dbPassword = flag.String("db-pass", "ThingsPanel2023", "数据库密码")
-	dbName     = flag.String("db-name", "thingspanel", "数据库名称")
-	dbSSLMode  = flag.String("db-ssl", "disable", "数据库SSL模式")
-
 	// 设备配置
 	tenantID     = flag.String("tenant", "9c3f8a70", "租户ID")
 	devicePrefix = flag.String("prefix", "2025.5.8测试", "设备名称前缀")
@@ -37,11 +30,19 @@ var (
 	idFileName    = flag.String("id-file", "device_id.txt", "设备ID文件名")
 	tokenFileName = flag.String("token-file", "device_username.txt", "设备Token文件名")
 	appendMode    = flag.Bool("append", true, "是否追加写入文件")
+	resultsDir    = flag.String("results-dir", "", "运行记录目录，设置后每次运行在 <时间>-<运行ID> 子目录中保存本次创建的设备凭证快照和日志")
 )
 
+// runDir 本次运行的记录目录，未启用时为空
+var runDir string
+
+// command 子命令(export/import)，为空时创建设备
+var command string
+
 // DeviceVoucher 设备凭证结构
 type DeviceVoucher struct {
-	Username string `json:"username"`
+	Username        string `json:"username"`
+	CertFingerprint string `json:"cert_fingerprint,omitempty"` // 证书认证时设备证书的SHA-256指纹
 }
 
 // Device 结构体表示要创建的设备
@@ -51,105 +52,143 @@ type Device struct {
 	Token        string
 	VoucherJSON  string
 	CreationTime time.Time
+	Cert         *DeviceCert // 未启用证书时为nil
 }
 
 func init() {
-	// 解析命令行参数
+	// Windows控制台按UTF-8输出中文日志
+	setupConsole()
+
+	// 解析命令行参数，子命令在参数之前
+	command = subcommand()
+	translateFlagUsage()
 	flag.Parse()
 
 	// 设置日志格式
 	log.SetFlags(log.Ldate | log.Ltime | log.Lshortfile)
+
+	// 合并配置文件和命令行参数中的数据库配置
+	if err := loadDatabaseConfig(); err != nil {
+		log.Fatalf("%v", err)
+	}
 }
 
 func main() {
-	log.Println("开始创建测试设备...")
+	if command != "" {
+		runFleetCommand(command)
+		return
+	}
+
+	log.Println(T("开始创建测试设备..."))
 
 	// 连接数据库
 	db, err := connectDB()
 	if err != nil {
-		log.Fatalf("连接数据库失败: %v", err)
+		log.Fatalf(T("连接数据库失败: %v"), err)
 	}
 	defer db.Close()
 
+	// 按平台版本的表结构生成插入语句
+	if err := detectDeviceSchema(db); err != nil {
+		log.Fatalf("%v", err)
+	}
+
 	// 创建输出目录
 	if err := os.MkdirAll(*outputDir, 0755); err != nil {
-		log.Fatalf("创建输出目录失败: %v", err)
+		log.Fatalf(T("创建输出目录失败: %v"), err)
 	}
 
-	// 生成设备并插入数据库
-	devices, err := createDevices(db, *deviceCount)
-	if err != nil {
-		log.Fatalf("创建设备失败: %v", err)
+	// 加载或生成签发设备证书的测试CA
+	if *certDir != "" {
+		if err := loadOrCreateCA(*certDir); err != nil {
+			log.Fatalf("%v", err)
+		}
 	}
-	log.Printf("成功创建 %d 个设备", len(devices))
 
-	// 保存设备ID和Token到文件
-	if err := saveDeviceInfo(devices); err != nil {
-		log.Fatalf("保存设备信息到文件失败: %v", err)
+	// 创建本次运行的记录目录
+	if *resultsDir != "" {
+		if err := setupRunDir(); err != nil {
+			log.Fatalf(T("创建运行目录失败: %v"), err)
+		}
+		log.Printf(T("运行目录: %s"), runDir)
 	}
 
-	log.Println("设备创建完成")
-}
+	// 创建设备前检查分组参数，避免设备已插入后才发现参数错误
+	if _, err := groupWeights(); err != nil {
+		log.Fatalf("%v", err)
+	}
 
-// connectDB 连接PostgreSQL数据库
-func connectDB() (*sql.DB, error) {
-	// 构建连接字符串
-	connStr := fmt.Sprintf("postgres://%s:%s@%s/%s?sslmode=%s",
-		*dbUser, *dbPassword, *dbHost, *dbName, *dbSSLMode)
+	// 创建物模型和设备配置，新设备绑定到该设备配置
+	var configID sql.NullString
+	if *withModel {
+		model, err := createDeviceModel(db)
+		if err != nil {
+			log.Fatalf(T("创建物模型失败: %v"), err)
+		}
+		configID = sql.NullString{String: model.ConfigID, Valid: true}
+	}
 
-	// 连接数据库
-	db, err := sql.Open("postgres", connStr)
-	if err != nil {
-		return nil, fmt.Errorf("无法连接数据库: %w", err)
+	// 补足模式：只创建已有设备与目标数量的差额，序号接在已有设备之后，避免名称重复
+	count, start := *deviceCount, 0
+	var existing []Device
+	if *ensureCount > 0 {
+		existing, start, err = existingDevices(db, *tenantID, deviceNamePrefix())
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		count = max(*ensureCount-len(existing), 0)
+		log.Printf(T("租户 %s 下已有 %d 个名称前缀为 %s 的设备，目标 %d 个，需要创建 %d 个"),
+			*tenantID, len(existing), deviceNamePrefix(), *ensureCount, count)
+		if len(existing) > *ensureCount {
+			log.Printf(T("警告: 已有设备数超过目标 %d 个，不会删除多出的设备"), len(existing)-*ensureCount)
+		}
 	}
 
-	// 测试连接
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	// 生成设备并插入数据库
+	var devices []Device
+	if count > 0 {
+		devices, err = createDevices(db, count, start, configID)
+		if err != nil {
+			log.Fatalf(T("创建设备失败: %v"), err)
+		}
+		log.Printf(T("成功创建 %d 个设备"), len(devices))
 
-	if err := db.PingContext(ctx); err != nil {
-		return nil, fmt.Errorf("数据库连接测试失败: %w", err)
+		// 创建设备分组并分配设备
+		groups, err := createDeviceGroups(db, devices)
+		if err != nil {
+			log.Fatalf(T("创建设备分组失败: %v"), err)
+		}
+		reportGroups(groups)
 	}
 
-	// 设置连接池参数
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(5)
-	db.SetConnMaxLifetime(5 * time.Minute)
+	// 保存设备ID和Token到文件
+	if err := saveDeviceInfo(devices, existing); err != nil {
+		log.Fatalf(T("保存设备信息到文件失败: %v"), err)
+	}
 
-	return db, nil
+	log.Println(T("设备创建完成"))
 }
 
-// createDevices 生成指定数量的设备并插入数据库
-func createDevices(db *sql.DB, count int) ([]Device, error) {
+// createDevices 生成指定数量的设备并插入数据库，设备名称的序号从start开始，configID不为空时绑定到该设备配置
+func createDevices(db *sql.DB, count, start int, configID sql.NullString) ([]Device, error) {
 	devices := make([]Device, 0, count)
 
 	// 开始事务
 	tx, err := db.Begin()
 	if err != nil {
-		return nil, fmt.Errorf("开始事务失败: %w", err)
+		return nil, fmt.Errorf(T("开始事务失败: %w"), err)
 	}
 	defer tx.Rollback() // 如果提交成功，这个回滚不会执行
 
 	// 准备SQL语句
-	stmt, err := tx.Prepare(`INSERT INTO devices (
-		id, "name", voucher, tenant_id, is_enabled, activate_flag, 
-		created_at, update_at, device_number, product_id, parent_id, 
-		protocol, "label", "location", sub_device_addr, current_version, 
-		additional_info, protocol_config, remark1, remark2, remark3, 
-		device_config_id, batch_number, activate_at, is_online, access_way, 
-		description, service_access_id) 
-	VALUES (
-		$1, $2, $3, $4, '', 'active', $5, $6, $7, 
-		NULL, NULL, NULL, '', NULL, NULL, NULL, 
-		'{}'::json, '{}'::json, NULL, NULL, NULL, 
-		NULL, NULL, NULL, 0, 'A', NULL, NULL)`)
+	stmt, err := prepareTx(tx, deviceInsert.sql)
 	if err != nil {
-		return nil, fmt.Errorf("准备SQL语句失败: %w", err)
+		return nil, fmt.Errorf(T("准备SQL语句失败: %w"), explainInsertError(err))
 	}
 	defer stmt.Close()
 
 	// 批量创建设备
-	log.Printf("开始创建 %d 个设备...", count)
+	log.Printf(T("开始创建 %d 个设备..."), count)
 	startTime := time.Now()
 
 	// 检查是批量处理还是一次性处理
@@ -160,55 +199,39 @@ func createDevices(db *sql.DB, count int) ([]Device, error) {
 
 	for i := 0; i < count; i++ {
 		// 创建设备信息
-		device := generateDevice(i)
+		device, err := generateDevice(start + i)
+		if err != nil {
+			return nil, fmt.Errorf(T("生成设备失败(序号 %d): %w"), i, err)
+		}
 		devices = append(devices, device)
 
 		// 执行插入
-		_, err = stmt.Exec(
-			device.ID,
-			device.Name,
-			device.VoucherJSON,
-			*tenantID,
-			device.CreationTime,
-			device.CreationTime,
-			device.ID,
-		)
+		_, err = stmt.Exec(deviceInsert.values(deviceRow{Device: device, Tenant: *tenantID, Config: configID})...)
 		if err != nil {
-			return nil, fmt.Errorf("插入设备数据失败(序号 %d): %w", i, err)
+			return nil, fmt.Errorf(T("插入设备数据失败(序号 %d): %w"), i, explainInsertError(err))
 		}
 
 		// 每批次提交一次事务
 		if (i+1)%batchCount == 0 || i == count-1 {
 			if err := tx.Commit(); err != nil {
-				return nil, fmt.Errorf("提交事务失败: %w", err)
+				return nil, fmt.Errorf(T("提交事务失败: %w"), err)
 			}
 
 			// 进度报告
 			progress := float64(i+1) / float64(count) * 100
-			log.Printf("进度: %.1f%% (%d/%d)", progress, i+1, count)
+			log.Printf(T("进度: %.1f%% (%d/%d)"), progress, i+1, count)
 
 			// 如果还有更多设备要创建，开始新事务
 			if i < count-1 {
 				tx, err = db.Begin()
 				if err != nil {
-					return nil, fmt.Errorf("开始新事务失败: %w", err)
+					return nil, fmt.Errorf(T("开始新事务失败: %w"), err)
 				}
 				defer tx.Rollback()
 
-				stmt, err = tx.Prepare(`INSERT INTO devices (
-					id, "name", voucher, tenant_id, is_enabled, activate_flag, 
-					created_at, update_at, device_number, product_id, parent_id, 
-					protocol, "label", "location", sub_device_addr, current_version, 
-					additional_info, protocol_config, remark1, remark2, remark3, 
-					device_config_id, batch_number, activate_at, is_online, access_way, 
-					description, service_access_id) 
-				VALUES (
-					$1, $2, $3, $4, '', 'active', $5, $6, $7, 
-					NULL, NULL, NULL, '', NULL, NULL, NULL, 
-					'{}'::json, '{}'::json, NULL, NULL, NULL, 
-					NULL, NULL, NULL, 0, 'A', NULL, NULL)`)
+				stmt, err = prepareTx(tx, deviceInsert.sql)
 				if err != nil {
-					return nil, fmt.Errorf("准备新SQL语句失败: %w", err)
+					return nil, fmt.Errorf(T("准备新SQL语句失败: %w"), explainInsertError(err))
 				}
 				defer stmt.Close()
 			}
@@ -216,23 +239,35 @@ func createDevices(db *sql.DB, count int) ([]Device, error) {
 	}
 
 	elapsed := time.Since(startTime)
-	log.Printf("创建完成，耗时: %v，平均: %.2f 设备/秒",
+	log.Printf(T("创建完成，耗时: %v，平均: %.2f 设备/秒"),
 		elapsed, float64(count)/elapsed.Seconds())
 
 	return devices, nil
 }
 
 // generateDevice 生成单个设备信息
-func generateDevice(index int) Device {
+func generateDevice(index int) (Device, error) {
 	id := uuid.New()
 	token := uuid.New()
 	now := time.Now()
 
+	// 启用证书时签发设备证书，凭证中记录证书指纹
+	var cert *DeviceCert
+	if caCert != nil {
+		var err error
+		if cert, err = issueDeviceCert(token); err != nil {
+			return Device{}, err
+		}
+	}
+
 	// 创建设备凭证
 	voucher := DeviceVoucher{Username: token}
+	if cert != nil {
+		voucher.CertFingerprint = cert.Fingerprint
+	}
 	voucherJSON, err := json.Marshal(voucher)
 	if err != nil {
-		log.Printf("警告: 序列化设备凭证失败: %v", err)
+		log.Printf(T("警告: 序列化设备凭证失败: %v"), err)
 		// 使用空JSON对象作为后备方案
 		voucherJSON = []byte("{}")
 	}
@@ -246,40 +281,102 @@ func generateDevice(index int) Device {
 		Token:        token,
 		VoucherJSON:  string(voucherJSON),
 		CreationTime: now,
-	}
+		Cert:         cert,
+	}, nil
 }
 
 // saveDeviceInfo 保存设备ID和Token到文件
-func saveDeviceInfo(devices []Device) error {
+//
+// 补足模式下文件重写为已有设备(existing)和本次创建的全部设备，运行目录中只保存本次创建的设备。
+func saveDeviceInfo(devices, existing []Device) error {
+	fleet := devices
+	if *ensureCount > 0 {
+		fleet = append(existing, devices...)
+	}
 	var idList []string
 	var tokenList []string
 
 	// 提取ID和Token
-	for _, device := range devices {
+	for _, device := range fleet {
 		idList = append(idList, device.ID)
 		tokenList = append(tokenList, device.Token)
 	}
 
 	// 创建文件写入函数
+	overwrite := !*appendMode || *ensureCount > 0
 	writeFunc := WriteFile
-	if *appendMode {
+	if !overwrite {
 		writeFunc = AppendFile
 	}
 
 	// 保存ID
 	idFilePath := filepath.Join(*outputDir, *idFileName)
 	if err := writeFunc(idFilePath, idList); err != nil {
-		return fmt.Errorf("写入ID文件失败: %w", err)
+		return fmt.Errorf(T("写入ID文件失败: %w"), err)
 	}
-	log.Printf("设备ID已保存到: %s", idFilePath)
+	log.Printf(T("设备ID已保存到: %s"), idFilePath)
 
 	// 保存Token
 	tokenFilePath := filepath.Join(*outputDir, *tokenFileName)
 	if err := writeFunc(tokenFilePath, tokenList); err != nil {
-		return fmt.Errorf("写入Token文件失败: %w", err)
+		return fmt.Errorf(T("写入Token文件失败: %w"), err)
+	}
+	log.Printf(T("设备Token已保存到: %s"), tokenFilePath)
+
+	// 保存证书清单，追加模式下只在新文件中写表头
+	if caCert != nil {
+		manifestPath := filepath.Join(*outputDir, *certManifest)
+		_, statErr := os.Stat(manifestPath)
+		header := overwrite || statErr != nil
+		if err := writeFunc(manifestPath, certManifestLines(fleet, header)); err != nil {
+			return fmt.Errorf(T("写入证书清单失败: %w"), err)
+		}
+		log.Printf(T("设备证书已保存到: %s，清单: %s"), *certDir, manifestPath)
+	}
+
+	// 在运行目录中保存本次创建的设备快照
+	if runDir != "" {
+		idList, tokenList = idList[len(fleet)-len(devices):], tokenList[len(fleet)-len(devices):]
+		if err := WriteFile(filepath.Join(runDir, *idFileName), idList); err != nil {
+			return fmt.Errorf(T("写入ID文件失败: %w"), err)
+		}
+		if err := WriteFile(filepath.Join(runDir, *tokenFileName), tokenList); err != nil {
+			return fmt.Errorf(T("写入Token文件失败: %w"), err)
+		}
+		if caCert != nil {
+			if err := WriteFile(filepath.Join(runDir, *certManifest), certManifestLines(devices, true)); err != nil {
+				return fmt.Errorf(T("写入证书清单失败: %w"), err)
+			}
+		}
+		log.Printf(T("本次创建的设备快照已保存到: %s"), runDir)
+	}
+
+	return nil
+}
+
+// setupRunDir 创建 <时间>-<运行ID> 运行目录，更新latest链接，并将日志同时写入run.log
+func setupRunDir() error {
+	name := time.Now().Format("20060102-150405") + "-" + uuid.New()[:8]
+	dir := filepath.Join(*resultsDir, name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	runDir = dir
+
+	// Windows上创建符号链接可能需要管理员权限，失败时改为写入目录名
+	latest := filepath.Join(*resultsDir, "latest")
+	os.Remove(latest)
+	if err := os.Symlink(name, latest); err != nil {
+		if err := os.WriteFile(latest, []byte(name+"\n"), 0644); err != nil {
+			log.Printf(T("警告: 更新 %s 失败: %v"), latest, err)
+		}
 	}
-	log.Printf("设备Token已保存到: %s", tokenFilePath)
 
+	logFile, err := os.OpenFile(filepath.Join(dir, "run.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	log.SetOutput(io.MultiWriter(os.Stderr, logFile))
 	return nil
 }
 
@@ -287,7 +384,7 @@ func saveDeviceInfo(devices []Device) error {
 func WriteFile(filepath string, lines []string) error {
 	f, err := os.Create(filepath)
 	if err != nil {
-		return fmt.Errorf("创建文件失败: %w", err)
+		return fmt.Errorf(T("创建文件失败: %w"), err)
 	}
 	defer f.Close()
 
@@ -303,7 +400,7 @@ func WriteFile(filepath string, lines []string) error {
 func AppendFile(filepath string, lines []string) error {
 	f, err := os.OpenFile(filepath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
-		return fmt.Errorf("打开文件失败: %w", err)
+		return fmt.Errorf(T("打开文件失败: %w"), err)
 	}
 	defer f.Close()
 
@@ -319,21 +416,22 @@ func AppendFile(filepath string, lines []string) error {
 func ReadFile(filepath string) ([]string, error) {
 	f, err := os.Open(filepath)
 	if err != nil {
-		return nil, fmt.Errorf("打开文件失败: %w", err)
+		return nil, fmt.Errorf(T("打开文件失败: %w"), err)
 	}
 	defer f.Close()
 
 	var lines []string
 	scanner := bufio.NewScanner(f)
 	for scanner.Scan() {
-		line := scanner.Text()
+		// 兼容Windows换行符
+		line := strings.TrimRight(scanner.Text(), "\r")
 		if line != "" { // 忽略空行
 			lines = append(lines, line)
 		}
 	}
 
 	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("读取文件内容失败: %w", err)
+		return nil, fmt.Errorf(T("读取文件内容失败: %w"), err)
 	}
 
 	return lines, nil
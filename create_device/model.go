@@ -0,0 +1,135 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-basic/uuid"
+)
+
+// 物模型配置
+var (
+	withModel       = flag.Bool("with-model", false, "同时创建设备模板(物模型)和设备配置，并将新设备绑定到该设备配置")
+	modelKeys       = flag.Int("model-keys", 1, "物模型中的遥测数量，与MQTT测试工具的 --data-points 一致")
+	modelKeyTmpl    = flag.String("model-key-template", "hum{i}", "遥测标识符模板，{i}为从1开始的序号，与MQTT测试工具的 --key-template 一致")
+	modelAttributes = flag.String("model-attributes", "version", "物模型中的属性标识符，逗号分隔")
+	modelCommands   = flag.String("model-commands", "reboot", "物模型中的命令标识符，逗号分隔")
+)
+
+// deviceModel 本次创建的设备模板和设备配置
+type deviceModel struct {
+	TemplateID string
+	ConfigID   string
+	Telemetry  []string
+	Attributes []string
+	Commands   []string
+}
+
+// modelTelemetryKeys 按模板生成遥测标识符，与MQTT测试工具生成数据点名称的规则一致
+func modelTelemetryKeys() ([]string, error) {
+	if strings.Contains(*modelKeyTmpl, "{device}") {
+		return nil, fmt.Errorf(T("遥测标识符模板不能包含 {device}，物模型在同一设备配置的所有设备间共享: %s"), *modelKeyTmpl)
+	}
+	if *modelKeys > 1 && !strings.Contains(*modelKeyTmpl, "{i}") {
+		return nil, fmt.Errorf(T("遥测标识符模板 %s 缺少 {i}，%d 个遥测会重名"), *modelKeyTmpl, *modelKeys)
+	}
+	keys := make([]string, *modelKeys)
+	for i := range keys {
+		keys[i] = strings.ReplaceAll(*modelKeyTmpl, "{i}", strconv.Itoa(i+1))
+	}
+	return keys, nil
+}
+
+// splitIdentifiers 拆分逗号分隔的标识符列表，忽略空项
+func splitIdentifiers(list string) []string {
+	var ids []string
+	for _, id := range strings.Split(list, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// createDeviceModel 在一个事务中创建设备模板、遥测/属性/命令定义和引用该模板的设备配置
+//
+// 模拟设备上报的数据点名称与物模型中的遥测标识符一致，平台侧按物模型进行的校验和可视化才会被覆盖到。
+func createDeviceModel(db *sql.DB) (*deviceModel, error) {
+	keys, err := modelTelemetryKeys()
+	if err != nil {
+		return nil, err
+	}
+	model := &deviceModel{
+		TemplateID: uuid.New(),
+		ConfigID:   uuid.New(),
+		Telemetry:  keys,
+		Attributes: splitIdentifiers(*modelAttributes),
+		Commands:   splitIdentifiers(*modelCommands),
+	}
+	name := fmt.Sprintf("%s_%s", *devicePrefix, *deviceNumber)
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf(T("开始事务失败: %w"), err)
+	}
+	defer tx.Rollback()
+
+	if err := insertDeviceModel(tx, model, name, *tenantID, time.Now()); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf(T("提交事务失败: %w"), err)
+	}
+	log.Printf(T("已创建设备模板 %s (遥测 %d 个, 属性 %d 个, 命令 %d 个) 和设备配置 %s"),
+		model.TemplateID, len(model.Telemetry), len(model.Attributes), len(model.Commands), model.ConfigID)
+	log.Printf(T("MQTT测试工具使用 --data-points %d --key-template %s 时上报的数据点与物模型一致"),
+		len(model.Telemetry), *modelKeyTmpl)
+	return model, nil
+}
+
+// insertDeviceModel 在事务中插入设备模板、遥测/属性/命令定义和引用该模板的设备配置(模板和配置同名)
+func insertDeviceModel(tx *sql.Tx, model *deviceModel, name, tenant string, now time.Time) error {
+	if _, err := tx.Exec(`INSERT INTO device_templates (id, "name", "version", description, tenant_id, created_at, updated_at, flag)
+		VALUES ($1, $2, '1.0.0', $3, $4, $5, $5, 1)`,
+		model.TemplateID, name, T("性能测试物模型"), tenant, now); err != nil {
+		return fmt.Errorf(T("创建设备模板失败: %w"), err)
+	}
+
+	for _, key := range model.Telemetry {
+		if _, err := tx.Exec(`INSERT INTO device_model_telemetry (id, device_template_id, data_name, data_identifier,
+			read_write_flag, data_type, tenant_id, created_at, updated_at)
+			VALUES ($1, $2, $3, $3, 'R', 'Number', $4, $5, $5)`,
+			uuid.New(), model.TemplateID, key, tenant, now); err != nil {
+			return fmt.Errorf(T("创建遥测定义 %s 失败: %w"), key, err)
+		}
+	}
+	for _, attr := range model.Attributes {
+		if _, err := tx.Exec(`INSERT INTO device_model_attributes (id, device_template_id, data_name, data_identifier,
+			read_write_flag, data_type, tenant_id, created_at, updated_at)
+			VALUES ($1, $2, $3, $3, 'R', 'String', $4, $5, $5)`,
+			uuid.New(), model.TemplateID, attr, tenant, now); err != nil {
+			return fmt.Errorf(T("创建属性定义 %s 失败: %w"), attr, err)
+		}
+	}
+	for _, cmd := range model.Commands {
+		if _, err := tx.Exec(`INSERT INTO device_model_commands (id, device_template_id, data_name, data_identifier,
+			params, tenant_id, created_at, updated_at)
+			VALUES ($1, $2, $3, $3, '[]'::json, $4, $5, $5)`,
+			uuid.New(), model.TemplateID, cmd, tenant, now); err != nil {
+			return fmt.Errorf(T("创建命令定义 %s 失败: %w"), cmd, err)
+		}
+	}
+
+	if _, err := tx.Exec(`INSERT INTO device_configs (id, "name", device_template_id, device_type, protocol_type,
+		voucher_type, protocol_config, device_conn_type, additional_info, tenant_id, created_at, updated_at)
+		VALUES ($1, $2, $3, '1', 'MQTT', 'ACCESSTOKEN', '{}'::json, 'A', '{}'::json, $4, $5, $5)`,
+		model.ConfigID, name, model.TemplateID, tenant, now); err != nil {
+		return fmt.Errorf(T("创建设备配置失败: %w"), err)
+	}
+	return nil
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// schemaFlag 指定devices表结构对应的平台版本，auto为根据information_schema自动识别
+var schemaFlag = flag.String("schema", "auto", "devices表结构对应的平台版本(auto: 根据information_schema自动识别; 可选: 1.1, 1.0, 0.5)")
+
+// deviceRow 插入devices表的一行
+type deviceRow struct {
+	Device
+	Tenant string
+	Config sql.NullString // 绑定的设备配置，为空时不绑定
+}
+
+// deviceColumn devices表的一列及其取值
+type deviceColumn struct {
+	name string
+	arg  func(r deviceRow) any // 按设备取值，作为参数传入
+	expr string                // arg为nil时使用的固定SQL表达式
+}
+
+// schemaAdapter 某个平台版本的devices表结构
+//
+// 各版本之间列不同，插入时只使用表中实际存在的列，表中多出的可为空或有默认值的列
+// 不影响插入。
+type schemaAdapter struct {
+	version string
+	markers []string // 该版本新增的列，全部存在时识别为该版本
+	columns []deviceColumn
+}
+
+// 设备各字段的取值
+var (
+	colID      = func(r deviceRow) any { return r.ID }
+	colName    = func(r deviceRow) any { return r.Name }
+	colVoucher = func(r deviceRow) any { return r.VoucherJSON }
+	colTenant  = func(r deviceRow) any { return r.Tenant }
+	colCreated = func(r deviceRow) any { return r.CreationTime }
+	colConfig  = func(r deviceRow) any { return r.Config }
+)
+
+// baseDeviceColumns 各版本共有的列
+var baseDeviceColumns = []deviceColumn{
+	{name: "id", arg: colID},
+	{name: `"name"`, arg: colName},
+	{name: "voucher", arg: colVoucher},
+	{name: "tenant_id", arg: colTenant},
+	{name: "is_enabled", expr: "''"},
+	{name: "activate_flag", expr: "'active'"},
+	{name: "created_at", arg: colCreated},
+	{name: "update_at", arg: colCreated},
+	{name: "device_number", arg: colID},
+	{name: "product_id", expr: "NULL"},
+	{name: "parent_id", expr: "NULL"},
+	{name: "protocol", expr: "NULL"},
+	{name: `"label"`, expr: "''"},
+	{name: `"location"`, expr: "NULL"},
+	{name: "sub_device_addr", expr: "NULL"},
+	{name: "current_version", expr: "NULL"},
+	{name: "additional_info", expr: "'{}'::json"},
+	{name: "protocol_config", expr: "'{}'::json"},
+	{name: "remark1", expr: "NULL"},
+	{name: "remark2", expr: "NULL"},
+	{name: "remark3", expr: "NULL"},
+	{name: "device_config_id", arg: colConfig},
+	{name: "batch_number", expr: "NULL"},
+	{name: "activate_at", expr: "NULL"},
+	{name: "is_online", expr: "0"},
+}
+
+// schemaAdapters 已知的表结构，从新到旧排列，自动识别时取第一个标志列全部存在的版本
+var schemaAdapters = []schemaAdapter{
+	{
+		version: "1.1",
+		markers: []string{"service_access_id"},
+		columns: append(append([]deviceColumn{}, baseDeviceColumns...),
+			deviceColumn{name: "access_way", expr: "'A'"},
+			deviceColumn{name: "description", expr: "NULL"},
+			deviceColumn{name: "service_access_id", expr: "NULL"}),
+	},
+	{
+		version: "1.0",
+		markers: []string{"access_way"},
+		columns: append(append([]deviceColumn{}, baseDeviceColumns...),
+			deviceColumn{name: "access_way", expr: "'A'"},
+			deviceColumn{name: "description", expr: "NULL"}),
+	},
+	{
+		version: "0.5",
+		columns: baseDeviceColumns,
+	},
+}
+
+// schemaVersions 已知表结构的版本列表
+func schemaVersions() []string {
+	versions := make([]string, 0, len(schemaAdapters))
+	for _, a := range schemaAdapters {
+		versions = append(versions, a.version)
+	}
+	return versions
+}
+
+// insertPlan 按实际表结构生成的插入语句
+type insertPlan struct {
+	version string
+	sql     string
+	args    []func(r deviceRow) any
+}
+
+// deviceInsert 本次运行使用的插入语句，由detectDeviceSchema生成
+var deviceInsert *insertPlan
+
+// detectDeviceSchema 读取devices表的列，选择表结构版本并生成插入语句
+//
+// 表结构与版本不一致时在插入之前失败，给出差异和建议的版本，而不是在批量插入中途
+// 报出难以理解的数据库错误。版本中有固定值而表中不存在的列(被平台删除或改名)
+// 不插入并给出警告。
+func detectDeviceSchema(db *sql.DB) error {
+	columns, err := tableColumns(db, "devices")
+	if err != nil {
+		return err
+	}
+	if len(columns) == 0 {
+		return errors.New(T("数据库中不存在devices表，请确认连接的是ThingsPanel数据库"))
+	}
+
+	adapter, err := selectSchemaAdapter(columns)
+	if err != nil {
+		return err
+	}
+	drift := compareSchema(adapter, columns)
+	if drift.breaking() {
+		return drift.error(adapter, columns)
+	}
+	deviceInsert = buildInsertPlan(adapter, columns)
+
+	log.Printf(T("devices表结构: 平台版本 %s (%s)"), adapter.version, schemaSource())
+	if len(drift.skipped) > 0 {
+		log.Printf(T("警告: devices表中没有以下列，插入时跳过: %s"), strings.Join(drift.skipped, ", "))
+	}
+	return nil
+}
+
+// schemaDrift 表结构版本与实际表结构的差异
+type schemaDrift struct {
+	added   []string // 表中有而版本中没有的必填列(NOT NULL且没有默认值)，插入必然失败
+	missing []string // 版本按设备取值而表中不存在的列，不能跳过
+	skipped []string // 版本中有固定值而表中不存在的列，插入时跳过
+}
+
+// compareSchema 比较表结构版本与实际表结构
+func compareSchema(adapter *schemaAdapter, columns map[string]tableColumn) schemaDrift {
+	var drift schemaDrift
+	defined := make(map[string]bool, len(adapter.columns))
+	for _, c := range adapter.columns {
+		name := strings.Trim(c.name, `"`)
+		defined[name] = true
+		if _, ok := columns[name]; ok {
+			continue
+		}
+		if c.arg != nil {
+			drift.missing = append(drift.missing, name)
+		} else {
+			drift.skipped = append(drift.skipped, c.name)
+		}
+	}
+	for name, c := range columns {
+		if c.required && !defined[name] {
+			drift.added = append(drift.added, name)
+		}
+	}
+	sort.Strings(drift.added)
+	return drift
+}
+
+// breaking 差异是否会导致插入失败
+func (d schemaDrift) breaking() bool {
+	return len(d.added) > 0 || len(d.missing) > 0
+}
+
+// error 生成列出差异和建议版本的错误
+func (d schemaDrift) error(adapter *schemaAdapter, columns map[string]tableColumn) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, T("devices表结构与平台版本 %s (%s) 不一致:"), adapter.version, schemaSource())
+	for _, name := range d.added {
+		fmt.Fprintf(&b, T("\n  + %s: 平台新增的必填列(NOT NULL且没有默认值)，版本 %s 不插入该列"), name, adapter.version)
+	}
+	for _, name := range d.missing {
+		fmt.Fprintf(&b, T("\n  - %s: 版本 %s 按设备写入该列，但表中不存在"), name, adapter.version)
+	}
+	if suggested := compatibleSchema(columns); suggested != nil {
+		fmt.Fprintf(&b, T("\n建议: 使用 --schema=%s"), suggested.version)
+	} else {
+		b.WriteString(T("\n建议: 平台版本比本工具已知的版本更新，需要在 schema.go 的 schemaAdapters 中添加该版本的表结构"))
+	}
+	return errors.New(b.String())
+}
+
+// compatibleSchema 返回第一个与实际表结构没有冲突的版本，没有时返回nil
+func compatibleSchema(columns map[string]tableColumn) *schemaAdapter {
+	for i := range schemaAdapters {
+		if !compareSchema(&schemaAdapters[i], columns).breaking() {
+			return &schemaAdapters[i]
+		}
+	}
+	return nil
+}
+
+// buildInsertPlan 按表中实际存在的列生成插入语句
+func buildInsertPlan(adapter *schemaAdapter, columns map[string]tableColumn) *insertPlan {
+	plan := &insertPlan{version: adapter.version}
+	var names, values []string
+	for _, c := range adapter.columns {
+		if _, ok := columns[strings.Trim(c.name, `"`)]; !ok {
+			continue
+		}
+		names = append(names, c.name)
+		if c.arg != nil {
+			plan.args = append(plan.args, c.arg)
+			values = append(values, fmt.Sprintf("$%d", len(plan.args)))
+		} else {
+			values = append(values, c.expr)
+		}
+	}
+	plan.sql = fmt.Sprintf("INSERT INTO devices (%s) VALUES (%s)", strings.Join(names, ", "), strings.Join(values, ", "))
+	return plan
+}
+
+// explainInsertError 插入失败由表结构不一致引起(列不存在、必填列为空)时附加处理建议
+//
+// 启动时已检查过表结构，运行期间平台升级或迁移仍可能改变表结构。
+func explainInsertError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+	switch pgErr.Code {
+	case "42703", "23502": // undefined_column, not_null_violation
+		return fmt.Errorf(T("%w\n建议: devices表结构与平台版本 %s 不一致(可能在运行期间被迁移)，重新运行以重新检测表结构，或用 --schema 指定版本"), err, deviceInsert.version)
+	}
+	return err
+}
+
+// schemaSource 表结构版本的来源描述
+func schemaSource() string {
+	if *schemaFlag == "auto" {
+		return T("自动识别")
+	}
+	return T("--schema 指定")
+}
+
+// selectSchemaAdapter 按 --schema 或标志列选择表结构版本
+func selectSchemaAdapter(columns map[string]tableColumn) (*schemaAdapter, error) {
+	for i := range schemaAdapters {
+		a := &schemaAdapters[i]
+		if *schemaFlag != "auto" {
+			if a.version == *schemaFlag {
+				return a, nil
+			}
+			continue
+		}
+		matched := true
+		for _, m := range a.markers {
+			_, ok := columns[m]
+			matched = matched && ok
+		}
+		if matched {
+			return a, nil
+		}
+	}
+	return nil, fmt.Errorf(T("不支持的表结构版本: %s (可选: auto, %s)"), *schemaFlag, strings.Join(schemaVersions(), ", "))
+}
+
+// tableColumn 表中一列的约束
+type tableColumn struct {
+	required bool // NOT NULL且没有默认值，插入时必须给出
+}
+
+// tableColumns 查询当前schema中表的列
+func tableColumns(db *sql.DB, table string) (map[string]tableColumn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	rows, err := db.QueryContext(ctx, `SELECT column_name,
+			is_nullable = 'NO' AND column_default IS NULL AND is_identity = 'NO' AND is_generated = 'NEVER'
+		FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = $1`, table)
+	if err != nil {
+		return nil, fmt.Errorf(T("查询表结构失败: %w"), err)
+	}
+	defer rows.Close()
+	columns := make(map[string]tableColumn)
+	for rows.Next() {
+		var name string
+		var c tableColumn
+		if err := rows.Scan(&name, &c.required); err != nil {
+			return nil, fmt.Errorf(T("查询表结构失败: %w"), err)
+		}
+		columns[name] = c
+	}
+	return columns, rows.Err()
+}
+
+// values 按插入语句的参数顺序取出设备各列的值
+func (p *insertPlan) values(r deviceRow) []any {
+	args := make([]any, len(p.args))
+	for i, arg := range p.args {
+		args[i] = arg(r)
+	}
+	return args
+}
@@ -7,13 +7,18 @@ toolchain go1.22.4
 require (
 	github.com/eclipse/paho.mqtt.golang v1.4.3
 	github.com/go-basic/uuid v1.0.0
-	github.com/lib/pq v1.10.9
+	github.com/jackc/pgx/v5 v5.5.5
 )
 
 require (
 	github.com/brianvoe/gofakeit/v7 v7.0.4 // indirect
 	github.com/gorilla/websocket v1.5.0 // indirect
-	golang.org/x/net v0.8.0 // indirect
+	github.com/jackc/pgpassfile v1.0.0 // indirect
+	github.com/jackc/pgservicefile v0.0.0-20221227161230-091c0ba34f0a // indirect
+	github.com/jackc/puddle/v2 v2.2.1 // indirect
+	golang.org/x/crypto v0.17.0 // indirect
+	golang.org/x/net v0.17.0 // indirect
 	golang.org/x/sync v0.1.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
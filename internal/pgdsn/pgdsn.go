@@ -0,0 +1,174 @@
+// Package pgdsn 构建压测工具(mqtt)和设备创建工具(create_device)共用的PostgreSQL连接字符串
+package pgdsn
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Options 数据库连接配置，与两个工具配置文件的database部分对应
+type Options struct {
+	Host     string // 数据库服务器地址和端口，或Unix域套接字目录(可带 :端口)
+	User     string // 数据库用户名
+	Password string // 数据库密码
+	Name     string // 数据库名称
+	SSLMode  string // 数据库SSL模式
+	DSN      string // 完整连接字符串(URL或libpq关键字格式)，设置后忽略以上各项
+
+	PgBouncer        bool          // 通过pgbouncer(事务池模式)连接
+	ConnectTimeout   time.Duration // 建立连接的超时时间，不足1秒按1秒
+	StatementTimeout time.Duration // 单条SQL的服务端执行超时，不大于0时不设置
+	StatementCache   int           // 每个连接缓存的预备语句数，负数为不缓存，0为驱动的默认容量
+}
+
+// Build 根据配置构建连接字符串
+//
+// statement_timeout作为会话参数传给服务端，查询在表被锁等情况下超时由数据库取消，
+// 不会无限期阻塞。statement_cache_capacity由pgx驱动处理，每个连接缓存重复执行的
+// 查询的预备语句，固定的查询和插入不必每次重新解析。
+func Build(o Options) string {
+	return BuildHost(o, "")
+}
+
+// BuildHost 构建连接指定主机(如只读副本)的连接字符串，host为空时使用配置的主机
+//
+// 账号和数据库名经过URL转义，密码中的@、#、/、%等字符按原样传给服务端。配置了完整
+// 连接字符串时以其为准，只替换主机并补充其中没有的参数。
+func BuildHost(o Options, host string) string {
+	params := url.Values{}
+	params.Set("sslmode", o.SSLMode)
+	params.Set("connect_timeout", strconv.Itoa(max(int(o.ConnectTimeout.Seconds()), 1)))
+	if o.PgBouncer {
+		// 事务池模式下服务端连接在事务之间分给其他客户端：带参数的查询以未命名语句一次发送，
+		// 不使用跨请求的预备语句；pgbouncer拒绝不认识的启动参数，SQL超时改由各查询的超时控制
+		params.Set("default_query_exec_mode", "exec")
+	} else {
+		if o.StatementTimeout > 0 {
+			params.Set("statement_timeout", strconv.FormatInt(o.StatementTimeout.Milliseconds(), 10))
+		}
+		switch {
+		case o.StatementCache > 0:
+			params.Set("statement_cache_capacity", strconv.Itoa(o.StatementCache))
+		case o.StatementCache < 0:
+			params.Set("default_query_exec_mode", "describe_exec")
+		}
+	}
+	if o.DSN != "" {
+		if host == o.Host {
+			host = ""
+		}
+		return overrideDSN(o.DSN, host, params)
+	}
+	if host == "" {
+		host = o.Host
+	}
+	if strings.HasPrefix(host, "/") {
+		// Unix域套接字目录，主机部分留空，目录和端口作为参数
+		dir, port := splitSocketHost(host)
+		params.Set("host", dir)
+		if port != "" {
+			params.Set("port", port)
+		}
+		host = ""
+	}
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(o.User, o.Password),
+		Host:     host,
+		Path:     "/" + o.Name,
+		RawQuery: params.Encode(),
+	}
+	return u.String()
+}
+
+// Check 检查完整连接字符串能否被驱动解析
+func Check(dsn string) error {
+	_, err := pgx.ParseConfig(dsn)
+	return err
+}
+
+// overrideDSN 在完整连接字符串的基础上补充参数，host不为空时替换其中的主机
+//
+// URL格式只补充查询参数中没有的默认参数。关键字格式中同一关键字出现多次时以最后一次
+// 为准，因此默认参数放在前面，由连接字符串中的同名参数覆盖，替换的主机放在最后。
+func overrideDSN(dsn, host string, defaults url.Values) string {
+	if isURLDSN(dsn) {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			// 原样返回，由Check或驱动报告错误
+			return dsn
+		}
+		query := u.Query()
+		for key := range defaults {
+			if !query.Has(key) {
+				query.Set(key, defaults.Get(key))
+			}
+		}
+		if host != "" {
+			query.Del("host")
+			query.Del("port")
+			u.Host = host
+			if strings.HasPrefix(host, "/") {
+				dir, port := splitSocketHost(host)
+				query.Set("host", dir)
+				if port != "" {
+					query.Set("port", port)
+				}
+				u.Host = ""
+			}
+		}
+		u.RawQuery = query.Encode()
+		return u.String()
+	}
+	var b strings.Builder
+	for _, key := range dsnDefaultKeys {
+		if v := defaults.Get(key); v != "" {
+			fmt.Fprintf(&b, "%s=%s ", key, dsnValue(v))
+		}
+	}
+	b.WriteString(dsn)
+	if host != "" {
+		name, port, err := net.SplitHostPort(host)
+		if strings.HasPrefix(host, "/") {
+			name, port = splitSocketHost(host)
+		} else if err != nil {
+			name, port = strings.Trim(host, "[]"), ""
+		}
+		fmt.Fprintf(&b, " host=%s", dsnValue(name))
+		if port != "" {
+			fmt.Fprintf(&b, " port=%s", dsnValue(port))
+		}
+	}
+	return b.String()
+}
+
+// dsnDefaultKeys 补充到完整连接字符串中的参数
+var dsnDefaultKeys = []string{"sslmode", "connect_timeout", "statement_timeout", "statement_cache_capacity", "default_query_exec_mode"}
+
+// isURLDSN 判断连接字符串是否为URL格式
+func isURLDSN(dsn string) bool {
+	return strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://")
+}
+
+// splitSocketHost 拆分Unix域套接字目录和可选的端口，如 /var/run/postgresql:6432
+//
+// 端口决定套接字文件名(.s.PGSQL.<端口>)，pgbouncer的套接字通常与数据库在同一目录、端口不同。
+func splitSocketHost(host string) (dir, port string) {
+	if i := strings.LastIndex(host, ":"); i > 0 {
+		if _, err := strconv.Atoi(host[i+1:]); err == nil {
+			return host[:i], host[i+1:]
+		}
+	}
+	return host, ""
+}
+
+// dsnValue 按libpq关键字格式引用参数值，值中的空格、引号和反斜杠不影响解析
+func dsnValue(v string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(v) + "'"
+}
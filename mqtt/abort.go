@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 失败预算命令行参数
+var (
+	abortConnectFailure = flag.Float64("abort-connect-failure", 0, "连接失败率(%)超过该值时终止测试，0为不检查")
+	abortPublishError   = flag.Float64("abort-publish-error", 0, "发布错误率(%)持续超过该值时终止测试，0为不检查")
+	abortWindow         = flag.Duration("abort-window", 0, "发布错误率需持续超标的时间")
+)
+
+// 提前终止状态
+var (
+	abortOnce   sync.Once
+	abortReason atomic.Value // 终止原因(string)，未终止时为nil
+)
+
+// abortTest 记录终止原因并取消所有设备，只有第一次调用生效
+func abortTest(cancel context.CancelFunc, reason string) {
+	abortOnce.Do(func() {
+		abortReason.Store(reason)
+		annotate("abort", fmt.Sprintf(T("超出失败预算，提前终止测试: %s"), reason))
+		cancel()
+	})
+}
+
+// abortedReason 返回提前终止的原因，未终止时返回空字符串
+func abortedReason() string {
+	if reason, ok := abortReason.Load().(string); ok {
+		return reason
+	}
+	return ""
+}
+
+// checkConnectBudget 连接等待结束后检查连接失败率
+func checkConnectBudget(cancel context.CancelFunc) {
+	limit := AppConfig.Abort.MaxConnectFailure
+	if limit <= 0 || AppConfig.Device.ClientNumber == 0 {
+		return
+	}
+	failed := runStats.ConnectFailed.Load()
+	rate := float64(failed) * 100 / float64(AppConfig.Device.ClientNumber)
+	if rate > limit {
+		abortTest(cancel, fmt.Sprintf(T("连接失败率 %.1f%% (%d/%d) 超过 %.1f%%"),
+			rate, failed, AppConfig.Device.ClientNumber, limit))
+	}
+}
+
+// watchPublishBudget 每秒计算发布错误率，持续超标达到窗口时间时终止测试
+func watchPublishBudget(ctx context.Context, cancel context.CancelFunc) {
+	limit := AppConfig.Abort.MaxPublishError
+	if limit <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	var lastOK, lastFailed uint64
+	var badSince time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			ok := publishLatency.Count()
+			failed := runStats.PublishFailed.Load()
+			deltaOK, deltaFailed := ok-lastOK, failed-lastFailed
+			lastOK, lastFailed = ok, failed
+
+			// 本秒没有发布(两个周期之间)时保持当前状态
+			if deltaOK+deltaFailed == 0 {
+				continue
+			}
+			rate := float64(deltaFailed) * 100 / float64(deltaOK+deltaFailed)
+			if rate <= limit {
+				badSince = time.Time{}
+				continue
+			}
+			if badSince.IsZero() {
+				badSince = now
+			}
+			if now.Sub(badSince) >= AppConfig.Abort.Window {
+				abortTest(cancel, fmt.Sprintf(T("发布错误率持续 %v 超过 %.1f%% (最近1秒 %.1f%%)"),
+					AppConfig.Abort.Window, limit, rate))
+				return
+			}
+		}
+	}
+}
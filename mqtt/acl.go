@@ -0,0 +1,245 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// acl子命令命令行参数
+var aclSamples = flag.Int("acl-samples", 0, "acl子命令: 抽样检查的设备数(默认3)")
+
+// ACLRule ACL矩阵中的一项检查
+type ACLRule struct {
+	Action string `yaml:"action"` // publish或subscribe
+	Topic  string `yaml:"topic"`  // 主题，{username}为本设备token，{other}为另一个抽样设备的token
+	Expect string `yaml:"expect"` // 期望结果(allow/deny)
+}
+
+// defaultACLRules 未配置acl.rules时检查的主题矩阵：本设备的遥测和命令主题应允许，
+// 其他设备的主题和通配符订阅应拒绝
+func defaultACLRules() []ACLRule {
+	return []ACLRule{
+		{Action: "publish", Topic: AppConfig.MQTT.Topic, Expect: "allow"},
+		{Action: "subscribe", Topic: "devices/command/{username}", Expect: "allow"},
+		{Action: "subscribe", Topic: "devices/command/{other}", Expect: "deny"},
+		{Action: "publish", Topic: "devices/command/{other}", Expect: "deny"},
+		{Action: "subscribe", Topic: "devices/#", Expect: "deny"},
+		{Action: "subscribe", Topic: "#", Expect: "deny"},
+	}
+}
+
+// aclResult 一项检查的结果
+type aclResult struct {
+	username string
+	rule     ACLRule
+	topic    string
+	actual   string // allow/deny/error
+	detail   string
+}
+
+// passed 实际结果是否符合期望
+func (r aclResult) passed() bool {
+	return r.actual == r.rule.Expect
+}
+
+// runACLCheck acl子命令：用抽样设备的凭据逐项尝试发布和订阅，输出ACL检查报告
+//
+// 订阅以SUBACK返回码判断。MQTT 3.1.1的Broker拒绝发布时通常静默丢弃或断开连接，
+// 因此配置了consumers账号时用它订阅同一主题确认消息是否真正投递；未配置时只能以
+// PUBACK和连接未被断开判断，结果标注为未确认投递。存在不符合期望的项时以状态码1退出。
+func runACLCheck() {
+	if err := initTLS(); err != nil {
+		log.Fatalf("%v", err)
+	}
+	tokens, err := loadTokens(AppConfig.Device.TokenFile, AppConfig.ACL.Samples, false)
+	if err != nil {
+		log.Fatalf(T("读取设备token文件失败: %v"), err)
+	}
+	if tokens.Len() == 0 {
+		log.Fatalf(T("token文件 %s 中没有设备"), AppConfig.Device.TokenFile)
+	}
+
+	observer := newACLObserver()
+	if observer != nil {
+		defer observer.Disconnect(200)
+	} else {
+		log.Println(T("警告: 未配置consumers账号，发布检查只能确认PUBACK和连接未被断开，无法确认消息是否被Broker丢弃"))
+	}
+
+	rules := AppConfig.ACL.Rules
+	var results []aclResult
+	for i := 0; i < tokens.Len(); i++ {
+		username := tokens.At(i)
+		other := ""
+		if tokens.Len() > 1 {
+			other = tokens.At((i + 1) % tokens.Len())
+		}
+		for _, rule := range rules {
+			if strings.Contains(rule.Topic, "{other}") && other == "" {
+				continue
+			}
+			topic := strings.NewReplacer("{username}", username, "{other}", other).Replace(rule.Topic)
+			r := aclResult{username: username, rule: rule, topic: topic}
+			if rule.Action == "subscribe" {
+				r.actual, r.detail = checkACLSubscribe(username, topic)
+			} else {
+				r.actual, r.detail = checkACLPublish(username, topic, observer)
+			}
+			results = append(results, r)
+		}
+	}
+
+	if !reportACL(results) {
+		os.Exit(1)
+	}
+}
+
+// newACLObserver 用consumers账号连接的观察者，用于确认发布是否被投递，未配置账号或连接失败时返回nil
+func newACLObserver() mqtt.Client {
+	if AppConfig.Consumers.Username == "" {
+		return nil
+	}
+	opts := mqtt.NewClientOptions().
+		SetClientID(fmt.Sprintf("tp-acl-observer-%d", time.Now().UnixNano())).
+		AddBroker(AppConfig.MQTT.Server).
+		SetUsername(AppConfig.Consumers.Username).
+		SetPassword(AppConfig.Consumers.Password).
+		SetCleanSession(true).
+		SetConnectTimeout(AppConfig.ACL.Timeout)
+	if tlsState.base != nil {
+		opts.SetTLSConfig(tlsState.base)
+	}
+	client := mqtt.NewClient(opts)
+	if token := client.Connect(); token.Wait() && token.Error() != nil {
+		log.Printf(T("警告: 观察者连接失败，发布检查无法确认投递: %v"), token.Error())
+		return nil
+	}
+	return client
+}
+
+// connectACLClient 用设备凭据建立一个新连接，每项检查单独连接，避免被拒绝的操作断开连接后影响后续检查
+func connectACLClient(username string, lost chan<- struct{}) (mqtt.Client, error) {
+	opts := mqtt.NewClientOptions().
+		SetClientID(username + "_acl").
+		AddBroker(AppConfig.MQTT.Server).
+		SetUsername(username).
+		SetCleanSession(true).
+		SetAutoReconnect(false).
+		SetConnectTimeout(AppConfig.ACL.Timeout).
+		SetConnectionLostHandler(func(mqtt.Client, error) {
+			if lost != nil {
+				close(lost)
+			}
+		})
+	if tlsConfig := deviceTLSConfig(username); tlsConfig != nil {
+		opts.SetTLSConfig(tlsConfig)
+	}
+	client := mqtt.NewClient(opts)
+	token := client.Connect()
+	if !token.WaitTimeout(AppConfig.ACL.Timeout) {
+		return nil, errors.New(T("等待CONNACK超时"))
+	}
+	return client, token.Error()
+}
+
+// checkACLSubscribe 以SUBACK返回码判断是否允许订阅
+func checkACLSubscribe(username, topic string) (actual, detail string) {
+	client, err := connectACLClient(username, nil)
+	if err != nil {
+		return "error", fmt.Sprintf(T("连接失败: %v"), err)
+	}
+	defer client.Disconnect(200)
+
+	token := client.Subscribe(topic, 1, nil)
+	if !token.WaitTimeout(AppConfig.ACL.Timeout) {
+		return "deny", T("等待SUBACK超时")
+	}
+	if err := token.Error(); err != nil {
+		return "deny", err.Error()
+	}
+	code, ok := token.(*mqtt.SubscribeToken).Result()[topic]
+	if !ok || code == subackFailure {
+		return "deny", fmt.Sprintf(T("SUBACK返回码 0x%02x"), code)
+	}
+	return "allow", fmt.Sprintf(T("SUBACK返回码 0x%02x"), code)
+}
+
+// checkACLPublish 以QoS1发布一条消息，根据观察者是否收到、PUBACK和连接是否被断开判断是否允许发布
+func checkACLPublish(username, topic string, observer mqtt.Client) (actual, detail string) {
+	payload := fmt.Sprintf(`{"acl_check":"%s-%d"}`, username, time.Now().UnixNano())
+	delivered := make(chan struct{}, 1)
+	if observer != nil {
+		token := observer.Subscribe(topic, 1, func(_ mqtt.Client, msg mqtt.Message) {
+			if string(msg.Payload()) == payload {
+				select {
+				case delivered <- struct{}{}:
+				default:
+				}
+			}
+		})
+		if token.Wait() && token.Error() != nil {
+			return "error", fmt.Sprintf(T("观察者订阅失败: %v"), token.Error())
+		}
+		defer observer.Unsubscribe(topic)
+	}
+
+	lost := make(chan struct{})
+	client, err := connectACLClient(username, lost)
+	if err != nil {
+		return "error", fmt.Sprintf(T("连接失败: %v"), err)
+	}
+	defer client.Disconnect(200)
+
+	token := client.Publish(topic, 1, false, payload)
+	acked := token.WaitTimeout(AppConfig.ACL.Timeout) && token.Error() == nil
+
+	if observer != nil {
+		select {
+		case <-delivered:
+			return "allow", T("观察者收到消息")
+		case <-lost:
+			return "deny", T("Broker断开了连接")
+		case <-time.After(AppConfig.ACL.Timeout):
+			return "deny", T("观察者未收到消息")
+		}
+	}
+
+	// 没有观察者时等待一段时间，确认Broker没有因拒绝发布而断开连接
+	select {
+	case <-lost:
+		return "deny", T("Broker断开了连接")
+	case <-time.After(AppConfig.ACL.Timeout):
+	}
+	if !acked {
+		return "deny", T("未收到PUBACK")
+	}
+	return "allow", T("收到PUBACK(未确认投递)")
+}
+
+// reportACL 输出ACL检查报告，全部符合期望时返回true
+func reportACL(results []aclResult) bool {
+	log.Println(T("\n========== ACL检查 =========="))
+	failed := 0
+	for _, r := range results {
+		status := T("通过")
+		if !r.passed() {
+			status = T("失败")
+			failed++
+		}
+		log.Printf(T(" [%s] %s %s %s: 期望=%s, 实际=%s (%s)"),
+			status, r.username, r.rule.Action, r.topic, r.rule.Expect, r.actual, r.detail)
+	}
+	log.Printf(T("共 %d 项检查, 通过 %d, 失败 %d"), len(results), len(results)-failed, failed)
+	log.Println("===============================")
+	if failed > 0 {
+		log.Println(T("存在不符合期望的ACL检查项，请检查Broker的ACL插件配置"))
+	}
+	return failed == 0
+}
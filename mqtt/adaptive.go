@@ -0,0 +1,110 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 自适应速率命令行参数
+var adaptiveRate = flag.Bool("adaptive", false, "自适应速率：入库持续落后于发送时降低发送速率，恢复后再逐步提高，寻找可持续吞吐量")
+
+// sendInterval 当前的发送间隔(纳秒)，自适应模式下由控制器调整
+var sendInterval atomic.Int64
+
+// currentSendInterval 返回当前的发送间隔
+func currentSendInterval() time.Duration {
+	return time.Duration(sendInterval.Load())
+}
+
+// RateController 根据监控模块的入库速率调整发送间隔的闭环控制器
+//
+// 每个监控间隔比较入库速率与发送速率：连续patience个间隔入库率低于阈值时，
+// 发送速率降低step；连续patience个间隔不落后时提高step，但不超过配置的速率。
+// 不落后的连续间隔中出现过的最高发送速率即为可持续吞吐量。
+type RateController struct {
+	mu          sync.Mutex
+	lagging     int     // 连续落后的间隔数
+	healthy     int     // 连续不落后的间隔数
+	streakPeak  float64 // 当前不落后区间内的最高发送速率(点/秒)
+	sustainable float64 // 可持续吞吐量(点/秒)
+	decreases   int     // 降速次数
+	increases   int     // 提速次数
+}
+
+// rateController 全局自适应速率控制器
+var rateController RateController
+
+// Observe 记录一个监控间隔的发送和入库速率(点/秒)，必要时调整发送间隔
+func (c *RateController) Observe(sentRate, dbRate float64) {
+	if !AppConfig.Adaptive.Enabled || sentRate <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cfg := AppConfig.Adaptive
+	if dbRate*100 < sentRate*cfg.MinWriteRatio {
+		c.lagging++
+		c.healthy = 0
+		c.streakPeak = 0
+	} else {
+		c.healthy++
+		c.lagging = 0
+		c.streakPeak = max(c.streakPeak, sentRate)
+		if c.healthy >= cfg.Patience {
+			c.sustainable = max(c.sustainable, c.streakPeak)
+		}
+	}
+
+	interval := currentSendInterval()
+	switch {
+	case c.lagging >= cfg.Patience:
+		// 降低速率即按比例拉长发送间隔
+		next := time.Duration(float64(interval) / (1 - cfg.Step/100))
+		sendInterval.Store(int64(next))
+		c.lagging = 0
+		c.decreases++
+		annotate("adaptive", fmt.Sprintf(T("自适应速率: 入库率低于 %.0f%% 已持续 %d 个监控间隔，发送间隔 %v → %v"),
+			cfg.MinWriteRatio, cfg.Patience, interval.Round(time.Millisecond), next.Round(time.Millisecond)))
+	case c.healthy >= cfg.Patience && interval > AppConfig.Test.DataInterval:
+		next := max(time.Duration(float64(interval)*(1-cfg.Step/100)), AppConfig.Test.DataInterval)
+		sendInterval.Store(int64(next))
+		c.healthy = 0
+		c.increases++
+		annotate("adaptive", fmt.Sprintf(T("自适应速率: 入库已跟上 %d 个监控间隔，发送间隔 %v → %v"),
+			cfg.Patience, interval.Round(time.Millisecond), next.Round(time.Millisecond)))
+	}
+}
+
+// Sustainable 返回可持续吞吐量(点/秒)，尚未确定时返回0
+func (c *RateController) Sustainable() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.sustainable
+}
+
+// Report 输出自适应速率的调整结果和可持续吞吐量
+func (c *RateController) Report() {
+	if !AppConfig.Adaptive.Enabled {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	interval := currentSendInterval()
+	log.Println(T("\n========== 自适应速率 =========="))
+	log.Printf(T("降速 %d 次, 提速 %d 次, 最终发送间隔: %v (配置: %v)"),
+		c.decreases, c.increases, interval.Round(time.Millisecond), AppConfig.Test.DataInterval)
+	if c.sustainable > 0 {
+		log.Printf(T("可持续吞吐量: %.1f 点/秒 (约 %.1f 消息/秒)"),
+			c.sustainable, c.sustainable/float64(max(AppConfig.Data.DataPointCount, 1)))
+	} else {
+		log.Printf(T("未找到可持续吞吐量: 入库率从未连续 %d 个监控间隔达到 %.0f%%，可延长测试时间或提高data_interval"),
+			AppConfig.Adaptive.Patience, AppConfig.Adaptive.MinWriteRatio)
+	}
+	log.Println("===============================")
+}
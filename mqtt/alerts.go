@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 告警命令行参数
+var (
+	alertWebhook = flag.String("alert-webhook", "", "告警Webhook地址，监控指标触发告警规则时推送通知")
+	alertFormat  = flag.String("alert-format", "", "告警消息格式(webhook: 通用JSON, dingtalk: 钉钉, feishu: 飞书, slack: Slack)")
+)
+
+// 告警规则名称
+const (
+	alertWriteRatio = "write_ratio" // 写入率持续低于阈值
+	alertDBStalled  = "db_stalled"  // 有发送但入库速率为0
+	alertErrorRate  = "error_rate"  // 发布错误率超过阈值
+)
+
+// alertRule 单条告警规则的状态
+type alertRule struct {
+	streak   int       // 连续满足条件的监控间隔数
+	firing   bool      // 是否处于告警状态
+	lastSent time.Time // 最近一次推送告警的时间
+}
+
+// AlertMonitor 根据每个监控间隔的指标判断告警规则，触发和恢复时推送Webhook
+//
+// 无人值守的长稳测试中入库链路卡死时及时通知，避免整个周末的测试白跑。告警持续期间
+// 每隔cooldown重复推送一次，条件消失时推送一次恢复通知。
+type AlertMonitor struct {
+	mu         sync.Mutex
+	runID      string
+	rules      map[string]*alertRule
+	lastSent   uint64 // 上一间隔结束时各数据流累计发送成功的消息数
+	lastFailed uint64 // 上一间隔结束时累计发布失败的消息数
+	fired      atomic.Uint64
+	delivered  atomic.Uint64
+	failed     atomic.Uint64
+}
+
+// alertMonitor 全局告警监控
+var alertMonitor = AlertMonitor{rules: make(map[string]*alertRule)}
+
+// alertsEnabled 是否配置了告警推送
+func alertsEnabled() bool {
+	return AppConfig.Alerts.Webhook != ""
+}
+
+// publishTotals 快照中所有数据流累计发送成功和发布失败的消息数
+func publishTotals(snap StatsSnapshot) (sent, failed uint64) {
+	for _, c := range snap.ByLabel {
+		sent += c.Sent
+	}
+	return sent, snap.PublishFailed
+}
+
+// Observe 记录一个监控间隔的指标，sentDiff为本间隔新增发送的数据点数，successRate为本间隔写入率(%)
+func (m *AlertMonitor) Observe(snap StatsSnapshot, sentDiff uint64, dbRate, successRate float64) {
+	if !alertsEnabled() {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cfg := AppConfig.Alerts
+	sent, failed := publishTotals(snap)
+	sentMsgs, _ := counterDelta(sent, m.lastSent)
+	failedMsgs, _ := counterDelta(failed, m.lastFailed)
+	m.lastSent, m.lastFailed = sent, failed
+
+	if cfg.MinWriteRatio > 0 {
+		bad := sentDiff > 0 && successRate < cfg.MinWriteRatio
+		m.evaluate(alertWriteRatio, bad, cfg.Patience, fmt.Sprintf(T("写入率 %.1f%% 连续 %d 个监控间隔低于 %.0f%%"),
+			successRate, cfg.Patience, cfg.MinWriteRatio))
+	}
+	if cfg.DBStallIntervals > 0 {
+		bad := sentDiff > 0 && dbRate <= 0
+		m.evaluate(alertDBStalled, bad, cfg.DBStallIntervals, fmt.Sprintf(T("持续发送但入库速率为0已有 %d 个监控间隔，入库链路可能已停止"),
+			cfg.DBStallIntervals))
+	}
+	if cfg.MaxErrorRate > 0 {
+		errorRate := 0.0
+		if total := sentMsgs + failedMsgs; total > 0 {
+			errorRate = float64(failedMsgs) / float64(total) * 100
+		}
+		m.evaluate(alertErrorRate, errorRate > cfg.MaxErrorRate, 1, fmt.Sprintf(T("本间隔发布错误率 %.1f%% 超过 %.1f%% (失败 %d 条)"),
+			errorRate, cfg.MaxErrorRate, failedMsgs))
+	}
+}
+
+// evaluate 更新一条规则的状态，连续patience个间隔满足条件时触发，调用方持有锁
+func (m *AlertMonitor) evaluate(name string, bad bool, patience int, text string) {
+	rule := m.rules[name]
+	if rule == nil {
+		rule = &alertRule{}
+		m.rules[name] = rule
+	}
+	if !bad {
+		rule.streak = 0
+		if rule.firing {
+			rule.firing = false
+			m.notify(name, T("已恢复"), fmt.Sprintf(T("告警规则 %s 的条件已消失"), name))
+		}
+		return
+	}
+	rule.streak++
+	if rule.streak < patience {
+		return
+	}
+	if rule.firing && time.Since(rule.lastSent) < AppConfig.Alerts.Cooldown {
+		return
+	}
+	rule.firing = true
+	rule.lastSent = time.Now()
+	m.fired.Add(1)
+	m.notify(name, T("告警"), text)
+}
+
+// notify 记录标注并异步推送通知，推送失败只记录日志，不影响测试
+func (m *AlertMonitor) notify(name, status, text string) {
+	annotate("alert", fmt.Sprintf("[%s] %s: %s", status, name, text))
+	host, _ := os.Hostname()
+	content := fmt.Sprintf(T("[MQTT性能测试%s] %s\n运行ID: %s, 主机: %s, 时间: %s\n%s"),
+		status, name, m.runID, host, time.Now().Format("2006-01-02 15:04:05"), text)
+	body, err := json.Marshal(alertPayload(name, status, content))
+	if err != nil {
+		log.Printf(T("告警消息编码失败: %v"), err)
+		return
+	}
+	go func() {
+		if err := postAlert(body); err != nil {
+			m.failed.Add(1)
+			log.Printf(T("告警推送失败: %v"), err)
+			return
+		}
+		m.delivered.Add(1)
+	}()
+}
+
+// alertPayload 按配置的格式构造推送的消息体
+func alertPayload(name, status, content string) any {
+	switch AppConfig.Alerts.Format {
+	case "dingtalk":
+		return map[string]any{"msgtype": "text", "text": map[string]string{"content": content}}
+	case "feishu":
+		return map[string]any{"msg_type": "text", "content": map[string]string{"text": content}}
+	case "slack":
+		return map[string]string{"text": content}
+	default:
+		return map[string]string{"rule": name, "status": status, "run_id": alertMonitor.runID, "text": content}
+	}
+}
+
+// postAlert 推送一条消息，非2xx响应视为失败
+func postAlert(body []byte) error {
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(AppConfig.Alerts.Webhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf(T("Webhook返回状态码 %d"), resp.StatusCode)
+	}
+	return nil
+}
+
+// reportAlerts 输出告警推送统计(未配置告警时不输出)
+func reportAlerts() {
+	if !alertsEnabled() {
+		return
+	}
+	m := &alertMonitor
+	log.Println(T("\n========== 告警 =========="))
+	log.Printf(T("告警格式: %s, 触发 %d 次, 推送成功 %d, 推送失败 %d"),
+		AppConfig.Alerts.Format, m.fired.Load(), m.delivered.Load(), m.failed.Load())
+	m.mu.Lock()
+	for name, rule := range m.rules {
+		if rule.firing {
+			log.Printf(T("测试结束时仍在告警: %s"), name)
+		}
+	}
+	m.mu.Unlock()
+	log.Println("===============================")
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 标注命令行参数
+var (
+	annotateStdin = flag.Bool("annotate-stdin", false, "测试期间从标准输入读取标注，每行一条(如: 数据库重启)")
+	controlAddr   = flag.String("control-addr", "", "控制接口监听地址(如 127.0.0.1:9091)，POST /annotations 添加标注，GET 查看全部标注")
+)
+
+// Annotation 时间线上的一条标注
+type Annotation struct {
+	Time   time.Time `json:"time"`
+	Source string    `json:"source"` // 来源: operator(键盘)、api(控制接口)、stage(阶段切换)、abort、adaptive等
+	Text   string    `json:"text"`
+}
+
+// annotations 按时间顺序记录的标注
+var annotations struct {
+	mu   sync.Mutex
+	list []Annotation
+}
+
+// annotate 在时间线上添加一条标注，同时写入日志
+//
+// 运维人员通过键盘或控制接口添加，自动化流程(阶段切换、提前终止、速率调整、故障注入等)直接调用。
+func annotate(source, text string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+	a := Annotation{Time: time.Now(), Source: source, Text: text}
+	annotations.mu.Lock()
+	annotations.list = append(annotations.list, a)
+	annotations.mu.Unlock()
+	log.Printf(T("标注[%s]: %s"), source, text)
+}
+
+// annotationsSince 返回指定时间之后(含)的标注
+func annotationsSince(since time.Time) []Annotation {
+	annotations.mu.Lock()
+	defer annotations.mu.Unlock()
+	var list []Annotation
+	for _, a := range annotations.list {
+		if !a.Time.Before(since) {
+			list = append(list, a)
+		}
+	}
+	return list
+}
+
+// stdinLines 标准输入的逐行读取通道，整个进程只有一个读取者，EOF时关闭
+var stdinLines = sync.OnceValue(func() <-chan string {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	return lines
+})
+
+// readStdinAnnotations 测试期间把标准输入的每一行记为运维人员的标注
+func readStdinAnnotations(ctx context.Context) {
+	log.Println(T("可在终端输入标注并按 Enter 记录到时间线(如: 数据库重启)"))
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case line, ok := <-stdinLines():
+			if !ok {
+				return
+			}
+			annotate("operator", line)
+		}
+	}
+}
+
+// startControlServer 启动控制接口，未配置地址时不启动(守护模式下默认启动)
+func startControlServer() {
+	addr := controlAddress()
+	if addr == "" {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/annotations", handleAnnotations)
+	mux.HandleFunc("/status", handleStatus)
+	mux.HandleFunc("/healthz", handleHealthz)
+	mux.HandleFunc("/readyz", handleReadyz)
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Printf(T("控制接口启动失败: %v"), err)
+		}
+	}()
+	log.Printf(T("控制接口: http://%s/annotations, http://%s/status, http://%s/healthz, http://%s/readyz"), addr, addr, addr, addr)
+}
+
+// handleAnnotations POST添加标注(请求体为文本或 {"text": "...", "source": "..."})，GET返回全部标注
+func handleAnnotations(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(annotationsSince(time.Time{}))
+	case http.MethodPost:
+		body, err := io.ReadAll(io.LimitReader(r.Body, 4096))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		req := struct {
+			Text   string `json:"text"`
+			Source string `json:"source"`
+		}{Source: "api"}
+		if json.Unmarshal(body, &req) != nil {
+			req.Text = string(body)
+		}
+		if req.Source == "" {
+			req.Source = "api"
+		}
+		if strings.TrimSpace(req.Text) == "" {
+			http.Error(w, T("标注内容为空"), http.StatusBadRequest)
+			return
+		}
+		annotate(req.Source, req.Text)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+// reportAnnotations 按时间顺序输出全部标注及其相对测试开始的时间
+func reportAnnotations(start time.Time) {
+	list := annotationsSince(time.Time{})
+	if len(list) == 0 {
+		return
+	}
+	log.Println(T("\n========== 事件时间线 =========="))
+	for _, a := range list {
+		offset, sign := a.Time.Sub(start).Round(time.Second), "+"
+		if offset < 0 {
+			offset, sign = -offset, "-"
+		}
+		log.Printf("%s (T%s%v) [%s] %s", a.Time.Format("15:04:05"), sign, offset, a.Source, a.Text)
+	}
+	log.Println("===============================")
+}
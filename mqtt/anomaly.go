@@ -0,0 +1,316 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// 异常注入命令行参数
+var (
+	anomalyRatio  = flag.Float64("anomaly-ratio", 0, "异常注入：在指定循环使该比例(%)设备的数据点突然跳变，并查询平台告警表，测量负载下的告警检测延迟，0为不注入")
+	anomalyCycles = flag.String("anomaly-cycles", "", "注入异常的循环，逗号分隔(默认为总循环数的一半)")
+)
+
+// defaultAlarmQuery 查询告警产生时间的默认SQL，对应ThingsPanel的告警历史表
+const defaultAlarmQuery = "SELECT create_at FROM alarm_history WHERE create_at >= $1 ORDER BY create_at"
+
+// anomalyEvent 一次计划的异常注入
+type anomalyEvent struct {
+	cycle    uint64
+	injected atomic.Uint64 // 发送成功的异常消息数(设备数)
+	at       atomic.Int64  // 最早一条异常消息发送完成的时间(UnixNano)，0为尚未发送
+	skipped  atomic.Uint64 // 消息中找不到可注入的数值而跳过的设备数
+
+	alarms   int           // 本次注入后(到下一次注入前)产生的告警数
+	detected time.Duration // 从注入到第一条告警的时长，没有告警时为0
+}
+
+// anomalyEvents 按循环索引的注入计划，初始化后只读
+var anomalyEvents map[uint64]*anomalyEvent
+
+// anomalyResult 告警检测结果，供JSON汇总使用
+var anomalyResult struct {
+	events   int       // 实际进行的注入次数
+	detected int       // 检测到告警的注入次数
+	latency  Histogram // 各条告警相对所属注入的检测延迟
+}
+
+// anomalyEnabled 是否进行异常注入
+func anomalyEnabled() bool {
+	return AppConfig.Anomaly.Ratio > 0
+}
+
+// parseAnomalyCycles 解析逗号分隔的注入循环
+func parseAnomalyCycles(s string) ([]int, error) {
+	var cycles []int
+	for _, part := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf(T("无效的异常注入循环: %q"), part)
+		}
+		cycles = append(cycles, n)
+	}
+	return cycles, nil
+}
+
+// initAnomalyEvents 按配置的循环建立注入计划
+func initAnomalyEvents() {
+	anomalyEvents = make(map[uint64]*anomalyEvent)
+	for _, cycle := range AppConfig.Anomaly.Cycles {
+		anomalyEvents[uint64(cycle)] = &anomalyEvent{cycle: uint64(cycle)}
+	}
+}
+
+// sortedAnomalyEvents 按循环排列的注入计划
+func sortedAnomalyEvents() []*anomalyEvent {
+	events := make([]*anomalyEvent, 0, len(anomalyEvents))
+	for _, e := range anomalyEvents {
+		events = append(events, e)
+	}
+	slices.SortFunc(events, func(a, b *anomalyEvent) int { return cmp.Compare(a.cycle, b.cycle) })
+	return events
+}
+
+// anomalyDevice 参与异常注入的设备
+type anomalyDevice struct {
+	field []byte // 注入的数据点的 "key": 前缀，为nil时使用消息中第一个数值数据点
+	event *anomalyEvent
+	until uint64 // 异常值持续到的循环(含)
+	buf   []byte
+}
+
+// newAnomalyDevice 按设备token的哈希选择参与异常注入的设备，未选中或未启用时返回nil
+func newAnomalyDevice(username string) *anomalyDevice {
+	if !anomalyEnabled() || !hashSelected(username, AppConfig.Anomaly.Ratio) {
+		return nil
+	}
+	d := &anomalyDevice{}
+	if key := AppConfig.Anomaly.Key; key != "" {
+		quoted, _ := json.Marshal(key)
+		d.field = append(quoted, ':')
+	}
+	return d
+}
+
+// apply 在注入循环及其后hold-1个循环中使数据点跳变，返回新消息
+func (d *anomalyDevice) apply(payload []byte, cycle uint64) []byte {
+	if d == nil {
+		return payload
+	}
+	if e, ok := anomalyEvents[cycle]; ok {
+		d.event, d.until = e, cycle+uint64(AppConfig.Anomaly.Hold)-1
+	} else if cycle > d.until {
+		d.event = nil
+	}
+	if d.event == nil {
+		return payload
+	}
+
+	start, end := d.numberAt(payload)
+	if start < 0 {
+		if d.event.cycle == cycle {
+			d.event.skipped.Add(1)
+		}
+		return payload
+	}
+	v, err := strconv.ParseFloat(string(payload[start:end]), 64)
+	if err != nil {
+		return payload
+	}
+	d.buf = append(d.buf[:0], payload[:start]...)
+	d.buf, _ = appendJSONFloat(d.buf, v+AppConfig.Anomaly.Jump)
+	d.buf = append(d.buf, payload[end:]...)
+	return d.buf
+}
+
+// numberAt 定位注入的数据点的数值，找不到数值时返回-1
+func (d *anomalyDevice) numberAt(payload []byte) (int, int) {
+	start := -1
+	if d.field != nil {
+		if i := bytes.Index(payload, d.field); i >= 0 {
+			start = i + len(d.field)
+		}
+	} else {
+		for i := 0; i+1 < len(payload); i++ {
+			if payload[i] == '"' && payload[i+1] == ':' && i+2 < len(payload) && isNumberStart(payload[i+2]) {
+				start = i + 2
+				break
+			}
+		}
+	}
+	if start < 0 || start >= len(payload) || !isNumberStart(payload[start]) {
+		return -1, -1
+	}
+	end := start
+	for end < len(payload) && strings.IndexByte("+-.0123456789eE", payload[end]) >= 0 {
+		end++
+	}
+	return start, end
+}
+
+// isNumberStart 是否为JSON数值的第一个字符
+func isNumberStart(c byte) bool {
+	return c == '-' || c >= '0' && c <= '9'
+}
+
+// record 记录注入循环的异常消息发送结果，以最早发送完成的时间作为本次注入的时间
+func (d *anomalyDevice) record(cycle uint64, done time.Time, err error) {
+	if d == nil || d.event == nil || d.event.cycle != cycle || err != nil {
+		return
+	}
+	d.event.injected.Add(1)
+	at := done.UnixNano()
+	for {
+		cur := d.event.at.Load()
+		if cur != 0 && cur <= at || d.event.at.CompareAndSwap(cur, at) {
+			return
+		}
+	}
+}
+
+// firstAnomalyAt 第一次注入的时间，尚未注入时返回零值
+func firstAnomalyAt() time.Time {
+	var first int64
+	for _, e := range anomalyEvents {
+		if at := e.at.Load(); at != 0 && (first == 0 || at < first) {
+			first = at
+		}
+	}
+	if first == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, first)
+}
+
+// alarmTimes 查询since之后产生的告警时间
+func alarmTimes(ctx context.Context, db *sql.DB, since time.Time) ([]time.Time, error) {
+	rows, err := db.QueryContext(ctx, AppConfig.Anomaly.AlarmQuery, since.Add(-dbClockSkew))
+	if err != nil {
+		return nil, fmt.Errorf(T("查询告警失败: %w"), err)
+	}
+	defer rows.Close()
+	var times []time.Time
+	for rows.Next() {
+		var t time.Time
+		if err := rows.Scan(&t); err != nil {
+			return nil, fmt.Errorf(T("查询告警失败: %w"), err)
+		}
+		times = append(times, t)
+	}
+	return times, rows.Err()
+}
+
+// logAnomaly 监控间隔输出已注入的异常和已产生的告警数(未启用或尚未注入时不输出)
+func logAnomaly(db *sql.DB) {
+	first := firstAnomalyAt()
+	if !anomalyEnabled() || first.IsZero() {
+		return
+	}
+	var injected uint64
+	for _, e := range anomalyEvents {
+		injected += e.injected.Load()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), AppConfig.Monitor.QueryTimeout)
+	defer cancel()
+	var alarms int64
+	err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM ("+AppConfig.Anomaly.AlarmQuery+") q", first.Add(-dbClockSkew)).Scan(&alarms)
+	if err != nil {
+		log.Printf(T("  - 异常注入: %v"), err)
+		return
+	}
+	log.Printf(T("  - 异常注入: 已注入 %d 个设备, 已产生告警 %d 条"), injected, alarms)
+}
+
+// reportAnomaly 等待告警产生后，按注入次数统计告警数和检测延迟(未启用时不输出)
+//
+// 告警按时间归属到它之前最近的一次注入，检测延迟为告警时间减去该次注入中最早一条
+// 异常消息发送完成的时间，包含平台的消息处理、规则计算和告警写入。告警数少于注入的
+// 设备数说明部分异常没有被检测到(或告警规则对同一设备做了合并)。
+func reportAnomaly() {
+	if !anomalyEnabled() {
+		return
+	}
+	log.Println(T("\n========== 异常注入与告警检测 =========="))
+	defer log.Println("===============================")
+	events := slices.DeleteFunc(sortedAnomalyEvents(), func(e *anomalyEvent) bool { return e.at.Load() == 0 })
+	if len(events) == 0 {
+		log.Println(T("测试未进行到注入循环，没有注入异常"))
+		return
+	}
+	anomalyResult.events = len(events)
+
+	// 最后一次注入后等待告警产生
+	last := time.Unix(0, events[len(events)-1].at.Load())
+	if wait := time.Until(last.Add(AppConfig.Anomaly.Timeout)); wait > 0 {
+		log.Printf(T("等待 %v 让平台产生告警..."), wait.Round(time.Second))
+		time.Sleep(wait)
+	}
+
+	db, err := openReadDatabase()
+	if err != nil {
+		log.Printf(T("异常注入: %v"), err)
+		return
+	}
+	defer db.Close()
+	waitReplicaReplay(db)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	first := time.Unix(0, events[0].at.Load())
+	times, err := alarmTimes(ctx, db, first)
+	if err != nil {
+		log.Printf(T("异常注入: %v"), err)
+		return
+	}
+
+	for i, e := range events {
+		at := time.Unix(0, e.at.Load())
+		next := time.Time{}
+		if i+1 < len(events) {
+			next = time.Unix(0, events[i+1].at.Load())
+		}
+		// 容忍平台与压测机的时钟偏差，早于第一次注入不超过dbClockSkew的告警也归属第一次注入
+		lower := at
+		if i == 0 {
+			lower = at.Add(-dbClockSkew)
+		}
+		for _, t := range times {
+			if t.Before(lower) || !next.IsZero() && !t.Before(next) {
+				continue
+			}
+			if e.alarms == 0 {
+				e.detected = max(t.Sub(at), 0)
+				anomalyResult.detected++
+			}
+			e.alarms++
+			anomalyResult.latency.Record(t.Sub(at))
+		}
+
+		line := fmt.Sprintf(T("第 %d 个循环: 注入 %d 个设备, 告警 %d 条"), e.cycle, e.injected.Load(), e.alarms)
+		if e.alarms > 0 {
+			line += fmt.Sprintf(T(", 首条告警延迟 %v"), e.detected.Round(time.Millisecond))
+		} else {
+			line += T(", 未检测到告警")
+		}
+		if n := e.skipped.Load(); n > 0 {
+			line += fmt.Sprintf(T(", %d 个设备的消息中没有可注入的数值"), n)
+		}
+		log.Println(line)
+	}
+	if h := &anomalyResult.latency; h.Count() > 0 {
+		log.Printf(T("告警检测延迟(负载下): p50=%v, p99=%v, 最大=%v"),
+			h.Quantile(0.50).Round(time.Millisecond), h.Quantile(0.99).Round(time.Millisecond), h.Max().Round(time.Millisecond))
+	}
+	log.Printf(T("检测到告警的注入: %d/%d"), anomalyResult.detected, len(events))
+}
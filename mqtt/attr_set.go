@@ -0,0 +1,351 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 属性下发测试命令行参数
+var (
+	attrSetRate    = flag.Float64("attr-set-rate", 0, "属性下发测试：每秒通过平台API下发的属性设置请求数，设备收到后通过MQTT回复，0为不启用")
+	attrSetDevices = flag.Int("attr-set-devices", 0, "属性下发测试：接收属性设置的设备数(token文件中前N个使用MQTT的设备，默认10)")
+)
+
+// 属性下发测试的默认配置，对应ThingsPanel的属性下发接口和设备协议
+const (
+	defaultAttrSetPath          = "/api/v1/attribute/datas/pub"
+	defaultAttrSetBody          = `{"device_id":"{device_id}","value":{value}}`
+	defaultAttrSetKey           = "perf_seq"
+	defaultAttrSetTopic         = "devices/attributes/set/{device_id}/+"
+	defaultAttrSetResponseTopic = "devices/attributes/set/response/{message_id}"
+)
+
+// attrSetRequest 一次属性设置请求
+type attrSetRequest struct {
+	sent      time.Time
+	delivered bool
+}
+
+// attrSet 属性下发测试的状态和统计
+var attrSet struct {
+	targets map[string]string // 接收属性设置的设备token -> 设备ID
+	devices []string          // 按顺序轮流下发的设备ID
+
+	seq     atomic.Uint64
+	mu      sync.Mutex
+	pending map[uint64]*attrSetRequest // 请求序号 -> 请求，测试结束时仍未收到的计为丢失
+	status  map[int]uint64             // API响应状态码 -> 次数，0为请求失败
+	lastAt  time.Time                  // 最后一次请求的时间
+	calls   sync.WaitGroup             // 进行中的API请求
+
+	sent       atomic.Uint64 // 发出的请求数
+	apiFailed  atomic.Uint64 // API返回失败的请求数
+	delivered  atomic.Uint64 // 设备收到的请求数
+	duplicates atomic.Uint64 // 设备重复收到的次数
+	unknown    atomic.Uint64 // 设备收到的无法关联到请求的属性设置
+	acked      atomic.Uint64 // 设备回复成功的次数
+	ackFailed  atomic.Uint64 // 设备回复失败的次数
+
+	apiLatency     Histogram // API请求耗时
+	deliverLatency Histogram // 发出请求到设备收到的耗时
+	ackLatency     Histogram // 发出请求到设备回复完成的耗时
+}
+
+// attrSetEnabled 是否进行属性下发测试
+func attrSetEnabled() bool {
+	return AppConfig.AttrSet.Rate > 0
+}
+
+// initAttrSet 选择接收属性设置的设备并查询其设备ID
+func initAttrSet(tokens *TokenList) error {
+	if !attrSetEnabled() {
+		return nil
+	}
+	targets, devices, missing, err := downlinkDevices(tokens, AppConfig.AttrSet.Devices)
+	if err != nil {
+		return fmt.Errorf(T("属性下发测试: %w"), err)
+	}
+	if missing > 0 {
+		log.Printf(T("警告: 属性下发测试: %d 个token在数据库中找不到设备，不参与测试"), missing)
+	}
+	attrSet.targets, attrSet.devices = targets, devices
+	attrSet.pending = make(map[uint64]*attrSetRequest)
+	attrSet.status = make(map[int]uint64)
+	log.Printf(T("属性下发测试: %d 个设备, 每秒 %.1f 个请求"), len(attrSet.devices), AppConfig.AttrSet.Rate)
+	return nil
+}
+
+// downlinkDevices 选择token文件中前n个使用MQTT的设备，返回token -> 设备ID、按顺序排列的设备ID，
+// 以及在数据库中找不到设备的token数
+//
+// HTTP设备没有下行通道，不参与平台下发的测试。
+func downlinkDevices(tokens *TokenList, n int) (map[string]string, []string, int, error) {
+	var usernames []string
+	for i := 0; i < min(tokens.Len(), AppConfig.Device.ClientNumber) && len(usernames) < n; i++ {
+		if username := tokens.At(i); !httpDevice(username) {
+			usernames = append(usernames, username)
+		}
+	}
+	if len(usernames) == 0 {
+		return nil, nil, 0, errors.New(T("没有使用MQTT的设备"))
+	}
+
+	db, err := openReadDatabase()
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	defer db.Close()
+	ids, err := deviceIDsByToken(db, usernames)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	targets := make(map[string]string, len(usernames))
+	var devices []string
+	for _, username := range usernames {
+		if id, ok := ids[username]; ok {
+			targets[username] = id
+			devices = append(devices, id)
+		}
+	}
+	if len(devices) == 0 {
+		return nil, nil, 0, fmt.Errorf(T("%d 个设备的token在数据库中都找不到设备"), len(usernames))
+	}
+	return targets, devices, len(usernames) - len(devices), nil
+}
+
+// deviceIDsByToken 批量查询token对应的设备ID，找不到的token不在结果中
+func deviceIDsByToken(db *sql.DB, usernames []string) (map[string]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	rows, err := db.QueryContext(ctx, `SELECT voucher::json->>'username', id FROM devices
+		WHERE voucher::json->>'username' = ANY($1)`, usernames)
+	if err != nil {
+		return nil, fmt.Errorf(T("查询设备ID失败: %w"), err)
+	}
+	defer rows.Close()
+	ids := make(map[string]string, len(usernames))
+	for rows.Next() {
+		var username, id string
+		if err := rows.Scan(&username, &id); err != nil {
+			return nil, fmt.Errorf(T("查询设备ID失败: %w"), err)
+		}
+		ids[username] = id
+	}
+	return ids, rows.Err()
+}
+
+// subscribeAttributeSet 参与测试的设备订阅属性设置主题
+func subscribeAttributeSet(client DeviceClient, username string) error {
+	id, ok := attrSet.targets[username]
+	if !ok {
+		return nil
+	}
+	topic := strings.NewReplacer("{username}", username, "{device_id}", id).Replace(AppConfig.AttrSet.Topic)
+	code, err := client.Subscribe(topic, byte(AppConfig.MQTT.QoS))
+	if err != nil {
+		return fmt.Errorf(T("订阅属性设置主题 %s 失败: %w"), topic, err)
+	}
+	if code == subackFailure {
+		return fmt.Errorf(T("服务器拒绝订阅属性设置主题 %s (SUBACK返回码 0x80)"), topic)
+	}
+	return nil
+}
+
+// startAttrSet 开始发送数据时启动属性设置请求，按配置的速率轮流下发给各设备，直到ctx结束
+func startAttrSet(ctx context.Context) {
+	if !attrSetEnabled() {
+		return
+	}
+	interval := time.Duration(float64(time.Second) / AppConfig.AttrSet.Rate)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				seq := attrSet.seq.Add(1)
+				device := attrSet.devices[int(seq-1)%len(attrSet.devices)]
+				attrSet.calls.Add(1)
+				go func() {
+					defer attrSet.calls.Done()
+					sendAttributeSet(seq, device)
+				}()
+			}
+		}
+	}()
+}
+
+// sendAttributeSet 通过平台API下发一次属性设置，属性值为请求序号，用于关联设备收到的消息
+func sendAttributeSet(seq uint64, device string) {
+	cfg := AppConfig.AttrSet
+	value, _ := json.Marshal(fmt.Sprintf(`{"%s":%d}`, cfg.Key, seq))
+	body := strings.NewReplacer("{device_id}", device, "{value}", string(value)).Replace(cfg.Body)
+
+	start := time.Now()
+	attrSet.mu.Lock()
+	attrSet.pending[seq] = &attrSetRequest{sent: start}
+	attrSet.lastAt = start
+	attrSet.mu.Unlock()
+	attrSet.sent.Add(1)
+
+	status, _, err := apiCall(context.Background(), "POST", cfg.Path, []byte(body))
+	attrSet.apiLatency.Record(time.Since(start))
+	attrSet.mu.Lock()
+	attrSet.status[status]++
+	attrSet.mu.Unlock()
+	if err != nil {
+		attrSet.apiFailed.Add(1)
+		logErrorf(T("属性下发测试: 设备 %s: %v"), device, err)
+	}
+}
+
+// handleAttributeSet 处理设备收到的属性设置：关联到请求并通过MQTT回复，不是本测试下发的消息时返回false
+//
+// 回复在单独的goroutine中发布，不阻塞客户端的消息分发。
+func handleAttributeSet(client DeviceClient, topic string, payload []byte) bool {
+	if !attrSetEnabled() {
+		return false
+	}
+	seq, ok := payloadSeq(payload, AppConfig.AttrSet.Key)
+	if !ok {
+		return false
+	}
+	now := time.Now()
+	attrSet.mu.Lock()
+	req := attrSet.pending[seq]
+	duplicate := req != nil && req.delivered
+	if req != nil {
+		req.delivered = true
+	}
+	attrSet.mu.Unlock()
+	switch {
+	case req == nil:
+		attrSet.unknown.Add(1)
+		return true
+	case duplicate:
+		attrSet.duplicates.Add(1)
+		return true
+	}
+	attrSet.delivered.Add(1)
+	attrSet.deliverLatency.Record(now.Sub(req.sent))
+
+	// 主题最后一级为平台生成的消息ID，回复主题中需要带上
+	messageID := topic[strings.LastIndex(topic, "/")+1:]
+	response := strings.ReplaceAll(AppConfig.AttrSet.ResponseTopic, "{message_id}", messageID)
+	ack := fmt.Appendf(nil, `{"result":0,"message":"success","ts":%d}`, now.Unix())
+	go func() {
+		if err := client.Publish(response, byte(AppConfig.MQTT.QoS), ack); err != nil {
+			attrSet.ackFailed.Add(1)
+			logErrorf(T("属性下发测试: 回复 %s 失败: %v"), response, err)
+			return
+		}
+		attrSet.acked.Add(1)
+		attrSet.ackLatency.Record(time.Since(req.sent))
+	}()
+	return true
+}
+
+// payloadSeq 从平台下发的消息中取出key对应的请求序号，兼容平台把下发的值包在外层对象或字符串中
+func payloadSeq(payload []byte, key string) (uint64, bool) {
+	var msg any
+	if json.Unmarshal(payload, &msg) != nil {
+		return 0, false
+	}
+	return findSeq(msg, key, 3)
+}
+
+// findSeq 在JSON值中查找key对应的序号，最多向下查找depth层
+func findSeq(v any, key string, depth int) (uint64, bool) {
+	switch v := v.(type) {
+	case map[string]any:
+		if seq, ok := v[key]; ok {
+			switch seq := seq.(type) {
+			case float64:
+				return uint64(seq), true
+			case string:
+				n, err := strconv.ParseUint(seq, 10, 64)
+				return n, err == nil
+			}
+		}
+		if depth > 0 {
+			for _, child := range v {
+				if seq, ok := findSeq(child, key, depth-1); ok {
+					return seq, true
+				}
+			}
+		}
+	case string:
+		var inner any
+		if depth > 0 && strings.HasPrefix(v, "{") && json.Unmarshal([]byte(v), &inner) == nil {
+			return findSeq(inner, key, depth-1)
+		}
+	}
+	return 0, false
+}
+
+// reportAttrSet 等待进行中的请求和设备回复，输出属性下发测试的统计(未启用时不输出)
+func reportAttrSet() {
+	if !attrSetEnabled() {
+		return
+	}
+	attrSet.calls.Wait()
+	// 最后一次请求后最多等待ack_timeout，让设备收到并回复剩余的属性设置
+	for attrSet.sent.Load() > attrSet.delivered.Load() {
+		attrSet.mu.Lock()
+		last := attrSet.lastAt
+		attrSet.mu.Unlock()
+		if time.Since(last) >= AppConfig.AttrSet.AckTimeout {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	time.Sleep(100 * time.Millisecond) // 等待最后的回复发布完成
+
+	sent, delivered := attrSet.sent.Load(), attrSet.delivered.Load()
+	log.Println(T("\n========== 属性下发(平台API→设备) =========="))
+	log.Printf(T("设备: %d 个, 请求: %d, API失败: %d (%.2f%%)"),
+		len(attrSet.devices), sent, attrSet.apiFailed.Load(), percent(attrSet.apiFailed.Load(), sent))
+	attrSet.mu.Lock()
+	for status, count := range attrSet.status {
+		if status == 0 {
+			log.Printf(T("  - 请求失败: %d"), count)
+		} else {
+			log.Printf(T("  - 状态码 %d: %d"), status, count)
+		}
+	}
+	attrSet.mu.Unlock()
+	log.Printf(T("设备收到: %d, 未收到: %d (%.2f%%), 重复: %d, 无法关联: %d"),
+		delivered, sent-delivered, percent(sent-delivered, sent), attrSet.duplicates.Load(), attrSet.unknown.Load())
+	log.Printf(T("设备回复: 成功 %d, 失败 %d"), attrSet.acked.Load(), attrSet.ackFailed.Load())
+	for _, h := range []struct {
+		name string
+		h    *Histogram
+	}{
+		{T("API耗时"), &attrSet.apiLatency},
+		{T("下发到设备收到"), &attrSet.deliverLatency},
+		{T("下发到设备回复"), &attrSet.ackLatency},
+	} {
+		if h.h.Count() > 0 {
+			log.Printf(T("%s: 平均 %v, p50 %v, p99 %v, 最大 %v"),
+				h.name, h.h.Mean(), h.h.Quantile(0.50), h.h.Quantile(0.99), h.h.Max())
+		}
+	}
+	if sent > 0 && delivered == 0 {
+		log.Printf(T("设备没有收到任何属性设置，请检查下发接口(%s)、设备订阅的主题(%s)和Broker的ACL"),
+			AppConfig.AttrSet.Path, AppConfig.AttrSet.Topic)
+	}
+	log.Println("===============================")
+}
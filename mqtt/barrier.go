@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 循环屏障命令行参数
+var (
+	cycleBarrierFlag = flag.Bool("cycle-barrier", false, "每个循环等待所有设备发送完成(超时见--barrier-timeout)，并报告未按时完成的设备")
+	barrierTimeout   = flag.Duration("barrier-timeout", 0, "每个循环等待设备发送完成的超时时间(默认等于发送间隔)")
+)
+
+// cycleRingSize 保留最近多少个循环的完成记录，更早循环的迟到完成不再统计
+const cycleRingSize = 64
+
+// cycleRecord 单个循环的完成情况
+type cycleRecord struct {
+	cycle     uint64
+	start     time.Time
+	deadline  time.Time
+	expected  int64
+	completed atomic.Int64
+	closed    atomic.Bool   // 屏障已结束(完成或超时)，之后的完成计为迟到
+	done      chan struct{} // 所有设备完成时关闭
+}
+
+// CycleBarrier 跟踪每个循环中设备的发送完成情况
+//
+// 设备在每个循环发送完成后调用complete；主循环触发发送后调用wait等待所有在线设备完成。
+// 超时未完成的设备为落后设备：之后完成的计为迟到(记录超出截止时间多少)，
+// 一直没有完成的说明设备错过了该循环(上一轮发送尚未结束)，这是实际消息数少于理论值的原因。
+type CycleBarrier struct {
+	active atomic.Int64 // 在线设备数
+
+	mu      sync.Mutex
+	records [cycleRingSize]*cycleRecord
+
+	expectedTotal   atomic.Uint64 // 理论完成数(各循环的在线设备数之和)
+	completedTotal  atomic.Uint64 // 实际完成数(含迟到)
+	lateTotal       atomic.Uint64 // 迟到完成数
+	stragglerTotal  atomic.Uint64 // 屏障超时时未完成的设备数之和
+	stragglerCycles atomic.Uint64 // 出现落后设备的循环数
+	lateness        Histogram     // 迟到完成超出截止时间的时长
+}
+
+// cycleBarrier 全局循环屏障
+var cycleBarrier CycleBarrier
+
+// join 设备连接成功后加入
+func (b *CycleBarrier) join() {
+	b.active.Add(1)
+}
+
+// leave 设备退出时离开
+func (b *CycleBarrier) leave() {
+	b.active.Add(-1)
+}
+
+// begin 在触发发送前登记新的循环
+func (b *CycleBarrier) begin(cycle uint64, timeout time.Duration) {
+	now := time.Now()
+	rec := &cycleRecord{
+		cycle:    cycle,
+		start:    now,
+		deadline: now.Add(timeout),
+		expected: b.active.Load(),
+		done:     make(chan struct{}),
+	}
+	if rec.expected <= 0 {
+		close(rec.done)
+	}
+
+	b.mu.Lock()
+	b.records[cycle%cycleRingSize] = rec
+	b.mu.Unlock()
+
+	b.expectedTotal.Add(uint64(max(rec.expected, 0)))
+}
+
+// record 返回循环的完成记录，已被覆盖时返回nil
+func (b *CycleBarrier) record(cycle uint64) *cycleRecord {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	rec := b.records[cycle%cycleRingSize]
+	if rec == nil || rec.cycle != cycle {
+		return nil
+	}
+	return rec
+}
+
+// complete 设备完成一个循环的发送
+func (b *CycleBarrier) complete(cycle uint64) {
+	rec := b.record(cycle)
+	if rec == nil {
+		return
+	}
+	b.completedTotal.Add(1)
+	if rec.closed.Load() {
+		b.lateTotal.Add(1)
+		b.lateness.Record(time.Since(rec.deadline))
+	}
+	if rec.completed.Add(1) == rec.expected {
+		close(rec.done)
+	}
+}
+
+// wait 等待循环中的所有设备完成或超时，返回超时时仍未完成的设备数
+func (b *CycleBarrier) wait(ctx context.Context, cycle uint64) int64 {
+	rec := b.record(cycle)
+	if rec == nil {
+		return 0
+	}
+	timer := time.NewTimer(time.Until(rec.deadline))
+	defer timer.Stop()
+	select {
+	case <-rec.done:
+	case <-timer.C:
+	case <-ctx.Done():
+		return 0
+	}
+	rec.closed.Store(true)
+
+	stragglers := rec.expected - rec.completed.Load()
+	if stragglers <= 0 {
+		return 0
+	}
+	b.stragglerTotal.Add(uint64(stragglers))
+	b.stragglerCycles.Add(1)
+	log.Printf(T("循环 %d: %d/%d 个设备未在 %v 内完成发送"),
+		cycle, stragglers, rec.expected, rec.deadline.Sub(rec.start).Round(time.Millisecond))
+	return stragglers
+}
+
+// Report 输出循环完成情况，解释理论消息数与实际消息数的差异
+func (b *CycleBarrier) Report(cycles uint64) {
+	if !AppConfig.Test.CycleBarrier {
+		return
+	}
+	expected, completed := b.expectedTotal.Load(), b.completedTotal.Load()
+	stragglers, late := b.stragglerTotal.Load(), b.lateTotal.Load()
+
+	log.Println(T("\n========== 循环完成情况 =========="))
+	log.Printf(T("出现落后设备的循环: %d/%d, 超时时间: %v"),
+		b.stragglerCycles.Load(), cycles, AppConfig.Test.BarrierTimeout)
+	log.Printf(T("理论完成数: %d, 实际完成数: %d (%.2f%%)"), expected, completed, percent(completed, expected))
+	if stragglers > 0 {
+		log.Printf(T("落后设备次数: %d, 其中迟到完成 %d (超出截止时间 p50=%v, p99=%v, 最大=%v), 错过循环 %d"),
+			stragglers, late, b.lateness.Quantile(0.50), b.lateness.Quantile(0.99), b.lateness.Max(),
+			stragglers-min(late, stragglers))
+	}
+	log.Println("===============================")
+}
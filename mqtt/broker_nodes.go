@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// brokerNode 一个Broker节点(实际连接到的地址)上的连接和发布统计
+type brokerNode struct {
+	Address  string
+	active   atomic.Int64  // 当前连接数
+	connects atomic.Uint64 // 累计建立的连接数(含重连)
+	sent     atomic.Uint64 // 发布成功的消息数(含额外数据流)
+	failed   atomic.Uint64 // 发布失败的消息数
+}
+
+// brokerNodes 按实际连接地址(IP:端口)记录的节点统计
+//
+// 集群部署时域名或负载均衡器背后有多个节点，按解析后实际连接的地址统计分布，
+// 可以发现负载均衡的会话保持或哈希不均导致的连接集中。
+var brokerNodes sync.Map // address → *brokerNode
+
+// brokerNodeFor 返回地址对应的节点统计，首次出现时创建
+func brokerNodeFor(address string) *brokerNode {
+	if n, ok := brokerNodes.Load(address); ok {
+		return n.(*brokerNode)
+	}
+	n, _ := brokerNodes.LoadOrStore(address, &brokerNode{Address: address})
+	return n.(*brokerNode)
+}
+
+// label 节点的显示名称，配置了节点名称时附加在地址后
+func (n *brokerNode) label() string {
+	if name := AppConfig.MQTT.Nodes[n.Address]; name != "" {
+		return name + " (" + n.Address + ")"
+	}
+	return n.Address
+}
+
+// nodeTracker 单个设备连接当前所在的节点
+type nodeTracker struct {
+	family string                     // 允许连接的地址族(v4/v6)，any为不限
+	dialed atomic.Pointer[string]     // 最近一次拨号的地址
+	node   atomic.Pointer[brokerNode] // 当前连接的节点，未连接时为nil
+	last   atomic.Pointer[brokerNode] // 最近一次连接的节点，断开后发布失败的消息计入该节点
+}
+
+// dialer 返回记录拨号地址的Dialer，Control回调拿到的是域名解析后的IP:端口
+//
+// 域名同时解析出IPv4和IPv6地址时，拒绝不属于本设备地址族的地址，拨号器会继续尝试下一个地址。
+func (t *nodeTracker) dialer(timeout time.Duration) *net.Dialer {
+	return &net.Dialer{
+		Timeout:  timeout,
+		Resolver: brokerResolver,
+		Control: func(network, address string, c syscall.RawConn) error {
+			if want := familyNetwork(t.family); want != "tcp" && network != want {
+				return fmt.Errorf(T("地址 %s 不属于IP%s"), address, t.family)
+			}
+			t.dialed.Store(&address)
+			return nil
+		},
+	}
+}
+
+// connected 连接建立后把设备计入最近一次拨号的节点
+func (t *nodeTracker) connected() {
+	address := t.dialed.Load()
+	if address == nil {
+		return
+	}
+	n := brokerNodeFor(*address)
+	n.connects.Add(1)
+	n.active.Add(1)
+	t.last.Store(n)
+	if old := t.node.Swap(n); old != nil {
+		old.active.Add(-1)
+	}
+}
+
+// connectedTo 连接建立后按连接的远端地址计入节点(轻量客户端)
+func (t *nodeTracker) connectedTo(addr net.Addr) {
+	address := addr.String()
+	t.dialed.Store(&address)
+	t.connected()
+}
+
+// disconnected 连接断开时从当前节点移除
+func (t *nodeTracker) disconnected() {
+	if old := t.node.Swap(nil); old != nil {
+		old.active.Add(-1)
+	}
+}
+
+// recordPublish 把一次发布计入最近一次连接的节点
+func (t *nodeTracker) recordPublish(err error) {
+	n := t.last.Load()
+	if n == nil {
+		return
+	}
+	if err != nil {
+		n.failed.Add(1)
+		return
+	}
+	n.sent.Add(1)
+}
+
+// address 最近一次连接的节点地址，尚未连接过时返回空字符串
+func (t *nodeTracker) address() string {
+	if n := t.last.Load(); n != nil {
+		return n.Address
+	}
+	return ""
+}
+
+// clientBroker 返回设备实际连接的Broker节点地址，用于按节点统计发布
+//
+// 不经过MQTT连接的客户端(HTTP上报、本地接收端)和尚未连接过的设备返回配置的服务器地址。
+func clientBroker(client DeviceClient) string {
+	var address string
+	switch c := client.(type) {
+	case *pahoClient:
+		address = c.node.address()
+	case *liteClient:
+		address = c.node.address()
+	}
+	if address == "" {
+		return AppConfig.MQTT.Server
+	}
+	return address
+}
+
+// BrokerNodeSummary 单个节点的汇总，用于JSON汇总
+type BrokerNodeSummary struct {
+	Address  string  `json:"address"`
+	Name     string  `json:"name,omitempty"`
+	Active   int64   `json:"active"`
+	Connects uint64  `json:"connects"`
+	Sent     uint64  `json:"sent"`
+	Failed   uint64  `json:"failed"`
+	Rate     float64 `json:"rate"` // 消息/秒
+}
+
+// brokerNodeSummaries 按地址排序的节点汇总
+func brokerNodeSummaries(elapsed time.Duration) []BrokerNodeSummary {
+	var list []BrokerNodeSummary
+	brokerNodes.Range(func(_, value any) bool {
+		n := value.(*brokerNode)
+		list = append(list, BrokerNodeSummary{
+			Address:  n.Address,
+			Name:     AppConfig.MQTT.Nodes[n.Address],
+			Active:   n.active.Load(),
+			Connects: n.connects.Load(),
+			Sent:     n.sent.Load(),
+			Failed:   n.failed.Load(),
+			Rate:     ratePerSecond(float64(n.sent.Load()), elapsed),
+		})
+		return true
+	})
+	sort.Slice(list, func(i, j int) bool { return list[i].Address < list[j].Address })
+	return list
+}
+
+// reportBrokerNodes 输出各节点的连接分布和吞吐量，只连接到一个节点时不输出
+func reportBrokerNodes(elapsed time.Duration) {
+	list := brokerNodeSummaries(elapsed)
+	if len(list) < 2 {
+		return
+	}
+	var connects, sent uint64
+	for _, n := range list {
+		connects += n.Connects
+		sent += n.Sent
+	}
+	log.Println(T("\n========== Broker节点分布 =========="))
+	minConnects, maxConnects := list[0].Connects, list[0].Connects
+	for _, s := range list {
+		n := brokerNodeFor(s.Address)
+		log.Printf(T("%s: 连接 %d 次(%.1f%%), 测试结束时连接 %d, 发布成功 %d 条(%.1f%%), 失败 %d, 速率 %.1f 条/秒"),
+			n.label(), s.Connects, percent(s.Connects, connects), s.Active,
+			s.Sent, percent(s.Sent, sent), s.Failed, s.Rate)
+		minConnects, maxConnects = min(minConnects, s.Connects), max(maxConnects, s.Connects)
+	}
+	if AppConfig.Resolve.Family == "dual" {
+		reportFamilySplit(list)
+	}
+	// 平均分配时各节点的连接数应接近，相差一倍以上通常是负载均衡的会话保持或哈希不均
+	if maxConnects > 2*minConnects {
+		log.Printf(T("警告: 连接分布不均，最多的节点 %d 次，最少的节点 %d 次，请检查负载均衡策略"), maxConnects, minConnects)
+	}
+	log.Println("===============================")
+}
+
+// reportFamilySplit 双栈测试时按地址族汇总连接和发布
+func reportFamilySplit(list []BrokerNodeSummary) {
+	connects, sent := make(map[string]uint64), make(map[string]uint64)
+	for _, s := range list {
+		host, _, err := net.SplitHostPort(s.Address)
+		if err != nil {
+			continue
+		}
+		family := addressFamily(net.ParseIP(host))
+		connects[family] += s.Connects
+		sent[family] += s.Sent
+	}
+	for _, family := range []string{"v4", "v6"} {
+		log.Printf(T("IP%s: 连接 %d 次, 发布成功 %d 条"), family, connects[family], sent[family])
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// calibrate子命令命令行参数
+var (
+	calibrateLevels   = flag.String("calibrate-levels", "", "calibrate: 测试的并发设备数，逗号分隔(默认: 1000,10000,100000和配置的设备数)")
+	calibrateDuration = flag.Duration("calibrate-duration", 10*time.Second, "calibrate: 每个并发级别的运行时长")
+)
+
+// calibrationUsername 校准中设备的占位token前缀，数据点名称模板包含{device}时使用
+const calibrationUsername = "calibrate-"
+
+// noopClient 不进行任何网络通信的设备客户端，发布立即成功，用于测量本机发送端的上限
+type noopClient struct{}
+
+func (noopClient) Connect() error                       { return nil }
+func (noopClient) Publish(string, byte, []byte) error   { return nil }
+func (noopClient) Subscribe(string, byte) (byte, error) { return 0, nil }
+func (noopClient) Disconnect()                          {}
+
+// calibrationResult 一个并发级别的校准结果
+type calibrationResult struct {
+	devices   int
+	requested float64 // 请求的消息速率(消息/秒)
+	achieved  float64 // 实际达到的消息速率(消息/秒)
+	points    float64 // 实际达到的数据点速率(点/秒)
+	missed    uint64  // 设备因上一次发送尚未完成而错过的循环数
+	behind    time.Duration
+	startLag  Histogram // 设备开始发送晚于计划时间的时长
+}
+
+// ratio 实际速率占请求速率的百分比
+func (r *calibrationResult) ratio() float64 {
+	if r.requested <= 0 {
+		return 0
+	}
+	return r.achieved / r.requested * 100
+}
+
+// runCalibrate calibrate子命令：用不联网的客户端按配置的间隔驱动发送调度，测量各并发级别下
+// 实际达到的发送速率
+//
+// 每个设备使用与正式运行相同的消息生成器和发布重试路径，只是发布立即成功。实际速率明显低于
+// 请求速率时，瓶颈在本机的消息生成和调度，而不是平台；正式测试的吞吐量不会超过这里测得的上限。
+func runCalibrate() {
+	levels, err := calibrationLevels()
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if *calibrateDuration < AppConfig.Test.DataInterval {
+		log.Fatalf(T("校准时长 %v 不能小于发送间隔 %v"), *calibrateDuration, AppConfig.Test.DataInterval)
+	}
+	if err := initStrings(); err != nil {
+		log.Fatalf("%v", err)
+	}
+	if err := initGPS(); err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	interval := AppConfig.Test.DataInterval
+	log.Printf(T("发送速率校准: 间隔=%v, 每级运行 %v, GOMAXPROCS=%d"), interval, *calibrateDuration, runtime.GOMAXPROCS(0))
+	results := make([]*calibrationResult, 0, len(levels))
+	for _, devices := range levels {
+		r := calibrateLevel(devices, interval, *calibrateDuration)
+		log.Printf(T("%d 个设备: 请求 %.0f 消息/秒, 实际 %.0f 消息/秒 (%.1f%%)"), devices, r.requested, r.achieved, r.ratio())
+		results = append(results, r)
+		runtime.GC()
+	}
+	reportCalibration(results)
+}
+
+// calibrationLevels 解析要测试的并发设备数，按从小到大排列
+func calibrationLevels() ([]int, error) {
+	var levels []int
+	if *calibrateLevels == "" {
+		levels = []int{1000, 10000, 100000}
+		if n := AppConfig.Device.ClientNumber; n > 0 && !slices.Contains(levels, n) {
+			levels = append(levels, n)
+		}
+	} else {
+		for _, s := range strings.Split(*calibrateLevels, ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf(T("无效的并发设备数: %q"), s)
+			}
+			levels = append(levels, n)
+		}
+	}
+	slices.Sort(levels)
+	return slices.Compact(levels), nil
+}
+
+// calibrateLevel 启动devices个设备，按interval触发发送并运行duration，统计实际达到的速率
+//
+// 触发方式与正式运行一致：所有设备等待同一个通道，主循环按单调时钟计算计划时间并关闭通道。
+// 新通道在关闭旧通道之前发布，设备发送完成后总能拿到下一个循环的通道。
+func calibrateLevel(devices int, interval, duration time.Duration) *calibrationResult {
+	r := &calibrationResult{devices: devices, requested: float64(devices) / interval.Seconds()}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		trigger  atomic.Pointer[chan struct{}]
+		current  atomic.Pointer[sendSlot]
+		messages atomic.Uint64
+		points   atomic.Uint64
+		missed   atomic.Uint64
+		wg       sync.WaitGroup
+	)
+	initial := make(chan struct{})
+	trigger.Store(&initial)
+
+	for i := 0; i < devices; i++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			faker := newDeviceFaker(index)
+			generator := newPayloadGenerator(index, calibrationUsername+strconv.Itoa(index), currentMessagePoints(), faker)
+			strs := newStringFields(faker)
+			var client DeviceClient = noopClient{}
+			var last uint64
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-*trigger.Load():
+				}
+				slot := *current.Load()
+				if last > 0 && slot.cycle > last+1 {
+					missed.Add(slot.cycle - last - 1)
+				}
+				last = slot.cycle
+				r.startLag.Record(time.Since(slot.at))
+
+				payload, err := generator.Next()
+				if err != nil {
+					continue
+				}
+				payload, extra := strs.append(payload)
+				if err := publishWithRetry(ctx, client, AppConfig.MQTT.Topic, 0, payload); err == nil {
+					messages.Add(1)
+					points.Add(uint64(generator.Points() + extra))
+				}
+				generator.Done()
+				runtime.Gosched()
+			}
+		}(i)
+	}
+
+	// 等待设备创建完生成器，避免把启动时间计入第一个循环
+	runtime.Gosched()
+	time.Sleep(min(time.Duration(devices)*time.Microsecond, 5*time.Second))
+
+	start := time.Now()
+	next := start
+	var first time.Time
+	for cycle := uint64(1); ; cycle++ {
+		next = next.Add(interval)
+		if next.Sub(start) > duration {
+			break
+		}
+		time.Sleep(time.Until(next))
+		now := time.Now()
+		if lag := now.Sub(next); lag > time.Millisecond {
+			r.behind += lag
+		}
+		if cycle == 1 {
+			first = now
+		}
+		current.Store(&sendSlot{cycle: cycle, at: next})
+		ch := make(chan struct{})
+		close(*trigger.Swap(&ch))
+	}
+	// 最后一个循环的发送留出一个间隔完成，速率按第一次触发起的时长计算
+	time.Sleep(time.Until(next))
+	elapsed := time.Since(first)
+	cancel()
+	wg.Wait()
+
+	r.achieved = ratePerSecond(float64(messages.Load()), elapsed)
+	r.points = ratePerSecond(float64(points.Load()), elapsed)
+	r.missed = missed.Load()
+	return r
+}
+
+// reportCalibration 输出各并发级别的校准结果和本机的发送上限
+func reportCalibration(results []*calibrationResult) {
+	log.Println(T("\n========== 发送速率校准 =========="))
+	log.Println(T("设备数 | 请求(消息/秒) | 实际(消息/秒) | 达成率 | 数据点/秒 | 错过循环 | 调度落后 | 开始延迟P99"))
+	var ceiling float64
+	for _, r := range results {
+		log.Printf("%d | %.0f | %.0f | %.1f%% | %.0f | %d | %v | %v",
+			r.devices, r.requested, r.achieved, r.ratio(), r.points, r.missed,
+			r.behind.Round(time.Millisecond), r.startLag.Quantile(0.99).Round(time.Microsecond))
+		ceiling = max(ceiling, r.achieved)
+	}
+
+	// 达成率低于95%的级别说明发送端已经跟不上请求的速率，此时的最高速率才是本机的上限
+	limited := slices.IndexFunc(results, func(r *calibrationResult) bool { return r.ratio() < 95 })
+	if limited < 0 {
+		log.Printf(T("所有级别都达到了请求速率，本机发送端上限高于 %.0f 消息/秒 (可增大设备数或缩短间隔继续测试)"), ceiling)
+	} else {
+		r := results[limited]
+		log.Printf(T("本机发送端上限: 约 %.0f 消息/秒 (不含网络和Broker开销)，%d 个设备时只达到请求速率的 %.1f%%"),
+			ceiling, r.devices, r.ratio())
+		if planned := float64(AppConfig.Device.ClientNumber) / AppConfig.Test.DataInterval.Seconds(); planned > ceiling {
+			log.Printf(T("警告: 配置的发送速率 %.0f 消息/秒 超过本机发送端上限，测试结果将低于配置值"), planned)
+		}
+	}
+	log.Println("===============================")
+}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	crand "crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"math/big"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TLS与设备证书命令行参数
+var (
+	tlsCAFile     = flag.String("tls-ca", "", "校验服务器证书的CA文件(启用TLS)")
+	certDir       = flag.String("cert-dir", "", "设备证书目录，按 <token>.crt/<token>.key 加载客户端证书(启用mTLS)")
+	generateCerts = flag.Bool("generate-certs", false, "证书目录中缺少设备证书时用测试CA即时签发(测试CA不存在时自动生成)")
+	certRotate    = flag.Duration("cert-rotate", 0, "测试过程中按该间隔为设备重新签发证书并重连，0为不轮换")
+)
+
+// 测试CA文件名(位于设备证书目录中)及证书有效期
+const (
+	testCACertFile     = "ca.crt"
+	testCAKeyFile      = "ca.key"
+	testCAValidity     = 10 * 365 * 24 * time.Hour
+	deviceCertValidity = 365 * 24 * time.Hour
+)
+
+// 证书轮换统计
+var (
+	certRotations        uint64 // 轮换成功次数
+	certRotationFailures uint64 // 轮换失败次数(签发或重连失败)
+)
+
+// tlsState TLS全局状态，由initTLS初始化，之后只读(identities除外)
+var tlsState struct {
+	base       *tls.Config       // 公共TLS配置(服务器证书校验)，未启用TLS时为nil
+	caCert     *x509.Certificate // 测试CA证书，未加载时为nil
+	caKey      crypto.Signer     // 测试CA私钥
+	identities sync.Map          // token → *deviceIdentity
+}
+
+// tlsSchemes 表示TLS连接的服务器地址协议前缀
+var tlsSchemes = []string{"ssl://", "tls://", "mqtts://"}
+
+// usesTLS 判断服务器地址是否为TLS协议
+func usesTLS(server string) bool {
+	for _, scheme := range tlsSchemes {
+		if strings.HasPrefix(server, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// tlsServerURL 把服务器地址转换为paho识别的TLS地址(ssl://)
+func tlsServerURL(server string) string {
+	if usesTLS(server) {
+		return server
+	}
+	return "ssl://" + brokerAddress(server)
+}
+
+// initTLS 加载服务器CA和测试CA，未启用TLS时不做任何事
+func initTLS() error {
+	cfg := AppConfig.TLS
+	if !cfg.Enabled {
+		return nil
+	}
+
+	base := &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify}
+	if cfg.CAFile != "" {
+		data, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return fmt.Errorf(T("读取CA文件失败: %w"), err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return fmt.Errorf(T("CA文件中没有有效证书: %s"), cfg.CAFile)
+		}
+		base.RootCAs = pool
+	}
+	tlsState.base = base
+
+	if cfg.CertDir == "" {
+		return nil
+	}
+	if err := loadTestCA(cfg.CertDir); err != nil {
+		return err
+	}
+	if cfg.RotateInterval > 0 && tlsState.caKey == nil {
+		return fmt.Errorf(T("证书轮换需要测试CA (%s 和 %s)，可使用 --generate-certs 生成"),
+			filepath.Join(cfg.CertDir, testCACertFile), filepath.Join(cfg.CertDir, testCAKeyFile))
+	}
+	return nil
+}
+
+// loadTestCA 从证书目录加载测试CA，不存在且允许即时签发时生成新的CA
+//
+// 没有测试CA时只能使用目录中已有的设备证书。
+func loadTestCA(dir string) error {
+	certFile, keyFile := filepath.Join(dir, testCACertFile), filepath.Join(dir, testCAKeyFile)
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if errors.Is(err, fs.ErrNotExist) && AppConfig.TLS.GenerateCerts {
+		if err = generateTestCA(certFile, keyFile); err != nil {
+			return fmt.Errorf(T("生成测试CA失败: %w"), err)
+		}
+		log.Printf(T("已生成测试CA: %s (需在平台上配置为设备证书的签发CA)"), certFile)
+		pair, err = tls.LoadX509KeyPair(certFile, keyFile)
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf(T("加载测试CA失败: %w"), err)
+	}
+
+	caCert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return fmt.Errorf(T("加载测试CA失败: %w"), err)
+	}
+	signer, ok := pair.PrivateKey.(crypto.Signer)
+	if !ok || !caCert.IsCA {
+		return fmt.Errorf(T("%s 不是有效的CA证书"), certFile)
+	}
+	tlsState.caCert, tlsState.caKey = caCert, signer
+	return nil
+}
+
+// generateTestCA 生成自签名的测试CA并写入文件
+func generateTestCA(certFile, keyFile string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), crand.Reader)
+	if err != nil {
+		return err
+	}
+	template := &x509.Certificate{
+		SerialNumber:          newSerialNumber(),
+		Subject:               pkix.Name{CommonName: "ThingsPanel Test CA", Organization: []string{"ThingsPanel Test"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(testCAValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(crand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(certFile), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		return err
+	}
+	return os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644)
+}
+
+// newSerialNumber 生成128位随机证书序列号
+func newSerialNumber() *big.Int {
+	serial, err := crand.Int(crand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		// 系统随机源不可用时退化为时间戳，测试证书不依赖序列号的不可预测性
+		return big.NewInt(time.Now().UnixNano())
+	}
+	return serial
+}
+
+// issueDeviceCert 用测试CA为设备签发客户端证书，CN为设备token
+//
+// save为true时把证书和私钥写入证书目录，下次运行直接加载；轮换签发的证书只保存在内存中。
+func issueDeviceCert(username string, save bool) (*tls.Certificate, error) {
+	if tlsState.caKey == nil {
+		return nil, errors.New(T("未加载测试CA，无法签发设备证书"))
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), crand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: newSerialNumber(),
+		Subject:      pkix.Name{CommonName: username, Organization: []string{"ThingsPanel Test"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(deviceCertValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(crand.Reader, template, tlsState.caCert, &key.PublicKey, tlsState.caKey)
+	if err != nil {
+		return nil, fmt.Errorf(T("签发设备证书失败: %w"), err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+
+	if save {
+		certFile, keyFile := deviceCertPaths(username)
+		if err := os.WriteFile(keyFile, keyPEM, 0o600); err != nil {
+			return nil, fmt.Errorf(T("保存设备证书失败: %w"), err)
+		}
+		if err := os.WriteFile(certFile, certPEM, 0o644); err != nil {
+			return nil, fmt.Errorf(T("保存设备证书失败: %w"), err)
+		}
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
+
+// deviceCertPaths 返回设备证书和私钥的文件路径
+func deviceCertPaths(username string) (string, string) {
+	base := filepath.Join(AppConfig.TLS.CertDir, username)
+	return base + ".crt", base + ".key"
+}
+
+// deviceIdentity 设备的客户端证书，轮换时替换
+type deviceIdentity struct {
+	username     string
+	mu           sync.Mutex
+	cert         *tls.Certificate
+	rng          *rand.Rand // 轮换时间的随机偏移，由rotatingIdentity按设备序号创建
+	nextRotation time.Time  // 未启用轮换时为零值
+}
+
+// certRandStream 证书轮换随机数流的起始编号，与其他用途的随机数流错开
+const certRandStream = 4 << 32
+
+// deviceIdentityFor 返回设备的证书身份，同一token共享一个实例
+func deviceIdentityFor(username string) *deviceIdentity {
+	v, _ := tlsState.identities.LoadOrStore(username, &deviceIdentity{username: username})
+	return v.(*deviceIdentity)
+}
+
+// certificate 返回设备当前的证书，首次使用时从证书目录加载，缺少时按配置即时签发
+func (d *deviceIdentity) certificate() (*tls.Certificate, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.cert != nil {
+		return d.cert, nil
+	}
+
+	certFile, keyFile := deviceCertPaths(d.username)
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	switch {
+	case err == nil:
+		d.cert = &cert
+	case errors.Is(err, fs.ErrNotExist) && AppConfig.TLS.GenerateCerts:
+		if d.cert, err = issueDeviceCert(d.username, true); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf(T("加载设备证书失败: %w"), err)
+	}
+	d.scheduleRotation()
+	return d.cert, nil
+}
+
+// scheduleRotation 安排下一次轮换，加入±10%的随机偏移，避免所有设备同时重连，调用方持有锁
+//
+// 预检等只连接一次的客户端也会加载证书，此时还没有随机数流，轮换由rotatingIdentity安排。
+func (d *deviceIdentity) scheduleRotation() {
+	interval := AppConfig.TLS.RotateInterval
+	if interval <= 0 || d.rng == nil {
+		return
+	}
+	d.nextRotation = time.Now().Add(time.Duration(float64(interval) * (0.9 + 0.2*d.rng.Float64())))
+}
+
+// rotationDue 判断是否到了轮换时间
+func (d *deviceIdentity) rotationDue(now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return !d.nextRotation.IsZero() && now.After(d.nextRotation)
+}
+
+// rotate 用测试CA签发新证书替换当前证书，失败时保留旧证书并等待下一个轮换时间
+func (d *deviceIdentity) rotate() error {
+	cert, err := issueDeviceCert(d.username, false)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err == nil {
+		d.cert = cert
+	}
+	d.scheduleRotation()
+	return err
+}
+
+// deviceTLSConfig 返回设备连接使用的TLS配置，未启用TLS时返回nil
+//
+// 客户端证书在每次握手时获取，因此轮换后重连(包括paho的自动重连)使用新证书。
+func deviceTLSConfig(username string) *tls.Config {
+	if tlsState.base == nil {
+		return nil
+	}
+	cfg := tlsState.base.Clone()
+	if AppConfig.TLS.CertDir != "" {
+		identity := deviceIdentityFor(username)
+		cfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return identity.certificate()
+		}
+	}
+	return cfg
+}
+
+// rotatingIdentity 返回需要轮换证书的设备身份，未启用轮换时返回nil
+//
+// 轮换时间的随机偏移使用按设备序号创建的随机数流，相同种子下轮换时间可复现。
+func rotatingIdentity(index int, username string) *deviceIdentity {
+	if tlsState.base == nil || AppConfig.TLS.CertDir == "" || AppConfig.TLS.RotateInterval <= 0 {
+		return nil
+	}
+	identity := deviceIdentityFor(username)
+	identity.mu.Lock()
+	defer identity.mu.Unlock()
+	if identity.rng == nil {
+		identity.rng = newRand(certRandStream + uint64(index))
+		if identity.cert != nil {
+			identity.scheduleRotation()
+		}
+	}
+	return identity
+}
+
+// rotateDeviceCert 为设备签发新证书并断开重连，使新证书在TLS握手中生效
+func rotateDeviceCert(client DeviceClient, identity *deviceIdentity) {
+	err := identity.rotate()
+	if err == nil {
+		client.Disconnect()
+		err = client.Connect()
+	}
+	if err != nil {
+		atomic.AddUint64(&certRotationFailures, 1)
+		logErrorf(T("设备 %s 证书轮换失败: %v"), identity.username, err)
+		return
+	}
+	atomic.AddUint64(&certRotations, 1)
+}
+
+// reportCertRotations 输出证书轮换统计(未启用轮换时不输出)
+func reportCertRotations() {
+	if tlsState.base == nil || AppConfig.TLS.RotateInterval <= 0 {
+		return
+	}
+	log.Printf(T("证书轮换: 成功 %d 次, 失败 %d 次"),
+		atomic.LoadUint64(&certRotations), atomic.LoadUint64(&certRotationFailures))
+}
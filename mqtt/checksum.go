@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 校验和核对命令行参数
+var verifyChecksum = flag.Int("verify-checksum", 0, "测试结束后按数据点核对N个抽样设备发送数值之和与telemetry_datas中SUM()聚合是否一致，0为不核对")
+
+// dbClockSkew 按时间戳查询历史数据时起始时间提前的余量，容忍压测机与平台的时钟偏差
+const dbClockSkew = 5 * time.Second
+
+// keySum 单个数据点发送成功的数值累计
+type keySum struct {
+	count int64
+	sum   float64
+	abs   float64 // 绝对值之和，用于确定浮点累加误差的容忍范围
+}
+
+// checksumSample 抽样设备发送成功的遥测数值按数据点累计
+type checksumSample struct {
+	username string
+	since    time.Time // 第一条发送成功的消息的发布时间
+	sums     map[string]*keySum
+}
+
+// record 累计发送成功的遥测消息中的数值，nil表示设备未被抽样
+func (s *checksumSample) record(payload []byte, at time.Time) {
+	if s == nil {
+		return
+	}
+	values, err := sentValues(payload)
+	if err != nil {
+		return
+	}
+	if s.since.IsZero() {
+		s.since = at
+	}
+	for key, v := range values {
+		k := s.sums[key]
+		if k == nil {
+			k = &keySum{}
+			s.sums[key] = k
+		}
+		k.count++
+		k.sum += v
+		k.abs += math.Abs(v)
+	}
+}
+
+// checksumSamples 参与校验和核对的设备
+var checksumSamples struct {
+	mu   sync.Mutex
+	list []*checksumSample
+}
+
+// newChecksumSample 前 verify.checksum_samples 个启动的设备参与核对，其余设备返回nil
+//
+// 离线缓存场景的设备会补发带原始时间戳的消息，不参与核对。
+func newChecksumSample(username string, offline bool) *checksumSample {
+	if offline {
+		return nil
+	}
+	checksumSamples.mu.Lock()
+	defer checksumSamples.mu.Unlock()
+	if len(checksumSamples.list) >= AppConfig.Verify.ChecksumSamples {
+		return nil
+	}
+	s := &checksumSample{username: username, sums: make(map[string]*keySum)}
+	checksumSamples.list = append(checksumSamples.list, s)
+	return s
+}
+
+// checksumResult 校验和核对结果
+type checksumResult struct {
+	devices   int
+	checked   int // 核对的数据点数
+	countDiff int // 入库条数与发送条数不同(丢失或重复)
+	corrupt   int // 条数相同但数值之和不同(数值被截断或损坏)
+}
+
+// checksumResultSummary 最近一次核对的结果，供JSON汇总使用
+var checksumResultSummary checksumResult
+
+// storedSums 查询设备自since起各数值型数据点的入库条数和数值之和
+func storedSums(ctx context.Context, db *sql.DB, deviceID string, since time.Time) (map[string]keySum, error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT key, COUNT(number_v), COALESCE(SUM(number_v), 0) FROM telemetry_datas
+		WHERE device_id = $1 AND ts >= $2 GROUP BY key`,
+		deviceID, since.Add(-dbClockSkew).UnixMilli())
+	if err != nil {
+		return nil, fmt.Errorf(T("查询历史数据失败: %w"), err)
+	}
+	defer rows.Close()
+
+	sums := make(map[string]keySum)
+	for rows.Next() {
+		var key string
+		var k keySum
+		if err := rows.Scan(&key, &k.count, &k.sum); err != nil {
+			return nil, fmt.Errorf(T("查询历史数据失败: %w"), err)
+		}
+		sums[key] = k
+	}
+	return sums, rows.Err()
+}
+
+// checkChecksums 核对单个设备的各数据点，返回不一致的明细
+func checkChecksums(ctx context.Context, db *sql.DB, s *checksumSample, result *checksumResult) ([]string, error) {
+	deviceID, err := deviceIDByToken(ctx, db, s.username)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := storedSums(ctx, db, deviceID, s.since)
+	if err != nil {
+		return nil, err
+	}
+	result.devices++
+
+	keys := make([]string, 0, len(s.sums))
+	for key := range s.sums {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var details []string
+	for _, key := range keys {
+		result.checked++
+		sent, got := s.sums[key], stored[key]
+		switch {
+		case got.count != sent.count:
+			result.countDiff++
+			details = append(details, fmt.Sprintf(T("设备 %s 数据点 %s: 入库 %d 条, 发送 %d 条"), s.username, key, got.count, sent.count))
+		case math.Abs(got.sum-sent.sum) > 1e-9*math.Max(1, sent.abs):
+			result.corrupt++
+			details = append(details, fmt.Sprintf(T("设备 %s 数据点 %s: 入库数值之和 %v, 发送数值之和 %v"), s.username, key, got.sum, sent.sum))
+		}
+	}
+	return details, nil
+}
+
+// reportChecksums 核对抽样设备每个数据点发送的数值之和与平台历史数据的SUM()聚合
+//
+// 逐行比对在数十亿行的表上代价太高，按数据点聚合只需每个设备一次索引范围扫描，
+// 就能发现数值被静默截断或损坏。条数不同时(QoS 0丢失、QoS 1重复)之和无法比较，单独计数。
+func reportChecksums() {
+	if AppConfig.Verify.ChecksumSamples <= 0 {
+		return
+	}
+	checksumSamples.mu.Lock()
+	var samples []*checksumSample
+	for _, s := range checksumSamples.list {
+		if len(s.sums) > 0 {
+			samples = append(samples, s)
+		}
+	}
+	checksumSamples.mu.Unlock()
+
+	log.Println(T("\n========== 校验和核对 =========="))
+	defer log.Println("===============================")
+	if len(samples) == 0 {
+		log.Println(T("抽样设备没有发送成功的消息，跳过核对"))
+		return
+	}
+
+	db, err := openReadDatabase()
+	if err != nil {
+		log.Printf(T("校验和核对: %v"), err)
+		return
+	}
+	defer db.Close()
+	ingestSettle()
+	waitReplicaReplay(db)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	var result checksumResult
+	var details []string
+	for _, s := range samples {
+		lines, err := checkChecksums(ctx, db, s, &result)
+		if err != nil {
+			log.Printf(T("校验和核对: 设备 %s: %v"), s.username, err)
+			continue
+		}
+		details = append(details, lines...)
+	}
+	checksumResultSummary = result
+
+	log.Printf(T("抽样设备: %d, 核对数据点: %d, 一致 %d, 条数不同 %d, 数值之和不同 %d"),
+		result.devices, result.checked, result.checked-result.countDiff-result.corrupt, result.countDiff, result.corrupt)
+	for i, line := range details {
+		if i == maxMismatchLines {
+			log.Printf(T("... 另有 %d 条不一致未列出"), len(details)-maxMismatchLines)
+			break
+		}
+		log.Println(line)
+	}
+}
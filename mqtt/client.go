@@ -0,0 +1,121 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// 客户端引擎命令行参数
+var clientEngine = flag.String("engine", "", "MQTT客户端引擎(paho: 完整实现, lite: 轻量3.1.1实现)")
+
+// DeviceClient 设备使用的MQTT客户端
+type DeviceClient interface {
+	// Connect 连接服务器并等待CONNACK
+	Connect() error
+	// Publish 发布消息并等待完成(QoS>0时等待确认)
+	Publish(topic string, qos byte, payload []byte) error
+	// Subscribe 订阅主题并等待SUBACK，返回SUBACK返回码
+	Subscribe(topic string, qos byte) (byte, error)
+	// Disconnect 断开连接
+	Disconnect()
+}
+
+// newDeviceClient 根据配置的引擎创建设备客户端，多协议混合时部分设备使用HTTP上报，
+// 使用本地接收端时全部设备都不连接网络。rng为该连接的重连抖动随机数流
+func newDeviceClient(clientID, username string, stats *DeviceStats, rng *rand.Rand) DeviceClient {
+	if sinkEnabled() {
+		return newSinkClient(username)
+	}
+	if httpDevice(username) {
+		return newHTTPClient(username)
+	}
+	server, family := nextBrokerTarget()
+	if AppConfig.MQTT.Engine == "lite" {
+		client := newLiteClient(clientID, username, server, stats)
+		client.node.family = family
+		return client
+	}
+	client := newPahoClient(clientID, username, server, stats, rng)
+	client.node.family = family
+	return client
+}
+
+// pahoClient 基于paho的客户端实现
+type pahoClient struct {
+	client mqtt.Client
+	node   *nodeTracker
+}
+
+// newPahoClient 创建paho客户端，按配置的重连方式自动重连
+func newPahoClient(clientID, username, server string, stats *DeviceStats, rng *rand.Rand) *pahoClient {
+	cfg := AppConfig.Reconnect
+	state := &reconnectState{rng: rng}
+	node := &nodeTracker{}
+	opts := mqtt.NewClientOptions().
+		SetClientID(clientID).
+		AddBroker(server).
+		SetUsername(username).
+		SetCleanSession(true).
+		SetAutoReconnect(cfg.Mode != "off").
+		SetKeepAlive(60 * time.Second).
+		SetMaxReconnectInterval(cfg.MaxInterval).
+		SetDialer(node.dialer(30 * time.Second)).
+		SetConnectionLostHandler(func(mqtt.Client, error) {
+			state.connectionLost()
+			node.disconnected()
+		}).
+		SetReconnectingHandler(func(mqtt.Client, *mqtt.ClientOptions) {
+			stats.recordReconnect()
+			time.Sleep(state.beforeAttempt())
+		}).
+		SetOnConnectHandler(func(mqtt.Client) {
+			state.connected()
+			node.connected()
+		})
+	if cfg.Mode == "backoff" {
+		// 等待时间由重连回调决定，paho内置的退避(从1秒开始翻倍)缩短到可以忽略
+		opts.SetMaxReconnectInterval(time.Millisecond)
+	}
+	if tlsConfig := deviceTLSConfig(username); tlsConfig != nil {
+		opts.SetTLSConfig(tlsConfig)
+	}
+
+	return &pahoClient{client: mqtt.NewClient(opts), node: node}
+}
+
+func (c *pahoClient) Connect() error {
+	token := c.client.Connect()
+	token.Wait()
+	return token.Error()
+}
+
+func (c *pahoClient) Publish(topic string, qos byte, payload []byte) error {
+	token := c.client.Publish(topic, qos, false, payload)
+	token.Wait()
+	c.node.recordPublish(token.Error())
+	return token.Error()
+}
+
+func (c *pahoClient) Subscribe(topic string, qos byte) (byte, error) {
+	token := c.client.Subscribe(topic, qos, func(_ mqtt.Client, msg mqtt.Message) {
+		onMessageReceived(c, msg.Topic(), msg.Payload())
+	})
+	token.Wait()
+	if err := token.Error(); err != nil {
+		return subackFailure, err
+	}
+	code, ok := token.(*mqtt.SubscribeToken).Result()[topic]
+	if !ok {
+		return subackFailure, fmt.Errorf(T("SUBACK中缺少主题 %s"), topic)
+	}
+	return code, nil
+}
+
+func (c *pahoClient) Disconnect() {
+	c.client.Disconnect(200)
+	c.node.disconnected()
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// 轻量客户端的网络超时
+const liteIOTimeout = 10 * time.Second
+
+// MQTT 报文类型
+const (
+	packetConnect    = 0x10
+	packetConnack    = 0x20
+	packetPublish    = 0x30
+	packetPuback     = 0x40
+	packetDisconnect = 0xE0
+)
+
+// liteClient 轻量MQTT 3.1.1/5客户端，仅支持QoS0/1发布
+//
+// 与paho不同，它不为每个连接启动读写goroutine，也不加锁：
+// 发布由设备goroutine同步完成，QoS1的PUBACK在同一goroutine中读取。
+// keepalive设为0(不启用)，因此不需要定时发送PINGREQ。
+// 连接断开后会在下一次发布时重连一次。
+type liteClient struct {
+	clientID string
+	username string
+	server   string
+	stats    *DeviceStats
+
+	tls      *tls.Config // 未启用TLS时为nil
+	conn     net.Conn
+	buf      []byte // 报文编码缓冲区，在连接内复用
+	rbuf     []byte // MQTT 5报文读取缓冲区
+	props    []byte // MQTT 5每条消息附带的属性，未启用时为nil
+	packetID uint16
+	lostAt   time.Time // 连接异常断开的时间
+	node     nodeTracker
+}
+
+// newLiteClient 创建轻量客户端
+func newLiteClient(clientID, username, server string, stats *DeviceStats) *liteClient {
+	return &liteClient{
+		clientID: clientID,
+		username: username,
+		server:   server,
+		stats:    stats,
+		tls:      deviceTLSConfig(username),
+		buf:      make([]byte, 0, 512),
+		props:    publishProperties(username),
+	}
+}
+
+// brokerAddress 去掉服务器地址中的协议前缀
+func brokerAddress(server string) string {
+	for _, prefix := range append([]string{"tcp://", "mqtt://"}, tlsSchemes...) {
+		server = strings.TrimPrefix(server, prefix)
+	}
+	return server
+}
+
+func (c *liteClient) Connect() error {
+	var conn net.Conn
+	var err error
+	dialer := c.node.dialer(liteIOTimeout)
+	if c.tls != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", brokerAddress(c.server), c.tls)
+	} else {
+		conn, err = dialer.Dial("tcp", brokerAddress(c.server))
+	}
+	if err != nil {
+		return fmt.Errorf(T("连接服务器失败: %w"), err)
+	}
+
+	// 可变报头: 协议名、协议级别、连接标志(清理会话+用户名)、keepalive，MQTT 5另有属性长度(无属性)
+	body := []byte{0, 4, 'M', 'Q', 'T', 'T', byte(AppConfig.MQTT.Version), 0x82, 0, 0}
+	if mqtt5Enabled() {
+		body = append(body, 0)
+	}
+	body = appendMQTTString(body, c.clientID)
+	body = appendMQTTString(body, c.username)
+	c.buf = appendPacket(c.buf[:0], packetConnect, body)
+
+	conn.SetDeadline(time.Now().Add(liteIOTimeout))
+	if _, err := conn.Write(c.buf); err != nil {
+		conn.Close()
+		return fmt.Errorf(T("发送CONNECT失败: %w"), err)
+	}
+
+	if mqtt5Enabled() {
+		if err := c.readConnack5(conn); err != nil {
+			conn.Close()
+			return err
+		}
+		conn.SetDeadline(time.Time{})
+		c.conn = conn
+		c.node.connectedTo(conn.RemoteAddr())
+		return nil
+	}
+
+	var connack [4]byte
+	if _, err := io.ReadFull(conn, connack[:]); err != nil {
+		conn.Close()
+		return fmt.Errorf(T("读取CONNACK失败: %w"), err)
+	}
+	if connack[0] != packetConnack || connack[1] != 2 {
+		conn.Close()
+		return fmt.Errorf(T("无效的CONNACK报文: % x"), connack)
+	}
+	if code := connack[3]; code != 0 {
+		conn.Close()
+		return &connectRefused{code: code}
+	}
+
+	conn.SetDeadline(time.Time{})
+	c.conn = conn
+	c.node.connectedTo(conn.RemoteAddr())
+	return nil
+}
+
+// Publish 发布消息，结果计入发布时所在的Broker节点
+func (c *liteClient) Publish(topic string, qos byte, payload []byte) error {
+	err := c.publish(topic, qos, payload)
+	c.node.recordPublish(err)
+	return err
+}
+
+func (c *liteClient) publish(topic string, qos byte, payload []byte) error {
+	if qos > 1 {
+		return errors.New(T("轻量客户端不支持QoS2"))
+	}
+
+	// 连接已断开时先重连
+	if c.conn == nil {
+		if AppConfig.Reconnect.Mode == "off" {
+			return errors.New(T("连接已断开"))
+		}
+		c.stats.recordReconnect()
+		err := c.Connect()
+		recordReconnectResult(c.lostAt, err)
+		if err != nil {
+			return err
+		}
+	}
+
+	// 报文: 主题、报文标识符(QoS1)、消息内容
+	c.buf = c.buf[:0]
+	header := byte(packetPublish) | qos<<1
+	remaining := 2 + len(topic) + len(payload)
+	if qos > 0 {
+		remaining += 2
+	}
+	if mqtt5Enabled() {
+		remaining += remainingLengthSize(len(c.props)) + len(c.props)
+	}
+	c.buf = append(c.buf, header)
+	c.buf = appendRemainingLength(c.buf, remaining)
+	c.buf = appendMQTTString(c.buf, topic)
+	if qos > 0 {
+		c.packetID++
+		if c.packetID == 0 {
+			c.packetID = 1
+		}
+		c.buf = binary.BigEndian.AppendUint16(c.buf, c.packetID)
+	}
+	if mqtt5Enabled() {
+		c.buf = appendRemainingLength(c.buf, len(c.props))
+		c.buf = append(c.buf, c.props...)
+	}
+	c.buf = append(c.buf, payload...)
+
+	c.conn.SetWriteDeadline(time.Now().Add(liteIOTimeout))
+	if _, err := c.conn.Write(c.buf); err != nil {
+		c.connLost()
+		return fmt.Errorf(T("发送PUBLISH失败: %w"), err)
+	}
+
+	if qos == 1 && mqtt5Enabled() {
+		return c.readPuback5()
+	}
+	if qos == 1 {
+		var puback [4]byte
+		c.conn.SetReadDeadline(time.Now().Add(liteIOTimeout))
+		if _, err := io.ReadFull(c.conn, puback[:]); err != nil {
+			c.connLost()
+			return fmt.Errorf(T("读取PUBACK失败: %w"), err)
+		}
+		if puback[0] != packetPuback || binary.BigEndian.Uint16(puback[2:]) != c.packetID {
+			c.connLost()
+			return fmt.Errorf(T("无效的PUBACK报文: % x"), puback)
+		}
+	}
+	return nil
+}
+
+// readConnack5 读取MQTT 5的CONNACK，原因码不小于0x80时表示拒绝连接
+func (c *liteClient) readConnack5(conn net.Conn) error {
+	header, body, err := readPacket(conn, c.rbuf)
+	if err != nil {
+		return fmt.Errorf(T("读取CONNACK失败: %w"), err)
+	}
+	c.rbuf = body[:0]
+	if header != packetConnack || len(body) < 2 {
+		return fmt.Errorf(T("无效的CONNACK报文: % x"), body)
+	}
+	if code := body[1]; code >= 0x80 {
+		return &connectRefused{code: code, v5: true}
+	}
+	return nil
+}
+
+// readPuback5 读取MQTT 5的PUBACK，剩余长度为2时原因码为0(成功)
+func (c *liteClient) readPuback5() error {
+	c.conn.SetReadDeadline(time.Now().Add(liteIOTimeout))
+	header, body, err := readPacket(c.conn, c.rbuf)
+	if err != nil {
+		c.connLost()
+		return fmt.Errorf(T("读取PUBACK失败: %w"), err)
+	}
+	c.rbuf = body[:0]
+	if header != packetPuback || len(body) < 2 || binary.BigEndian.Uint16(body) != c.packetID {
+		c.connLost()
+		return fmt.Errorf(T("无效的PUBACK报文: % x"), body)
+	}
+	if len(body) > 2 && body[2] >= 0x80 {
+		return &publishRejected{code: body[2]}
+	}
+	return nil
+}
+
+func (c *liteClient) Subscribe(topic string, qos byte) (byte, error) {
+	return subackFailure, errors.New(T("轻量客户端不支持订阅"))
+}
+
+func (c *liteClient) Disconnect() {
+	if c.conn == nil {
+		return
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(liteIOTimeout))
+	c.conn.Write([]byte{packetDisconnect, 0})
+	c.closeConn()
+}
+
+// closeConn 关闭连接
+func (c *liteClient) closeConn() {
+	c.conn.Close()
+	c.conn = nil
+	c.node.disconnected()
+}
+
+// connLost 连接异常时关闭连接并记录断开，下次发布时重连
+func (c *liteClient) connLost() {
+	c.closeConn()
+	c.lostAt = time.Now()
+	reconnectStats.lost.Add(1)
+}
+
+// appendPacket 追加完整报文(固定报头+剩余长度+报文体)
+func appendPacket(buf []byte, header byte, body []byte) []byte {
+	buf = append(buf, header)
+	buf = appendRemainingLength(buf, len(body))
+	return append(buf, body...)
+}
+
+// appendRemainingLength 按MQTT变长编码追加剩余长度
+func appendRemainingLength(buf []byte, n int) []byte {
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		buf = append(buf, b)
+		if n == 0 {
+			return buf
+		}
+	}
+}
+
+// appendMQTTString 追加带2字节长度前缀的UTF-8字符串
+func appendMQTTString(buf []byte, s string) []byte {
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(s)))
+	return append(buf, s...)
+}
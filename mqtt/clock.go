@@ -0,0 +1,16 @@
+package main
+
+import "time"
+
+// 时间计算约定:
+// 所有耗时和速率均通过 time.Now() 返回值的 Sub/Since/Until 计算，这些值携带单调时钟读数，
+// 不受NTP校时或手动修改系统时间影响。不要使用 Unix()/UnixNano() 等墙上时间做差，
+// 也不要对参与计算的时间调用 Round(0)/序列化后再比较，否则会丢失单调时钟读数。
+
+// ratePerSecond 计算每秒速率，耗时非正时返回0，避免除零或异常时钟导致的无意义速率
+func ratePerSecond(count float64, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return count / elapsed.Seconds()
+}
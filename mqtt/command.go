@@ -0,0 +1,314 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 命令下发压测命令行参数
+var (
+	commandDevices     = flag.Int("command-devices", 0, "命令下发压测：同时通过平台API下发命令的设备数(token文件中前N个使用MQTT的设备)，设备收到后通过MQTT回复，0为不启用")
+	commandRounds      = flag.Int("command-rounds", 0, "命令下发压测：轮数，每轮给每个设备下发一条命令，上一轮的接口全部返回后开始下一轮(默认1)")
+	commandConcurrency = flag.Int("command-concurrency", 0, "命令下发压测：同时进行的API请求数上限(默认等于设备数)")
+)
+
+// 命令下发压测的默认配置，对应ThingsPanel的命令下发接口和设备协议
+const (
+	defaultCommandPath          = "/api/v1/command/datas/pub"
+	defaultCommandBody          = `{"device_id":"{device_id}","identify":"{identify}","value":{value}}`
+	defaultCommandIdentify      = "perf_command"
+	defaultCommandKey           = "perf_request_id"
+	defaultCommandTopic         = "devices/command/{device_id}/+"
+	defaultCommandResponseTopic = "devices/command/response/{message_id}"
+)
+
+// commandRequest 一次命令下发请求，各时间为零值表示还没有发生
+type commandRequest struct {
+	device    string
+	sent      time.Time // 调用API的时间
+	delivered time.Time // 设备收到命令的时间
+	acked     time.Time // 设备回复发布完成的时间
+	returned  time.Time // API返回的时间
+	apiErr    error
+}
+
+// commandFlood 命令下发压测的状态和统计
+var commandFlood struct {
+	targets map[string]string // 接收命令的设备token -> 设备ID
+	devices []string          // 设备ID
+
+	seq     atomic.Uint64
+	mu      sync.Mutex
+	pending map[uint64]*commandRequest // 请求ID -> 请求
+	lastAt  time.Time                  // 最后一次请求的时间
+	done    chan struct{}              // 全部轮次的API请求返回后关闭，未开始时为nil
+
+	duplicates atomic.Uint64 // 设备重复收到的次数
+	unknown    atomic.Uint64 // 设备收到的无法关联到请求的命令
+	ackFailed  atomic.Uint64 // 设备回复失败的次数
+
+	apiLatency     Histogram // API请求耗时
+	deliverLatency Histogram // 发出请求到设备收到的耗时
+	ackLatency     Histogram // 发出请求到设备回复完成的耗时
+}
+
+// commandFloodEnabled 是否进行命令下发压测
+func commandFloodEnabled() bool {
+	return AppConfig.Command.Devices > 0
+}
+
+// initCommandFlood 选择接收命令的设备并查询其设备ID
+func initCommandFlood(tokens *TokenList) error {
+	if !commandFloodEnabled() {
+		return nil
+	}
+	targets, devices, missing, err := downlinkDevices(tokens, AppConfig.Command.Devices)
+	if err != nil {
+		return fmt.Errorf(T("命令下发压测: %w"), err)
+	}
+	if missing > 0 {
+		log.Printf(T("警告: 命令下发压测: %d 个token在数据库中找不到设备，不参与测试"), missing)
+	}
+	commandFlood.targets, commandFlood.devices = targets, devices
+	commandFlood.pending = make(map[uint64]*commandRequest)
+	log.Printf(T("命令下发压测: %d 个设备, %d 轮, 并发 %d"),
+		len(commandFlood.devices), AppConfig.Command.Rounds, min(AppConfig.Command.Concurrency, len(commandFlood.devices)))
+	return nil
+}
+
+// subscribeCommand 参与压测的设备订阅命令主题
+func subscribeCommand(client DeviceClient, username string) error {
+	id, ok := commandFlood.targets[username]
+	if !ok {
+		return nil
+	}
+	topic := strings.NewReplacer("{username}", username, "{device_id}", id).Replace(AppConfig.Command.Topic)
+	code, err := client.Subscribe(topic, byte(AppConfig.MQTT.QoS))
+	if err != nil {
+		return fmt.Errorf(T("订阅命令主题 %s 失败: %w"), topic, err)
+	}
+	if code == subackFailure {
+		return fmt.Errorf(T("服务器拒绝订阅命令主题 %s (SUBACK返回码 0x80)"), topic)
+	}
+	return nil
+}
+
+// startCommandFlood 开始发送数据时启动命令下发压测
+//
+// 每轮同时给所有设备下发一条命令(受并发上限约束)，本轮的API请求全部返回后开始下一轮，
+// 直到完成全部轮次或ctx结束。
+func startCommandFlood(ctx context.Context) {
+	if !commandFloodEnabled() {
+		return
+	}
+	commandFlood.done = make(chan struct{})
+	annotate("stage", T("开始命令下发压测"))
+	go func() {
+		defer close(commandFlood.done)
+		sem := make(chan struct{}, AppConfig.Command.Concurrency)
+		for round := 0; round < AppConfig.Command.Rounds; round++ {
+			var wg sync.WaitGroup
+			for _, device := range commandFlood.devices {
+				select {
+				case <-ctx.Done():
+					wg.Wait()
+					return
+				case sem <- struct{}{}:
+				}
+				seq := commandFlood.seq.Add(1)
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					defer func() { <-sem }()
+					sendCommand(seq, device)
+				}()
+			}
+			wg.Wait()
+		}
+	}()
+}
+
+// sendCommand 通过平台API下发一条命令，命令参数中带有请求ID，用于关联设备收到的消息和回复
+func sendCommand(seq uint64, device string) {
+	cfg := AppConfig.Command
+	value, _ := json.Marshal(fmt.Sprintf(`{"%s":%d}`, cfg.Key, seq))
+	body := strings.NewReplacer("{device_id}", device, "{identify}", cfg.Identify, "{value}", string(value)).Replace(cfg.Body)
+
+	req := &commandRequest{device: device, sent: time.Now()}
+	commandFlood.mu.Lock()
+	commandFlood.pending[seq] = req
+	commandFlood.lastAt = req.sent
+	commandFlood.mu.Unlock()
+
+	_, _, err := apiCall(context.Background(), "POST", cfg.Path, []byte(body))
+	now := time.Now()
+	commandFlood.apiLatency.Record(now.Sub(req.sent))
+	commandFlood.mu.Lock()
+	req.returned, req.apiErr = now, err
+	commandFlood.mu.Unlock()
+	if err != nil {
+		logErrorf(T("命令下发压测: 设备 %s: %v"), device, err)
+	}
+}
+
+// handleCommand 处理设备收到的命令：关联到请求并通过MQTT回复，不是本压测下发的消息时返回false
+//
+// 回复在单独的goroutine中发布，不阻塞客户端的消息分发。
+func handleCommand(client DeviceClient, topic string, payload []byte) bool {
+	if !commandFloodEnabled() {
+		return false
+	}
+	seq, ok := payloadSeq(payload, AppConfig.Command.Key)
+	if !ok {
+		return false
+	}
+	now := time.Now()
+	commandFlood.mu.Lock()
+	req := commandFlood.pending[seq]
+	duplicate := req != nil && !req.delivered.IsZero()
+	if req != nil && !duplicate {
+		req.delivered = now
+	}
+	commandFlood.mu.Unlock()
+	switch {
+	case req == nil:
+		commandFlood.unknown.Add(1)
+		return true
+	case duplicate:
+		commandFlood.duplicates.Add(1)
+		return true
+	}
+	commandFlood.deliverLatency.Record(now.Sub(req.sent))
+
+	// 主题最后一级为平台生成的消息ID，回复主题中需要带上
+	messageID := topic[strings.LastIndex(topic, "/")+1:]
+	response := strings.ReplaceAll(AppConfig.Command.ResponseTopic, "{message_id}", messageID)
+	ack := fmt.Appendf(nil, `{"result":0,"message":"success","ts":%d,"method":%q}`, now.Unix(), AppConfig.Command.Identify)
+	go func() {
+		if err := client.Publish(response, byte(AppConfig.MQTT.QoS), ack); err != nil {
+			commandFlood.ackFailed.Add(1)
+			logErrorf(T("命令下发压测: 回复 %s 失败: %v"), response, err)
+			return
+		}
+		acked := time.Now()
+		commandFlood.ackLatency.Record(acked.Sub(req.sent))
+		commandFlood.mu.Lock()
+		req.acked = acked
+		commandFlood.mu.Unlock()
+	}()
+	return true
+}
+
+// timeoutError 判断API请求是否因超时失败
+func timeoutError(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// commandOutcome 命令下发压测的结果分类
+type commandOutcome struct {
+	sent        uint64
+	succeeded   uint64 // API成功且设备在超时时间内回复
+	apiTimeout  uint64 // API请求超时
+	apiFailed   uint64 // API返回错误(不含超时)
+	lost        uint64 // 设备没有收到
+	late        uint64 // 设备收到或回复超过了超时时间
+	unacked     uint64 // 设备收到但没有回复成功
+	ackBeforeOK uint64 // API在设备回复之后才返回的请求数，说明接口同步等待设备响应
+}
+
+// classifyCommands 按请求ID逐条关联API响应和设备回复，统计各类结果
+func classifyCommands() commandOutcome {
+	timeout := AppConfig.Command.Timeout
+	commandFlood.mu.Lock()
+	defer commandFlood.mu.Unlock()
+	var o commandOutcome
+	for _, req := range commandFlood.pending {
+		o.sent++
+		switch {
+		case req.apiErr != nil && timeoutError(req.apiErr):
+			o.apiTimeout++
+		case req.apiErr != nil:
+			o.apiFailed++
+		}
+		switch {
+		case req.delivered.IsZero():
+			o.lost++
+		case req.acked.IsZero():
+			o.unacked++
+		case req.acked.Sub(req.sent) > timeout:
+			o.late++
+		case req.apiErr == nil:
+			o.succeeded++
+		}
+		if !req.acked.IsZero() && !req.returned.IsZero() && req.returned.After(req.acked) {
+			o.ackBeforeOK++
+		}
+	}
+	return o
+}
+
+// reportCommandFlood 等待全部轮次和设备回复，输出命令下发压测的统计(未启用时不输出)
+func reportCommandFlood() {
+	if !commandFloodEnabled() || commandFlood.done == nil {
+		return
+	}
+	<-commandFlood.done
+	// 最后一次请求后最多等待超时时间，让设备收到并回复剩余的命令
+	for {
+		commandFlood.mu.Lock()
+		last, waiting := commandFlood.lastAt, false
+		for _, req := range commandFlood.pending {
+			if req.acked.IsZero() {
+				waiting = true
+				break
+			}
+		}
+		commandFlood.mu.Unlock()
+		if !waiting || time.Since(last) >= AppConfig.Command.Timeout {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	time.Sleep(100 * time.Millisecond) // 等待最后的回复发布完成
+
+	o := classifyCommands()
+	log.Println(T("\n========== 命令下发压测(平台API→设备→回复) =========="))
+	log.Printf(T("设备: %d 个, 轮数: %d, 并发: %d, 请求: %d"),
+		len(commandFlood.devices), AppConfig.Command.Rounds, min(AppConfig.Command.Concurrency, len(commandFlood.devices)), o.sent)
+	log.Printf(T("成功(接口成功且设备在 %v 内回复): %d (%.2f%%)"), AppConfig.Command.Timeout, o.succeeded, percent(o.succeeded, o.sent))
+	log.Printf(T("接口超时: %d (%.2f%%), 接口失败: %d (%.2f%%)"),
+		o.apiTimeout, percent(o.apiTimeout, o.sent), o.apiFailed, percent(o.apiFailed, o.sent))
+	log.Printf(T("设备未收到: %d (%.2f%%), 收到未回复: %d, 回复超时: %d (%.2f%%)"),
+		o.lost, percent(o.lost, o.sent), o.unacked, o.late, percent(o.late, o.sent))
+	log.Printf(T("重复收到: %d, 无法关联: %d, 回复发布失败: %d"),
+		commandFlood.duplicates.Load(), commandFlood.unknown.Load(), commandFlood.ackFailed.Load())
+	log.Printf(T("接口在设备回复之后返回: %d (%.2f%%)"), o.ackBeforeOK, percent(o.ackBeforeOK, o.sent))
+	for _, h := range []struct {
+		name string
+		h    *Histogram
+	}{
+		{T("API耗时"), &commandFlood.apiLatency},
+		{T("下发到设备收到"), &commandFlood.deliverLatency},
+		{T("下发到设备回复"), &commandFlood.ackLatency},
+	} {
+		if h.h.Count() > 0 {
+			log.Printf(T("%s: 平均 %v, p50 %v, p99 %v, 最大 %v"),
+				h.name, h.h.Mean(), h.h.Quantile(0.50), h.h.Quantile(0.99), h.h.Max())
+		}
+	}
+	if o.sent > 0 && o.lost == o.sent {
+		log.Printf(T("设备没有收到任何命令，请检查下发接口(%s)、设备订阅的主题(%s)和Broker的ACL"),
+			AppConfig.Command.Path, AppConfig.Command.Topic)
+	}
+	log.Println("===============================")
+}
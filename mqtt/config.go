@@ -3,49 +3,448 @@ package main
 import (
 	"flag"
 	"log"
+	"net"
 	"os"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"test/internal/pgdsn"
 )
 
 // Config 应用程序配置
 type Config struct {
+	Lang string `yaml:"lang"` // 输出语言(zh/en)
+
 	Device struct {
-		TokenFile    string `yaml:"token_file"`    // 设备token文件路径
-		ClientNumber int    `yaml:"client_number"` // 模拟连接的设备数量
+		TokenFile       string `yaml:"token_file"`       // 设备token文件路径
+		ClientNumber    int    `yaml:"client_number"`    // 模拟连接的设备数量
+		TokenMmap       bool   `yaml:"token_mmap"`       // 是否使用mmap映射token文件
+		DuplicateTokens string `yaml:"duplicate_tokens"` // 重复token的处理方式(dedupe, error, allow)
+		Filter          string `yaml:"filter"`           // 设备筛选条件，如 "range:1-1000;every:10"
 	} `yaml:"device"`
 
 	MQTT struct {
-		Server string `yaml:"server"` // MQTT服务器地址
-		QoS    int    `yaml:"qos"`    // MQTT服务质量(0,1,2)
-		Topic  string `yaml:"topic"`  // 发布主题
+		Server string `yaml:"server"`  // MQTT服务器地址
+		QoS    int    `yaml:"qos"`     // MQTT服务质量(0,1,2)
+		Topic  string `yaml:"topic"`   // 发布主题
+		Engine string `yaml:"engine"`  // 客户端引擎(paho/lite)
+		QoSMix string `yaml:"qos_mix"` // 按比例为设备分配QoS，如 "0:60,1:35,2:5"(覆盖qos)
+
+		Nodes map[string]string `yaml:"nodes"` // Broker节点地址(IP:端口)到节点名称的映射，用于节点分布报告
+
+		Version    int `yaml:"version"` // 协议版本(4: 3.1.1, 5: MQTT 5，仅lite引擎)
+		Properties struct {
+			ContentType string         `yaml:"content_type"` // 每条消息的内容类型属性
+			User        []UserProperty `yaml:"user"`         // 每条消息附带的用户属性
+		} `yaml:"properties"` // MQTT 5消息属性
 	} `yaml:"mqtt"`
 
+	LocalBroker struct {
+		Enabled bool   `yaml:"enabled"` // 在进程内启动MQTT Broker并连接它(忽略mqtt.server)，用于没有外部环境时验证和测量本工具
+		Listen  string `yaml:"listen"`  // 监听地址，端口为0时随机选择
+	} `yaml:"local_broker"`
+
+	Sink struct {
+		Mode string `yaml:"mode"` // 消息不发送到Broker而由本地接收端处理(null: 丢弃, file: 写入文件)，为空时正常发送
+		File string `yaml:"file"` // file接收端的输出文件，相对路径位于运行目录下
+	} `yaml:"sink"`
+
+	Resolve struct {
+		Mode       string   `yaml:"mode"`        // Broker域名解析方式(per-connection/once)
+		PinIPs     []string `yaml:"pin_ips"`     // 只连接这些IP，设备轮流使用(不再解析域名)
+		DNSServers []string `yaml:"dns_servers"` // 解析域名使用的DNS服务器(host:port)，为空时使用系统配置
+		Family     string   `yaml:"family"`      // 连接使用的地址族(any/v4/v6/dual)
+	} `yaml:"resolve"`
+
+	TLS struct {
+		Enabled            bool          `yaml:"enabled"`              // 使用TLS连接服务器(服务器地址为ssl://时自动启用)
+		CAFile             string        `yaml:"ca_file"`              // 校验服务器证书的CA文件，为空时使用系统根证书
+		InsecureSkipVerify bool          `yaml:"insecure_skip_verify"` // 不校验服务器证书(仅用于测试环境)
+		CertDir            string        `yaml:"cert_dir"`             // 设备证书目录(<token>.crt/<token>.key)，为空时不使用客户端证书
+		GenerateCerts      bool          `yaml:"generate_certs"`       // 缺少设备证书时用测试CA(cert_dir下的ca.crt/ca.key)签发
+		RotateInterval     time.Duration `yaml:"rotate_interval"`      // 测试中重新签发证书并重连的间隔，0为不轮换
+	} `yaml:"tls"`
+
+	Prime struct {
+		Enabled     bool `yaml:"enabled"`     // 正式测试前逐个认证全部设备一次，预热平台的认证缓存
+		Rate        int  `yaml:"rate"`        // 每秒认证的设备数
+		Concurrency int  `yaml:"concurrency"` // 同时进行的认证连接数上限
+	} `yaml:"prime"`
+
+	Takeover struct {
+		Enabled bool    `yaml:"enabled"` // 会话接管场景：测试中途用相同的客户端ID为设备再建立一个连接
+		Cycle   int     `yaml:"cycle"`   // 在第几个循环进行接管(默认为总循环数的一半)
+		Ratio   float64 `yaml:"ratio"`   // 参与接管的设备比例(%)
+	} `yaml:"takeover"`
+
+	CredRotation struct {
+		Devices       int           `yaml:"devices"`        // 凭证轮换场景：测试中途更换凭证的设备数，0为不启用
+		Cycle         int           `yaml:"cycle"`          // 在第几个循环更换凭证(默认为总循环数的一半)
+		Method        string        `yaml:"method"`         // 更换凭证的方式: db(直接更新数据库) 或 api(调用平台API)
+		Path          string        `yaml:"path"`           // method为api时的设备更新接口路径(PUT)
+		Body          string        `yaml:"body"`           // method为api时的请求体，{device_id}替换为设备ID，{voucher}替换为新凭证(JSON字符串)
+		ProbeInterval time.Duration `yaml:"probe_interval"` // 用新旧凭证探测连接的间隔
+		Timeout       time.Duration `yaml:"timeout"`        // 探测的最长时间，超时后恢复原凭证
+	} `yaml:"cred_rotation"`
+
+	FleetShrink struct {
+		Ratio         float64       `yaml:"ratio"`          // 设备删除场景：测试中途删除的设备比例(%)，0为不启用
+		Cycle         int           `yaml:"cycle"`          // 在第几个循环删除设备(默认为总循环数的一半)
+		Method        string        `yaml:"method"`         // 删除方式: db(直接删除devices表中的行) 或 api(调用平台API)
+		Path          string        `yaml:"path"`           // method为api时的设备删除接口路径(DELETE)，{device_id}替换为设备ID
+		ProbeInterval time.Duration `yaml:"probe_interval"` // 用被删除设备的凭证探测连接的间隔
+		Timeout       time.Duration `yaml:"timeout"`        // 探测的最长时间
+		Keep          bool          `yaml:"keep"`           // 测试结束后不恢复db方式删除的设备
+	} `yaml:"fleet_shrink"`
+
+	FleetGrowth struct {
+		Rate         int           `yaml:"rate"`          // 设备接入场景：测试中途每分钟接入的新设备数，0为不启用
+		Source       string        `yaml:"source"`        // 新设备的来源: file(监视token文件中新追加的行) 或 db(定期查询新创建的设备)
+		TokenFile    string        `yaml:"token_file"`    // source为file时监视的token文件(默认为device.token_file)
+		PollInterval time.Duration `yaml:"poll_interval"` // 查找新设备的间隔
+		Max          int           `yaml:"max"`           // 最多接入的设备数，0为不限制
+	} `yaml:"fleet_growth"`
+
+	Provision struct {
+		Enabled     bool   `yaml:"enabled"`     // 动态注册模式：设备连接前通过一型一密动态注册接口获取凭证，不使用token文件
+		Secret      string `yaml:"secret"`      // 设备配置的一型一密密钥
+		Path        string `yaml:"path"`        // 动态注册接口路径(POST)
+		Body        string `yaml:"body"`        // 请求体，{secret}、{device_number}、{device_name}替换为密钥、设备编号和设备名称
+		Prefix      string `yaml:"prefix"`      // 设备编号前缀，设备编号为前缀加6位序号
+		Concurrency int    `yaml:"concurrency"` // 同时进行的注册请求数上限，0为不限制
+	} `yaml:"provision"`
+
+	Offline struct {
+		Enabled    bool    `yaml:"enabled"`     // 离线缓存场景：部分设备离线若干循环，重连后一次性补发缓存的数据
+		StartCycle int     `yaml:"start_cycle"` // 设备在第几个循环离线(默认为总循环数的三分之一)
+		Cycles     int     `yaml:"cycles"`      // 离线持续的循环数(默认为总循环数的三分之一)
+		Ratio      float64 `yaml:"ratio"`       // 参与离线的设备比例(%)
+		TimeKey    string  `yaml:"time_key"`    // 缓存消息中毫秒时间戳的字段名，为空时不加时间戳
+	} `yaml:"offline"`
+
+	Anomaly struct {
+		Ratio      float64       `yaml:"ratio"`       // 注入异常的设备比例(%)，0为不注入
+		Cycles     []int         `yaml:"cycles"`      // 注入异常的循环(默认为总循环数的一半)
+		Key        string        `yaml:"key"`         // 跳变的数据点，为空时使用消息中的第一个数值数据点
+		Jump       float64       `yaml:"jump"`        // 异常值相对正常值的跳变量(默认为数值范围的10倍)
+		Hold       int           `yaml:"hold"`        // 异常值持续的循环数，1为单点尖峰，更大时为阶跃后恢复
+		AlarmQuery string        `yaml:"alarm_query"` // 查询告警产生时间的SQL，$1为起始时间(timestamptz)，返回一列时间
+		Timeout    time.Duration `yaml:"timeout"`     // 最后一次注入后等待告警产生的时长
+	} `yaml:"anomaly"`
+
+	HTTP struct {
+		Ratio   float64           `yaml:"ratio"`   // 以HTTP POST上报遥测的设备比例(%)，其余设备使用MQTT，0为不启用
+		URL     string            `yaml:"url"`     // 上报地址，{username}替换为设备token
+		Timeout time.Duration     `yaml:"timeout"` // 单次请求超时
+		Headers map[string]string `yaml:"headers"` // 附加的请求头
+	} `yaml:"http"`
+
+	API struct {
+		URL     string            `yaml:"url"`     // 平台API地址，如 http://127.0.0.1:9999
+		Headers map[string]string `yaml:"headers"` // 附加的请求头，如 x-api-key 或 x-token
+		Timeout time.Duration     `yaml:"timeout"` // 单次请求超时
+	} `yaml:"api"`
+
+	AttrSet struct {
+		Rate          float64       `yaml:"rate"`           // 每秒通过平台API下发的属性设置请求数，0为不启用
+		Devices       int           `yaml:"devices"`        // 接收属性设置的设备数(token文件中前N个使用MQTT的设备)
+		Path          string        `yaml:"path"`           // 属性下发接口的路径
+		Body          string        `yaml:"body"`           // 请求体，{device_id}替换为设备ID，{value}替换为属性对象的JSON字符串
+		Key           string        `yaml:"key"`            // 下发的属性标识符，值为请求序号，用于关联设备收到的消息
+		Topic         string        `yaml:"topic"`          // 设备订阅的属性设置主题，支持{username}和{device_id}
+		ResponseTopic string        `yaml:"response_topic"` // 设备回复的主题，{message_id}替换为收到的主题的最后一级
+		AckTimeout    time.Duration `yaml:"ack_timeout"`    // 测试结束后等待设备收到剩余请求的最长时间
+	} `yaml:"attr_set"`
+
+	Command struct {
+		Devices       int           `yaml:"devices"`        // 同时下发命令的设备数(token文件中前N个使用MQTT的设备)，0为不启用
+		Rounds        int           `yaml:"rounds"`         // 轮数，每轮给每个设备下发一条命令
+		Concurrency   int           `yaml:"concurrency"`    // 同时进行的API请求数上限，0为等于设备数
+		Path          string        `yaml:"path"`           // 命令下发接口的路径
+		Body          string        `yaml:"body"`           // 请求体，{device_id}替换为设备ID，{identify}替换为命令标识符，{value}替换为命令参数的JSON字符串
+		Identify      string        `yaml:"identify"`       // 命令标识符
+		Key           string        `yaml:"key"`            // 命令参数中的请求ID字段名，用于关联设备收到的命令
+		Topic         string        `yaml:"topic"`          // 设备订阅的命令主题，支持{username}和{device_id}
+		ResponseTopic string        `yaml:"response_topic"` // 设备回复的主题，{message_id}替换为收到的主题的最后一级
+		Timeout       time.Duration `yaml:"timeout"`        // 设备在下发后多长时间内回复计为成功，也是测试结束后等待回复的最长时间
+	} `yaml:"command"`
+
+	HistoryQuery struct {
+		Ratio         float64       `yaml:"ratio"`          // 每条写入消息对应的查询数(读:写)，0为不启用
+		Concurrency   int           `yaml:"concurrency"`    // 同时进行的查询数上限，达到上限时跳过查询
+		Devices       int           `yaml:"devices"`        // 查询的设备数(token文件中前N个设备)
+		Window        time.Duration `yaml:"window"`         // 查询的时间范围(当前时间往前)
+		Aggregate     float64       `yaml:"aggregate"`      // 聚合查询所占比例(0-1)，其余为历史数据查询
+		Key           string        `yaml:"key"`            // 查询的数据点名称，为空时使用各设备的第一个数据点
+		HistoryPath   string        `yaml:"history_path"`   // 历史数据查询接口，支持{device_id}、{key}、{start_time}和{end_time}(毫秒)
+		AggregatePath string        `yaml:"aggregate_path"` // 聚合查询接口，占位符同上
+	} `yaml:"history_query"`
+
+	Dashboards struct {
+		Count   int               `yaml:"count"`   // 模拟的WebSocket看板连接数，0为不启用
+		URL     string            `yaml:"url"`     // 看板地址(ws://或wss://)，{username}和{device_id}替换为所看设备的token和ID
+		Message string            `yaml:"message"` // 连接后发送的订阅消息，支持{username}、{device_id}和{token}
+		Token   string            `yaml:"token"`   // 平台用户的登录令牌，替换订阅消息中的{token}
+		Headers map[string]string `yaml:"headers"` // 握手时附加的请求头，支持同样的占位符
+		Timeout time.Duration     `yaml:"timeout"` // 连接和握手超时
+
+		LatencyEvery int    `yaml:"latency_every"` // 被看板订阅的设备每N条消息附加一个序号数据点，测量端到端延迟，0为不启用
+		LatencyKey   string `yaml:"latency_key"`   // 序号数据点的名称
+	} `yaml:"dashboards"`
+
+	DeviceStates struct {
+		Enabled           bool    `yaml:"enabled"`             // 设备状态机：每个设备在正常、降级、离线状态之间按转移概率切换
+		NormalToDegraded  float64 `yaml:"normal_to_degraded"`  // 每个循环从正常转为降级的概率(%)
+		NormalToOffline   float64 `yaml:"normal_to_offline"`   // 每个循环从正常转为离线的概率(%)
+		DegradedToNormal  float64 `yaml:"degraded_to_normal"`  // 每个循环从降级恢复正常的概率(%)
+		DegradedToOffline float64 `yaml:"degraded_to_offline"` // 每个循环从降级转为离线的概率(%)
+		OfflineToNormal   float64 `yaml:"offline_to_normal"`   // 每个循环从离线恢复正常的概率(%)
+		OfflineToDegraded float64 `yaml:"offline_to_degraded"` // 每个循环从离线恢复为降级的概率(%)
+		DegradedEvery     int     `yaml:"degraded_every"`      // 降级状态下每N个循环上报一次
+		ErrorKey          string  `yaml:"error_key"`           // 降级状态下消息中附加的错误码字段名
+	} `yaml:"device_states"`
+
+	Heartbeat struct {
+		Ratio    float64       `yaml:"ratio"`    // 只发送心跳的设备比例(%)，0为不启用
+		Interval time.Duration `yaml:"interval"` // 心跳发送间隔
+		Topic    string        `yaml:"topic"`    // 心跳主题，{username}替换为设备token
+		Payload  string        `yaml:"payload"`  // 心跳消息模板，占位符与streams相同
+	} `yaml:"heartbeat"`
+
+	DeviceInfo struct {
+		Enabled bool              `yaml:"enabled"` // 连接成功后上报一次设备信息
+		Topic   string            `yaml:"topic"`   // 设备信息主题，{username}替换为设备token
+		Models  []string          `yaml:"models"`  // 随机选取的设备型号
+		Extra   map[string]string `yaml:"extra"`   // 附加的固定字段，{username}替换为设备token
+	} `yaml:"device_info"`
+
+	Subscribe struct {
+		Topics []string `yaml:"topics"` // 设备订阅的主题，{username}替换为设备token
+		QoS    int      `yaml:"qos"`    // 订阅的服务质量(0,1,2)
+	} `yaml:"subscribe"`
+
+	Streams []StreamConfig `yaml:"streams"` // 每个周期额外发布的数据流
+
+	TenantGroups []TenantGroupConfig `yaml:"tenant_groups"` // 多租户分组：同一次运行中相互独立的多组设备，各组分别统计
+
+	Consumers struct {
+		Count    int    `yaml:"count"`    // 模拟平台消费者数量(0为不启用)
+		Group    string `yaml:"group"`    // 共享订阅组名，为空时每个消费者独立订阅全部消息
+		Topic    string `yaml:"topic"`    // 订阅主题(默认为mqtt.topic)
+		QoS      int    `yaml:"qos"`      // 订阅的服务质量(0,1,2)
+		Username string `yaml:"username"` // 消费者连接用户名
+		Password string `yaml:"password"` // 消费者连接密码
+	} `yaml:"consumers"`
+
 	Test struct {
 		DataInterval    time.Duration `yaml:"data_interval"`     // 数据上报间隔时间
 		CycleCount      int           `yaml:"cycle_count"`       // 测试循环次数
 		ConnectWaitTime time.Duration `yaml:"connect_wait_time"` // 连接等待时间
+		Seed            int64         `yaml:"seed"`              // 随机数种子(0表示随机)
+		CycleBarrier    bool          `yaml:"cycle_barrier"`     // 每个循环等待所有设备发送完成并报告落后设备
+		BarrierTimeout  time.Duration `yaml:"barrier_timeout"`   // 等待设备发送完成的超时时间(默认等于发送间隔)
+		Stages          string        `yaml:"stages"`            // 分阶段负载，如 "warmup:100x1s,20k:300x50ms"(覆盖cycle_count和data_interval)
 	} `yaml:"test"`
 
 	Data struct {
 		MinValue       float64 `yaml:"min_value"`        // 传感器数据最小值
 		MaxValue       float64 `yaml:"max_value"`        // 传感器数据最大值
 		DataPointCount int     `yaml:"data_point_count"` // 每条消息包含的数据点数量
+		PayloadMode    string  `yaml:"payload_mode"`     // 消息生成模式(json/template)
+		KeyTemplate    string  `yaml:"key_template"`     // 数据点名称模板，{i}为序号，{device}为设备token
+		Profile        string  `yaml:"profile"`          // 工业网关消息格式(modbus/dlt645)，为空时按数据点名称模板生成同类数据点
+
+		Fields []FieldConfig `yaml:"fields"` // 用表达式定义的数据点，配置后每条消息只包含这些数据点
 	} `yaml:"data"`
 
+	Strings struct {
+		Count       int    `yaml:"count"`        // 每条消息附加的字符串数据点数量，0为不附加
+		Kinds       string `yaml:"kinds"`        // 字符串内容类型，逗号分隔(ascii, cjk, emoji, escape, mixed)，各数据点轮流使用
+		MinLength   int    `yaml:"min_length"`   // 最短长度(字符数)
+		MaxLength   int    `yaml:"max_length"`   // 最长长度(字符数)
+		LimitLength int    `yaml:"limit_length"` // 平台的字符串长度上限，一半的数据点取上限-1、上限、上限+1的长度，0为不测试
+	} `yaml:"strings"`
+
+	Numeric struct {
+		Precision       int     `yaml:"precision"`        // 数值保留的小数位数，0为不限制(按float64最短表示)
+		SpecialRatio    float64 `yaml:"special_ratio"`    // 以字符串"NaN"、"Infinity"、"-Infinity"发送的比例(%)
+		ExtremeRatio    float64 `yaml:"extreme_ratio"`    // 以极大/极小数值字面量(如1.7976931348623157e308、5e-324、2^53+1)发送的比例(%)
+		IntegerRatio    float64 `yaml:"integer_ratio"`    // 以整数发送的比例(%)
+		ScientificRatio float64 `yaml:"scientific_ratio"` // 以科学计数法(如2.35e+01)发送的比例(%)
+	} `yaml:"numeric"`
+
+	Counter struct {
+		Enabled      bool    `yaml:"enabled"`       // 累计量模式：数据点单调递增，初始示值在min_value~max_value内随机
+		MinIncrement float64 `yaml:"min_increment"` // 每条消息的最小增量
+		MaxIncrement float64 `yaml:"max_increment"` // 每条消息的最大增量
+		ResetChance  float64 `yaml:"reset_chance"`  // 每个数据点每条消息清零的概率(%)，模拟换表和设备重启
+		Rollover     float64 `yaml:"rollover"`      // 示值到达该值后从0重新累计，模拟计数器位数溢出，0为不翻转
+	} `yaml:"counter"`
+
+	GPS struct {
+		Routes   string  `yaml:"routes"`    // GeoJSON路线文件，设备沿其中的LineString循环行驶
+		BBox     string  `yaml:"bbox"`      // 未指定路线时随机行驶的范围(最小经度,最小纬度,最大经度,最大纬度)
+		MinSpeed float64 `yaml:"min_speed"` // 最低速度(km/h)
+		MaxSpeed float64 `yaml:"max_speed"` // 最高速度(km/h)
+		LatKey   string  `yaml:"lat_key"`   // 纬度数据点名称
+		LngKey   string  `yaml:"lng_key"`   // 经度数据点名称
+		SpeedKey string  `yaml:"speed_key"` // 速度数据点名称
+	} `yaml:"gps"`
+
 	Database struct {
-		Host     string `yaml:"host"`     // 数据库服务器地址和端口
+		Host     string `yaml:"host"`     // 数据库服务器地址和端口，或Unix域套接字目录(如 /var/run/postgresql:5432)
 		User     string `yaml:"user"`     // 数据库用户名
 		Password string `yaml:"password"` // 数据库密码
 		Name     string `yaml:"name"`     // 数据库名称
 		SSLMode  string `yaml:"ssl_mode"` // 数据库SSL模式
+		DSN      string `yaml:"dsn"`      // 完整连接字符串(URL或libpq关键字格式)，设置后忽略以上各项
+
+		PgBouncer bool `yaml:"pgbouncer"` // 通过pgbouncer(事务池模式)连接：不使用预备语句，不在连接参数中设置SQL超时
+
+		ReplicaHost string `yaml:"replica_host"` // 只读副本地址和端口(账号和数据库名与主库相同)，监控和核对查询使用，为空时使用主库
+
+		MaxConns         int           `yaml:"max_conns"`         // 连接池最大连接数
+		MaxIdleConns     int           `yaml:"max_idle_conns"`    // 连接池最大空闲连接数
+		ConnMaxLifetime  time.Duration `yaml:"conn_max_lifetime"` // 连接最长使用时间，到期后重新建立
+		ConnectTimeout   time.Duration `yaml:"connect_timeout"`   // 建立连接的超时时间
+		StatementTimeout time.Duration `yaml:"statement_timeout"` // 单条SQL的服务端执行超时，负数为不限制
+		StatementCache   int           `yaml:"statement_cache"`   // 每个连接缓存的预备语句数，负数为不缓存
 	} `yaml:"database"`
 
 	Monitor struct {
 		LogInterval time.Duration `yaml:"log_interval"` // 日志输出间隔
 		LogCycle    bool          `yaml:"log_cycle"`    // 是否输出循环日志
+		Probe       bool          `yaml:"probe"`        // 每个间隔向探测表插入一行并计时，测量数据库写入耗时
+		CountMode   string        `yaml:"count_mode"`   // 入库计数方式(total: 全表行数, window: 只计时间戳在测试时间窗口内的行, partition: 同window但只查询相关分区)
+
+		QueryTimeout time.Duration `yaml:"query_timeout"` // 单次监控查询的超时时间
+		QueryRetries int           `yaml:"query_retries"` // 监控查询失败后的重试次数，用尽后跳过本间隔，负数为不重试
+
+		LatencyWindow int `yaml:"latency_window"` // 监控报告中滚动分位数覆盖的间隔数，1为只看最近一个间隔
 	} `yaml:"monitor"`
+
+	Cron struct {
+		Expr     string        `yaml:"expr"`     // cron子命令的定时表达式(分 时 日 月 周)
+		Duration time.Duration `yaml:"duration"` // 每次运行的最长时长，0为运行到测试自行结束
+		Args     []string      `yaml:"args"`     // 每次运行额外的命令行参数(如 --config 指定场景配置文件)
+	} `yaml:"cron"`
+
+	Daemon struct {
+		Enabled      bool          `yaml:"enabled"`       // 守护模式：不限循环次数持续发送，SIGHUP重新加载配置
+		StatusAddr   string        `yaml:"status_addr"`   // 未指定 --control-addr 时状态接口(/status)的监听地址
+		StallTimeout time.Duration `yaml:"stall_timeout"` // 连接或发送阶段超过该时长没有进展时 /healthz 返回503，0为发送间隔的3倍且不少于1分钟，负数为不检查
+	} `yaml:"daemon"`
+
+	Soak struct {
+		Enabled            bool          `yaml:"enabled"`             // 是否启用长稳测试模式
+		CheckpointFile     string        `yaml:"checkpoint_file"`     // 检查点文件路径
+		CheckpointInterval time.Duration `yaml:"checkpoint_interval"` // 检查点保存间隔
+		Resume             bool          `yaml:"resume"`              // 中断恢复：非长稳测试也保存检查点，重新启动时从检查点的下一个循环继续
+	} `yaml:"soak"`
+
+	Stats struct {
+		PerDevice bool   `yaml:"per_device"` // 是否记录每个设备的统计数据
+		WorstN    int    `yaml:"worst_n"`    // 测试结束时输出最差的N个设备
+		CSVFile   string `yaml:"csv_file"`   // 每设备统计CSV输出文件路径
+	} `yaml:"stats"`
+
+	Verify struct {
+		CurrentSamples  int           `yaml:"current_samples"`  // 测试结束后核对当前值的抽样设备数，0为不核对
+		ChecksumSamples int           `yaml:"checksum_samples"` // 测试结束后按数据点核对数值之和的抽样设备数，0为不核对
+		Settle          time.Duration `yaml:"settle"`           // 核对前等待平台处理完最后一批消息的时间
+	} `yaml:"verify"`
+
+	TenantLimit struct {
+		Tenant        string  `yaml:"tenant"`          // 被限额的租户ID，为空时不验证
+		Quota         float64 `yaml:"quota"`           // 该租户在平台上配置的入库限额(数据点/秒)
+		Tolerance     float64 `yaml:"tolerance"`       // 入库速率超过限额的容差(%)
+		MinWriteRatio float64 `yaml:"min_write_ratio"` // 其他租户的写入率(%)低于该值视为受到影响
+	} `yaml:"tenant_limit"`
+
+	Results struct {
+		Enabled bool   `yaml:"enabled"` // 是否将运行结果写入结果库
+		Schema  string `yaml:"schema"`  // 结果库schema名称
+	} `yaml:"results"`
+
+	Adaptive struct {
+		Enabled       bool    `yaml:"enabled"`         // 是否启用自适应速率
+		MinWriteRatio float64 `yaml:"min_write_ratio"` // 入库速率/发送速率(%)低于该值视为落后
+		Patience      int     `yaml:"patience"`        // 连续多少个监控间隔落后(或恢复)才调整速率
+		Step          float64 `yaml:"step"`            // 每次调整发送速率的百分比
+	} `yaml:"adaptive"`
+
+	Abort struct {
+		MaxConnectFailure float64       `yaml:"max_connect_failure"` // 连接失败率(%)超过该值时终止测试，0为不检查
+		MaxPublishError   float64       `yaml:"max_publish_error"`   // 发布错误率(%)持续超过该值时终止测试，0为不检查
+		Window            time.Duration `yaml:"window"`              // 发布错误率需持续超标的时间
+	} `yaml:"abort"`
+
+	Alerts struct {
+		Webhook          string        `yaml:"webhook"`            // 告警Webhook地址，为空时不告警
+		Format           string        `yaml:"format"`             // 消息格式(webhook/dingtalk/feishu/slack)
+		MinWriteRatio    float64       `yaml:"min_write_ratio"`    // 写入率(%)连续patience个监控间隔低于该值时告警，0为不检查
+		Patience         int           `yaml:"patience"`           // 写入率告警需连续低于阈值的监控间隔数
+		DBStallIntervals int           `yaml:"db_stall_intervals"` // 有发送但入库速率为0连续该数量个监控间隔时告警，0为不检查
+		MaxErrorRate     float64       `yaml:"max_error_rate"`     // 单个监控间隔的发布错误率(%)超过该值时告警，0为不检查
+		Cooldown         time.Duration `yaml:"cooldown"`           // 告警持续期间重复推送的间隔
+	} `yaml:"alerts"`
+
+	Report struct {
+		MailTo   string        `yaml:"mail_to"`   // 测试结束后发送报告的收件人，逗号分隔，为空时不发送邮件
+		MailFrom string        `yaml:"mail_from"` // 发件人地址，为空时使用smtp_user
+		SMTPHost string        `yaml:"smtp_host"` // SMTP服务器地址和端口，465端口使用SSL，其他端口在服务器支持时使用STARTTLS
+		SMTPUser string        `yaml:"smtp_user"` // SMTP登录用户名，为空时不登录
+		SMTPPass string        `yaml:"smtp_pass"` // SMTP登录密码或授权码
+		Webhook  string        `yaml:"webhook"`   // 测试结束后以multipart/form-data上传报告的地址，为空时不上传
+		Subject  string        `yaml:"subject"`   // 报告标题前缀，标题中附加运行ID、主机和关键结果
+		Timeout  time.Duration `yaml:"timeout"`   // 单次发送的超时时间
+	} `yaml:"report"`
+
+	Retry struct {
+		MaxAttempts int           `yaml:"max_attempts"` // 每条消息最多发布次数(含首次)，1为不重试
+		Backoff     time.Duration `yaml:"backoff"`      // 首次重试前的等待时间，之后每次翻倍
+		MaxBackoff  time.Duration `yaml:"max_backoff"`  // 单次等待时间上限
+		Jitter      float64       `yaml:"jitter"`       // 等待时间的随机抖动比例(0-1)
+	} `yaml:"retry"`
+
+	Reconnect struct {
+		Mode        string        `yaml:"mode"`         // 断线重连方式(backoff/paho/off)
+		Initial     time.Duration `yaml:"initial"`      // 首次重连前的等待时间(backoff方式)
+		MaxInterval time.Duration `yaml:"max_interval"` // 重连等待时间上限
+		Multiplier  float64       `yaml:"multiplier"`   // 每次重连失败后等待时间的增长倍数(backoff方式)
+		Jitter      float64       `yaml:"jitter"`       // 等待时间的随机抖动比例(0-1，backoff方式)
+	} `yaml:"reconnect"`
+
+	Throttle struct {
+		MaxProcs  int     `yaml:"max_procs"`  // 限制使用的CPU核数(GOMAXPROCS)，0为不限制
+		Nice      int     `yaml:"nice"`       // 降低进程调度优先级(1-19，仅Linux/macOS)，0为不修改
+		CPULimit  float64 `yaml:"cpu_limit"`  // CPU使用率上限(%，100为一个核)，超出时推迟发送，0为不限制
+		GCPercent int     `yaml:"gc_percent"` // GC触发比例(GOGC)，0为不修改
+	} `yaml:"throttle"`
+
+	ACL struct {
+		Samples int           `yaml:"samples"` // acl子命令抽样检查的设备数
+		Timeout time.Duration `yaml:"timeout"` // 等待CONNACK、SUBACK、PUBACK和观察者收到消息的超时时间
+		Rules   []ACLRule     `yaml:"rules"`   // 检查的主题矩阵，为空时使用默认矩阵
+	} `yaml:"acl"`
+
+	RampDown struct {
+		Rate         int           `yaml:"rate"`          // 测试结束时每秒断开的设备数，0为同时断开
+		Samples      int           `yaml:"samples"`       // 轮询在线状态的抽样设备数
+		Timeout      time.Duration `yaml:"timeout"`       // 等待平台标记离线和处理剩余写入的最长时间
+		PollInterval time.Duration `yaml:"poll_interval"` // 轮询数据库的间隔
+	} `yaml:"ramp_down"`
+
+	Output struct {
+		Dir         string `yaml:"dir"`           // 运行产物根目录，每次运行创建 <时间>-<运行ID> 子目录
+		MaxLogSize  int    `yaml:"max_log_size"`  // run.log单个文件大小上限(MB)，超过后轮转
+		MaxLogFiles int    `yaml:"max_log_files"` // 轮转后保留的旧日志文件数
+		MinFreeDisk int    `yaml:"min_free_disk"` // 运行目录所在磁盘剩余空间(MB)低于该值时停止测试，-1为不检查
+
+		ErrorSummaryInterval time.Duration `yaml:"error_summary_interval"` // 相同错误的汇总输出间隔，负数为不聚合
+	} `yaml:"output"`
 }
 
 // AppConfig 全局配置变量
@@ -56,132 +455,1639 @@ var configFile = flag.String("config", "config.yml", "配置文件路径")
 
 // 数据库相关命令行参数
 var (
-	dbHost     = flag.String("db-host", "", "数据库服务器地址和端口")
-	dbUser     = flag.String("db-user", "", "数据库用户名")
-	dbPassword = flag.String("db-pass", "", "数据库密码")
-	dbName     = flag.String("db-name", "", "数据库名称")
-	dbSSLMode  = flag.String("db-ssl", "", "数据库SSL模式")
+	dbHost      = flag.String("db-host", "", "数据库服务器地址和端口，或Unix域套接字目录(如 /var/run/postgresql，可带 :端口)")
+	dbUser      = flag.String("db-user", "", "数据库用户名")
+	dbPassword  = flag.String("db-pass", "", "数据库密码")
+	dbName      = flag.String("db-name", "", "数据库名称")
+	dbSSLMode   = flag.String("db-ssl", "", "数据库SSL模式")
+	dbDSN       = flag.String("db-dsn", "", "完整的数据库连接字符串(postgres://... 或 host=... user=... 关键字格式)，设置后忽略 --db-host、--db-user、--db-pass、--db-name 和 --db-ssl")
+	dbPgBouncer = flag.Bool("db-pgbouncer", false, "通过pgbouncer连接数据库(事务池模式)：不使用预备语句，SQL超时改由查询超时控制")
+
+	dbReplica          = flag.String("db-replica", "", "只读副本地址和端口，监控和核对查询在副本上执行，避免观测负载影响主库入库性能")
+	dbMaxConns         = flag.Int("db-max-conns", 0, "数据库连接池最大连接数 (默认: 10)")
+	dbMaxIdleConns     = flag.Int("db-max-idle-conns", 0, "数据库连接池最大空闲连接数 (默认: 2)")
+	dbConnMaxLifetime  = flag.Duration("db-conn-max-lifetime", 0, "数据库连接最长使用时间，到期后重新建立 (默认: 5m)")
+	dbConnectTimeout   = flag.Duration("db-connect-timeout", 0, "建立数据库连接的超时时间 (默认: 5s)")
+	dbStatementTimeout = flag.Duration("db-statement-timeout", 0, "单条SQL的服务端执行超时，超时后由数据库取消查询，避免表被锁时监控卡住，负数为不限制 (默认: 30s)")
+	dbStatementCache   = flag.Int("db-statement-cache", 0, "每个数据库连接缓存的预备语句数，负数为不缓存 (默认: 512)")
 )
 
 // 监控相关命令行参数
 var logInterval = flag.Duration("log-interval", 0, "日志输出间隔")
+var latencyWindow = flag.Int("latency-window", 0, "监控报告中滚动分位数覆盖的监控间隔数，除本间隔外再输出最近N个间隔的p99(默认1，只看本间隔)")
 var logCycle = flag.Bool("log-cycle", true, "是否输出循环日志")
+var monitorQueryTimeout = flag.Duration("monitor-query-timeout", 0, "单次监控查询的超时时间，超时后重试，重试用尽时跳过本间隔 (默认: 10s)")
+var countMode = flag.String("count-mode", "", "入库计数方式(total: 全表行数, window: 只计时间戳在测试时间窗口内的行，测试期间有数据保留清理任务时使用, partition: 同window但只查询与时间窗口相关的分区或chunk，适用于大型分区表)")
+
+// 结果库相关命令行参数
+var saveResults = flag.Bool("save-results", false, "将运行配置和汇总指标写入结果库")
 
 // 数据点配置
 var dataPointCount = flag.Int("data-points", 0, "每条消息包含的数据点数量")
+var payloadMode = flag.String("payload-mode", "", "消息生成模式(json: 每次编码, template: 预生成模板只改写数值)")
+var payloadProfileName = flag.String("payload-profile", "", "工业网关消息格式(modbus: Modbus寄存器表, dlt645: DL/T 645电能表数据标识)，用于测试协议插件的解析性能，为空时生成hum1..humN")
+var fieldsFlag = flag.String("fields", "", "用表达式定义数据点，分号分隔，如 'temp=20 + 5*sin(t/300) + noise(0.5);level=prev + rand(-1,1)'，可用变量t(秒)、i(设备序号)、n(消息序号)、prev(上一次的值)")
+var keyTemplate = flag.String("key-template", "", "数据点名称模板，{i}为序号，{device}为设备token，如 {device}_temp、sensor/{i}/hum (默认: hum{i})")
 
 // LoadConfig 加载配置
 func LoadConfig() {
 	// 解析命令行参数
+	translateFlagUsage()
 	flag.Parse()
 
 	// 读取配置文件
 	configData, err := os.ReadFile(*configFile)
 	if err != nil {
-		log.Printf("读取配置文件 %s 失败: %v", *configFile, err)
-		log.Println("使用默认配置和命令行参数")
+		log.Printf(T("读取配置文件 %s 失败: %v"), *configFile, err)
+		log.Println(T("使用默认配置和命令行参数"))
 	} else {
 		// 解析YAML配置
 		if err := yaml.Unmarshal(configData, &AppConfig); err != nil {
-			log.Fatalf("解析配置文件失败: %v", err)
+			log.Fatalf(T("解析配置文件失败: %v"), err)
 		}
 	}
 
+	// 输出语言: 命令行 > 环境变量 > 配置文件
+	if *langFlag == "" && os.Getenv("TP_LANG") == "" && AppConfig.Lang != "" {
+		setLang(AppConfig.Lang)
+	}
+	AppConfig.Lang = currentLang
+
 	// 命令行参数覆盖配置文件
 	overrideConfigWithFlags()
 
 	// 设置默认值（如果未指定）
 	if AppConfig.Data.DataPointCount <= 0 {
 		AppConfig.Data.DataPointCount = 10 // 默认10个数据点
-		log.Printf("数据点数量未指定，使用默认值: %d", AppConfig.Data.DataPointCount)
+		log.Printf(T("数据点数量未指定，使用默认值: %d"), AppConfig.Data.DataPointCount)
 	} else {
-		log.Printf("使用配置的数据点数量: %d", AppConfig.Data.DataPointCount)
+		log.Printf(T("使用配置的数据点数量: %d"), AppConfig.Data.DataPointCount)
 	}
 
-	// 输出最终配置
-	log.Println("当前配置:")
-	log.Printf("- 设备配置: 文件=%s, 数量=%d",
-		AppConfig.Device.TokenFile, AppConfig.Device.ClientNumber)
-	log.Printf("- MQTT配置: 服务器=%s, QoS=%d, 主题=%s",
-		AppConfig.MQTT.Server, AppConfig.MQTT.QoS, AppConfig.MQTT.Topic)
-	log.Printf("- 测试配置: 间隔=%v, 循环=%d, 等待=%v",
-		AppConfig.Test.DataInterval, AppConfig.Test.CycleCount, AppConfig.Test.ConnectWaitTime)
-	log.Printf("- 数据配置: 最小值=%.1f, 最大值=%.1f, 数据点数=%d",
-		AppConfig.Data.MinValue, AppConfig.Data.MaxValue, AppConfig.Data.DataPointCount)
-	log.Printf("- 数据库配置: 主机=%s, 用户=%s, 数据库=%s",
-		AppConfig.Database.Host, AppConfig.Database.User, AppConfig.Database.Name)
-	log.Printf("- 监控配置: 日志间隔=%v",
-		AppConfig.Monitor.LogInterval)
-	log.Printf("- 监控配置: 循环日志=%v",
-		AppConfig.Monitor.LogCycle)
-}
+	if AppConfig.Soak.CheckpointFile == "" {
+		AppConfig.Soak.CheckpointFile = "soak_checkpoint.json"
+	}
+	if AppConfig.Soak.CheckpointInterval <= 0 {
+		AppConfig.Soak.CheckpointInterval = time.Minute
+	}
+	if AppConfig.Daemon.StatusAddr == "" {
+		AppConfig.Daemon.StatusAddr = defaultStatusAddr
+	}
 
-// overrideConfigWithFlags 使用命令行参数覆盖配置文件
-func overrideConfigWithFlags() {
-	// 设备配置
-	if *deviceTokenFile != "" {
-		AppConfig.Device.TokenFile = *deviceTokenFile
+	// 初始化随机数种子
+	initRandom()
+
+	// 校验额外数据流
+	initStreams()
+	initHeartbeat()
+	initDeviceInfo()
+
+	if AppConfig.Consumers.Topic == "" {
+		AppConfig.Consumers.Topic = AppConfig.MQTT.Topic
 	}
-	if *clientNumber > 0 {
-		AppConfig.Device.ClientNumber = *clientNumber
+
+	if AppConfig.MQTT.QoSMix != "" {
+		mix, err := parseQoSMix(AppConfig.MQTT.QoSMix)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		qosMix = mix
 	}
 
-	// MQTT配置
-	if *mqttServer != "" {
-		AppConfig.MQTT.Server = *mqttServer
+	// 分阶段负载决定总循环数和初始发送间隔，需在依赖二者的默认值之前解析
+	if AppConfig.Test.Stages != "" {
+		stages, err := parseStages(AppConfig.Test.Stages)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		loadStages = stages
+		AppConfig.Test.CycleCount = stageCycles(stages)
+		AppConfig.Test.DataInterval = stages[0].interval
 	}
-	if *qos >= 0 {
-		AppConfig.MQTT.QoS = *qos
+
+	// 多租户分组决定设备总数，各组的发送间隔按data_interval换算为循环数
+	initTenantGroups()
+
+	switch AppConfig.MQTT.Engine {
+	case "":
+		AppConfig.MQTT.Engine = "paho"
+	case "paho":
+	case "lite":
+		if AppConfig.MQTT.QoS > 1 {
+			log.Fatalf(T("轻量客户端引擎不支持QoS%d，请使用paho引擎"), AppConfig.MQTT.QoS)
+		}
+		for _, share := range qosMix {
+			if share.qos > 1 && share.percent > 0 {
+				log.Fatalf(T("轻量客户端引擎不支持QoS%d，请使用paho引擎"), share.qos)
+			}
+		}
+		if len(AppConfig.Subscribe.Topics) > 0 {
+			log.Fatalf(T("轻量客户端引擎不支持订阅，请使用paho引擎"))
+		}
+	default:
+		log.Fatalf(T("不支持的客户端引擎: %s (可选: paho, lite)"), AppConfig.MQTT.Engine)
 	}
-	if *topic != "" {
-		AppConfig.MQTT.Topic = *topic
+
+	// paho.mqtt.golang只实现了3.1.1，MQTT 5由轻量客户端实现
+	props := AppConfig.MQTT.Properties
+	switch AppConfig.MQTT.Version {
+	case 0:
+		AppConfig.MQTT.Version = 4
+	case 4:
+	case 5:
+		if AppConfig.MQTT.Engine != "lite" {
+			log.Fatalf(T("MQTT 5仅支持lite引擎，请使用 --engine lite"))
+		}
+	default:
+		log.Fatalf(T("不支持的MQTT协议版本: %d (可选: 4, 5)"), AppConfig.MQTT.Version)
+	}
+	if !mqtt5Enabled() && (props.ContentType != "" || len(props.User) > 0) {
+		log.Fatalf(T("消息属性(内容类型、用户属性)需要MQTT 5，请使用 --mqtt-version 5"))
 	}
 
-	// 测试配置
-	if *dataInterval > 0 {
-		AppConfig.Test.DataInterval = *dataInterval
+	// 本地接收端：消息在本机丢弃或写入文件，不连接Broker
+	switch AppConfig.Sink.Mode {
+	case "", "null":
+	case "file":
+		if AppConfig.Sink.File == "" {
+			AppConfig.Sink.File = defaultSinkFile
+		}
+	default:
+		log.Fatalf(T("不支持的接收端: %s (可选: null, file)"), AppConfig.Sink.Mode)
 	}
-	if *testCycleCount > 0 {
-		AppConfig.Test.CycleCount = *testCycleCount
+	if sinkEnabled() && (localBrokerEnabled() || httpDevicesEnabled() || dashboardsEnabled() || AppConfig.Consumers.Count > 0) {
+		log.Fatal(T("本地接收端不连接Broker，不能与本地Broker、HTTP设备、WebSocket看板或平台消费者同时使用"))
 	}
-	if *connectWaitTime > 0 {
-		AppConfig.Test.ConnectWaitTime = *connectWaitTime
+
+	// 本地Broker：设备连接进程内的Broker，不使用配置的服务器
+	if localBrokerEnabled() {
+		if usesTLS(AppConfig.MQTT.Server) || AppConfig.TLS.Enabled || AppConfig.TLS.CAFile != "" || AppConfig.TLS.CertDir != "" {
+			log.Fatal(T("本地Broker不支持TLS，请去掉TLS相关配置"))
+		}
+		if AppConfig.LocalBroker.Listen == "" {
+			AppConfig.LocalBroker.Listen = "127.0.0.1:0"
+		}
+		broker, err := startLocalBroker(AppConfig.LocalBroker.Listen)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		localBroker = broker
+		AppConfig.MQTT.Server = broker.Addr()
 	}
 
-	// 数据配置
-	if *minValue > 0 {
-		AppConfig.Data.MinValue = *minValue
+	// 配置了证书或服务器地址为TLS协议时启用TLS，paho通过ssl://识别TLS连接
+	if usesTLS(AppConfig.MQTT.Server) || AppConfig.TLS.CAFile != "" || AppConfig.TLS.CertDir != "" {
+		AppConfig.TLS.Enabled = true
 	}
-	if *maxValue > 0 {
-		AppConfig.Data.MaxValue = *maxValue
+	if AppConfig.TLS.Enabled {
+		AppConfig.MQTT.Server = tlsServerURL(AppConfig.MQTT.Server)
 	}
-	if *dataPointCount > 0 {
-		AppConfig.Data.DataPointCount = *dataPointCount
+
+	switch AppConfig.Resolve.Mode {
+	case "":
+		AppConfig.Resolve.Mode = "per-connection"
+	case "per-connection", "once":
+	default:
+		log.Fatalf(T("不支持的域名解析方式: %s (可选: per-connection, once)"), AppConfig.Resolve.Mode)
+	}
+	switch AppConfig.Resolve.Family {
+	case "":
+		AppConfig.Resolve.Family = "any"
+	case "any", "v4", "v6", "dual":
+	default:
+		log.Fatalf(T("不支持的地址族: %s (可选: any, v4, v6, dual)"), AppConfig.Resolve.Family)
+	}
+	// IPv6地址必须带方括号，否则无法区分地址和端口(本地接收端不使用服务器地址)
+	if _, _, _, err := splitServer(AppConfig.MQTT.Server); err != nil && !sinkEnabled() {
+		log.Fatalf(T("服务器地址 %s 无效，需要写成 host:port 的形式(IPv6地址如 [::1]:1883): %v"), AppConfig.MQTT.Server, err)
+	}
+	for _, ip := range AppConfig.Resolve.PinIPs {
+		if net.ParseIP(ip) == nil {
+			log.Fatalf(T("无效的Broker IP: %s"), ip)
+		}
 	}
 
-	// 数据库配置
-	if *dbHost != "" {
-		AppConfig.Database.Host = *dbHost
+	if AppConfig.Test.BarrierTimeout <= 0 {
+		AppConfig.Test.BarrierTimeout = AppConfig.Test.DataInterval
 	}
-	if *dbUser != "" {
-		AppConfig.Database.User = *dbUser
+
+	if AppConfig.Prime.Rate <= 0 {
+		AppConfig.Prime.Rate = 100
 	}
-	if *dbPassword != "" {
-		AppConfig.Database.Password = *dbPassword
+	if AppConfig.Prime.Concurrency <= 0 {
+		AppConfig.Prime.Concurrency = 50
 	}
-	if *dbName != "" {
-		AppConfig.Database.Name = *dbName
+
+	if AppConfig.Takeover.Enabled {
+		if AppConfig.Takeover.Cycle <= 0 {
+			AppConfig.Takeover.Cycle = AppConfig.Test.CycleCount / 2
+		}
+		if AppConfig.Takeover.Cycle <= 0 {
+			log.Fatalf(T("会话接管场景需要指定接管循环(--takeover-cycle)"))
+		}
+		if AppConfig.Takeover.Ratio <= 0 || AppConfig.Takeover.Ratio > 100 {
+			AppConfig.Takeover.Ratio = 100
+		}
 	}
-	if *dbSSLMode != "" {
-		AppConfig.Database.SSLMode = *dbSSLMode
+
+	if credRotationEnabled() {
+		r := &AppConfig.CredRotation
+		if sinkEnabled() || localBrokerEnabled() {
+			log.Fatal(T("凭证轮换场景需要连接平台，不能与本地Broker或本地接收端同时使用"))
+		}
+		if r.Cycle <= 0 {
+			r.Cycle = AppConfig.Test.CycleCount / 2
+		}
+		if r.Cycle <= 0 {
+			log.Fatalf(T("凭证轮换场景需要指定更换凭证的循环(--rotate-cycle)"))
+		}
+		if r.Devices > AppConfig.Device.ClientNumber {
+			r.Devices = AppConfig.Device.ClientNumber
+		}
+		switch r.Method {
+		case "":
+			r.Method = "db"
+		case "db", "api":
+		default:
+			log.Fatalf(T("不支持的凭证更换方式: %s (可选: db, api)"), r.Method)
+		}
+		if r.Method == "api" && !apiEnabled() {
+			log.Fatal(T("通过API更换凭证需要指定平台API地址(--api-url)"))
+		}
+		if r.Path == "" {
+			r.Path = defaultRotationPath
+		}
+		if r.Body == "" {
+			r.Body = defaultRotationBody
+		}
+		if r.ProbeInterval <= 0 {
+			r.ProbeInterval = 200 * time.Millisecond
+		}
+		if r.Timeout <= 0 {
+			r.Timeout = time.Minute
+		}
 	}
 
-	// 监控配置
-	if *logInterval > 0 {
-		AppConfig.Monitor.LogInterval = *logInterval
+	if fleetShrinkEnabled() {
+		f := &AppConfig.FleetShrink
+		if sinkEnabled() || localBrokerEnabled() {
+			log.Fatal(T("设备删除场景需要连接平台，不能与本地Broker或本地接收端同时使用"))
+		}
+		if f.Ratio > 100 {
+			f.Ratio = 100
+		}
+		if f.Cycle <= 0 {
+			f.Cycle = AppConfig.Test.CycleCount / 2
+		}
+		if f.Cycle <= 0 {
+			log.Fatalf(T("设备删除场景需要指定删除设备的循环(--delete-cycle)"))
+		}
+		switch f.Method {
+		case "":
+			f.Method = "db"
+		case "db", "api":
+		default:
+			log.Fatalf(T("不支持的设备删除方式: %s (可选: db, api)"), f.Method)
+		}
+		if f.Method == "api" && !apiEnabled() {
+			log.Fatal(T("通过API删除设备需要指定平台API地址(--api-url)"))
+		}
+		if f.Path == "" {
+			f.Path = defaultDeletePath
+		}
+		if f.ProbeInterval <= 0 {
+			f.ProbeInterval = 200 * time.Millisecond
+		}
+		if f.Timeout <= 0 {
+			f.Timeout = time.Minute
+		}
 	}
 
-	if logCycle != nil {
-		AppConfig.Monitor.LogCycle = *logCycle
+	if fleetGrowthEnabled() {
+		g := &AppConfig.FleetGrowth
+		switch g.Source {
+		case "":
+			g.Source = "file"
+		case "file", "db":
+		default:
+			log.Fatalf(T("不支持的新设备来源: %s (可选: file, db)"), g.Source)
+		}
+		if g.Source == "db" && (sinkEnabled() || localBrokerEnabled()) {
+			log.Fatal(T("从数据库发现新设备需要连接平台，不能与本地Broker或本地接收端同时使用"))
+		}
+		if g.TokenFile == "" {
+			g.TokenFile = AppConfig.Device.TokenFile
+		}
+		if g.PollInterval <= 0 {
+			g.PollInterval = 10 * time.Second
+		}
+	}
+
+	if provisionEnabled() {
+		p := &AppConfig.Provision
+		if !apiEnabled() {
+			log.Fatal(T("动态注册模式需要指定平台API地址(--api-url)"))
+		}
+		if p.Secret == "" {
+			log.Fatal(T("动态注册模式需要指定一型一密密钥(provision.secret 或 --provision-secret)"))
+		}
+		if tenantGroupsEnabled() {
+			log.Fatal(T("动态注册模式不能与多租户分组同时使用"))
+		}
+		if p.Path == "" {
+			p.Path = defaultProvisionPath
+		}
+		if p.Body == "" {
+			p.Body = defaultProvisionBody
+		}
+		if p.Prefix == "" {
+			p.Prefix = "perf-"
+		}
+	}
+
+	if AppConfig.Offline.Enabled {
+		if AppConfig.Offline.StartCycle <= 0 {
+			AppConfig.Offline.StartCycle = AppConfig.Test.CycleCount / 3
+		}
+		if AppConfig.Offline.Cycles <= 0 {
+			AppConfig.Offline.Cycles = AppConfig.Test.CycleCount / 3
+		}
+		if AppConfig.Offline.StartCycle <= 0 || AppConfig.Offline.Cycles <= 0 {
+			log.Fatalf(T("离线缓存场景需要指定离线循环(--offline-start, --offline-cycles)"))
+		}
+		if AppConfig.Offline.Ratio <= 0 || AppConfig.Offline.Ratio > 100 {
+			AppConfig.Offline.Ratio = 10
+		}
+	}
+
+	if anomalyEnabled() {
+		a := &AppConfig.Anomaly
+		if a.Ratio > 100 {
+			log.Fatalf(T("异常注入的设备比例应在0-100之间: %.1f"), a.Ratio)
+		}
+		if len(a.Cycles) == 0 && AppConfig.Test.CycleCount >= 2 {
+			a.Cycles = []int{AppConfig.Test.CycleCount / 2}
+		}
+		if len(a.Cycles) == 0 {
+			log.Fatal(T("异常注入需要指定注入循环(--anomaly-cycles)"))
+		}
+		for _, cycle := range a.Cycles {
+			if cycle <= 0 {
+				log.Fatalf(T("无效的异常注入循环: %d"), cycle)
+			}
+		}
+		if a.Hold <= 0 {
+			a.Hold = 1
+		}
+		if a.Jump == 0 {
+			a.Jump = 10 * (AppConfig.Data.MaxValue - AppConfig.Data.MinValue)
+		}
+		if a.Jump == 0 {
+			a.Jump = 1000
+		}
+		if a.AlarmQuery == "" {
+			a.AlarmQuery = defaultAlarmQuery
+		}
+		if a.Timeout <= 0 {
+			a.Timeout = time.Minute
+		}
+		initAnomalyEvents()
+	}
+
+	if httpDevicesEnabled() {
+		h := &AppConfig.HTTP
+		if h.Ratio > 100 {
+			log.Fatalf(T("HTTP设备比例应在0-100之间: %.1f"), h.Ratio)
+		}
+		if h.URL == "" {
+			log.Fatal(T("HTTP设备需要指定上报地址(--http-url)"))
+		}
+		if h.Timeout <= 0 {
+			h.Timeout = 10 * time.Second
+		}
+	}
+
+	if AppConfig.API.Timeout <= 0 {
+		AppConfig.API.Timeout = 10 * time.Second
+	}
+	if attrSetEnabled() {
+		a := &AppConfig.AttrSet
+		if !apiEnabled() {
+			log.Fatal(T("属性下发测试需要指定平台API地址(--api-url)"))
+		}
+		if AppConfig.MQTT.Engine == "lite" {
+			log.Fatal(T("属性下发测试需要设备订阅主题，不支持 mqtt.engine: lite"))
+		}
+		if sinkEnabled() || localBrokerEnabled() {
+			log.Fatal(T("属性下发测试需要连接平台，不能与本地Broker或本地接收端同时使用"))
+		}
+		if a.Devices <= 0 {
+			a.Devices = 10
+		}
+		if a.Path == "" {
+			a.Path = defaultAttrSetPath
+		}
+		if a.Body == "" {
+			a.Body = defaultAttrSetBody
+		}
+		if a.Key == "" {
+			a.Key = defaultAttrSetKey
+		}
+		if a.Topic == "" {
+			a.Topic = defaultAttrSetTopic
+		}
+		if a.ResponseTopic == "" {
+			a.ResponseTopic = defaultAttrSetResponseTopic
+		}
+		if a.AckTimeout <= 0 {
+			a.AckTimeout = 10 * time.Second
+		}
+	}
+	if commandFloodEnabled() {
+		c := &AppConfig.Command
+		if !apiEnabled() {
+			log.Fatal(T("命令下发压测需要指定平台API地址(--api-url)"))
+		}
+		if AppConfig.MQTT.Engine == "lite" {
+			log.Fatal(T("命令下发压测需要设备订阅主题，不支持 mqtt.engine: lite"))
+		}
+		if sinkEnabled() || localBrokerEnabled() {
+			log.Fatal(T("命令下发压测需要连接平台，不能与本地Broker或本地接收端同时使用"))
+		}
+		if c.Rounds <= 0 {
+			c.Rounds = 1
+		}
+		if c.Concurrency <= 0 {
+			c.Concurrency = c.Devices
+		}
+		if c.Path == "" {
+			c.Path = defaultCommandPath
+		}
+		if c.Body == "" {
+			c.Body = defaultCommandBody
+		}
+		if c.Identify == "" {
+			c.Identify = defaultCommandIdentify
+		}
+		if c.Key == "" {
+			c.Key = defaultCommandKey
+		}
+		if attrSetEnabled() && c.Key == AppConfig.AttrSet.Key {
+			log.Fatal(T("command.key 不能与 attr_set.key 相同，否则无法区分设备收到的命令和属性设置"))
+		}
+		if c.Topic == "" {
+			c.Topic = defaultCommandTopic
+		}
+		if c.ResponseTopic == "" {
+			c.ResponseTopic = defaultCommandResponseTopic
+		}
+		if c.Timeout <= 0 {
+			c.Timeout = 30 * time.Second
+		}
+	}
+	if historyQueryEnabled() {
+		h := &AppConfig.HistoryQuery
+		if !apiEnabled() {
+			log.Fatal(T("读写混合负载需要指定平台API地址(--api-url)"))
+		}
+		if sinkEnabled() || localBrokerEnabled() {
+			log.Fatal(T("读写混合负载需要连接平台，不能与本地Broker或本地接收端同时使用"))
+		}
+		if h.Key == "" && !keyTemplateInUse() {
+			log.Fatal(T("使用GPS轨迹、表达式数据点或网关消息格式时，读写混合负载需要指定查询的数据点名称(history_query.key)"))
+		}
+		if h.Aggregate < 0 || h.Aggregate > 1 {
+			log.Fatalf(T("history_query.aggregate 必须在0到1之间: %g"), h.Aggregate)
+		}
+		if h.Concurrency <= 0 {
+			h.Concurrency = 50
+		}
+		if h.Devices <= 0 {
+			h.Devices = 100
+		}
+		if h.Window <= 0 {
+			h.Window = 5 * time.Minute
+		}
+		if h.HistoryPath == "" {
+			h.HistoryPath = defaultHistoryPath
+		}
+		if h.AggregatePath == "" {
+			h.AggregatePath = defaultAggregatePath
+		}
+	}
+
+	if dashboardsEnabled() {
+		d := &AppConfig.Dashboards
+		if d.URL == "" {
+			log.Fatal(T("WebSocket看板需要指定看板地址(--dashboard-url)"))
+		}
+		if d.Message == "" {
+			d.Message = defaultDashboardMessage
+		}
+		if d.Timeout <= 0 {
+			d.Timeout = 10 * time.Second
+		}
+		if d.LatencyKey == "" {
+			d.LatencyKey = defaultDashboardLatencyKey
+		}
+	} else if AppConfig.Dashboards.LatencyEvery > 0 {
+		log.Fatal(T("看板端到端延迟需要启用WebSocket看板(--dashboards)"))
+	}
+
+	if AppConfig.DeviceStates.Enabled {
+		ds := &AppConfig.DeviceStates
+		if ds.NormalToDegraded == 0 && ds.NormalToOffline == 0 && ds.DegradedToNormal == 0 &&
+			ds.DegradedToOffline == 0 && ds.OfflineToNormal == 0 && ds.OfflineToDegraded == 0 {
+			ds.NormalToDegraded, ds.NormalToOffline = 1, 0.2
+			ds.DegradedToNormal, ds.DegradedToOffline = 10, 2
+			ds.OfflineToNormal = 10
+		}
+		for from := stateNormal; from < stateCount; from++ {
+			var sum float64
+			for _, p := range transitionRow(from) {
+				if p < 0 {
+					log.Fatalf(T("设备状态机的转移概率不能为负数: %s"), deviceStateNames[from])
+				}
+				sum += p
+			}
+			if sum > 100 {
+				log.Fatalf(T("设备状态机从 %s 转出的概率之和 %.1f%% 超过100%%"), deviceStateNames[from], sum)
+			}
+		}
+		if ds.DegradedEvery <= 0 {
+			ds.DegradedEvery = 3
+		}
+		if ds.ErrorKey == "" {
+			ds.ErrorKey = "error_code"
+		}
+	}
+
+	switch AppConfig.Device.DuplicateTokens {
+	case "":
+		AppConfig.Device.DuplicateTokens = "dedupe"
+	case "dedupe", "error", "allow":
+	default:
+		log.Fatalf(T("不支持的重复token处理方式: %s (可选: dedupe, error, allow)"), AppConfig.Device.DuplicateTokens)
+	}
+
+	switch AppConfig.Data.PayloadMode {
+	case "":
+		AppConfig.Data.PayloadMode = "json"
+	case "json", "template":
+	default:
+		log.Fatalf(T("不支持的消息生成模式: %s (可选: json, template)"), AppConfig.Data.PayloadMode)
+	}
+
+	if AppConfig.Database.DSN != "" {
+		if err := pgdsn.Check(AppConfig.Database.DSN); err != nil {
+			log.Fatalf(T("database.dsn 格式错误: %v"), err)
+		}
+	}
+	if AppConfig.Database.MaxConns <= 0 {
+		AppConfig.Database.MaxConns = 10
+	}
+	if AppConfig.Database.MaxIdleConns <= 0 {
+		AppConfig.Database.MaxIdleConns = 2
+	}
+	if AppConfig.Database.ConnMaxLifetime <= 0 {
+		AppConfig.Database.ConnMaxLifetime = 5 * time.Minute
+	}
+	if AppConfig.Database.ConnectTimeout <= 0 {
+		AppConfig.Database.ConnectTimeout = 5 * time.Second
+	}
+	if AppConfig.Database.StatementTimeout == 0 {
+		AppConfig.Database.StatementTimeout = 30 * time.Second
+	}
+	if AppConfig.Database.StatementCache == 0 {
+		AppConfig.Database.StatementCache = 512
+	}
+
+	if AppConfig.Monitor.QueryTimeout <= 0 {
+		AppConfig.Monitor.QueryTimeout = 10 * time.Second
+	}
+	if AppConfig.Monitor.QueryRetries == 0 {
+		AppConfig.Monitor.QueryRetries = 2
+	}
+	if AppConfig.Monitor.LatencyWindow <= 0 {
+		AppConfig.Monitor.LatencyWindow = 1
+	}
+
+	switch AppConfig.Monitor.CountMode {
+	case "":
+		AppConfig.Monitor.CountMode = "total"
+	case "total", "window", "partition":
+	default:
+		log.Fatalf(T("不支持的入库计数方式: %s (可选: total, window, partition)"), AppConfig.Monitor.CountMode)
+	}
+
+	if AppConfig.Verify.Settle <= 0 {
+		AppConfig.Verify.Settle = 5 * time.Second
+	}
+
+	if AppConfig.TenantLimit.Tenant != "" && AppConfig.TenantLimit.Quota <= 0 {
+		log.Fatal(T("租户限额验证需要配置租户的入库限额(tenant_limit.quota 或 --tenant-quota)"))
+	}
+	if AppConfig.TenantLimit.Tolerance <= 0 {
+		AppConfig.TenantLimit.Tolerance = 10
+	}
+	if AppConfig.TenantLimit.MinWriteRatio <= 0 {
+		AppConfig.TenantLimit.MinWriteRatio = 95
+	}
+
+	if AppConfig.Data.KeyTemplate == "" {
+		AppConfig.Data.KeyTemplate = "hum{i}"
+	}
+	if stringsEnabled() {
+		s := &AppConfig.Strings
+		if AppConfig.Data.PayloadMode == "template" {
+			log.Fatal(T("字符串数据点不支持模板生成模式(payload_mode: template)"))
+		}
+		if s.Kinds == "" {
+			s.Kinds = strings.Join(stringKindNames, ",")
+		}
+		if s.MaxLength == 0 {
+			s.MinLength, s.MaxLength = max(s.MinLength, 1), max(s.MinLength, 256)
+		}
+		if s.MinLength < 0 || s.MaxLength < s.MinLength || s.LimitLength < 0 {
+			log.Fatalf(T("无效的字符串长度: %d~%d, 上限=%d"), s.MinLength, s.MaxLength, s.LimitLength)
+		}
+	}
+	if numericEnabled() {
+		n := &AppConfig.Numeric
+		if !keyTemplateInUse() || countersEnabled() || AppConfig.Data.PayloadMode == "template" {
+			log.Fatal(T("数值边界配置只适用于默认的数据生成(json生成模式，未使用表达式数据点、网关消息格式、GPS轨迹或累计量)"))
+		}
+		sum := n.SpecialRatio + n.ExtremeRatio + n.IntegerRatio + n.ScientificRatio
+		if n.Precision < 0 || n.Precision > 17 || n.SpecialRatio < 0 || n.ExtremeRatio < 0 ||
+			n.IntegerRatio < 0 || n.ScientificRatio < 0 || sum > 100 {
+			log.Fatalf(T("无效的数值边界配置: 小数位数=%d (0~17), 各类比例之和=%.1f%% (不超过100%%)"), n.Precision, sum)
+		}
+	}
+	if countersEnabled() {
+		c := &AppConfig.Counter
+		if AppConfig.Data.PayloadMode == "template" {
+			log.Fatal(T("累计量模式不支持模板生成模式(payload_mode: template)"))
+		}
+		if c.MaxIncrement == 0 {
+			c.MaxIncrement = 0.5
+		}
+		if c.MinIncrement < 0 || c.MaxIncrement < c.MinIncrement || c.ResetChance < 0 || c.Rollover < 0 {
+			log.Fatalf(T("无效的累计量配置: 增量=%v~%v, 清零概率=%v%%, 翻转值=%v"),
+				c.MinIncrement, c.MaxIncrement, c.ResetChance, c.Rollover)
+		}
+	}
+	if gpsEnabled() {
+		gps := &AppConfig.GPS
+		if len(AppConfig.Data.Fields) > 0 || AppConfig.Data.Profile != "" || AppConfig.Data.PayloadMode == "template" {
+			log.Fatal(T("GPS轨迹模拟不能与表达式数据点、网关消息格式或模板生成模式同时使用"))
+		}
+		if countersEnabled() {
+			log.Fatal(T("累计量模式不能与GPS轨迹模拟同时使用"))
+		}
+		rejectStagePoints(T("GPS轨迹模拟"))
+		if gps.MaxSpeed <= 0 {
+			gps.MaxSpeed = 60
+		}
+		if gps.MinSpeed < 0 || gps.MinSpeed > gps.MaxSpeed {
+			log.Fatalf(T("无效的GPS速度范围: %.1f~%.1f km/h"), gps.MinSpeed, gps.MaxSpeed)
+		}
+		if gps.LatKey == "" {
+			gps.LatKey = "lat"
+		}
+		if gps.LngKey == "" {
+			gps.LngKey = "lng"
+		}
+		if gps.SpeedKey == "" {
+			gps.SpeedKey = "speed"
+		}
+		AppConfig.Data.DataPointCount = gpsPointCount
+	} else if len(AppConfig.Data.Fields) > 0 {
+		if err := compileFields(AppConfig.Data.Fields); err != nil {
+			log.Fatalf("%v", err)
+		}
+		if AppConfig.Data.Profile != "" || AppConfig.Data.PayloadMode == "template" {
+			log.Fatal(T("表达式数据点不能与网关消息格式或模板生成模式同时使用"))
+		}
+		if countersEnabled() {
+			log.Fatal(T("累计量模式不能与表达式数据点同时使用(表达式中可以用prev实现累计)"))
+		}
+		rejectStagePoints(T("表达式数据点"))
+		AppConfig.Data.DataPointCount = len(AppConfig.Data.Fields)
+	} else if AppConfig.Data.Profile != "" {
+		if countersEnabled() {
+			log.Fatal(T("累计量模式不能与网关消息格式同时使用"))
+		}
+		if _, ok := payloadProfiles[AppConfig.Data.Profile]; !ok {
+			log.Fatalf(T("不支持的网关消息格式: %s (可选: %s)"), AppConfig.Data.Profile, profileNames())
+		}
+		if AppConfig.Data.PayloadMode == "template" {
+			log.Fatal(T("网关消息格式不支持模板生成模式(payload_mode: template)"))
+		}
+	} else if points := maxMessagePoints(); points > 1 && !strings.Contains(AppConfig.Data.KeyTemplate, "{i}") {
+		log.Fatalf(T("数据点名称模板 %s 缺少 {i}，每条消息的 %d 个数据点会重名"),
+			AppConfig.Data.KeyTemplate, points)
+	}
+
+	if AppConfig.Results.Schema == "" {
+		AppConfig.Results.Schema = "results"
+	}
+
+	if AppConfig.Adaptive.MinWriteRatio <= 0 {
+		AppConfig.Adaptive.MinWriteRatio = 95
+	}
+	if AppConfig.Adaptive.Patience <= 0 {
+		AppConfig.Adaptive.Patience = 3
+	}
+	if AppConfig.Adaptive.Step <= 0 || AppConfig.Adaptive.Step >= 100 {
+		AppConfig.Adaptive.Step = 10
+	}
+
+	if AppConfig.Abort.Window <= 0 {
+		AppConfig.Abort.Window = 2 * time.Minute
+	}
+
+	switch AppConfig.Alerts.Format {
+	case "":
+		AppConfig.Alerts.Format = "webhook"
+	case "webhook", "dingtalk", "feishu", "slack":
+	default:
+		log.Fatalf(T("不支持的告警消息格式: %s (可选 webhook/dingtalk/feishu/slack)"), AppConfig.Alerts.Format)
+	}
+	if AppConfig.Alerts.Patience <= 0 {
+		AppConfig.Alerts.Patience = 3
+	}
+	if AppConfig.Alerts.Cooldown <= 0 {
+		AppConfig.Alerts.Cooldown = 30 * time.Minute
+	}
+	if AppConfig.Report.MailTo != "" {
+		if AppConfig.Report.SMTPHost == "" {
+			log.Fatalln(T("发送报告邮件需要配置 report.smtp_host"))
+		}
+		if AppConfig.Report.MailFrom == "" {
+			AppConfig.Report.MailFrom = AppConfig.Report.SMTPUser
+		}
+		if AppConfig.Report.MailFrom == "" {
+			log.Fatalln(T("发送报告邮件需要配置 report.mail_from 或 report.smtp_user"))
+		}
+	}
+	if AppConfig.Report.Subject == "" {
+		AppConfig.Report.Subject = T("[MQTT性能测试报告]")
+	}
+	if AppConfig.Report.Timeout <= 0 {
+		AppConfig.Report.Timeout = 30 * time.Second
+	}
+
+	if AppConfig.Retry.MaxAttempts <= 0 {
+		AppConfig.Retry.MaxAttempts = 1
+	}
+	if AppConfig.Retry.Backoff <= 0 {
+		AppConfig.Retry.Backoff = 100 * time.Millisecond
+	}
+	if AppConfig.Retry.MaxBackoff < AppConfig.Retry.Backoff {
+		AppConfig.Retry.MaxBackoff = max(5*time.Second, AppConfig.Retry.Backoff)
+	}
+	if AppConfig.Retry.Jitter < 0 || AppConfig.Retry.Jitter > 1 {
+		AppConfig.Retry.Jitter = 0.2
+	}
+
+	switch AppConfig.Reconnect.Mode {
+	case "":
+		AppConfig.Reconnect.Mode = "backoff"
+	case "backoff", "paho", "off":
+	default:
+		log.Fatalf(T("不支持的重连方式: %s (可选: backoff, paho, off)"), AppConfig.Reconnect.Mode)
+	}
+	if AppConfig.Reconnect.Initial <= 0 {
+		AppConfig.Reconnect.Initial = time.Second
+	}
+	if AppConfig.Reconnect.MaxInterval <= 0 {
+		AppConfig.Reconnect.MaxInterval = 5 * time.Second
+	}
+	if AppConfig.Reconnect.Multiplier < 1 {
+		AppConfig.Reconnect.Multiplier = 2
+	}
+	if AppConfig.Reconnect.Jitter < 0 || AppConfig.Reconnect.Jitter > 1 {
+		AppConfig.Reconnect.Jitter = 0.5
+	}
+
+	if AppConfig.Throttle.Nice < 0 || AppConfig.Throttle.Nice > 19 {
+		log.Fatalf(T("调度优先级应在1-19之间: %d"), AppConfig.Throttle.Nice)
+	}
+
+	if AppConfig.ACL.Samples <= 0 {
+		AppConfig.ACL.Samples = 3
+	}
+	if AppConfig.ACL.Timeout <= 0 {
+		AppConfig.ACL.Timeout = 2 * time.Second
+	}
+	if len(AppConfig.ACL.Rules) == 0 {
+		AppConfig.ACL.Rules = defaultACLRules()
+	}
+	for _, rule := range AppConfig.ACL.Rules {
+		if (rule.Action != "publish" && rule.Action != "subscribe") || (rule.Expect != "allow" && rule.Expect != "deny") {
+			log.Fatalf(T("无效的ACL检查项: %s %s %s (action: publish/subscribe, expect: allow/deny)"),
+				rule.Action, rule.Topic, rule.Expect)
+		}
+	}
+
+	if AppConfig.RampDown.Samples <= 0 {
+		AppConfig.RampDown.Samples = 100
+	}
+	if AppConfig.RampDown.Timeout <= 0 {
+		AppConfig.RampDown.Timeout = 2 * time.Minute
+	}
+	if AppConfig.RampDown.PollInterval <= 0 {
+		AppConfig.RampDown.PollInterval = time.Second
+	}
+
+	if AppConfig.Output.Dir == "" {
+		AppConfig.Output.Dir = "results"
+	}
+	if AppConfig.Output.MaxLogSize <= 0 {
+		AppConfig.Output.MaxLogSize = 100
+	}
+	if AppConfig.Output.MaxLogFiles <= 0 {
+		AppConfig.Output.MaxLogFiles = 5
+	}
+	if AppConfig.Output.MinFreeDisk == 0 {
+		AppConfig.Output.MinFreeDisk = 500
+	}
+	if AppConfig.Output.ErrorSummaryInterval == 0 {
+		AppConfig.Output.ErrorSummaryInterval = 10 * time.Second
+	}
+
+	// 输出最终配置
+	log.Println(T("当前配置:"))
+	log.Printf(T("- 设备配置: 文件=%s, 数量=%d"),
+		AppConfig.Device.TokenFile, AppConfig.Device.ClientNumber)
+	log.Printf(T("- MQTT配置: 服务器=%s, QoS=%d, 主题=%s, 引擎=%s"),
+		AppConfig.MQTT.Server, AppConfig.MQTT.QoS, AppConfig.MQTT.Topic, AppConfig.MQTT.Engine)
+	if localBrokerEnabled() {
+		log.Printf(T("- 本地Broker: %s (不连接外部Broker和数据库)"), localBroker.Addr())
+	}
+	if sinkEnabled() {
+		log.Printf(T("- 本地接收端: %s %s (不连接Broker和数据库)"), AppConfig.Sink.Mode, AppConfig.Sink.File)
+	}
+	if AppConfig.MQTT.QoSMix != "" {
+		log.Printf(T("- QoS分布: %s"), AppConfig.MQTT.QoSMix)
+	}
+	if mqtt5Enabled() {
+		log.Printf(T("- MQTT 5: 内容类型=%q, 用户属性 %d 个, 每条消息属性约 %d 字节"),
+			AppConfig.MQTT.Properties.ContentType, len(AppConfig.MQTT.Properties.User), len(publishProperties("")))
+	}
+	if len(AppConfig.Resolve.PinIPs) > 0 || len(AppConfig.Resolve.DNSServers) > 0 ||
+		AppConfig.Resolve.Mode != "per-connection" || AppConfig.Resolve.Family != "any" {
+		log.Printf(T("- 域名解析: 方式=%s, 固定IP=%v, DNS服务器=%v, 地址族=%s"),
+			AppConfig.Resolve.Mode, AppConfig.Resolve.PinIPs, AppConfig.Resolve.DNSServers, AppConfig.Resolve.Family)
+	}
+	if AppConfig.TLS.Enabled {
+		log.Printf(T("- TLS配置: CA=%s, 设备证书目录=%s, 即时签发=%v, 轮换间隔=%v"),
+			AppConfig.TLS.CAFile, AppConfig.TLS.CertDir, AppConfig.TLS.GenerateCerts, AppConfig.TLS.RotateInterval)
+	}
+	if AppConfig.Prime.Enabled {
+		log.Printf(T("- 认证预热: %d 个/秒, 并发=%d"), AppConfig.Prime.Rate, AppConfig.Prime.Concurrency)
+	}
+	if AppConfig.Takeover.Enabled {
+		log.Printf(T("- 会话接管: 第 %d 个循环, 设备比例=%.0f%%"), AppConfig.Takeover.Cycle, AppConfig.Takeover.Ratio)
+	}
+	if credRotationEnabled() {
+		r := AppConfig.CredRotation
+		log.Printf(T("- 凭证轮换: 第 %d 个循环, 设备数=%d, 方式=%s, 探测间隔=%v, 超时=%v"), r.Cycle, r.Devices, r.Method, r.ProbeInterval, r.Timeout)
+	}
+	if fleetShrinkEnabled() {
+		f := AppConfig.FleetShrink
+		log.Printf(T("- 设备删除: 第 %d 个循环, 设备比例=%.1f%%, 方式=%s, 探测间隔=%v, 超时=%v, 保留删除=%v"), f.Cycle, f.Ratio, f.Method, f.ProbeInterval, f.Timeout, f.Keep)
+	}
+	if fleetGrowthEnabled() {
+		g := AppConfig.FleetGrowth
+		log.Printf(T("- 设备接入: 每分钟 %d 个, 来源=%s, 查找间隔=%v, 上限=%d"), g.Rate, growthSourceName(), g.PollInterval, g.Max)
+	}
+	if provisionEnabled() {
+		log.Printf(T("- 动态注册: 接口=%s, 设备编号前缀=%s, 并发上限=%d"), AppConfig.Provision.Path, AppConfig.Provision.Prefix, AppConfig.Provision.Concurrency)
+	}
+	if AppConfig.Offline.Enabled {
+		log.Printf(T("- 离线缓存: 第 %d 个循环起离线 %d 个循环, 设备比例=%.0f%%, 时间戳字段=%s"),
+			AppConfig.Offline.StartCycle, AppConfig.Offline.Cycles, AppConfig.Offline.Ratio, AppConfig.Offline.TimeKey)
+	}
+	if anomalyEnabled() {
+		a := AppConfig.Anomaly
+		key := a.Key
+		if key == "" {
+			key = T("第一个数值数据点")
+		}
+		log.Printf(T("- 异常注入: 设备比例=%.1f%%, 循环=%v, 数据点=%s, 跳变=%g, 持续 %d 个循环, 告警等待=%v"),
+			a.Ratio, a.Cycles, key, a.Jump, a.Hold, a.Timeout)
+	}
+	if httpDevicesEnabled() {
+		log.Printf(T("- HTTP设备: 设备比例=%.1f%%, 地址=%s, 超时=%v"),
+			AppConfig.HTTP.Ratio, AppConfig.HTTP.URL, AppConfig.HTTP.Timeout)
+	}
+	if dashboardsEnabled() {
+		log.Printf(T("- WebSocket看板: %d 个, 地址=%s"), AppConfig.Dashboards.Count, AppConfig.Dashboards.URL)
+	}
+	if glassLatencyEnabled() {
+		log.Printf(T("- 看板端到端延迟: 被订阅的设备每 %d 条消息附加序号数据点 %s"),
+			AppConfig.Dashboards.LatencyEvery, AppConfig.Dashboards.LatencyKey)
+	}
+	if attrSetEnabled() {
+		log.Printf(T("- 属性下发: %.1f 请求/秒, 设备 %d 个, 接口=%s%s, 主题=%s"),
+			AppConfig.AttrSet.Rate, AppConfig.AttrSet.Devices, AppConfig.API.URL, AppConfig.AttrSet.Path, AppConfig.AttrSet.Topic)
+	}
+	if commandFloodEnabled() {
+		c := AppConfig.Command
+		log.Printf(T("- 命令下发压测: 设备 %d 个, %d 轮, 并发 %d, 接口=%s%s, 主题=%s, 超时=%v"),
+			c.Devices, c.Rounds, c.Concurrency, AppConfig.API.URL, c.Path, c.Topic, c.Timeout)
+	}
+	if historyQueryEnabled() {
+		h := AppConfig.HistoryQuery
+		log.Printf(T("- 读写混合负载: 读:写 = %g, 并发上限 %d, 设备 %d 个, 时间范围 %v, 聚合查询 %.0f%%, 接口=%s"),
+			h.Ratio, h.Concurrency, h.Devices, h.Window, h.Aggregate*100, AppConfig.API.URL)
+	}
+	if deviceStatesEnabled() {
+		ds := AppConfig.DeviceStates
+		log.Printf(T("- 设备状态机: 正常->降级 %.1f%%, 正常->离线 %.1f%%, 降级->正常 %.1f%%, 降级->离线 %.1f%%, 离线->正常 %.1f%%, 离线->降级 %.1f%% (每循环), 降级时每 %d 个循环上报一次, 错误码字段=%s"),
+			ds.NormalToDegraded, ds.NormalToOffline, ds.DegradedToNormal, ds.DegradedToOffline,
+			ds.OfflineToNormal, ds.OfflineToDegraded, ds.DegradedEvery, ds.ErrorKey)
+	}
+	log.Printf(T("- 订阅配置: 主题=%v, QoS=%d"),
+		AppConfig.Subscribe.Topics, AppConfig.Subscribe.QoS)
+	log.Printf(T("- 额外数据流: %s"), streamSummary())
+	if AppConfig.Heartbeat.Ratio > 0 {
+		log.Printf(T("- 心跳设备: 比例=%.0f%%, 间隔=%v, 主题=%s"),
+			AppConfig.Heartbeat.Ratio, AppConfig.Heartbeat.Interval, AppConfig.Heartbeat.Topic)
+	}
+	log.Printf(T("- 平台消费者: 数量=%d, 共享组=%s, 主题=%s"),
+		AppConfig.Consumers.Count, AppConfig.Consumers.Group, AppConfig.Consumers.Topic)
+	log.Printf(T("- 测试配置: 间隔=%v, 循环=%d, 等待=%v"),
+		AppConfig.Test.DataInterval, AppConfig.Test.CycleCount, AppConfig.Test.ConnectWaitTime)
+	if AppConfig.Test.Stages != "" {
+		log.Printf(T("- 分阶段负载: %s"), AppConfig.Test.Stages)
+	}
+	if AppConfig.Test.CycleBarrier {
+		log.Printf(T("- 循环屏障: 超时=%v"), AppConfig.Test.BarrierTimeout)
+	}
+	log.Printf(T("- 数据配置: 最小值=%.1f, 最大值=%.1f, 数据点数=%d, 生成模式=%s"),
+		AppConfig.Data.MinValue, AppConfig.Data.MaxValue, AppConfig.Data.DataPointCount, AppConfig.Data.PayloadMode)
+	for _, f := range AppConfig.Data.Fields {
+		log.Printf(T("- 表达式数据点: %s = %s"), f.Key, f.Expr)
+	}
+	if stringsEnabled() {
+		log.Printf(T("- 字符串数据点: 每条消息 %d 个, 类型=%s, 长度=%d~%d, 平台长度上限=%d"), AppConfig.Strings.Count,
+			AppConfig.Strings.Kinds, AppConfig.Strings.MinLength, AppConfig.Strings.MaxLength, AppConfig.Strings.LimitLength)
+	}
+	if numericEnabled() {
+		n := AppConfig.Numeric
+		log.Printf(T("- 数值边界: 小数位数=%d, 字符串NaN/Infinity=%.1f%%, 极端数值=%.1f%%, 整数=%.1f%%, 科学计数法=%.1f%%"),
+			n.Precision, n.SpecialRatio, n.ExtremeRatio, n.IntegerRatio, n.ScientificRatio)
+	}
+	if countersEnabled() {
+		c := AppConfig.Counter
+		log.Printf(T("- 累计量模式: 每条消息增量=%v~%v, 清零概率=%v%%, 翻转值=%v"),
+			c.MinIncrement, c.MaxIncrement, c.ResetChance, c.Rollover)
+	}
+	if gpsEnabled() {
+		area := AppConfig.GPS.Routes
+		if area == "" {
+			area = AppConfig.GPS.BBox
+		}
+		log.Printf(T("- GPS轨迹模拟: %s, 速度=%.0f~%.0f km/h, 数据点=%s/%s/%s"), area,
+			AppConfig.GPS.MinSpeed, AppConfig.GPS.MaxSpeed, AppConfig.GPS.LatKey, AppConfig.GPS.LngKey, AppConfig.GPS.SpeedKey)
+	}
+	if AppConfig.Data.Profile != "" {
+		log.Printf(T("- 数据点名称: 网关消息格式=%s, 平台键数量=%d"), AppConfig.Data.Profile, distinctKeyCount())
+	} else if keyTemplateInUse() {
+		log.Printf(T("- 数据点名称: 模板=%s, 平台键数量=%d"), AppConfig.Data.KeyTemplate, distinctKeyCount())
+	}
+	if AppConfig.Database.DSN != "" {
+		log.Println(T("- 数据库配置: 使用完整连接字符串(database.dsn)"))
+	} else {
+		log.Printf(T("- 数据库配置: 主机=%s, 用户=%s, 数据库=%s"),
+			AppConfig.Database.Host, AppConfig.Database.User, AppConfig.Database.Name)
+	}
+	if AppConfig.Database.PgBouncer {
+		log.Println(T("- 数据库经pgbouncer连接: 不使用预备语句，SQL超时由查询超时控制"))
+	}
+	if replicaEnabled() {
+		log.Printf(T("- 只读副本: %s (监控和核对查询使用)"), AppConfig.Database.ReplicaHost)
+	}
+	log.Printf(T("- 数据库连接池: 最大连接=%d, 最大空闲=%d, 连接寿命=%v, 连接超时=%v, SQL超时=%v, 语句缓存=%d"),
+		AppConfig.Database.MaxConns, AppConfig.Database.MaxIdleConns, AppConfig.Database.ConnMaxLifetime,
+		AppConfig.Database.ConnectTimeout, AppConfig.Database.StatementTimeout, AppConfig.Database.StatementCache)
+	log.Printf(T("- 监控配置: 日志间隔=%v"),
+		AppConfig.Monitor.LogInterval)
+	log.Printf(T("- 监控配置: 循环日志=%v"),
+		AppConfig.Monitor.LogCycle)
+	if AppConfig.Monitor.LatencyWindow > 1 {
+		log.Printf(T("- 监控配置: 滚动分位数窗口=%d 个间隔"), AppConfig.Monitor.LatencyWindow)
+	}
+	log.Printf(T("- 监控配置: 入库计数=%s, 查询超时=%v, 重试=%d"),
+		AppConfig.Monitor.CountMode, AppConfig.Monitor.QueryTimeout, AppConfig.Monitor.QueryRetries)
+	if probeEnabled() {
+		log.Printf(T("- 数据库写入探测: 探测表=%s"), probeTable())
+	}
+	if tenantLimitEnabled() {
+		log.Printf(T("- 租户限额验证: 租户=%s, 限额=%.0f 点/秒, 容差=%.0f%%, 其他租户写入率阈值=%.0f%%"),
+			AppConfig.TenantLimit.Tenant, AppConfig.TenantLimit.Quota, AppConfig.TenantLimit.Tolerance, AppConfig.TenantLimit.MinWriteRatio)
+	}
+	log.Printf(T("- 长稳测试配置: 启用=%v, 检查点=%s, 间隔=%v"),
+		AppConfig.Soak.Enabled, AppConfig.Soak.CheckpointFile, AppConfig.Soak.CheckpointInterval)
+	if resumeEnabled() {
+		log.Printf(T("- 中断恢复: 启用, 检查点=%s"), AppConfig.Soak.CheckpointFile)
+	}
+	for _, g := range tenantGroups {
+		log.Printf(T("- 多租户分组 %s: 设备=%d, token文件=%s, 主题=%s, 每 %d 个循环发送一次"), g.Name, g.Clients, g.TokenFile, g.Topic, g.every)
+	}
+	if daemonEnabled() {
+		log.Printf(T("- 守护模式: 状态接口=http://%s/status"), controlAddress())
+	}
+	if AppConfig.Cron.Expr != "" {
+		log.Printf(T("- 定时运行: 表达式=%q, 最长时长=%v"), AppConfig.Cron.Expr, AppConfig.Cron.Duration)
+	}
+	log.Printf(T("- 设备统计配置: 启用=%v, 最差设备数=%d, CSV=%s"),
+		AppConfig.Stats.PerDevice, AppConfig.Stats.WorstN, AppConfig.Stats.CSVFile)
+	log.Printf(T("- 结果库配置: 启用=%v, schema=%s"),
+		AppConfig.Results.Enabled, AppConfig.Results.Schema)
+	log.Printf(T("- 自适应速率: 启用=%v, 入库率阈值=%.0f%%, 连续间隔=%d, 步长=%.0f%%"),
+		AppConfig.Adaptive.Enabled, AppConfig.Adaptive.MinWriteRatio, AppConfig.Adaptive.Patience, AppConfig.Adaptive.Step)
+	log.Printf(T("- 失败预算: 连接失败率>%.1f%%, 发布错误率>%.1f%% 持续 %v (0为不检查)"),
+		AppConfig.Abort.MaxConnectFailure, AppConfig.Abort.MaxPublishError, AppConfig.Abort.Window)
+	if alertsEnabled() {
+		a := AppConfig.Alerts
+		log.Printf(T("- 告警: 格式=%s, 写入率<%.0f%% 连续 %d 个间隔, 入库停滞 %d 个间隔, 错误率>%.1f%%, 重复间隔=%v (0为不检查)"),
+			a.Format, a.MinWriteRatio, a.Patience, a.DBStallIntervals, a.MaxErrorRate, a.Cooldown)
+	}
+	if AppConfig.Report.MailTo != "" {
+		log.Printf(T("- 报告邮件: 收件人=%s, SMTP服务器=%s"), AppConfig.Report.MailTo, AppConfig.Report.SMTPHost)
+	}
+	if AppConfig.Report.Webhook != "" {
+		log.Println(T("- 报告上传: 测试结束后上传到Webhook"))
+	}
+	log.Printf(T("- 发布重试: 最多次数=%d, 退避=%v~%v, 抖动=%.0f%%"),
+		AppConfig.Retry.MaxAttempts, AppConfig.Retry.Backoff, AppConfig.Retry.MaxBackoff, AppConfig.Retry.Jitter*100)
+	log.Printf(T("- 断线重连: 方式=%s, 首次等待=%v, 上限=%v, 倍数=%.1f, 抖动=%.0f%%"),
+		AppConfig.Reconnect.Mode, AppConfig.Reconnect.Initial, AppConfig.Reconnect.MaxInterval,
+		AppConfig.Reconnect.Multiplier, AppConfig.Reconnect.Jitter*100)
+	t := AppConfig.Throttle
+	if t.MaxProcs > 0 || t.Nice > 0 || t.CPULimit > 0 || t.GCPercent > 0 {
+		log.Printf(T("- 资源限制: 核数=%d, 调度优先级=%d, CPU上限=%.0f%%, GC比例=%d (0为不限制)"),
+			t.MaxProcs, t.Nice, t.CPULimit, t.GCPercent)
+	}
+	if rampDownEnabled() {
+		log.Printf(T("- 逐步断开: %d 个/秒, 抽样 %d 个设备, 超时=%v"),
+			AppConfig.RampDown.Rate, AppConfig.RampDown.Samples, AppConfig.RampDown.Timeout)
+	}
+	log.Printf(T("- 输出配置: 目录=%s, 日志上限=%d MB x %d, 最小剩余磁盘=%d MB"),
+		AppConfig.Output.Dir, AppConfig.Output.MaxLogSize, AppConfig.Output.MaxLogFiles, AppConfig.Output.MinFreeDisk)
+	if errorLogEnabled() {
+		log.Printf(T("- 错误日志: 相同错误只输出第一次，每 %v 汇总一次出现次数"), AppConfig.Output.ErrorSummaryInterval)
+	}
+}
+
+// rejectStagePoints 数据点由生成器固定时，阶段不能再指定每条消息的数据点数
+func rejectStagePoints(generator string) {
+	for _, s := range loadStages {
+		if s.points > 0 {
+			log.Fatalf(T("阶段 %s 指定了每条消息的数据点数，与%s冲突"), s.name, generator)
+		}
+	}
+}
+
+// overrideConfigWithFlags 使用命令行参数覆盖配置文件
+func overrideConfigWithFlags() {
+	// 设备配置
+	if *deviceTokenFile != "" {
+		AppConfig.Device.TokenFile = *deviceTokenFile
+	}
+	if *clientNumber > 0 {
+		AppConfig.Device.ClientNumber = *clientNumber
+	}
+	if *tokenMmap {
+		AppConfig.Device.TokenMmap = true
+	}
+	if *duplicateTokens != "" {
+		AppConfig.Device.DuplicateTokens = *duplicateTokens
+	}
+	if *deviceFilter != "" {
+		AppConfig.Device.Filter = *deviceFilter
+	}
+
+	// MQTT配置
+	if *mqttServer != "" {
+		AppConfig.MQTT.Server = *mqttServer
+	}
+	if *qos >= 0 {
+		AppConfig.MQTT.QoS = *qos
+	}
+	if *localBrokerFlag {
+		AppConfig.LocalBroker.Enabled = true
+	}
+	if *localBrokerListen != "" {
+		AppConfig.LocalBroker.Listen = *localBrokerListen
+	}
+	if *sinkMode != "" {
+		AppConfig.Sink.Mode = *sinkMode
+	}
+	if *sinkFile != "" {
+		AppConfig.Sink.File = *sinkFile
+	}
+	if *qosMixFlag != "" {
+		AppConfig.MQTT.QoSMix = *qosMixFlag
+	}
+	if *topic != "" {
+		AppConfig.MQTT.Topic = *topic
+	}
+	if *clientEngine != "" {
+		AppConfig.MQTT.Engine = *clientEngine
+	}
+	if *mqttVersion > 0 {
+		AppConfig.MQTT.Version = *mqttVersion
+	}
+	if *contentType != "" {
+		AppConfig.MQTT.Properties.ContentType = *contentType
+	}
+	if *userProperties != "" {
+		props, err := parseUserProperties(*userProperties)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		AppConfig.MQTT.Properties.User = props
+	}
+
+	// 订阅配置
+	if *subscribeTopicsFlag != "" {
+		AppConfig.Subscribe.Topics = strings.Split(*subscribeTopicsFlag, ",")
+	}
+	if *subscribeQoS >= 0 {
+		AppConfig.Subscribe.QoS = *subscribeQoS
+	}
+
+	// 心跳设备配置
+	if *heartbeatRatio > 0 {
+		AppConfig.Heartbeat.Ratio = *heartbeatRatio
+	}
+	if *heartbeatInterval > 0 {
+		AppConfig.Heartbeat.Interval = *heartbeatInterval
+	}
+
+	// 设备信息配置
+	if *deviceInfo {
+		AppConfig.DeviceInfo.Enabled = true
+	}
+
+	// 测试配置
+	if *dataInterval > 0 {
+		AppConfig.Test.DataInterval = *dataInterval
+	}
+	if *testCycleCount > 0 {
+		AppConfig.Test.CycleCount = *testCycleCount
+	}
+	if *connectWaitTime > 0 {
+		AppConfig.Test.ConnectWaitTime = *connectWaitTime
+	}
+	if *seed != 0 {
+		AppConfig.Test.Seed = *seed
+	}
+	if *stagesFlag != "" {
+		AppConfig.Test.Stages = *stagesFlag
+	}
+	if *cycleBarrierFlag {
+		AppConfig.Test.CycleBarrier = true
+	}
+	if *barrierTimeout > 0 {
+		AppConfig.Test.BarrierTimeout = *barrierTimeout
+	}
+
+	// 数据配置
+	if *minValue > 0 {
+		AppConfig.Data.MinValue = *minValue
+	}
+	if *maxValue > 0 {
+		AppConfig.Data.MaxValue = *maxValue
+	}
+	if *dataPointCount > 0 {
+		AppConfig.Data.DataPointCount = *dataPointCount
+	}
+	if *payloadMode != "" {
+		AppConfig.Data.PayloadMode = *payloadMode
+	}
+	if *keyTemplate != "" {
+		AppConfig.Data.KeyTemplate = *keyTemplate
+	}
+	if *payloadProfileName != "" {
+		AppConfig.Data.Profile = *payloadProfileName
+	}
+	if *stringFieldCount > 0 {
+		AppConfig.Strings.Count = *stringFieldCount
+	}
+	if *stringKinds != "" {
+		AppConfig.Strings.Kinds = *stringKinds
+	}
+	if *precision > 0 {
+		AppConfig.Numeric.Precision = *precision
+	}
+	if *numericEdges {
+		n := &AppConfig.Numeric
+		if n.SpecialRatio == 0 && n.ExtremeRatio == 0 && n.IntegerRatio == 0 && n.ScientificRatio == 0 {
+			n.SpecialRatio, n.ExtremeRatio, n.IntegerRatio, n.ScientificRatio = 1, 1, 20, 5
+		}
+	}
+	if *countersFlag {
+		AppConfig.Counter.Enabled = true
+	}
+	if *gpsRoutes != "" {
+		AppConfig.GPS.Routes = *gpsRoutes
+	}
+	if *gpsBBox != "" {
+		AppConfig.GPS.BBox = *gpsBBox
+	}
+	if *fieldsFlag != "" {
+		fields, err := parseFieldsFlag(*fieldsFlag)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		AppConfig.Data.Fields = fields
+	}
+
+	// 数据库配置
+	if *dbHost != "" {
+		AppConfig.Database.Host = *dbHost
+	}
+	if *dbUser != "" {
+		AppConfig.Database.User = *dbUser
+	}
+	if *dbPassword != "" {
+		AppConfig.Database.Password = *dbPassword
+	}
+	if *dbName != "" {
+		AppConfig.Database.Name = *dbName
+	}
+	if *dbSSLMode != "" {
+		AppConfig.Database.SSLMode = *dbSSLMode
+	}
+	if *dbDSN != "" {
+		AppConfig.Database.DSN = *dbDSN
+	}
+	if *dbPgBouncer {
+		AppConfig.Database.PgBouncer = true
+	}
+	if *dbReplica != "" {
+		AppConfig.Database.ReplicaHost = *dbReplica
+	}
+	if *dbMaxConns > 0 {
+		AppConfig.Database.MaxConns = *dbMaxConns
+	}
+	if *dbMaxIdleConns > 0 {
+		AppConfig.Database.MaxIdleConns = *dbMaxIdleConns
+	}
+	if *dbConnMaxLifetime > 0 {
+		AppConfig.Database.ConnMaxLifetime = *dbConnMaxLifetime
+	}
+	if *dbConnectTimeout > 0 {
+		AppConfig.Database.ConnectTimeout = *dbConnectTimeout
+	}
+	if *dbStatementTimeout != 0 {
+		AppConfig.Database.StatementTimeout = *dbStatementTimeout
+	}
+	if *dbStatementCache != 0 {
+		AppConfig.Database.StatementCache = *dbStatementCache
+	}
+
+	// 监控配置
+	if *logInterval > 0 {
+		AppConfig.Monitor.LogInterval = *logInterval
+	}
+	if *latencyWindow > 0 {
+		AppConfig.Monitor.LatencyWindow = *latencyWindow
+	}
+
+	if logCycle != nil {
+		AppConfig.Monitor.LogCycle = *logCycle
+	}
+
+	if *monitorQueryTimeout > 0 {
+		AppConfig.Monitor.QueryTimeout = *monitorQueryTimeout
+	}
+
+	if *countMode != "" {
+		AppConfig.Monitor.CountMode = *countMode
+	}
+
+	if *dbProbe {
+		AppConfig.Monitor.Probe = true
+	}
+
+	// 定时运行
+	if *cronExpr != "" {
+		AppConfig.Cron.Expr = *cronExpr
+	}
+	if *cronDuration > 0 {
+		AppConfig.Cron.Duration = *cronDuration
+	}
+
+	// 守护模式
+	if *daemonFlag {
+		AppConfig.Daemon.Enabled = true
+	}
+	if *stallTimeout != 0 {
+		AppConfig.Daemon.StallTimeout = *stallTimeout
+	}
+
+	// 长稳测试配置
+	if *soakMode {
+		AppConfig.Soak.Enabled = true
+	}
+	if *checkpointFile != "" {
+		AppConfig.Soak.CheckpointFile = *checkpointFile
+	}
+	if *checkpointInterval > 0 {
+		AppConfig.Soak.CheckpointInterval = *checkpointInterval
+	}
+	if *resumeFlag {
+		AppConfig.Soak.Resume = true
+	}
+
+	// 设备统计配置
+	if *deviceStatsEnabled {
+		AppConfig.Stats.PerDevice = true
+	}
+	if *deviceStatsWorstN > 0 {
+		AppConfig.Stats.WorstN = *deviceStatsWorstN
+	}
+	if *deviceStatsCSV != "" {
+		AppConfig.Stats.CSVFile = *deviceStatsCSV
+	}
+
+	// 入库核对配置
+	if *verifyCurrent > 0 {
+		AppConfig.Verify.CurrentSamples = *verifyCurrent
+	}
+	if *verifyChecksum > 0 {
+		AppConfig.Verify.ChecksumSamples = *verifyChecksum
+	}
+
+	// 租户限额验证配置
+	if *limitTenant != "" {
+		AppConfig.TenantLimit.Tenant = *limitTenant
+	}
+	if *tenantQuota > 0 {
+		AppConfig.TenantLimit.Quota = *tenantQuota
+	}
+
+	// 结果库配置
+	if *saveResults {
+		AppConfig.Results.Enabled = true
+	}
+
+	// 平台消费者配置
+	if *consumerCount > 0 {
+		AppConfig.Consumers.Count = *consumerCount
+	}
+	if *consumerGroup != "" {
+		AppConfig.Consumers.Group = *consumerGroup
+	}
+
+	// 自适应速率配置
+	if *adaptiveRate {
+		AppConfig.Adaptive.Enabled = true
+	}
+
+	// 失败预算配置
+	if *abortConnectFailure > 0 {
+		AppConfig.Abort.MaxConnectFailure = *abortConnectFailure
+	}
+	if *abortPublishError > 0 {
+		AppConfig.Abort.MaxPublishError = *abortPublishError
+	}
+	if *abortWindow > 0 {
+		AppConfig.Abort.Window = *abortWindow
+	}
+
+	// 告警配置
+	if *alertWebhook != "" {
+		AppConfig.Alerts.Webhook = *alertWebhook
+	}
+	if *alertFormat != "" {
+		AppConfig.Alerts.Format = *alertFormat
+	}
+
+	// 报告发送配置
+	if *reportMailTo != "" {
+		AppConfig.Report.MailTo = *reportMailTo
+	}
+	if *reportWebhook != "" {
+		AppConfig.Report.Webhook = *reportWebhook
+	}
+
+	// 域名解析配置
+	if *resolveMode != "" {
+		AppConfig.Resolve.Mode = *resolveMode
+	}
+	if *pinIPs != "" {
+		AppConfig.Resolve.PinIPs = strings.Split(*pinIPs, ",")
+	}
+	if *dnsServers != "" {
+		AppConfig.Resolve.DNSServers = strings.Split(*dnsServers, ",")
+	}
+	if *ipFamily != "" {
+		AppConfig.Resolve.Family = *ipFamily
+	}
+
+	// TLS与设备证书配置
+	if *tlsCAFile != "" {
+		AppConfig.TLS.CAFile = *tlsCAFile
+	}
+	if *certDir != "" {
+		AppConfig.TLS.CertDir = *certDir
+	}
+	if *generateCerts {
+		AppConfig.TLS.GenerateCerts = true
+	}
+	if *certRotate > 0 {
+		AppConfig.TLS.RotateInterval = *certRotate
+	}
+
+	// 认证缓存预热配置
+	if *primeAuth {
+		AppConfig.Prime.Enabled = true
+	}
+	if *primeRate > 0 {
+		AppConfig.Prime.Rate = *primeRate
+	}
+
+	// 会话接管场景配置
+	if *takeoverFlag {
+		AppConfig.Takeover.Enabled = true
+	}
+	if *takeoverCycle > 0 {
+		AppConfig.Takeover.Cycle = *takeoverCycle
+	}
+	if *takeoverRatio > 0 {
+		AppConfig.Takeover.Ratio = *takeoverRatio
+	}
+
+	// 凭证轮换场景配置
+	if *rotateCredentials > 0 {
+		AppConfig.CredRotation.Devices = *rotateCredentials
+	}
+	if *rotateCycle > 0 {
+		AppConfig.CredRotation.Cycle = *rotateCycle
+	}
+
+	// 设备删除场景配置
+	if *deleteDevices > 0 {
+		AppConfig.FleetShrink.Ratio = *deleteDevices
+	}
+	if *deleteCycle > 0 {
+		AppConfig.FleetShrink.Cycle = *deleteCycle
+	}
+
+	// 设备接入场景配置
+	if *growRate > 0 {
+		AppConfig.FleetGrowth.Rate = *growRate
+	}
+	if *growSource != "" {
+		AppConfig.FleetGrowth.Source = *growSource
+	}
+
+	// 动态注册模式配置
+	if *provisionFlag {
+		AppConfig.Provision.Enabled = true
+	}
+	if *provisionSecret != "" {
+		AppConfig.Provision.Secret = *provisionSecret
+	}
+
+	// 离线缓存场景配置
+	if *offlineFlag {
+		AppConfig.Offline.Enabled = true
+	}
+	if *offlineStart > 0 {
+		AppConfig.Offline.StartCycle = *offlineStart
+	}
+	if *offlineCycles > 0 {
+		AppConfig.Offline.Cycles = *offlineCycles
+	}
+	if *offlineRatio > 0 {
+		AppConfig.Offline.Ratio = *offlineRatio
+	}
+
+	// 异常注入配置
+	if *anomalyRatio > 0 {
+		AppConfig.Anomaly.Ratio = *anomalyRatio
+	}
+	if *anomalyCycles != "" {
+		cycles, err := parseAnomalyCycles(*anomalyCycles)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		AppConfig.Anomaly.Cycles = cycles
+	}
+
+	// 多协议混合配置
+	if *httpRatio > 0 {
+		AppConfig.HTTP.Ratio = *httpRatio
+	}
+	if *httpURL != "" {
+		AppConfig.HTTP.URL = *httpURL
+	}
+	if *dashboardCount > 0 {
+		AppConfig.Dashboards.Count = *dashboardCount
+	}
+	if *dashboardURL != "" {
+		AppConfig.Dashboards.URL = *dashboardURL
+	}
+	if *dashboardLatencyEvery > 0 {
+		AppConfig.Dashboards.LatencyEvery = *dashboardLatencyEvery
+	}
+
+	// 平台API配置
+	if *apiURL != "" {
+		AppConfig.API.URL = *apiURL
+	}
+	if *apiKey != "" {
+		if AppConfig.API.Headers == nil {
+			AppConfig.API.Headers = make(map[string]string)
+		}
+		AppConfig.API.Headers["x-api-key"] = *apiKey
+	}
+	if *attrSetRate > 0 {
+		AppConfig.AttrSet.Rate = *attrSetRate
+	}
+	if *attrSetDevices > 0 {
+		AppConfig.AttrSet.Devices = *attrSetDevices
+	}
+	if *commandDevices > 0 {
+		AppConfig.Command.Devices = *commandDevices
+	}
+	if *commandRounds > 0 {
+		AppConfig.Command.Rounds = *commandRounds
+	}
+	if *commandConcurrency > 0 {
+		AppConfig.Command.Concurrency = *commandConcurrency
+	}
+	if *historyQueryRatio > 0 {
+		AppConfig.HistoryQuery.Ratio = *historyQueryRatio
+	}
+	if *historyQueryConcurrency > 0 {
+		AppConfig.HistoryQuery.Concurrency = *historyQueryConcurrency
+	}
+
+	// 设备状态机配置
+	if *deviceStatesFlag {
+		AppConfig.DeviceStates.Enabled = true
+	}
+
+	// 发布重试配置
+	if *publishRetries > 0 {
+		AppConfig.Retry.MaxAttempts = *publishRetries
+	}
+	if *retryBackoff > 0 {
+		AppConfig.Retry.Backoff = *retryBackoff
+	}
+
+	// 断线重连配置
+	if *reconnectMode != "" {
+		AppConfig.Reconnect.Mode = *reconnectMode
+	}
+	if *reconnectMax > 0 {
+		AppConfig.Reconnect.MaxInterval = *reconnectMax
+	}
+
+	// 资源限制配置
+	if *maxProcs > 0 {
+		AppConfig.Throttle.MaxProcs = *maxProcs
+	}
+	if *niceLevel > 0 {
+		AppConfig.Throttle.Nice = *niceLevel
+	}
+	if *cpuLimit > 0 {
+		AppConfig.Throttle.CPULimit = *cpuLimit
+	}
+	if *gcPercent > 0 {
+		AppConfig.Throttle.GCPercent = *gcPercent
+	}
+
+	// ACL检查配置
+	if *aclSamples > 0 {
+		AppConfig.ACL.Samples = *aclSamples
+	}
+
+	// 逐步断开配置
+	if *rampDownRate > 0 {
+		AppConfig.RampDown.Rate = *rampDownRate
+	}
+
+	// 输出目录配置
+	if *outputDir != "" {
+		AppConfig.Output.Dir = *outputDir
+	}
+	if *logMaxSize > 0 {
+		AppConfig.Output.MaxLogSize = *logMaxSize
+	}
+	if *minFreeDisk != 0 {
+		AppConfig.Output.MinFreeDisk = *minFreeDisk
+	}
+	if *errorSummaryInterval != 0 {
+		AppConfig.Output.ErrorSummaryInterval = *errorSummaryInterval
 	}
 }
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// ConnectLatency 记录建立连接(CONNECT→CONNACK)的耗时分布及按秒的时间序列
+type ConnectLatency struct {
+	total Histogram
+
+	mu     sync.Mutex
+	start  time.Time            // 第一个连接开始的时间
+	series map[int64]*Histogram // 按连接开始后的秒数分组
+}
+
+// connectLatency 全局连接耗时统计
+var connectLatency = &ConnectLatency{series: make(map[int64]*Histogram)}
+
+// markStart 记录爬坡开始时间
+func (c *ConnectLatency) markStart(t time.Time) {
+	c.mu.Lock()
+	c.start = t
+	c.mu.Unlock()
+}
+
+// Record 记录一次成功连接的耗时，started为发起连接的时间
+func (c *ConnectLatency) Record(started time.Time, latency time.Duration) {
+	c.total.Record(latency)
+
+	c.mu.Lock()
+	second := int64(started.Sub(c.start) / time.Second)
+	h := c.series[second]
+	if h == nil {
+		h = &Histogram{}
+		c.series[second] = h
+	}
+	c.mu.Unlock()
+
+	h.Record(latency)
+}
+
+// Report 输出连接耗时分布和时间序列
+func (c *ConnectLatency) Report() {
+	if c.total.Count() == 0 {
+		return
+	}
+
+	log.Printf(T("\n========== 连接耗时(CONNECT→CONNACK) =========="))
+	log.Printf(T("成功连接数: %d, 平均: %v, p50: %v, p90: %v, p99: %v, 最大: %v"),
+		c.total.Count(), c.total.Mean(), c.total.Quantile(0.50), c.total.Quantile(0.90),
+		c.total.Quantile(0.99), c.total.Max())
+	if primeResult.done {
+		log.Printf(T("认证缓存已预热(热认证)，预热阶段认证耗时 p50: %v, p99: %v"),
+			primeResult.latency.Quantile(0.50), primeResult.latency.Quantile(0.99))
+	} else {
+		log.Printf(T("认证缓存未预热(冷认证)，使用 --prime-auth 可测量热认证下的连接性能"))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var last int64
+	for second := range c.series {
+		if second > last {
+			last = second
+		}
+	}
+	log.Printf(T("按秒时间序列(以发起连接的时间归类):"))
+	for second := int64(0); second <= last; second++ {
+		h := c.series[second]
+		if h == nil {
+			log.Printf(T("  第%3d秒: 连接数 0"), second)
+			continue
+		}
+		log.Printf(T("  第%3d秒: 连接数 %d, p50: %v, p99: %v, 最大: %v"),
+			second, h.Count(), h.Quantile(0.50), h.Quantile(0.99), h.Max())
+	}
+	log.Printf("==============================")
+}
@@ -0,0 +1,14 @@
+//go:build windows
+
+package main
+
+import "syscall"
+
+// setupConsole 将控制台输出代码页切换为UTF-8
+//
+// 中文Windows控制台默认使用GBK(代码页936)，直接输出的UTF-8中文日志会显示为乱码。
+// 输出重定向到文件或不在控制台中运行时调用失败，忽略即可。
+func setupConsole() {
+	const utf8CodePage = 65001
+	syscall.NewLazyDLL("kernel32.dll").NewProc("SetConsoleOutputCP").Call(utf8CodePage)
+}
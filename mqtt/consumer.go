@@ -0,0 +1,162 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"sync/atomic"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// 平台消费者相关命令行参数
+var (
+	consumerCount = flag.Int("consumers", 0, "模拟平台消费者数量，以共享订阅接收设备上报的消息")
+	consumerGroup = flag.String("consumer-group", "", "消费者共享订阅组名($share/<组名>/<主题>)")
+)
+
+// consumer 模拟的平台消费者
+type consumer struct {
+	id       int
+	client   mqtt.Client
+	received uint64 // 收到的消息数
+}
+
+// consumerReceived 全部消费者收到的消息总数
+var consumerReceived uint64
+
+// consumerTopic 消费者订阅的主题，配置了组名时使用共享订阅
+func consumerTopic() string {
+	if AppConfig.Consumers.Group == "" {
+		return AppConfig.Consumers.Topic
+	}
+	return fmt.Sprintf("$share/%s/%s", AppConfig.Consumers.Group, AppConfig.Consumers.Topic)
+}
+
+// startConsumers 在设备上线前连接并订阅全部消费者，连接失败的消费者不参与统计
+func startConsumers(runID string) []*consumer {
+	if AppConfig.Consumers.Count <= 0 {
+		return nil
+	}
+
+	topic := consumerTopic()
+	var consumers []*consumer
+	for i := 0; i < AppConfig.Consumers.Count; i++ {
+		c := &consumer{id: i + 1}
+		opts := mqtt.NewClientOptions().
+			SetClientID(fmt.Sprintf("tp-consumer-%s-%d", runID, c.id)).
+			AddBroker(AppConfig.MQTT.Server).
+			SetUsername(AppConfig.Consumers.Username).
+			SetPassword(AppConfig.Consumers.Password).
+			SetCleanSession(true).
+			SetAutoReconnect(true).
+			SetKeepAlive(60 * time.Second).
+			SetOrderMatters(false) // 并发处理消息，避免消费者自身成为瓶颈
+		if tlsState.base != nil {
+			opts.SetTLSConfig(tlsState.base)
+		}
+		c.client = mqtt.NewClient(opts)
+
+		if token := c.client.Connect(); token.Wait() && token.Error() != nil {
+			log.Printf(T("消费者 %d 连接失败: %v"), c.id, token.Error())
+			continue
+		}
+		token := c.client.Subscribe(topic, byte(AppConfig.Consumers.QoS), func(mqtt.Client, mqtt.Message) {
+			atomic.AddUint64(&c.received, 1)
+			atomic.AddUint64(&consumerReceived, 1)
+		})
+		token.Wait()
+		if err := token.Error(); err != nil {
+			log.Printf(T("消费者 %d 订阅 %s 失败: %v"), c.id, topic, err)
+			c.client.Disconnect(200)
+			continue
+		}
+		if code := token.(*mqtt.SubscribeToken).Result()[topic]; code == subackFailure {
+			log.Printf(T("消费者 %d 订阅 %s 被服务器拒绝，请检查消费者账号的ACL"), c.id, topic)
+			c.client.Disconnect(200)
+			continue
+		}
+		consumers = append(consumers, c)
+	}
+
+	log.Printf(T("平台消费者: %d/%d 个已订阅 %s"), len(consumers), AppConfig.Consumers.Count, topic)
+	return consumers
+}
+
+// drainConsumers 设备停止发送后等待消费者收完在途消息(接收数1秒内不再增加或最多等待10秒)
+func drainConsumers(consumers []*consumer) {
+	if len(consumers) == 0 {
+		return
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	last := atomic.LoadUint64(&consumerReceived)
+	for time.Now().Before(deadline) {
+		time.Sleep(time.Second)
+		current := atomic.LoadUint64(&consumerReceived)
+		if current == last {
+			break
+		}
+		last = current
+	}
+}
+
+// stopConsumers 断开全部消费者
+func stopConsumers(consumers []*consumer) {
+	for _, c := range consumers {
+		c.client.Disconnect(200)
+	}
+}
+
+// reportConsumers 输出消费者接收统计和共享订阅的负载均衡情况
+func reportConsumers(consumers []*consumer, sent uint64, duration time.Duration) {
+	if len(consumers) == 0 {
+		return
+	}
+
+	total := atomic.LoadUint64(&consumerReceived)
+	// 共享订阅时每条消息只投递给一个消费者，否则每个消费者都会收到
+	expected := sent
+	if AppConfig.Consumers.Group == "" {
+		expected = sent * uint64(len(consumers))
+	}
+
+	log.Println(T("\n========== 平台消费者统计 =========="))
+	log.Printf(T("订阅主题: %s, QoS: %d, 消费者数: %d"), consumerTopic(), AppConfig.Consumers.QoS, len(consumers))
+	log.Printf(T("收到消息: %d / 应收 %d (%.1f%%), 总速率: %.1f 条/秒"),
+		total, expected, percent(total, expected), ratePerSecond(float64(total), duration))
+
+	// 每个消费者的接收数、占比及速率
+	var minCount, maxCount uint64 = math.MaxUint64, 0
+	var sum, sumSq float64
+	for _, c := range consumers {
+		n := atomic.LoadUint64(&c.received)
+		log.Printf(T(" - 消费者 %d: %d 条 (%.1f%%), %.1f 条/秒"),
+			c.id, n, percent(n, total), ratePerSecond(float64(n), duration))
+		minCount = min(minCount, n)
+		maxCount = max(maxCount, n)
+		sum += float64(n)
+		sumSq += float64(n) * float64(n)
+	}
+
+	// 变异系数越小分配越均匀
+	mean := sum / float64(len(consumers))
+	if mean > 0 {
+		stddev := math.Sqrt(math.Max(sumSq/float64(len(consumers))-mean*mean, 0))
+		log.Printf(T("负载均衡: 最少 %d, 最多 %d, 变异系数 %.3f"), minCount, maxCount, stddev/mean)
+	}
+	if total < expected {
+		log.Printf(T("提示: 有 %d 条消息未被消费者收到，可能是消费者处理能力不足、Broker丢弃(QoS0)或测试结束时仍在途"),
+			expected-total)
+	}
+	log.Println("===============================")
+}
+
+// percent 计算百分比，分母为0时返回0
+func percent(part, whole uint64) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return float64(part) * 100 / float64(whole)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"sync/atomic"
+
+	"github.com/brianvoe/gofakeit/v7"
+)
+
+// 累计量命令行参数
+var countersFlag = flag.Bool("counters", false, "累计量模式：数据点按电能表示值的方式单调递增，偶尔清零或到达上限后翻转，用于测试平台对累计量的差值和聚合计算")
+
+// counterStats 累计量清零和翻转的次数，平台的差值计算需要正确处理这两种情况
+var counterStats struct {
+	resets    atomic.Uint64 // 清零次数(换表、设备重启)
+	rollovers atomic.Uint64 // 到达上限后从0重新开始的次数
+}
+
+// counterDecimals 累计量保留的小数位数，与电能表示值的精度一致
+const counterDecimals = 2
+
+// countersEnabled 是否启用累计量模式
+func countersEnabled() bool {
+	return AppConfig.Counter.Enabled
+}
+
+// counterGenerator 每个数据点是一个单调递增的累计量
+//
+// 每条消息的增量在[min_increment, max_increment]内随机(按设备负荷小幅波动)，
+// 每个数据点每条消息有reset_chance的概率清零，到达rollover时减去rollover继续累计。
+type counterGenerator struct {
+	jsonGenerator
+	load []float64 // 各数据点的负荷系数，使同一设备的增量有持续的高低差异
+}
+
+// newCounterGenerator 创建累计量生成器，初始示值在[min_value, max_value]内随机
+func newCounterGenerator(keys []string, faker *gofakeit.Faker) *counterGenerator {
+	g := &counterGenerator{
+		jsonGenerator: jsonGenerator{data: newSensorData(keys), faker: faker},
+		load:          make([]float64, len(keys)),
+	}
+	for i := range g.data {
+		g.data[i].Value = round(faker.Float64Range(AppConfig.Data.MinValue, AppConfig.Data.MaxValue), counterDecimals)
+		g.load[i] = faker.Float64Range(0.2, 1)
+	}
+	return g
+}
+
+func (g *counterGenerator) Next() ([]byte, error) {
+	cfg := AppConfig.Counter
+	for i := range g.data {
+		if cfg.ResetChance > 0 && g.faker.Float64Range(0, 100) < cfg.ResetChance {
+			g.data[i].Value = 0
+			counterStats.resets.Add(1)
+			continue
+		}
+		// 负荷系数在0.2~1之间缓慢变化，模拟用电高峰和低谷
+		g.load[i] = min(max(g.load[i]+g.faker.Float64Range(-0.05, 0.05), 0.2), 1)
+		v := g.data[i].Value + cfg.MinIncrement + (cfg.MaxIncrement-cfg.MinIncrement)*g.load[i]*g.faker.Float64Range(0, 1)
+		if cfg.Rollover > 0 && v >= cfg.Rollover {
+			v -= cfg.Rollover
+			counterStats.rollovers.Add(1)
+		}
+		g.data[i].Value = round(v, counterDecimals)
+	}
+
+	g.buf = getPayloadBuffer()
+	payload, err := g.data.AppendJSON(*g.buf)
+	if err != nil {
+		g.Done()
+		return nil, err
+	}
+	*g.buf = payload
+	return payload, nil
+}
+
+// reportCounters 输出累计量清零和翻转的次数(未启用时不输出)
+func reportCounters() {
+	if !countersEnabled() {
+		return
+	}
+	log.Println(T("\n========== 累计量 =========="))
+	log.Printf(T("清零: %d 次, 翻转: %d 次 (平台按差值计算用量时应忽略这些点的负差值)"),
+		counterStats.resets.Load(), counterStats.rollovers.Load())
+	log.Println("===============================")
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-basic/uuid"
+)
+
+// 凭证轮换命令行参数
+var (
+	rotateCredentials = flag.Int("rotate-credentials", 0, "凭证轮换：测试中途更换N个设备的凭证(voucher)，测量Broker多快接受新凭证、旧凭证何时不能再连接，结束后恢复原凭证，0为不启用")
+	rotateCycle       = flag.Int("rotate-cycle", 0, "凭证轮换：在第几个循环更换凭证(默认为总循环数的一半)")
+)
+
+// 通过平台API更换凭证的默认配置，对应ThingsPanel的设备更新接口
+const (
+	defaultRotationPath = "/api/v1/device"
+	defaultRotationBody = `{"id":"{device_id}","voucher":{voucher}}`
+)
+
+// rotatedDevice 更换凭证的设备及探测结果
+type rotatedDevice struct {
+	id          string
+	oldVoucher  string
+	newVoucher  string
+	oldUsername string
+	newUsername string
+
+	rotatedAt   time.Time     // 更换凭证的请求返回的时间
+	newAccepted time.Duration // 从更换到新凭证首次连接成功的耗时，-1为超时前未成功
+	oldRejected time.Duration // 从更换到旧凭证首次被拒绝的耗时，-1为超时前仍可连接
+	oldAccepts  int           // 更换后旧凭证仍连接成功的次数
+	err         error         // 更换凭证失败的原因
+	restoreErr  error         // 恢复原凭证失败的原因
+}
+
+// credRotation 凭证轮换的状态和统计
+var credRotation struct {
+	devices []*rotatedDevice
+	done    sync.WaitGroup
+
+	probeErrors atomic.Uint64 // 探测连接的网络错误(未收到CONNACK)，不计为接受或拒绝
+	newLatency  Histogram     // 新凭证生效的耗时
+	oldLatency  Histogram     // 旧凭证失效的耗时
+}
+
+// credRotationEnabled 是否进行凭证轮换
+func credRotationEnabled() bool {
+	return AppConfig.CredRotation.Devices > 0
+}
+
+// initCredRotation 选择设备并查询其设备ID和当前凭证
+//
+// 选择参与测试的最后N个设备。这些设备的现有连接不受影响，但更换凭证期间断线重连会失败，
+// 直到原凭证恢复。
+func initCredRotation(tokens *TokenList) error {
+	if !credRotationEnabled() {
+		return nil
+	}
+	count := min(tokens.Len(), AppConfig.Device.ClientNumber)
+	var usernames []string
+	for i := max(count-AppConfig.CredRotation.Devices, 0); i < count; i++ {
+		usernames = append(usernames, tokens.At(i))
+	}
+	db, err := openDatabase()
+	if err != nil {
+		return fmt.Errorf(T("凭证轮换: %w"), err)
+	}
+	defer db.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	rows, err := db.QueryContext(ctx, `SELECT id, voucher, voucher::json->>'username' FROM devices
+		WHERE voucher::json->>'username' = ANY($1)`, usernames)
+	if err != nil {
+		return fmt.Errorf(T("凭证轮换: 查询设备凭证失败: %w"), err)
+	}
+	defer rows.Close()
+	suffix := "_rot" + uuid.New()[:8]
+	for rows.Next() {
+		d := &rotatedDevice{newAccepted: -1, oldRejected: -1}
+		if err := rows.Scan(&d.id, &d.oldVoucher, &d.oldUsername); err != nil {
+			return fmt.Errorf(T("凭证轮换: 查询设备凭证失败: %w"), err)
+		}
+		var voucher map[string]any
+		if err := json.Unmarshal([]byte(d.oldVoucher), &voucher); err != nil {
+			return fmt.Errorf(T("凭证轮换: 设备 %s 的凭证不是JSON: %w"), d.oldUsername, err)
+		}
+		d.newUsername = d.oldUsername + suffix
+		voucher["username"] = d.newUsername
+		data, _ := json.Marshal(voucher)
+		d.newVoucher = string(data)
+		credRotation.devices = append(credRotation.devices, d)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf(T("凭证轮换: 查询设备凭证失败: %w"), err)
+	}
+	if len(credRotation.devices) == 0 {
+		return fmt.Errorf(T("凭证轮换: %d 个设备的token在数据库中都找不到设备"), len(usernames))
+	}
+	log.Printf(T("凭证轮换: 第 %d 个循环更换 %d 个设备的凭证 (方式: %s)"),
+		AppConfig.CredRotation.Cycle, len(credRotation.devices), AppConfig.CredRotation.Method)
+	return nil
+}
+
+// startCredRotation 更换凭证并开始探测，探测结束(或超时、测试结束)后恢复原凭证
+func startCredRotation(ctx context.Context) {
+	if !credRotationEnabled() || len(credRotation.devices) == 0 {
+		return
+	}
+	annotate("stage", fmt.Sprintf(T("更换 %d 个设备的凭证"), len(credRotation.devices)))
+	credRotation.done.Add(1)
+	go func() {
+		defer credRotation.done.Done()
+		db, err := openDatabase()
+		if err != nil {
+			log.Printf(T("凭证轮换: %v"), err)
+			return
+		}
+		defer db.Close()
+
+		var probes sync.WaitGroup
+		for _, d := range credRotation.devices {
+			if d.err = updateVoucher(db, d.id, d.newVoucher); d.err != nil {
+				logErrorf(T("凭证轮换: 更换设备 %s 的凭证失败: %v"), d.oldUsername, d.err)
+				continue
+			}
+			d.rotatedAt = time.Now()
+			probes.Add(1)
+			go func() {
+				defer probes.Done()
+				probeCredentials(ctx, d)
+			}()
+		}
+		probes.Wait()
+
+		// 无论测试是否已结束都恢复原凭证，否则token文件中的这些设备将无法再连接
+		for _, d := range credRotation.devices {
+			if d.err != nil {
+				continue
+			}
+			if d.restoreErr = updateVoucher(db, d.id, d.oldVoucher); d.restoreErr != nil {
+				log.Printf(T("凭证轮换: 恢复设备 %s 的凭证失败: %v，原凭证: %s"), d.oldUsername, d.restoreErr, d.oldVoucher)
+			}
+		}
+		annotate("stage", T("恢复更换过凭证的设备的原凭证"))
+	}()
+}
+
+// updateVoucher 通过数据库或平台API更新设备凭证
+func updateVoucher(db *sql.DB, id, voucher string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if AppConfig.CredRotation.Method == "api" {
+		quoted, _ := json.Marshal(voucher)
+		body := strings.NewReplacer("{device_id}", id, "{voucher}", string(quoted)).Replace(AppConfig.CredRotation.Body)
+		_, _, err := apiCall(ctx, "PUT", AppConfig.CredRotation.Path, []byte(body))
+		return err
+	}
+	_, err := db.ExecContext(ctx, `UPDATE devices SET voucher = $2 WHERE id = $1`, id, voucher)
+	return err
+}
+
+// probeCredentials 按间隔分别用新旧凭证建立连接，直到新凭证被接受且旧凭证被拒绝，或超时
+func probeCredentials(ctx context.Context, d *rotatedDevice) {
+	cfg := AppConfig.CredRotation
+	ticker := time.NewTicker(cfg.ProbeInterval)
+	defer ticker.Stop()
+	deadline := d.rotatedAt.Add(cfg.Timeout)
+	for d.newAccepted < 0 || d.oldRejected < 0 {
+		if d.newAccepted < 0 {
+			accepted, ok := probeConnect(d.newUsername+"_new", d.newUsername)
+			switch {
+			case !ok:
+				credRotation.probeErrors.Add(1)
+			case accepted:
+				d.newAccepted = time.Since(d.rotatedAt)
+				credRotation.newLatency.Record(d.newAccepted)
+			}
+		}
+		if d.oldRejected < 0 {
+			accepted, ok := probeConnect(d.oldUsername+"_old", d.oldUsername)
+			switch {
+			case !ok:
+				credRotation.probeErrors.Add(1)
+			case accepted:
+				d.oldAccepts++
+			default:
+				d.oldRejected = time.Since(d.rotatedAt)
+				credRotation.oldLatency.Record(d.oldRejected)
+			}
+		}
+		if time.Now().After(deadline) {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// probeConnect 用指定凭证连接一次后立即断开，返回是否被接受；ok为false表示网络错误，无法判断
+func probeConnect(clientID, username string) (accepted, ok bool) {
+	client := newLiteClient(clientID, username, AppConfig.MQTT.Server, nil)
+	err := client.Connect()
+	if err == nil {
+		client.Disconnect()
+		return true, true
+	}
+	var refused *connectRefused
+	return false, errors.As(err, &refused)
+}
+
+// credRotationOutcome 凭证轮换的结果计数
+type credRotationOutcome struct {
+	rotated       int // 更换成功的设备数
+	newAccepted   int // 新凭证在超时前生效的设备数
+	oldRejected   int // 旧凭证在超时前失效的设备数
+	oldStillValid int // 超时时旧凭证仍可连接的设备数
+	restoreFailed int // 恢复原凭证失败的设备数
+}
+
+// classifyCredRotation 统计凭证轮换的结果
+func classifyCredRotation() credRotationOutcome {
+	var o credRotationOutcome
+	for _, d := range credRotation.devices {
+		if d.err != nil {
+			continue
+		}
+		o.rotated++
+		if d.newAccepted >= 0 {
+			o.newAccepted++
+		}
+		if d.oldRejected >= 0 {
+			o.oldRejected++
+		} else if d.oldAccepts > 0 {
+			o.oldStillValid++
+		}
+		if d.restoreErr != nil {
+			o.restoreFailed++
+		}
+	}
+	return o
+}
+
+// reportCredRotation 等待探测和恢复完成，输出新凭证生效和旧凭证失效的耗时(未启用时不输出)
+func reportCredRotation() {
+	if !credRotationEnabled() {
+		return
+	}
+	credRotation.done.Wait()
+	o := classifyCredRotation()
+	log.Println(T("\n========== 凭证轮换 =========="))
+	log.Printf(T("更换凭证: %d 个设备, 失败 %d, 探测超时 %v, 探测网络错误 %d 次"),
+		o.rotated, len(credRotation.devices)-o.rotated, AppConfig.CredRotation.Timeout, credRotation.probeErrors.Load())
+	if o.rotated == 0 {
+		log.Println(T("没有设备在测试结束前更换凭证，请确认 cred_rotation.cycle 不超过总循环数"))
+		log.Println("===============================")
+		return
+	}
+	log.Printf(T("新凭证生效: %d/%d 个设备"), o.newAccepted, o.rotated)
+	if h := &credRotation.newLatency; h.Count() > 0 {
+		log.Printf(T("  更换到首次连接成功: p50 %v, p99 %v, 最大 %v"), h.Quantile(0.50), h.Quantile(0.99), h.Max())
+	}
+	log.Printf(T("旧凭证失效: %d/%d 个设备"), o.oldRejected, o.rotated)
+	if h := &credRotation.oldLatency; h.Count() > 0 {
+		log.Printf(T("  更换到首次被拒绝: p50 %v, p99 %v, 最大 %v"), h.Quantile(0.50), h.Quantile(0.99), h.Max())
+	}
+	if o.oldStillValid > 0 {
+		log.Printf(T("  安全问题: %d 个设备的旧凭证在更换 %v 后仍可连接"), o.oldStillValid, AppConfig.CredRotation.Timeout)
+	}
+	if o.restoreFailed > 0 {
+		log.Printf(T("警告: %d 个设备恢复原凭证失败，需手动恢复(原凭证见上方日志)"), o.restoreFailed)
+	}
+	log.Println("===============================")
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// cron子命令命令行参数
+var (
+	cronExpr     = flag.String("cron-expr", "", "cron: 定时表达式(分 时 日 月 周，如 \"0 2 * * *\" 为每天2点，也支持@hourly、@daily、@weekly、@monthly)")
+	cronDuration = flag.Duration("cron-duration", 0, "cron: 每次运行的最长时长，到期后像Ctrl+C一样提前结束测试(0为运行到测试自行结束)")
+)
+
+// cronStopGrace 要求测试结束后等待其输出报告、写入结果库的时间，超时后强制结束
+const cronStopGrace = 2 * time.Minute
+
+// cronMacros 定时表达式的预定义写法
+var cronMacros = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+}
+
+// cronSchedule 解析后的定时表达式，每个字段为允许取值的位图
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool // 日、周字段为 * 时两者同时满足，否则满足其一即可(与cron一致)
+}
+
+// parseCron 解析5字段的定时表达式，字段支持 *、数值、范围(a-b)、列表(a,b)和步长(*/n、a-b/n)
+func parseCron(expr string) (*cronSchedule, error) {
+	if macro, ok := cronMacros[strings.ToLower(strings.TrimSpace(expr))]; ok {
+		expr = macro
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf(T("需要5个字段(分 时 日 月 周)，实际为 %d 个"), len(fields))
+	}
+	var s cronSchedule
+	var err error
+	if s.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf(T("分钟字段: %w"), err)
+	}
+	if s.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf(T("小时字段: %w"), err)
+	}
+	if s.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf(T("日期字段: %w"), err)
+	}
+	if s.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf(T("月份字段: %w"), err)
+	}
+	if s.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf(T("星期字段: %w"), err)
+	}
+	if s.dow&(1<<7) != 0 { // 7和0都表示星期日
+		s.dow |= 1
+	}
+	s.domAny = strings.HasPrefix(fields[2], "*")
+	s.dowAny = strings.HasPrefix(fields[4], "*")
+	return &s, nil
+}
+
+// parseCronField 解析一个字段，返回允许取值的位图
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, stepText, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepText)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf(T("步长 %q 无效"), stepText)
+			}
+			step = n
+		}
+		lo, hi := min, max
+		if rng != "*" {
+			loText, hiText, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = strconv.Atoi(loText); err != nil {
+				return 0, fmt.Errorf(T("取值 %q 无效"), part)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(hiText); err != nil {
+					return 0, fmt.Errorf(T("取值 %q 无效"), part)
+				}
+			} else if hasStep {
+				hi = max // a/n 表示从a开始每隔n
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf(T("取值 %q 超出范围 %d-%d"), part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// dayMatches 日期是否满足日、周字段
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// next 返回t之后(不含t所在的分钟)第一个满足表达式的时间，5年内没有时返回false
+func (s *cronSchedule) next(t time.Time) (time.Time, bool) {
+	loc := t.Location()
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, loc)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// runCron cron子命令：按定时表达式反复启动测试，每次运行的结果写入结果库，用于持续跟踪预发环境的性能
+//
+// 每次运行启动本程序的一个子进程(命令行参数与本次相同，去掉cron参数，加上 cron.args 和
+// --save-results)，运行结束后才计算下一次运行时间，上一次运行超过了计划时间时跳过错过的时间点。
+func runCron() {
+	if AppConfig.Cron.Expr == "" {
+		log.Fatal(T("cron: 未配置定时表达式(cron.expr 或 --cron-expr)"))
+	}
+	schedule, err := parseCron(AppConfig.Cron.Expr)
+	if err != nil {
+		log.Fatalf(T("cron: 定时表达式 %q 无效: %v"), AppConfig.Cron.Expr, err)
+	}
+	if (AppConfig.Soak.Enabled || daemonEnabled()) && AppConfig.Cron.Duration <= 0 {
+		log.Fatal(T("cron: 长稳测试和守护模式不会自行结束，需要设置 cron.duration"))
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		log.Fatalf(T("cron: 无法获取程序路径: %v"), err)
+	}
+
+	// 启动时检查结果库，避免到了运行时间才发现无法写入
+	db, err := openDatabase()
+	if err != nil {
+		log.Fatalf(T("cron: 结果库: %v"), err)
+	}
+	err = ensureResultsSchema(db)
+	db.Close()
+	if err != nil {
+		log.Fatalf(T("cron: 结果库: %v"), err)
+	}
+
+	args := cronRunArgs()
+	log.Printf(T("定时运行: 表达式=%q, 最长时长=%v, 参数=%s"), AppConfig.Cron.Expr, AppConfig.Cron.Duration, strings.Join(args, " "))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	for run := 1; ; run++ {
+		next, ok := schedule.next(time.Now())
+		if !ok {
+			log.Fatalf(T("cron: 定时表达式 %q 在5年内没有匹配的时间"), AppConfig.Cron.Expr)
+		}
+		log.Printf(T("下次运行: %s"), next.Format("2006-01-02 15:04 MST"))
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			log.Println(T("收到中断信号，定时运行结束"))
+			return
+		case <-timer.C:
+		}
+
+		runScheduled(ctx, run, exe, args)
+		if ctx.Err() != nil {
+			log.Println(T("收到中断信号，定时运行结束"))
+			return
+		}
+	}
+}
+
+// cronRunArgs 每次运行的命令行参数
+func cronRunArgs() []string {
+	var args []string
+	for i := 1; i < len(os.Args); i++ {
+		arg := os.Args[i]
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if strings.HasPrefix(arg, "-") && strings.HasPrefix(name, "cron-") {
+			if !hasValue {
+				i++ // 跳过参数值
+			}
+			continue
+		}
+		args = append(args, arg)
+	}
+	args = append(args, AppConfig.Cron.Args...)
+	return append(args, "--save-results")
+}
+
+// runScheduled 执行一次运行，超过最长时长或收到中断信号时要求测试提前结束
+//
+// 子进程的标准输入为空，测试完成后直接退出，不等待按Enter键。
+func runScheduled(ctx context.Context, run int, exe string, args []string) {
+	cmd := exec.Command(exe, args...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	start := time.Now()
+	log.Printf(T("定时运行 #%d 开始"), run)
+	if err := cmd.Start(); err != nil {
+		log.Printf(T("定时运行 #%d 启动失败: %v"), run, err)
+		return
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	var limit <-chan time.Time
+	if d := AppConfig.Cron.Duration; d > 0 {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		limit = timer.C
+	}
+	var err error
+	select {
+	case err = <-done:
+	case <-limit:
+		log.Printf(T("定时运行 #%d 已达到最长时长 %v，结束测试"), run, AppConfig.Cron.Duration)
+		err = stopScheduled(cmd, done)
+	case <-ctx.Done():
+		err = stopScheduled(cmd, done)
+	}
+
+	elapsed := time.Since(start).Round(time.Second)
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		log.Printf(T("定时运行 #%d 完成，耗时 %v"), run, elapsed)
+	case errors.As(err, &exitErr):
+		log.Printf(T("定时运行 #%d 结束，耗时 %v，退出码 %d"), run, elapsed, exitErr.ExitCode())
+	default:
+		log.Printf(T("定时运行 #%d 结束，耗时 %v: %v"), run, elapsed, err)
+	}
+}
+
+// stopScheduled 向测试发送中断信号，等待其输出报告并写入结果库，超时或不支持中断信号(Windows)时强制结束
+func stopScheduled(cmd *exec.Cmd, done <-chan error) error {
+	if err := cmd.Process.Signal(os.Interrupt); err != nil {
+		cmd.Process.Kill()
+		return <-done
+	}
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(cronStopGrace):
+		log.Printf(T("测试在 %v 内没有结束，强制终止"), cronStopGrace)
+		cmd.Process.Kill()
+		return <-done
+	}
+}
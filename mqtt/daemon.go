@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 守护模式命令行参数
+var daemonFlag = flag.Bool("daemon", false, "守护模式：不限循环次数持续发送，用于演示和预发环境；SIGHUP重新加载配置，提供 /status 状态接口，支持systemd的Type=notify")
+
+// defaultStatusAddr 守护模式下未指定 --control-addr 时状态接口的默认监听地址
+const defaultStatusAddr = "127.0.0.1:9091"
+
+// daemonState 守护模式的运行状态
+var daemonState struct {
+	mu         sync.Mutex
+	runID      string
+	startedAt  time.Time
+	configData []byte    // 启动时(或上次重新加载时)读取的配置文件内容，用于比较哪些配置发生了变化
+	reloads    int       // 重新加载的次数
+	reloadedAt time.Time // 上次重新加载的时间
+	recentRate float64   // 最近一个监控间隔的消息速率
+}
+
+// daemonEnabled 是否以守护模式运行
+func daemonEnabled() bool {
+	return AppConfig.Daemon.Enabled
+}
+
+// controlAddress 控制接口的监听地址，守护模式下默认启用
+func controlAddress() string {
+	if *controlAddr == "" && daemonEnabled() {
+		return AppConfig.Daemon.StatusAddr
+	}
+	return *controlAddr
+}
+
+// startDaemon 记录启动状态，启动SIGHUP处理和状态采样，直到ctx结束
+func startDaemon(ctx context.Context, runID string) {
+	daemonState.mu.Lock()
+	daemonState.runID = runID
+	daemonState.startedAt = time.Now()
+	daemonState.configData, _ = os.ReadFile(*configFile)
+	daemonState.mu.Unlock()
+	if !daemonEnabled() {
+		return
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				reloadDaemonConfig()
+			}
+		}
+	}()
+	go sampleDaemonStatus(ctx)
+}
+
+// reloadDaemonConfig 重新读取配置文件，应用可以在运行中修改的配置
+//
+// 只有发送间隔可以在运行中修改(命令行指定了 --interval 时以命令行为准)；其他配置
+// 在各设备goroutine中使用，修改后需要重启才能生效，这里只列出发生变化的配置段。
+func reloadDaemonConfig() {
+	sdNotify("RELOADING=1")
+	defer sdNotify("READY=1")
+
+	data, err := os.ReadFile(*configFile)
+	if err != nil {
+		log.Printf(T("重新加载配置失败: %v"), err)
+		return
+	}
+	var next Config
+	if err := yaml.Unmarshal(data, &next); err != nil {
+		log.Printf(T("重新加载配置失败: %v"), err)
+		return
+	}
+
+	daemonState.mu.Lock()
+	var prev Config
+	yaml.Unmarshal(daemonState.configData, &prev)
+	daemonState.configData = data
+	daemonState.reloads++
+	daemonState.reloadedAt = time.Now()
+	daemonState.mu.Unlock()
+
+	var applied []string
+	if interval := next.Test.DataInterval; interval > 0 && interval != prev.Test.DataInterval && *dataInterval <= 0 {
+		if AppConfig.Adaptive.Enabled {
+			log.Println(T("重新加载配置: 自适应速率已启用，发送间隔由控制器调整，不修改"))
+		} else {
+			sendInterval.Store(int64(interval))
+			applied = append(applied, fmt.Sprintf(T("发送间隔=%v"), interval))
+		}
+	}
+	next.Test.DataInterval, prev.Test.DataInterval = 0, 0
+	restart := changedSections(prev, next)
+
+	text := T("重新加载配置")
+	if len(applied) > 0 {
+		text += fmt.Sprintf(T(", 已应用: %s"), strings.Join(applied, ", "))
+	}
+	if len(restart) > 0 {
+		text += fmt.Sprintf(T(", 需要重启才能生效: %s"), strings.Join(restart, ", "))
+	}
+	if len(applied) == 0 && len(restart) == 0 {
+		text += T(", 配置没有变化")
+	}
+	annotate("daemon", text)
+}
+
+// changedSections 按顶层配置段(yaml名称)比较两份配置，返回发生变化的配置段
+func changedSections(prev, next Config) []string {
+	var changed []string
+	pv, nv := reflect.ValueOf(prev), reflect.ValueOf(next)
+	for i := 0; i < pv.NumField(); i++ {
+		if !reflect.DeepEqual(pv.Field(i).Interface(), nv.Field(i).Interface()) {
+			name, _, _ := strings.Cut(pv.Type().Field(i).Tag.Get("yaml"), ",")
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
+
+// sampleDaemonStatus 按监控间隔采样消息速率，供状态接口和systemd状态使用
+func sampleDaemonStatus(ctx context.Context) {
+	ticker := time.NewTicker(AppConfig.Monitor.LogInterval)
+	defer ticker.Stop()
+	last, lastAt := runStats.Snapshot().Messages, time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			snap := runStats.Snapshot()
+			rate := ratePerSecond(float64(snap.Messages-last), now.Sub(lastAt))
+			last, lastAt = snap.Messages, now
+			daemonState.mu.Lock()
+			daemonState.recentRate = rate
+			daemonState.mu.Unlock()
+			sdNotify("STATUS=" + fmt.Sprintf(T("在线 %d 个设备, %.1f 条/秒"), onlineDevices(snap), rate))
+		}
+	}
+}
+
+// onlineDevices 当前在线的设备数，连接失败的设备也会计入退出数
+func onlineDevices(snap StatsSnapshot) uint64 {
+	if snap.Connected+snap.ConnectFailed < snap.Exited {
+		return 0
+	}
+	return snap.Connected + snap.ConnectFailed - snap.Exited
+}
+
+// DaemonStatus 状态接口返回的运行状态
+type DaemonStatus struct {
+	RunID         string    `json:"run_id"`
+	Daemon        bool      `json:"daemon"`
+	Phase         string    `json:"phase"` // 运行阶段: starting/connecting/running/stopping/reporting
+	StartedAt     time.Time `json:"started_at"`
+	UptimeSeconds float64   `json:"uptime_s"`
+	Devices       int       `json:"devices"`        // 配置的设备数
+	Connected     uint64    `json:"connected"`      // 当前在线的设备数
+	Cycles        uint64    `json:"cycles"`         // 已进行的循环数
+	IntervalMs    float64   `json:"interval_ms"`    // 当前的发送间隔
+	Messages      uint64    `json:"msgs"`           // 发送成功的遥测消息数
+	Points        uint64    `json:"points"`         // 发送的数据点数
+	PublishFailed uint64    `json:"publish_failed"` // 发布失败的消息数
+	MsgsPerSec    float64   `json:"msgs_per_sec"`   // 最近一个监控间隔的消息速率(守护模式)，否则为平均速率
+	ConnectFailed uint64    `json:"connect_failed"` // 连接失败的设备数
+	Errors        uint64    `json:"errors"`         // 设备级错误日志条数(含被聚合的)
+	LastProgress  time.Time `json:"last_progress"`  // 最近一次有进展(连接、循环、发布)的时间
+	Stalled       bool      `json:"stalled"`        // 是否超过判定时长没有进展，同 /healthz 返回503
+
+	Reloads    int        `json:"reloads,omitempty"`     // 重新加载配置的次数
+	ReloadedAt *time.Time `json:"reloaded_at,omitempty"` // 上次重新加载的时间
+}
+
+// currentDaemonStatus 当前的运行状态
+func currentDaemonStatus() DaemonStatus {
+	snap := runStats.Snapshot()
+	phase, last := currentPhase(), lastProgressAt(snap)
+	_, stuck := stalled(phase, last)
+	daemonState.mu.Lock()
+	defer daemonState.mu.Unlock()
+	status := DaemonStatus{
+		RunID:         daemonState.runID,
+		Daemon:        daemonEnabled(),
+		Phase:         phase,
+		StartedAt:     daemonState.startedAt,
+		UptimeSeconds: time.Since(daemonState.startedAt).Seconds(),
+		Devices:       AppConfig.Device.ClientNumber,
+		Connected:     onlineDevices(snap),
+		Cycles:        snap.Cycles,
+		IntervalMs:    durationMs(currentSendInterval()),
+		Messages:      snap.Messages,
+		Points:        snap.DataPoints,
+		PublishFailed: snap.PublishFailed,
+		MsgsPerSec:    daemonState.recentRate,
+		ConnectFailed: snap.ConnectFailed,
+		Errors:        errorCount.Load(),
+		LastProgress:  last,
+		Stalled:       stuck,
+		Reloads:       daemonState.reloads,
+	}
+	if !daemonEnabled() {
+		status.MsgsPerSec = ratePerSecond(float64(snap.Messages), time.Since(daemonState.startedAt))
+	}
+	if daemonState.reloads > 0 {
+		at := daemonState.reloadedAt
+		status.ReloadedAt = &at
+	}
+	return status
+}
+
+// handleStatus GET返回当前的运行状态
+func handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(currentDaemonStatus())
+}
+
+// sdNotify 向systemd发送状态通知(Type=notify)，未由systemd启动时不做任何操作
+func sdNotify(state string) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return
+	}
+	conn, err := net.Dial("unixgram", socket)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	conn.Write([]byte(state))
+}
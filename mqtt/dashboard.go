@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WebSocket看板命令行参数
+var (
+	dashboardCount = flag.Int("dashboards", 0, "多协议混合：模拟的WebSocket看板连接数，每个连接订阅一个设备的实时数据，0为不启用")
+	dashboardURL   = flag.String("dashboard-url", "", "WebSocket看板地址(ws://或wss://)，{username}和{device_id}替换为所看设备的token和ID")
+)
+
+// defaultDashboardMessage 连接后发送的默认订阅消息，对应ThingsPanel的设备实时数据接口
+const defaultDashboardMessage = `{"device_id":"{device_id}","token":"{token}"}`
+
+// dashboardRetryDelay 看板连接断开后重连前的等待时间
+const dashboardRetryDelay = time.Second
+
+// dashboardStats WebSocket看板统计
+var dashboardStats struct {
+	connected   atomic.Int64  // 当前已连接的看板数
+	connects    atomic.Uint64 // 连接成功次数(含重连)
+	failures    atomic.Uint64 // 连接失败次数
+	disconnects atomic.Uint64 // 连接中途断开的次数
+	received    atomic.Uint64 // 收到的推送消息数
+	bytes       atomic.Uint64 // 收到的推送字节数
+	interval    Histogram     // 同一看板相邻两次推送的间隔
+}
+
+// dashboardsEnabled 是否模拟WebSocket看板
+func dashboardsEnabled() bool {
+	return AppConfig.Dashboards.Count > 0
+}
+
+// dashboardTarget 看板所看的设备
+type dashboardTarget struct {
+	username string
+	deviceID string
+}
+
+// dashboardTargets 按顺序为每个看板分配设备，地址或订阅消息需要设备ID时到数据库查询
+func dashboardTargets(tokens *TokenList) []dashboardTarget {
+	cfg := AppConfig.Dashboards
+	devices := min(tokens.Len(), AppConfig.Device.ClientNumber)
+	targets := make([]dashboardTarget, cfg.Count)
+	for i := range targets {
+		targets[i].username = tokens.At(i % devices)
+	}
+	if !strings.Contains(cfg.URL+cfg.Message, "{device_id}") {
+		return targets
+	}
+
+	db, err := openReadDatabase()
+	if err != nil {
+		log.Printf(T("WebSocket看板: %v，{device_id}无法替换"), err)
+		return targets
+	}
+	defer db.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	ids := make(map[string]string)
+	for i := range targets {
+		username := targets[i].username
+		if _, ok := ids[username]; !ok {
+			id, err := deviceIDByToken(ctx, db, username)
+			if err != nil {
+				log.Printf(T("WebSocket看板: 设备 %s: %v"), username, err)
+			}
+			ids[username] = id
+		}
+		targets[i].deviceID = ids[username]
+	}
+	return targets
+}
+
+// dashboardReplacer 替换看板地址和订阅消息中的占位符
+func dashboardReplacer(target dashboardTarget) *strings.Replacer {
+	return strings.NewReplacer("{username}", target.username, "{device_id}", target.deviceID,
+		"{token}", AppConfig.Dashboards.Token)
+}
+
+// startDashboards 在设备上线前启动全部看板，每个看板断开后自动重连，直到ctx结束
+//
+// 看板和设备在同一时间线上运行：设备发送的同时平台需要向看板推送实时数据，
+// 两种负载的相互影响体现在入库速率、发布耗时和推送间隔上。
+func startDashboards(ctx context.Context, tokens *TokenList) *sync.WaitGroup {
+	var wg sync.WaitGroup
+	if !dashboardsEnabled() {
+		return &wg
+	}
+	targets := dashboardTargets(tokens)
+	initGlassLatency(targets)
+	for i, target := range targets {
+		wg.Add(1)
+		go func(id int, target dashboardTarget) {
+			defer wg.Done()
+			runDashboard(ctx, id, target)
+		}(i+1, target)
+	}
+
+	// 等待首次连接完成，便于确认看板配置是否正确
+	deadline := time.Now().Add(AppConfig.Dashboards.Timeout)
+	for time.Now().Before(deadline) &&
+		dashboardStats.connects.Load()+dashboardStats.failures.Load() < uint64(AppConfig.Dashboards.Count) {
+		time.Sleep(100 * time.Millisecond)
+	}
+	log.Printf(T("WebSocket看板: %d/%d 个已连接"), dashboardStats.connected.Load(), AppConfig.Dashboards.Count)
+	return &wg
+}
+
+// runDashboard 单个看板的连接、订阅和接收循环
+func runDashboard(ctx context.Context, id int, target dashboardTarget) {
+	cfg := AppConfig.Dashboards
+	replacer := dashboardReplacer(target)
+	header := make(http.Header)
+	for key, value := range cfg.Headers {
+		header.Set(key, replacer.Replace(value))
+	}
+	for ctx.Err() == nil {
+		conn, err := dialWebSocket(replacer.Replace(cfg.URL), header, cfg.Timeout)
+		if err == nil && cfg.Message != "" {
+			if err = conn.WriteText([]byte(replacer.Replace(cfg.Message))); err != nil {
+				conn.Close()
+			}
+		}
+		if err != nil {
+			dashboardStats.failures.Add(1)
+			logErrorf(T("WebSocket看板 %d 连接失败: %v"), id, err)
+		} else {
+			dashboardStats.connects.Add(1)
+			dashboardStats.connected.Add(1)
+			err = receiveDashboard(ctx, conn)
+			dashboardStats.connected.Add(-1)
+			if ctx.Err() == nil {
+				dashboardStats.disconnects.Add(1)
+				logErrorf(T("WebSocket看板 %d 连接断开: %v"), id, err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(dashboardRetryDelay):
+		}
+	}
+}
+
+// receiveDashboard 接收推送直到连接断开或ctx结束
+func receiveDashboard(ctx context.Context, conn *wsConn) error {
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	defer conn.Close()
+
+	var last time.Time
+	for {
+		message, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		recordGlassPush(message, now)
+		dashboardStats.received.Add(1)
+		dashboardStats.bytes.Add(uint64(len(message)))
+		if !last.IsZero() {
+			dashboardStats.interval.Record(now.Sub(last))
+		}
+		last = now
+	}
+}
+
+// logDashboards 监控间隔输出看板的连接数和收到的推送数(未启用时不输出)
+func logDashboards() {
+	if !dashboardsEnabled() {
+		return
+	}
+	log.Printf(T("  - WebSocket看板: 已连接 %d/%d, 收到推送 %d 条, 断开 %d 次"),
+		dashboardStats.connected.Load(), AppConfig.Dashboards.Count,
+		dashboardStats.received.Load(), dashboardStats.disconnects.Load())
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+
+	"test/internal/pgdsn"
+)
+
+// databaseDSN 根据配置构建数据库连接字符串
+func databaseDSN() string {
+	return pgdsn.Build(databaseOptions())
+}
+
+// databaseDSNFor 使用配置的账号和参数构建连接指定主机(只读副本)的连接字符串
+func databaseDSNFor(host string) string {
+	return pgdsn.BuildHost(databaseOptions(), host)
+}
+
+// databaseOptions 配置中的数据库连接参数
+//
+// 配置向导在加载默认值之前连接数据库时语句缓存为0，使用驱动的默认容量。
+func databaseOptions() pgdsn.Options {
+	cfg := AppConfig.Database
+	return pgdsn.Options{
+		Host:             cfg.Host,
+		User:             cfg.User,
+		Password:         cfg.Password,
+		Name:             cfg.Name,
+		SSLMode:          cfg.SSLMode,
+		DSN:              cfg.DSN,
+		PgBouncer:        cfg.PgBouncer,
+		ConnectTimeout:   cfg.ConnectTimeout,
+		StatementTimeout: cfg.StatementTimeout,
+		StatementCache:   cfg.StatementCache,
+	}
+}
+
+// openDatabase 打开主库连接并测试连通性
+func openDatabase() (*sql.DB, error) {
+	return openDatabaseDSN(databaseDSN())
+}
+
+// replicaEnabled 是否配置了只读副本
+func replicaEnabled() bool {
+	return AppConfig.Database.ReplicaHost != ""
+}
+
+// openReadDatabase 打开监控和核对查询使用的数据库连接，配置了只读副本时连接副本
+//
+// 监控的COUNT(*)和核对的聚合查询在大表上代价不小，放到副本上执行，避免观测负载
+// 影响本次测试要测量的主库入库性能。
+func openReadDatabase() (*sql.DB, error) {
+	if !replicaEnabled() {
+		return openDatabase()
+	}
+	db, err := openDatabaseDSN(databaseDSNFor(AppConfig.Database.ReplicaHost))
+	if err != nil {
+		return nil, fmt.Errorf(T("只读副本: %w"), err)
+	}
+	return db, nil
+}
+
+// replicaLag 副本最后重放的事务距今的时间
+func replicaLag(ctx context.Context, db *sql.DB) (time.Duration, error) {
+	var seconds sql.NullFloat64
+	err := db.QueryRowContext(ctx,
+		`SELECT EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp())`).Scan(&seconds)
+	if err != nil {
+		return 0, err
+	}
+	if !seconds.Valid {
+		return 0, errors.New(T("数据库不是只读副本"))
+	}
+	return time.Duration(seconds.Float64 * float64(time.Second)), nil
+}
+
+// replicaReplayTarget 核对前副本需要重放到的主库WAL位置，第一次核对时查询
+var replicaReplayTarget = sync.OnceValues(func() (string, error) {
+	db, err := openDatabase()
+	if err != nil {
+		return "", err
+	}
+	defer db.Close()
+	var lsn string
+	err = db.QueryRow(`SELECT pg_current_wal_lsn()::text`).Scan(&lsn)
+	return lsn, err
+})
+
+// waitReplicaReplay 等待副本重放到开始核对时主库的WAL位置，未配置副本时直接返回
+//
+// 核对在等待平台处理完最后一批消息之后进行，副本若仍有复制延迟，结果会把尚未复制的
+// 数据误报为缺失。最多等待1分钟，超时后照常核对并输出警告。
+func waitReplicaReplay(db *sql.DB) {
+	if !replicaEnabled() {
+		return
+	}
+	target, err := replicaReplayTarget()
+	if err != nil {
+		log.Printf(T("警告: 查询主库WAL位置失败，核对结果可能受复制延迟影响: %v"), err)
+		return
+	}
+	deadline := time.Now().Add(time.Minute)
+	for {
+		var caughtUp sql.NullBool
+		err := db.QueryRow(`SELECT pg_last_wal_replay_lsn() >= $1::pg_lsn`, target).Scan(&caughtUp)
+		if err == nil && caughtUp.Bool {
+			return
+		}
+		if time.Now().After(deadline) {
+			log.Printf(T("警告: 只读副本1分钟内未重放到主库WAL位置 %s，核对结果可能受复制延迟影响"), target)
+			return
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// openDatabaseDSN 打开数据库连接并测试连通性
+func openDatabaseDSN(dsn string) (*sql.DB, error) {
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, fmt.Errorf(T("无法连接数据库: %w"), err)
+	}
+
+	// 配置向导在加载默认值之前也会连接数据库
+	timeout := AppConfig.Database.ConnectTimeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf(T("数据库连接测试失败: %w"), err)
+	}
+
+	// 设置连接池参数，监控、核对和结果库共用同样的配置
+	db.SetMaxOpenConns(AppConfig.Database.MaxConns)
+	db.SetMaxIdleConns(AppConfig.Database.MaxIdleConns)
+	db.SetConnMaxLifetime(AppConfig.Database.ConnMaxLifetime)
+
+	return db, nil
+}
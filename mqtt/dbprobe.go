@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// 数据库写入探测命令行参数
+var dbProbe = flag.Bool("db-probe", false, "每个监控间隔向探测表插入一行并计时，区分入库变慢是数据库慢还是消费端慢")
+
+// probeSlowFactor 探测耗时超过最小值的该倍数时视为数据库写入变慢
+const probeSlowFactor = 5
+
+// probeState 数据库写入探测的统计
+var probeState struct {
+	mu      sync.Mutex
+	latency Histogram     // 每次探测插入的耗时
+	min     time.Duration // 探测耗时的最小值，作为数据库空闲时的基准
+	failed  int           // 探测插入失败的次数
+}
+
+// probeEnabled 是否启用数据库写入探测
+func probeEnabled() bool {
+	return AppConfig.Monitor.Probe
+}
+
+// probeTable 探测表的完整名称，放在结果库的schema中，不影响平台的业务表
+func probeTable() string {
+	return resultsTable("write_probe")
+}
+
+// ensureProbeTable 创建探测表
+func ensureProbeTable(db *sql.DB) error {
+	stmts := []string{
+		fmt.Sprintf(`CREATE SCHEMA IF NOT EXISTS %s`, resultsSchema()),
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+			id        bigserial PRIMARY KEY,
+			run_id    text NOT NULL,
+			probed_at timestamptz NOT NULL
+		)`, probeTable()),
+	}
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf(T("创建探测表失败: %w"), err)
+		}
+	}
+	return nil
+}
+
+// probeWrite 向探测表插入一行，返回插入耗时
+//
+// 探测行与平台写入走同一个数据库，耗时升高说明数据库本身变慢；入库速率下降但探测耗时
+// 正常时，瓶颈在消费端(规则引擎、消息队列等)。
+//
+// 探测不重试，超时按失败计，重试会掩盖本间隔的真实写入耗时。
+func probeWrite(db *sql.DB) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), AppConfig.Monitor.QueryTimeout)
+	defer cancel()
+	start := time.Now()
+	_, err := db.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %s (run_id, probed_at) VALUES ($1, $2)`, probeTable()),
+		alertMonitor.runID, start)
+	d := time.Since(start)
+	probeState.mu.Lock()
+	defer probeState.mu.Unlock()
+	if err != nil {
+		probeState.failed++
+		return 0, err
+	}
+	probeState.latency.Record(d)
+	if probeState.min == 0 || d < probeState.min {
+		probeState.min = d
+	}
+	return d, nil
+}
+
+// probeDiagnosis 根据探测耗时判断本间隔入库落后的原因
+func probeDiagnosis(d time.Duration) string {
+	probeState.mu.Lock()
+	defer probeState.mu.Unlock()
+	if probeState.min > 0 && d > probeState.min*probeSlowFactor {
+		return fmt.Sprintf(T("数据库写入变慢(探测耗时为最小值 %v 的 %.1f 倍)"), probeState.min, float64(d)/float64(probeState.min))
+	}
+	return T("数据库写入正常，瓶颈可能在消费端")
+}
+
+// reportDBProbe 输出数据库写入探测的统计(未启用时不输出)
+func reportDBProbe() {
+	probeState.mu.Lock()
+	defer probeState.mu.Unlock()
+	if !probeEnabled() || probeState.latency.Count() == 0 && probeState.failed == 0 {
+		return
+	}
+	h := &probeState.latency
+	log.Println(T("\n========== 数据库写入探测 =========="))
+	log.Printf(T("探测 %d 次, 失败 %d 次"), h.Count(), probeState.failed)
+	log.Printf(T("插入耗时: 最小=%v, p50=%v, p99=%v, 最大=%v"),
+		probeState.min, h.Quantile(0.50), h.Quantile(0.99), h.Max())
+	log.Println("===============================")
+}
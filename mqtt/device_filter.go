@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 设备筛选命令行参数
+var deviceFilter = flag.String("device-filter", "", "从token文件中筛选部分设备，多个条件用分号分隔(range:起-止, every:N, name:正则)")
+
+// filterTokens 按筛选条件依次过滤token列表
+//
+// 支持的条件(按顺序应用):
+//
+//	range:100-200  保留第100到200个token(从1开始，含两端，省略结束值表示到末尾)
+//	every:10       每10个保留1个
+//	name:^压测     关联数据库devices表，保留名称匹配PostgreSQL正则的设备
+func filterTokens(tokens *TokenList, spec string) error {
+	for _, clause := range strings.Split(spec, ";") {
+		clause = strings.TrimSpace(clause)
+		if clause == "" {
+			continue
+		}
+		kind, arg, ok := strings.Cut(clause, ":")
+		if !ok {
+			return fmt.Errorf(T("无效的设备筛选条件: %s"), clause)
+		}
+
+		var keep func(i int) bool
+		switch kind {
+		case "range":
+			from, to, err := parseIndexRange(arg, tokens.Len())
+			if err != nil {
+				return fmt.Errorf(T("无效的设备筛选条件 %s: %w"), clause, err)
+			}
+			keep = func(i int) bool { return i+1 >= from && i+1 <= to }
+		case "every":
+			n, err := strconv.Atoi(arg)
+			if err != nil || n <= 0 {
+				return fmt.Errorf(T("无效的设备筛选条件: %s"), clause)
+			}
+			keep = func(i int) bool { return i%n == 0 }
+		case "name":
+			names, err := usernamesByDeviceName(arg)
+			if err != nil {
+				return fmt.Errorf(T("按设备名称筛选失败: %w"), err)
+			}
+			keep = func(i int) bool { _, ok := names[tokens.At(i)]; return ok }
+		default:
+			return fmt.Errorf(T("不支持的设备筛选条件: %s (可选: range, every, name)"), kind)
+		}
+
+		before := tokens.Len()
+		var drop []int
+		for i := 0; i < before; i++ {
+			if !keep(i) {
+				drop = append(drop, i)
+			}
+		}
+		tokens.remove(drop)
+		log.Printf(T("设备筛选 %s: %d → %d"), clause, before, tokens.Len())
+	}
+
+	if tokens.Len() == 0 {
+		return fmt.Errorf(T("筛选后没有剩余设备: %s"), spec)
+	}
+	return nil
+}
+
+// parseIndexRange 解析"起-止"格式的序号范围(从1开始，含两端)
+func parseIndexRange(arg string, count int) (int, int, error) {
+	fromStr, toStr, ok := strings.Cut(arg, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf(T("应为 起-止 格式"))
+	}
+	from, err := strconv.Atoi(fromStr)
+	if err != nil || from < 1 {
+		return 0, 0, fmt.Errorf(T("起始序号无效: %s"), fromStr)
+	}
+	to := count
+	if toStr != "" {
+		if to, err = strconv.Atoi(toStr); err != nil || to < from {
+			return 0, 0, fmt.Errorf(T("结束序号无效: %s"), toStr)
+		}
+	}
+	return from, to, nil
+}
+
+// usernamesByDeviceName 查询名称匹配正则的设备token
+func usernamesByDeviceName(pattern string) (map[string]struct{}, error) {
+	db, err := openDatabase()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx,
+		`SELECT voucher::json->>'username' FROM devices WHERE "name" ~ $1 AND voucher IS NOT NULL`, pattern)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := make(map[string]struct{})
+	for rows.Next() {
+		var username *string
+		if err := rows.Scan(&username); err != nil {
+			return nil, err
+		}
+		if username != nil {
+			names[*username] = struct{}{}
+		}
+	}
+	return names, rows.Err()
+}
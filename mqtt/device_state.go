@@ -0,0 +1,173 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"math/rand/v2"
+	"sync/atomic"
+)
+
+// 设备状态机命令行参数
+var deviceStatesFlag = flag.Bool("device-states", false, "设备状态机：每个设备按转移概率在正常、降级(降低上报频率、上报错误码)和离线(断开连接)之间切换，模拟长稳测试中的局部故障")
+
+// deviceStateKind 设备所处的状态
+type deviceStateKind int
+
+const (
+	stateNormal   deviceStateKind = iota // 正常上报
+	stateDegraded                        // 降级，每degraded_every个循环上报一次并附加错误码
+	stateOffline                         // 离线，断开连接不上报
+	stateCount
+)
+
+// deviceStateNames 状态名称，用于输出
+var deviceStateNames = [stateCount]string{"normal", "degraded", "offline"}
+
+// stateRandStream 状态机随机数流的起始编号，与其他用途的随机数流错开
+const stateRandStream = 1 << 32
+
+// deviceStateStats 设备状态机统计
+var deviceStateStats struct {
+	current         [stateCount]atomic.Int64              // 当前处于各状态的设备数
+	cycles          [stateCount]atomic.Uint64             // 各状态累计的设备循环数
+	transitions     [stateCount][stateCount]atomic.Uint64 // 状态转移次数
+	skipped         atomic.Uint64                         // 降级和离线状态下未上报的循环数
+	reconnectFailed atomic.Uint64                         // 离线恢复时重连失败的次数
+}
+
+// deviceState 单个设备的状态机
+type deviceState struct {
+	rng   *rand.Rand
+	state deviceStateKind
+	since uint64 // 进入当前状态后经过的循环数
+	code  int    // 降级状态下上报的错误码
+}
+
+// deviceStatesEnabled 是否启用设备状态机
+func deviceStatesEnabled() bool {
+	return AppConfig.DeviceStates.Enabled
+}
+
+// newDeviceState 创建设备的状态机，初始为正常状态
+//
+// 离线缓存场景的设备按场景配置的循环离线，不参与状态机；未启用时返回nil。
+func newDeviceState(index int, offline bool) *deviceState {
+	if !deviceStatesEnabled() || offline {
+		return nil
+	}
+	deviceStateStats.current[stateNormal].Add(1)
+	return &deviceState{rng: newRand(stateRandStream + uint64(index))}
+}
+
+// transitionRow 返回从from转移到各状态的概率(%)
+func transitionRow(from deviceStateKind) [stateCount]float64 {
+	cfg := AppConfig.DeviceStates
+	switch from {
+	case stateNormal:
+		return [stateCount]float64{stateDegraded: cfg.NormalToDegraded, stateOffline: cfg.NormalToOffline}
+	case stateDegraded:
+		return [stateCount]float64{stateNormal: cfg.DegradedToNormal, stateOffline: cfg.DegradedToOffline}
+	default:
+		return [stateCount]float64{stateNormal: cfg.OfflineToNormal, stateDegraded: cfg.OfflineToDegraded}
+	}
+}
+
+// next 按转移概率抽取下一个循环的状态
+func (s *deviceState) next() deviceStateKind {
+	r := s.rng.Float64() * 100
+	for to, p := range transitionRow(s.state) {
+		if r < p {
+			return deviceStateKind(to)
+		}
+		r -= p
+	}
+	return s.state
+}
+
+// advance 在每个循环开始时推进状态机，返回本循环是否上报
+//
+// 进入离线状态时断开连接，离开离线状态时重连，重连失败时本循环仍视为离线，
+// 下个循环再按转移概率决定。降级状态下每degraded_every个循环上报一次。
+func (s *deviceState) advance(client DeviceClient, username string) bool {
+	if s == nil {
+		return true
+	}
+	from, to := s.state, s.next()
+	if to != from {
+		if from == stateOffline {
+			if err := client.Connect(); err != nil {
+				deviceStateStats.reconnectFailed.Add(1)
+				logErrorf(T("设备 %s 从离线状态恢复时重连失败: %v"), username, err)
+				to = stateOffline
+			}
+		} else if to == stateOffline {
+			client.Disconnect()
+		}
+	}
+	if to != from {
+		deviceStateStats.transitions[from][to].Add(1)
+		deviceStateStats.current[from].Add(-1)
+		deviceStateStats.current[to].Add(1)
+		s.state, s.since = to, 0
+		if to == stateDegraded {
+			s.code = 1 + s.rng.IntN(255)
+		}
+	}
+	deviceStateStats.cycles[s.state].Add(1)
+	s.since++
+
+	send := s.state == stateNormal ||
+		s.state == stateDegraded && (s.since-1)%uint64(AppConfig.DeviceStates.DegradedEvery) == 0
+	if !send {
+		deviceStateStats.skipped.Add(1)
+	}
+	return send
+}
+
+// annotate 降级状态下在消息中附加错误码，返回新消息和增加的数据点数
+func (s *deviceState) annotate(payload []byte) ([]byte, int) {
+	if s == nil || s.state != stateDegraded || AppConfig.DeviceStates.ErrorKey == "" {
+		return payload, 0
+	}
+	return withTimestamp(payload, AppConfig.DeviceStates.ErrorKey, int64(s.code)), 1
+}
+
+// logDeviceStates 监控间隔输出当前处于各状态的设备数
+func logDeviceStates() {
+	if !deviceStatesEnabled() {
+		return
+	}
+	log.Printf(T("  - 设备状态: 正常 %d, 降级 %d, 离线 %d"),
+		deviceStateStats.current[stateNormal].Load(), deviceStateStats.current[stateDegraded].Load(),
+		deviceStateStats.current[stateOffline].Load())
+}
+
+// reportDeviceStates 输出各状态的时间占比和状态转移次数(未启用时不输出)
+func reportDeviceStates() {
+	if !deviceStatesEnabled() {
+		return
+	}
+	var total uint64
+	for i := range deviceStateStats.cycles {
+		total += deviceStateStats.cycles[i].Load()
+	}
+	if total == 0 {
+		return
+	}
+	log.Println(T("\n========== 设备状态机 =========="))
+	for i, name := range deviceStateNames {
+		cycles := deviceStateStats.cycles[i].Load()
+		log.Printf(T("%s: %d 个设备循环 (%.1f%%), 测试结束时 %d 个设备"),
+			name, cycles, percent(cycles, total), deviceStateStats.current[i].Load())
+	}
+	for from := range deviceStateStats.transitions {
+		for to := range deviceStateStats.transitions[from] {
+			if n := deviceStateStats.transitions[from][to].Load(); n > 0 {
+				log.Printf(T("状态转移 %s -> %s: %d 次"), deviceStateNames[from], deviceStateNames[to], n)
+			}
+		}
+	}
+	log.Printf(T("未上报的循环: %d, 离线恢复重连失败: %d 次"),
+		deviceStateStats.skipped.Load(), deviceStateStats.reconnectFailed.Load())
+	log.Println("===============================")
+}
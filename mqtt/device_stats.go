@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// DeviceStats 单个设备的统计计数
+type DeviceStats struct {
+	Username        string // 设备token
+	ConnectFailed   uint32 // 是否连接失败(0/1)
+	Sent            uint64 // 发送成功的消息数
+	Failures        uint64 // 发送失败的消息数
+	Reconnects      uint64 // 重连次数
+	AckLatencyTotal int64  // 累计发布确认耗时(纳秒)
+
+	SubscribeLatency time.Duration // 最近一次订阅耗时
+	SubackCode       byte          // 最近一次SUBACK返回码
+}
+
+// 设备统计相关命令行参数
+var (
+	deviceStatsEnabled = flag.Bool("device-stats", false, "记录每个设备的统计数据")
+	deviceStatsWorstN  = flag.Int("device-stats-worst", 0, "测试结束时输出最差的N个设备")
+	deviceStatsCSV     = flag.String("device-stats-csv", "", "每设备统计CSV输出文件路径")
+)
+
+// newDeviceStats 为每个设备创建统计对象，未启用时返回nil
+func newDeviceStats(tokens *TokenList, count int) []*DeviceStats {
+	if !AppConfig.Stats.PerDevice {
+		return nil
+	}
+	stats := make([]*DeviceStats, count)
+	for i := range stats {
+		stats[i] = &DeviceStats{Username: tokens.At(i)}
+	}
+	return stats
+}
+
+// recordPublish 记录一次发布结果
+func (s *DeviceStats) recordPublish(latency time.Duration, err error) {
+	if s == nil {
+		return
+	}
+	if err != nil {
+		atomic.AddUint64(&s.Failures, 1)
+		return
+	}
+	atomic.AddUint64(&s.Sent, 1)
+	atomic.AddInt64(&s.AckLatencyTotal, int64(latency))
+}
+
+// recordSubscribe 记录一次订阅结果
+func (s *DeviceStats) recordSubscribe(latency time.Duration, code byte) {
+	if s == nil {
+		return
+	}
+	s.SubscribeLatency = latency
+	s.SubackCode = code
+}
+
+// recordReconnect 记录一次重连
+func (s *DeviceStats) recordReconnect() {
+	if s == nil {
+		return
+	}
+	atomic.AddUint64(&s.Reconnects, 1)
+}
+
+// recordConnectFailure 记录连接失败
+func (s *DeviceStats) recordConnectFailure() {
+	if s == nil {
+		return
+	}
+	atomic.StoreUint32(&s.ConnectFailed, 1)
+}
+
+// MeanAckLatency 平均发布确认耗时
+func (s *DeviceStats) MeanAckLatency() time.Duration {
+	sent := atomic.LoadUint64(&s.Sent)
+	if sent == 0 {
+		return 0
+	}
+	return time.Duration(atomic.LoadInt64(&s.AckLatencyTotal) / int64(sent))
+}
+
+// reportDeviceStats 输出最差的N个设备，并按配置写入完整CSV
+func reportDeviceStats(stats []*DeviceStats) {
+	if len(stats) == 0 {
+		return
+	}
+
+	if n := AppConfig.Stats.WorstN; n > 0 {
+		sorted := make([]*DeviceStats, len(stats))
+		copy(sorted, stats)
+		// 按连接失败、发送失败、重连次数、平均耗时依次排序
+		sort.SliceStable(sorted, func(i, j int) bool {
+			a, b := sorted[i], sorted[j]
+			if a.ConnectFailed != b.ConnectFailed {
+				return a.ConnectFailed > b.ConnectFailed
+			}
+			if a.Failures != b.Failures {
+				return a.Failures > b.Failures
+			}
+			if a.Reconnects != b.Reconnects {
+				return a.Reconnects > b.Reconnects
+			}
+			return a.MeanAckLatency() > b.MeanAckLatency()
+		})
+		if n > len(sorted) {
+			n = len(sorted)
+		}
+
+		log.Printf(T("\n========== 最差的 %d 个设备 =========="), n)
+		for _, s := range sorted[:n] {
+			log.Printf(T("设备 %s: 连接失败=%v, 发送=%d, 失败=%d, 重连=%d, 平均确认耗时=%v"),
+				s.Username, s.ConnectFailed == 1, s.Sent, s.Failures, s.Reconnects, s.MeanAckLatency())
+		}
+		log.Println("===============================")
+	}
+
+	if AppConfig.Stats.CSVFile != "" {
+		path := runArtifact(AppConfig.Stats.CSVFile)
+		if err := writeDeviceStatsCSV(path, stats); err != nil {
+			log.Printf(T("写入设备统计CSV失败: %v"), err)
+		} else {
+			log.Printf(T("设备统计已保存到: %s"), path)
+		}
+	}
+}
+
+// writeDeviceStatsCSV 将全部设备统计写入CSV文件
+func writeDeviceStatsCSV(path string, stats []*DeviceStats) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf(T("创建文件失败: %w"), err)
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	w.Write([]string{"device", "connect_failed", "sent", "failures", "reconnects", "mean_ack_ms",
+		"subscribe_ms", "suback_code"})
+	for _, s := range stats {
+		w.Write([]string{
+			s.Username,
+			strconv.FormatBool(s.ConnectFailed == 1),
+			strconv.FormatUint(s.Sent, 10),
+			strconv.FormatUint(s.Failures, 10),
+			strconv.FormatUint(s.Reconnects, 10),
+			strconv.FormatFloat(float64(s.MeanAckLatency())/float64(time.Millisecond), 'f', 3, 64),
+			strconv.FormatFloat(float64(s.SubscribeLatency)/float64(time.Millisecond), 'f', 3, 64),
+			strconv.Itoa(int(s.SubackCode)),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+)
+
+// 设备信息上报命令行参数
+var deviceInfo = flag.Bool("device-info", false, "设备连接成功后上报一次设备信息(固件版本、型号、IMEI等)")
+
+// deviceInfoStream 设备信息消息的数据流名称
+const deviceInfoStream = "device_info"
+
+// deviceInfoStats 设备信息消息的发布统计，未启用时为nil
+var deviceInfoStats *StreamStats
+
+// initDeviceInfo 设备信息上报的默认值，启用时把设备信息作为单独的数据流统计
+func initDeviceInfo() {
+	cfg := &AppConfig.DeviceInfo
+	if !cfg.Enabled {
+		return
+	}
+	if cfg.Topic == "" {
+		cfg.Topic = "devices/attributes/{username}"
+	}
+	if len(cfg.Models) == 0 {
+		cfg.Models = []string{"TP-100", "TP-200", "TP-300"}
+	}
+	deviceInfoStats = &StreamStats{Name: deviceInfoStream, Topic: cfg.Topic}
+	streamStats = append(streamStats, deviceInfoStats)
+}
+
+// imei 生成带Luhn校验位的15位IMEI格式编号
+func imei(faker *gofakeit.Faker) string {
+	digits := []byte("86" + faker.DigitN(12))
+	sum := 0
+	for i, d := range digits {
+		n := int(d - '0')
+		// 从右数(含校验位)偶数位加倍
+		if i%2 == 1 {
+			if n *= 2; n > 9 {
+				n -= 9
+			}
+		}
+		sum += n
+	}
+	return string(append(digits, byte('0'+(10-sum%10)%10)))
+}
+
+// deviceInfoPayload 生成设备信息消息
+//
+// 使用设备自己的随机数生成器，同一设备序号在每次运行中上报相同的信息，和真实设备一样不会每次连接都变化。
+func deviceInfoPayload(username string, faker *gofakeit.Faker) ([]byte, error) {
+	cfg := AppConfig.DeviceInfo
+	info := map[string]string{
+		"firmware_version": faker.AppVersion(),
+		"hardware_version": "v" + faker.DigitN(1),
+		"model":            faker.RandomString(cfg.Models),
+		"manufacturer":     faker.Company(),
+		"imei":             imei(faker),
+		"serial_number":    strings.ToUpper(faker.LetterN(4)) + faker.DigitN(8),
+		"mac":              faker.MacAddress(),
+		"ip":               faker.IPv4Address(),
+	}
+	for key, value := range cfg.Extra {
+		info[key] = strings.ReplaceAll(value, "{username}", username)
+	}
+	return json.Marshal(info)
+}
+
+// publishDeviceInfo 连接成功后上报一次设备信息，和真实设备一样在发送数据前完成
+func publishDeviceInfo(ctx context.Context, client DeviceClient, username string, qos byte, stats *DeviceStats, faker *gofakeit.Faker) {
+	if deviceInfoStats == nil {
+		return
+	}
+	payload, err := deviceInfoPayload(username, faker)
+	if err != nil {
+		log.Printf(T("序列化设备信息失败: %v"), err)
+		return
+	}
+	topic := strings.ReplaceAll(AppConfig.DeviceInfo.Topic, "{username}", username)
+
+	publishStart := time.Now()
+	err = publishWithRetry(ctx, client, topic, qos, payload)
+	publishElapsed := time.Since(publishStart)
+	stats.recordPublish(publishElapsed, err)
+	recordQoS(qos, publishElapsed, err)
+	deviceInfoStats.record(publishElapsed, err)
+	runStats.RecordPublish(deviceInfoStream, qos, clientBroker(client), 0, err)
+	if err != nil {
+		logErrorf(T("设备 %s 上报设备信息失败: %v"), username, err)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !windows
+
+package main
+
+import "errors"
+
+// diskFree 当前平台不支持获取磁盘剩余空间
+func diskFree(path string) (uint64, error) {
+	return 0, errors.ErrUnsupported
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 错误日志聚合命令行参数
+var errorSummaryInterval = flag.Duration("error-summary-interval", 0, "相同错误的汇总输出间隔：每种错误只完整输出第一次，之后按间隔输出出现次数(默认10s)，负数为不聚合")
+
+// errorLogMaxKinds 单独聚合的错误种类上限，超出后新出现的错误直接输出
+const errorLogMaxKinds = 1000
+
+// errorLogTop 测试结束时输出的错误种类数
+const errorLogTop = 10
+
+// errorKind 一种重复出现的错误
+type errorKind struct {
+	first   string    // 首次出现时的完整日志
+	at      time.Time // 首次出现的时间
+	total   uint64    // 累计出现次数
+	pending uint64    // 上次汇总后新增的次数
+}
+
+// errorLog 按种类聚合的错误日志
+var errorLog struct {
+	mu         sync.Mutex
+	kinds      map[string]*errorKind
+	order      []*errorKind // 按首次出现的顺序，汇总按该顺序输出
+	suppressed uint64       // 被聚合而未单独输出的日志条数，供JSON汇总使用
+}
+
+// errorLogEnabled 是否聚合重复的错误日志
+func errorLogEnabled() bool {
+	return AppConfig.Output.ErrorSummaryInterval > 0
+}
+
+// logErrorf 输出设备级错误日志，相同的错误只完整输出第一次
+//
+// 以格式字符串和其中error参数的内容识别相同的错误，设备token、计数等其他参数不参与比较。
+// 大量设备因同一原因(如connection refused)失败时只输出一条，之后由汇总按间隔输出次数，
+// 避免错误日志淹没监控输出。
+func logErrorf(format string, args ...any) {
+	var key strings.Builder
+	key.WriteString(format)
+	for _, arg := range args {
+		if err, ok := arg.(error); ok {
+			key.WriteByte(0)
+			key.WriteString(err.Error())
+		}
+	}
+	logRepeated(key.String(), func() string { return fmt.Sprintf(format, args...) })
+}
+
+// logRepeated 按key聚合一条日志，首次出现时输出line()的内容
+func logRepeated(key string, line func() string) {
+	errorCount.Add(1)
+	if !errorLogEnabled() {
+		log.Print(line())
+		return
+	}
+	errorLog.mu.Lock()
+	kind, ok := errorLog.kinds[key]
+	if !ok && len(errorLog.kinds) >= errorLogMaxKinds {
+		errorLog.mu.Unlock()
+		log.Print(line())
+		return
+	}
+	if !ok {
+		if errorLog.kinds == nil {
+			errorLog.kinds = make(map[string]*errorKind)
+		}
+		kind = &errorKind{first: line(), at: time.Now()}
+		errorLog.kinds[key] = kind
+		errorLog.order = append(errorLog.order, kind)
+	} else {
+		kind.pending++
+		errorLog.suppressed++
+	}
+	kind.total++
+	errorLog.mu.Unlock()
+
+	if !ok {
+		log.Print(kind.first)
+	}
+}
+
+// pahoErrorLogger 将paho的错误日志接入聚合，大量连接同时断开时paho对每个连接各输出一条
+type pahoErrorLogger struct {
+	prefix string
+}
+
+func (l pahoErrorLogger) Println(v ...any) {
+	line := l.prefix + strings.TrimSuffix(fmt.Sprintln(v...), "\n")
+	logRepeated(line, func() string { return line })
+}
+
+func (l pahoErrorLogger) Printf(format string, v ...any) {
+	line := l.prefix + fmt.Sprintf(format, v...)
+	logRepeated(line, func() string { return line })
+}
+
+// runErrorSummary 按间隔输出各种错误在本间隔内重复出现的次数，直到ctx结束
+func runErrorSummary(ctx context.Context) {
+	if !errorLogEnabled() {
+		return
+	}
+	ticker := time.NewTicker(AppConfig.Output.ErrorSummaryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			flushErrorSummary()
+			return
+		case <-ticker.C:
+			flushErrorSummary()
+		}
+	}
+}
+
+// flushErrorSummary 输出上次汇总后重复出现过的错误及次数
+func flushErrorSummary() {
+	errorLog.mu.Lock()
+	defer errorLog.mu.Unlock()
+	for _, kind := range errorLog.order {
+		if kind.pending == 0 {
+			continue
+		}
+		log.Printf(T("错误又出现 %d 次 (共 %d 次): %s"), kind.pending, kind.total, kind.first)
+		kind.pending = 0
+	}
+}
+
+// errorsSuppressed 聚合后未单独输出的日志条数
+func errorsSuppressed() uint64 {
+	errorLog.mu.Lock()
+	defer errorLog.mu.Unlock()
+	return errorLog.suppressed
+}
+
+// reportErrorLog 输出出现次数最多的错误(没有重复出现的错误时不输出)
+func reportErrorLog() {
+	errorLog.mu.Lock()
+	defer errorLog.mu.Unlock()
+	if errorLog.suppressed == 0 {
+		return
+	}
+	kinds := slices.Clone(errorLog.order)
+	slices.SortStableFunc(kinds, func(a, b *errorKind) int {
+		return cmp.Compare(b.total, a.total)
+	})
+	log.Println(T("\n========== 错误汇总 =========="))
+	log.Printf(T("错误种类: %d, 聚合后未单独输出: %d 条"), len(errorLog.order), errorLog.suppressed)
+	for _, kind := range kinds[:min(len(kinds), errorLogTop)] {
+		log.Printf(T("%d 次 (首次 %s): %s"), kind.total, kind.at.Format("15:04:05"), kind.first)
+	}
+	log.Println("===============================")
+}
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"unicode"
+
+	"github.com/brianvoe/gofakeit/v7"
+)
+
+// FieldConfig 用表达式定义的数据点
+type FieldConfig struct {
+	Key  string  `yaml:"key"`  // 数据点名称
+	Expr string  `yaml:"expr"` // 每个循环求值的表达式，如 "20 + 5*sin(t/300) + noise(0.5)"
+	Init float64 `yaml:"init"` // prev在第一次求值时的初始值
+}
+
+// exprEnv 表达式求值时可以访问的变量
+type exprEnv struct {
+	t     float64 // 自第一个循环起的秒数，按循环序号和发送间隔计算
+	i     float64 // 设备序号(从0开始)
+	n     float64 // 本设备已生成的消息数(从0开始)
+	prev  float64 // 本数据点上一次的值
+	faker *gofakeit.Faker
+}
+
+// expr 编译后的表达式
+type expr func(env *exprEnv) float64
+
+// exprFunc 表达式中可调用的函数
+type exprFunc struct {
+	args int // 参数个数
+	call func(env *exprEnv, args []float64) float64
+}
+
+// exprFuncs 表达式中可调用的函数
+var exprFuncs = map[string]exprFunc{
+	"sin":   {1, func(_ *exprEnv, a []float64) float64 { return math.Sin(a[0]) }},
+	"cos":   {1, func(_ *exprEnv, a []float64) float64 { return math.Cos(a[0]) }},
+	"abs":   {1, func(_ *exprEnv, a []float64) float64 { return math.Abs(a[0]) }},
+	"sqrt":  {1, func(_ *exprEnv, a []float64) float64 { return math.Sqrt(a[0]) }},
+	"exp":   {1, func(_ *exprEnv, a []float64) float64 { return math.Exp(a[0]) }},
+	"log":   {1, func(_ *exprEnv, a []float64) float64 { return math.Log(a[0]) }},
+	"floor": {1, func(_ *exprEnv, a []float64) float64 { return math.Floor(a[0]) }},
+	"ceil":  {1, func(_ *exprEnv, a []float64) float64 { return math.Ceil(a[0]) }},
+	"round": {2, func(_ *exprEnv, a []float64) float64 { return round(a[0], int(a[1])) }},
+	"min":   {2, func(_ *exprEnv, a []float64) float64 { return math.Min(a[0], a[1]) }},
+	"max":   {2, func(_ *exprEnv, a []float64) float64 { return math.Max(a[0], a[1]) }},
+	"pow":   {2, func(_ *exprEnv, a []float64) float64 { return math.Pow(a[0], a[1]) }},
+	"clamp": {3, func(_ *exprEnv, a []float64) float64 { return math.Min(math.Max(a[0], a[1]), a[2]) }},
+	// rand(a, b) 在[a, b)内均匀分布的随机数
+	"rand": {2, func(env *exprEnv, a []float64) float64 { return env.faker.Float64Range(a[0], a[1]) }},
+	// noise(sigma) 均值为0、标准差为sigma的正态分布噪声
+	"noise": {1, func(env *exprEnv, a []float64) float64 {
+		u1 := env.faker.Float64Range(math.SmallestNonzeroFloat64, 1)
+		u2 := env.faker.Float64Range(0, 1)
+		return a[0] * math.Sqrt(-2*math.Log(u1)) * math.Cos(2*math.Pi*u2)
+	}},
+}
+
+// exprVars 表达式中可以使用的变量
+var exprVars = map[string]expr{
+	"t":    func(env *exprEnv) float64 { return env.t },
+	"i":    func(env *exprEnv) float64 { return env.i },
+	"n":    func(env *exprEnv) float64 { return env.n },
+	"prev": func(env *exprEnv) float64 { return env.prev },
+	"pi":   func(*exprEnv) float64 { return math.Pi },
+}
+
+// exprParser 递归下降解析表达式，支持 + - * / % ^、括号、数字、变量和函数调用
+type exprParser struct {
+	src string
+	pos int
+}
+
+// compileExpr 编译表达式
+func compileExpr(src string) (expr, error) {
+	p := &exprParser{src: src}
+	e, err := p.parseSum()
+	if err == nil && p.skipSpace() < len(p.src) {
+		err = fmt.Errorf(T("位置 %d 处有多余的内容 %q"), p.pos+1, p.src[p.pos:])
+	}
+	if err != nil {
+		return nil, fmt.Errorf(T("表达式 %q: %w"), src, err)
+	}
+	return e, nil
+}
+
+// skipSpace 跳过空白，返回当前位置
+func (p *exprParser) skipSpace() int {
+	for p.pos < len(p.src) && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+	return p.pos
+}
+
+// peek 返回下一个非空白字符，到达末尾时返回0
+func (p *exprParser) peek() byte {
+	if p.skipSpace() < len(p.src) {
+		return p.src[p.pos]
+	}
+	return 0
+}
+
+// parseSum 解析加减
+func (p *exprParser) parseSum() (expr, error) {
+	left, err := p.parseProduct()
+	for err == nil {
+		op := p.peek()
+		if op != '+' && op != '-' {
+			break
+		}
+		p.pos++
+		var right expr
+		if right, err = p.parseProduct(); err != nil {
+			break
+		}
+		l := left
+		if op == '+' {
+			left = func(env *exprEnv) float64 { return l(env) + right(env) }
+		} else {
+			left = func(env *exprEnv) float64 { return l(env) - right(env) }
+		}
+	}
+	return left, err
+}
+
+// parseProduct 解析乘除和取余
+func (p *exprParser) parseProduct() (expr, error) {
+	left, err := p.parseUnary()
+	for err == nil {
+		op := p.peek()
+		if op != '*' && op != '/' && op != '%' {
+			break
+		}
+		p.pos++
+		var right expr
+		if right, err = p.parseUnary(); err != nil {
+			break
+		}
+		l := left
+		switch op {
+		case '*':
+			left = func(env *exprEnv) float64 { return l(env) * right(env) }
+		case '/':
+			left = func(env *exprEnv) float64 { return l(env) / right(env) }
+		default:
+			left = func(env *exprEnv) float64 { return math.Mod(l(env), right(env)) }
+		}
+	}
+	return left, err
+}
+
+// parseUnary 解析正负号
+func (p *exprParser) parseUnary() (expr, error) {
+	switch p.peek() {
+	case '-':
+		p.pos++
+		e, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(env *exprEnv) float64 { return -e(env) }, nil
+	case '+':
+		p.pos++
+		return p.parseUnary()
+	}
+	return p.parsePower()
+}
+
+// parsePower 解析乘方(右结合，优先级高于正负号，-2^2 = -4)
+func (p *exprParser) parsePower() (expr, error) {
+	base, err := p.parsePrimary()
+	if err != nil || p.peek() != '^' {
+		return base, err
+	}
+	p.pos++
+	exponent, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return func(env *exprEnv) float64 { return math.Pow(base(env), exponent(env)) }, nil
+}
+
+// parsePrimary 解析数字、变量、函数调用和括号
+func (p *exprParser) parsePrimary() (expr, error) {
+	c := p.peek()
+	start := p.pos
+	switch {
+	case c == '(':
+		p.pos++
+		e, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ')' {
+			return nil, fmt.Errorf(T("位置 %d 处缺少 )"), p.pos+1)
+		}
+		p.pos++
+		return e, nil
+	case c >= '0' && c <= '9' || c == '.':
+		for p.pos < len(p.src) && (p.src[p.pos] >= '0' && p.src[p.pos] <= '9' || p.src[p.pos] == '.' ||
+			p.src[p.pos] == 'e' || p.src[p.pos] == 'E' ||
+			(p.src[p.pos] == '-' || p.src[p.pos] == '+') && (p.src[p.pos-1] == 'e' || p.src[p.pos-1] == 'E')) {
+			p.pos++
+		}
+		v, err := strconv.ParseFloat(p.src[start:p.pos], 64)
+		if err != nil {
+			return nil, fmt.Errorf(T("无效的数字 %q"), p.src[start:p.pos])
+		}
+		return func(*exprEnv) float64 { return v }, nil
+	case c == '_' || unicode.IsLetter(rune(c)):
+		for p.pos < len(p.src) && (p.src[p.pos] == '_' || unicode.IsLetter(rune(p.src[p.pos])) || unicode.IsDigit(rune(p.src[p.pos]))) {
+			p.pos++
+		}
+		name := p.src[start:p.pos]
+		if p.peek() != '(' {
+			if v, ok := exprVars[name]; ok {
+				return v, nil
+			}
+			return nil, fmt.Errorf(T("未知的变量 %s (可用: t, i, n, prev, pi)"), name)
+		}
+		return p.parseCall(name)
+	case c == 0:
+		return nil, errors.New(T("表达式不完整"))
+	}
+	return nil, fmt.Errorf(T("位置 %d 处有无法识别的字符 %q"), p.pos+1, c)
+}
+
+// parseCall 解析函数调用的参数列表
+func (p *exprParser) parseCall(name string) (expr, error) {
+	fn, ok := exprFuncs[name]
+	if !ok {
+		return nil, fmt.Errorf(T("未知的函数 %s"), name)
+	}
+	p.pos++ // (
+	var args []expr
+	for p.peek() != ')' {
+		if len(args) > 0 {
+			if p.peek() != ',' {
+				return nil, fmt.Errorf(T("位置 %d 处缺少 , 或 )"), p.pos+1)
+			}
+			p.pos++
+		}
+		arg, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	p.pos++
+	if len(args) != fn.args {
+		return nil, fmt.Errorf(T("函数 %s 需要 %d 个参数，实际为 %d 个"), name, fn.args, len(args))
+	}
+	return func(env *exprEnv) float64 {
+		values := make([]float64, len(args))
+		for i, arg := range args {
+			values[i] = arg(env)
+		}
+		return fn.call(env, values)
+	}, nil
+}
+
+// parseFieldsFlag 解析 "key=表达式;key=表达式" 形式的数据点定义，表达式中可以含逗号
+func parseFieldsFlag(spec string) ([]FieldConfig, error) {
+	var fields []FieldConfig
+	for _, item := range strings.Split(spec, ";") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		key, e, ok := strings.Cut(item, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf(T("无效的数据点定义: %s (格式: 名称=表达式)"), item)
+		}
+		fields = append(fields, FieldConfig{Key: strings.TrimSpace(key), Expr: strings.TrimSpace(e)})
+	}
+	return fields, nil
+}
+
+// compiledFields 编译后的数据点表达式，按 data.fields 的顺序
+var compiledFields []expr
+
+// compileFields 编译配置的数据点表达式，检查名称是否重复
+func compileFields(fields []FieldConfig) error {
+	seen := make(map[string]bool, len(fields))
+	compiledFields = make([]expr, len(fields))
+	for i, f := range fields {
+		if f.Key == "" {
+			return fmt.Errorf(T("第 %d 个数据点缺少名称"), i+1)
+		}
+		if seen[f.Key] {
+			return fmt.Errorf(T("数据点 %s 重复定义"), f.Key)
+		}
+		seen[f.Key] = true
+		e, err := compileExpr(f.Expr)
+		if err != nil {
+			return fmt.Errorf(T("数据点 %s: %w"), f.Key, err)
+		}
+		compiledFields[i] = e
+	}
+	return nil
+}
+
+// exprErrors 表达式结果不是有限数(如除以0、log(0))的次数，此时数据点保留上一次的值
+var exprErrors atomic.Uint64
+
+// exprGenerator 每个循环对各数据点的表达式求值
+type exprGenerator struct {
+	jsonGenerator
+	env   exprEnv
+	cycle uint64 // 本次生成对应的循环，未设置时为0
+}
+
+// newExprGenerator 创建按表达式生成数据的生成器，index为设备序号
+func newExprGenerator(index int, faker *gofakeit.Faker) *exprGenerator {
+	keys := make([]string, len(AppConfig.Data.Fields))
+	for i, f := range AppConfig.Data.Fields {
+		keys[i] = f.Key
+	}
+	g := &exprGenerator{
+		jsonGenerator: jsonGenerator{data: newSensorData(keys), faker: faker},
+		env:           exprEnv{i: float64(index), faker: faker},
+	}
+	for i, f := range AppConfig.Data.Fields {
+		g.data[i].Value = f.Init
+	}
+	return g
+}
+
+// setGeneratorCycle 设置表达式生成器下一次生成对应的循环，其他生成器忽略
+//
+// t按循环序号和配置的发送间隔计算而不是读取时钟，所有设备同一循环的t相同(周期形状同相)，
+// 相同种子下的输出可以复现。
+func setGeneratorCycle(generator PayloadGenerator, cycle uint64) {
+	if g, ok := generator.(*exprGenerator); ok {
+		g.cycle = cycle
+	}
+}
+
+func (g *exprGenerator) Next() ([]byte, error) {
+	g.env.t = float64(max(g.cycle, 1)-1) * AppConfig.Test.DataInterval.Seconds()
+	for i, e := range compiledFields {
+		g.env.prev = g.data[i].Value
+		v := e(&g.env)
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			// 保留上一次的值，否则prev也变为非有限数，之后每个循环都无法编码
+			exprErrors.Add(1)
+			continue
+		}
+		g.data[i].Value = v
+	}
+	g.env.n++
+
+	g.buf = getPayloadBuffer()
+	payload, err := g.data.AppendJSON(*g.buf)
+	if err != nil {
+		g.Done()
+		return nil, err
+	}
+	*g.buf = payload
+	return payload, nil
+}
+
+// reportExprErrors 输出表达式求值错误统计(没有错误时不输出)
+func reportExprErrors() {
+	if n := exprErrors.Load(); n > 0 {
+		log.Printf(T("警告: 表达式数据点 %d 次求值结果不是有限数(如除以0、log(0))，这些数据点保留了上一次的值"), n)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+)
+
+func TestCompileExpr(t *testing.T) {
+	env := &exprEnv{t: 30, i: 2, n: 5, prev: 10}
+	tests := []struct {
+		src  string
+		want float64
+	}{
+		{"1 + 2 * 3", 7},
+		{"(1 + 2) * 3", 9},
+		{"10 - 4 - 3", 3},
+		{"24 / 4 / 3", 2},
+		{"7 % 4 * 2", 6},
+		{"-2^2", -4},
+		{"(-2)^2", 4},
+		{"2^3^2", 512},
+		{"2^-1", 0.5},
+		{"--3", 3},
+		{"1.5e2 + 1e-1", 150.1},
+		{"prev + i * n - t / 10", 17},
+		{"max(1, min(5, 3))", 3},
+		{"clamp(prev * 20, 0, 100)", 100},
+		{"round(pi, 2)", 3.14},
+		{"pow(2, 10)", 1024},
+		{"abs(-3) + sqrt(16) + floor(1.7) + ceil(1.2)", 10},
+	}
+	for _, tt := range tests {
+		e, err := compileExpr(tt.src)
+		if err != nil {
+			t.Errorf("compileExpr(%q) error: %v", tt.src, err)
+			continue
+		}
+		if got := e(env); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%q = %v, want %v", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestCompileExprErrors(t *testing.T) {
+	for _, src := range []string{
+		"",
+		"1 +",
+		"(1 + 2",
+		"1 2",
+		"x + 1",
+		"temp",
+		"foo(1)",
+		"sin()",
+		"sin(1, 2)",
+		"max(1)",
+		"clamp(1, 2)",
+		"rand(1, 2, 3)",
+		"sin(1 2)",
+		"1 # 2",
+	} {
+		if _, err := compileExpr(src); err == nil {
+			t.Errorf("compileExpr(%q) succeeded, want error", src)
+		}
+	}
+}
+
+func TestExprGeneratorNonFinite(t *testing.T) {
+	savedFields, savedCompiled, savedInterval := AppConfig.Data.Fields, compiledFields, AppConfig.Test.DataInterval
+	defer func() {
+		AppConfig.Data.Fields, compiledFields, AppConfig.Test.DataInterval = savedFields, savedCompiled, savedInterval
+	}()
+
+	AppConfig.Data.Fields = []FieldConfig{
+		{Key: "ratio", Expr: "prev / (n - 1)", Init: 4},
+		{Key: "elapsed", Expr: "t"},
+	}
+	AppConfig.Test.DataInterval = 10 * time.Second
+	if err := compileFields(AppConfig.Data.Fields); err != nil {
+		t.Fatal(err)
+	}
+
+	g := newExprGenerator(0, gofakeit.New(1))
+	before := exprErrors.Load()
+	for cycle := uint64(1); cycle <= 3; cycle++ {
+		setGeneratorCycle(g, cycle)
+		if _, err := g.Next(); err != nil {
+			t.Fatalf("cycle %d: %v", cycle, err)
+		}
+		g.Done()
+		if v := g.data[1].Value; v != float64(cycle-1)*10 {
+			t.Errorf("cycle %d: t = %v, want %v", cycle, v, float64(cycle-1)*10)
+		}
+	}
+	// n=1时除以0，保留上一次的值(-4)，之后的循环继续正常求值
+	if got := exprErrors.Load() - before; got != 1 {
+		t.Errorf("expression errors = %d, want 1", got)
+	}
+	if v := g.data[0].Value; v != -4 {
+		t.Errorf("ratio = %v, want -4", v)
+	}
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 设备接入场景命令行参数
+var (
+	growRate   = flag.Int("grow-rate", 0, "设备接入场景：测试中途持续接入新创建的设备(每分钟接入数)，现有设备的负载保持不变，0为不启用")
+	growSource = flag.String("grow-source", "", "设备接入场景：新设备的来源，file(监视token文件中新追加的行)或db(定期查询新创建的设备)")
+)
+
+// joinedDevice 测试中途接入的设备
+type joinedDevice struct {
+	found     time.Time   // 发现设备的时间
+	published atomic.Bool // 是否已首次发布成功
+}
+
+// fleetGrowth 设备接入的状态和统计
+var fleetGrowth struct {
+	known   map[string]bool // 已参与测试或已发现的token，只由发现goroutine访问
+	queue   chan string     // 已发现、等待接入的token
+	offset  int64           // file来源已读取到的位置
+	cursor  time.Time       // db来源已查询到的创建时间
+	started time.Time       // 开始接入的时间
+
+	mu      sync.Mutex
+	devices map[string]*joinedDevice // 已发现的新设备
+
+	discovered    atomic.Uint64
+	added         atomic.Uint64 // 已启动连接的设备数
+	connectFailed atomic.Uint64
+	connect       Histogram // 新设备CONNECT→CONNACK耗时
+	onboard       Histogram // 发现到首次发布成功的耗时
+}
+
+// fleetGrowthEnabled 是否在测试中途接入新设备
+func fleetGrowthEnabled() bool {
+	return AppConfig.FleetGrowth.Rate > 0
+}
+
+// initFleetGrowth 记录启动时已有的设备，之后发现的设备才视为新设备
+func initFleetGrowth(tokens *TokenList) error {
+	if !fleetGrowthEnabled() {
+		return nil
+	}
+	cfg := AppConfig.FleetGrowth
+	fleetGrowth.known = make(map[string]bool, tokens.Len())
+	for i := 0; i < tokens.Len(); i++ {
+		fleetGrowth.known[tokens.At(i)] = true
+	}
+	fleetGrowth.queue = make(chan string, 1024)
+	fleetGrowth.devices = make(map[string]*joinedDevice)
+	fleetGrowth.cursor = time.Now()
+
+	if cfg.Source == "file" {
+		// token文件中已有的行都视为已有设备，只接入之后追加的行
+		fleetGrowth.offset = -1
+		if _, err := readNewTokens(cfg.TokenFile); err != nil {
+			return fmt.Errorf(T("设备接入: %w"), err)
+		}
+	}
+	log.Printf(T("设备接入: 从 %s 发现新设备，每分钟接入 %d 个"), growthSourceName(), cfg.Rate)
+	return nil
+}
+
+// growthSourceName 新设备来源的描述
+func growthSourceName() string {
+	if AppConfig.FleetGrowth.Source == "file" {
+		return AppConfig.FleetGrowth.TokenFile
+	}
+	return T("数据库")
+}
+
+// startFleetGrowth 开始定期发现新设备并按速率接入，接入的设备和初始设备一样运行到测试结束
+//
+// wg为设备goroutine的等待组，新设备启动期间本goroutine持有一个计数，保证Wait之前完成Add。
+func startFleetGrowth(ctx context.Context, wg *sync.WaitGroup, next int) {
+	if !fleetGrowthEnabled() {
+		return
+	}
+	annotate("stage", fmt.Sprintf(T("开始接入新设备: 每分钟 %d 个"), AppConfig.FleetGrowth.Rate))
+	fleetGrowth.started = time.Now()
+	go discoverDevices(ctx)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(time.Minute / time.Duration(AppConfig.FleetGrowth.Rate))
+		defer ticker.Stop()
+		for index := next; ; index++ {
+			var username string
+			select {
+			case <-ctx.Done():
+				return
+			case username = <-fleetGrowth.queue:
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if limit := AppConfig.FleetGrowth.Max; limit > 0 && fleetGrowth.added.Load() >= uint64(limit) {
+				continue
+			}
+			fleetGrowth.added.Add(1)
+			qos := deviceQoS(index)
+			atomic.AddUint64(&qosStats[qos].Devices, 1)
+			wg.Add(1)
+			go connectAndPublish(wg, ctx, index, username, qos, false, nil, newDeviceFaker(index))
+		}
+	}()
+}
+
+// discoverDevices 按间隔查找新设备并放入接入队列
+func discoverDevices(ctx context.Context) {
+	ticker := time.NewTicker(AppConfig.FleetGrowth.PollInterval)
+	defer ticker.Stop()
+	for {
+		var found []string
+		var err error
+		if AppConfig.FleetGrowth.Source == "file" {
+			found, err = readNewTokens(AppConfig.FleetGrowth.TokenFile)
+		} else {
+			found, err = queryNewTokens(ctx)
+		}
+		if err != nil {
+			logErrorf(T("设备接入: %v"), err)
+		}
+		now := time.Now()
+		for _, username := range found {
+			if fleetGrowth.known[username] {
+				continue
+			}
+			fleetGrowth.known[username] = true
+			fleetGrowth.discovered.Add(1)
+			fleetGrowth.mu.Lock()
+			fleetGrowth.devices[username] = &joinedDevice{found: now}
+			fleetGrowth.mu.Unlock()
+			select {
+			case fleetGrowth.queue <- username:
+			case <-ctx.Done():
+				return
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// readNewTokens 读取token文件中上次读取位置之后追加的完整行
+//
+// offset为-1时只记录已有的token和文件末尾位置。文件被截断或替换(比上次读取的位置短)时
+// 从头重新读取，已知的token由调用方去重。
+func readNewTokens(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf(T("打开文件失败: %w"), err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	initial := fleetGrowth.offset < 0
+	if initial || info.Size() < fleetGrowth.offset {
+		fleetGrowth.offset = 0
+	}
+	if _, err := file.Seek(fleetGrowth.offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	var found []string
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			// 没有换行符的最后一行可能还在写入，下次再读
+			break
+		}
+		fleetGrowth.offset += int64(len(line))
+		username := strings.TrimRight(line, "\r\n")
+		if username == "" {
+			continue
+		}
+		if initial {
+			fleetGrowth.known[username] = true
+			continue
+		}
+		found = append(found, username)
+	}
+	return found, nil
+}
+
+// queryNewTokens 查询上次查询之后创建的设备的token
+func queryNewTokens(ctx context.Context) ([]string, error) {
+	db, err := openReadDatabase()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	rows, err := db.QueryContext(queryCtx, `SELECT voucher::json->>'username', created_at FROM devices
+		WHERE created_at > $1 AND voucher IS NOT NULL ORDER BY created_at`, fleetGrowth.cursor)
+	if err != nil {
+		return nil, fmt.Errorf(T("查询新创建的设备失败: %w"), err)
+	}
+	defer rows.Close()
+	var found []string
+	for rows.Next() {
+		var username sql.NullString
+		var created time.Time
+		if err := rows.Scan(&username, &created); err != nil {
+			return nil, fmt.Errorf(T("查询新创建的设备失败: %w"), err)
+		}
+		if username.Valid && username.String != "" {
+			found = append(found, username.String)
+		}
+		fleetGrowth.cursor = created
+	}
+	return found, rows.Err()
+}
+
+// joinedDeviceOf 返回测试中途接入的设备，未启用或为初始设备时返回nil
+func joinedDeviceOf(username string) *joinedDevice {
+	if !fleetGrowthEnabled() {
+		return nil
+	}
+	fleetGrowth.mu.Lock()
+	defer fleetGrowth.mu.Unlock()
+	return fleetGrowth.devices[username]
+}
+
+// recordConnect 记录新设备的连接结果，nil表示初始设备
+func (d *joinedDevice) recordConnect(latency time.Duration, err error) {
+	if d == nil {
+		return
+	}
+	if err != nil {
+		fleetGrowth.connectFailed.Add(1)
+		return
+	}
+	fleetGrowth.connect.Record(latency)
+}
+
+// recordPublish 新设备首次发布成功时记录从发现到接入完成的耗时，nil表示初始设备
+func (d *joinedDevice) recordPublish(err error) {
+	if d == nil || err != nil || d.published.Swap(true) {
+		return
+	}
+	fleetGrowth.onboard.Record(time.Since(d.found))
+}
+
+// reportFleetGrowth 输出测试中途接入设备的统计(未启用时不输出)
+func reportFleetGrowth() {
+	if !fleetGrowthEnabled() {
+		return
+	}
+	log.Println(T("\n========== 设备接入 =========="))
+	if fleetGrowth.started.IsZero() {
+		log.Println(T("测试未开始发送，没有接入新设备"))
+		log.Println("===============================")
+		return
+	}
+	added := fleetGrowth.added.Load()
+	elapsed := time.Since(fleetGrowth.started)
+	discovered := fleetGrowth.discovered.Load()
+	log.Printf(T("发现新设备: %d 个, 接入 %d 个 (%.1f 个/分钟, 目标 %d), 连接失败 %d, 未接入 %d"),
+		discovered, added, ratePerSecond(float64(added), elapsed)*60, AppConfig.FleetGrowth.Rate,
+		fleetGrowth.connectFailed.Load(), discovered-added)
+	if h := &fleetGrowth.connect; h.Count() > 0 {
+		log.Printf(T("  新设备连接耗时: p50 %v, p99 %v, 最大 %v"), h.Quantile(0.50), h.Quantile(0.99), h.Max())
+	}
+	if h := &fleetGrowth.onboard; h.Count() > 0 {
+		log.Printf(T("  发现到首次发布成功: %d 个设备, p50 %v, p99 %v, 最大 %v"), h.Count(), h.Quantile(0.50), h.Quantile(0.99), h.Max())
+	}
+	if limit := AppConfig.FleetGrowth.Max; limit > 0 && added >= uint64(limit) {
+		log.Printf(T("  已达到接入上限 %d 个，之后发现的设备未接入"), limit)
+	}
+	log.Println("===============================")
+}
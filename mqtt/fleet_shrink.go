@@ -0,0 +1,418 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 设备删除场景命令行参数
+var (
+	deleteDevices = flag.Float64("delete-devices", 0, "设备删除场景：测试中途删除该比例(%)的设备，验证其后续发布被拒绝、数据不再入库，测量删除在认证缓存和入库链路中的生效耗时，0为不启用")
+	deleteCycle   = flag.Int("delete-cycle", 0, "设备删除场景：在第几个循环删除设备(默认为总循环数的一半)")
+)
+
+// defaultDeletePath 通过平台API删除设备的默认接口，对应ThingsPanel的设备删除接口
+const defaultDeletePath = "/api/v1/device/{device_id}"
+
+// deletedDevice 被删除的设备及删除后的观测结果
+type deletedDevice struct {
+	id       string
+	username string
+	row      string // db方式删除前的设备行(JSON)，用于测试结束后恢复
+	err      error  // 删除失败的原因
+
+	deletedAt     atomic.Int64  // 删除请求返回的时间(UnixNano)，0为尚未删除
+	rejectedAt    atomic.Int64  // 删除后首次发布失败的时间(UnixNano)，0为没有失败
+	acceptedAfter atomic.Uint64 // 删除后仍发布成功的消息数
+
+	authRejected time.Duration // 从删除到凭证首次被拒绝的耗时，-1为超时前仍可连接
+	authAccepts  int           // 删除后凭证仍连接成功的次数
+}
+
+// fleetShrink 设备删除场景的状态和统计
+var fleetShrink struct {
+	devices []*deletedDevice
+	byToken map[string]*deletedDevice // 启动后只读
+	done    sync.WaitGroup
+
+	probeErrors   atomic.Uint64 // 探测连接的网络错误(未收到CONNACK)
+	authLatency   Histogram     // 删除到凭证被拒绝的耗时
+	rejectLatency Histogram     // 删除到已连接的设备发布首次失败的耗时
+	lastAccepted  atomic.Int64  // 被删除的设备最后一次发布成功的时间(UnixNano)
+
+	stored     int64         // 删除后入库的数据点数，-1为未查询
+	lastStored time.Duration // 删除到最后一个入库数据点的时间
+}
+
+// fleetShrinkEnabled 是否进行设备删除
+func fleetShrinkEnabled() bool {
+	return AppConfig.FleetShrink.Ratio > 0
+}
+
+// initFleetShrink 按token哈希选择要删除的设备并查询设备ID
+//
+// 同一配置下每次运行选中的设备相同。db方式删除的设备在测试结束时恢复(fleet_shrink.keep
+// 为true时不恢复)，api方式删除的设备需要重新创建。
+func initFleetShrink(tokens *TokenList) error {
+	fleetShrink.stored = -1
+	if !fleetShrinkEnabled() {
+		return nil
+	}
+	var usernames []string
+	for i := 0; i < min(tokens.Len(), AppConfig.Device.ClientNumber); i++ {
+		if hashSelected(tokens.At(i), AppConfig.FleetShrink.Ratio) {
+			usernames = append(usernames, tokens.At(i))
+		}
+	}
+	if len(usernames) == 0 {
+		return fmt.Errorf(T("设备删除: 按比例 %.1f%% 没有选中任何设备"), AppConfig.FleetShrink.Ratio)
+	}
+	db, err := openDatabase()
+	if err != nil {
+		return fmt.Errorf(T("设备删除: %w"), err)
+	}
+	defer db.Close()
+
+	fleetShrink.byToken = make(map[string]*deletedDevice, len(usernames))
+	for start := 0; start < len(usernames); start += tenantLookupBatch {
+		if err := lookupDeletedDevices(db, usernames[start:min(start+tenantLookupBatch, len(usernames))]); err != nil {
+			return err
+		}
+	}
+	if len(fleetShrink.devices) == 0 {
+		return fmt.Errorf(T("设备删除: %d 个设备的token在数据库中都找不到设备"), len(usernames))
+	}
+	if missing := len(usernames) - len(fleetShrink.devices); missing > 0 {
+		log.Printf(T("警告: 设备删除: %d 个token在数据库中找不到设备，不参与删除"), missing)
+	}
+	log.Printf(T("设备删除: 第 %d 个循环删除 %d 个设备 (方式: %s)"),
+		AppConfig.FleetShrink.Cycle, len(fleetShrink.devices), AppConfig.FleetShrink.Method)
+	return nil
+}
+
+// lookupDeletedDevices 查询一批token对应的设备ID
+func lookupDeletedDevices(db *sql.DB, batch []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	rows, err := db.QueryContext(ctx, `SELECT id, voucher::json->>'username' FROM devices
+		WHERE voucher::json->>'username' = ANY($1)`, batch)
+	if err != nil {
+		return fmt.Errorf(T("设备删除: 查询设备失败: %w"), err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		d := &deletedDevice{authRejected: -1}
+		if err := rows.Scan(&d.id, &d.username); err != nil {
+			return fmt.Errorf(T("设备删除: 查询设备失败: %w"), err)
+		}
+		if _, ok := fleetShrink.byToken[d.username]; ok {
+			continue
+		}
+		fleetShrink.byToken[d.username] = d
+		fleetShrink.devices = append(fleetShrink.devices, d)
+	}
+	return rows.Err()
+}
+
+// deletedDeviceOf 返回设备的删除状态，未启用或设备未被选中时返回nil
+func deletedDeviceOf(username string) *deletedDevice {
+	return fleetShrink.byToken[username]
+}
+
+// record 记录设备的一次遥测发布结果，只统计删除之后开始的发布，nil表示设备未被选中
+func (d *deletedDevice) record(start time.Time, err error) {
+	if d == nil {
+		return
+	}
+	deletedAt := d.deletedAt.Load()
+	if deletedAt == 0 || start.UnixNano() < deletedAt {
+		return
+	}
+	now := time.Now()
+	if err == nil {
+		d.acceptedAfter.Add(1)
+		for last := fleetShrink.lastAccepted.Load(); now.UnixNano() > last; last = fleetShrink.lastAccepted.Load() {
+			if fleetShrink.lastAccepted.CompareAndSwap(last, now.UnixNano()) {
+				break
+			}
+		}
+		return
+	}
+	if d.rejectedAt.CompareAndSwap(0, now.UnixNano()) {
+		fleetShrink.rejectLatency.Record(time.Duration(now.UnixNano() - deletedAt))
+	}
+}
+
+// startFleetShrink 删除选中的设备并开始用其凭证探测连接，直到被拒绝或超时
+//
+// 被删除的设备继续按计划发布，由record统计已建立的连接上的发布何时开始失败。
+func startFleetShrink(ctx context.Context) {
+	if !fleetShrinkEnabled() || len(fleetShrink.devices) == 0 {
+		return
+	}
+	annotate("stage", fmt.Sprintf(T("删除 %d 个设备"), len(fleetShrink.devices)))
+	fleetShrink.done.Add(1)
+	go func() {
+		defer fleetShrink.done.Done()
+		if err := deleteDevicesNow(); err != nil {
+			log.Printf(T("设备删除: %v"), err)
+			return
+		}
+		var probes sync.WaitGroup
+		for _, d := range fleetShrink.devices {
+			if d.err != nil {
+				continue
+			}
+			probes.Add(1)
+			go func() {
+				defer probes.Done()
+				probeDeleted(ctx, d)
+			}()
+		}
+		probes.Wait()
+	}()
+}
+
+// deleteDevicesNow 通过数据库或平台API删除设备，db方式将删除前的设备行保存到运行目录
+func deleteDevicesNow() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	if AppConfig.FleetShrink.Method == "api" {
+		for _, d := range fleetShrink.devices {
+			path := strings.ReplaceAll(AppConfig.FleetShrink.Path, "{device_id}", d.id)
+			if _, _, d.err = apiCall(ctx, "DELETE", path, nil); d.err != nil {
+				logErrorf(T("设备删除: 删除设备 %s 失败: %v"), d.username, d.err)
+				continue
+			}
+			d.deletedAt.Store(time.Now().UnixNano())
+		}
+		return nil
+	}
+
+	db, err := openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	ids := make([]string, 0, len(fleetShrink.devices))
+	for _, d := range fleetShrink.devices {
+		ids = append(ids, d.id)
+	}
+	rows, err := db.QueryContext(ctx, `DELETE FROM devices WHERE id = ANY($1) RETURNING id, row_to_json(devices)::text`, ids)
+	if err != nil {
+		return fmt.Errorf(T("删除设备失败: %w"), err)
+	}
+	defer rows.Close()
+	deleted := make(map[string]string, len(ids))
+	for rows.Next() {
+		var id, row string
+		if err := rows.Scan(&id, &row); err != nil {
+			return fmt.Errorf(T("删除设备失败: %w"), err)
+		}
+		deleted[id] = row
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf(T("删除设备失败: %w"), err)
+	}
+	now := time.Now().UnixNano()
+	var saved []json.RawMessage
+	for _, d := range fleetShrink.devices {
+		row, ok := deleted[d.id]
+		if !ok {
+			d.err = errors.New(T("设备已不存在"))
+			continue
+		}
+		d.row = row
+		d.deletedAt.Store(now)
+		saved = append(saved, json.RawMessage(row))
+	}
+	// 进程异常退出时可以据此手动恢复
+	data, _ := json.MarshalIndent(saved, "", "  ")
+	writeRunArtifact("deleted_devices.json", data)
+	return nil
+}
+
+// probeDeleted 按间隔用被删除设备的凭证建立连接，直到被拒绝或超时
+func probeDeleted(ctx context.Context, d *deletedDevice) {
+	cfg := AppConfig.FleetShrink
+	ticker := time.NewTicker(cfg.ProbeInterval)
+	defer ticker.Stop()
+	deletedAt := time.Unix(0, d.deletedAt.Load())
+	deadline := deletedAt.Add(cfg.Timeout)
+	for {
+		accepted, ok := probeConnect(d.username+"_deleted", d.username)
+		switch {
+		case !ok:
+			fleetShrink.probeErrors.Add(1)
+		case accepted:
+			d.authAccepts++
+		default:
+			d.authRejected = time.Since(deletedAt)
+			fleetShrink.authLatency.Record(d.authRejected)
+			return
+		}
+		if time.Now().After(deadline) {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// fleetShrinkOutcome 设备删除的结果计数
+type fleetShrinkOutcome struct {
+	deleted       int    // 删除成功的设备数
+	authRejected  int    // 凭证在超时前被拒绝的设备数
+	authValid     int    // 超时时凭证仍可连接的设备数
+	publishFailed int    // 删除后已建立的连接上发布失败的设备数
+	acceptedAfter uint64 // 删除后仍发布成功的消息数
+	since         time.Time
+}
+
+// classifyFleetShrink 统计设备删除的结果
+func classifyFleetShrink() fleetShrinkOutcome {
+	var o fleetShrinkOutcome
+	for _, d := range fleetShrink.devices {
+		deletedAt := d.deletedAt.Load()
+		if deletedAt == 0 {
+			continue
+		}
+		if o.deleted == 0 || deletedAt < o.since.UnixNano() {
+			o.since = time.Unix(0, deletedAt)
+		}
+		o.deleted++
+		if d.authRejected >= 0 {
+			o.authRejected++
+		} else if d.authAccepts > 0 {
+			o.authValid++
+		}
+		if d.rejectedAt.Load() != 0 {
+			o.publishFailed++
+		}
+		o.acceptedAfter += d.acceptedAfter.Load()
+	}
+	return o
+}
+
+// reportFleetShrink 等待探测完成，输出删除在认证和入库链路中的生效情况，并恢复db方式删除的设备(未启用时不输出)
+func reportFleetShrink() {
+	if !fleetShrinkEnabled() {
+		return
+	}
+	fleetShrink.done.Wait()
+	o := classifyFleetShrink()
+	if o.deleted > 0 {
+		if err := queryDeletedStored(o.since); err != nil {
+			log.Printf(T("设备删除: %v"), err)
+		}
+	}
+
+	log.Println(T("\n========== 设备删除 =========="))
+	log.Printf(T("删除设备: %d 个, 失败 %d, 探测超时 %v, 探测网络错误 %d 次"),
+		o.deleted, len(fleetShrink.devices)-o.deleted, AppConfig.FleetShrink.Timeout, fleetShrink.probeErrors.Load())
+	if o.deleted == 0 {
+		log.Println(T("没有设备在测试结束前被删除，请确认 fleet_shrink.cycle 不超过总循环数"))
+		log.Println("===============================")
+		return
+	}
+	log.Printf(T("凭证被拒绝: %d/%d 个设备"), o.authRejected, o.deleted)
+	if h := &fleetShrink.authLatency; h.Count() > 0 {
+		log.Printf(T("  删除到首次被拒绝: p50 %v, p99 %v, 最大 %v"), h.Quantile(0.50), h.Quantile(0.99), h.Max())
+	}
+	if o.authValid > 0 {
+		log.Printf(T("  安全问题: %d 个已删除设备的凭证在删除 %v 后仍可连接"), o.authValid, AppConfig.FleetShrink.Timeout)
+	}
+	log.Printf(T("已建立的连接: %d/%d 个设备删除后发布失败, 删除后仍发布成功 %d 条消息"), o.publishFailed, o.deleted, o.acceptedAfter)
+	if h := &fleetShrink.rejectLatency; h.Count() > 0 {
+		log.Printf(T("  删除到首次发布失败: p50 %v, p99 %v, 最大 %v"), h.Quantile(0.50), h.Quantile(0.99), h.Max())
+	}
+	if last := fleetShrink.lastAccepted.Load(); last > 0 {
+		log.Printf(T("  删除后最后一次发布成功: 删除后 %v"), time.Unix(0, last).Sub(o.since).Round(time.Millisecond))
+	}
+	if fleetShrink.stored >= 0 {
+		log.Printf(T("删除后入库: %d 个数据点"), fleetShrink.stored)
+		if fleetShrink.stored > 0 {
+			log.Printf(T("  最后一个入库数据点: 删除后 %v"), fleetShrink.lastStored.Round(time.Millisecond))
+		}
+	}
+	restoreDeletedDevices()
+	log.Println("===============================")
+}
+
+// queryDeletedStored 查询被删除的设备自删除起入库的数据点数和最后一个数据点的时间
+func queryDeletedStored(since time.Time) error {
+	db, err := openReadDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	ingestSettle()
+	waitReplicaReplay(db)
+
+	ids := make([]string, 0, len(fleetShrink.devices))
+	for _, d := range fleetShrink.devices {
+		if d.deletedAt.Load() != 0 {
+			ids = append(ids, d.id)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	var last int64
+	err = db.QueryRowContext(ctx, `SELECT COUNT(*), COALESCE(MAX(ts), 0) FROM telemetry_datas
+		WHERE device_id = ANY($1) AND ts >= $2`, ids, since.UnixMilli()).Scan(&fleetShrink.stored, &last)
+	if err != nil {
+		fleetShrink.stored = -1
+		return fmt.Errorf(T("查询删除后入库数失败: %w"), err)
+	}
+	if last > 0 {
+		fleetShrink.lastStored = time.UnixMilli(last).Sub(since)
+	}
+	return nil
+}
+
+// restoreDeletedDevices 恢复db方式删除的设备行，使token文件中的这些设备可以继续用于测试
+func restoreDeletedDevices() {
+	if AppConfig.FleetShrink.Method == "api" {
+		log.Println(T("通过API删除的设备不会恢复，需用create_device重新创建"))
+		return
+	}
+	if AppConfig.FleetShrink.Keep {
+		log.Println(T("fleet_shrink.keep 已启用，被删除的设备不会恢复(删除前的设备行见运行目录的deleted_devices.json)"))
+		return
+	}
+	db, err := openDatabase()
+	if err != nil {
+		log.Printf(T("设备删除: %v"), err)
+		return
+	}
+	defer db.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	restored, failed := 0, 0
+	for _, d := range fleetShrink.devices {
+		if d.row == "" {
+			continue
+		}
+		_, err := db.ExecContext(ctx, `INSERT INTO devices SELECT * FROM json_populate_record(NULL::devices, $1::json)
+			ON CONFLICT DO NOTHING`, d.row)
+		if err != nil {
+			failed++
+			logErrorf(T("设备删除: 恢复设备 %s 失败: %v"), d.username, err)
+			continue
+		}
+		restored++
+	}
+	log.Printf(T("恢复被删除的设备: %d 个, 失败 %d (删除前的设备行见运行目录的deleted_devices.json)"), restored, failed)
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"log"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 看板端到端延迟命令行参数
+var dashboardLatencyEvery = flag.Int("dashboard-latency", 0, "看板端到端延迟：被看板订阅的设备每N条消息附加一个序号数据点，测量从设备发布到看板收到推送的耗时，0为不启用")
+
+// defaultDashboardLatencyKey 附加的序号数据点的默认名称
+const defaultDashboardLatencyKey = "perf_g2g"
+
+// glassLatency 看板端到端延迟("glass-to-glass")的状态和统计
+var glassLatency struct {
+	viewers map[string]int // 被看板订阅的设备token -> 订阅它的看板数，启动看板时确定，之后只读
+	quoted  []byte         // 序号数据点名称(带引号)，附加到消息中
+	key     []byte         // 序号数据点名称，用于快速过滤推送(推送可能把数据放在转义的字符串中)
+
+	seq  atomic.Uint64
+	mu   sync.Mutex
+	sent map[uint64]time.Time // 序号 -> 开始发布的时间，发布失败时删除
+
+	marked   atomic.Uint64 // 发布成功的带序号消息数
+	expected atomic.Uint64 // 应收到的推送数(带序号消息数 x 订阅该设备的看板数)
+	received atomic.Uint64 // 看板收到的带序号推送数
+	unknown  atomic.Uint64 // 收到的无法关联的序号(发布失败或重复推送)
+	latency  Histogram     // 开始发布到看板收到推送的耗时
+}
+
+// glassLatencyEnabled 是否测量看板端到端延迟
+func glassLatencyEnabled() bool {
+	return dashboardsEnabled() && AppConfig.Dashboards.LatencyEvery > 0
+}
+
+// initGlassLatency 记录被看板订阅的设备，只有这些设备的消息附加序号数据点
+func initGlassLatency(targets []dashboardTarget) {
+	if !glassLatencyEnabled() {
+		return
+	}
+	glassLatency.viewers = make(map[string]int)
+	for _, target := range targets {
+		glassLatency.viewers[target.username]++
+	}
+	glassLatency.quoted = strconv.AppendQuote(nil, AppConfig.Dashboards.LatencyKey)
+	glassLatency.key = []byte(AppConfig.Dashboards.LatencyKey)
+	glassLatency.sent = make(map[uint64]time.Time)
+}
+
+// glassMarker 设备附加序号数据点的状态
+type glassMarker struct {
+	viewers int
+	count   int    // 已生成的消息数
+	seq     uint64 // 本条消息的序号，0表示本条没有附加
+	buf     []byte
+}
+
+// newGlassMarker 创建设备的序号标记器，未启用或设备没有被看板订阅时返回nil
+func newGlassMarker(username string) *glassMarker {
+	if !glassLatencyEnabled() || glassLatency.viewers[username] == 0 {
+		return nil
+	}
+	return &glassMarker{viewers: glassLatency.viewers[username]}
+}
+
+// append 每N条消息在末尾附加序号数据点，返回新消息和增加的数据点数，nil表示未启用
+func (m *glassMarker) append(payload []byte) ([]byte, int) {
+	if m == nil {
+		return payload, 0
+	}
+	m.seq = 0
+	m.count++
+	if m.count%AppConfig.Dashboards.LatencyEvery != 0 || len(payload) < 2 || payload[len(payload)-1] != '}' {
+		return payload, 0
+	}
+	m.seq = glassLatency.seq.Add(1)
+	m.buf = append(m.buf[:0], payload[:len(payload)-1]...)
+	if len(m.buf) > 1 {
+		m.buf = append(m.buf, ',')
+	}
+	m.buf = append(m.buf, glassLatency.quoted...)
+	m.buf = append(m.buf, ':')
+	m.buf = strconv.AppendUint(m.buf, m.seq, 10)
+	m.buf = append(m.buf, '}')
+
+	// 在发布前记录时间：看板可能在PUBACK返回前就收到推送
+	glassLatency.mu.Lock()
+	glassLatency.sent[m.seq] = time.Now()
+	glassLatency.mu.Unlock()
+	return m.buf, 1
+}
+
+// record 记录本条消息的发布结果，发布失败的序号不再等待推送，nil表示未启用
+func (m *glassMarker) record(err error) {
+	if m == nil || m.seq == 0 {
+		return
+	}
+	if err != nil {
+		glassLatency.mu.Lock()
+		delete(glassLatency.sent, m.seq)
+		glassLatency.mu.Unlock()
+		return
+	}
+	glassLatency.marked.Add(1)
+	glassLatency.expected.Add(uint64(m.viewers))
+}
+
+// recordGlassPush 看板收到推送时调用，推送中带有序号时记录端到端延迟
+//
+// 同一设备的多个看板都会收到同一序号，每个看板的耗时分别记录。
+func recordGlassPush(message []byte, at time.Time) {
+	if !glassLatencyEnabled() || !bytes.Contains(message, glassLatency.key) {
+		return
+	}
+	seq, ok := payloadSeq(message, AppConfig.Dashboards.LatencyKey)
+	if !ok {
+		return
+	}
+	glassLatency.mu.Lock()
+	sent, ok := glassLatency.sent[seq]
+	glassLatency.mu.Unlock()
+	if !ok {
+		glassLatency.unknown.Add(1)
+		return
+	}
+	glassLatency.received.Add(1)
+	glassLatency.latency.Record(at.Sub(sent))
+}
+
+// reportGlassLatency 输出看板端到端延迟(未启用时不输出)
+func reportGlassLatency() {
+	if !glassLatencyEnabled() {
+		return
+	}
+	expected, received := glassLatency.expected.Load(), glassLatency.received.Load()
+	log.Println(T("\n========== 看板端到端延迟(设备发布→看板收到) =========="))
+	log.Printf(T("带序号的消息: %d 条 (每 %d 条消息一次), 应收到推送: %d, 收到: %d, 未收到: %d (%.2f%%), 无法关联: %d"),
+		glassLatency.marked.Load(), AppConfig.Dashboards.LatencyEvery, expected, received,
+		expected-min(received, expected), percent(expected-min(received, expected), expected), glassLatency.unknown.Load())
+	h := &glassLatency.latency
+	if h.Count() > 0 {
+		log.Printf(T("延迟: 平均 %v, p50 %v, p95 %v, p99 %v, 最大 %v"),
+			h.Mean(), h.Quantile(0.50), h.Quantile(0.95), h.Quantile(0.99), h.Max())
+	} else if expected > 0 {
+		log.Printf(T("看板没有收到带序号 %s 的推送，请确认平台推送的是设备上报的原始数据点"), AppConfig.Dashboards.LatencyKey)
+	}
+	log.Println("===============================")
+}
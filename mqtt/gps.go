@@ -0,0 +1,273 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+)
+
+// GPS轨迹模拟命令行参数
+var (
+	gpsRoutes = flag.String("gps-routes", "", "GPS轨迹模拟: 包含LineString/MultiLineString的GeoJSON文件，设备沿路线循环行驶并上报经纬度和速度")
+	gpsBBox   = flag.String("gps-bbox", "", "GPS轨迹模拟: 未指定路线时在该范围内随机行驶，格式为 最小经度,最小纬度,最大经度,最大纬度")
+)
+
+// earthRadius 地球平均半径(米)
+const earthRadius = 6371000
+
+// gpsPointCount 每条轨迹消息包含的数据点数(纬度、经度、速度)
+const gpsPointCount = 3
+
+// geoPoint 经纬度坐标(度)
+type geoPoint struct {
+	lng, lat float64
+}
+
+// gpsRouteList 从GeoJSON文件加载的路线，每条路线至少两个点
+var gpsRouteList [][]geoPoint
+
+// gpsArea 随机行驶的范围
+var gpsArea struct {
+	min, max geoPoint
+}
+
+// gpsEnabled 是否启用GPS轨迹模拟
+func gpsEnabled() bool {
+	return AppConfig.GPS.Routes != "" || AppConfig.GPS.BBox != ""
+}
+
+// initGPS 加载路线文件或解析随机行驶的范围
+func initGPS() error {
+	if !gpsEnabled() {
+		return nil
+	}
+	if AppConfig.GPS.Routes != "" {
+		routes, err := loadRoutes(AppConfig.GPS.Routes)
+		if err != nil {
+			return err
+		}
+		gpsRouteList = routes
+		log.Printf(T("GPS轨迹模拟: 从 %s 加载 %d 条路线"), AppConfig.GPS.Routes, len(routes))
+		return nil
+	}
+	var bounds [4]float64
+	parts := strings.Split(AppConfig.GPS.BBox, ",")
+	for i, part := range parts {
+		if i >= len(bounds) {
+			break
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return fmt.Errorf(T("无效的GPS范围 %q: %w"), AppConfig.GPS.BBox, err)
+		}
+		bounds[i] = v
+	}
+	gpsArea.min = geoPoint{lng: bounds[0], lat: bounds[1]}
+	gpsArea.max = geoPoint{lng: bounds[2], lat: bounds[3]}
+	if len(parts) != 4 || gpsArea.min.lng >= gpsArea.max.lng || gpsArea.min.lat >= gpsArea.max.lat ||
+		gpsArea.min.lat < -90 || gpsArea.max.lat > 90 || gpsArea.min.lng < -180 || gpsArea.max.lng > 180 {
+		return fmt.Errorf(T("无效的GPS范围 %q (格式: 最小经度,最小纬度,最大经度,最大纬度)"), AppConfig.GPS.BBox)
+	}
+	return nil
+}
+
+// geoJSON GeoJSON对象，FeatureCollection、Feature和几何对象共用
+type geoJSON struct {
+	Type        string          `json:"type"`
+	Features    []geoJSON       `json:"features"`
+	Geometry    *geoJSON        `json:"geometry"`
+	Geometries  []geoJSON       `json:"geometries"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// loadRoutes 读取GeoJSON文件中的全部LineString和MultiLineString
+func loadRoutes(path string) ([][]geoPoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf(T("读取GPS路线文件失败: %w"), err)
+	}
+	var doc geoJSON
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf(T("解析GPS路线文件失败: %w"), err)
+	}
+	var routes [][]geoPoint
+	if err := collectRoutes(&doc, &routes); err != nil {
+		return nil, fmt.Errorf(T("解析GPS路线文件失败: %w"), err)
+	}
+	if len(routes) == 0 {
+		return nil, fmt.Errorf(T("GPS路线文件 %s 中没有至少包含两个点的LineString"), path)
+	}
+	return routes, nil
+}
+
+// collectRoutes 递归收集GeoJSON对象中的路线，其他几何类型忽略
+func collectRoutes(g *geoJSON, routes *[][]geoPoint) error {
+	switch g.Type {
+	case "FeatureCollection":
+		for i := range g.Features {
+			if err := collectRoutes(&g.Features[i], routes); err != nil {
+				return err
+			}
+		}
+	case "Feature":
+		if g.Geometry != nil {
+			return collectRoutes(g.Geometry, routes)
+		}
+	case "GeometryCollection":
+		for i := range g.Geometries {
+			if err := collectRoutes(&g.Geometries[i], routes); err != nil {
+				return err
+			}
+		}
+	case "LineString":
+		var coords [][]float64
+		if err := json.Unmarshal(g.Coordinates, &coords); err != nil {
+			return err
+		}
+		return appendRoute(coords, routes)
+	case "MultiLineString":
+		var lines [][][]float64
+		if err := json.Unmarshal(g.Coordinates, &lines); err != nil {
+			return err
+		}
+		for _, coords := range lines {
+			if err := appendRoute(coords, routes); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// appendRoute 将GeoJSON坐标数组([经度, 纬度, ...])加入路线列表，少于两个点的路线忽略
+func appendRoute(coords [][]float64, routes *[][]geoPoint) error {
+	route := make([]geoPoint, 0, len(coords))
+	for _, c := range coords {
+		if len(c) < 2 {
+			return errors.New(T("坐标至少需要经度和纬度"))
+		}
+		route = append(route, geoPoint{lng: c[0], lat: c[1]})
+	}
+	if len(route) >= 2 {
+		*routes = append(*routes, route)
+	}
+	return nil
+}
+
+// distance 两点间的大圆距离(米)
+func distance(a, b geoPoint) float64 {
+	lat1, lat2 := a.lat*math.Pi/180, b.lat*math.Pi/180
+	dLat, dLng := lat2-lat1, (b.lng-a.lng)*math.Pi/180
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(h))
+}
+
+// gpsGenerator 设备沿路线或在范围内随机行驶，每条消息上报当前位置和速度
+type gpsGenerator struct {
+	jsonGenerator
+	route   []geoPoint // 沿路线行驶时的路线，为nil时随机行驶
+	segment int        // 当前所在的路段(route[segment] -> route[segment+1])
+	offset  float64    // 在当前路段上已行驶的距离(米)
+	pos     geoPoint
+	heading float64 // 随机行驶的方向(弧度，正北为0，顺时针)
+	speed   float64 // 当前速度(km/h)
+	last    time.Time
+}
+
+// newGPSGenerator 创建第index个设备的轨迹生成器，设备按序号轮流分配到各路线，起点随机
+func newGPSGenerator(index int, faker *gofakeit.Faker) *gpsGenerator {
+	cfg := AppConfig.GPS
+	g := &gpsGenerator{
+		jsonGenerator: jsonGenerator{data: newSensorData([]string{cfg.LatKey, cfg.LngKey, cfg.SpeedKey}), faker: faker},
+		speed:         faker.Float64Range(cfg.MinSpeed, cfg.MaxSpeed),
+	}
+	if len(gpsRouteList) > 0 {
+		g.route = gpsRouteList[index%len(gpsRouteList)]
+		g.segment = faker.IntN(len(g.route) - 1)
+		g.offset = faker.Float64Range(0, distance(g.route[g.segment], g.route[g.segment+1]))
+		g.pos = g.routePosition()
+	} else {
+		g.pos = geoPoint{
+			lng: faker.Float64Range(gpsArea.min.lng, gpsArea.max.lng),
+			lat: faker.Float64Range(gpsArea.min.lat, gpsArea.max.lat),
+		}
+		g.heading = faker.Float64Range(0, 2*math.Pi)
+	}
+	return g
+}
+
+// routePosition 按当前路段和已行驶距离插值出位置
+func (g *gpsGenerator) routePosition() geoPoint {
+	a, b := g.route[g.segment], g.route[g.segment+1]
+	length := distance(a, b)
+	if length == 0 {
+		return a
+	}
+	f := g.offset / length
+	return geoPoint{lng: a.lng + (b.lng-a.lng)*f, lat: a.lat + (b.lat-a.lat)*f}
+}
+
+// move 按当前速度行驶meters米
+func (g *gpsGenerator) move(meters float64) {
+	if g.route != nil {
+		// 沿路线前进，到达终点后回到起点继续
+		g.offset += meters
+		for {
+			length := distance(g.route[g.segment], g.route[g.segment+1])
+			if g.offset <= length {
+				break
+			}
+			g.offset -= length
+			g.segment = (g.segment + 1) % (len(g.route) - 1)
+		}
+		g.pos = g.routePosition()
+		return
+	}
+
+	// 随机行驶：方向小幅随机变化，到达范围边界时反向
+	g.heading += g.faker.Float64Range(-math.Pi/6, math.Pi/6)
+	lat := g.pos.lat + meters*math.Cos(g.heading)/earthRadius*180/math.Pi
+	lng := g.pos.lng + meters*math.Sin(g.heading)/(earthRadius*math.Cos(g.pos.lat*math.Pi/180))*180/math.Pi
+	if lat < gpsArea.min.lat || lat > gpsArea.max.lat {
+		g.heading = math.Pi - g.heading
+		lat = math.Min(math.Max(lat, gpsArea.min.lat), gpsArea.max.lat)
+	}
+	if lng < gpsArea.min.lng || lng > gpsArea.max.lng {
+		g.heading = -g.heading
+		lng = math.Min(math.Max(lng, gpsArea.min.lng), gpsArea.max.lng)
+	}
+	g.pos = geoPoint{lng: lng, lat: lat}
+}
+
+func (g *gpsGenerator) Next() ([]byte, error) {
+	// 按实际经过的时间行驶，发送间隔变化(分阶段负载、降级)时轨迹仍然连续
+	now := time.Now()
+	if !g.last.IsZero() {
+		cfg := AppConfig.GPS
+		g.speed = math.Min(math.Max(g.speed+g.faker.Float64Range(-5, 5), cfg.MinSpeed), cfg.MaxSpeed)
+		g.move(g.speed / 3.6 * now.Sub(g.last).Seconds())
+	}
+	g.last = now
+
+	g.data[0].Value = round(g.pos.lat, 6)
+	g.data[1].Value = round(g.pos.lng, 6)
+	g.data[2].Value = round(g.speed, 1)
+
+	g.buf = getPayloadBuffer()
+	payload, err := g.data.AppendJSON(*g.buf)
+	if err != nil {
+		g.Done()
+		return nil, err
+	}
+	*g.buf = payload
+	return payload, nil
+}
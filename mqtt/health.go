@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// 健康检查命令行参数
+var stallTimeout = flag.Duration("stall-timeout", 0, "健康检查：连接或发送阶段超过该时长没有任何进展(连接、循环、发布)时 /healthz 返回503，默认为发送间隔的3倍且不少于1分钟，负数为不检查")
+
+// 运行阶段，由 /healthz、/readyz 和 /status 返回
+const (
+	phaseStarting   = "starting"   // 读取配置、准备设备
+	phaseConnecting = "connecting" // 连接设备
+	phaseRunning    = "running"    // 发送数据
+	phaseStopping   = "stopping"   // 断开设备
+	phaseReporting  = "reporting"  // 输出测试结果
+)
+
+// health 运行阶段和最近一次进展，供外部(k8s探针、CI)判断压测程序是否卡住
+var health struct {
+	phase        atomic.Pointer[string]
+	progress     atomic.Uint64 // 上次检查时的进展计数
+	lastProgress atomic.Int64  // 最近一次有进展的时间(UnixNano)
+}
+
+// errorCount 输出的设备级错误日志条数(含被聚合的)
+var errorCount atomic.Uint64
+
+// setPhase 进入新的运行阶段，进入阶段也算作一次进展
+func setPhase(phase string) {
+	health.phase.Store(&phase)
+	health.lastProgress.Store(time.Now().UnixNano())
+}
+
+// currentPhase 当前的运行阶段
+func currentPhase() string {
+	if p := health.phase.Load(); p != nil {
+		return *p
+	}
+	return phaseStarting
+}
+
+// lastProgressAt 最近一次有进展的时间
+//
+// 进展为连接(成功或失败)、循环、发布(成功或失败)计数的变化，每次调用时比较，
+// 由 /healthz 和 /status 在请求时检查，不需要单独的采样goroutine。
+func lastProgressAt(snap StatsSnapshot) time.Time {
+	progress := snap.Connected + snap.ConnectFailed + snap.Exited + snap.Cycles + snap.Messages + snap.PublishFailed
+	if health.progress.Swap(progress) != progress {
+		health.lastProgress.Store(time.Now().UnixNano())
+	}
+	if at := health.lastProgress.Load(); at != 0 {
+		return time.Unix(0, at)
+	}
+	return daemonState.startedAt
+}
+
+// healthStallTimeout 判定卡住的无进展时长，0为不检查
+func healthStallTimeout() time.Duration {
+	timeout := AppConfig.Daemon.StallTimeout
+	if timeout < 0 {
+		return 0
+	}
+	if timeout == 0 {
+		timeout = max(3*currentSendInterval(), time.Minute)
+	}
+	return timeout
+}
+
+// stalled 连接或发送阶段是否超过判定时长没有进展，返回无进展的时长
+func stalled(phase string, last time.Time) (time.Duration, bool) {
+	idle := time.Since(last)
+	timeout := healthStallTimeout()
+	if timeout == 0 || (phase != phaseConnecting && phase != phaseRunning) {
+		return idle, false
+	}
+	return idle, idle > timeout
+}
+
+// HealthStatus 健康检查接口返回的内容
+type HealthStatus struct {
+	Status       string    `json:"status"` // ok / stalled / not_ready
+	Phase        string    `json:"phase"`
+	LastProgress time.Time `json:"last_progress"`
+	IdleSeconds  float64   `json:"idle_s"` // 距最近一次进展的秒数
+}
+
+// handleHealthz 存活检查：连接或发送阶段长时间没有进展时返回503，其余情况返回200
+func handleHealthz(w http.ResponseWriter, r *http.Request) {
+	phase := currentPhase()
+	last := lastProgressAt(runStats.Snapshot())
+	idle, stuck := stalled(phase, last)
+	status := HealthStatus{Status: "ok", Phase: phase, LastProgress: last, IdleSeconds: idle.Seconds()}
+	code := http.StatusOK
+	if stuck {
+		status.Status, code = "stalled", http.StatusServiceUnavailable
+	}
+	writeHealth(w, code, status)
+}
+
+// handleReadyz 就绪检查：开始发送数据后返回200，连接阶段和停止后返回503
+func handleReadyz(w http.ResponseWriter, r *http.Request) {
+	phase := currentPhase()
+	last := lastProgressAt(runStats.Snapshot())
+	status := HealthStatus{Status: "ok", Phase: phase, LastProgress: last, IdleSeconds: time.Since(last).Seconds()}
+	code := http.StatusOK
+	if phase != phaseRunning {
+		status.Status, code = "not_ready", http.StatusServiceUnavailable
+	}
+	writeHealth(w, code, status)
+}
+
+// writeHealth 输出健康检查结果
+func writeHealth(w http.ResponseWriter, code int, status HealthStatus) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"math"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+)
+
+// 心跳设备命令行参数
+var (
+	heartbeatRatio    = flag.Float64("heartbeat-ratio", 0, "只发送心跳的设备比例(%)，模拟大量基本空闲的设备，0为不启用")
+	heartbeatInterval = flag.Duration("heartbeat-interval", 0, "心跳设备的发送间隔(默认30s)")
+)
+
+// heartbeatStream 心跳消息的数据流名称
+const heartbeatStream = "heartbeat"
+
+// heartbeatStats 心跳消息的发布统计，未启用心跳设备时为nil
+var heartbeatStats *StreamStats
+
+// heartbeatDevices 只发送心跳的设备数
+var heartbeatDevices atomic.Uint64
+
+// initHeartbeat 心跳设备的默认值，启用时把心跳作为单独的数据流统计
+func initHeartbeat() {
+	cfg := &AppConfig.Heartbeat
+	if cfg.Ratio <= 0 {
+		return
+	}
+	if cfg.Ratio > 100 {
+		log.Fatalf(T("心跳设备比例应在0-100之间: %.1f"), cfg.Ratio)
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 30 * time.Second
+	}
+	if cfg.Topic == "" {
+		cfg.Topic = "devices/status/{username}"
+	}
+	if cfg.Payload == "" {
+		cfg.Payload = "1"
+	}
+	heartbeatStats = &StreamStats{Name: heartbeatStream, Topic: cfg.Topic}
+	streamStats = append(streamStats, heartbeatStats)
+}
+
+// heartbeatDevice 判断第i个设备是否只发送心跳
+//
+// 与deviceQoS一样按无理数序列交错分配，但使用不同的步长，心跳设备在各QoS中按比例分布。
+func heartbeatDevice(i int) bool {
+	ratio := AppConfig.Heartbeat.Ratio
+	if ratio <= 0 {
+		return false
+	}
+	return math.Mod(float64(i)*0.4142135623730951, 1)*100 < ratio
+}
+
+// runHeartbeat 心跳设备的主循环：测试开始后按心跳间隔发送状态消息，不发送遥测数据
+//
+// 各设备的首次心跳在一个间隔内随机错开，避免所有心跳集中在同一时刻。
+func runHeartbeat(ctx context.Context, client DeviceClient, username string, qos byte, stats *DeviceStats, faker *gofakeit.Faker) {
+	cfg := AppConfig.Heartbeat
+	publisher := &streamPublisher{
+		stats:    heartbeatStats,
+		topic:    strings.ReplaceAll(cfg.Topic, "{username}", username),
+		segments: parseStreamTemplate(strings.ReplaceAll(cfg.Payload, "{username}", username)),
+	}
+
+	// 等待测试开始
+	select {
+	case <-ctx.Done():
+		return
+	case <-startChan:
+	}
+
+	timer := time.NewTimer(time.Duration(faker.Float64Range(0, float64(cfg.Interval))))
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+		timer.Reset(cfg.Interval)
+
+		publishStart := time.Now()
+		err := publishWithRetry(ctx, client, publisher.topic, qos, publisher.Next(faker))
+		publishElapsed := time.Since(publishStart)
+		stats.recordPublish(publishElapsed, err)
+		recordQoS(qos, publishElapsed, err)
+		publisher.stats.record(publishElapsed, err)
+		runStats.RecordPublish(heartbeatStream, qos, clientBroker(client), 0, err)
+		if err != nil {
+			logErrorf(T("发布消息失败: %v"), err)
+		}
+	}
+}
+
+// reportHeartbeat 输出心跳设备与遥测设备的数量和心跳消息速率(未启用时不输出)
+func reportHeartbeat(elapsed time.Duration) {
+	if heartbeatStats == nil {
+		return
+	}
+	devices := heartbeatDevices.Load()
+	sent, failed := atomic.LoadUint64(&heartbeatStats.Sent), atomic.LoadUint64(&heartbeatStats.Failures)
+	log.Println(T("\n========== 心跳设备 =========="))
+	log.Printf(T("心跳设备: %d, 遥测设备: %d, 心跳间隔: %v"),
+		devices, uint64(AppConfig.Device.ClientNumber)-devices, AppConfig.Heartbeat.Interval)
+	log.Printf(T("心跳消息: 成功=%d, 失败=%d, %.1f 条/秒, p99=%v"),
+		sent, failed, ratePerSecond(float64(sent), elapsed), heartbeatStats.Latency.Quantile(0.99))
+	log.Println("===============================")
+}
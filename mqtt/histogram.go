@@ -0,0 +1,221 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+// 直方图分桶参数：小于histSubBuckets微秒的值精确计数，
+// 更大的值按2的幂分段，每段再分histSubBuckets个桶，相对误差约1.5%
+const (
+	histSubBuckets = 64
+	histMaxExp     = 40
+	histBuckets    = histSubBuckets + histMaxExp*histSubBuckets
+)
+
+// Histogram 无锁延迟直方图，以微秒为单位记录
+type Histogram struct {
+	counts [histBuckets]uint64
+	total  uint64
+	sum    uint64 // 微秒
+	max    uint64 // 微秒
+}
+
+// histIndex 计算值所在的桶
+func histIndex(v uint64) int {
+	if v < histSubBuckets {
+		return int(v)
+	}
+	e := bits.Len64(v) - 7 // 使 v>>e 落在 [64,128)
+	idx := histSubBuckets + e*histSubBuckets + int(v>>e) - histSubBuckets
+	if idx >= histBuckets {
+		return histBuckets - 1
+	}
+	return idx
+}
+
+// histValue 返回桶的代表值(桶区间中点)
+func histValue(idx int) uint64 {
+	if idx < histSubBuckets {
+		return uint64(idx)
+	}
+	e := (idx - histSubBuckets) / histSubBuckets
+	m := uint64((idx-histSubBuckets)%histSubBuckets + histSubBuckets)
+	return m<<e + (uint64(1)<<e)/2
+}
+
+// Record 记录一个耗时
+func (h *Histogram) Record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	v := uint64(d / time.Microsecond)
+	atomic.AddUint64(&h.counts[histIndex(v)], 1)
+	atomic.AddUint64(&h.total, 1)
+	atomic.AddUint64(&h.sum, v)
+	for {
+		old := atomic.LoadUint64(&h.max)
+		if v <= old || atomic.CompareAndSwapUint64(&h.max, old, v) {
+			break
+		}
+	}
+}
+
+// Count 记录总数
+func (h *Histogram) Count() uint64 {
+	return atomic.LoadUint64(&h.total)
+}
+
+// Mean 平均耗时
+func (h *Histogram) Mean() time.Duration {
+	total := atomic.LoadUint64(&h.total)
+	if total == 0 {
+		return 0
+	}
+	return time.Duration(atomic.LoadUint64(&h.sum)/total) * time.Microsecond
+}
+
+// Max 最大耗时
+func (h *Histogram) Max() time.Duration {
+	return time.Duration(atomic.LoadUint64(&h.max)) * time.Microsecond
+}
+
+// Quantile 返回分位数(q取0~1)对应的耗时
+func (h *Histogram) Quantile(q float64) time.Duration {
+	total := atomic.LoadUint64(&h.total)
+	if total == 0 {
+		return 0
+	}
+	rank := uint64(q*float64(total) + 0.5)
+	if rank < 1 {
+		rank = 1
+	}
+
+	var seen uint64
+	for i := range h.counts {
+		seen += atomic.LoadUint64(&h.counts[i])
+		if seen >= rank {
+			v := histValue(i)
+			if max := atomic.LoadUint64(&h.max); v > max {
+				v = max
+			}
+			return time.Duration(v) * time.Microsecond
+		}
+	}
+	return h.Max()
+}
+
+// Merge 将另一个直方图的数据合并进来
+func (h *Histogram) Merge(other *Histogram) {
+	for i := range other.counts {
+		if n := atomic.LoadUint64(&other.counts[i]); n > 0 {
+			atomic.AddUint64(&h.counts[i], n)
+		}
+	}
+	atomic.AddUint64(&h.total, atomic.LoadUint64(&other.total))
+	atomic.AddUint64(&h.sum, atomic.LoadUint64(&other.sum))
+	otherMax := atomic.LoadUint64(&other.max)
+	for {
+		old := atomic.LoadUint64(&h.max)
+		if otherMax <= old || atomic.CompareAndSwapUint64(&h.max, old, otherMax) {
+			break
+		}
+	}
+}
+
+// Snapshot 返回当前数据的副本，用于之后计算区间内的分布
+func (h *Histogram) Snapshot() *Histogram {
+	s := &Histogram{}
+	for i := range h.counts {
+		s.counts[i] = atomic.LoadUint64(&h.counts[i])
+	}
+	s.total = atomic.LoadUint64(&h.total)
+	s.sum = atomic.LoadUint64(&h.sum)
+	s.max = atomic.LoadUint64(&h.max)
+	return s
+}
+
+// Sub 返回快照相对较早的快照prev新增的记录，最大值按最高非空桶估算
+//
+// 快照不是原子的，总数按各桶的差值重新累加，保证分位数计算口径一致。
+func (h *Histogram) Sub(prev *Histogram) *Histogram {
+	d := &Histogram{sum: h.sum - prev.sum}
+	for i := range h.counts {
+		d.counts[i] = h.counts[i] - prev.counts[i]
+		if d.counts[i] > 0 {
+			d.total += d.counts[i]
+			d.max = min(histValue(i), h.max)
+		}
+	}
+	return d
+}
+
+// histogramJSON 直方图的稀疏JSON表示，只保存非空桶
+//
+// 分位数不能在进程之间平均，各worker的汇总中保存直方图，merge子命令合并桶计数后重新计算。
+type histogramJSON struct {
+	Count   uint64      `json:"count"`
+	SumUs   uint64      `json:"sum_us"`
+	MaxUs   uint64      `json:"max_us"`
+	Buckets [][2]uint64 `json:"buckets"` // [桶序号, 计数]，桶的划分见histIndex
+}
+
+// MarshalJSON 按稀疏格式编码
+func (h *Histogram) MarshalJSON() ([]byte, error) {
+	out := histogramJSON{
+		Count:   atomic.LoadUint64(&h.total),
+		SumUs:   atomic.LoadUint64(&h.sum),
+		MaxUs:   atomic.LoadUint64(&h.max),
+		Buckets: [][2]uint64{},
+	}
+	for i := range h.counts {
+		if n := atomic.LoadUint64(&h.counts[i]); n > 0 {
+			out.Buckets = append(out.Buckets, [2]uint64{uint64(i), n})
+		}
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON 从稀疏格式解码
+func (h *Histogram) UnmarshalJSON(data []byte) error {
+	var in histogramJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	*h = Histogram{total: in.Count, sum: in.SumUs, max: in.MaxUs}
+	for _, b := range in.Buckets {
+		if b[0] >= histBuckets {
+			return fmt.Errorf(T("直方图桶序号超出范围: %d"), b[0])
+		}
+		h.counts[b[0]] += b[1]
+	}
+	return nil
+}
+
+// HistogramWindow 按监控间隔对累计直方图取快照，得到最近一个间隔和最近若干个间隔内的分布
+//
+// 累计分位数在长时间测试后期几乎不再变化，会掩盖后期的劣化，监控报告使用窗口内的分布。
+type HistogramWindow struct {
+	source *Histogram
+	snaps  []*Histogram // 最近的快照，最多intervals+1个，最早的在前
+	size   int
+}
+
+// NewHistogramWindow 创建覆盖最近intervals个间隔的窗口，以当前数据为起点
+func NewHistogramWindow(source *Histogram, intervals int) *HistogramWindow {
+	return &HistogramWindow{source: source, snaps: []*Histogram{source.Snapshot()}, size: max(intervals, 1)}
+}
+
+// Advance 结束当前间隔，返回本间隔和最近窗口内新增的记录
+func (w *HistogramWindow) Advance() (interval, window *Histogram) {
+	snap := w.source.Snapshot()
+	interval = snap.Sub(w.snaps[len(w.snaps)-1])
+	w.snaps = append(w.snaps, snap)
+	if len(w.snaps) > w.size+1 {
+		w.snaps = w.snaps[1:]
+	}
+	return interval, snap.Sub(w.snaps[0])
+}
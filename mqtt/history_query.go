@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math/bits"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 历史数据查询混合负载命令行参数
+var (
+	historyQueryRatio       = flag.Float64("history-query-ratio", 0, "读写混合负载：每条写入消息对应的历史/聚合查询数(读:写)，如0.01为每100条消息查询一次，查询速率随写入速率变化，0为不启用")
+	historyQueryConcurrency = flag.Int("history-query-concurrency", 0, "读写混合负载：同时进行的查询数上限，达到上限时跳过查询并计数(默认50)")
+)
+
+// 历史数据查询的默认配置，对应ThingsPanel的遥测历史分页和统计接口，时间为毫秒时间戳
+const (
+	defaultHistoryPath   = "/api/v1/telemetry/datas/history/pagination?device_id={device_id}&key={key}&start_time={start_time}&end_time={end_time}&page=1&page_size=100"
+	defaultAggregatePath = "/api/v1/telemetry/datas/statistic?device_id={device_id}&key={key}&time_range=custom&start_time={start_time}&end_time={end_time}&aggregate_window=1m&aggregate_function=avg"
+)
+
+// historyQueryKinds 查询类型，报告中按此顺序输出
+var historyQueryKinds = []string{"history", "aggregate"}
+
+// rateBand 写入速率处于同一档位的各秒内发起的查询统计
+type rateBand struct {
+	seconds  int    // 处于该档位的秒数
+	messages uint64 // 这些秒内写入的消息数
+	queries  map[string]*Histogram
+	failed   map[string]uint64
+}
+
+// historyQueryStream 选择查询设备和类型的随机数流编号，与其他用途的随机数流错开
+const historyQueryStream = 6 << 32
+
+// historyQuery 读写混合负载的状态和统计
+var historyQuery struct {
+	devices []string    // 查询的设备ID
+	keys    []string    // 各设备查询的数据点名称
+	rng     *lockedRand // 选择设备和查询类型，各查询goroutine共享
+
+	mu      sync.Mutex
+	bands   map[int]*rateBand // 写入速率档位 -> 统计，档位i为 [2^(i-1), 2^i) 条/秒
+	skipped atomic.Uint64     // 达到并发上限跳过的查询数
+	calls   sync.WaitGroup    // 进行中的查询
+	total   Histogram         // 全部查询的耗时
+	failed  atomic.Uint64     // 失败的查询数
+}
+
+// historyQueryEnabled 是否进行读写混合负载
+func historyQueryEnabled() bool {
+	return AppConfig.HistoryQuery.Ratio > 0
+}
+
+// initHistoryQuery 选择查询的设备并查询其设备ID
+func initHistoryQuery(tokens *TokenList) error {
+	if !historyQueryEnabled() {
+		return nil
+	}
+	var usernames []string
+	for i := 0; i < min(tokens.Len(), AppConfig.Device.ClientNumber, AppConfig.HistoryQuery.Devices); i++ {
+		usernames = append(usernames, tokens.At(i))
+	}
+	db, err := openReadDatabase()
+	if err != nil {
+		return fmt.Errorf(T("读写混合负载: %w"), err)
+	}
+	defer db.Close()
+	ids, err := deviceIDsByToken(db, usernames)
+	if err != nil {
+		return fmt.Errorf(T("读写混合负载: %w"), err)
+	}
+	for _, username := range usernames {
+		id, ok := ids[username]
+		if !ok {
+			continue
+		}
+		key := AppConfig.HistoryQuery.Key
+		if key == "" {
+			key = sensorKeys(username, 1)[0]
+		}
+		historyQuery.devices = append(historyQuery.devices, id)
+		historyQuery.keys = append(historyQuery.keys, key)
+	}
+	if len(historyQuery.devices) == 0 {
+		return fmt.Errorf(T("读写混合负载: %d 个设备的token在数据库中都找不到设备"), len(usernames))
+	}
+	historyQuery.bands = make(map[int]*rateBand)
+	historyQuery.rng = newLockedRand(historyQueryStream)
+	log.Printf(T("读写混合负载: 查询 %d 个设备, 读:写 = %g"), len(historyQuery.devices), AppConfig.HistoryQuery.Ratio)
+	return nil
+}
+
+// startHistoryQuery 开始发送数据时启动查询
+//
+// 每秒按上一秒写入的消息数乘以读写比例计算本秒的查询数(小数部分累积到下一秒)，在本秒内
+// 均匀发起，查询耗时按发起时的写入速率档位归类。
+func startHistoryQuery(ctx context.Context) {
+	if !historyQueryEnabled() {
+		return
+	}
+	go func() {
+		sem := make(chan struct{}, AppConfig.HistoryQuery.Concurrency)
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		last := runStats.Snapshot().Messages
+		var carry float64
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			messages := runStats.Snapshot().Messages
+			written := messages - last
+			last = messages
+			band := bits.Len64(written)
+			historyQuery.mu.Lock()
+			b := historyQuery.bands[band]
+			if b == nil {
+				b = &rateBand{queries: make(map[string]*Histogram), failed: make(map[string]uint64)}
+				historyQuery.bands[band] = b
+			}
+			b.seconds++
+			b.messages += written
+			historyQuery.mu.Unlock()
+
+			carry += float64(written) * AppConfig.HistoryQuery.Ratio
+			n := int(carry)
+			carry -= float64(n)
+			if n == 0 {
+				continue
+			}
+			spacing := time.Second / time.Duration(n)
+			go func() {
+				for i := 0; i < n; i++ {
+					if i > 0 {
+						select {
+						case <-ctx.Done():
+							return
+						case <-time.After(spacing):
+						}
+					}
+					if ctx.Err() != nil {
+						return
+					}
+					select {
+					case sem <- struct{}{}:
+					default:
+						historyQuery.skipped.Add(1)
+						continue
+					}
+					historyQuery.calls.Add(1)
+					go func() {
+						defer historyQuery.calls.Done()
+						defer func() { <-sem }()
+						runHistoryQuery(b)
+					}()
+				}
+			}()
+		}
+	}()
+}
+
+// runHistoryQuery 随机选择设备和查询类型发起一次查询，统计到写入速率档位b
+func runHistoryQuery(b *rateBand) {
+	cfg := AppConfig.HistoryQuery
+	i := historyQuery.rng.IntN(len(historyQuery.devices))
+	kind, path := "history", cfg.HistoryPath
+	if historyQuery.rng.Float64() < cfg.Aggregate {
+		kind, path = "aggregate", cfg.AggregatePath
+	}
+	end := time.Now()
+	path = strings.NewReplacer(
+		"{device_id}", url.QueryEscape(historyQuery.devices[i]),
+		"{key}", url.QueryEscape(historyQuery.keys[i]),
+		"{start_time}", strconv.FormatInt(end.Add(-cfg.Window).UnixMilli(), 10),
+		"{end_time}", strconv.FormatInt(end.UnixMilli(), 10),
+	).Replace(path)
+
+	_, _, err := apiCall(context.Background(), "GET", path, nil)
+	d := time.Since(end)
+	historyQuery.mu.Lock()
+	defer historyQuery.mu.Unlock()
+	if err != nil {
+		historyQuery.failed.Add(1)
+		b.failed[kind]++
+		logErrorf(T("读写混合负载: %s 查询失败: %v"), kind, err)
+		return
+	}
+	historyQuery.total.Record(d)
+	h := b.queries[kind]
+	if h == nil {
+		h = &Histogram{}
+		b.queries[kind] = h
+	}
+	h.Record(d)
+}
+
+// reportHistoryQuery 等待进行中的查询，按写入速率档位输出查询耗时，比较读延迟随写入速率的变化(未启用时不输出)
+func reportHistoryQuery() {
+	if !historyQueryEnabled() {
+		return
+	}
+	historyQuery.calls.Wait()
+	historyQuery.mu.Lock()
+	defer historyQuery.mu.Unlock()
+
+	log.Println(T("\n========== 读写混合负载(历史/聚合查询) =========="))
+	log.Printf(T("查询: %d 次, 失败: %d, 达到并发上限跳过: %d"),
+		historyQuery.total.Count()+historyQuery.failed.Load(), historyQuery.failed.Load(), historyQuery.skipped.Load())
+	if historyQuery.total.Count() > 0 {
+		log.Printf(T("查询耗时: 平均 %v, p50 %v, p99 %v, 最大 %v"), historyQuery.total.Mean(),
+			historyQuery.total.Quantile(0.50), historyQuery.total.Quantile(0.99), historyQuery.total.Max())
+	}
+
+	bands := make([]int, 0, len(historyQuery.bands))
+	for band := range historyQuery.bands {
+		bands = append(bands, band)
+	}
+	sort.Ints(bands)
+	baseline := map[string]time.Duration{} // 最低写入速率档位的p99，作为比较基准
+	log.Println(T("按写入速率档位(条/秒):"))
+	for _, band := range bands {
+		b := historyQuery.bands[band]
+		lo, hi := uint64(0), uint64(1)
+		if band > 0 {
+			lo, hi = 1<<(band-1), 1<<band
+		}
+		log.Printf(T("  [%d, %d): %d 秒, 平均写入 %.0f 条/秒"), lo, hi, b.seconds, float64(b.messages)/float64(b.seconds))
+		for _, kind := range historyQueryKinds {
+			h := b.queries[kind]
+			if h == nil && b.failed[kind] == 0 {
+				continue
+			}
+			if h == nil {
+				log.Printf(T("    - %s: 失败 %d"), kind, b.failed[kind])
+				continue
+			}
+			p99 := h.Quantile(0.99)
+			degrade := ""
+			if base, ok := baseline[kind]; !ok {
+				baseline[kind] = p99
+			} else if base > 0 {
+				degrade = fmt.Sprintf(T(", p99为最低档的 %.1f 倍"), float64(p99)/float64(base))
+			}
+			log.Printf(T("    - %s: %d 次, 失败 %d, p50 %v, p99 %v, 最大 %v%s"),
+				kind, h.Count(), b.failed[kind], h.Quantile(0.50), p99, h.Max(), degrade)
+		}
+	}
+	log.Println("===============================")
+}
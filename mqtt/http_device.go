@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HTTP设备命令行参数
+var (
+	httpRatio = flag.Float64("http-ratio", 0, "多协议混合：以HTTP POST上报遥测的设备比例(%)，其余设备使用MQTT，0为全部使用MQTT")
+	httpURL   = flag.String("http-url", "", "HTTP设备的上报地址，{username}替换为设备token")
+)
+
+// httpTransport HTTP设备共用的连接池，空闲连接上限足够大，各设备的请求复用长连接
+var httpTransport = &http.Transport{
+	Proxy:               http.ProxyFromEnvironment,
+	MaxIdleConnsPerHost: 100000,
+	IdleConnTimeout:     90 * time.Second,
+}
+
+// httpStatus HTTP设备按响应状态码统计的请求数
+var httpStatus struct {
+	mu     sync.Mutex
+	counts map[int]uint64 // 状态码 -> 次数，0为请求失败(连接错误、超时)
+}
+
+// httpDevicesEnabled 是否有设备使用HTTP上报
+func httpDevicesEnabled() bool {
+	return AppConfig.HTTP.Ratio > 0
+}
+
+// httpDevice 按设备token的哈希选择使用HTTP上报的设备
+func httpDevice(username string) bool {
+	return httpDevicesEnabled() && hashSelected(username, AppConfig.HTTP.Ratio)
+}
+
+// recordHTTPStatus 记录一次请求的状态码
+func recordHTTPStatus(code int) {
+	httpStatus.mu.Lock()
+	if httpStatus.counts == nil {
+		httpStatus.counts = make(map[int]uint64)
+	}
+	httpStatus.counts[code]++
+	httpStatus.mu.Unlock()
+}
+
+// httpClient 以HTTP POST上报遥测的设备，实现DeviceClient以复用MQTT设备的发送循环和统计
+//
+// HTTP没有连接状态和下行通道：Connect和Disconnect不做任何操作，Subscribe不支持。
+// 消息主题被忽略，每条消息POST到配置的地址，2xx响应视为成功。
+type httpClient struct {
+	url    string
+	client *http.Client
+}
+
+// newHTTPClient 创建HTTP设备客户端
+func newHTTPClient(username string) *httpClient {
+	return &httpClient{
+		url:    strings.ReplaceAll(AppConfig.HTTP.URL, "{username}", username),
+		client: &http.Client{Transport: httpTransport, Timeout: AppConfig.HTTP.Timeout},
+	}
+}
+
+func (c *httpClient) Connect() error {
+	return nil
+}
+
+func (c *httpClient) Publish(topic string, qos byte, payload []byte) error {
+	req, err := http.NewRequest(http.MethodPost, c.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range AppConfig.HTTP.Headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		recordHTTPStatus(0)
+		return fmt.Errorf(T("HTTP上报失败: %w"), err)
+	}
+	// 读完响应才能复用连接
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	recordHTTPStatus(resp.StatusCode)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf(T("HTTP上报返回状态码 %d"), resp.StatusCode)
+	}
+	return nil
+}
+
+func (c *httpClient) Subscribe(topic string, qos byte) (byte, error) {
+	return subackFailure, errors.New(T("HTTP设备不支持订阅"))
+}
+
+func (c *httpClient) Disconnect() {}
@@ -0,0 +1,1122 @@
+package main
+
+// messagesEN 英文消息表，键为源码中的中文消息
+var messagesEN = map[string]string{
+	"连接失败率(%)超过该值时终止测试，0为不检查":            "abort the test when the connect failure rate (%) exceeds this value, 0 disables",
+	"发布错误率(%)持续超过该值时终止测试，0为不检查":          "abort the test when the publish error rate (%) stays above this value, 0 disables",
+	"发布错误率需持续超标的时间":                      "how long the publish error rate must stay above the limit",
+	"超出失败预算，提前终止测试: %s":                  "failure budget exceeded, aborting test: %s",
+	"连接失败率 %.1f%% (%d/%d) 超过 %.1f%%":     "connect failure rate %.1f%% (%d/%d) exceeds %.1f%%",
+	"发布错误率持续 %v 超过 %.1f%% (最近1秒 %.1f%%)": "publish error rate stayed high for %v, above %.1f%% (last second %.1f%%)",
+	"acl子命令: 抽样检查的设备数(默认3)":              "acl subcommand: number of sampled devices to check (default 3)",
+	"读取设备token文件失败: %v":                  "Failed to read device token file: %v",
+	"token文件 %s 中没有设备":                   "Token file %s contains no devices",
+	"警告: 未配置consumers账号，发布检查只能确认PUBACK和连接未被断开，无法确认消息是否被Broker丢弃": "Warning: no consumers account configured, publish checks can only confirm PUBACK and that the connection was not dropped, not whether the broker discarded the message",
+	"警告: 观察者连接失败，发布检查无法确认投递: %v":                                 "Warning: observer failed to connect, publish checks cannot confirm delivery: %v",
+	"等待CONNACK超时":                       "timed out waiting for CONNACK",
+	"连接失败: %v":                          "connect failed: %v",
+	"等待SUBACK超时":                        "timed out waiting for SUBACK",
+	"SUBACK返回码 0x%02x":                  "SUBACK return code 0x%02x",
+	"观察者订阅失败: %v":                       "observer subscribe failed: %v",
+	"观察者收到消息":                           "observer received the message",
+	"Broker断开了连接":                       "broker closed the connection",
+	"观察者未收到消息":                          "observer did not receive the message",
+	"未收到PUBACK":                         "no PUBACK received",
+	"收到PUBACK(未确认投递)":                   "PUBACK received (delivery not confirmed)",
+	"\n========== ACL检查 ==========":     "\n========== ACL check ==========",
+	"通过":                                "PASS",
+	"失败":                                "FAIL",
+	" [%s] %s %s %s: 期望=%s, 实际=%s (%s)": " [%s] %s %s %s: expected=%s, actual=%s (%s)",
+	"共 %d 项检查, 通过 %d, 失败 %d":            "%d checks, %d passed, %d failed",
+	"存在不符合期望的ACL检查项，请检查Broker的ACL插件配置":                                "Some ACL checks did not match expectations, check the broker ACL plugin configuration",
+	"自适应速率：入库持续落后于发送时降低发送速率，恢复后再逐步提高，寻找可持续吞吐量":                        "adaptive rate: lower the publish rate while DB writes keep lagging behind, ramp back up once they recover, to find the sustainable throughput",
+	"自适应速率: 入库率低于 %.0f%% 已持续 %d 个监控间隔，发送间隔 %v → %v":                   "adaptive rate: write ratio below %.0f%% for %d monitor intervals, send interval %v → %v",
+	"自适应速率: 入库已跟上 %d 个监控间隔，发送间隔 %v → %v":                              "adaptive rate: DB kept up for %d monitor intervals, send interval %v → %v",
+	"\n========== 自适应速率 ==========":                                   "\n========== Adaptive rate ==========",
+	"降速 %d 次, 提速 %d 次, 最终发送间隔: %v (配置: %v)":                           "%d decreases, %d increases, final send interval: %v (configured: %v)",
+	"可持续吞吐量: %.1f 点/秒 (约 %.1f 消息/秒)":                                  "sustainable throughput: %.1f points/s (about %.1f msgs/s)",
+	"未找到可持续吞吐量: 入库率从未连续 %d 个监控间隔达到 %.0f%%，可延长测试时间或提高data_interval":    "no sustainable throughput found: for %d consecutive monitor intervals the write ratio never stayed at %.0f%%, run longer or increase data_interval",
+	"告警Webhook地址，监控指标触发告警规则时推送通知":                                     "Alert webhook URL; a notification is pushed when monitor metrics trigger an alert rule",
+	"告警消息格式(webhook: 通用JSON, dingtalk: 钉钉, feishu: 飞书, slack: Slack)": "Alert message format (webhook: generic JSON, dingtalk: DingTalk, feishu: Feishu, slack: Slack)",
+	"写入率 %.1f%% 连续 %d 个监控间隔低于 %.0f%%":                                 "Write ratio %.1f%% has been below threshold for %d monitor intervals (threshold %.0f%%)",
+	"持续发送但入库速率为0已有 %d 个监控间隔，入库链路可能已停止":                                "Publishing continues but DB write rate has been 0 for %d monitor intervals; the ingestion pipeline may have stalled",
+	"本间隔发布错误率 %.1f%% 超过 %.1f%% (失败 %d 条)":                             "Publish error rate this interval %.1f%% exceeds %.1f%% (%d failed)",
+	"已恢复":            "resolved",
+	"告警规则 %s 的条件已消失": "Condition for alert rule %s has cleared",
+	"告警":             "alert",
+	"[MQTT性能测试%s] %s\n运行ID: %s, 主机: %s, 时间: %s\n%s":                "[MQTT load test %s] %s\nRun ID: %s, host: %s, time: %s\n%s",
+	"告警消息编码失败: %v":                                                 "Failed to encode alert message: %v",
+	"告警推送失败: %v":                                                   "Failed to push alert: %v",
+	"Webhook返回状态码 %d":                                              "Webhook returned status code %d",
+	"\n========== 告警 ==========":                                   "\n========== Alerts ==========",
+	"告警格式: %s, 触发 %d 次, 推送成功 %d, 推送失败 %d":                          "Alert format: %s, fired %d times, delivered %d, failed %d",
+	"测试结束时仍在告警: %s":                                                "Still firing at end of test: %s",
+	"测试期间从标准输入读取标注，每行一条(如: 数据库重启)":                                 "Read annotations from stdin during the test, one per line (e.g. DB restarted)",
+	"控制接口监听地址(如 127.0.0.1:9091)，POST /annotations 添加标注，GET 查看全部标注": "Control API listen address (e.g. 127.0.0.1:9091); POST /annotations adds an annotation, GET lists them",
+	"标注[%s]: %s": "Annotation [%s]: %s",
+	"可在终端输入标注并按 Enter 记录到时间线(如: 数据库重启)": "Type an annotation and press Enter to add it to the timeline (e.g. DB restarted)",
+	"控制接口启动失败: %v": "Control API failed to start: %v",
+	"控制接口: http://%s/annotations, http://%s/status, http://%s/healthz, http://%s/readyz": "Control API: http://%s/annotations, http://%s/status, http://%s/healthz, http://%s/readyz",
+	"标注内容为空":                        "empty annotation",
+	"\n========== 事件时间线 ==========": "\n========== Event timeline ==========",
+	"异常注入：在指定循环使该比例(%)设备的数据点突然跳变，并查询平台告警表，测量负载下的告警检测延迟，0为不注入": "anomaly injection: at the given cycles make one data point of this percentage of devices jump suddenly and query the platform alarm table to measure alarm detection latency under load, 0 to disable",
+	"注入异常的循环，逗号分隔(默认为总循环数的一半)":                                "cycles at which anomalies are injected, comma separated (default: half of the cycle count)",
+	"无效的异常注入循环: %q": "invalid anomaly injection cycle: %q",
+	"查询告警失败: %w":    "failed to query alarms: %w",
+	"  - 异常注入: %v":  "  - Anomaly injection: %v",
+	"  - 异常注入: 已注入 %d 个设备, 已产生告警 %d 条":   "  - Anomaly injection: %d devices injected, %d alarms raised",
+	"\n========== 异常注入与告警检测 ==========":  "\n========== Anomaly Injection and Alarm Detection ==========",
+	"测试未进行到注入循环，没有注入异常":                  "the test did not reach an injection cycle, no anomalies were injected",
+	"等待 %v 让平台产生告警...":                   "waiting %v for the platform to raise alarms...",
+	"异常注入: %v":                           "anomaly injection: %v",
+	"第 %d 个循环: 注入 %d 个设备, 告警 %d 条":       "cycle %d: %d devices injected, %d alarms",
+	", 首条告警延迟 %v":                        ", first alarm after %v",
+	", 未检测到告警":                           ", no alarm detected",
+	", %d 个设备的消息中没有可注入的数值":               ", %d devices had no numeric value to inject",
+	"告警检测延迟(负载下): p50=%v, p99=%v, 最大=%v": "alarm detection latency (under load): p50=%v, p99=%v, max=%v",
+	"检测到告警的注入: %d/%d":                    "injections with an alarm detected: %d/%d",
+	"属性下发测试：每秒通过平台API下发的属性设置请求数，设备收到后通过MQTT回复，0为不启用": "Attribute-set test: attribute-set requests per second sent through the platform API; devices reply over MQTT on receipt, 0 to disable",
+	"属性下发测试：接收属性设置的设备数(token文件中前N个使用MQTT的设备，默认10)":   "Attribute-set test: number of devices receiving attribute sets (the first N MQTT devices in the token file, default 10)",
+	"属性下发测试: %w": "Attribute-set test: %w",
+	"警告: 属性下发测试: %d 个token在数据库中找不到设备，不参与测试": "Warning: attribute-set test: %d tokens not found in the database, excluded from the test",
+	"属性下发测试: %d 个设备, 每秒 %.1f 个请求":           "Attribute-set test: %d devices, %.1f requests per second",
+	"没有使用MQTT的设备":                                  "no devices use MQTT",
+	"%d 个设备的token在数据库中都找不到设备":                      "none of the %d device tokens were found in the database",
+	"查询设备ID失败: %w":                                 "Failed to query device ID: %w",
+	"订阅属性设置主题 %s 失败: %w":                           "Failed to subscribe to attribute-set topic %s: %w",
+	"服务器拒绝订阅属性设置主题 %s (SUBACK返回码 0x80)":            "Server rejected subscription to attribute-set topic %s (SUBACK return code 0x80)",
+	"属性下发测试: 设备 %s: %v":                            "Attribute-set test: device %s: %v",
+	"属性下发测试: 回复 %s 失败: %v":                         "Attribute-set test: reply to %s failed: %v",
+	"\n========== 属性下发(平台API→设备) ==========":       "\n========== Attribute Set (Platform API -> Device) ==========",
+	"设备: %d 个, 请求: %d, API失败: %d (%.2f%%)":         "Devices: %d, requests: %d, API failures: %d (%.2f%%)",
+	"  - 请求失败: %d":                                 "  - Request errors: %d",
+	"  - 状态码 %d: %d":                               "  - Status %d: %d",
+	"设备收到: %d, 未收到: %d (%.2f%%), 重复: %d, 无法关联: %d": "Received by devices: %d, lost: %d (%.2f%%), duplicates: %d, uncorrelated: %d",
+	"设备回复: 成功 %d, 失败 %d":                           "Device replies: %d succeeded, %d failed",
+	"API耗时":                                        "API latency",
+	"下发到设备收到":                                      "Request to device receipt",
+	"下发到设备回复":                                      "Request to device reply",
+	"%s: 平均 %v, p50 %v, p99 %v, 最大 %v":             "%s: mean %v, p50 %v, p99 %v, max %v",
+	"设备没有收到任何属性设置，请检查下发接口(%s)、设备订阅的主题(%s)和Broker的ACL":               "Devices received no attribute sets; check the API path (%s), the device subscription topic (%s) and the broker ACL",
+	"每个循环等待所有设备发送完成(超时见--barrier-timeout)，并报告未按时完成的设备":              "Wait for all devices to finish sending in each cycle (see --barrier-timeout) and report devices that miss it",
+	"每个循环等待设备发送完成的超时时间(默认等于发送间隔)":                                   "Timeout for devices to finish sending in each cycle (defaults to the send interval)",
+	"循环 %d: %d/%d 个设备未在 %v 内完成发送":                                   "Cycle %d: %d/%d devices did not finish sending within %v",
+	"\n========== 循环完成情况 ==========":                                "\n========== Cycle completion ==========",
+	"出现落后设备的循环: %d/%d, 超时时间: %v":                                    "Cycles with stragglers: %d/%d, timeout: %v",
+	"理论完成数: %d, 实际完成数: %d (%.2f%%)":                                 "Expected completions: %d, actual: %d (%.2f%%)",
+	"落后设备次数: %d, 其中迟到完成 %d (超出截止时间 p50=%v, p99=%v, 最大=%v), 错过循环 %d": "Stragglers: %d, of which %d finished late (past deadline p50=%v, p99=%v, max=%v), %d missed the cycle",
+	"地址 %s 不属于IP%s":                      "Address %s is not IP%s",
+	"\n========== Broker节点分布 ==========": "\n========== Broker node distribution ==========",
+	"%s: 连接 %d 次(%.1f%%), 测试结束时连接 %d, 发布成功 %d 条(%.1f%%), 失败 %d, 速率 %.1f 条/秒": "%s: %d connects (%.1f%%), %d connected at end, %d published (%.1f%%), %d failed, rate %.1f msg/s",
+	"警告: 连接分布不均，最多的节点 %d 次，最少的节点 %d 次，请检查负载均衡策略":                             "Warning: uneven connection distribution, busiest node %d connects, least busy node %d; check the load balancer policy",
+	"IP%s: 连接 %d 次, 发布成功 %d 条":                                        "IP%s: %d connects, %d published",
+	"calibrate: 测试的并发设备数，逗号分隔(默认: 1000,10000,100000和配置的设备数)":          "calibrate: concurrency levels (device counts) to test, comma separated (default: 1000,10000,100000 and the configured device count)",
+	"calibrate: 每个并发级别的运行时长":                                          "calibrate: run duration of each concurrency level",
+	"校准时长 %v 不能小于发送间隔 %v":                                             "calibration duration %v must not be shorter than the send interval %v",
+	"发送速率校准: 间隔=%v, 每级运行 %v, GOMAXPROCS=%d":                           "publish rate calibration: interval=%v, %v per level, GOMAXPROCS=%d",
+	"%d 个设备: 请求 %.0f 消息/秒, 实际 %.0f 消息/秒 (%.1f%%)":                     "%d devices: requested %.0f msg/s, achieved %.0f msg/s (%.1f%%)",
+	"无效的并发设备数: %q":                                                    "invalid concurrency level: %q",
+	"\n========== 发送速率校准 ==========":                                  "\n========== Publish Rate Calibration ==========",
+	"设备数 | 请求(消息/秒) | 实际(消息/秒) | 达成率 | 数据点/秒 | 错过循环 | 调度落后 | 开始延迟P99": "devices | requested (msg/s) | achieved (msg/s) | ratio | points/s | missed cycles | scheduler behind | start lag P99",
+	"所有级别都达到了请求速率，本机发送端上限高于 %.0f 消息/秒 (可增大设备数或缩短间隔继续测试)":              "every level reached the requested rate, the publisher ceiling of this host is above %.0f msg/s (increase the device count or shorten the interval to probe further)",
+	"本机发送端上限: 约 %.0f 消息/秒 (不含网络和Broker开销)，%d 个设备时只达到请求速率的 %.1f%%":     "publisher ceiling of this host: about %.0f msg/s (excluding network and broker overhead), %d devices reached only %.1f%% of the requested rate",
+	"警告: 配置的发送速率 %.0f 消息/秒 超过本机发送端上限，测试结果将低于配置值":                      "warning: the configured rate of %.0f msg/s exceeds the publisher ceiling of this host, results will fall short of the configured rate",
+	"校验服务器证书的CA文件(启用TLS)":                                             "CA file used to verify the server certificate (enables TLS)",
+	"设备证书目录，按 <token>.crt/<token>.key 加载客户端证书(启用mTLS)":                "Device certificate directory, client certificates are loaded from <token>.crt/<token>.key (enables mTLS)",
+	"证书目录中缺少设备证书时用测试CA即时签发(测试CA不存在时自动生成)":                             "Issue missing device certificates on the fly with the test CA (generated if it does not exist)",
+	"测试过程中按该间隔为设备重新签发证书并重连，0为不轮换":                                     "Re-issue device certificates and reconnect at this interval during the test, 0 disables rotation",
+	"读取CA文件失败: %w":    "failed to read CA file: %w",
+	"CA文件中没有有效证书: %s": "no valid certificate in CA file: %s",
+	"证书轮换需要测试CA (%s 和 %s)，可使用 --generate-certs 生成": "certificate rotation requires a test CA (%s and %s), use --generate-certs to create one",
+	"生成测试CA失败: %w": "failed to generate test CA: %w",
+	"已生成测试CA: %s (需在平台上配置为设备证书的签发CA)": "Generated test CA: %s (configure it on the platform as the device certificate issuer)",
+	"加载测试CA失败: %w":           "failed to load test CA: %w",
+	"%s 不是有效的CA证书":           "%s is not a valid CA certificate",
+	"未加载测试CA，无法签发设备证书":       "no test CA loaded, cannot issue device certificates",
+	"签发设备证书失败: %w":           "failed to issue device certificate: %w",
+	"保存设备证书失败: %w":           "failed to save device certificate: %w",
+	"加载设备证书失败: %w":           "failed to load device certificate: %w",
+	"设备 %s 证书轮换失败: %v":       "Certificate rotation failed for device %s: %v",
+	"证书轮换: 成功 %d 次, 失败 %d 次": "Certificate rotation: %d succeeded, %d failed",
+	"测试结束后按数据点核对N个抽样设备发送数值之和与telemetry_datas中SUM()聚合是否一致，0为不核对": "after the test, compare per-key sums of values sent by N sampled devices against SUM() aggregates in telemetry_datas, 0 to disable",
+	"查询历史数据失败: %w":                                   "Failed to query historical data: %w",
+	"设备 %s 数据点 %s: 入库 %d 条, 发送 %d 条":                 "device %s key %s: %d rows stored, %d sent",
+	"设备 %s 数据点 %s: 入库数值之和 %v, 发送数值之和 %v":             "device %s key %s: stored sum %v, sent sum %v",
+	"\n========== 校验和核对 ==========":                  "\n========== Checksum Verification ==========",
+	"抽样设备没有发送成功的消息，跳过核对":                             "Sampled devices have no successfully sent messages, skipping the check",
+	"校验和核对: %v":                                      "checksum verification: %v",
+	"校验和核对: 设备 %s: %v":                               "checksum verification: device %s: %v",
+	"抽样设备: %d, 核对数据点: %d, 一致 %d, 条数不同 %d, 数值之和不同 %d": "sampled devices: %d, keys checked: %d, matched %d, count differs %d, sum differs %d",
+	"... 另有 %d 条不一致未列出":                              "... %d more mismatches not listed",
+	"MQTT客户端引擎(paho: 完整实现, lite: 轻量3.1.1实现)":         "MQTT client engine (paho: full implementation, lite: lightweight 3.1.1 implementation)",
+	"SUBACK中缺少主题 %s":                                 "topic %s missing from SUBACK",
+	"连接服务器失败: %w":                                    "failed to connect to server: %w",
+	"发送CONNECT失败: %w":                                "failed to send CONNECT: %w",
+	"读取CONNACK失败: %w":                                "failed to read CONNACK: %w",
+	"无效的CONNACK报文: % x":                              "invalid CONNACK packet: % x",
+	"轻量客户端不支持QoS2":                                   "lite client does not support QoS 2",
+	"连接已断开":                                          "connection lost",
+	"发送PUBLISH失败: %w":                                "failed to send PUBLISH: %w",
+	"读取PUBACK失败: %w":                                 "failed to read PUBACK: %w",
+	"无效的PUBACK报文: % x":                               "invalid PUBACK packet: % x",
+	"轻量客户端不支持订阅":                                     "lite client does not support subscriptions",
+	"命令下发压测：同时通过平台API下发命令的设备数(token文件中前N个使用MQTT的设备)，设备收到后通过MQTT回复，0为不启用": "Command flood: number of devices receiving concurrent commands through the platform API (the first N MQTT devices in the token file); devices reply over MQTT on receipt, 0 to disable",
+	"命令下发压测：轮数，每轮给每个设备下发一条命令，上一轮的接口全部返回后开始下一轮(默认1)":                      "Command flood: number of rounds; each round sends one command to every device and starts after all API calls of the previous round return (default 1)",
+	"命令下发压测：同时进行的API请求数上限(默认等于设备数)":                                      "Command flood: maximum concurrent API requests (default: number of devices)",
+	"命令下发压测: %w": "Command flood: %w",
+	"警告: 命令下发压测: %d 个token在数据库中找不到设备，不参与测试":            "Warning: command flood: %d tokens not found in the database, excluded from the test",
+	"命令下发压测: %d 个设备, %d 轮, 并发 %d":                      "Command flood: %d devices, %d rounds, concurrency %d",
+	"订阅命令主题 %s 失败: %w":                                 "Failed to subscribe to command topic %s: %w",
+	"服务器拒绝订阅命令主题 %s (SUBACK返回码 0x80)":                  "Server rejected subscription to command topic %s (SUBACK return code 0x80)",
+	"开始命令下发压测":                                         "Command flood started",
+	"命令下发压测: 设备 %s: %v":                                "Command flood: device %s: %v",
+	"命令下发压测: 回复 %s 失败: %v":                             "Command flood: reply to %s failed: %v",
+	"\n========== 命令下发压测(平台API→设备→回复) ==========":      "\n========== Command Flood (Platform API -> Device -> Reply) ==========",
+	"设备: %d 个, 轮数: %d, 并发: %d, 请求: %d":                 "Devices: %d, rounds: %d, concurrency: %d, requests: %d",
+	"成功(接口成功且设备在 %v 内回复): %d (%.2f%%)":                 "Succeeded (API OK and device replied within %v): %d (%.2f%%)",
+	"接口超时: %d (%.2f%%), 接口失败: %d (%.2f%%)":             "API timeouts: %d (%.2f%%), API failures: %d (%.2f%%)",
+	"设备未收到: %d (%.2f%%), 收到未回复: %d, 回复超时: %d (%.2f%%)": "Not received by device: %d (%.2f%%), received without reply: %d, late replies: %d (%.2f%%)",
+	"重复收到: %d, 无法关联: %d, 回复发布失败: %d":                   "Duplicates: %d, uncorrelated: %d, reply publish failures: %d",
+	"接口在设备回复之后返回: %d (%.2f%%)":                         "API returned after the device reply: %d (%.2f%%)",
+	"设备没有收到任何命令，请检查下发接口(%s)、设备订阅的主题(%s)和Broker的ACL":    "Devices received no commands; check the API path (%s), the device subscription topic (%s) and the broker ACL",
+	"配置文件路径": "config file path",
+	"数据库服务器地址和端口，或Unix域套接字目录(如 /var/run/postgresql，可带 :端口)": "Database server address and port, or a unix socket directory (e.g. /var/run/postgresql, optionally with :port)",
+	"数据库用户名":   "database user name",
+	"数据库密码":    "database password",
+	"数据库名称":    "database name",
+	"数据库SSL模式": "database SSL mode",
+	"数据库连接池最大连接数 (默认: 10)":             "maximum connections in the database pool (default: 10)",
+	"数据库连接池最大空闲连接数 (默认: 2)":            "maximum idle connections in the database pool (default: 2)",
+	"数据库连接最长使用时间，到期后重新建立 (默认: 5m)":     "maximum lifetime of a database connection before it is replaced (default: 5m)",
+	"建立数据库连接的超时时间 (默认: 5s)":            "timeout for establishing a database connection (default: 5s)",
+	"每个数据库连接缓存的预备语句数，负数为不缓存 (默认: 512)": "prepared statements cached per database connection, negative to disable the cache (default: 512)",
+	"完整的数据库连接字符串(postgres://... 或 host=... user=... 关键字格式)，设置后忽略 --db-host、--db-user、--db-pass、--db-name 和 --db-ssl": "Full database connection string (postgres://... or host=... user=... keyword format), overrides --db-host, --db-user, --db-pass, --db-name and --db-ssl",
+	"通过pgbouncer连接数据库(事务池模式)：不使用预备语句，SQL超时改由查询超时控制":                                                                  "Connect through pgbouncer (transaction pooling): no prepared statements, SQL timeouts enforced by query timeouts instead",
+	"只读副本地址和端口，监控和核对查询在副本上执行，避免观测负载影响主库入库性能":                                                                         "read replica host and port, monitor and verification queries run on the replica so observation load does not distort ingest on the primary",
+	"单条SQL的服务端执行超时，超时后由数据库取消查询，避免表被锁时监控卡住，负数为不限制 (默认: 30s)":                                                          "server-side timeout for each SQL statement, the database cancels the query so a locked table cannot hang the monitor, negative for no limit (default: 30s)",
+	"日志输出间隔": "monitor log interval",
+	"监控报告中滚动分位数覆盖的监控间隔数，除本间隔外再输出最近N个间隔的p99(默认1，只看本间隔)": "Number of monitor intervals covered by the rolling percentiles; besides the current interval, also print p99 over the last N intervals (default 1, current interval only)",
+	"是否输出循环日志": "log every cycle",
+	"单次监控查询的超时时间，超时后重试，重试用尽时跳过本间隔 (默认: 10s)":                                                                         "timeout of a single monitor query, retried on timeout, the interval is skipped when retries run out (default: 10s)",
+	"入库计数方式(total: 全表行数, window: 只计时间戳在测试时间窗口内的行，测试期间有数据保留清理任务时使用, partition: 同window但只查询与时间窗口相关的分区或chunk，适用于大型分区表)": "how stored rows are counted (total: all rows in the table, window: only rows with ts inside the test window, use when retention jobs run during the test, partition: like window but only queries partitions or chunks overlapping the window, for large partitioned tables)",
+	"将运行配置和汇总指标写入结果库":                          "store run config and summary metrics in the results database",
+	"每条消息包含的数据点数量":                             "number of data points per message",
+	"消息生成模式(json: 每次编码, template: 预生成模板只改写数值)": "payload mode (json: encode every message, template: precomputed template with numbers rewritten in place)",
+	"工业网关消息格式(modbus: Modbus寄存器表, dlt645: DL/T 645电能表数据标识)，用于测试协议插件的解析性能，为空时生成hum1..humN":                                 "industrial gateway payload profile (modbus: Modbus register map, dlt645: DL/T 645 meter data identifiers) for benchmarking protocol plugin parsing; empty generates hum1..humN",
+	"用表达式定义数据点，分号分隔，如 'temp=20 + 5*sin(t/300) + noise(0.5);level=prev + rand(-1,1)'，可用变量t(秒)、i(设备序号)、n(消息序号)、prev(上一次的值)": "define data points with expressions, separated by semicolons, e.g. 'temp=20 + 5*sin(t/300) + noise(0.5);level=prev + rand(-1,1)'; variables t (seconds), i (device index), n (message index), prev (previous value)",
+	"数据点名称模板，{i}为序号，{device}为设备token，如 {device}_temp、sensor/{i}/hum (默认: hum{i})":                                         "Data point name template, {i} is the index and {device} the device token, e.g. {device}_temp, sensor/{i}/hum (default: hum{i})",
+	"读取配置文件 %s 失败: %v":                                             "failed to read config file %s: %v",
+	"使用默认配置和命令行参数":                                                 "using defaults and command line flags",
+	"解析配置文件失败: %v":                                                 "failed to parse config file: %v",
+	"数据点数量未指定，使用默认值: %d":                                           "data point count not set, using default: %d",
+	"使用配置的数据点数量: %d":                                               "using configured data point count: %d",
+	"轻量客户端引擎不支持QoS%d，请使用paho引擎":                                    "lite client engine does not support QoS %d, use the paho engine",
+	"轻量客户端引擎不支持订阅，请使用paho引擎":                                       "lite client engine does not support subscriptions, use the paho engine",
+	"不支持的客户端引擎: %s (可选: paho, lite)":                               "unsupported client engine: %s (choices: paho, lite)",
+	"MQTT 5仅支持lite引擎，请使用 --engine lite":                            "MQTT 5 is only supported by the lite engine, use --engine lite",
+	"不支持的MQTT协议版本: %d (可选: 4, 5)":                                  "Unsupported MQTT protocol version: %d (choose 4, 5)",
+	"消息属性(内容类型、用户属性)需要MQTT 5，请使用 --mqtt-version 5":                 "Message properties (content type, user properties) require MQTT 5, use --mqtt-version 5",
+	"不支持的接收端: %s (可选: null, file)":                                 "Unsupported sink: %s (options: null, file)",
+	"本地接收端不连接Broker，不能与本地Broker、HTTP设备、WebSocket看板或平台消费者同时使用":      "The local sink does not connect to a broker and cannot be combined with the local broker, HTTP devices, WebSocket dashboards or platform consumers",
+	"本地Broker不支持TLS，请去掉TLS相关配置":                                    "The local broker does not support TLS, remove the TLS settings",
+	"不支持的域名解析方式: %s (可选: per-connection, once)":                    "Unsupported resolution mode: %s (choose per-connection, once)",
+	"不支持的地址族: %s (可选: any, v4, v6, dual)":                          "Unsupported address family: %s (choose any, v4, v6, dual)",
+	"服务器地址 %s 无效，需要写成 host:port 的形式(IPv6地址如 [::1]:1883): %v":       "Invalid server address %s, expected host:port (IPv6 addresses like [::1]:1883): %v",
+	"无效的Broker IP: %s":                                             "Invalid broker IP: %s",
+	"会话接管场景需要指定接管循环(--takeover-cycle)":                             "the session takeover scenario needs a takeover cycle (--takeover-cycle)",
+	"凭证轮换场景需要连接平台，不能与本地Broker或本地接收端同时使用":                           "Credential rotation needs the platform and cannot be combined with the local broker or local sink",
+	"凭证轮换场景需要指定更换凭证的循环(--rotate-cycle)":                            "Credential rotation needs a rotation cycle (--rotate-cycle)",
+	"不支持的凭证更换方式: %s (可选: db, api)":                                 "Unsupported credential rotation method: %s (options: db, api)",
+	"通过API更换凭证需要指定平台API地址(--api-url)":                              "Rotating credentials via the API requires the platform API address (--api-url)",
+	"设备删除场景需要连接平台，不能与本地Broker或本地接收端同时使用":                           "Device deletion needs the platform and cannot be combined with the local broker or local sink",
+	"设备删除场景需要指定删除设备的循环(--delete-cycle)":                            "Device deletion needs a deletion cycle (--delete-cycle)",
+	"不支持的设备删除方式: %s (可选: db, api)":                                 "Unsupported device deletion method: %s (options: db, api)",
+	"通过API删除设备需要指定平台API地址(--api-url)":                              "Deleting devices via the API requires the platform API address (--api-url)",
+	"不支持的新设备来源: %s (可选: file, db)":                                 "Unsupported new device source: %s (options: file, db)",
+	"从数据库发现新设备需要连接平台，不能与本地Broker或本地接收端同时使用":                        "Discovering new devices from the database needs the platform and cannot be combined with the local broker or local sink",
+	"动态注册模式需要指定平台API地址(--api-url)":                                 "Provisioning mode requires the platform API address (--api-url)",
+	"动态注册模式需要指定一型一密密钥(provision.secret 或 --provision-secret)":      "Provisioning mode requires the template secret (provision.secret or --provision-secret)",
+	"动态注册模式不能与多租户分组同时使用":                                           "Provisioning mode cannot be combined with tenant groups",
+	"离线缓存场景需要指定离线循环(--offline-start, --offline-cycles)":            "The offline buffering scenario requires offline cycles (--offline-start, --offline-cycles)",
+	"异常注入的设备比例应在0-100之间: %.1f":                                     "anomaly injection device ratio must be between 0 and 100: %.1f",
+	"异常注入需要指定注入循环(--anomaly-cycles)":                               "anomaly injection requires injection cycles (--anomaly-cycles)",
+	"无效的异常注入循环: %d":                                                "invalid anomaly injection cycle: %d",
+	"HTTP设备比例应在0-100之间: %.1f":                                      "HTTP device ratio must be between 0 and 100: %.1f",
+	"HTTP设备需要指定上报地址(--http-url)":                                   "HTTP devices require a report URL (--http-url)",
+	"属性下发测试需要指定平台API地址(--api-url)":                                 "The attribute-set test requires the platform API address (--api-url)",
+	"属性下发测试需要设备订阅主题，不支持 mqtt.engine: lite":                         "The attribute-set test needs device subscriptions and does not support mqtt.engine: lite",
+	"属性下发测试需要连接平台，不能与本地Broker或本地接收端同时使用":                           "The attribute-set test needs the platform and cannot be combined with the local broker or local sink",
+	"命令下发压测需要指定平台API地址(--api-url)":                                 "The command flood requires the platform API address (--api-url)",
+	"命令下发压测需要设备订阅主题，不支持 mqtt.engine: lite":                         "The command flood needs device subscriptions and does not support mqtt.engine: lite",
+	"命令下发压测需要连接平台，不能与本地Broker或本地接收端同时使用":                           "The command flood needs the platform and cannot be combined with the local broker or local sink",
+	"command.key 不能与 attr_set.key 相同，否则无法区分设备收到的命令和属性设置":           "command.key must differ from attr_set.key, otherwise commands cannot be told apart from attribute sets",
+	"读写混合负载需要指定平台API地址(--api-url)":                                 "The read/write mixed workload requires the platform API address (--api-url)",
+	"读写混合负载需要连接平台，不能与本地Broker或本地接收端同时使用":                           "The read/write mixed workload needs the platform and cannot be combined with the local broker or local sink",
+	"使用GPS轨迹、表达式数据点或网关消息格式时，读写混合负载需要指定查询的数据点名称(history_query.key)": "With GPS tracks, expression fields or gateway payload profiles, the read/write mixed workload requires the key to query (history_query.key)",
+	"history_query.aggregate 必须在0到1之间: %g":                         "history_query.aggregate must be between 0 and 1: %g",
+	"WebSocket看板需要指定看板地址(--dashboard-url)":                         "WebSocket dashboards require a dashboard URL (--dashboard-url)",
+	"看板端到端延迟需要启用WebSocket看板(--dashboards)":                         "Dashboard end-to-end latency requires WebSocket dashboards (--dashboards)",
+	"设备状态机的转移概率不能为负数: %s":                                          "device state machine transition probabilities must not be negative: %s",
+	"设备状态机从 %s 转出的概率之和 %.1f%% 超过100%%":                             "device state machine transition probabilities out of %s add up to %.1f%%, more than 100%%",
+	"不支持的重复token处理方式: %s (可选: dedupe, error, allow)":               "unsupported duplicate token handling: %s (options: dedupe, error, allow)",
+	"不支持的消息生成模式: %s (可选: json, template)":                          "unsupported payload mode: %s (choices: json, template)",
+	"database.dsn 格式错误: %v":                                        "Invalid database.dsn: %v",
+	"不支持的入库计数方式: %s (可选: total, window, partition)":                "unsupported count mode: %s (options: total, window, partition)",
+	"租户限额验证需要配置租户的入库限额(tenant_limit.quota 或 --tenant-quota)":       "tenant limit verification requires the tenant ingest quota (tenant_limit.quota or --tenant-quota)",
+	"字符串数据点不支持模板生成模式(payload_mode: template)":                      "string data points do not support the template payload mode (payload_mode: template)",
+	"无效的字符串长度: %d~%d, 上限=%d":                                       "invalid string lengths: %d~%d, limit=%d",
+	"数值边界配置只适用于默认的数据生成(json生成模式，未使用表达式数据点、网关消息格式、GPS轨迹或累计量)":       "numeric edge settings only apply to the default data generation (json payload mode without expression fields, gateway profiles, GPS routes or counters)",
+	"无效的数值边界配置: 小数位数=%d (0~17), 各类比例之和=%.1f%% (不超过100%%)":          "invalid numeric edge settings: precision=%d (0~17), sum of ratios=%.1f%% (at most 100%%)",
+	"累计量模式不支持模板生成模式(payload_mode: template)":                       "counter mode does not support the template payload mode (payload_mode: template)",
+	"无效的累计量配置: 增量=%v~%v, 清零概率=%v%%, 翻转值=%v":                        "invalid counter settings: increment=%v~%v, reset chance=%v%%, rollover=%v",
+	"GPS轨迹模拟不能与表达式数据点、网关消息格式或模板生成模式同时使用":                           "GPS route simulation cannot be combined with expression fields, a gateway payload profile or the template payload mode",
+	"累计量模式不能与GPS轨迹模拟同时使用":                                          "counter mode cannot be combined with GPS route simulation",
+	"GPS轨迹模拟":                    "GPS route simulation",
+	"无效的GPS速度范围: %.1f~%.1f km/h": "invalid GPS speed range: %.1f~%.1f km/h",
+	"表达式数据点不能与网关消息格式或模板生成模式同时使用":          "expression fields cannot be combined with a gateway payload profile or the template payload mode",
+	"累计量模式不能与表达式数据点同时使用(表达式中可以用prev实现累计)": "counter mode cannot be combined with expression fields (use prev in an expression for accumulation)",
+	"表达式数据点": "expression fields",
+	"累计量模式不能与网关消息格式同时使用":                                                  "counter mode cannot be combined with a gateway payload profile",
+	"不支持的网关消息格式: %s (可选: %s)":                                             "unsupported gateway payload profile: %s (options: %s)",
+	"网关消息格式不支持模板生成模式(payload_mode: template)":                             "gateway payload profiles do not support the template payload mode (payload_mode: template)",
+	"数据点名称模板 %s 缺少 {i}，每条消息的 %d 个数据点会重名":                                  "Data point name template %s lacks {i}, the %d data points of each message would share a name",
+	"不支持的告警消息格式: %s (可选 webhook/dingtalk/feishu/slack)":                   "Unsupported alert message format: %s (choose webhook/dingtalk/feishu/slack)",
+	"发送报告邮件需要配置 report.smtp_host":                                         "Sending the report by email requires report.smtp_host",
+	"发送报告邮件需要配置 report.mail_from 或 report.smtp_user":                      "Sending the report by email requires report.mail_from or report.smtp_user",
+	"[MQTT性能测试报告]":                                                        "[MQTT benchmark report]",
+	"不支持的重连方式: %s (可选: backoff, paho, off)":                               "Unsupported reconnect mode: %s (options: backoff, paho, off)",
+	"调度优先级应在1-19之间: %d":                                                   "Nice level must be between 1 and 19: %d",
+	"无效的ACL检查项: %s %s %s (action: publish/subscribe, expect: allow/deny)": "Invalid ACL check: %s %s %s (action: publish/subscribe, expect: allow/deny)",
+	"当前配置:":                                                             "Effective configuration:",
+	"- 设备配置: 文件=%s, 数量=%d":                                              "- device: file=%s, count=%d",
+	"- MQTT配置: 服务器=%s, QoS=%d, 主题=%s, 引擎=%s":                            "- MQTT: server=%s, QoS=%d, topic=%s, engine=%s",
+	"- 本地Broker: %s (不连接外部Broker和数据库)":                                  "- Local broker: %s (no external broker or database)",
+	"- 本地接收端: %s %s (不连接Broker和数据库)":                                    "- Local sink: %s %s (no broker or database)",
+	"- QoS分布: %s":                                                       "- QoS mix: %s",
+	"- MQTT 5: 内容类型=%q, 用户属性 %d 个, 每条消息属性约 %d 字节":                       "- MQTT 5: content type=%q, %d user properties, about %d bytes of properties per message",
+	"- 域名解析: 方式=%s, 固定IP=%v, DNS服务器=%v, 地址族=%s":                         "- Resolution: mode=%s, pinned IPs=%v, DNS servers=%v, family=%s",
+	"- TLS配置: CA=%s, 设备证书目录=%s, 即时签发=%v, 轮换间隔=%v":                       "- TLS: CA=%s, device cert dir=%s, issue on the fly=%v, rotation interval=%v",
+	"- 认证预热: %d 个/秒, 并发=%d":                                             "- Auth priming: %d/s, concurrency=%d",
+	"- 会话接管: 第 %d 个循环, 设备比例=%.0f%%":                                     "- Session takeover: cycle %d, device ratio=%.0f%%",
+	"- 凭证轮换: 第 %d 个循环, 设备数=%d, 方式=%s, 探测间隔=%v, 超时=%v":                   "- Credential rotation: cycle %d, devices=%d, method=%s, probe interval=%v, timeout=%v",
+	"- 设备删除: 第 %d 个循环, 设备比例=%.1f%%, 方式=%s, 探测间隔=%v, 超时=%v, 保留删除=%v":     "- Device deletion: cycle %d, device ratio=%.1f%%, method=%s, probe interval=%v, timeout=%v, keep deleted=%v",
+	"- 设备接入: 每分钟 %d 个, 来源=%s, 查找间隔=%v, 上限=%d":                           "- Device onboarding: %d per minute, source=%s, poll interval=%v, limit=%d",
+	"- 动态注册: 接口=%s, 设备编号前缀=%s, 并发上限=%d":                                 "- Provisioning: endpoint=%s, device number prefix=%s, concurrency limit=%d",
+	"- 离线缓存: 第 %d 个循环起离线 %d 个循环, 设备比例=%.0f%%, 时间戳字段=%s":                 "- Offline buffering: offline from cycle %d for %d cycles, device ratio=%.0f%%, timestamp field=%s",
+	"第一个数值数据点":                                                          "first numeric data point",
+	"- 异常注入: 设备比例=%.1f%%, 循环=%v, 数据点=%s, 跳变=%g, 持续 %d 个循环, 告警等待=%v":     "- Anomaly injection: device ratio=%.1f%%, cycles=%v, data point=%s, jump=%g, lasting %d cycles, alarm wait=%v",
+	"- HTTP设备: 设备比例=%.1f%%, 地址=%s, 超时=%v":                               "- HTTP devices: ratio=%.1f%%, url=%s, timeout=%v",
+	"- WebSocket看板: %d 个, 地址=%s":                                        "- WebSocket dashboards: %d, url=%s",
+	"- 看板端到端延迟: 被订阅的设备每 %d 条消息附加序号数据点 %s":                               "- Dashboard end-to-end latency: watched devices add sequence data point %[2]s every %[1]d messages",
+	"- 属性下发: %.1f 请求/秒, 设备 %d 个, 接口=%s%s, 主题=%s":                        "- Attribute set: %.1f requests/s, %d devices, API=%s%s, topic=%s",
+	"- 命令下发压测: 设备 %d 个, %d 轮, 并发 %d, 接口=%s%s, 主题=%s, 超时=%v":             "- Command flood: %d devices, %d rounds, concurrency %d, API=%s%s, topic=%s, timeout=%v",
+	"- 读写混合负载: 读:写 = %g, 并发上限 %d, 设备 %d 个, 时间范围 %v, 聚合查询 %.0f%%, 接口=%s": "- Read/write mix: read:write = %g, max concurrency %d, %d devices, window %v, aggregate queries %.0f%%, API=%s",
+	"- 设备状态机: 正常->降级 %.1f%%, 正常->离线 %.1f%%, 降级->正常 %.1f%%, 降级->离线 %.1f%%, 离线->正常 %.1f%%, 离线->降级 %.1f%% (每循环), 降级时每 %d 个循环上报一次, 错误码字段=%s": "- Device state machine: normal->degraded %.1f%%, normal->offline %.1f%%, degraded->normal %.1f%%, degraded->offline %.1f%%, offline->normal %.1f%%, offline->degraded %.1f%% (per cycle), degraded devices report every %d cycles, error code field=%s",
+	"- 订阅配置: 主题=%v, QoS=%d":                        "- subscribe: topics=%v, QoS=%d",
+	"- 额外数据流: %s":                                  "- extra streams: %s",
+	"- 心跳设备: 比例=%.0f%%, 间隔=%v, 主题=%s":              "- Heartbeat devices: ratio=%.0f%%, interval=%v, topic=%s",
+	"- 平台消费者: 数量=%d, 共享组=%s, 主题=%s":                "- platform consumers: count=%d, group=%s, topic=%s",
+	"- 测试配置: 间隔=%v, 循环=%d, 等待=%v":                  "- test: interval=%v, cycles=%d, connect wait=%v",
+	"- 分阶段负载: %s":                                  "- Staged load: %s",
+	"- 循环屏障: 超时=%v":                                "- Cycle barrier: timeout=%v",
+	"- 数据配置: 最小值=%.1f, 最大值=%.1f, 数据点数=%d, 生成模式=%s": "- data: min=%.1f, max=%.1f, points=%d, payload mode=%s",
+	"- 表达式数据点: %s = %s":                            "- Expression field: %s = %s",
+	"警告: 表达式数据点 %d 次求值结果不是有限数(如除以0、log(0))，这些数据点保留了上一次的值":                           "Warning: expression fields evaluated to a non-finite number %d times (e.g. division by 0, log(0)); those fields kept their previous value",
+	"- 字符串数据点: 每条消息 %d 个, 类型=%s, 长度=%d~%d, 平台长度上限=%d":                               "- String data points: %d per message, kinds=%s, length=%d~%d, platform length limit=%d",
+	"- 数值边界: 小数位数=%d, 字符串NaN/Infinity=%.1f%%, 极端数值=%.1f%%, 整数=%.1f%%, 科学计数法=%.1f%%": "- Numeric edge cases: precision=%d, NaN/Infinity strings=%.1f%%, extreme values=%.1f%%, integers=%.1f%%, scientific notation=%.1f%%",
+	"- 累计量模式: 每条消息增量=%v~%v, 清零概率=%v%%, 翻转值=%v":                                      "- Counter mode: increment per message=%v~%v, reset chance=%v%%, rollover=%v",
+	"- GPS轨迹模拟: %s, 速度=%.0f~%.0f km/h, 数据点=%s/%s/%s":                                "- GPS route simulation: %s, speed=%.0f~%.0f km/h, data points=%s/%s/%s",
+	"- 数据点名称: 网关消息格式=%s, 平台键数量=%d":                                                  "- Data point names: gateway profile=%s, platform key count=%d",
+	"- 数据点名称: 模板=%s, 平台键数量=%d":                                                      "- Data point names: template=%s, keys on the platform=%d",
+	"- 数据库配置: 使用完整连接字符串(database.dsn)":                                              "- Database: using full connection string (database.dsn)",
+	"- 数据库配置: 主机=%s, 用户=%s, 数据库=%s":                                                 "- database: host=%s, user=%s, database=%s",
+	"- 数据库连接池: 最大连接=%d, 最大空闲=%d, 连接寿命=%v, 连接超时=%v, SQL超时=%v, 语句缓存=%d":               "- DB pool: max conns=%d, max idle=%d, lifetime=%v, connect timeout=%v, statement timeout=%v, statement cache=%d",
+	"- 数据库经pgbouncer连接: 不使用预备语句，SQL超时由查询超时控制":                                       "- Database via pgbouncer: no prepared statements, SQL timeouts enforced by query timeouts",
+	"- 只读副本: %s (监控和核对查询使用)":                                                        "- Read replica: %s (used by monitor and verification queries)",
+	"- 监控配置: 日志间隔=%v":                                                               "- monitor: log interval=%v",
+	"- 监控配置: 循环日志=%v":                                                               "- monitor: cycle log=%v",
+	"- 监控配置: 滚动分位数窗口=%d 个间隔":                                                        "- Monitor: rolling percentile window=%d intervals",
+	"- 监控配置: 入库计数=%s, 查询超时=%v, 重试=%d":                                               "- Monitor: count mode=%s, query timeout=%v, retries=%d",
+	"- 数据库写入探测: 探测表=%s":                                                             "- DB write probe: table=%s",
+	"- 租户限额验证: 租户=%s, 限额=%.0f 点/秒, 容差=%.0f%%, 其他租户写入率阈值=%.0f%%":                     "- Tenant limit verification: tenant=%s, quota=%.0f points/s, tolerance=%.0f%%, other tenants write ratio threshold=%.0f%%",
+	"- 长稳测试配置: 启用=%v, 检查点=%s, 间隔=%v":                                                "- soak: enabled=%v, checkpoint=%s, interval=%v",
+	"- 中断恢复: 启用, 检查点=%s":                                                            "- Resume: enabled, checkpoint=%s",
+	"- 多租户分组 %s: 设备=%d, token文件=%s, 主题=%s, 每 %d 个循环发送一次":                            "- Tenant group %s: clients=%d, token file=%s, topic=%s, sends every %d cycles",
+	"- 守护模式: 状态接口=http://%s/status":                                                 "- Daemon mode: status endpoint=http://%s/status",
+	"- 定时运行: 表达式=%q, 最长时长=%v":                                                       "- Scheduled runs: expression=%q, max duration=%v",
+	"- 设备统计配置: 启用=%v, 最差设备数=%d, CSV=%s":                                             "- device stats: enabled=%v, worst N=%d, CSV=%s",
+	"- 结果库配置: 启用=%v, schema=%s":                                                     "- results database: enabled=%v, schema=%s",
+	"- 自适应速率: 启用=%v, 入库率阈值=%.0f%%, 连续间隔=%d, 步长=%.0f%%":                              "- adaptive rate: enabled=%v, write ratio threshold=%.0f%%, intervals=%d, step=%.0f%%",
+	"- 失败预算: 连接失败率>%.1f%%, 发布错误率>%.1f%% 持续 %v (0为不检查)":                              "- failure budget: connect failures>%.1f%%, publish errors>%.1f%% for %v (0 disables)",
+	"- 告警: 格式=%s, 写入率<%.0f%% 连续 %d 个间隔, 入库停滞 %d 个间隔, 错误率>%.1f%%, 重复间隔=%v (0为不检查)":   "- Alerts: format=%s, write ratio<%.0f%% for %d intervals, DB stalled for %d intervals, error rate>%.1f%%, repeat every %v (0 disables)",
+	"- 报告邮件: 收件人=%s, SMTP服务器=%s":                                                    "- Report email: to=%s, SMTP server=%s",
+	"- 报告上传: 测试结束后上传到Webhook":                                                       "- Report upload: upload to webhook when the test finishes",
+	"- 发布重试: 最多次数=%d, 退避=%v~%v, 抖动=%.0f%%":                                          "- Publish retry: max attempts=%d, backoff=%v~%v, jitter=%.0f%%",
+	"- 断线重连: 方式=%s, 首次等待=%v, 上限=%v, 倍数=%.1f, 抖动=%.0f%%":                             "- Reconnect: mode=%s, initial wait=%v, max=%v, multiplier=%.1f, jitter=%.0f%%",
+	"- 资源限制: 核数=%d, 调度优先级=%d, CPU上限=%.0f%%, GC比例=%d (0为不限制)":                        "- Resource limits: cores=%d, nice=%d, CPU cap=%.0f%%, GC percent=%d (0 for no limit)",
+	"- 逐步断开: %d 个/秒, 抽样 %d 个设备, 超时=%v":                                              "- Ramp-down: %d/s, %d sampled devices, timeout=%v",
+	"- 输出配置: 目录=%s, 日志上限=%d MB x %d, 最小剩余磁盘=%d MB":                                  "- Output: dir=%s, log cap=%d MB x %d, min free disk=%d MB",
+	"- 错误日志: 相同错误只输出第一次，每 %v 汇总一次出现次数":                                              "- Error log: repeated errors are logged once, with occurrence counts summarized every %v",
+	"阶段 %s 指定了每条消息的数据点数，与%s冲突":                                                      "stage %s sets the data points per message, which conflicts with %s",
+	"\n========== 连接耗时(CONNECT→CONNACK) ==========":                                 "\n========== Connect latency (CONNECT→CONNACK) ==========",
+	"成功连接数: %d, 平均: %v, p50: %v, p90: %v, p99: %v, 最大: %v":                          "connections: %d, mean: %v, p50: %v, p90: %v, p99: %v, max: %v",
+	"认证缓存已预热(热认证)，预热阶段认证耗时 p50: %v, p99: %v":                                        "Auth cache was primed (warm auth), priming auth latency p50: %v, p99: %v",
+	"认证缓存未预热(冷认证)，使用 --prime-auth 可测量热认证下的连接性能":                                     "Auth cache not primed (cold auth), use --prime-auth to measure warm-auth connect performance",
+	"按秒时间序列(以发起连接的时间归类):":                                                           "per-second series (grouped by connect start time):",
+	"  第%3d秒: 连接数 0":                                                                "  second %3d: connections 0",
+	"  第%3d秒: 连接数 %d, p50: %v, p99: %v, 最大: %v":                                     "  second %3d: connections %d, p50: %v, p99: %v, max: %v",
+	"模拟平台消费者数量，以共享订阅接收设备上报的消息":                                                      "number of simulated platform consumers receiving device messages via shared subscription",
+	"消费者共享订阅组名($share/<组名>/<主题>)":                                                   "consumer shared subscription group ($share/<group>/<topic>)",
+	"消费者 %d 连接失败: %v":                                                               "consumer %d failed to connect: %v",
+	"消费者 %d 订阅 %s 失败: %v":                                                           "consumer %d failed to subscribe to %s: %v",
+	"消费者 %d 订阅 %s 被服务器拒绝，请检查消费者账号的ACL":                                              "consumer %d subscription to %s rejected by the broker, check the ACL of the consumer account",
+	"平台消费者: %d/%d 个已订阅 %s":                                                          "platform consumers: %d/%d subscribed to %s",
+	"\n========== 平台消费者统计 ==========":                                               "\n========== Platform consumers ==========",
+	"订阅主题: %s, QoS: %d, 消费者数: %d":                                                   "topic: %s, QoS: %d, consumers: %d",
+	"收到消息: %d / 应收 %d (%.1f%%), 总速率: %.1f 条/秒":                                      "received: %d / expected %d (%.1f%%), total rate: %.1f msgs/s",
+	" - 消费者 %d: %d 条 (%.1f%%), %.1f 条/秒":                                            " - consumer %d: %d msgs (%.1f%%), %.1f msgs/s",
+	"负载均衡: 最少 %d, 最多 %d, 变异系数 %.3f":                                                 "balance: min %d, max %d, coefficient of variation %.3f",
+	"提示: 有 %d 条消息未被消费者收到，可能是消费者处理能力不足、Broker丢弃(QoS0)或测试结束时仍在途":                      "note: %d messages were not received by consumers; consumers may be too slow, the broker may have dropped them (QoS0), or they were still in flight when the test ended",
+	"累计量模式：数据点按电能表示值的方式单调递增，偶尔清零或到达上限后翻转，用于测试平台对累计量的差值和聚合计算": "counter mode: data points increase monotonically like energy meter readings, with occasional resets and rollovers, to test delta and aggregation logic on counters",
+	"\n========== 累计量 ==========": "\n========== Counters ==========",
+	"清零: %d 次, 翻转: %d 次 (平台按差值计算用量时应忽略这些点的负差值)":                             "resets: %d, rollovers: %d (delta-based usage should ignore the negative deltas at these points)",
+	"凭证轮换：测试中途更换N个设备的凭证(voucher)，测量Broker多快接受新凭证、旧凭证何时不能再连接，结束后恢复原凭证，0为不启用": "Credential rotation: replace the credentials (voucher) of N devices mid-run, measure how quickly the broker accepts the new credentials and when the old ones stop working, then restore the originals; 0 disables",
+	"凭证轮换：在第几个循环更换凭证(默认为总循环数的一半)":                                           "Credential rotation: cycle at which to rotate credentials (default: half the total cycles)",
+	"凭证轮换: %w":                                       "Credential rotation: %w",
+	"凭证轮换: 查询设备凭证失败: %w":                             "Credential rotation: failed to query device credentials: %w",
+	"凭证轮换: 设备 %s 的凭证不是JSON: %w":                      "Credential rotation: credentials of device %s are not JSON: %w",
+	"凭证轮换: %d 个设备的token在数据库中都找不到设备":                  "Credential rotation: none of the %d device tokens were found in the database",
+	"凭证轮换: 第 %d 个循环更换 %d 个设备的凭证 (方式: %s)":            "Credential rotation: rotating credentials of %[2]d devices at cycle %[1]d (method: %[3]s)",
+	"更换 %d 个设备的凭证":                                   "Rotate credentials of %d devices",
+	"凭证轮换: %v":                                       "Credential rotation: %v",
+	"凭证轮换: 更换设备 %s 的凭证失败: %v":                        "Credential rotation: failed to rotate credentials of device %s: %v",
+	"凭证轮换: 恢复设备 %s 的凭证失败: %v，原凭证: %s":                "Credential rotation: failed to restore credentials of device %s: %v, original credentials: %s",
+	"恢复更换过凭证的设备的原凭证":                                 "Restore original credentials of rotated devices",
+	"\n========== 凭证轮换 ==========":                   "\n========== Credential Rotation ==========",
+	"更换凭证: %d 个设备, 失败 %d, 探测超时 %v, 探测网络错误 %d 次":      "Rotated: %d devices, failed %d, probe timeout %v, probe network errors %d",
+	"没有设备在测试结束前更换凭证，请确认 cred_rotation.cycle 不超过总循环数": "No device credentials were rotated before the test ended; make sure cred_rotation.cycle does not exceed the total cycles",
+	"新凭证生效: %d/%d 个设备":                               "New credentials accepted: %d/%d devices",
+	"  更换到首次连接成功: p50 %v, p99 %v, 最大 %v":             "  Rotation to first successful connect: p50 %v, p99 %v, max %v",
+	"旧凭证失效: %d/%d 个设备":                               "Old credentials rejected: %d/%d devices",
+	"  更换到首次被拒绝: p50 %v, p99 %v, 最大 %v":              "  Rotation to first rejection: p50 %v, p99 %v, max %v",
+	"  安全问题: %d 个设备的旧凭证在更换 %v 后仍可连接":                 "  Security issue: old credentials of %d devices still connect %v after rotation",
+	"警告: %d 个设备恢复原凭证失败，需手动恢复(原凭证见上方日志)":              "Warning: failed to restore original credentials of %d devices, restore them manually (originals are in the log above)",
+	"cron: 定时表达式(分 时 日 月 周，如 \"0 2 * * *\" 为每天2点，也支持@hourly、@daily、@weekly、@monthly)": "cron: schedule expression (minute hour day month weekday, e.g. \"0 2 * * *\" for 2am daily; @hourly, @daily, @weekly, @monthly are also supported)",
+	"cron: 每次运行的最长时长，到期后像Ctrl+C一样提前结束测试(0为运行到测试自行结束)":                                 "cron: maximum duration of each run; when reached the test is stopped as with Ctrl+C (0 runs until the test finishes)",
+	"需要5个字段(分 时 日 月 周)，实际为 %d 个":                                                      "5 fields required (minute hour day month weekday), got %d",
+	"分钟字段: %w":         "minute field: %w",
+	"小时字段: %w":         "hour field: %w",
+	"日期字段: %w":         "day field: %w",
+	"月份字段: %w":         "month field: %w",
+	"星期字段: %w":         "weekday field: %w",
+	"步长 %q 无效":         "invalid step %q",
+	"取值 %q 无效":         "invalid value %q",
+	"取值 %q 超出范围 %d-%d": "value %q out of range %d-%d",
+	"cron: 未配置定时表达式(cron.expr 或 --cron-expr)":  "cron: no schedule expression configured (cron.expr or --cron-expr)",
+	"cron: 定时表达式 %q 无效: %v":                    "cron: invalid schedule expression %q: %v",
+	"cron: 长稳测试和守护模式不会自行结束，需要设置 cron.duration": "cron: soak tests and daemon mode never finish on their own, cron.duration is required",
+	"cron: 无法获取程序路径: %v":                       "cron: cannot determine executable path: %v",
+	"cron: 结果库: %v":                            "cron: result store: %v",
+	"定时运行: 表达式=%q, 最长时长=%v, 参数=%s":             "Scheduled runs: expression=%q, max duration=%v, args=%s",
+	"cron: 定时表达式 %q 在5年内没有匹配的时间":               "cron: schedule expression %q matches no time within 5 years",
+	"下次运行: %s":                 "Next run: %s",
+	"收到中断信号，定时运行结束":            "Interrupt received, stopping scheduled runs",
+	"定时运行 #%d 开始":              "Scheduled run #%d started",
+	"定时运行 #%d 启动失败: %v":        "Scheduled run #%d failed to start: %v",
+	"定时运行 #%d 已达到最长时长 %v，结束测试": "Scheduled run #%d reached its maximum duration %v, stopping the test",
+	"定时运行 #%d 完成，耗时 %v":        "Scheduled run #%d finished in %v",
+	"定时运行 #%d 结束，耗时 %v，退出码 %d": "Scheduled run #%d ended after %v with exit code %d",
+	"定时运行 #%d 结束，耗时 %v: %v":    "Scheduled run #%d ended after %v: %v",
+	"测试在 %v 内没有结束，强制终止":        "Test did not exit within %v, killing it",
+	"守护模式：不限循环次数持续发送，用于演示和预发环境；SIGHUP重新加载配置，提供 /status 状态接口，支持systemd的Type=notify": "Daemon mode: publish indefinitely for demo and staging environments; reload config on SIGHUP, serve a /status endpoint, support systemd Type=notify",
+	"重新加载配置失败: %v": "Failed to reload config: %v",
+	"重新加载配置: 自适应速率已启用，发送间隔由控制器调整，不修改": "Config reload: adaptive rate is enabled, the send interval is controlled by it and left unchanged",
+	"发送间隔=%v":             "send interval=%v",
+	"重新加载配置":              "Config reloaded",
+	", 已应用: %s":           ", applied: %s",
+	", 需要重启才能生效: %s":      ", requires restart: %s",
+	", 配置没有变化":            ", no changes",
+	"在线 %d 个设备, %.1f 条/秒": "%d devices online, %.1f msg/s",
+	"多协议混合：模拟的WebSocket看板连接数，每个连接订阅一个设备的实时数据，0为不启用":                      "Mixed protocols: number of simulated WebSocket dashboard connections, each subscribing to one device live data, 0 to disable",
+	"WebSocket看板地址(ws://或wss://)，{username}和{device_id}替换为所看设备的token和ID": "WebSocket dashboard URL (ws:// or wss://), {username} and {device_id} are replaced with the watched device token and ID",
+	"WebSocket看板: %v，{device_id}无法替换":                                    "WebSocket dashboards: %v, {device_id} cannot be replaced",
+	"WebSocket看板: 设备 %s: %v":                         "WebSocket dashboards: device %s: %v",
+	"WebSocket看板: %d/%d 个已连接":                        "WebSocket dashboards: %d/%d connected",
+	"WebSocket看板 %d 连接失败: %v":                        "WebSocket dashboard %d failed to connect: %v",
+	"WebSocket看板 %d 连接断开: %v":                        "WebSocket dashboard %d disconnected: %v",
+	"  - WebSocket看板: 已连接 %d/%d, 收到推送 %d 条, 断开 %d 次": "  - WebSocket dashboards: connected %d/%d, received %d pushes, %d disconnects",
+	"只读副本: %w":                                       "read replica: %w",
+	"数据库不是只读副本":                                      "the database is not a read replica",
+	"警告: 查询主库WAL位置失败，核对结果可能受复制延迟影响: %v":              "warning: failed to query the primary WAL position, verification may be affected by replication lag: %v",
+	"警告: 只读副本1分钟内未重放到主库WAL位置 %s，核对结果可能受复制延迟影响": "warning: the read replica did not replay up to primary WAL position %s within 1 minute, verification may be affected by replication lag",
+	"无法连接数据库: %w":   "cannot connect to database: %w",
+	"数据库连接测试失败: %w": "database ping failed: %w",
+	"每个监控间隔向探测表插入一行并计时，区分入库变慢是数据库慢还是消费端慢": "insert one row into a probe table each monitor interval and time it, to tell a slow database from a slow consumer when the write rate drops",
+	"创建探测表失败: %w":                                            "failed to create probe table: %w",
+	"数据库写入变慢(探测耗时为最小值 %v 的 %.1f 倍)":                          "database writes slowed down (minimum probe latency %v, now %.1fx)",
+	"数据库写入正常，瓶颈可能在消费端":                                       "database writes look normal, the bottleneck is likely the consumer",
+	"\n========== 数据库写入探测 ==========":                        "\n========== DB Write Probe ==========",
+	"探测 %d 次, 失败 %d 次":                                       "probes: %d, failed: %d",
+	"插入耗时: 最小=%v, p50=%v, p99=%v, 最大=%v":                     "insert latency: min=%v, p50=%v, p99=%v, max=%v",
+	"从token文件中筛选部分设备，多个条件用分号分隔(range:起-止, every:N, name:正则)": "select a subset of devices from the token file, clauses separated by semicolons (range:from-to, every:N, name:regex)",
+	"无效的设备筛选条件: %s":                                          "invalid device filter: %s",
+	"无效的设备筛选条件 %s: %w":                                       "invalid device filter %s: %w",
+	"按设备名称筛选失败: %w":                                          "failed to filter by device name: %w",
+	"不支持的设备筛选条件: %s (可选: range, every, name)":                "unsupported device filter: %s (options: range, every, name)",
+	"设备筛选 %s: %d → %d":                                       "device filter %s: %d → %d",
+	"筛选后没有剩余设备: %s":                                          "no devices left after filter: %s",
+	"应为 起-止 格式":                                              "expected from-to",
+	"起始序号无效: %s":                                             "invalid start index: %s",
+	"结束序号无效: %s":                                             "invalid end index: %s",
+	"设备状态机：每个设备按转移概率在正常、降级(降低上报频率、上报错误码)和离线(断开连接)之间切换，模拟长稳测试中的局部故障": "device state machine: each device switches between normal, degraded (lower reporting rate, error codes) and offline (disconnected) by transition probabilities, simulating partial outages in soak tests",
+	"设备 %s 从离线状态恢复时重连失败: %v":                         "device %s failed to reconnect when leaving the offline state: %v",
+	"  - 设备状态: 正常 %d, 降级 %d, 离线 %d":                  "  - Device states: normal %d, degraded %d, offline %d",
+	"\n========== 设备状态机 ==========":                  "\n========== Device State Machine ==========",
+	"%s: %d 个设备循环 (%.1f%%), 测试结束时 %d 个设备":            "%s: %d device cycles (%.1f%%), %d devices at the end of the test",
+	"状态转移 %s -> %s: %d 次":                            "transition %s -> %s: %d times",
+	"未上报的循环: %d, 离线恢复重连失败: %d 次":                     "cycles not reported: %d, reconnect failures when leaving offline: %d",
+	"记录每个设备的统计数据":                                    "record per-device statistics",
+	"测试结束时输出最差的N个设备":                                 "print the worst N devices at the end of the test",
+	"每设备统计CSV输出文件路径":                                 "per-device statistics CSV output path",
+	"\n========== 最差的 %d 个设备 ==========":             "\n========== Worst %d devices ==========",
+	"设备 %s: 连接失败=%v, 发送=%d, 失败=%d, 重连=%d, 平均确认耗时=%v": "device %s: connect failed=%v, sent=%d, failures=%d, reconnects=%d, mean ack latency=%v",
+	"写入设备统计CSV失败: %v":                                "failed to write device statistics CSV: %v",
+	"设备统计已保存到: %s":                                   "device statistics saved to: %s",
+	"创建文件失败: %w":                                     "failed to create file: %w",
+	"设备连接成功后上报一次设备信息(固件版本、型号、IMEI等)":                 "Report device info once after connecting (firmware version, model, IMEI and so on)",
+	"序列化设备信息失败: %v":                                  "Failed to serialize device info: %v",
+	"设备 %s 上报设备信息失败: %v":                             "Device %s failed to report device info: %v",
+	"相同错误的汇总输出间隔：每种错误只完整输出第一次，之后按间隔输出出现次数(默认10s)，负数为不聚合": "summary interval for repeated errors: each error is logged in full only the first time, then its occurrence count is logged at this interval (default 10s), negative to disable aggregation",
+	"错误又出现 %d 次 (共 %d 次): %s":          "error repeated %d more times (%d total): %s",
+	"\n========== 错误汇总 ==========":     "\n========== Error Summary ==========",
+	"错误种类: %d, 聚合后未单独输出: %d 条":         "error kinds: %d, lines suppressed by aggregation: %d",
+	"%d 次 (首次 %s): %s":                 "%d times (first at %s): %s",
+	"位置 %d 处有多余的内容 %q":                 "unexpected trailing input at position %d: %q",
+	"表达式 %q: %w":                       "expression %q: %w",
+	"位置 %d 处缺少 )":                      "missing ) at position %d",
+	"无效的数字 %q":                         "invalid number %q",
+	"未知的变量 %s (可用: t, i, n, prev, pi)": "unknown variable %s (available: t, i, n, prev, pi)",
+	"表达式不完整":                           "incomplete expression",
+	"位置 %d 处有无法识别的字符 %q":               "unrecognized character at position %d: %q",
+	"未知的函数 %s":                         "unknown function %s",
+	"位置 %d 处缺少 , 或 )":                  "missing , or ) at position %d",
+	"函数 %s 需要 %d 个参数，实际为 %d 个":         "function %s takes %d arguments, got %d",
+	"无效的数据点定义: %s (格式: 名称=表达式)":        "invalid field definition: %s (format: name=expression)",
+	"第 %d 个数据点缺少名称":                    "field %d has no name",
+	"数据点 %s 重复定义":                      "field %s defined more than once",
+	"数据点 %s: %w":                       "data point %s: %w",
+	"设备接入场景：测试中途持续接入新创建的设备(每分钟接入数)，现有设备的负载保持不变，0为不启用":    "Device onboarding: keep adding newly created devices to the running test (devices per minute) while the existing load continues; 0 disables",
+	"设备接入场景：新设备的来源，file(监视token文件中新追加的行)或db(定期查询新创建的设备)": "Device onboarding: source of new devices, file (watch lines appended to the token file) or db (poll for newly created devices)",
+	"设备接入: %w": "Device onboarding: %w",
+	"设备接入: 从 %s 发现新设备，每分钟接入 %d 个": "Device onboarding: discovering new devices from %s, adding %d per minute",
+	"数据库": "database",
+	"开始接入新设备: 每分钟 %d 个":            "Start onboarding new devices: %d per minute",
+	"设备接入: %v":                     "Device onboarding: %v",
+	"打开文件失败: %w":                   "failed to open file: %w",
+	"查询新创建的设备失败: %w":               "failed to query newly created devices: %w",
+	"\n========== 设备接入 ==========": "\n========== Device Onboarding ==========",
+	"测试未开始发送，没有接入新设备":              "The test did not start sending, no new devices were added",
+	"发现新设备: %d 个, 接入 %d 个 (%.1f 个/分钟, 目标 %d), 连接失败 %d, 未接入 %d": "New devices discovered: %d, added %d (%.1f per minute, target %d), connect failed %d, not added %d",
+	"  新设备连接耗时: p50 %v, p99 %v, 最大 %v":                         "  New device connect time: p50 %v, p99 %v, max %v",
+	"  发现到首次发布成功: %d 个设备, p50 %v, p99 %v, 最大 %v":               "  Discovery to first successful publish: %d devices, p50 %v, p99 %v, max %v",
+	"  已达到接入上限 %d 个，之后发现的设备未接入":                                "  Reached the limit of %d added devices, devices discovered afterwards were not added",
+	"设备删除场景：测试中途删除该比例(%)的设备，验证其后续发布被拒绝、数据不再入库，测量删除在认证缓存和入库链路中的生效耗时，0为不启用": "Device deletion: delete this percentage of devices mid-run, verify their later publishes are rejected and no longer stored, and measure how long the deletion takes to propagate through auth caches and ingestion; 0 disables",
+	"设备删除场景：在第几个循环删除设备(默认为总循环数的一半)":                                       "Device deletion: cycle at which to delete devices (default: half the total cycles)",
+	"设备删除: 按比例 %.1f%% 没有选中任何设备":                                           "Device deletion: ratio %.1f%% selected no devices",
+	"设备删除: %w": "Device deletion: %w",
+	"设备删除: %d 个设备的token在数据库中都找不到设备":                "Device deletion: none of the %d device tokens were found in the database",
+	"警告: 设备删除: %d 个token在数据库中找不到设备，不参与删除":          "Warning: device deletion: %d tokens not found in the database, they will not be deleted",
+	"设备删除: 第 %d 个循环删除 %d 个设备 (方式: %s)":             "Device deletion: deleting %[2]d devices at cycle %[1]d (method: %[3]s)",
+	"设备删除: 查询设备失败: %w":                             "Device deletion: failed to query devices: %w",
+	"删除 %d 个设备":                                    "Delete %d devices",
+	"设备删除: %v":                                     "Device deletion: %v",
+	"设备删除: 删除设备 %s 失败: %v":                         "Device deletion: failed to delete device %s: %v",
+	"删除设备失败: %w":                                   "failed to delete devices: %w",
+	"设备已不存在":                                       "device no longer exists",
+	"\n========== 设备删除 ==========":                 "\n========== Device Deletion ==========",
+	"删除设备: %d 个, 失败 %d, 探测超时 %v, 探测网络错误 %d 次":      "Deleted: %d devices, failed %d, probe timeout %v, probe network errors %d",
+	"没有设备在测试结束前被删除，请确认 fleet_shrink.cycle 不超过总循环数": "No devices were deleted before the test ended; make sure fleet_shrink.cycle does not exceed the total cycles",
+	"凭证被拒绝: %d/%d 个设备":                             "Credentials rejected: %d/%d devices",
+	"  删除到首次被拒绝: p50 %v, p99 %v, 最大 %v":            "  Deletion to first rejection: p50 %v, p99 %v, max %v",
+	"  安全问题: %d 个已删除设备的凭证在删除 %v 后仍可连接":             "  Security issue: credentials of %d deleted devices still connect %v after deletion",
+	"已建立的连接: %d/%d 个设备删除后发布失败, 删除后仍发布成功 %d 条消息":    "Established connections: %d/%d devices failed to publish after deletion, %d messages still accepted after deletion",
+	"  删除到首次发布失败: p50 %v, p99 %v, 最大 %v":           "  Deletion to first publish failure: p50 %v, p99 %v, max %v",
+	"  删除后最后一次发布成功: 删除后 %v":                        "  Last accepted publish: %v after deletion",
+	"删除后入库: %d 个数据点":                               "Stored after deletion: %d data points",
+	"  最后一个入库数据点: 删除后 %v":                          "  Last stored data point: %v after deletion",
+	"查询删除后入库数失败: %w":                               "failed to query rows stored after deletion: %w",
+	"通过API删除的设备不会恢复，需用create_device重新创建":           "Devices deleted via the API are not restored; recreate them with create_device",
+	"fleet_shrink.keep 已启用，被删除的设备不会恢复(删除前的设备行见运行目录的deleted_devices.json)": "fleet_shrink.keep is set, deleted devices are not restored (the deleted rows are in deleted_devices.json in the run directory)",
+	"设备删除: 恢复设备 %s 失败: %v": "Device deletion: failed to restore device %s: %v",
+	"恢复被删除的设备: %d 个, 失败 %d (删除前的设备行见运行目录的deleted_devices.json)":                "Restored deleted devices: %d, failed %d (the deleted rows are in deleted_devices.json in the run directory)",
+	"看板端到端延迟：被看板订阅的设备每N条消息附加一个序号数据点，测量从设备发布到看板收到推送的耗时，0为不启用":                   "Dashboard end-to-end latency: devices watched by a dashboard add a sequence data point every N messages to measure the time from device publish to dashboard push, 0 to disable",
+	"\n========== 看板端到端延迟(设备发布→看板收到) ==========":                               "\n========== Dashboard End-to-End Latency (Device Publish -> Dashboard Push) ==========",
+	"带序号的消息: %d 条 (每 %d 条消息一次), 应收到推送: %d, 收到: %d, 未收到: %d (%.2f%%), 无法关联: %d": "Sequenced messages: %d (every %d messages), expected pushes: %d, received: %d, missing: %d (%.2f%%), uncorrelated: %d",
+	"延迟: 平均 %v, p50 %v, p95 %v, p99 %v, 最大 %v":                                 "Latency: mean %v, p50 %v, p95 %v, p99 %v, max %v",
+	"看板没有收到带序号 %s 的推送，请确认平台推送的是设备上报的原始数据点":                                     "Dashboards received no pushes carrying sequence %s; make sure the platform pushes the raw data points reported by the device",
+	"GPS轨迹模拟: 包含LineString/MultiLineString的GeoJSON文件，设备沿路线循环行驶并上报经纬度和速度":       "GPS route simulation: GeoJSON file with LineString/MultiLineString features; devices drive the routes in a loop and report latitude, longitude and speed",
+	"GPS轨迹模拟: 未指定路线时在该范围内随机行驶，格式为 最小经度,最小纬度,最大经度,最大纬度":                         "GPS route simulation: without routes, devices wander randomly within this box, format min_lng,min_lat,max_lng,max_lat",
+	"GPS轨迹模拟: 从 %s 加载 %d 条路线":                                                  "GPS route simulation: loaded from %s: %d routes",
+	"无效的GPS范围 %q: %w": "invalid GPS bounding box %q: %w",
+	"无效的GPS范围 %q (格式: 最小经度,最小纬度,最大经度,最大纬度)": "invalid GPS bounding box %q (format: min_lng,min_lat,max_lng,max_lat)",
+	"读取GPS路线文件失败: %w":                  "failed to read GPS route file: %w",
+	"解析GPS路线文件失败: %w":                  "failed to parse GPS route file: %w",
+	"GPS路线文件 %s 中没有至少包含两个点的LineString": "GPS route file %s has no LineString with at least two points",
+	"坐标至少需要经度和纬度":                      "coordinates need at least longitude and latitude",
+	"健康检查：连接或发送阶段超过该时长没有任何进展(连接、循环、发布)时 /healthz 返回503，默认为发送间隔的3倍且不少于1分钟，负数为不检查": "Health check: /healthz returns 503 when the connecting or sending phase makes no progress (connects, cycles, publishes) for this long; defaults to 3x the send interval and at least 1 minute, negative disables the check",
+	"只发送心跳的设备比例(%)，模拟大量基本空闲的设备，0为不启用":                                            "Percentage of devices that only send heartbeats, modeling mostly idle devices, 0 to disable",
+	"心跳设备的发送间隔(默认30s)":                     "Send interval of heartbeat devices (default 30s)",
+	"心跳设备比例应在0-100之间: %.1f":                "Heartbeat device ratio must be between 0 and 100: %.1f",
+	"发布消息失败: %v":                           "failed to publish message: %v",
+	"\n========== 心跳设备 ==========":         "\n========== Heartbeat devices ==========",
+	"心跳设备: %d, 遥测设备: %d, 心跳间隔: %v":         "Heartbeat devices: %d, telemetry devices: %d, heartbeat interval: %v",
+	"心跳消息: 成功=%d, 失败=%d, %.1f 条/秒, p99=%v": "Heartbeat messages: ok=%d, failed=%d, %.1f msg/s, p99=%v",
+	"直方图桶序号超出范围: %d":                       "Histogram bucket index out of range: %d",
+	"读写混合负载：每条写入消息对应的历史/聚合查询数(读:写)，如0.01为每100条消息查询一次，查询速率随写入速率变化，0为不启用": "Read/write mixed workload: history/aggregate queries per written message (read:write), e.g. 0.01 queries once every 100 messages; the query rate follows the write rate, 0 to disable",
+	"读写混合负载：同时进行的查询数上限，达到上限时跳过查询并计数(默认50)":                              "Read/write mixed workload: maximum concurrent queries; queries beyond the limit are skipped and counted (default 50)",
+	"读写混合负载: %w": "Read/write mixed workload: %w",
+	"读写混合负载: %d 个设备的token在数据库中都找不到设备":         "Read/write mixed workload: none of the %d device tokens were found in the database",
+	"读写混合负载: 查询 %d 个设备, 读:写 = %g":             "Read/write mixed workload: querying %d devices, read:write = %g",
+	"读写混合负载: %s 查询失败: %v":                     "Read/write mixed workload: %s query failed: %v",
+	"\n========== 读写混合负载(历史/聚合查询) ==========": "\n========== Read/Write Mix (History/Aggregate Queries) ==========",
+	"查询: %d 次, 失败: %d, 达到并发上限跳过: %d":          "Queries: %d, failed: %d, skipped at concurrency limit: %d",
+	"查询耗时: 平均 %v, p50 %v, p99 %v, 最大 %v":      "Query latency: mean %v, p50 %v, p99 %v, max %v",
+	"按写入速率档位(条/秒):":                           "By write rate band (msgs/s):",
+	"  [%d, %d): %d 秒, 平均写入 %.0f 条/秒":         "  [%d, %d): %d s, average write rate %.0f msgs/s",
+	"    - %s: 失败 %d":                                "    - %s: %d failed",
+	", p99为最低档的 %.1f 倍":                              ", p99 %.1fx the lowest band",
+	"    - %s: %d 次, 失败 %d, p50 %v, p99 %v, 最大 %v%s": "    - %s: %d queries, %d failed, p50 %v, p99 %v, max %v%s",
+	"多协议混合：以HTTP POST上报遥测的设备比例(%)，其余设备使用MQTT，0为全部使用MQTT": "Mixed protocols: ratio (%) of devices reporting telemetry via HTTP POST, the rest use MQTT, 0 for all MQTT",
+	"HTTP设备的上报地址，{username}替换为设备token":                   "Report URL for HTTP devices, {username} is replaced with the device token",
+	"HTTP上报失败: %w":                  "HTTP report failed: %w",
+	"HTTP上报返回状态码 %d":                "HTTP report returned status code %d",
+	"HTTP设备不支持订阅":                   "HTTP devices do not support subscriptions",
+	"输出语言(zh/en)，也可通过环境变量TP_LANG设置": "output language (zh/en), can also be set with the TP_LANG environment variable",
+	"在进程内启动MQTT Broker并连接它，不需要外部的Broker和数据库即可验证和测量本工具(调度、数据生成、统计和报告)": "Start an in-process MQTT broker and connect to it, to validate and benchmark this tool (scheduling, payload generation, stats and reports) without an external broker or database",
+	"本地Broker的监听地址(默认127.0.0.1:0，随机端口)":                               "Listen address of the local broker (default 127.0.0.1:0, random port)",
+	"启动本地Broker失败: %w":                 "Failed to start local broker: %w",
+	"无效的CONNECT报文":                     "Invalid CONNECT packet",
+	"无效的PUBLISH报文":                     "Invalid PUBLISH packet",
+	"无效的SUBSCRIBE报文":                   "Invalid SUBSCRIBE packet",
+	"无效的UNSUBSCRIBE报文":                 "Invalid UNSUBSCRIBE packet",
+	"\n========== 本地Broker ==========": "\n========== Local Broker ==========",
+	"地址: %s, 连接 %d 次, 最大同时连接 %d, 会话接管 %d 次":            "Address: %s, connects %d, peak connections %d, session takeovers %d",
+	"收到消息: QoS0 %d 条, QoS1 %d 条, QoS2 %d 条, 共 %.1f KB": "Received: QoS0 %d, QoS1 %d, QoS2 %d, %.1f KB total",
+	"遥测消息: Broker收到 %d 条 (%.1f 条/秒), 工具统计发送成功 %d 条":    "Telemetry: broker received %d (%.1f/s), tool counted %d sent",
+	"订阅主题 %d 个, 投递给订阅者 %d 条":                           "Subscriptions %d, delivered to subscribers %d",
+	"警告: Broker收到的遥测消息数与发送成功数相差 %d 条":                  "Warning: telemetry received by the broker differs from messages sent by %d",
+	"run.log单个文件大小上限(MB)，超过后轮转为run.log.1等(默认100)":      "Size cap of run.log in MB; rotated to run.log.1 and so on when exceeded (default 100)",
+	"运行目录所在磁盘剩余空间(MB)低于该值时停止测试并输出报告(默认500)，-1为不检查":     "Stop the test and write the report when free space (MB) on the run directory disk falls below this (default 500); -1 disables the check",
+	"日志轮转失败: %v": "Log rotation failed: %v",
+	"警告: 无法获取磁盘剩余空间，不检查磁盘空间: %v":                                    "Warning: cannot read free disk space, disk space check disabled: %v",
+	"运行目录 %s 所在磁盘剩余空间 %d MB 低于 %d MB，请清理磁盘或调整 output.min_free_disk": "Free space on the disk of run directory %s is %d MB, below %d MB; free up disk space or adjust output.min_free_disk",
+	"设备token文件路径":      "device token file path",
+	"模拟连接的设备数量":        "number of simulated devices",
+	"MQTT服务器地址":        "MQTT server address",
+	"MQTT服务质量(0,1,2)":  "MQTT QoS (0,1,2)",
+	"发布主题":             "publish topic",
+	"数据上报间隔时间":         "data report interval",
+	"测试循环次数":           "number of test cycles",
+	"连接等待时间":           "time to wait for connections",
+	"传感器数据最小值":         "minimum sensor value",
+	"传感器数据最大值":         "maximum sensor value",
+	"运行ID: %s":         "run ID: %s",
+	"警告: %v，产物将写入当前目录": "warning: %v, artifacts will be written to the current directory",
+	"运行目录: %s":         "run directory: %s",
+	"性能测试开始":           "Performance test started",
+	"配置信息: 设备数=%d, 间隔时间=%v, 循环次数=%d":                      "configuration: devices=%d, interval=%v, cycles=%d",
+	"token文件 %s 不存在，生成 %d 个设备token":                       "Token file %s does not exist, generating %d device tokens",
+	"监控模块初始化完成，开始进行测试...":                                 "monitor initialized, starting test...",
+	"警告: 监控模块初始化超时，继续进行测试...":                             "warning: monitor initialization timed out, continuing test...",
+	"可用设备数量: %d":                                          "available devices: %d",
+	"警告: 可用设备数量(%d)少于请求数量(%d)":                            "warning: available devices (%d) fewer than requested (%d)",
+	"启动 %d 个WebSocket看板":                                  "Starting %d WebSocket dashboards",
+	"开始连接 %d 个设备":                                         "Connecting %d devices",
+	"成功连接设备数: %d (%.1f%%)":                                "connected devices: %d (%.1f%%)",
+	"存在被拒绝的订阅，测试终止":                                       "some subscriptions were rejected, test aborted",
+	"没有设备连接成功，测试终止":                                       "no device connected, test aborted",
+	"长稳测试模式: 持续运行直到收到中断信号，检查点间隔: %v":                      "soak mode: running until interrupted, checkpoint interval: %v",
+	"中断恢复: 检查点保存到 %s，间隔: %v":                              "resume: saving checkpoints to %s, interval: %v",
+	"开始发送数据":                                              "Sending started",
+	"第 %d 个循环: 会话接管":                                      "Cycle %d: session takeover",
+	"第 %d 个循环: 部分设备离线并开始缓存数据":                             "Cycle %d: some devices go offline and start buffering data",
+	"第 %d 个循环: 离线设备重连并补发缓存":                               "Cycle %d: offline devices reconnect and flush their backlog",
+	"循环 %d/%d: 已发送数据点数: %d (%.1f点/秒), 消息数: %d (%.1f消息/秒)": "cycle %d/%d: points sent: %d (%.1f points/s), messages: %d (%.1f msgs/s)",
+	"收到中断信号，提前结束测试":                                       "interrupt received, ending test early",
+	"停止发送数据":                                              "Sending stopped",
+	"等待所有设备退出...":                                         "waiting for all devices to exit...",
+	"\n========== 测试完成 ==========":                        "\n========== Test finished ==========",
+	"测试总耗时: %v":                                           "total duration: %v",
+	"测试循环次数: %d":                                          "cycles: %d",
+	"已退出设备数: %d (%.1f%%)":                                 "devices exited: %d (%.1f%%)",
+	"总发送数据点数: %d":                                         "total points sent: %d",
+	"总发送消息数: %d":                                          "total messages sent: %d",
+	"发布失败消息数: %d":                                         "failed publishes: %d",
+	"提前终止: %s":                                            "aborted: %s",
+	"收到下行消息数: %d":                                         "downlink messages received: %d",
+	"长稳测试: %v":                                            "soak: %v",
+	"长稳测试: 检查点已保存到 %s":                                    "soak: checkpoint saved to %s",
+	"长稳测试累计(含 %d 次恢复): 时长=%v, 循环=%d, 数据点=%d, 消息=%d":       "soak totals (%d resumes): duration=%v, cycles=%d, points=%d, messages=%d",
+	"保存运行结果失败: %v":                                        "failed to save run results: %v",
+	"运行结果已写入结果库: %s.runs (运行ID: %s)":                      "run results saved to %s.runs (run ID: %s)",
+	"程序正在退出...":                                           "exiting...",
+	"\n测试已完成。监控线程仍在运行，可以继续观察数据入库情况。":                      "\nTest finished. The monitor is still running so you can keep watching database ingestion.",
+	"按 Enter 键退出程序...":                                    "Press Enter to exit...",
+	"设备 %s %v":                                            "device %s: %v",
+	"设备 %s 连接MQTT服务器失败: %v":                               "device %s failed to connect to MQTT server: %v",
+	"序列化数据失败: %v":                                         "failed to serialize data: %v",
+	"merge: 合并后的汇总写入该文件，为空时只输出到标准输出":                      "merge: write the merged summary to this file; when empty it is only printed to stdout",
+	"用法: mqtt merge [--merge-output merged.json] worker1/summary.json worker2/summary.json ...": "Usage: mqtt merge [--merge-output merged.json] worker1/summary.json worker2/summary.json ...",
+	"读取 %s 失败: %v": "Failed to read %s: %v",
+	"警告: %s 中没有直方图(旧版本生成)，分位数按各worker的最大值估算，偏高": "Warning: %s has no histograms (written by an older version); percentiles are estimated from the per-worker maximum and will be high",
+	"序列化JSON汇总失败: %v":              "failed to serialize JSON summary: %v",
+	"写入 %s 失败: %v":                 "failed to write %s: %v",
+	"合并后的汇总: %s":                   "Merged summary: %s",
+	"\n========== 合并报告 ==========": "\n========== Merged Report ==========",
+	"worker数: %d, 运行ID: %s":        "Workers: %d, run IDs: %s",
+	"测试时长: %.0f 秒, 设备数: %d / %d":   "Duration: %.0f s, devices: %d / %d",
+	"发送消息数: %d (%.1f 条/秒), 发送数据点数: %d (%.1f 点/秒), 发布失败 %d": "Messages sent: %d (%.1f msg/s), points sent: %d (%.1f points/s), publish failures %d",
+	"入库数据点数: %d, 入库率: %.2f%%":                              "Points stored: %d, write rate: %.2f%%",
+	"发布耗时: p50 %.2fms, p99 %.2fms, 连接耗时p99 %.2fms":         "Publish latency: p50 %.2fms, p99 %.2fms, connect p99 %.2fms",
+	"  %s: %d 设备, %.1f 点/秒, 发布耗时p99 %.2fms":                "  %s: %d devices, %.1f points/s, publish p99 %.2fms",
+	"  汇总中没有直方图，合并后的分位数为各worker的最大值(偏高)":                   "  No histograms in the summaries; merged percentiles are the per-worker maximum (high)",
+	"监控模块: %s失败(第 %d 次): %v，%v 后重试":                        "monitor: %s failed (attempt %d): %v, retrying in %v",
+	"监控模块: %v": "monitor: %v",
+	"监控模块: 成功连接到数据库，开始监控数据写入情况，监控间隔: %v": "monitor: connected to database, watching ingestion every %v",
+	"获取初始数据点数":                                   "query initial data point count",
+	"监控模块: 获取初始数据点数失败: %v":                       "monitor: failed to get initial row count: %v",
+	"监控模块: 数据库中当前数据点数: %d":                       "monitor: current rows in database: %d",
+	"监控模块: %v，不进行数据库写入探测":                        "monitor: %v, DB write probe disabled",
+	"\n========== 初始监控状态 ==========":             "\n========== Initial monitor state ==========",
+	"数据库初始数据点数: %d":                              "initial rows in database: %d",
+	"查询数据库点数":                                    "query data point count",
+	"监控模块: 查询数据库点数失败，跳过本间隔: %v":                  "monitor: failed to query data point count, skipping this interval: %v",
+	"\n========== 监控报告 ==========":               "\n========== Monitor report ==========",
+	"已运行时间: %v":                                  "elapsed: %v",
+	"当前配置: 每条消息数据点数: %d":                         "config: points per message: %d",
+	"当前间隔(%v)统计:":                                "last interval (%v):",
+	"  - 已发送数据点: %d (本次新增: %d), 速率: %.1f 点/秒":    "  - points sent: %d (new: %d), rate: %.1f points/s",
+	"  - 已发送消息: %d (本次新增: %d), 速率: %.1f 条/秒":     "  - messages sent: %d (new: %d), rate: %.1f msgs/s",
+	"  - 数据库记录数: %d (本次新增: %d), 速率: %.1f 点/秒":    "  - database rows: %d (new: %d), rate: %.1f points/s",
+	"  - 检测到计数器重置: 已发送数据点从 %d 变为 %d，本间隔按重置后重新计数": "  - Counter reset detected: data points sent went from %d to %d; this interval counts from the reset",
+	"  - 检测到计数器重置: 已发送消息从 %d 变为 %d，本间隔按重置后重新计数":  "  - Counter reset detected: messages sent went from %d to %d; this interval counts from the reset",
+	"  - 数据库记录数减少 %d (从 %d 变为 %d)，可能有数据保留任务在清理旧数据；本间隔入库数无法确定，不计入累计统计，需要准确统计时设置 monitor.count_mode: window": "  - Database row count dropped by %d (from %d to %d), probably a retention job deleting old data; rows written this interval are unknown and excluded from the totals. Set monitor.count_mode: window for accurate counts",
+	"  - 只读副本复制延迟: %v":                    "  - read replica lag: %v",
+	"  - 数据库写入探测失败: %v":                   "  - DB write probe failed: %v",
+	"  - 数据库写入探测: %v":                     "  - DB write probe: %v",
+	"  - 本次写入率: %.1f%% (数据库新增/发送新增)":      "  - interval write rate: %.1f%% (new rows / new points sent)",
+	"  - 入库落后: %s":                        "  - writes falling behind: %s",
+	"  - 调度落后: 本次 %v (累计 %v)，发送未能按计划间隔进行": "  - Behind schedule: %v this interval (%v total), sends did not keep the planned interval",
+	"  - 标注 %s [%s]: %s":                  "  - Annotation %s [%s]: %s",
+	"累计统计:":                               "cumulative:",
+	"  - 总发送数据点: %d, 平均速率: %.1f 点/秒":      "  - total points sent: %d, average rate: %.1f points/s",
+	"  - 总发送消息: %d, 平均速率: %.1f 条/秒":       "  - total messages sent: %d, average rate: %.1f msgs/s",
+	"  - 总入库数据点: %d, 平均速率: %.1f 点/秒":      "  - total points stored: %d, average rate: %.1f points/s",
+	"  - 总体写入率: %.1f%% (总入库/总发送)":         "  - overall write rate: %.1f%% (stored / sent)",
+	"  - 注意: 数据库可能还在处理之前的数据":              "  - note: the database may still be processing earlier data",
+	"  - 实际平均每条消息数据点数: %.2f":              "  - actual average points per message: %.2f",
+	"  - 基于数据点计算的理论消息数: %d (用于验证)":        "  - theoretical message count from points: %d (for verification)",
+	"  - 理论值与实际值差异: %.2f%%":               "  - theoretical vs actual difference: %.2f%%",
+	"监控模块: 使用本地Broker %s，不连接数据库，监控Broker收到的消息，监控间隔: %v":    "Monitor: using local broker %s, not connecting to the database, monitoring messages received by the broker, interval: %v",
+	"监控模块: 消息由本地接收端(%s)处理，不连接数据库，监控间隔: %v":                 "Monitor: messages handled by the local sink (%s), not connecting to the database, interval: %v",
+	"  - 发送遥测消息: 本次 %d 条 (%.1f 条/秒), 累计 %d 条":              "  - Telemetry sent: %d this interval (%.1f/s), %d total",
+	"  - 本地Broker收到: 本次 %d 条 (%.1f 条/秒), 累计 %d 条, 当前连接 %d": "  - Local broker received: %d this interval (%.1f/s), %d total, %d connections",
+	"  - 接收端: 本次 %.1f MB (%.1f MB/秒), 累计 %.1f MB":          "  - Sink: %.1f MB this interval (%.1f MB/s), %.1f MB total",
+	"  - 检测到计数器重置，本间隔按重置后重新计数":                             "  - Counter reset detected; this interval counts from the reset",
+	"发布耗时":      "Publish latency",
+	"心跳发布耗时":    "Heartbeat publish latency",
+	"看板端到端延迟":   "Dashboard end-to-end latency",
+	"属性下发到设备回复": "Attribute set to device reply",
+	"命令下发到设备回复": "Command to device reply",
+	"历史/聚合查询耗时": "History/aggregate query latency",
+	"  - %s: 本间隔 %d 次, p50=%v, p99=%v, 最大=%v; 最近 %d 个间隔 p99=%v; 累计 p99=%v":         "  - %s: %d this interval, p50=%v, p99=%v, max=%v; last %d intervals p99=%v; cumulative p99=%v",
+	"  - %s: 本间隔 %d 次, p50=%v, p99=%v, 最大=%v; 累计 p99=%v":                           "  - %s: %d this interval, p50=%v, p99=%v, max=%v; cumulative p99=%v",
+	"MQTT协议版本(4: 3.1.1, 5: MQTT 5，仅lite引擎)":                                        "MQTT protocol version (4: 3.1.1, 5: MQTT 5, lite engine only)",
+	"MQTT 5: 每条消息的内容类型属性(如 application/json)":                                      "MQTT 5: content type property on every message (e.g. application/json)",
+	"MQTT 5: 每条消息附带的用户属性，逗号分隔的 key=value，{device}为设备token，如 model=TP-100,fw=1.2.3": "MQTT 5: user properties on every message, comma separated key=value, {device} is the device token, e.g. model=TP-100,fw=1.2.3",
+	"服务器拒绝发布，原因码: 0x%02x":                                                          "server rejected publish, reason code: 0x%02x",
+	"服务器拒绝连接，原因码: 0x%02x":                                                          "Server refused connection, reason code: 0x%02x",
+	"服务器拒绝连接，返回码: %d":                                                              "server refused connection, return code: %d",
+	"无效的用户属性 %s，应为 key=value":                                                      "Invalid user property %s, expected key=value",
+	"无效的剩余长度": "Invalid remaining length",
+	"数值保留的小数位数，0为不限制(按float64最短表示)":                                "decimal places of values, 0 for no limit (shortest float64 representation)",
+	"数值边界测试：按比例混入整数、科学计数法、极大/极小数值和字符串形式的NaN/Infinity，测试平台的数值解析和存储": "numeric edge cases: mix in integers, scientific notation, extreme magnitudes and NaN/Infinity as strings to probe numeric parsing and storage",
+	"\n========== 数值边界 ==========":                     "\n========== Numeric Edge Cases ==========",
+	"字符串NaN/Infinity: %d, 极端数值: %d, 整数: %d, 科学计数法: %d": "NaN/Infinity strings: %d, extreme values: %d, integers: %d, scientific notation: %d",
+	"核对平台中这些数据点的入库值和解析错误日志，数据库写入率低于100%时检查是否被拒绝":       "check the stored values and parse error logs on the platform; if the write ratio is below 100%, check whether these were rejected",
+	"离线缓存场景：部分设备离线若干循环并在本地缓存数据，重连后一次性补发全部缓存":           "Offline buffering scenario: some devices go offline for several cycles and buffer data locally, then flush the whole backlog after reconnecting",
+	"设备在第几个循环离线(默认为总循环数的三分之一)":                         "Cycle at which devices go offline (default: one third of the total cycles)",
+	"离线持续的循环数(默认为总循环数的三分之一)":                           "Number of cycles devices stay offline (default: one third of the total cycles)",
+	"参与离线的设备比例(%)，默认10":                                "Percentage of devices that go offline, default 10",
+	"设备 %s 恢复在线时重连失败，丢弃 %d 条缓存消息: %v":                  "Device %s failed to reconnect when coming back online, dropping %d buffered messages: %v",
+	"\n========== 离线缓存补发 ==========":                   "\n========== Offline buffering flush ==========",
+	"测试未进行到第 %d 个循环，没有设备离线":                            "Test did not reach cycle %d, no devices went offline",
+	"离线设备: %d, 离线循环: 第 %d-%d 个":                        "Offline devices: %d, offline cycles: %d-%d",
+	"缓存消息: %d, 补发成功 %d (%.2f%%), 补发失败 %d":              "Buffered messages: %d, flushed %d (%.2f%%), flush failed %d",
+	"未补发: %d 条(测试在设备恢复在线前结束)":                          "Not flushed: %d (test ended before devices came back online)",
+	"恢复在线时重连失败的设备: %d":                                 "Devices that failed to reconnect when coming back online: %d",
+	"单设备补发耗时: p50=%v, p99=%v, 最大=%v":                   "Per-device flush time: p50=%v, p99=%v, max=%v",
+	"补发消息发布耗时: p50=%v, p99=%v, 最大=%v":                  "Flushed message publish latency: p50=%v, p99=%v, max=%v",
+	"补发突发: %v 内补发 %d 条, %.2f 条/秒(入库情况见该时段的监控输出)":       "Flush burst: %v for %d messages, %.2f msg/s (see monitor output for that period for DB writes)",
+	"离线缓存核对: %v":        "Offline buffering check: %v",
+	"离线缓存核对: 设备 %s: %v": "Offline buffering check: device %s: %v",
+	"离线缓存核对: 设备 %s 的 %s 当前值为 %v，最后发送的值为 %v":                                                       "Offline buffering check: device %s current value of %s is %v, last sent value is %v",
+	"抽样核对 %d 个设备(各设备的第一个数据点):":                                                                    "Checked %d sampled devices (first data point of each device):",
+	"- 补发数据按原始时间戳入库: %d/%d (%.2f%%)，偏低说明平台丢弃了补发数据或以接收时间入库":                                        "- Flushed data stored with original timestamps: %d/%d (%.2f%%), a low value means the platform dropped flushed data or stored it with the receive time",
+	"- 当前值为最后一条实时消息: %d/%d":                                                                       "- Current value matches the last live message: %d/%d",
+	"系统限制不足时自动减少设备数量，而不是拒绝启动":                                                                     "reduce the number of devices when OS limits are too low instead of refusing to start",
+	"警告: 提高文件描述符上限失败: %v":                                                                         "warning: failed to raise open file limit: %v",
+	"已将文件描述符软上限从 %d 提高到 %d (硬上限 %d)":                                                              "raised open file soft limit from %d to %d (hard limit %d)",
+	"警告: net.core.somaxconn=%d 小于设备数量，本机Broker在集中连接时可能丢弃连接请求，可执行 sysctl -w net.core.somaxconn=%d": "warning: net.core.somaxconn=%d is below the device count, a local broker may drop connection attempts during the connect burst, consider sysctl -w net.core.somaxconn=%d",
+	"警告: 受系统限制(文件描述符上限 %d, 本地端口 %d)，设备数量从 %d 减少到 %d":                                              "warning: limited by the OS (open files %d, local ports %d), reducing devices from %d to %d",
+	"查询分区信息失败: %w":                                      "failed to query partition info: %w",
+	"监控模块: telemetry_datas 未分区，按时间窗口统计全表":               "monitor: telemetry_datas is not partitioned, counting the whole table within the time window",
+	"监控模块: 按分区统计入库数，当前统计 %d 个分区: %s":                    "monitor: counting stored rows per partition, %d partitions: %s",
+	"不支持的数值: %v":                                        "unsupported value: %v",
+	"平台API地址(如 http://127.0.0.1:9999)，属性下发等测试通过它调用平台接口": "Platform API address (e.g. http://127.0.0.1:9999), used by tests such as attribute set to call platform APIs",
+	"平台API密钥，作为x-api-key请求头":                            "Platform API key, sent as the x-api-key header",
+	"接口返回状态码 %d":                                        "API returned status %d",
+	"接口返回错误 %d: %s":                                     "API returned error %d: %s",
+	"跳过启动前检查":                                           "skip preflight checks",
+	"启动前检查时用第一个token向遥测主题发布一条QoS1空消息({})，检查发布权限": "during preflight, publish one empty QoS1 message ({}) to the telemetry topic with the first token to check publish permission",
+	"设备token数量":      "device token count",
+	"文件描述符上限":        "open file limit",
+	"本地端口范围":         "local port range",
+	"动态注册":           "Provisioning",
+	"MQTT服务器":        "MQTT broker",
+	"遥测表结构":          "Telemetry table schema",
+	"只读副本":           "Read replica",
+	"IP%s连通性":        "IP%s reachability",
+	"启动前检查:":         "preflight checks:",
+	" [失败] %s: %v":   " [FAIL] %s: %v",
+	"        建议: %s": "        hint: %s",
+	" [通过] %s":       " [ OK ] %s",
+	"启动前检查未通过，可使用 --skip-preflight 跳过检查":                                                                     "preflight checks failed, use --skip-preflight to bypass",
+	"使用create_device再创建 %d 个设备，或将 --clients 减少到 %d":                                                          "create %d more devices with create_device, or reduce --clients to %d",
+	"token文件 %s 只有 %d 个设备，少于请求的 %d 个":                                                                        "token file %s has only %d devices, fewer than the %d requested",
+	"硬上限为 %d，需由管理员在 /etc/security/limits.conf 或 systemd 的 LimitNOFILE 中提高到 %d 以上，或使用 --cap-clients 自动减少设备数量": "the hard limit is %d, an administrator must raise it to at least %d in /etc/security/limits.conf or systemd LimitNOFILE, or use --cap-clients to reduce the device count",
+	"当前上限 %d，%d 个设备至少需要 %d":                                                                                  "current limit is %d, %d devices need at least %d",
+	"执行 sysctl -w net.ipv4.ip_local_port_range='1024 65535'，超过该数量时需从多台机器或多个源IP发起测试，或使用 --cap-clients":        "run sysctl -w net.ipv4.ip_local_port_range='1024 65535'; beyond that run the test from several machines or source IPs, or use --cap-clients",
+	"本地端口范围只有 %d 个端口，少于 %d 个设备":                                                                              "local port range has only %d ports, fewer than %d devices",
+	"确认 %s 地址和端口正确，且Broker已启动":                                                                               "make sure %s is the right host and port and the broker is running",
+	"确认token文件来自当前ThingsPanel实例，且设备未被删除":                                                                     "make sure the token file comes from this ThingsPanel instance and the devices still exist",
+	"使用第一个token认证失败: %w":                                                                                     "authentication with the first token failed: %w",
+	"检查Broker的ACL是否允许设备向 %s 发布":                                                                              "check that the broker ACL allows devices to publish to %s",
+	"发布到主题 %s 失败: %w":                                                                                        "publish to topic %s failed: %w",
+	"确认Broker域名有IP%s解析记录、Broker监听了IP%s地址，且本机有可用的IP%s路由":                                                      "Make sure the broker hostname has IP%s records, the broker listens on an IP%s address and this host has an IP%s route",
+	"检查 --db-host、--db-user、--db-pass 和 --db-name 参数(或 --db-dsn)":                                            "Check --db-host, --db-user, --db-pass and --db-name (or --db-dsn)",
+	"%s: 表不存在":   "%s: table does not exist",
+	"%s: 缺少列 %s": "%s: missing columns %s",
+	"确认 --db-name 指向ThingsPanel数据库；平台版本改变了遥测表结构时需要同步修改本工具的查询": "Make sure --db-name points to the ThingsPanel database; if the platform changed the telemetry table schema, the queries in this tool need to be updated",
+	"遥测表结构与本工具不一致: %s":                                   "Telemetry table schema does not match this tool: %s",
+	"检查 --db-replica 参数，副本使用与主库相同的账号和数据库名":               "check --db-replica, the replica uses the same credentials and database name as the primary",
+	"--db-replica 应指向流复制的只读副本，而不是主库":                     "--db-replica should point to a streaming read replica, not the primary",
+	"只读副本最后重放的事务距今: %v":                                  "time since the read replica last replayed a transaction: %v",
+	"preview: 每种消息输出的示例数量":                               "preview: number of sample messages printed per message type",
+	"preview: 额外预览的网关消息格式，逗号分隔，all为全部内置格式(默认只预览配置的消息格式)": "preview: additional gateway payload profiles to preview, comma separated, all for every built-in profile (default: only the configured payload format)",
+	"# 警告: 不是合法的JSON":                                    "# warning: not valid JSON",
+	"示例数量必须大于0: %d":                                      "sample count must be greater than 0: %d",
+	"预览完成: %d 条示例消息不是合法的JSON，平台可能无法解析":                   "preview finished: %d sample messages are not valid JSON and the platform may fail to parse them",
+	"警告: %v，预览使用占位token":                                 "warning: %v, the preview uses a placeholder token",
+	"警告: token文件 %s 中没有设备，预览使用占位token":                   "warning: no devices in token file %s, the preview uses a placeholder token",
+	"正式测试前以低速率逐个认证全部设备一次，预热平台的认证缓存(测量热认证下的连接性能)":         "Authenticate every device once at a low rate before the test to warm the platform auth cache (measures warm-auth connect performance)",
+	"预热阶段每秒认证的设备数":                                       "Devices authenticated per second during priming",
+	"开始预热认证缓存: %d 个设备, %d 个/秒":                           "Priming auth cache: %d devices, %d/s",
+	"预热进度: %d/%d": "Priming progress: %d/%d",
+	"认证缓存预热完成: 成功 %d, 失败 %d, 耗时 %v, 认证耗时 p50=%v, p99=%v":  "Auth cache primed: %d succeeded, %d failed, took %v, auth latency p50=%v, p99=%v",
+	"\n========== 多协议混合 ==========":                       "\n========== Mixed Protocols ==========",
+	"%s 设备: %d 个, 发送成功 %d 条 (%.1f 条/秒), 失败 %d 条 (%.2f%%)": "%s devices: %d, sent %d (%.1f/s), failed %d (%.2f%%)",
+	"  发送耗时: p50=%v, p99=%v, 最大=%v":                       "  Send latency: p50=%v, p99=%v, max=%v",
+	"  请求失败(连接错误或超时): %d 次":                               "  Request failures (connection error or timeout): %d",
+	"  状态码 %d: %d 次":                                      "  Status %d: %d",
+	"WebSocket看板: %d 个, 连接 %d 次, 连接失败 %d 次, 中途断开 %d 次":    "WebSocket dashboards: %d, connects %d, connect failures %d, disconnects %d",
+	"  收到推送 %d 条 (%.1f 条/秒), 共 %.1f KB":                   "  Received %d pushes (%.1f/s), %.1f KB total",
+	"  推送间隔: p50=%v, p99=%v, 最大=%v":                       "  Push interval: p50=%v, p99=%v, max=%v",
+	"  警告: 看板没有收到任何推送，请检查看板地址和订阅消息":                       "  Warning: dashboards received no pushes, check the dashboard URL and subscribe message",
+	"动态注册模式：设备连接前通过平台的一型一密动态注册接口获取凭证，而不是使用预先创建的token，用于压测注册接口本身": "Provisioning mode: devices obtain credentials from the platform dynamic registration (one-type-one-secret) endpoint before connecting instead of using pre-created tokens, to load test the registration endpoint itself",
+	"动态注册模式：设备配置的一型一密密钥(template secret)":                        "Provisioning mode: template secret of the device configuration (one-type-one-secret)",
+	"动态注册失败: %w":                       "provisioning failed: %w",
+	"响应中没有设备凭证(data.voucher.username)": "no device credentials in the response (data.voucher.username)",
+	"确认 provision.secret 是平台上设备配置的一型一密密钥，且 provision.path 与平台版本一致": "Make sure provision.secret is the template secret of a device configuration on the platform and provision.path matches the platform version",
+	"\n========== 动态注册 ==========":                               "\n========== Provisioning ==========",
+	"注册请求: %d, 失败 %d (%.2f%%), 响应中没有凭证 %d":                       "Registration requests: %d, failed %d (%.2f%%), without credentials %d",
+	"  注册耗时: p50 %v, p99 %v, 最大 %v":                              "  Registration time: p50 %v, p99 %v, max %v",
+	"  等待并发名额(provision.concurrency=%d): p99 %v, 最大 %v":          "  Waiting for a concurrency slot (provision.concurrency=%d): p99 %v, max %v",
+	"按比例为设备分配QoS，如 0:60,1:35,2:5 (覆盖--qos)":                      "assign QoS to devices by share, e.g. 0:60,1:35,2:5 (overrides --qos)",
+	"无效的QoS比例: %s":                                               "invalid QoS share: %s",
+	"QoS比例之和应为100，当前为 %.1f":                                      "QoS shares must add up to 100, got %.1f",
+	"\n========== QoS分布统计 ==========":                            "\n========== QoS breakdown ==========",
+	"QoS%d: 设备=%d, 成功=%d, 失败=%d (%.2f%%), p50=%v, p99=%v, 最大=%v": "QoS%d: devices=%d, ok=%d, failed=%d (%.2f%%), p50=%v, p99=%v, max=%v",
+	"测试结束时每秒断开的设备数，测量平台标记离线和处理剩余写入的耗时，0为同时断开":                    "Devices disconnected per second at the end of the test, measuring how long the platform takes to mark them offline and flush pending writes; 0 disconnects all at once",
+	"开始逐步断开设备":                                                   "Start ramping down devices",
+	"逐步断开: %v":                                                   "Ramp-down: %v",
+	"逐步断开: 等待平台处理断开超时(%v)":                                       "Ramp-down: timed out waiting for the platform to process disconnects (%v)",
+	"\n========== 逐步断开 ==========":                               "\n========== Ramp-down ==========",
+	"断开速率: %d 个/秒, 断开 %d 个设备用时 %v":                               "Disconnect rate: %d/s, disconnecting %d devices took %v",
+	"平台标记离线(抽样 %d 个设备): %d 个设备超时仍未被标记离线, 已离线设备p99=%v":            "Platform offline marking (%d sampled devices): %d devices still not marked offline at timeout, p99 of offline devices=%v",
+	"平台标记离线(抽样 %d 个设备): 最后一个设备断开后全部离线用时 %v, 单设备p99=%v":           "Platform offline marking (%d sampled devices): all offline %v after the last disconnect, per-device p99=%v",
+	"剩余写入: 最后一个设备断开后入库 %d 个数据点, 用时 %v":                           "Pending writes: %d data points stored after the last disconnect, took %v",
+	"随机数种子(0表示按当前时间生成)，相同种子可复现测试数据":                              "random seed (0 derives one from the current time); the same seed reproduces the test data",
+	"随机数种子: %d (使用 --seed %d 可复现本次运行)":                           "random seed: %d (use --seed %d to reproduce this run)",
+	"断线重连方式(backoff: 指数退避加随机抖动, paho: paho内置退避, off: 不重连)":       "Reconnect mode (backoff: exponential backoff with jitter, paho: paho built-in backoff, off: no reconnect)",
+	"重连等待时间上限(默认5s)":                                             "Maximum reconnect wait (default 5s)",
+	"\n========== 断线重连 ==========":                               "\n========== Reconnects ==========",
+	"重连方式: %s, 首次等待=%v, 上限=%v, 倍数=%.1f, 抖动=%.0f%%":               "Reconnect mode: %s, initial wait=%v, max=%v, multiplier=%.1f, jitter=%.0f%%",
+	"断开 %d 次, 重连尝试 %d 次: 成功 %d, 失败 %d, 测试结束时未恢复 %d":              "Connections lost %d times, %d reconnect attempts: %d succeeded, %d failed, %d not recovered at the end of the test",
+	"断开到重连成功: p50=%v, p99=%v, 最大=%v":                             "Time from loss to reconnect: p50=%v, p99=%v, max=%v",
+	"数据点速率":         "Data point rate",
+	"点/秒":           "points/s",
+	"发送":            "Sent",
+	"入库":            "Stored",
+	"消息速率":          "Message rate",
+	"条/秒":           "msg/s",
+	"数据库写入探测耗时":     "DB write probe latency",
+	"插入":            "insert",
+	"运行ID":          "Run ID",
+	"开始时间":          "Start time",
+	"测试时长":          "Duration",
+	"设备数":           "Devices",
+	"循环次数":          "Cycles",
+	"发送消息数":         "Messages sent",
+	"%d (%.1f 条/秒)": "%d (%.1f msg/s)",
+	"发送数据点数":        "Data points sent",
+	"%d (%.1f 点/秒)": "%d (%.1f points/s)",
+	"入库数据点数":        "Data points stored",
+	"提前终止":          "Aborted",
+	"MQTT性能测试报告":    "MQTT performance test report",
+	"没有监控样本(监控间隔内测试已结束或数据库不可用)，未生成图表": "No monitor samples (the test ended within one monitor interval or the database was unavailable), charts not generated",
+	"分阶段统计":                 "Per-stage statistics",
+	"阶段":                    "Stage",
+	"循环":                    "Cycles",
+	"发送间隔":                  "Send interval",
+	"点/条":                   "points/msg",
+	"入库 点/秒":                "Stored points/s",
+	"发布耗时 p50/p95/p99 (ms)": "Publish latency p50/p95/p99 (ms)",
+	"成功率":                   "Success rate",
+	"事件时间线":                 "Event timeline",
+	"生成HTML报告失败: %v":        "Failed to generate HTML report: %v",
+	"HTML报告: %s":            "HTML report: %s",
+	"测试结束后把HTML报告和JSON汇总发送到这些邮箱(逗号分隔)，SMTP服务器见配置文件report部分": "Email the HTML report and JSON summary to these addresses (comma separated) when the test finishes, SMTP server in the report section of the config file",
+	"测试结束后以multipart/form-data上传HTML报告和JSON汇总的HTTP地址":       "HTTP URL to upload the HTML report and JSON summary to (multipart/form-data) when the test finishes",
+	"发送报告邮件失败: %v":       "Failed to send report email: %v",
+	"报告已发送到: %s":         "Report sent to: %s",
+	"上传报告失败: %v":         "Failed to upload report: %v",
+	"报告已上传到Webhook":      "Report uploaded to webhook",
+	"%.0f 点/秒, 设备 %d/%d": "%.0f points/s, devices %d/%d",
+	"提前终止: ":             "aborted: ",
+	", 被中断":              ", interrupted",
+	"SMTP服务器地址格式错误: %w":  "Invalid SMTP server address: %w",
+	"SMTP服务器不支持登录认证":     "SMTP server does not support authentication",
+	"收件人 %s: %w":         "Recipient %s: %w",
+	"Broker域名解析方式(per-connection: 每次连接时解析, once: 启动时解析一次，设备轮流使用解析结果)":        "Broker hostname resolution (per-connection: resolve on every connection, once: resolve once at startup and rotate devices over the results)",
+	"只连接指定的Broker IP，逗号分隔，设备轮流使用(不再解析域名)":                                    "Connect only to these broker IPs, comma separated, rotated across devices (hostname is not resolved)",
+	"解析Broker域名使用的DNS服务器(host:port)，逗号分隔，为空时使用系统配置":                          "DNS servers (host:port) used to resolve the broker hostname, comma separated; system settings when empty",
+	"连接Broker使用的地址族(any: 不限, v4: 只用IPv4, v6: 只用IPv6, dual: 设备交替使用IPv4和IPv6)": "Address family for broker connections (any: no restriction, v4: IPv4 only, v6: IPv6 only, dual: devices alternate between IPv4 and IPv6)",
+	"解析服务器地址 %s 失败: %w":                                  "Failed to parse server address %s: %w",
+	"解析Broker域名 %s 失败: %w":                               "Failed to resolve broker hostname %s: %w",
+	"Broker地址 %s 没有可用的IP%s地址 (%s)":                       "Broker %s has no usable IP%s address (%s)",
+	"Broker地址 %s 使用 %d 个IP，设备轮流连接: %s":                   "Broker %s uses %d IPs, devices connect in rotation: %s",
+	"history: 显示最近的运行记录数量":                               "history: number of recent runs to list",
+	"history: 对比的两个运行ID，逗号分隔":                            "history: two run IDs to compare, comma separated",
+	"创建结果表失败: %w":                                        "failed to create results table: %w",
+	"写入运行结果失败: %w":                                       "failed to insert run results: %w",
+	"警告: 序列化配置失败: %v":                                    "warning: failed to serialize config: %v",
+	"查询运行记录失败: %w":                                       "failed to query runs: %w",
+	"读取运行记录失败: %w":                                       "failed to read run: %w",
+	"连接结果库失败: %v":                                        "failed to connect to results database: %v",
+	"--compare 需要两个运行ID，例如: --compare a1b2c3d4,e5f6a7b8": "--compare needs two run IDs, e.g. --compare a1b2c3d4,e5f6a7b8",
+	"未找到全部运行记录: %s":                                      "not all runs found: %s",
+	"结果库中没有运行记录":                                         "no runs in the results database",
+	"运行ID\t开始时间\t耗时\t设备数\t循环\t数据点\t点/秒\t消息/秒\t入库数":       "Run ID\tStarted\tDuration\tDevices\tCycles\tPoints\tPoints/s\tMsgs/s\tStored",
+	"指标\t%s\t%s\t变化\n":                                   "Metric\t%s\t%s\tChange\n",
+	"开始时间\t%s\t%s\t\n":                                   "Started\t%s\t%s\t\n",
+	"耗时\t%v\t%v\t%s\n":                                   "Duration\t%v\t%v\t%s\n",
+	"连接设备数\t%d\t%d\t%s\n":                                "Connected devices\t%d\t%d\t%s\n",
+	"总发送数据点\t%d\t%d\t%s\n":                               "Points sent\t%d\t%d\t%s\n",
+	"总发送消息\t%d\t%d\t%s\n":                                "Messages sent\t%d\t%d\t%s\n",
+	"数据点速率(点/秒)\t%.1f\t%.1f\t%s\n":                       "Point rate (points/s)\t%.1f\t%.1f\t%s\n",
+	"消息速率(条/秒)\t%.1f\t%.1f\t%s\n":                        "Message rate (msgs/s)\t%.1f\t%.1f\t%s\n",
+	"入库数据点\t%d\t%d\t%s\n":                                "Points stored\t%d\t%d\t%s\n",
+	"中断后恢复：定期保存场景进度检查点(当前阶段、已完成循环、每设备偏移)，压测程序崩溃后重新启动时从检查点的下一个循环继续，正常结束后删除检查点": "Resume after interruption: periodically checkpoint scenario progress (current stage, completed cycles, per-device offsets); after a crash, restarting continues from the cycle after the checkpoint; the checkpoint is deleted when the run completes",
+	"中断恢复: 检查点的场景 (%s) 与当前配置 (%s) 不一致，请删除 %s 后重新开始":                           "resume: checkpoint scenario (%s) does not match the current configuration (%s), delete %s to start over",
+	"中断恢复: 检查点显示已完成全部 %d 个循环，请删除 %s 后重新开始":                                    "resume: checkpoint shows all %d cycles completed, delete %s to start over",
+	"中断恢复: 从第 %d 个循环继续 (阶段: %s, %d 个设备的进度)":                                   "resume: continuing from cycle %d (stage: %s, progress of %d devices)",
+	"中断恢复: 删除检查点失败: %v": "resume: failed to delete checkpoint: %v",
+	"检查点: %v": "checkpoint: %v",
+	"中断恢复: 检查点已保存到 %s，使用 --resume 重新启动可从第 %d 个循环继续": "resume: checkpoint saved to %s, restart with --resume to continue from cycle %d",
+	"每条消息最多发布次数(含首次)，1为不重试":                         "Maximum publish attempts per message (including the first), 1 disables retries",
+	"首次重试前的等待时间，之后每次翻倍":                             "Wait before the first retry, doubled on each subsequent retry",
+	"发布重试: 重试后成功 %d 条, 放弃 %d 条, 共重试 %d 次":           "Publish retries: %d succeeded after retry, %d abandoned, %d retries in total",
+	"运行产物根目录，每次运行写入 <时间>-<运行ID> 子目录":                "root directory for run artifacts, each run writes into a <time>-<run id> subdirectory",
+	"创建运行目录失败: %w":                                  "failed to create run directory: %w",
+	"警告: 更新 %s 失败: %v":                              "warning: failed to update %s: %v",
+	"创建日志文件失败: %w":                                  "failed to create log file: %w",
+	"# 运行 %s 的完整配置(已合并配置文件、命令行参数、环境变量和默认值)，使用 --config 指定本文件可复现本次运行\n# 密码等敏感信息已隐藏为 %s，复现前需要填回\n# 命令行参数: %s\n\n": "# Full config of run %s (config file, flags, environment and defaults merged), pass this file with --config to reproduce the run\n# Passwords and other secrets are redacted as %s, fill them in before reproducing\n# Flags: %s\n\n",
+	"\n========== 调度与协调遗漏 ==========":         "\n========== Schedule and coordinated omission ==========",
+	"实测发布耗时: p50=%v, p99=%v, 最大=%v":           "Measured publish latency: p50=%v, p99=%v, max=%v",
+	"修正后耗时(从计划发送时间算起): p50=%v, p99=%v, 最大=%v": "Corrected latency (from scheduled send time): p50=%v, p99=%v, max=%v",
+	"设备开始发送晚于计划: p50=%v, p99=%v, 最大=%v":       "Device send start behind schedule: p50=%v, p99=%v, max=%v",
+	"主循环晚于计划触发: %d/%d 个循环, 累计 %v":             "Main loop fired late: %d/%d cycles, %v in total",
+	"设备因上一次发布未完成而错过的循环: %d 次(已按计划时间补记耗时样本)":   "Cycles missed because the previous publish had not finished: %d (latency samples backfilled at their scheduled times)",
+	"消息不发送到Broker而由本地接收端处理(null: 直接丢弃, file: 写入本地文件)，用于测量本机的数据生成能力或生成样本数据集": "Handle messages with a local sink instead of a broker (null: discard, file: write to a local file), to measure the generation capacity of this host or produce sample datasets",
+	"file接收端的输出文件(默认为运行目录下的sink.jsonl)":                                     "Output file of the file sink (default sink.jsonl in the run directory)",
+	"创建接收端输出文件失败: %w":                                   "Failed to create sink output file: %w",
+	"写入接收端输出文件失败: %w":                                   "Failed to write sink output file: %w",
+	"接收端不支持订阅":                                          "The sink does not support subscriptions",
+	"\n========== 本地接收端 ==========":                     "\n========== Local Sink ==========",
+	"模式: %s, 收到消息 %d 条 (%.1f 条/秒), %.1f MB (%.1f MB/秒)": "Mode: %s, received %d messages (%.1f/s), %.1f MB (%.1f MB/s)",
+	"输出文件 %s: %v":                                       "Output file %s: %v",
+	"输出文件: %s":                                          "Output file: %s",
+	"以上速率为本机生成消息的能力，不经过网络和Broker":                       "These rates are the message generation capacity of this host, without network or broker",
+	"长稳测试模式：持续运行直到手动停止":                                 "soak mode: run until stopped manually",
+	"长稳测试检查点文件路径":                                       "soak checkpoint file path",
+	"长稳测试检查点保存间隔":                                       "soak checkpoint save interval",
+	"读取检查点文件失败: %w":                                     "failed to read checkpoint file: %w",
+	"解析检查点文件失败: %w":                                     "failed to parse checkpoint file: %w",
+	"序列化检查点失败: %w":                                      "failed to serialize checkpoint: %w",
+	"写入检查点文件失败: %w":                                     "failed to write checkpoint file: %w",
+	"替换检查点文件失败: %w":                                     "failed to replace checkpoint file: %w",
+	"检查点: 未找到检查点 %s，从零开始计数":                             "checkpoint: checkpoint %s not found, starting from zero",
+	"检查点: 从检查点恢复 (运行ID=%s, 循环=%d, 数据点=%d, 消息=%d, 累计时长=%v, 保存于 %s)":                                       "checkpoint: resumed from checkpoint (run ID=%s, cycles=%d, points=%d, messages=%d, elapsed=%v, saved at %s)",
+	"分阶段负载，如 warmup:100x1s,20k:300x50ms/20 (名称:循环数x发送间隔[/每条消息数据点数])，依次运行并分别统计各阶段(覆盖--cycles和--interval)": "Staged load, e.g. warmup:100x1s,20k:300x50ms/20 (name:cyclesxinterval[/points per message]), run in order with per-stage statistics (overrides --cycles and --interval)",
+	"无效的阶段: %s (格式: 名称:循环数x发送间隔[/每条消息数据点数])":                                                             "Invalid stage: %s (format: name:cyclesxinterval[/points per message])",
+	"阶段 %s 开始: %d 个循环, 发送间隔 %v, 每条消息 %d 个数据点":                                                            "Stage %s started: %d cycles, send interval %v, %d data points per message",
+	"\n========== 分阶段统计 ==========":                                   "\n========== Per-stage statistics ==========",
+	"阶段 %s (第 %d-%d 个循环, %s-%s, 发送间隔 %v, 每条消息 %d 个数据点, 耗时 %v):":       "Stage %s (cycles %d-%d, %s-%s, send interval %v, %d data points per message, took %v):",
+	"  - 吞吐量: %.1f 条/秒, %.1f 点/秒 (消息 %d, 数据点 %d)":                     "  - Throughput: %.1f msg/s, %.1f points/s (messages %d, data points %d)",
+	"  - 入库速率(监控采样平均): %.1f 点/秒":                                      "  - DB write rate (average of monitor samples): %.1f points/s",
+	"  - 发布耗时: p50=%.1fms, p95=%.1fms, p99=%.1fms, 最大=%.1fms":         "  - Publish latency: p50=%.1fms, p95=%.1fms, p99=%.1fms, max=%.1fms",
+	"  - 成功率: %.2f%% (失败 %d)":                                         "  - Success rate: %.2f%% (failed %d)",
+	"数据流 %d 未配置主题":                                                    "stream %d has no topic",
+	"数据流 %s 未配置消息模板":                                                  "stream %s has no payload template",
+	"数据流名称重复: %s":                                                     "duplicate stream name: %s",
+	"\n========== 数据流统计 ==========":                                   "\n========== Streams ==========",
+	"%s (%s): 成功=%d, 失败=%d, p50=%v, p99=%v":                           "%s (%s): ok=%d, failed=%d, p50=%v, p99=%v",
+	"每条消息附加的字符串数据点数量，用于测试string_v存储、JSON转义和索引，0为不附加":                  "number of string data points appended to each message to stress string_v storage, JSON escaping and indexes, 0 for none",
+	"字符串内容类型，逗号分隔(ascii, cjk, emoji, escape, mixed)，各数据点轮流使用(默认: 全部)": "string content kinds, comma separated (ascii, cjk, emoji, escape, mixed), assigned to data points in turn (default: all)",
+	"不支持的字符串内容类型: %s (可选: %s)":                                        "unsupported string kind: %s (options: %s)",
+	"查询字符串数据点失败: %w":                                                  "failed to query string data points: %w",
+	"\n========== 字符串数据点 ==========":                                  "\n========== String Data Points ==========",
+	"附加字符串的消息: 成功 %d 条, 失败 %d 条":                                      "messages with strings: %d succeeded, %d failed",
+	"  服务器拒绝(原因码 0x%02x): %d 条":                                       "  rejected by the server (reason code 0x%02x): %d",
+	"字符串数据点: %v":                                                      "string data points: %v",
+	"%s: 发送 %d, 入库 %d, 最大长度 发送 %d / 入库 %d":                            "%s: sent %d, stored %d, max length sent %d / stored %d",
+	", 未入库 %d": ", not stored %d",
+	", 可能被截断":  ", possibly truncated",
+	"设备订阅的主题，逗号分隔，{username}替换为设备token":              "topics each device subscribes to, comma separated; {username} is replaced by the device token",
+	"订阅的服务质量(0,1,2)":                                 "subscription QoS (0,1,2)",
+	"订阅主题 %s 失败: %w":                                 "failed to subscribe to %s: %w",
+	"服务器拒绝订阅主题 %s (SUBACK返回码 0x80)":                  "server rejected subscription to %s (SUBACK return code 0x80)",
+	"\n========== 订阅统计(SUBSCRIBE→SUBACK) ==========": "\n========== Subscriptions (SUBSCRIBE→SUBACK) ==========",
+	"订阅主题: %s, QoS: %d":                              "topics: %s, QoS: %d",
+	"成功: %d, 被拒绝: %d, 请求失败: %d":                      "granted: %d, rejected: %d, failed requests: %d",
+	"耗时: 平均 %v, p50 %v, p99 %v, 最大 %v":               "latency: mean %v, p50 %v, p99 %v, max %v",
+	"  - 返回码 0x%02x: %d":                             "  - return code 0x%02x: %d",
+	"错误: 服务器拒绝了 %d 个订阅，请检查Broker的ACL配置是否允许设备订阅这些主题，示例:":  "error: the server rejected %d subscriptions; check that the broker ACL allows devices to subscribe to these topics. Examples:",
+	"测试结束时向标准输出打印单行JSON汇总(日志输出到标准错误)":                    "print a single-line JSON summary to stdout at the end of the test (logs go to stderr)",
+	"会话接管场景：测试中途用相同的客户端ID为设备再建立一个连接，测量接管耗时和交接期间的消息丢失":    "Session takeover scenario: midway through the test, open a second connection per device with the same client ID and measure takeover time and handover message loss",
+	"在第几个循环进行接管(默认为总循环数的一半)":                             "Cycle at which the takeover happens (defaults to half of the cycle count)",
+	"参与接管的设备比例(%)，默认100":                                 "Percentage of devices taking part in the takeover, default 100",
+	"设备 %s 会话接管失败: %v":                                   "Session takeover failed for device %s: %v",
+	"\n========== 会话接管 ==========":                       "\n========== Session takeover ==========",
+	"测试未进行到第 %d 个循环，未进行接管":                               "The test did not reach cycle %d, no takeover happened",
+	"第 %d 个循环接管设备: %d, 成功 %d, 失败 %d":                     "Takeover at cycle %d: %d devices, %d succeeded, %d failed",
+	"接管耗时(新连接CONNECT→CONNACK): p50=%v, p99=%v, 最大=%v":    "Takeover latency (new connection CONNECT→CONNACK): p50=%v, p99=%v, max=%v",
+	"交接期间丢失: %d 个设备接管后首条消息发布失败 (%.2f%%)":                 "Lost during handover: first message after takeover failed for %d devices (%.2f%%)",
+	"多租户分组: 不能与设备筛选同时使用":                                 "tenant groups: cannot be combined with the device filter",
+	"多租户分组: 第 %d 组没有名称":                                  "tenant groups: group %d has no name",
+	"多租户分组: 名称 %s 重复":                                    "tenant groups: duplicate name %s",
+	"多租户分组: %s 的设备数必须大于0":                                "tenant groups: %s must have more than 0 clients",
+	"多租户分组: %s 没有配置token文件":                              "tenant groups: %s has no token file",
+	"多租户分组: %s 的发送间隔 %v 不是 test.data_interval (%v) 的整数倍": "tenant groups: interval %[2]v of %[1]s is not a multiple of test.data_interval (%[3]v)",
+	"多租户分组 %s: %w":                                       "tenant group %s: %w",
+	"警告: 多租户分组 %s: 可用设备数量(%d)少于请求数量(%d)":                 "Warning: tenant group %s: available devices (%d) fewer than requested (%d)",
+	"多租户分组: %v":                     "tenant groups: %v",
+	"\n========== 多租户分组 ==========": "\n========== Tenant Groups ==========",
+	"%s: 设备 %d (连接成功 %d, 失败 %d), 主题 %s, 发送间隔 %v":                              "%s: %d devices (%d connected, %d failed), topic %s, interval %v",
+	"  发送: %d 条消息, %d 个数据点 (%.1f 点/秒), 失败 %d 条 (%.2f%%), 发布耗时 p50 %v, p99 %v": "  sent: %d messages, %d points (%.1f points/s), %d failed (%.2f%%), publish latency p50 %v, p99 %v",
+	"  入库(租户 %s): %d 个数据点, 写入率 %.1f%%":                                        "  stored (tenant %s): %d points, write rate %.1f%%",
+	"租户限额验证: 被限额的租户ID，测试结束时核对该租户的入库速率不超过限额、其他租户不受影响":                          "tenant limit verification: ID of the rate-limited tenant; at the end checks that its ingest rate stays within the quota and other tenants are unaffected",
+	"租户限额验证: 该租户在平台上配置的入库限额(数据点/秒)":                                           "tenant limit verification: ingest quota configured for the tenant on the platform (data points/s)",
+	"租户限额验证: %w": "tenant limit verification: %w",
+	"租户限额验证: token文件中没有租户 %s 的设备":                                          "tenant limit verification: token file has no devices of tenant %s",
+	"警告: 租户限额验证: 参与测试的设备都属于被限额租户，无法验证其他租户不受影响":                             "warning: tenant limit verification: all test devices belong to the limited tenant, cannot verify that other tenants are unaffected",
+	"警告: 租户限额验证: %d 个token在数据库中找不到设备，不计入租户统计":                              "warning: tenant limit verification: %d tokens have no device in the database and are not counted per tenant",
+	"租户限额验证: 被限额租户 %s 有 %d 个设备，其他 %d 个租户":                                  "tenant limit verification: limited tenant %s has %d devices, %d other tenants",
+	"查询设备所属租户失败: %w":                                                       "failed to query device tenants: %w",
+	"查询租户入库数失败: %w":                                                        "failed to query stored rows per tenant: %w",
+	"\n========== 租户限额验证 ==========":                                       "\n========== Tenant Limit Verification ==========",
+	"租户限额验证: %v":                                                           "tenant limit verification: %v",
+	"租户 %s: 设备 %d, 发送 %.1f 点/秒 (成功 %d 条, 失败 %d 条, 拒绝原因码: %s), 入库 %.1f 点/秒": "tenant %s: devices %d, sent %.1f points/s (succeeded %d, failed %d, rejection reason codes: %s), stored %.1f points/s",
+	"  结论: 无法验证，发送速率 %.1f 点/秒未超过限额 %.0f 点/秒，需增加该租户的设备数或发送频率":               "  verdict: inconclusive, offered rate %.1f points/s does not exceed the quota of %.0f points/s; add devices or raise the send rate for this tenant",
+	"  结论: 限额生效，入库速率 %.1f 点/秒未超过限额 %.0f 点/秒(容差 %.0f%%)":                    "  verdict: limit enforced, ingest rate %.1f points/s within the quota of %.0f points/s (tolerance %.0f%%)",
+	"  结论: 限额未生效，入库速率 %.1f 点/秒超过限额 %.0f 点/秒(容差 %.0f%%)":                    "  verdict: limit NOT enforced, ingest rate %.1f points/s exceeds the quota of %.0f points/s (tolerance %.0f%%)",
+	"  结论: 受到影响，写入率 %.1f%% 低于 %.0f%%":                                      "  verdict: degraded, write ratio %.1f%% below %.0f%%",
+	"  结论: 未受影响，写入率 %.1f%%":                                                "  verdict: unaffected, write ratio %.1f%%",
+	"限制使用的CPU核数(GOMAXPROCS)，与Broker等服务共用机器时使用，0为不限制":                       "Limit the number of CPU cores used (GOMAXPROCS) when sharing the host with the broker or other services, 0 for no limit",
+	"降低进程调度优先级(1-19，仅Linux/macOS)":                                         "Lower the process scheduling priority (1-19, Linux/macOS only)",
+	"CPU使用率上限(%，100为一个核)，超出时推迟发送，0为不限制":                                    "CPU usage cap (%, 100 is one core), sending is delayed when exceeded, 0 for no limit",
+	"GC触发比例(GOGC)，调低可减少内存占用但会增加CPU开销，0为不修改":                                "GC target percentage (GOGC), lower values reduce memory but cost more CPU, 0 to leave unchanged",
+	"警告: 设置调度优先级失败: %v":                                                    "Warning: failed to set scheduling priority: %v",
+	"警告: 当前平台无法采集进程CPU使用率，不会报告是否达到CPU上限":                                   "Warning: process CPU usage cannot be sampled on this platform, hitting the CPU cap will not be reported",
+	"CPU使用率 %.0f%% 达到上限 %.0f%%":                                            "CPU usage %.0f%% reached the cap of %.0f%%",
+	"\n========== 资源限制 ==========":                                         "\n========== Resource limits ==========",
+	"GOMAXPROCS=%d, 调度优先级=%d, GC比例=%d":                                     "GOMAXPROCS=%d, nice=%d, GC percent=%d",
+	"CPU使用率(100%%为一个核): 平均 %.0f%%, 峰值 %.0f%%":                              "CPU usage (100%% is one core): average %.0f%%, peak %.0f%%",
+	"CPU上限 %.0f%%: %d/%d 次采样达到上限":                                          "CPU cap %.0f%%: %d/%d samples reached the cap",
+	"警告: 压测工具自身的CPU达到上限(首次在 %s)，吞吐量和耗时可能受工具本身限制而非平台":                       "Warning: the load generator hit its own CPU cap (first at %s), throughput and latency may be limited by the tool rather than the platform",
+	"因CPU限速累计推迟发送: %v":                                                     "Total send delay from CPU throttling: %v",
+	"使用mmap映射设备token文件(仅类Unix系统)":                                          "mmap the device token file (Unix-like systems only)",
+	"重复token的处理方式(dedupe: 去重, error: 报错退出, allow: 保留，用于客户端抢占测试)":           "how to handle duplicate tokens (dedupe: remove, error: exit with an error, allow: keep, for client takeover tests)",
+	"当前平台不支持mmap":                                                          "mmap is not supported on this platform",
+	"token文件中有 %d 个重复token(如 %s)，可使用 --duplicate-tokens=dedupe 去重":         "token file contains %d duplicate tokens (e.g. %s), use --duplicate-tokens=dedupe to remove them",
+	"警告: 保留 %d 个重复token(如 %s)，相同token的设备会互相抢占连接":                           "warning: keeping %d duplicate tokens (e.g. %s), devices sharing a token will keep taking over one another",
+	"已去除 %d 个重复token(如 %s)，剩余 %d 个":                                        "removed %d duplicate tokens (e.g. %s), %d remaining",
+	"警告: %v，改为流式读取token文件":                                                 "warning: %v, streaming the token file instead",
+	"文件为空或不包含有效设备token":                                                    "file is empty or contains no device tokens",
+	"读取文件内容失败: %w":                                                         "failed to read file content: %w",
+	"获取文件信息失败: %w":                                                         "failed to stat file: %w",
+	"映射文件失败: %w":                                                           "failed to mmap file: %w",
+	"测试结束后核对N个抽样设备在telemetry_current_datas中的当前值是否为最后发送的值，0为不核对":            "After the run, check for N sampled devices that the current value in telemetry_current_datas equals the last value sent, 0 to disable",
+	"查询当前值失败: %w":                                                          "Failed to query current value: %w",
+	"解析最后发送的消息失败: %w":                                                      "Failed to parse the last sent message: %w",
+	"设备 %s 数据点 %s: 平台没有当前值, 最后发送 %v":                                       "Device %s data point %s: no current value on the platform, last sent %v",
+	"设备 %s 数据点 %s: 平台当前值 %v, 最后发送 %v":                                      "Device %s data point %s: platform current value %v, last sent %v",
+	"\n========== 当前值核对 ==========":                                        "\n========== Current value check ==========",
+	"当前值核对: %v":        "Current value check: %v",
+	"当前值核对: 设备 %s: %v": "Current value check: device %s: %v",
+	"抽样设备: %d, 核对数据点: %d, 一致 %d, 不一致 %d, 缺失 %d": "Sampled devices: %d, data points checked: %d, matching %d, mismatched %d, missing %d",
+	"不支持的WebSocket地址: %s":                       "Unsupported WebSocket URL: %s",
+	"WebSocket握手失败: %s":                         "WebSocket handshake failed: %s",
+	"WebSocket消息超过大小上限: %d":                     "WebSocket message exceeds size limit: %d",
+	"服务器关闭了WebSocket连接":                         "Server closed the WebSocket connection",
+	"请输入正整数":                                    "please enter a positive integer",
+	"请输入正数":                                     "please enter a positive number",
+	"正在检查%s...":                                 "checking %s...",
+	" 失败: %v\n":                                 " failed: %v\n",
+	"仍然使用该配置?":                                  "keep this configuration anyway?",
+	" 正常":                                       " ok",
+	"警告: 解析已有配置文件失败，使用默认值: %v\n":         "warning: failed to parse existing config file, using defaults: %v\n",
+	"ThingsPanel性能测试配置向导，直接回车使用方括号中的默认值": "ThingsPanel load test setup wizard, press Enter to accept the default in brackets",
+	"\n[1/4] MQTT服务器":                "\n[1/4] MQTT broker",
+	"\n[2/4] 负载参数":                   "\n[2/4] Load",
+	"每台设备每秒发送消息数":                    "messages per second per device",
+	"预计总发送速率: %.1f 消息/秒, %.1f 点/秒\n": "expected total rate: %.1f msgs/s, %.1f points/s\n",
+	"\n[3/4] 数据库":                    "\n[3/4] Database",
+	"数据库服务器地址和端口":                    "database server host and port",
+	"\n[4/4] 租户":                     "\n[4/4] Tenant",
+	"租户ID":                           "tenant ID",
+	"租户":                             "tenant",
+	"配置文件 %s 已存在，是否覆盖?":              "config file %s already exists, overwrite?",
+	"已取消，未写入配置文件":                    "cancelled, config file not written",
+	"生成配置失败: %v\n":                   "failed to generate config: %v\n",
+	"写入配置文件失败: %v\n":                 "failed to write config file: %v\n",
+	"\n配置已写入 %s\n":                   "\nconfig written to %s\n",
+	"下一步:":                           "next steps:",
+	"  1. 创建设备: cd ../create_device && go run . --config %s --tenant %s --count %d\n": "  1. create devices: cd ../create_device && go run . --config %s --tenant %s --count %d\n",
+	"  2. 开始测试: go run .": "  2. start the test: go run .",
+	"  开始测试: go run .":    "  start the test: go run .",
+	" (跳过认证检查: %v)":       " (skipping auth check: %v)",
+	"数据库中不存在telemetry_datas表，请确认是ThingsPanel数据库": "table telemetry_datas not found, is this a ThingsPanel database?",
+	" (该租户已有 %d 个设备)":                            " (tenant already has %d devices)",
+}
@@ -0,0 +1,610 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 本地Broker命令行参数
+var (
+	localBrokerFlag   = flag.Bool("local-broker", false, "在进程内启动MQTT Broker并连接它，不需要外部的Broker和数据库即可验证和测量本工具(调度、数据生成、统计和报告)")
+	localBrokerListen = flag.String("local-broker-listen", "", "本地Broker的监听地址(默认127.0.0.1:0，随机端口)")
+)
+
+// MQTT报文类型(客户端使用的类型见client_lite.go)
+const (
+	packetPubrec      = 0x50
+	packetPubrel      = 0x60
+	packetPubcomp     = 0x70
+	packetSubscribe   = 0x80
+	packetSuback      = 0x90
+	packetUnsubscribe = 0xA0
+	packetUnsuback    = 0xB0
+	packetPingreq     = 0xC0
+	packetPingresp    = 0xD0
+)
+
+// localBrokerTokenPrefix 未提供token文件时生成的设备token前缀
+const localBrokerTokenPrefix = "local-device-"
+
+// localBrokerSettle 核对前等待在途消息到达Broker的最长时间
+const localBrokerSettle = 2 * time.Second
+
+// localBroker 进程内的Broker，未启用时为nil
+var localBroker *embeddedBroker
+
+// localBrokerStats 本地Broker统计
+var localBrokerStats struct {
+	connects  atomic.Uint64    // 连接成功次数
+	connected atomic.Int64     // 当前连接数
+	peak      atomic.Int64     // 最大同时连接数
+	takeovers atomic.Uint64    // 相同客户端ID的新连接踢下旧连接的次数
+	received  [3]atomic.Uint64 // 按QoS收到的PUBLISH数
+	telemetry atomic.Uint64    // 收到的遥测主题消息数
+	baseline  atomic.Uint64    // 测试开始前(启动前检查)收到的遥测主题消息数
+	bytes     atomic.Uint64    // 收到的消息内容字节数
+	delivered atomic.Uint64    // 投递给订阅者的消息数
+	subscribe atomic.Uint64    // 订阅的主题数
+}
+
+// localBrokerEnabled 是否使用本地Broker
+func localBrokerEnabled() bool {
+	return AppConfig.LocalBroker.Enabled
+}
+
+// embeddedBroker 最小的MQTT 3.1.1/5 Broker，用于在没有外部环境时验证和测量本工具
+//
+// 接受任何客户端ID和用户名，支持QoS0/1/2发布、通配符订阅和共享订阅($share/组名/主题)。
+// 消息一律以QoS0投递给订阅者(SUBACK授予QoS0)，不保存会话、保留消息和遗嘱。
+// 它测量的是工具本身的上限：Broker和设备在同一进程中竞争CPU，结果不代表平台的性能。
+type embeddedBroker struct {
+	ln net.Listener
+
+	mu       sync.RWMutex
+	sessions map[string]*brokerSession // 客户端ID -> 当前连接
+	exact    map[string][]*brokerSub   // 不含通配符的订阅，按主题索引
+	wildcard []*brokerSub              // 含通配符的订阅
+	shareRR  atomic.Uint64             // 共享订阅轮流投递的计数
+
+	telemetry []telemetryPattern // 设备发布遥测数据的主题(mqtt.topic和各租户分组的主题)
+}
+
+// brokerSession Broker端的一个客户端连接
+type brokerSession struct {
+	conn     net.Conn
+	clientID string
+	version  byte       // 协议级别(4: 3.1.1, 5: MQTT 5)
+	mu       sync.Mutex // 保护写入，投递来自其他连接的goroutine
+	wbuf     []byte
+	subs     []*brokerSub
+}
+
+// brokerSub 一个订阅
+type brokerSub struct {
+	session *brokerSession
+	filter  string
+	group   string // 共享订阅的组名，普通订阅为空
+}
+
+// startLocalBroker 在listen地址上启动本地Broker
+func startLocalBroker(listen string) (*embeddedBroker, error) {
+	ln, err := net.Listen("tcp", listen)
+	if err != nil {
+		return nil, fmt.Errorf(T("启动本地Broker失败: %w"), err)
+	}
+	b := &embeddedBroker{
+		ln:       ln,
+		sessions: make(map[string]*brokerSession),
+		exact:    make(map[string][]*brokerSub),
+	}
+	b.telemetry = append(b.telemetry, newTelemetryPattern(AppConfig.MQTT.Topic))
+	for _, g := range tenantGroups {
+		b.telemetry = append(b.telemetry, newTelemetryPattern(g.Topic))
+	}
+	go b.serve()
+	return b, nil
+}
+
+// Addr 实际监听的地址(host:port)
+func (b *embeddedBroker) Addr() string {
+	return b.ln.Addr().String()
+}
+
+// Close 停止接受新连接并断开全部客户端
+func (b *embeddedBroker) Close() {
+	b.ln.Close()
+	b.mu.Lock()
+	for _, s := range b.sessions {
+		s.conn.Close()
+	}
+	b.mu.Unlock()
+}
+
+func (b *embeddedBroker) serve() {
+	for {
+		conn, err := b.ln.Accept()
+		if err != nil {
+			return
+		}
+		go b.handle(conn)
+	}
+}
+
+// handle 处理一个连接：CONNECT之后循环读取报文，直到连接断开
+func (b *embeddedBroker) handle(conn net.Conn) {
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(liteIOTimeout))
+	header, body, err := readPacket(conn, nil)
+	if err != nil || header != packetConnect {
+		return
+	}
+	s, err := parseConnect(conn, body)
+	if err != nil {
+		return
+	}
+	if s.version == 5 {
+		s.write(packetConnack, []byte{0, 0, 0})
+	} else {
+		s.write(packetConnack, []byte{0, 0})
+	}
+	conn.SetReadDeadline(time.Time{})
+	b.register(s)
+	defer b.unregister(s)
+
+	var buf []byte
+	for {
+		header, body, err := readPacket(conn, buf)
+		if err != nil {
+			return
+		}
+		buf = body[:0]
+		switch header & 0xF0 {
+		case packetPublish:
+			if err := b.publish(s, header, body); err != nil {
+				return
+			}
+		case packetPubrel:
+			if len(body) >= 2 {
+				s.write(packetPubcomp, body[:2])
+			}
+		case packetSubscribe:
+			if err := b.subscribe(s, body); err != nil {
+				return
+			}
+		case packetUnsubscribe:
+			if err := b.unsubscribe(s, body); err != nil {
+				return
+			}
+		case packetPingreq:
+			s.write(packetPingresp, nil)
+		case packetDisconnect:
+			return
+		}
+	}
+}
+
+// parseConnect 解析CONNECT报文，只取出协议级别和客户端ID
+func parseConnect(conn net.Conn, body []byte) (*brokerSession, error) {
+	name, rest, ok := readBrokerString(body)
+	if !ok || name != "MQTT" && name != "MQIsdp" || len(rest) < 4 {
+		return nil, errors.New(T("无效的CONNECT报文"))
+	}
+	s := &brokerSession{conn: conn, version: rest[0]}
+	rest = rest[4:] // 协议级别、连接标志、keepalive
+	if s.version == 5 {
+		if rest, ok = skipProperties(rest); !ok {
+			return nil, errors.New(T("无效的CONNECT报文"))
+		}
+	}
+	if s.clientID, _, ok = readBrokerString(rest); !ok {
+		return nil, errors.New(T("无效的CONNECT报文"))
+	}
+	if s.clientID == "" {
+		s.clientID = conn.RemoteAddr().String()
+	}
+	return s, nil
+}
+
+// register 登记连接，相同客户端ID的旧连接被断开(会话接管)
+func (b *embeddedBroker) register(s *brokerSession) {
+	b.mu.Lock()
+	old := b.sessions[s.clientID]
+	b.sessions[s.clientID] = s
+	b.mu.Unlock()
+	if old != nil {
+		localBrokerStats.takeovers.Add(1)
+		old.conn.Close()
+	}
+	localBrokerStats.connects.Add(1)
+	n := localBrokerStats.connected.Add(1)
+	for {
+		peak := localBrokerStats.peak.Load()
+		if n <= peak || localBrokerStats.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+}
+
+// unregister 连接断开后移除它的登记和全部订阅
+func (b *embeddedBroker) unregister(s *brokerSession) {
+	b.mu.Lock()
+	if b.sessions[s.clientID] == s {
+		delete(b.sessions, s.clientID)
+	}
+	for _, sub := range s.subs {
+		b.removeSub(sub)
+	}
+	b.mu.Unlock()
+	localBrokerStats.connected.Add(-1)
+}
+
+// publish 处理PUBLISH：确认后投递给匹配的订阅者
+func (b *embeddedBroker) publish(s *brokerSession, header byte, body []byte) error {
+	qos := header >> 1 & 3
+	topic, rest, ok := readBrokerString(body)
+	if !ok || qos > 2 {
+		return errors.New(T("无效的PUBLISH报文"))
+	}
+	var packetID []byte
+	if qos > 0 {
+		if len(rest) < 2 {
+			return errors.New(T("无效的PUBLISH报文"))
+		}
+		packetID, rest = rest[:2], rest[2:]
+	}
+	if s.version == 5 {
+		if rest, ok = skipProperties(rest); !ok {
+			return errors.New(T("无效的PUBLISH报文"))
+		}
+	}
+
+	localBrokerStats.received[qos].Add(1)
+	localBrokerStats.bytes.Add(uint64(len(rest)))
+	if b.isTelemetry(topic) {
+		localBrokerStats.telemetry.Add(1)
+	}
+	b.deliver(topic, rest)
+
+	switch qos {
+	case 1:
+		s.write(packetPuback, packetID)
+	case 2:
+		s.write(packetPubrec, packetID)
+	}
+	return nil
+}
+
+// isTelemetry 主题是否为设备发布遥测数据的主题
+func (b *embeddedBroker) isTelemetry(topic string) bool {
+	for _, p := range b.telemetry {
+		if p.matches(topic) {
+			return true
+		}
+	}
+	return false
+}
+
+// deliver 以QoS0把消息投递给匹配的订阅者，共享订阅的每个组只投递给其中一个
+func (b *embeddedBroker) deliver(topic string, payload []byte) {
+	b.mu.RLock()
+	var matched []*brokerSub
+	matched = append(matched, b.exact[topic]...)
+	for _, sub := range b.wildcard {
+		if topicMatches(sub.filter, topic) {
+			matched = append(matched, sub)
+		}
+	}
+	b.mu.RUnlock()
+	if len(matched) == 0 {
+		return
+	}
+
+	var groups map[string][]*brokerSub
+	for _, sub := range matched {
+		if sub.group == "" {
+			sub.session.deliver(topic, payload)
+			continue
+		}
+		if groups == nil {
+			groups = make(map[string][]*brokerSub)
+		}
+		groups[sub.group] = append(groups[sub.group], sub)
+	}
+	for _, members := range groups {
+		members[(b.shareRR.Add(1)-1)%uint64(len(members))].session.deliver(topic, payload)
+	}
+}
+
+// subscribe 处理SUBSCRIBE，每个主题都授予QoS0
+func (b *embeddedBroker) subscribe(s *brokerSession, body []byte) error {
+	if len(body) < 2 {
+		return errors.New(T("无效的SUBSCRIBE报文"))
+	}
+	ack := append([]byte(nil), body[:2]...)
+	rest := body[2:]
+	ok := true
+	if s.version == 5 {
+		ack = append(ack, 0)
+		if rest, ok = skipProperties(rest); !ok {
+			return errors.New(T("无效的SUBSCRIBE报文"))
+		}
+	}
+	for len(rest) > 0 {
+		var filter string
+		if filter, rest, ok = readBrokerString(rest); !ok || len(rest) < 1 {
+			return errors.New(T("无效的SUBSCRIBE报文"))
+		}
+		rest = rest[1:] // 订阅选项
+		sub := &brokerSub{session: s, filter: filter}
+		if strings.HasPrefix(filter, "$share/") {
+			group, shared, found := strings.Cut(strings.TrimPrefix(filter, "$share/"), "/")
+			if !found || group == "" {
+				ack = append(ack, subackFailure)
+				continue
+			}
+			sub.group, sub.filter = group, shared
+		}
+		b.mu.Lock()
+		s.subs = append(s.subs, sub)
+		if strings.ContainsAny(sub.filter, "+#") {
+			b.wildcard = append(b.wildcard, sub)
+		} else {
+			b.exact[sub.filter] = append(b.exact[sub.filter], sub)
+		}
+		b.mu.Unlock()
+		localBrokerStats.subscribe.Add(1)
+		ack = append(ack, 0)
+	}
+	s.write(packetSuback, ack)
+	return nil
+}
+
+// unsubscribe 处理UNSUBSCRIBE
+func (b *embeddedBroker) unsubscribe(s *brokerSession, body []byte) error {
+	if len(body) < 2 {
+		return errors.New(T("无效的UNSUBSCRIBE报文"))
+	}
+	ack := append([]byte(nil), body[:2]...)
+	rest := body[2:]
+	ok := true
+	if s.version == 5 {
+		ack = append(ack, 0)
+		if rest, ok = skipProperties(rest); !ok {
+			return errors.New(T("无效的UNSUBSCRIBE报文"))
+		}
+	}
+	for len(rest) > 0 {
+		var filter string
+		if filter, rest, ok = readBrokerString(rest); !ok {
+			return errors.New(T("无效的UNSUBSCRIBE报文"))
+		}
+		b.mu.Lock()
+		s.subs = removeSubs(s.subs, func(sub *brokerSub) bool {
+			if sub.filter != filter && "$share/"+sub.group+"/"+sub.filter != filter {
+				return false
+			}
+			b.removeSub(sub)
+			return true
+		})
+		b.mu.Unlock()
+		if s.version == 5 {
+			ack = append(ack, 0)
+		}
+	}
+	s.write(packetUnsuback, ack)
+	return nil
+}
+
+// removeSub 从索引中移除订阅，调用时持有b.mu
+func (b *embeddedBroker) removeSub(sub *brokerSub) {
+	same := func(other *brokerSub) bool { return other == sub }
+	if subs, ok := b.exact[sub.filter]; ok {
+		if subs = removeSubs(subs, same); len(subs) == 0 {
+			delete(b.exact, sub.filter)
+		} else {
+			b.exact[sub.filter] = subs
+		}
+	}
+	b.wildcard = removeSubs(b.wildcard, same)
+}
+
+// removeSubs 返回去掉满足条件的订阅后的新切片，不修改原切片(投递可能正在读取)
+func removeSubs(subs []*brokerSub, remove func(*brokerSub) bool) []*brokerSub {
+	kept := make([]*brokerSub, 0, len(subs))
+	for _, sub := range subs {
+		if !remove(sub) {
+			kept = append(kept, sub)
+		}
+	}
+	return kept
+}
+
+// write 发送一个报文，写入失败时关闭连接(读取循环随之结束)
+func (s *brokerSession) write(header byte, body []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.wbuf = appendPacket(s.wbuf[:0], header, body)
+	s.conn.SetWriteDeadline(time.Now().Add(liteIOTimeout))
+	if _, err := s.conn.Write(s.wbuf); err != nil {
+		s.conn.Close()
+	}
+}
+
+// deliver 以QoS0向订阅者发送一条消息
+func (s *brokerSession) deliver(topic string, payload []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	remaining := 2 + len(topic) + len(payload)
+	if s.version == 5 {
+		remaining++
+	}
+	s.wbuf = append(s.wbuf[:0], packetPublish)
+	s.wbuf = appendRemainingLength(s.wbuf, remaining)
+	s.wbuf = appendMQTTString(s.wbuf, topic)
+	if s.version == 5 {
+		s.wbuf = append(s.wbuf, 0)
+	}
+	s.wbuf = append(s.wbuf, payload...)
+	s.conn.SetWriteDeadline(time.Now().Add(liteIOTimeout))
+	if _, err := s.conn.Write(s.wbuf); err != nil {
+		s.conn.Close()
+		return
+	}
+	localBrokerStats.delivered.Add(1)
+}
+
+// readBrokerString 读取带2字节长度前缀的字符串
+func readBrokerString(b []byte) (string, []byte, bool) {
+	if len(b) < 2 {
+		return "", nil, false
+	}
+	n := int(binary.BigEndian.Uint16(b))
+	if len(b) < 2+n {
+		return "", nil, false
+	}
+	return string(b[2 : 2+n]), b[2+n:], true
+}
+
+// skipProperties 跳过MQTT 5的属性(变长编码的属性长度+属性)
+func skipProperties(b []byte) ([]byte, bool) {
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 || i >= len(b) {
+			return nil, false
+		}
+		length += int(b[i]&0x7f) * multiplier
+		if b[i]&0x80 == 0 {
+			b = b[i+1:]
+			break
+		}
+		multiplier *= 128
+	}
+	if len(b) < length {
+		return nil, false
+	}
+	return b[length:], true
+}
+
+// topicMatches 主题是否匹配订阅的主题过滤器(+匹配一层，#匹配其余各层)
+func topicMatches(filter, topic string) bool {
+	// 通配符不匹配以$开头的系统主题
+	if topic != "" && topic[0] == '$' && filter != "" && (filter[0] == '+' || filter[0] == '#') {
+		return false
+	}
+	for {
+		level, filterRest, more := strings.Cut(filter, "/")
+		if level == "#" {
+			return true
+		}
+		topicLevel, topicRest, topicMore := strings.Cut(topic, "/")
+		if level != "+" && level != topicLevel {
+			return false
+		}
+		if !more || !topicMore {
+			// "a/#" 也匹配 "a"
+			return more == topicMore || more && filterRest == "#"
+		}
+		filter, topic = filterRest, topicRest
+	}
+}
+
+// telemetryPattern 遥测主题模板，租户分组的主题中{username}替换为设备token
+type telemetryPattern struct {
+	parts []string // 以{username}分隔的固定部分
+	fixed int      // 固定部分的总长度
+}
+
+func newTelemetryPattern(template string) telemetryPattern {
+	p := telemetryPattern{parts: strings.Split(template, "{username}")}
+	for _, part := range p.parts {
+		p.fixed += len(part)
+	}
+	return p
+}
+
+// matches 主题是否为模板中的{username}全部替换为同一个非空token的结果
+func (p telemetryPattern) matches(topic string) bool {
+	k := len(p.parts) - 1
+	if k == 0 {
+		return topic == p.parts[0]
+	}
+	// 各处{username}相同，token的长度由主题长度确定
+	n := len(topic) - p.fixed
+	if n < k || n%k != 0 {
+		return false
+	}
+	n /= k
+	var username string
+	for i, part := range p.parts {
+		if !strings.HasPrefix(topic, part) {
+			return false
+		}
+		topic = topic[len(part):]
+		if i == k {
+			break
+		}
+		if i == 0 {
+			username = topic[:n]
+		} else if topic[:n] != username {
+			return false
+		}
+		topic = topic[n:]
+	}
+	return topic == ""
+}
+
+// localBrokerTokens 生成n个设备token，本地Broker不校验设备，没有token文件时使用
+func localBrokerTokens(n int) *TokenList {
+	var buf strings.Builder
+	offsets := make([]uint32, 0, 2*n)
+	for i := 1; i <= n; i++ {
+		offsets = append(offsets, uint32(buf.Len()))
+		fmt.Fprintf(&buf, "%s%06d", localBrokerTokenPrefix, i)
+		offsets = append(offsets, uint32(buf.Len()))
+	}
+	return &TokenList{data: buf.String(), offsets: offsets}
+}
+
+// localBrokerTelemetry 测试开始后Broker收到的遥测消息数
+func localBrokerTelemetry() uint64 {
+	return localBrokerStats.telemetry.Load() - localBrokerStats.baseline.Load()
+}
+
+// reportLocalBroker 输出本地Broker统计，并与工具统计的发送成功数核对(未启用时不输出)
+//
+// QoS1/2消息只有Broker收到后才会被确认，两者应当一致；QoS0消息在连接断开时可能丢失，
+// Broker收到的可能少于工具统计的数量。HTTP设备的消息不经过Broker。
+func reportLocalBroker(sent uint64, duration time.Duration) {
+	if !localBrokerEnabled() {
+		return
+	}
+	s := &localBrokerStats
+	// QoS0消息发送完成时可能仍在Broker的读取缓冲区中，等待计数稳定
+	telemetry := localBrokerTelemetry()
+	for deadline := time.Now().Add(localBrokerSettle); telemetry < sent && time.Now().Before(deadline); {
+		time.Sleep(localBrokerSettle / 20)
+		telemetry = localBrokerTelemetry()
+	}
+	log.Println(T("\n========== 本地Broker =========="))
+	log.Printf(T("地址: %s, 连接 %d 次, 最大同时连接 %d, 会话接管 %d 次"),
+		localBroker.Addr(), s.connects.Load(), s.peak.Load(), s.takeovers.Load())
+	log.Printf(T("收到消息: QoS0 %d 条, QoS1 %d 条, QoS2 %d 条, 共 %.1f KB"),
+		s.received[0].Load(), s.received[1].Load(), s.received[2].Load(), float64(s.bytes.Load())/1024)
+	log.Printf(T("遥测消息: Broker收到 %d 条 (%.1f 条/秒), 工具统计发送成功 %d 条"),
+		telemetry, ratePerSecond(float64(telemetry), duration), sent)
+	if n := s.subscribe.Load(); n > 0 {
+		log.Printf(T("订阅主题 %d 个, 投递给订阅者 %d 条"), n, s.delivered.Load())
+	}
+	if telemetry != sent && !httpDevicesEnabled() {
+		log.Printf(T("警告: Broker收到的遥测消息数与发送成功数相差 %d 条"), int64(sent)-int64(telemetry))
+	}
+	log.Println("===============================")
+}
@@ -0,0 +1,66 @@
+package main
+
+import "testing"
+
+func TestTelemetryPattern(t *testing.T) {
+	tests := []struct {
+		template, topic string
+		want            bool
+	}{
+		{"devices/telemetry", "devices/telemetry", true},
+		{"devices/telemetry", "devices/telemetry/x", false},
+		{"tenants/a/{username}/telemetry", "tenants/a/token-1/telemetry", true},
+		{"tenants/a/{username}/telemetry", "tenants/a//telemetry", false},
+		{"tenants/a/{username}/telemetry", "tenants/b/token-1/telemetry", false},
+		{"{username}/up/{username}", "abc/up/abc", true},
+		{"{username}/up/{username}", "abc/up/abd", false},
+		{"{username}/up/{username}", "abc/up/abcd", false},
+	}
+	for _, tt := range tests {
+		if got := newTelemetryPattern(tt.template).matches(tt.topic); got != tt.want {
+			t.Errorf("%q matches %q = %v, want %v", tt.template, tt.topic, got, tt.want)
+		}
+	}
+}
+
+func TestLocalBrokerLiteClient(t *testing.T) {
+	savedTopic, savedVersion, savedGroups := AppConfig.MQTT.Topic, AppConfig.MQTT.Version, tenantGroups
+	defer func() {
+		AppConfig.MQTT.Topic, AppConfig.MQTT.Version, tenantGroups = savedTopic, savedVersion, savedGroups
+	}()
+
+	AppConfig.MQTT.Topic = "devices/telemetry"
+	AppConfig.MQTT.Version = 4
+	tenantGroups = []*tenantGroup{{TenantGroupConfig: TenantGroupConfig{Name: "a", Topic: "tenants/a/{username}/telemetry"}}}
+
+	b, err := startLocalBroker("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	username := localBrokerTokenPrefix + "1"
+	c := newLiteClient("client-1", username, "tcp://"+b.Addr(), &DeviceStats{Username: username})
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Disconnect()
+
+	telemetry, received := localBrokerStats.telemetry.Load(), localBrokerStats.received[1].Load()
+	// QoS1发布在收到PUBACK后返回，此时Broker已经计数
+	for _, topic := range []string{
+		AppConfig.MQTT.Topic,
+		tenantGroups[0].telemetryTopic(username),
+		"devices/attributes",
+	} {
+		if err := c.Publish(topic, 1, []byte(`{"temperature":25}`)); err != nil {
+			t.Fatalf("publish %s: %v", topic, err)
+		}
+	}
+	if got := localBrokerStats.received[1].Load() - received; got != 3 {
+		t.Errorf("received = %d, want 3", got)
+	}
+	if got := localBrokerStats.telemetry.Load() - telemetry; got != 2 {
+		t.Errorf("telemetry = %d, want 2", got)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// 日志轮转与磁盘空间保护命令行参数
+var (
+	logMaxSize  = flag.Int("log-max-size", 0, "run.log单个文件大小上限(MB)，超过后轮转为run.log.1等(默认100)")
+	minFreeDisk = flag.Int("min-free-disk", 0, "运行目录所在磁盘剩余空间(MB)低于该值时停止测试并输出报告(默认500)，-1为不检查")
+)
+
+// diskCheckInterval 检查磁盘剩余空间的间隔
+const diskCheckInterval = 10 * time.Second
+
+// rotatingWriter 按大小轮转的日志文件，当前文件写满后依次重命名为 .1、.2…，超出保留数量的最旧文件被删除
+type rotatingWriter struct {
+	mu       sync.Mutex
+	path     string
+	maxSize  int64
+	maxFiles int
+	file     *os.File
+	size     int64
+}
+
+// newRotatingWriter 以追加方式打开日志文件
+func newRotatingWriter(path string, maxSize int64, maxFiles int) (*rotatingWriter, error) {
+	w := &rotatingWriter{path: path, maxSize: maxSize, maxFiles: maxFiles}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// open 打开(或创建)当前日志文件，调用方持有锁或尚未共享
+func (w *rotatingWriter) open() error {
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.file, w.size = f, info.Size()
+	return nil
+}
+
+// Write 写入一条日志，写入后超过大小上限时轮转。轮转失败时继续写当前文件，不丢日志
+func (w *rotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	if w.maxSize > 0 && w.size >= w.maxSize {
+		if rerr := w.rotate(); rerr != nil {
+			fmt.Fprintf(os.Stderr, T("日志轮转失败: %v")+"\n", rerr)
+		}
+	}
+	return n, err
+}
+
+// rotate 关闭当前文件并依次后移编号，调用方持有锁
+func (w *rotatingWriter) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	os.Remove(fmt.Sprintf("%s.%d", w.path, w.maxFiles))
+	for i := w.maxFiles - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", w.path, i), fmt.Sprintf("%s.%d", w.path, i+1))
+	}
+	if w.maxFiles > 0 {
+		if err := os.Rename(w.path, w.path+".1"); err != nil {
+			return err
+		}
+	} else {
+		os.Remove(w.path)
+	}
+	return w.open()
+}
+
+// watchDiskSpace 定期检查运行目录所在磁盘的剩余空间，低于阈值时停止测试
+//
+// 多日长稳测试曾写满磁盘导致发生器在运行中崩溃，报告和检查点都没能保存。提前停止
+// 仍会留出足够空间写入汇总、HTML报告和设备统计。
+func watchDiskSpace(ctx context.Context, cancel context.CancelFunc) {
+	limit := uint64(AppConfig.Output.MinFreeDisk) << 20
+	if AppConfig.Output.MinFreeDisk <= 0 || runDir == "" {
+		return
+	}
+	if _, err := diskFree(runDir); err != nil {
+		log.Printf(T("警告: 无法获取磁盘剩余空间，不检查磁盘空间: %v"), err)
+		return
+	}
+
+	ticker := time.NewTicker(diskCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			free, err := diskFree(runDir)
+			if err != nil || free >= limit {
+				continue
+			}
+			abortTest(cancel, fmt.Sprintf(T("运行目录 %s 所在磁盘剩余空间 %d MB 低于 %d MB，请清理磁盘或调整 output.min_free_disk"),
+				runDir, free>>20, AppConfig.Output.MinFreeDisk))
+			return
+		}
+	}
+}
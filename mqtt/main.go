@@ -1,35 +1,36 @@
 package main
 
 import (
-	"bufio"
 	"context"
-	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"runtime"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/brianvoe/gofakeit/v7"
 	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/go-basic/uuid"
 )
 
-// SensorData 表示设备上报的传感器数据结构（使用动态map）
-type SensorData map[string]float64
-
-// 全局计数变量
+// 全局状态变量(计数见runStats)
 var (
-	successNum uint64        // 成功连接的设备数
-	dataCount  uint64        // 已发送的数据点数
-	msgCount   uint64        // 已发送的消息数
-	exitCount  uint64        // 已退出的goroutine数
-	startChan  chan struct{} // 同步开始信号
+	startChan chan struct{} // 同步开始信号
+
+	dbWrittenCount int64 // 监控模块统计的累计入库数据点数
+
+	publishLatency Histogram // 发布耗时(QoS>0时为确认耗时)
 
 	// 添加第一次发送数据的时间记录
-	firstSendTime atomic.Value // 记录第一次发送数据的时间点
+	firstSendTime atomic.Value // 记录第一次发送数据的时间点(*time.Time，保留单调时钟读数)
 )
 
 // 命令行参数定义(保留以支持命令行配置)
@@ -54,27 +55,176 @@ var (
 )
 
 func init() {
-	// 初始化随机数生成器
-	gofakeit.Seed(time.Now().UnixNano())
+	// Windows控制台按UTF-8输出中文日志
+	setupConsole()
 
 	// 设置MQTT日志
-	mqtt.ERROR = log.New(os.Stderr, "[MQTT ERROR] ", log.LstdFlags)
+	mqtt.ERROR = pahoErrorLogger{prefix: "[MQTT ERROR] "}
+}
+
+// subcommand 识别并移除命令行中的子命令，返回子命令名称（无则为空）
+func subcommand() string {
+	if len(os.Args) < 2 {
+		return ""
+	}
+	switch os.Args[1] {
+	case "history", "init", "acl", "preview", "calibrate", "cron", "merge":
+		cmd := os.Args[1]
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+		return cmd
+	}
+	return ""
 }
 
 func main() {
+	cmd := subcommand()
+
+	// 配置向导在加载配置前运行，避免缺少配置文件时的校验报错
+	if cmd == "init" {
+		translateFlagUsage()
+		flag.Parse()
+		runInit()
+		return
+	}
+
+	// 合并各worker的汇总不需要配置文件和测试环境
+	if cmd == "merge" {
+		translateFlagUsage()
+		flag.Parse()
+		runMerge()
+		return
+	}
+
 	// 加载配置
 	LoadConfig()
 
-	log.Println("性能测试开始")
-	log.Printf("配置信息: 设备数=%d, 间隔时间=%v, 循环次数=%d",
+	if cmd == "history" {
+		runHistory()
+		return
+	}
+	if cmd == "acl" {
+		runACLCheck()
+		return
+	}
+	if cmd == "preview" {
+		runPreview()
+		return
+	}
+	if cmd == "calibrate" {
+		runCalibrate()
+		return
+	}
+	if cmd == "cron" {
+		runCron()
+		return
+	}
+
+	// 生成本次运行ID
+	runID := uuid.New()[:8]
+	if checkpointEnabled() {
+		runID = resumeSoak(runID)
+	}
+	log.Printf(T("运行ID: %s"), runID)
+	alertMonitor.runID = runID
+
+	// 本次运行的日志、汇总和统计文件都写入独立目录，避免多次运行互相覆盖
+	if err := setupRunDir(runID, time.Now()); err != nil {
+		log.Printf(T("警告: %v，产物将写入当前目录"), err)
+	} else {
+		log.Printf(T("运行目录: %s"), runDir)
+	}
+	startControlServer()
+
+	log.Println(T("性能测试开始"))
+	log.Printf(T("配置信息: 设备数=%d, 间隔时间=%v, 循环次数=%d"),
 		AppConfig.Device.ClientNumber,
 		AppConfig.Test.DataInterval,
 		AppConfig.Test.CycleCount)
 
-	// 从文件中读取设备token
-	tokenLines, err := readFile(AppConfig.Device.TokenFile)
+	// 与其他服务共用机器时限制本进程的资源占用
+	applyThrottle()
+
+	// 检测并放宽系统连接数限制
+	tuneOSLimits()
+
+	// 从文件中读取设备token(只读取需要的数量，重复token不计入，需要筛选时读取全部)
+	tokenLimit := AppConfig.Device.ClientNumber
+	if AppConfig.Device.Filter != "" {
+		tokenLimit = 0
+	}
+	var tokens *TokenList
+	var err error
+	if tenantGroupsEnabled() {
+		tokens, err = loadTenantGroupTokens()
+	} else if provisionEnabled() {
+		// 动态注册模式：token为设备编号，连接时再注册获取凭证
+		tokens = provisionTokens(AppConfig.Device.ClientNumber)
+	} else {
+		tokens, err = loadTokens(AppConfig.Device.TokenFile, tokenLimit, AppConfig.Device.TokenMmap)
+	}
+	if err != nil && !tenantGroupsEnabled() && (localBrokerEnabled() || sinkEnabled()) && errors.Is(err, os.ErrNotExist) {
+		// 本地Broker和本地接收端不校验设备，没有token文件时按设备数生成
+		log.Printf(T("token文件 %s 不存在，生成 %d 个设备token"), AppConfig.Device.TokenFile, AppConfig.Device.ClientNumber)
+		tokens, err = localBrokerTokens(AppConfig.Device.ClientNumber), nil
+	}
 	if err != nil {
-		log.Fatalf("读取设备token文件失败: %v", err)
+		log.Fatalf(T("读取设备token文件失败: %v"), err)
+	}
+	if err := applyDuplicatePolicy(tokens); err != nil {
+		log.Fatalf("%v", err)
+	}
+	if AppConfig.Device.Filter != "" {
+		if err := filterTokens(tokens, AppConfig.Device.Filter); err != nil {
+			log.Fatalf("%v", err)
+		}
+		tokens.truncate(AppConfig.Device.ClientNumber)
+	}
+
+	if err := initTLS(); err != nil {
+		log.Fatalf("%v", err)
+	}
+	if err := initResolve(); err != nil {
+		log.Fatalf("%v", err)
+	}
+	if err := initStrings(); err != nil {
+		log.Fatalf("%v", err)
+	}
+	if err := initGPS(); err != nil {
+		log.Fatalf("%v", err)
+	}
+	if err := initAttrSet(tokens); err != nil {
+		log.Fatalf("%v", err)
+	}
+	if err := initCommandFlood(tokens); err != nil {
+		log.Fatalf("%v", err)
+	}
+	if err := initHistoryQuery(tokens); err != nil {
+		log.Fatalf("%v", err)
+	}
+	if err := initTenantLimit(tokens); err != nil {
+		log.Fatalf("%v", err)
+	}
+	if err := initCredRotation(tokens); err != nil {
+		log.Fatalf("%v", err)
+	}
+	if err := initFleetShrink(tokens); err != nil {
+		log.Fatalf("%v", err)
+	}
+	if err := initFleetGrowth(tokens); err != nil {
+		log.Fatalf("%v", err)
+	}
+	if err := openSink(); err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	// 启动前检查，避免配置错误时产生大量重复错误
+	if !*skipPreflight && !runPreflight(tokens) {
+		os.Exit(1)
+	}
+
+	// 认证缓存预热，之后的连接测量的是热认证性能
+	if AppConfig.Prime.Enabled {
+		primeAuthCache(tokens)
 	}
 
 	// 初始化通道
@@ -83,10 +233,25 @@ func main() {
 	// 初始化firstSendTime为nil表示尚未发送数据
 	firstSendTime.Store((*time.Time)(nil))
 
+	// 捕获中断信号，收到后提前结束测试并输出结果
+	// (Windows下Ctrl+C对应os.Interrupt，关闭控制台窗口对应SIGTERM)
+	stopCtx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
 	// 创建上下文，用于控制所有设备goroutine的生命周期
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(stopCtx)
 	defer cancel() // 确保在main函数退出时取消所有goroutine
 
+	if *annotateStdin {
+		go readStdinAnnotations(ctx)
+	}
+
+	// 相同的错误按间隔汇总输出，避免大量设备同时失败时刷屏
+	go runErrorSummary(ctx)
+
+	// 守护模式：SIGHUP重新加载配置，记录状态接口使用的运行信息
+	startDaemon(ctx, runID)
+
 	// 启动监控日志，并等待其初始化完成
 	monitorInitDone := make(chan struct{})
 	go func() {
@@ -97,182 +262,447 @@ func main() {
 	// 等待监控初始化完成或超时
 	select {
 	case <-monitorInitDone:
-		log.Println("监控模块初始化完成，开始进行测试...")
+		log.Println(T("监控模块初始化完成，开始进行测试..."))
 	case <-time.After(10 * time.Second):
-		log.Println("警告: 监控模块初始化超时，继续进行测试...")
+		log.Println(T("警告: 监控模块初始化超时，继续进行测试..."))
 	}
 
 	// 创建等待组，用于等待所有设备goroutine完成
 	var wg sync.WaitGroup
-	log.Printf("可用设备数量: %d", len(tokenLines))
+	log.Printf(T("可用设备数量: %d"), tokens.Len())
 
 	// 启动设备连接，每个设备一个goroutine
-	availableDevices := len(tokenLines)
+	availableDevices := tokens.Len()
 	if availableDevices < AppConfig.Device.ClientNumber {
-		log.Printf("警告: 可用设备数量(%d)少于请求数量(%d)", availableDevices, AppConfig.Device.ClientNumber)
+		log.Printf(T("警告: 可用设备数量(%d)少于请求数量(%d)"), availableDevices, AppConfig.Device.ClientNumber)
 		AppConfig.Device.ClientNumber = availableDevices
 	}
 
+	// 每设备统计(未启用时为nil)
+	deviceStats := newDeviceStats(tokens, AppConfig.Device.ClientNumber)
+
+	// 平台消费者先于设备订阅，避免漏收首批消息
+	consumers := startConsumers(runID)
+	defer stopConsumers(consumers)
+
+	// WebSocket看板和设备在同一时间线上运行，先于设备连接以观察完整的推送过程
+	if dashboardsEnabled() {
+		annotate("stage", fmt.Sprintf(T("启动 %d 个WebSocket看板"), AppConfig.Dashboards.Count))
+	}
+	dashboards := startDashboards(ctx, tokens)
+
+	connectLatency.markStart(time.Now())
+	annotate("stage", fmt.Sprintf(T("开始连接 %d 个设备"), AppConfig.Device.ClientNumber))
+	setPhase(phaseConnecting)
 	for i := 0; i < AppConfig.Device.ClientNumber; i++ {
 		wg.Add(1)
-		go connectAndPublish(&wg, ctx, tokenLines[i])
+		var stats *DeviceStats
+		if deviceStats != nil {
+			stats = deviceStats[i]
+		}
+		qos := deviceQoS(i)
+		atomic.AddUint64(&qosStats[qos].Devices, 1)
+		heartbeat := heartbeatDevice(i)
+		if heartbeat {
+			heartbeatDevices.Add(1)
+		}
+		go connectAndPublish(&wg, ctx, i, tokens.At(i), qos, heartbeat, stats, newDeviceFaker(i))
 	}
 
 	// 等待设备连接完成
 	time.Sleep(AppConfig.Test.ConnectWaitTime)
 
-	connectedDevices := atomic.LoadUint64(&successNum)
-	log.Printf("成功连接设备数: %d (%.1f%%)", connectedDevices, float64(connectedDevices)*100/float64(AppConfig.Device.ClientNumber))
+	connectedDevices := runStats.Connected.Load()
+	log.Printf(T("成功连接设备数: %d (%.1f%%)"), connectedDevices, float64(connectedDevices)*100/float64(AppConfig.Device.ClientNumber))
+	connectLatency.Report()
+
+	// 连接失败率超出预算时直接进入结果汇总
+	checkConnectBudget(cancel)
+
+	// 订阅被拒绝时立即终止，避免在收不到下行消息的情况下继续测试
+	if !reportSubscriptions() {
+		log.Println(T("存在被拒绝的订阅，测试终止"))
+		cancel()
+		wg.Wait()
+		return
+	}
 
 	if connectedDevices == 0 {
-		log.Println("没有设备连接成功，测试终止")
+		log.Println(T("没有设备连接成功，测试终止"))
 		cancel()
 		wg.Wait()
 		return
 	}
 
 	// 创建测试开始时间变量，但实际值在第一次发送时设置
+	// 调度基于单调时钟，系统时间跳变不会影响发送节奏
 	testStartTime := time.Now()
 	nextSendTime := time.Now()
 
-	// 主测试循环
-	for cycle := 1; cycle <= AppConfig.Test.CycleCount; cycle++ {
-		// 计算此次发送的目标时间
-		nextSendTime = nextSendTime.Add(AppConfig.Test.DataInterval)
+	// 长稳测试模式下定期保存检查点
+	if AppConfig.Soak.Enabled {
+		log.Printf(T("长稳测试模式: 持续运行直到收到中断信号，检查点间隔: %v"), AppConfig.Soak.CheckpointInterval)
+		go runCheckpointer(ctx, runID)
+	} else if resumeEnabled() {
+		log.Printf(T("中断恢复: 检查点保存到 %s，间隔: %v"), AppConfig.Soak.CheckpointFile, AppConfig.Soak.CheckpointInterval)
+		go runCheckpointer(ctx, runID)
+	}
+
+	// 发送间隔从配置值开始，自适应模式下由控制器调整
+	sendInterval.Store(int64(AppConfig.Test.DataInterval))
+
+	// 发布错误率持续超出预算或磁盘空间不足时提前终止
+	go watchPublishBudget(ctx, cancel)
+	go watchDiskSpace(ctx, cancel)
+
+	// 采集本进程的CPU使用率，配置了CPU上限时据此限速
+	go monitorCPU(ctx)
+
+	// 主测试循环(长稳测试和守护模式下不限循环次数)
+testLoop:
+	for cycle := startCycle; AppConfig.Soak.Enabled || daemonEnabled() || cycle <= AppConfig.Test.CycleCount; cycle++ {
+		// 分阶段负载：进入新阶段时切换发送间隔并开始该阶段的统计
+		if len(loadStages) > 0 {
+			beginStage(cycle)
+		}
+
+		// 计算此次发送的目标时间，CPU超出上限时额外推迟(推迟的时间不计为调度落后)
+		interval := currentSendInterval()
+		nextSendTime = nextSendTime.Add(interval + throttleDelay(interval))
 
-		// 计算需要等待的时间
+		// 计算需要等待的时间，等待期间可被中断信号打断
 		waitTime := time.Until(nextSendTime)
 		if waitTime > 0 {
-			time.Sleep(waitTime)
+			select {
+			case <-ctx.Done():
+				break testLoop
+			case <-time.After(waitTime):
+			}
+		} else if ctx.Err() != nil {
+			break testLoop
 		}
 
-		// 触发所有设备同时发送数据
+		// 触发所有设备同时发送数据，设备以计划时间而非触发时间计算修正后的耗时
+		schedule.begin(uint64(cycle), nextSendTime, time.Now())
+		if AppConfig.Test.CycleBarrier {
+			cycleBarrier.begin(uint64(cycle), AppConfig.Test.BarrierTimeout)
+		}
 		close(startChan)
 
 		// 如果是第一次发送数据，记录时间
-		if cycle == 1 {
+		if cycle == startCycle {
 			now := time.Now()
 			firstSendTime.Store(&now)
 			testStartTime = now // 同步更新testStartTime
+			annotate("stage", T("开始发送数据"))
+			setPhase(phaseRunning)
+			sdNotify("READY=1")
+			startAttrSet(ctx)
+			startCommandFlood(ctx)
+			startHistoryQuery(ctx)
+			startFleetGrowth(ctx, &wg, AppConfig.Device.ClientNumber)
+		}
+		if AppConfig.Takeover.Enabled && cycle == AppConfig.Takeover.Cycle {
+			annotate("stage", fmt.Sprintf(T("第 %d 个循环: 会话接管"), cycle))
+		}
+		if credRotationEnabled() && cycle == AppConfig.CredRotation.Cycle {
+			startCredRotation(ctx)
+		}
+		if fleetShrinkEnabled() && cycle == AppConfig.FleetShrink.Cycle {
+			startFleetShrink(ctx)
+		}
+		if AppConfig.Offline.Enabled {
+			switch cycle {
+			case AppConfig.Offline.StartCycle:
+				annotate("stage", fmt.Sprintf(T("第 %d 个循环: 部分设备离线并开始缓存数据"), cycle))
+			case AppConfig.Offline.StartCycle + AppConfig.Offline.Cycles:
+				annotate("stage", fmt.Sprintf(T("第 %d 个循环: 离线设备重连并补发缓存"), cycle))
+			}
 		}
 
 		// 创建新的触发通道，用于下一轮测试
 		startChan = make(chan struct{})
+		runStats.RecordCycle()
+
+		// 等待本循环所有设备发送完成，超时的设备计为落后设备
+		if AppConfig.Test.CycleBarrier {
+			cycleBarrier.wait(ctx, uint64(cycle))
+		}
 
 		if AppConfig.Monitor.LogCycle {
-			currentDataCount := atomic.LoadUint64(&dataCount)
-			currentMsgCount := atomic.LoadUint64(&msgCount)
+			snap := runStats.Snapshot()
+			currentDataCount, currentMsgCount := snap.DataPoints, snap.Messages
 
 			// 从第一次发送开始计算速率
-			pointsPerSecond := float64(currentDataCount) / time.Since(testStartTime).Seconds()
-			msgsPerSecond := float64(currentMsgCount) / time.Since(testStartTime).Seconds()
+			elapsed := time.Since(testStartTime)
+			pointsPerSecond := ratePerSecond(float64(currentDataCount), elapsed)
+			msgsPerSecond := ratePerSecond(float64(currentMsgCount), elapsed)
 
-			log.Printf("循环 %d/%d: 已发送数据点数: %d (%.1f点/秒), 消息数: %d (%.1f消息/秒)",
+			log.Printf(T("循环 %d/%d: 已发送数据点数: %d (%.1f点/秒), 消息数: %d (%.1f消息/秒)"),
 				cycle, AppConfig.Test.CycleCount, currentDataCount, pointsPerSecond,
 				currentMsgCount, msgsPerSecond)
 		}
 	}
 
 	// 测试完成，关闭所有设备连接
+	interrupted := stopCtx.Err() != nil
+	if interrupted {
+		log.Println(T("收到中断信号，提前结束测试"))
+	}
+	annotate("stage", T("停止发送数据"))
+	setPhase(phaseStopping)
+	sdNotify("STOPPING=1")
+	endStage()
+	if !interrupted && abortedReason() == "" {
+		beginRampDown()
+	}
 	cancel()
+	stopSignals() // 恢复默认信号处理
 	testDuration := time.Since(testStartTime)
 
+	// 逐步断开：按速率发放断开许可，收到中断信号时全部设备直接断开
+	rampCtx, stopRamp := context.WithCancel(context.Background())
+	if rampDown.active.Load() {
+		go runRampDown(rampCtx)
+	}
+
 	// 输出测试结果
-	log.Printf("等待所有设备退出...")
+	log.Printf(T("等待所有设备退出..."))
 	wg.Wait()
+	dashboards.Wait()
+	stopRamp()
+	measureRampDown()
+	setPhase(phaseReporting)
 
 	// 获取最终统计
-	finalDataCount := atomic.LoadUint64(&dataCount)
-	finalMsgCount := atomic.LoadUint64(&msgCount)
-	finalExitCount := atomic.LoadUint64(&exitCount)
+	final := runStats.Snapshot()
+	finalDataCount := final.DataPoints
+	finalMsgCount := final.Messages
+	finalExitCount := final.Exited
+	finalCycles := final.Cycles
 
 	// 打印简要测试总结
-	log.Println("\n========== 测试完成 ==========")
-	log.Printf("测试总耗时: %v", testDuration)
-	log.Printf("测试循环次数: %d", AppConfig.Test.CycleCount)
-	log.Printf("已退出设备数: %d (%.1f%%)", finalExitCount, float64(finalExitCount)*100/float64(AppConfig.Device.ClientNumber))
-	log.Printf("总发送数据点数: %d", finalDataCount)
-	log.Printf("总发送消息数: %d", finalMsgCount)
+	log.Println(T("\n========== 测试完成 =========="))
+	log.Printf(T("测试总耗时: %v"), testDuration)
+	log.Printf(T("测试循环次数: %d"), finalCycles)
+	log.Printf(T("已退出设备数: %d (%.1f%%)"), finalExitCount, float64(finalExitCount)*100/float64(AppConfig.Device.ClientNumber))
+	log.Printf(T("总发送数据点数: %d"), finalDataCount)
+	log.Printf(T("总发送消息数: %d"), finalMsgCount)
+	if failed := final.PublishFailed; failed > 0 {
+		log.Printf(T("发布失败消息数: %d"), failed)
+	}
+	if reason := abortedReason(); reason != "" {
+		log.Printf(T("提前终止: %s"), reason)
+	}
+	if len(AppConfig.Subscribe.Topics) > 0 {
+		log.Printf(T("收到下行消息数: %d"), final.Received)
+	}
 	log.Println("===============================")
-	log.Println("\n测试已完成。监控线程仍在运行，可以继续观察数据入库情况。")
-	log.Println("按 Enter 键退出程序...")
+
+	// 长稳测试：保存最终检查点并输出累计结果
+	if AppConfig.Soak.Enabled {
+		cp := currentCheckpoint(runID)
+		if err := saveSoakCheckpoint(AppConfig.Soak.CheckpointFile, cp); err != nil {
+			log.Printf(T("长稳测试: %v"), err)
+		} else {
+			log.Printf(T("长稳测试: 检查点已保存到 %s"), AppConfig.Soak.CheckpointFile)
+		}
+		log.Printf(T("长稳测试累计(含 %d 次恢复): 时长=%v, 循环=%d, 数据点=%d, 消息=%d"),
+			cp.Restarts, cp.Elapsed.Round(time.Second), cp.Cycles, cp.DataPoints, cp.Messages)
+	}
+
+	// 中断恢复：中断或提前终止时保存最终检查点，正常结束后删除检查点
+	finishResume(runID, !interrupted && abortedReason() == "")
+
+	// 输出消费者、各数据流和每设备统计
+	drainConsumers(consumers)
+	reportConsumers(consumers, finalMsgCount, testDuration)
+	reportProtocols(testDuration)
+	reportGlassLatency()
+	reportLocalBroker(finalMsgCount, testDuration)
+	reportSink(testDuration)
+	reportAttrSet()
+	reportCommandFlood()
+	reportHistoryQuery()
+	reportStreams()
+	reportHeartbeat(testDuration)
+	reportQoSMix()
+	reportRetries()
+	reportExprErrors()
+	reportReconnects()
+	reportErrorLog()
+	reportBrokerNodes(testDuration)
+	reportRampDown()
+	reportDBProbe()
+	reportCertRotations()
+	cycleBarrier.Report(finalCycles)
+	schedule.Report(finalCycles)
+	reportStages()
+	reportTakeover()
+	reportCredRotation()
+	reportFleetShrink()
+	reportFleetGrowth()
+	reportProvision()
+	reportOffline()
+	reportDeviceStates()
+	reportCounters()
+	reportNumeric()
+	reportCurrentValues()
+	reportChecksums()
+	reportTenantLimit(testStartTime, testDuration)
+	reportTenantGroups(testStartTime, testDuration)
+	reportStrings(testStartTime)
+	reportAnomaly()
+	reportAlerts()
+	reportAnnotations(testStartTime)
+	rateController.Report()
+	reportThrottle()
+	reportDeviceStats(deviceStats)
+
+	// 汇总本次运行结果
+	summary := RunSummary{
+		RunID:         runID,
+		StartedAt:     testStartTime,
+		Duration:      testDuration,
+		Clients:       AppConfig.Device.ClientNumber,
+		Connected:     connectedDevices,
+		Cycles:        int(finalCycles),
+		DataPoints:    finalDataCount,
+		Messages:      finalMsgCount,
+		DBWritten:     atomic.LoadInt64(&dbWrittenCount),
+		Received:      final.Received,
+		ConfigPayload: configSnapshot(),
+	}
+	summary.PointsPerSec = ratePerSecond(float64(finalDataCount), testDuration)
+	summary.MsgsPerSec = ratePerSecond(float64(finalMsgCount), testDuration)
+
+	// 写入结果库
+	if AppConfig.Results.Enabled {
+		if err := saveRunSummary(summary); err != nil {
+			log.Printf(T("保存运行结果失败: %v"), err)
+		} else {
+			log.Printf(T("运行结果已写入结果库: %s.runs (运行ID: %s)"), AppConfig.Results.Schema, runID)
+		}
+	}
+
+	// 生成带图表的HTML报告，向标准输出打印单行JSON汇总，按配置发送报告
+	html := writeHTMLReport(summary)
+	summaryJSON := printJSONSummary(summary, final, interrupted)
+	deliverReport(summary, html, summaryJSON, interrupted)
+
+	// 超出失败预算时以非零状态码退出，便于脚本判断
+	if abortedReason() != "" {
+		log.Println(T("程序正在退出..."))
+		stopConsumers(consumers)
+		os.Exit(2)
+	}
+
+	// 被信号中断时直接退出，不再等待用户输入
+	if interrupted {
+		log.Println(T("程序正在退出..."))
+		return
+	}
+
+	log.Println(T("\n测试已完成。监控线程仍在运行，可以继续观察数据入库情况。"))
+	log.Println(T("按 Enter 键退出程序..."))
 
 	// 创建一个通道用于接收输入完成信号
 	inputDone := make(chan struct{})
 
 	// 启动一个goroutine等待用户输入
 	go func() {
-		// 读取一行输入(等待按Enter键)
-		reader := bufio.NewReader(os.Stdin)
-		_, _ = reader.ReadString('\n')
+		// 读取一行输入(等待按Enter键)，与测试期间的标注共用同一个读取者
+		<-stdinLines()
 		close(inputDone)
 	}()
 
 	// 等待用户输入或者CTRL+C信号
 	<-inputDone
 
-	log.Println("程序正在退出...")
+	log.Println(T("程序正在退出..."))
 }
 
-// readFile 从指定的文件中读取每一行内容并返回字符串切片
-func readFile(fileName string) ([]string, error) {
-	file, err := os.Open(fileName)
-	if err != nil {
-		return nil, fmt.Errorf("打开文件失败: %w", err)
-	}
-	defer file.Close()
-
-	var lines []string
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if line != "" { // 忽略空行
-			lines = append(lines, line)
+// connectAndPublish 连接MQTT服务器并定时发布传感器数据，心跳设备只按心跳间隔发送状态消息
+func connectAndPublish(wg *sync.WaitGroup, ctx context.Context, index int, username string, qos byte, heartbeat bool, stats *DeviceStats, faker *gofakeit.Faker) {
+	defer wg.Done()
+	defer runStats.RecordExit()
+
+	// 动态注册模式：连接前先用设备编号注册，之后使用获得的凭证
+	if provisionEnabled() {
+		provisioned, err := provisionCredentials(ctx, username)
+		if err != nil {
+			logErrorf(T("设备 %s %v"), username, err)
+			stats.recordConnectFailure()
+			runStats.RecordConnectFailure()
+			return
 		}
+		username = provisioned
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("读取文件内容失败: %w", err)
+	// 创建并连接MQTT客户端
+	clientID := username + "_" + time.Now().Format("150405")
+	client := newDeviceClient(clientID, username, stats, newReconnectRand(index))
+	group := tenantGroupOf(index)
+	joined := joinedDeviceOf(username)
+	connectStart := time.Now()
+	if err := client.Connect(); err != nil {
+		logErrorf(T("设备 %s 连接MQTT服务器失败: %v"), username, err)
+		stats.recordConnectFailure()
+		group.recordConnect(err)
+		joined.recordConnect(0, err)
+		runStats.RecordConnectFailure()
+		return
 	}
-
-	if len(lines) == 0 {
-		return nil, fmt.Errorf("文件为空或不包含有效设备token")
+	connectLatency.Record(connectStart, time.Since(connectStart))
+	joined.recordConnect(time.Since(connectStart), nil)
+	recordProtocolDevice(client)
+	group.recordConnect(nil)
+
+	// 订阅下行主题(如已配置)，被拒绝的订阅在连接等待结束后统一报告
+	if err := subscribeTopics(client, username, stats); err != nil {
+		logErrorf(T("设备 %s %v"), username, err)
+	}
+	if err := subscribeAttributeSet(client, username); err != nil {
+		logErrorf(T("设备 %s %v"), username, err)
+	}
+	if err := subscribeCommand(client, username); err != nil {
+		logErrorf(T("设备 %s %v"), username, err)
 	}
 
-	return lines, nil
-}
-
-// connectAndPublish 连接MQTT服务器并定时发布传感器数据
-func connectAndPublish(wg *sync.WaitGroup, ctx context.Context, username string) {
-	defer wg.Done()
-	defer func() {
-		atomic.AddUint64(&exitCount, 1)
-	}()
+	// 连接成功，计数器加1
+	runStats.RecordConnected()
+	defer func() { rampDownDisconnect(client, username) }() // 确保在函数结束时断开连接(会话接管后为新连接)，逐步断开时等待许可
 
-	// 设置MQTT客户端选项
-	clientID := username + "_" + time.Now().Format("150405")
-	opts := mqtt.NewClientOptions().
-		SetClientID(clientID).
-		AddBroker(AppConfig.MQTT.Server).
-		SetUsername(username).
-		SetCleanSession(true).
-		SetAutoReconnect(true).
-		SetKeepAlive(60 * time.Second).
-		SetMaxReconnectInterval(5 * time.Second)
+	// 和真实设备一样，连接后先上报设备信息
+	publishDeviceInfo(ctx, client, username, qos, stats, faker)
 
-	// 创建并连接MQTT客户端
-	client := mqtt.NewClient(opts)
-	if token := client.Connect(); token.Wait() && token.Error() != nil {
-		log.Printf("设备 %s 连接MQTT服务器失败: %v", username, token.Error())
+	// 心跳设备只保持连接并定期发送心跳，不参与遥测和各测试场景
+	if heartbeat {
+		runHeartbeat(ctx, client, username, qos, stats, faker)
 		return
 	}
 
-	// 连接成功，计数器加1
-	atomic.AddUint64(&successNum, 1)
-	defer client.Disconnect(200) // 确保在函数结束时断开连接
-
-	// 预生成传感器数据对象，避免频繁创建
-	sensorData := make(SensorData)
+	// 预生成消息生成器，避免频繁创建
+	generator := newPayloadGenerator(index, username, currentMessagePoints(), faker)
+	streams := newStreamPublishers(username)
+	progress := trackDeviceOffset(username, streams)
+	identity := rotatingIdentity(index, username)
+	topic := group.telemetryTopic(username)
+	offline := newOfflineDevice(username, topic)
+	sample := newCurrentSample(username)
+	checksum := newChecksumSample(username, offline != nil)
+	tenant := tenantOf(username)
+	removed := deletedDeviceOf(username)
+	state := newDeviceState(index, offline != nil)
+	strs := newStringFields(faker)
+	anomaly := newAnomalyDevice(username)
+	marker := newGlassMarker(username)
+
+	cycleBarrier.join()
+	defer cycleBarrier.leave()
+
+	// 上一次发送的循环，用于发现因发布阻塞而错过的循环
+	var lastCycle uint64
 
 	// 主循环：等待触发信号并发送数据
 	for {
@@ -280,28 +710,140 @@ func connectAndPublish(wg *sync.WaitGroup, ctx context.Context, username string)
 		case <-ctx.Done(): // 测试结束信号
 			return
 		default:
-			<-startChan // 等待开始信号
+			// 等待开始信号，测试结束时立即退出
+			select {
+			case <-ctx.Done():
+				return
+			case <-startChan:
+			}
+			slot := schedule.current()
 
-			// 生成模拟传感器数据
-			updateSensorData(sensorData)
+			// 分阶段负载切换了每条消息的数据点数时重建生成器，连接保持不变
+			if points := currentMessagePoints(); points != generator.Points() {
+				generator = newPayloadGenerator(index, username, points, faker)
+			}
+			setGeneratorCycle(generator, slot.cycle)
+
+			// 多租户分组：发送间隔较长的组只在部分循环发送
+			if !group.due(slot.cycle) {
+				lastCycle = slot.cycle
+				if AppConfig.Test.CycleBarrier {
+					cycleBarrier.complete(slot.cycle)
+				}
+				continue
+			}
 
-			// 将数据序列化为JSON
-			jsonData, err := json.Marshal(sensorData)
-			if err != nil {
-				log.Printf("序列化数据失败: %v", err)
+			// 离线缓存场景：离线期间只缓存数据，恢复在线时先补发全部缓存再发送本循环的数据
+			if offline != nil {
+				switch offline.phase(slot.cycle) {
+				case offlineBuffer:
+					if err := offline.buffer(client, generator, slot); err != nil {
+						logErrorf(T("序列化数据失败: %v"), err)
+					}
+					lastCycle = slot.cycle
+					if AppConfig.Test.CycleBarrier {
+						cycleBarrier.complete(slot.cycle)
+					}
+					continue
+				case offlineFlush:
+					offline.flush(ctx, client, qos, generator.Points(), stats)
+				}
+			}
+
+			// 设备状态机：离线状态下断开连接不上报，降级状态下降低上报频率
+			if !state.advance(client, username) {
+				lastCycle = slot.cycle
+				if AppConfig.Test.CycleBarrier {
+					cycleBarrier.complete(slot.cycle)
+				}
 				continue
 			}
 
-			// 发布数据到MQTT主题
-			token := client.Publish(AppConfig.MQTT.Topic, byte(AppConfig.MQTT.QoS), false, jsonData)
-			token.Wait()
+			// 证书到期轮换时重连，新证书在握手中生效
+			if identity != nil && identity.rotationDue(time.Now()) {
+				rotateDeviceCert(client, identity)
+			}
 
-			if token.Error() != nil {
-				log.Printf("发布消息失败: %v", token.Error())
-			} else {
-				// 每条消息包含配置的数据点数量
-				atomic.AddUint64(&dataCount, uint64(len(sensorData)))
-				atomic.AddUint64(&msgCount, 1)
+			// 会话接管场景：用相同的客户端ID建立新连接，之后改用新连接发送
+			handover := false
+			if takeoverDue(slot.cycle, username) {
+				client, handover = takeoverSession(client, clientID, username, stats, newReconnectRand(index))
+			}
+
+			// 生成模拟传感器数据并编码
+			jsonData, err := generator.Next()
+			if err != nil {
+				logErrorf(T("序列化数据失败: %v"), err)
+				lastCycle = slot.cycle
+				if AppConfig.Test.CycleBarrier {
+					cycleBarrier.complete(slot.cycle)
+				}
+				continue
+			}
+			jsonData, extra := state.annotate(jsonData)
+			points := generator.Points() + extra
+			jsonData, extra = strs.append(jsonData)
+			points += extra
+			jsonData = anomaly.apply(jsonData, slot.cycle)
+			jsonData, extra = marker.append(jsonData)
+			points += extra
+
+			// 发布数据到MQTT主题，耗时包含重试等待
+			publishStart := time.Now()
+			schedule.recordStart(slot, publishStart)
+			err = publishWithRetry(ctx, client, topic, qos, jsonData)
+			publishElapsed := time.Since(publishStart)
+			stats.recordPublish(publishElapsed, err)
+			recordQoS(qos, publishElapsed, err)
+			streamStats[0].record(publishElapsed, err)
+			recordProtocol(client, publishElapsed, err)
+			if err == nil {
+				publishLatency.Record(publishElapsed)
+				schedule.recordDone(slot, lastCycle, publishStart.Add(publishElapsed))
+				if offline != nil {
+					offline.recordLive(jsonData)
+				}
+				sample.record(jsonData)
+				checksum.record(jsonData, publishStart)
+			}
+			if handover {
+				recordHandover(err)
+			}
+			tenant.record(points, err)
+			removed.record(publishStart, err)
+			joined.recordPublish(err)
+			group.record(points, publishElapsed, err)
+			strs.record(err)
+			marker.record(err)
+			anomaly.record(slot.cycle, publishStart.Add(publishElapsed), err)
+			lastCycle = slot.cycle
+
+			// 发布已完成，消息数据可以复用
+			generator.Done()
+
+			// 每条消息包含配置的数据点数量
+			runStats.RecordPublish(telemetryStream, qos, clientBroker(client), points, err)
+			progress.recordMessage()
+			if err != nil {
+				logErrorf(T("发布消息失败: %v"), err)
+			}
+
+			// 额外数据流(属性、事件等)单独计数，不计入数据点和消息数
+			for i, stream := range streams {
+				publishStart = time.Now()
+				err = publishWithRetry(ctx, client, stream.topic, qos, stream.Next(faker))
+				progress.recordStream(i, stream.seq)
+				publishElapsed = time.Since(publishStart)
+				stats.recordPublish(publishElapsed, err)
+				recordQoS(qos, publishElapsed, err)
+				stream.stats.record(publishElapsed, err)
+				runStats.RecordPublish(stream.stats.Name, qos, clientBroker(client), 0, err)
+				if err != nil {
+					logErrorf(T("发布消息失败: %v"), err)
+				}
+			}
+			if AppConfig.Test.CycleBarrier {
+				cycleBarrier.complete(slot.cycle)
 			}
 
 			// 让出CPU时间片，避免单个goroutine占用过多资源
@@ -310,16 +852,29 @@ func connectAndPublish(wg *sync.WaitGroup, ctx context.Context, username string)
 	}
 }
 
-// updateSensorData 更新传感器数据对象的值
-func updateSensorData(data SensorData) {
-	// 清空旧数据
-	for k := range data {
-		delete(data, k)
+// sensorKeys 按数据点名称模板生成设备的数据点名称列表
+//
+// {i}替换为从1开始的序号，{device}替换为设备token。模板包含{device}时每个设备的
+// 数据点名称各不相同，平台上的键数量随设备数增长而不是由所有设备共享。
+func sensorKeys(username string, count int) []string {
+	keys := make([]string, count)
+	for i := range keys {
+		keys[i] = strings.NewReplacer("{i}", strconv.Itoa(i+1), "{device}", username).Replace(AppConfig.Data.KeyTemplate)
 	}
+	return keys
+}
 
-	// 根据配置生成指定数量的数据点
-	for i := 1; i <= AppConfig.Data.DataPointCount; i++ {
-		key := fmt.Sprintf("hum%d", i)
-		data[key] = gofakeit.Float64Range(AppConfig.Data.MinValue, AppConfig.Data.MaxValue)
+// distinctKeyCount 估算平台上出现的不同数据点名称数量
+func distinctKeyCount() int {
+	if keyTemplateInUse() && strings.Contains(AppConfig.Data.KeyTemplate, "{device}") {
+		return maxMessagePoints() * AppConfig.Device.ClientNumber
+	}
+	return maxMessagePoints()
+}
+
+// updateSensorData 更新传感器数据对象的值
+func updateSensorData(data SensorData, faker *gofakeit.Faker) {
+	for i := range data {
+		data[i].Value = faker.Float64Range(AppConfig.Data.MinValue, AppConfig.Data.MaxValue)
 	}
 }
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// merge子命令参数
+var mergeOutput = flag.String("merge-output", "", "merge: 合并后的汇总写入该文件，为空时只输出到标准输出")
+
+// mergeMaxFields 合并时取各worker最大值的字段(JSON字段名)
+//
+// 各worker同时运行，时长取最长的；入库数、平台消费者收到数等在同一个数据库或平台上统计，
+// 每个worker看到的都是全部worker的总量，相加会重复计算。
+var mergeMaxFields = map[string]bool{
+	"duration_s":               true,
+	"cycles":                   true,
+	"db_written":               true,
+	"consumer_received":        true,
+	"behind_schedule_s":        true,
+	"ramp_down_s":              true,
+	"ramp_down_offline_s":      true,
+	"ramp_down_flush_s":        true,
+	"ramp_down_offline_p99_ms": true,
+	"strings_truncated":        true,
+}
+
+// runMerge 把分布式运行中各worker的summary.json合并为一份汇总
+//
+// 计数相加，速率相加(各worker同时运行)，分位数由合并后的直方图重新计算——各worker的p99
+// 取平均或最大值都不是整体的p99。没有直方图的分位数(旧版本生成的汇总)按最大值估算并给出警告。
+func runMerge() {
+	files := flag.Args()
+	if len(files) < 2 {
+		log.Fatalln(T("用法: mqtt merge [--merge-output merged.json] worker1/summary.json worker2/summary.json ..."))
+	}
+
+	var merged *jsonSummaryLine
+	var workers []*jsonSummaryLine
+	for _, path := range files {
+		line, err := readSummaryFile(path)
+		if err != nil {
+			log.Fatalf(T("读取 %s 失败: %v"), path, err)
+		}
+		if len(line.Histograms) == 0 {
+			log.Printf(T("警告: %s 中没有直方图(旧版本生成)，分位数按各worker的最大值估算，偏高"), path)
+		}
+		workers = append(workers, line)
+		if merged == nil {
+			copied := *line
+			merged = &copied
+			merged.Workers = max(line.Workers, 1)
+			merged.Histograms = cloneHistograms(line.Histograms)
+			merged.Stages = mergeStages(nil, line.Stages)
+			merged.TenantGroups = mergeTenantGroups(nil, line.TenantGroups)
+			merged.BrokerNodes = mergeBrokerNodes(nil, line.BrokerNodes)
+			merged.Streams = mergeCounts(nil, line.Streams)
+			merged.Annotations = workerAnnotations(line)
+			continue
+		}
+		mergeSummaryLine(merged, line)
+	}
+	finishMerge(merged)
+
+	reportMerge(merged, workers)
+	data, err := json.Marshal(merged)
+	if err != nil {
+		log.Fatalf(T("序列化JSON汇总失败: %v"), err)
+	}
+	data = append(data, '\n')
+	if *mergeOutput != "" {
+		if err := os.WriteFile(*mergeOutput, data, 0644); err != nil {
+			log.Fatalf(T("写入 %s 失败: %v"), *mergeOutput, err)
+		}
+		log.Printf(T("合并后的汇总: %s"), *mergeOutput)
+	}
+	if *jsonSummary {
+		os.Stdout.Write(data)
+	}
+}
+
+// readSummaryFile 读取summary.json，文件中有多行时(标准输出的重定向)取最后一个JSON对象
+func readSummaryFile(path string) (*jsonSummaryLine, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if strings.HasPrefix(strings.TrimSpace(lines[i]), "{") {
+			data = []byte(lines[i])
+			break
+		}
+	}
+	var line jsonSummaryLine
+	if err := json.Unmarshal(data, &line); err != nil {
+		return nil, err
+	}
+	return &line, nil
+}
+
+// mergeSummaryLine 把一个worker的汇总合并到dst
+//
+// 标量字段按类型和名称合并：整数相加(mergeMaxFields中的取最大值)，速率(_per_sec)相加，
+// 其他浮点数取最大值(分位数之后由直方图重新计算)，布尔值取或，字符串去重拼接。
+// 直方图、分阶段、多租户分组、Broker节点和标注单独合并。
+func mergeSummaryLine(dst, src *jsonSummaryLine) {
+	dv, sv := reflect.ValueOf(dst).Elem(), reflect.ValueOf(src).Elem()
+	for i := 0; i < dv.NumField(); i++ {
+		name := jsonFieldName(dv.Type().Field(i))
+		if name == "workers" {
+			continue
+		}
+		d, s := dv.Field(i), sv.Field(i)
+		switch d.Kind() {
+		case reflect.Int, reflect.Int64:
+			if mergeMaxFields[name] {
+				d.SetInt(max(d.Int(), s.Int()))
+			} else {
+				d.SetInt(d.Int() + s.Int())
+			}
+		case reflect.Uint64:
+			if mergeMaxFields[name] {
+				d.SetUint(max(d.Uint(), s.Uint()))
+			} else {
+				d.SetUint(d.Uint() + s.Uint())
+			}
+		case reflect.Float64:
+			if strings.HasSuffix(name, "_per_sec") && !mergeMaxFields[name] {
+				d.SetFloat(d.Float() + s.Float())
+			} else {
+				d.SetFloat(max(d.Float(), s.Float()))
+			}
+		case reflect.Bool:
+			d.SetBool(d.Bool() || s.Bool())
+		case reflect.String:
+			d.SetString(joinDistinct(d.String(), s.String()))
+		}
+	}
+
+	// 已合并的汇总可以再次合并
+	dst.Workers += max(src.Workers, 1)
+	for name, h := range src.Histograms {
+		if dst.Histograms[name] == nil {
+			dst.Histograms[name] = &Histogram{}
+		}
+		dst.Histograms[name].Merge(h)
+	}
+	dst.Stages = mergeStages(dst.Stages, src.Stages)
+	dst.TenantGroups = mergeTenantGroups(dst.TenantGroups, src.TenantGroups)
+	dst.BrokerNodes = mergeBrokerNodes(dst.BrokerNodes, src.BrokerNodes)
+	dst.Streams = mergeCounts(dst.Streams, src.Streams)
+	dst.Annotations = append(dst.Annotations, workerAnnotations(src)...)
+}
+
+// finishMerge 按合并后的直方图和计数重新计算分位数、入库率，标注按时间排序
+func finishMerge(merged *jsonSummaryLine) {
+	v := reflect.ValueOf(merged).Elem()
+	for _, q := range summaryQuantiles {
+		h := merged.Histograms[q.histogram]
+		if h == nil {
+			continue
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if jsonFieldName(v.Type().Field(i)) == q.field {
+				v.Field(i).SetFloat(durationMs(h.Quantile(q.q)))
+			}
+		}
+	}
+	merged.WriteRate = 0
+	if merged.Points > 0 {
+		merged.WriteRate = float64(merged.DBWritten) / float64(merged.Points) * 100
+	}
+	sort.SliceStable(merged.Annotations, func(i, j int) bool {
+		return merged.Annotations[i].Time.Before(merged.Annotations[j].Time)
+	})
+}
+
+// jsonFieldName 结构体字段的JSON名称
+func jsonFieldName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	return name
+}
+
+// joinDistinct 拼接两个字符串，相同或为空时不重复
+func joinDistinct(a, b string) string {
+	switch {
+	case b == "" || a == b:
+		return a
+	case a == "":
+		return b
+	}
+	for _, part := range strings.Split(a, ", ") {
+		if part == b {
+			return a
+		}
+	}
+	return a + ", " + b
+}
+
+// cloneHistograms 复制直方图，合并时不修改读入的原始数据
+func cloneHistograms(src map[string]*Histogram) map[string]*Histogram {
+	out := make(map[string]*Histogram, len(src))
+	for name, h := range src {
+		out[name] = h.Snapshot()
+	}
+	return out
+}
+
+// mergeCounts 按键相加
+func mergeCounts(dst, src map[string]uint64) map[string]uint64 {
+	if len(src) == 0 {
+		return dst
+	}
+	if dst == nil {
+		dst = make(map[string]uint64, len(src))
+	}
+	for k, n := range src {
+		dst[k] += n
+	}
+	return dst
+}
+
+// workerAnnotations 标注来源前加上worker的运行ID，合并后的时间线中可以区分
+func workerAnnotations(line *jsonSummaryLine) []Annotation {
+	out := make([]Annotation, 0, len(line.Annotations))
+	for _, a := range line.Annotations {
+		a.Source = line.RunID + "/" + a.Source
+		out = append(out, a)
+	}
+	return out
+}
+
+// mergeStages 按阶段名称合并分阶段统计
+//
+// 各worker按相同的阶段配置运行，阶段边界取最早的开始和最晚的结束；成功率按消息数加权，
+// 发布耗时由合并后的直方图重新计算，入库速率在同一个数据库上统计，取最大值。
+func mergeStages(dst, src []StageSummary) []StageSummary {
+	for _, s := range src {
+		i := indexOf(len(dst), func(i int) bool { return dst[i].Name == s.Name })
+		if i < 0 {
+			s.Latency = cloneHistogram(s.Latency)
+			dst = append(dst, s)
+			continue
+		}
+		d := &dst[i]
+		if total := d.Msgs + s.Msgs; total > 0 {
+			d.SuccessRate = (d.SuccessRate*float64(d.Msgs) + s.SuccessRate*float64(s.Msgs)) / float64(total)
+		}
+		d.StartedAt = earlier(d.StartedAt, s.StartedAt)
+		d.EndedAt = later(d.EndedAt, s.EndedAt)
+		d.DurationS = max(d.DurationS, s.DurationS)
+		d.Msgs += s.Msgs
+		d.Points += s.Points
+		d.Failed += s.Failed
+		d.MsgsPerSec += s.MsgsPerSec
+		d.PointsPerSec += s.PointsPerSec
+		d.DBRate = max(d.DBRate, s.DBRate)
+		d.P50Ms, d.P95Ms, d.P99Ms = max(d.P50Ms, s.P50Ms), max(d.P95Ms, s.P95Ms), max(d.P99Ms, s.P99Ms)
+		d.MaxMs = max(d.MaxMs, s.MaxMs)
+		if d.Latency != nil && s.Latency != nil {
+			d.Latency.Merge(s.Latency)
+			d.P50Ms = durationMs(d.Latency.Quantile(0.50))
+			d.P95Ms = durationMs(d.Latency.Quantile(0.95))
+			d.P99Ms = durationMs(d.Latency.Quantile(0.99))
+		}
+	}
+	return dst
+}
+
+// mergeTenantGroups 按分组名称合并多租户分组统计，入库数在同一个数据库上按租户统计，取最大值
+func mergeTenantGroups(dst, src []TenantGroupSummary) []TenantGroupSummary {
+	for _, g := range src {
+		i := indexOf(len(dst), func(i int) bool { return dst[i].Name == g.Name })
+		if i < 0 {
+			g.Latency = cloneHistogram(g.Latency)
+			dst = append(dst, g)
+			continue
+		}
+		d := &dst[i]
+		d.Devices += g.Devices
+		d.Connected += g.Connected
+		d.ConnectFailed += g.ConnectFailed
+		d.Msgs += g.Msgs
+		d.Points += g.Points
+		d.Failed += g.Failed
+		d.PointsPerSec += g.PointsPerSec
+		d.Stored = max(d.Stored, g.Stored)
+		d.P50Ms, d.P99Ms = max(d.P50Ms, g.P50Ms), max(d.P99Ms, g.P99Ms)
+		if d.Latency != nil && g.Latency != nil {
+			d.Latency.Merge(g.Latency)
+			d.P50Ms = durationMs(d.Latency.Quantile(0.50))
+			d.P99Ms = durationMs(d.Latency.Quantile(0.99))
+		}
+	}
+	return dst
+}
+
+// mergeBrokerNodes 按节点地址合并Broker节点分布
+func mergeBrokerNodes(dst, src []BrokerNodeSummary) []BrokerNodeSummary {
+	for _, n := range src {
+		i := indexOf(len(dst), func(i int) bool { return dst[i].Address == n.Address })
+		if i < 0 {
+			dst = append(dst, n)
+			continue
+		}
+		d := &dst[i]
+		d.Name = joinDistinct(d.Name, n.Name)
+		d.Active += n.Active
+		d.Connects += n.Connects
+		d.Sent += n.Sent
+		d.Failed += n.Failed
+		d.Rate += n.Rate
+	}
+	return dst
+}
+
+// indexOf 返回第一个满足条件的下标，没有时返回-1
+func indexOf(n int, match func(i int) bool) int {
+	for i := 0; i < n; i++ {
+		if match(i) {
+			return i
+		}
+	}
+	return -1
+}
+
+// cloneHistogram 复制直方图，nil时返回nil
+func cloneHistogram(h *Histogram) *Histogram {
+	if h == nil {
+		return nil
+	}
+	return h.Snapshot()
+}
+
+// earlier 返回较早的非零时间
+func earlier(a, b time.Time) time.Time {
+	if a.IsZero() || (!b.IsZero() && b.Before(a)) {
+		return b
+	}
+	return a
+}
+
+// later 返回较晚的时间
+func later(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+// reportMerge 输出合并结果，并列出各worker的p99作为对照
+func reportMerge(merged *jsonSummaryLine, workers []*jsonSummaryLine) {
+	log.Println(T("\n========== 合并报告 =========="))
+	log.Printf(T("worker数: %d, 运行ID: %s"), merged.Workers, merged.RunID)
+	log.Printf(T("测试时长: %.0f 秒, 设备数: %d / %d"), merged.DurationS, merged.Connected, merged.Clients)
+	log.Printf(T("发送消息数: %d (%.1f 条/秒), 发送数据点数: %d (%.1f 点/秒), 发布失败 %d"),
+		merged.Msgs, merged.MsgsPerSec, merged.Points, merged.PointsPerSec, merged.PublishFailed)
+	log.Printf(T("入库数据点数: %d, 入库率: %.2f%%"), merged.DBWritten, merged.WriteRate)
+	log.Printf(T("发布耗时: p50 %.2fms, p99 %.2fms, 连接耗时p99 %.2fms"), merged.P50Ms, merged.P99Ms, merged.ConnectP99Ms)
+	for _, w := range workers {
+		log.Printf(T("  %s: %d 设备, %.1f 点/秒, 发布耗时p99 %.2fms"), w.RunID, w.Connected, w.PointsPerSec, w.P99Ms)
+	}
+	if merged.Histograms["publish"] == nil {
+		log.Println(T("  汇总中没有直方图，合并后的分位数为各worker的最大值(偏高)"))
+	}
+	log.Println("===============================")
+}
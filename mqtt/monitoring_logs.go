@@ -3,64 +3,130 @@ package main
 import (
 	"context"
 	"database/sql"
-	"fmt"
 	"log"
 	"sync/atomic"
 	"time"
-
-	_ "github.com/lib/pq"
 )
 
+// countWindowStart 时间窗口计数方式下只统计时间戳不早于该时间的行
+var countWindowStart time.Time
+
+// countTelemetry 按配置的计数方式查询telemetry_datas的行数
+//
+// 测试期间运行的数据保留(TTL)清理任务会删除旧数据，全表行数可能不增反减，使累计入库速率
+// 为负、写入率失去意义。时间窗口方式只统计测试开始后的行，不受清理旧数据的影响。
+//
+// 分区方式同样只统计测试时间窗口内的行，但只查询与时间窗口相关的分区或chunk。
+func countTelemetry(ctx context.Context, db *sql.DB) (int64, error) {
+	since := countWindowStart.Add(-dbClockSkew).UnixMilli()
+	var count int64
+	switch AppConfig.Monitor.CountMode {
+	case "window":
+		err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM telemetry_datas WHERE ts >= $1", since).Scan(&count)
+		return count, err
+	case "partition":
+		return countPartitions(ctx, db, since)
+	}
+	err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM telemetry_datas").Scan(&count)
+	return count, err
+}
+
+// counterDelta 计算单调计数器两次采样之间的增量
+//
+// 计数器变小说明发生了重置(或溢出回绕)，此时按从0重新计数，以当前值作为增量并返回true，
+// 避免无符号减法回绕成 18446744073709551615 这样的增量。
+func counterDelta(current, last uint64) (uint64, bool) {
+	if current < last {
+		return current, true
+	}
+	return current - last, false
+}
+
+// monitorRetryBackoff 监控查询失败后首次重试前的等待时间，之后每次翻倍
+const monitorRetryBackoff = 500 * time.Millisecond
+
+// monitorQuery 以配置的超时执行监控查询，失败时退避重试
+//
+// 数据库过载正是最需要监控的时候，单次查询超时后取消并重试，重试用尽时由调用方
+// 跳过本间隔，而不是无限期阻塞监控循环。
+func monitorQuery[V any](name string, query func(ctx context.Context) (V, error)) (V, error) {
+	backoff := monitorRetryBackoff
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), AppConfig.Monitor.QueryTimeout)
+		v, err := query(ctx)
+		cancel()
+		if err == nil || attempt > AppConfig.Monitor.QueryRetries {
+			return v, err
+		}
+		log.Printf(T("监控模块: %s失败(第 %d 次): %v，%v 后重试"), name, attempt, err, backoff)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
 // MonitorLogs 监控数据库写入状态和对比已发送数据点数
 func MonitorLogs(initDone chan<- struct{}, firstSendTime *atomic.Value) {
-	// 连接数据库
-	connStr := fmt.Sprintf("postgres://%s:%s@%s/%s?sslmode=%s",
-		AppConfig.Database.User,
-		AppConfig.Database.Password,
-		AppConfig.Database.Host,
-		AppConfig.Database.Name,
-		AppConfig.Database.SSLMode)
-
-	db, err := sql.Open("postgres", connStr)
-	if err != nil {
-		log.Printf("监控模块: 无法连接数据库: %v", err)
-		close(initDone) // 通知初始化完成(虽然失败)
+	if localBrokerEnabled() || sinkEnabled() {
+		monitorWithoutDatabase(initDone)
 		return
 	}
-	defer db.Close()
-
-	// 测试连接
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
 
-	if err := db.PingContext(ctx); err != nil {
-		log.Printf("监控模块: 数据库连接测试失败: %v", err)
+	// 连接数据库并测试连接，配置了只读副本时在副本上计数
+	db, err := openReadDatabase()
+	if err != nil {
+		log.Printf(T("监控模块: %v"), err)
 		close(initDone) // 通知初始化完成(虽然失败)
 		return
 	}
+	defer db.Close()
 
-	log.Printf("监控模块: 成功连接到数据库，开始监控数据写入情况，监控间隔: %v", AppConfig.Monitor.LogInterval)
+	log.Printf(T("监控模块: 成功连接到数据库，开始监控数据写入情况，监控间隔: %v"), AppConfig.Monitor.LogInterval)
 
-	// 查询初始值作为基准
-	var initialCount int64
-	err = db.QueryRow("SELECT COUNT(*) FROM telemetry_datas").Scan(&initialCount)
+	// 查询初始值作为基准，时间窗口从监控启动时开始
+	countWindowStart = time.Now()
+	initialCount, err := monitorQuery(T("获取初始数据点数"), func(ctx context.Context) (int64, error) {
+		return countTelemetry(ctx, db)
+	})
 	if err != nil {
-		log.Printf("监控模块: 获取初始数据点数失败: %v", err)
+		log.Printf(T("监控模块: 获取初始数据点数失败: %v"), err)
 		initialCount = 0
 	}
 
-	log.Printf("监控模块: 数据库中当前数据点数: %d", initialCount)
+	log.Printf(T("监控模块: 数据库中当前数据点数: %d"), initialCount)
+
+	// 数据库写入探测，创建探测表失败时本次测试不再探测
+	// 探测的是主库的写入耗时，配置了只读副本时单独连接主库
+	probing := probeEnabled()
+	probeDB := db
+	if probing {
+		var err error
+		if replicaEnabled() {
+			if probeDB, err = openDatabase(); err == nil {
+				defer probeDB.Close()
+			}
+		}
+		if err == nil {
+			err = ensureProbeTable(probeDB)
+		}
+		if err != nil {
+			log.Printf(T("监控模块: %v，不进行数据库写入探测"), err)
+			probing = false
+		}
+	}
 
 	// 初始发送点数
-	initialSentCount := atomic.LoadUint64(&dataCount)
-	initialMsgCount := atomic.LoadUint64(&msgCount)
+	initial := runStats.Snapshot()
+	initialSentCount, initialMsgCount := initial.DataPoints, initial.Messages
 	lastDBCount := initialCount
+	var dbTotal int64 // 累计入库数，只累加行数增加的间隔，行数减少的间隔不计入
 	lastSentCount := initialSentCount
 	lastMsgCount := initialMsgCount
+	var lastBehind time.Duration
+	latencies := latencyWindows()
 
 	// 输出初始监控信息
-	log.Printf("\n========== 初始监控状态 ==========")
-	log.Printf("数据库初始数据点数: %d", initialCount)
+	log.Printf(T("\n========== 初始监控状态 =========="))
+	log.Printf(T("数据库初始数据点数: %d"), initialCount)
 	log.Printf("==============================")
 
 	// 通知初始化完成，测试可以开始
@@ -71,25 +137,41 @@ func MonitorLogs(initDone chan<- struct{}, firstSendTime *atomic.Value) {
 	defer ticker.Stop()
 
 	startTime := time.Now() // 保留这个变量作为后备
+	lastTickTime := startTime
 
 	for {
 		<-ticker.C
 
+		// 以实际经过的时间计算区间速率，而不是假定正好经过一个监控间隔
+		tickTime := time.Now()
+		intervalElapsed := tickTime.Sub(lastTickTime)
+
 		// 当前已发送点数
-		currentSentCount := atomic.LoadUint64(&dataCount)
-		currentMsgCount := atomic.LoadUint64(&msgCount)
-		sentDiff := currentSentCount - lastSentCount
-		msgDiff := currentMsgCount - lastMsgCount
+		snap := runStats.Snapshot()
+		currentSentCount, currentMsgCount := snap.DataPoints, snap.Messages
+		sentDiff, sentReset := counterDelta(currentSentCount, lastSentCount)
+		msgDiff, msgReset := counterDelta(currentMsgCount, lastMsgCount)
 
 		// 查询当前数据库点数
-		var currentDBCount int64
-		err = db.QueryRow("SELECT COUNT(*) FROM telemetry_datas").Scan(&currentDBCount)
+		currentDBCount, err := monitorQuery(T("查询数据库点数"), func(ctx context.Context) (int64, error) {
+			return countTelemetry(ctx, db)
+		})
 		if err != nil {
-			log.Printf("监控模块: 查询数据库点数失败: %v", err)
+			log.Printf(T("监控模块: 查询数据库点数失败，跳过本间隔: %v"), err)
 			continue
 		}
 
+		// 行数减少说明有清理任务(数据保留/TTL)删除了旧数据，本间隔实际入库了多少无法得知，
+		// 按0计并跳过本间隔的写入率和告警判断，而不是输出负的速率
 		dbDiff := currentDBCount - lastDBCount
+		dbShrunk := dbDiff < 0
+		if dbShrunk {
+			dbDiff = 0
+		}
+		dbTotal += dbDiff
+
+		// 记录累计入库数，供测试结果汇总使用
+		atomic.StoreInt64(&dbWrittenCount, dbTotal)
 
 		// 使用第一次发送数据的时间作为计时起点（如有）
 		var elapsedTime time.Duration
@@ -102,16 +184,23 @@ func MonitorLogs(initDone chan<- struct{}, firstSendTime *atomic.Value) {
 		}
 
 		// 计算统计信息
-		sentRate := float64(sentDiff) / AppConfig.Monitor.LogInterval.Seconds()
-		msgRate := float64(msgDiff) / AppConfig.Monitor.LogInterval.Seconds()
-		dbRate := float64(dbDiff) / AppConfig.Monitor.LogInterval.Seconds()
+		sentRate := ratePerSecond(float64(sentDiff), intervalElapsed)
+		msgRate := ratePerSecond(float64(msgDiff), intervalElapsed)
+		dbRate := ratePerSecond(float64(dbDiff), intervalElapsed)
+
+		// 与入库速率同一间隔测量数据库写入耗时
+		var probeLatency time.Duration
+		var probeErr error
+		if probing {
+			probeLatency, probeErr = probeWrite(probeDB)
+		}
 
 		// 使用从第一次发送开始的时间计算总平均速率
 		var totalSentRate, totalMsgRate, totalDBRate float64
-		if firstTime != nil && firstTime.(*time.Time) != nil && elapsedTime.Seconds() > 0 {
-			totalSentRate = float64(currentSentCount) / elapsedTime.Seconds()
-			totalMsgRate = float64(currentMsgCount) / elapsedTime.Seconds()
-			totalDBRate = float64(currentDBCount-initialCount) / elapsedTime.Seconds()
+		if firstTime != nil && firstTime.(*time.Time) != nil {
+			totalSentRate = ratePerSecond(float64(currentSentCount), elapsedTime)
+			totalMsgRate = ratePerSecond(float64(currentMsgCount), elapsedTime)
+			totalDBRate = ratePerSecond(float64(dbTotal), elapsedTime)
 		} else {
 			// 如果尚未开始发送，则速率为0
 			totalSentRate = 0
@@ -121,7 +210,7 @@ func MonitorLogs(initDone chan<- struct{}, firstSendTime *atomic.Value) {
 
 		// 计算写入成功率
 		successRate := 0.0
-		if sentDiff > 0 {
+		if sentDiff > 0 && !dbShrunk {
 			successRate = float64(dbDiff) / float64(sentDiff) * 100.0
 			// 限制最大显示为100%
 			if successRate > 100.0 {
@@ -132,45 +221,112 @@ func MonitorLogs(initDone chan<- struct{}, firstSendTime *atomic.Value) {
 		// 累计成功率
 		totalSuccessRate := 0.0
 		if currentSentCount > 0 {
-			totalSuccessRate = float64(currentDBCount-initialCount) / float64(currentSentCount) * 100.0
+			totalSuccessRate = float64(dbTotal) / float64(currentSentCount) * 100.0
 			// 限制最大显示为100%
 			if totalSuccessRate > 100.0 {
 				totalSuccessRate = 100.0
 			}
 		}
 
+		// 记录本间隔的速率和发布耗时，用于HTML报告的图表
+		advanceLatencyWindows(latencies)
+		intervalLatency := latencies[0].interval
+		recordThroughputSample(ThroughputSample{
+			Time:     tickTime,
+			SentRate: sentRate,
+			MsgRate:  msgRate,
+			DBRate:   dbRate,
+			P50:      intervalLatency.Quantile(0.50),
+			P99:      intervalLatency.Quantile(0.99),
+			DBProbe:  probeLatency,
+		})
+
+		// 自适应速率根据本间隔的发送和入库速率调整发送间隔
+		if !dbShrunk {
+			if sentDiff > 0 {
+				rateController.Observe(sentRate, dbRate)
+			}
+			alertMonitor.Observe(snap, sentDiff, dbRate, successRate)
+		}
+
 		// 打印监控信息
-		log.Printf("\n========== 监控报告 ==========")
-		log.Printf("已运行时间: %v", elapsedTime.Round(time.Second))
-		log.Printf("当前配置: 每条消息数据点数: %d", AppConfig.Data.DataPointCount)
-		log.Printf("当前间隔(%v)统计:", AppConfig.Monitor.LogInterval)
-		log.Printf("  - 已发送数据点: %d (本次新增: %d), 速率: %.1f 点/秒",
+		log.Printf(T("\n========== 监控报告 =========="))
+		log.Printf(T("已运行时间: %v"), elapsedTime.Round(time.Second))
+		log.Printf(T("当前配置: 每条消息数据点数: %d"), AppConfig.Data.DataPointCount)
+		log.Printf(T("当前间隔(%v)统计:"), intervalElapsed.Round(time.Millisecond))
+		log.Printf(T("  - 已发送数据点: %d (本次新增: %d), 速率: %.1f 点/秒"),
 			currentSentCount, sentDiff, sentRate)
-		log.Printf("  - 已发送消息: %d (本次新增: %d), 速率: %.1f 条/秒",
+		log.Printf(T("  - 已发送消息: %d (本次新增: %d), 速率: %.1f 条/秒"),
 			currentMsgCount, msgDiff, msgRate)
-		log.Printf("  - 数据库记录数: %d (本次新增: %d), 速率: %.1f 点/秒",
+		log.Printf(T("  - 数据库记录数: %d (本次新增: %d), 速率: %.1f 点/秒"),
 			currentDBCount, dbDiff, dbRate)
+		if sentReset {
+			log.Printf(T("  - 检测到计数器重置: 已发送数据点从 %d 变为 %d，本间隔按重置后重新计数"), lastSentCount, currentSentCount)
+		}
+		if msgReset {
+			log.Printf(T("  - 检测到计数器重置: 已发送消息从 %d 变为 %d，本间隔按重置后重新计数"), lastMsgCount, currentMsgCount)
+		}
+		if dbShrunk {
+			log.Printf(T("  - 数据库记录数减少 %d (从 %d 变为 %d)，可能有数据保留任务在清理旧数据；本间隔入库数无法确定，不计入累计统计，需要准确统计时设置 monitor.count_mode: window"),
+				lastDBCount-currentDBCount, lastDBCount, currentDBCount)
+		}
+
+		if replicaEnabled() {
+			// 副本复制落后时本间隔的入库数偏低，速率需结合复制延迟判断
+			ctx, cancel := context.WithTimeout(context.Background(), AppConfig.Monitor.QueryTimeout)
+			if lag, err := replicaLag(ctx, db); err == nil {
+				log.Printf(T("  - 只读副本复制延迟: %v"), lag.Round(time.Millisecond))
+			}
+			cancel()
+		}
+		if probeErr != nil {
+			log.Printf(T("  - 数据库写入探测失败: %v"), probeErr)
+		} else if probing {
+			log.Printf(T("  - 数据库写入探测: %v"), probeLatency.Round(time.Microsecond))
+		}
 
 		// 只在有新数据时显示写入率
-		if sentDiff > 0 {
-			log.Printf("  - 本次写入率: %.1f%% (数据库新增/发送新增)", successRate)
+		if sentDiff > 0 && !dbShrunk {
+			log.Printf(T("  - 本次写入率: %.1f%% (数据库新增/发送新增)"), successRate)
+			// 入库落后时根据探测耗时区分数据库慢还是消费端慢
+			if probing && probeErr == nil && successRate < 95.0 {
+				log.Printf(T("  - 入库落后: %s"), probeDiagnosis(probeLatency))
+			}
+		}
+		logLatencyWindows(latencies)
+
+		logDeviceStates()
+		logAnomaly(db)
+		logDashboards()
+
+		// 主循环晚于计划触发时，本间隔的速率低于配置值
+		behind := schedule.behindSchedule()
+		if behindDiff := behind - lastBehind; behindDiff > 0 {
+			log.Printf(T("  - 调度落后: 本次 %v (累计 %v)，发送未能按计划间隔进行"),
+				behindDiff.Round(time.Millisecond), behind.Round(time.Millisecond))
+		}
+		lastBehind = behind
+
+		// 本间隔内的标注与指标一起输出，便于对照
+		for _, a := range annotationsSince(lastTickTime) {
+			log.Printf(T("  - 标注 %s [%s]: %s"), a.Time.Format("15:04:05"), a.Source, a.Text)
 		}
 
-		log.Printf("累计统计:")
-		log.Printf("  - 总发送数据点: %d, 平均速率: %.1f 点/秒",
+		log.Printf(T("累计统计:"))
+		log.Printf(T("  - 总发送数据点: %d, 平均速率: %.1f 点/秒"),
 			currentSentCount, totalSentRate)
-		log.Printf("  - 总发送消息: %d, 平均速率: %.1f 条/秒",
+		log.Printf(T("  - 总发送消息: %d, 平均速率: %.1f 条/秒"),
 			currentMsgCount, totalMsgRate)
-		log.Printf("  - 总入库数据点: %d, 平均速率: %.1f 点/秒",
-			currentDBCount-initialCount, totalDBRate)
+		log.Printf(T("  - 总入库数据点: %d, 平均速率: %.1f 点/秒"),
+			dbTotal, totalDBRate)
 
 		// 有数据发送时才计算成功率和平均值
 		if currentSentCount > 0 {
-			log.Printf("  - 总体写入率: %.1f%% (总入库/总发送)", totalSuccessRate)
+			log.Printf(T("  - 总体写入率: %.1f%% (总入库/总发送)"), totalSuccessRate)
 
 			// 如果数据库新增明显超过发送量，给出提示
 			if totalSuccessRate > 95.0 {
-				log.Printf("  - 注意: 数据库可能还在处理之前的数据")
+				log.Printf(T("  - 注意: 数据库可能还在处理之前的数据"))
 			}
 		}
 
@@ -178,18 +334,18 @@ func MonitorLogs(initDone chan<- struct{}, firstSendTime *atomic.Value) {
 		if currentMsgCount > 0 {
 			// 计算实际平均每条消息的数据点数
 			avgPointsPerMsg := float64(currentSentCount) / float64(currentMsgCount)
-			log.Printf("  - 实际平均每条消息数据点数: %.2f", avgPointsPerMsg)
+			log.Printf(T("  - 实际平均每条消息数据点数: %.2f"), avgPointsPerMsg)
 		}
 
 		// 如果配置了数据点数，计算基于数据点的理论消息数(用于与实际消息数对比验证)
 		if AppConfig.Data.DataPointCount > 0 && currentSentCount > 0 {
 			theoreticalMsgCount := currentSentCount / uint64(AppConfig.Data.DataPointCount)
-			log.Printf("  - 基于数据点计算的理论消息数: %d (用于验证)", theoreticalMsgCount)
+			log.Printf(T("  - 基于数据点计算的理论消息数: %d (用于验证)"), theoreticalMsgCount)
 
 			// 如果有实际消息，计算理论值与实际值的差异率
 			if currentMsgCount > 0 {
 				diffRate := (float64(theoreticalMsgCount) - float64(currentMsgCount)) / float64(currentMsgCount) * 100.0
-				log.Printf("  - 理论值与实际值差异: %.2f%%", diffRate)
+				log.Printf(T("  - 理论值与实际值差异: %.2f%%"), diffRate)
 			}
 		}
 
@@ -199,5 +355,118 @@ func MonitorLogs(initDone chan<- struct{}, firstSendTime *atomic.Value) {
 		lastDBCount = currentDBCount
 		lastSentCount = currentSentCount
 		lastMsgCount = currentMsgCount
+		lastTickTime = tickTime
+	}
+}
+
+// monitorWithoutDatabase 使用本地Broker或消息接收端时代替数据库监控，按间隔输出发送速率，
+// 以及Broker收到或接收端写入的数量
+func monitorWithoutDatabase(initDone chan<- struct{}) {
+	if localBrokerEnabled() {
+		log.Printf(T("监控模块: 使用本地Broker %s，不连接数据库，监控Broker收到的消息，监控间隔: %v"),
+			localBroker.Addr(), AppConfig.Monitor.LogInterval)
+		localBrokerStats.baseline.Store(localBrokerStats.telemetry.Load())
+	} else {
+		log.Printf(T("监控模块: 消息由本地接收端(%s)处理，不连接数据库，监控间隔: %v"),
+			AppConfig.Sink.Mode, AppConfig.Monitor.LogInterval)
+	}
+	close(initDone)
+
+	ticker := time.NewTicker(AppConfig.Monitor.LogInterval)
+	defer ticker.Stop()
+	lastTick := time.Now()
+	lastSent, lastReceived := runStats.Snapshot().Messages, localBrokerTelemetry()
+	lastBytes := sinkStats.bytes.Load()
+	latencies := latencyWindows()
+	for range ticker.C {
+		now := time.Now()
+		elapsed := now.Sub(lastTick)
+		sent, received := runStats.Snapshot().Messages, localBrokerTelemetry()
+		bytes := sinkStats.bytes.Load()
+		sentDiff, sentReset := counterDelta(sent, lastSent)
+		receivedDiff, receivedReset := counterDelta(received, lastReceived)
+		bytesDiff, bytesReset := counterDelta(bytes, lastBytes)
+		log.Printf(T("\n========== 监控报告 =========="))
+		log.Printf(T("  - 发送遥测消息: 本次 %d 条 (%.1f 条/秒), 累计 %d 条"),
+			sentDiff, ratePerSecond(float64(sentDiff), elapsed), sent)
+		if localBrokerEnabled() {
+			log.Printf(T("  - 本地Broker收到: 本次 %d 条 (%.1f 条/秒), 累计 %d 条, 当前连接 %d"),
+				receivedDiff, ratePerSecond(float64(receivedDiff), elapsed), received,
+				localBrokerStats.connected.Load())
+		}
+		if sinkEnabled() {
+			log.Printf(T("  - 接收端: 本次 %.1f MB (%.1f MB/秒), 累计 %.1f MB"),
+				float64(bytesDiff)/(1<<20), ratePerSecond(float64(bytesDiff)/(1<<20), elapsed), float64(bytes)/(1<<20))
+		}
+		if sentReset || receivedReset || bytesReset {
+			log.Printf(T("  - 检测到计数器重置，本间隔按重置后重新计数"))
+		}
+		advanceLatencyWindows(latencies)
+		logLatencyWindows(latencies)
+		logDeviceStates()
+		logDashboards()
+		log.Printf("==============================")
+		lastTick, lastSent, lastReceived, lastBytes = now, sent, received, bytes
+	}
+}
+
+// latencyTracker 监控报告中按间隔输出分位数的一项耗时
+type latencyTracker struct {
+	name     string
+	total    *Histogram
+	window   *HistogramWindow
+	interval *Histogram // 最近一个间隔内的记录
+	recent   *Histogram // 最近monitor.latency_window个间隔内的记录
+}
+
+// latencyWindows 创建监控报告中输出的各项耗时的窗口，第一项固定为遥测消息的发布耗时
+func latencyWindows() []*latencyTracker {
+	n := AppConfig.Monitor.LatencyWindow
+	add := func(windows []*latencyTracker, name string, h *Histogram) []*latencyTracker {
+		return append(windows, &latencyTracker{name: name, total: h, window: NewHistogramWindow(h, n)})
+	}
+	windows := add(nil, T("发布耗时"), &publishLatency)
+	if heartbeatStats != nil {
+		windows = add(windows, T("心跳发布耗时"), &heartbeatStats.Latency)
+	}
+	if glassLatencyEnabled() {
+		windows = add(windows, T("看板端到端延迟"), &glassLatency.latency)
+	}
+	if attrSetEnabled() {
+		windows = add(windows, T("属性下发到设备回复"), &attrSet.ackLatency)
+	}
+	if commandFloodEnabled() {
+		windows = add(windows, T("命令下发到设备回复"), &commandFlood.ackLatency)
+	}
+	if historyQueryEnabled() {
+		windows = add(windows, T("历史/聚合查询耗时"), &historyQuery.total)
+	}
+	return windows
+}
+
+// advanceLatencyWindows 结束当前间隔，更新各项耗时的间隔和窗口分布
+func advanceLatencyWindows(windows []*latencyTracker) {
+	for _, w := range windows {
+		w.interval, w.recent = w.window.Advance()
+	}
+}
+
+// logLatencyWindows 输出各项耗时在本间隔内的分位数，以及最近窗口和累计的p99作为对照
+//
+// 只看累计分位数时，长时间测试后期的劣化会被前期的大量样本平均掉。
+func logLatencyWindows(windows []*latencyTracker) {
+	for _, w := range windows {
+		h := w.interval
+		if h.Count() == 0 {
+			continue
+		}
+		if AppConfig.Monitor.LatencyWindow > 1 {
+			log.Printf(T("  - %s: 本间隔 %d 次, p50=%v, p99=%v, 最大=%v; 最近 %d 个间隔 p99=%v; 累计 p99=%v"),
+				w.name, h.Count(), h.Quantile(0.50), h.Quantile(0.99), h.Max(),
+				AppConfig.Monitor.LatencyWindow, w.recent.Quantile(0.99), w.total.Quantile(0.99))
+		} else {
+			log.Printf(T("  - %s: 本间隔 %d 次, p50=%v, p99=%v, 最大=%v; 累计 p99=%v"),
+				w.name, h.Count(), h.Quantile(0.50), h.Quantile(0.99), h.Max(), w.total.Quantile(0.99))
+		}
 	}
 }
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// MQTT 5命令行参数
+var (
+	mqttVersion    = flag.Int("mqtt-version", 0, "MQTT协议版本(4: 3.1.1, 5: MQTT 5，仅lite引擎)")
+	contentType    = flag.String("content-type", "", "MQTT 5: 每条消息的内容类型属性(如 application/json)")
+	userProperties = flag.String("user-properties", "", "MQTT 5: 每条消息附带的用户属性，逗号分隔的 key=value，{device}为设备token，如 model=TP-100,fw=1.2.3")
+)
+
+// MQTT 5 属性标识符
+const (
+	propContentType  = 0x03
+	propUserProperty = 0x26
+)
+
+// mqtt5Enabled 是否使用MQTT 5协议
+func mqtt5Enabled() bool {
+	return AppConfig.MQTT.Version == 5
+}
+
+// publishRejected 服务器在PUBACK中以原因码拒绝发布(如0x97: 超出配额)
+type publishRejected struct {
+	code byte
+}
+
+func (e *publishRejected) Error() string {
+	return fmt.Sprintf(T("服务器拒绝发布，原因码: 0x%02x"), e.code)
+}
+
+// connectRefused 服务器在CONNACK中拒绝连接(认证失败、未授权等)，区别于网络错误
+type connectRefused struct {
+	code byte
+	v5   bool // code为MQTT 5原因码，否则为MQTT 3.1.1返回码
+}
+
+func (e *connectRefused) Error() string {
+	if e.v5 {
+		return fmt.Sprintf(T("服务器拒绝连接，原因码: 0x%02x"), e.code)
+	}
+	return fmt.Sprintf(T("服务器拒绝连接，返回码: %d"), e.code)
+}
+
+// UserProperty MQTT 5用户属性
+type UserProperty struct {
+	Key   string `yaml:"key"`
+	Value string `yaml:"value"` // {device}为设备token
+}
+
+// parseUserProperties 解析 key=value 形式的用户属性列表
+func parseUserProperties(list string) ([]UserProperty, error) {
+	var props []UserProperty
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(item, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf(T("无效的用户属性 %s，应为 key=value"), item)
+		}
+		props = append(props, UserProperty{Key: key, Value: value})
+	}
+	return props, nil
+}
+
+// publishProperties 编码设备每条消息附带的MQTT 5属性(不含属性长度)
+//
+// 属性内容在设备的整个测试期间不变，连接建立前编码一次，发布时直接追加到报文中。
+func publishProperties(username string) []byte {
+	if !mqtt5Enabled() {
+		return nil
+	}
+	cfg := AppConfig.MQTT.Properties
+	var props []byte
+	if cfg.ContentType != "" {
+		props = append(props, propContentType)
+		props = appendMQTTString(props, cfg.ContentType)
+	}
+	for _, p := range cfg.User {
+		props = append(props, propUserProperty)
+		props = appendMQTTString(props, p.Key)
+		props = appendMQTTString(props, strings.ReplaceAll(p.Value, "{device}", username))
+	}
+	return props
+}
+
+// remainingLengthSize 按MQTT变长编码表示n所需的字节数
+func remainingLengthSize(n int) int {
+	size := 1
+	for n >= 128 {
+		n /= 128
+		size++
+	}
+	return size
+}
+
+// readPacket 读取一个完整报文，返回固定报头和报文体
+//
+// MQTT 5的CONNACK和PUBACK带有可变长度的属性，不能像3.1.1那样按固定长度读取。
+func readPacket(conn io.Reader, buf []byte) (byte, []byte, error) {
+	var b [1]byte
+	if _, err := io.ReadFull(conn, b[:]); err != nil {
+		return 0, nil, err
+	}
+	header := b[0]
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return 0, nil, errors.New(T("无效的剩余长度"))
+		}
+		if _, err := io.ReadFull(conn, b[:]); err != nil {
+			return 0, nil, err
+		}
+		length += int(b[0]&0x7f) * multiplier
+		if b[0]&0x80 == 0 {
+			break
+		}
+		multiplier *= 128
+	}
+	if cap(buf) < length {
+		buf = make([]byte, length)
+	}
+	buf = buf[:length]
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return 0, nil, err
+	}
+	return header, buf, nil
+}
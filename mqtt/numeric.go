@@ -0,0 +1,110 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"math"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/brianvoe/gofakeit/v7"
+)
+
+// 数值边界命令行参数
+var (
+	precision    = flag.Int("precision", 0, "数值保留的小数位数，0为不限制(按float64最短表示)")
+	numericEdges = flag.Bool("numeric-edges", false, "数值边界测试：按比例混入整数、科学计数法、极大/极小数值和字符串形式的NaN/Infinity，测试平台的数值解析和存储")
+)
+
+// numericSpecials 以字符串形式注入的非有限数值，JSON本身不能表示NaN和Infinity
+var numericSpecials = []string{`"NaN"`, `"Infinity"`, `"-Infinity"`}
+
+// numericExtremes 原样写入的极端数值字面量
+var numericExtremes = []string{
+	"1.7976931348623157e308",  // float64最大值
+	"-1.7976931348623157e308", // float64最小值
+	"5e-324",                  // 最小的正非规格化数
+	"2.2250738585072014e-308", // 最小的正规格化数
+	"1e-300",
+	"1E+21",            // 大写E和显式正号
+	"9007199254740993", // 2^53+1，转为float64后丢失精度
+	"-9223372036854775808",
+	"-0",
+	"0.000001",
+	"123456789012345678901234567890",
+}
+
+// numericStats 各类数值的发送数量
+var numericStats struct {
+	special    atomic.Uint64
+	extreme    atomic.Uint64
+	integer    atomic.Uint64
+	scientific atomic.Uint64
+}
+
+// numericEnabled 是否按数值边界配置编码数值
+func numericEnabled() bool {
+	n := AppConfig.Numeric
+	return n.Precision > 0 || n.SpecialRatio > 0 || n.ExtremeRatio > 0 || n.IntegerRatio > 0 || n.ScientificRatio > 0
+}
+
+// numericGenerator 按数值边界配置逐个编码数据点的生成器
+type numericGenerator struct {
+	jsonGenerator
+}
+
+func (g *numericGenerator) Next() ([]byte, error) {
+	updateSensorData(g.data, g.faker)
+
+	g.buf = getPayloadBuffer()
+	buf := append(*g.buf, '{')
+	for i := range g.data {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, g.data[i].field...)
+		buf = appendNumeric(buf, g.data[i].Value, g.faker)
+	}
+	*g.buf = append(buf, '}')
+	return *g.buf, nil
+}
+
+// appendNumeric 按配置的比例选择数值的编码方式
+func appendNumeric(buf []byte, v float64, faker *gofakeit.Faker) []byte {
+	n := AppConfig.Numeric
+	r := faker.Float64Range(0, 100)
+	switch {
+	case r < n.SpecialRatio:
+		numericStats.special.Add(1)
+		return append(buf, numericSpecials[faker.IntN(len(numericSpecials))]...)
+	case r < n.SpecialRatio+n.ExtremeRatio:
+		numericStats.extreme.Add(1)
+		return append(buf, numericExtremes[faker.IntN(len(numericExtremes))]...)
+	case r < n.SpecialRatio+n.ExtremeRatio+n.IntegerRatio:
+		numericStats.integer.Add(1)
+		return strconv.AppendInt(buf, int64(math.Round(v)), 10)
+	case r < n.SpecialRatio+n.ExtremeRatio+n.IntegerRatio+n.ScientificRatio:
+		numericStats.scientific.Add(1)
+		prec := -1
+		if n.Precision > 0 {
+			prec = n.Precision
+		}
+		return strconv.AppendFloat(buf, v, 'e', prec, 64)
+	case n.Precision > 0:
+		return strconv.AppendFloat(buf, v, 'f', n.Precision, 64)
+	}
+	buf, _ = appendJSONFloat(buf, v)
+	return buf
+}
+
+// reportNumeric 输出各类边界数值的发送数量(未启用时不输出)
+func reportNumeric() {
+	if !numericEnabled() {
+		return
+	}
+	log.Println(T("\n========== 数值边界 =========="))
+	log.Printf(T("字符串NaN/Infinity: %d, 极端数值: %d, 整数: %d, 科学计数法: %d"),
+		numericStats.special.Load(), numericStats.extreme.Load(), numericStats.integer.Load(), numericStats.scientific.Load())
+	log.Println(T("核对平台中这些数据点的入库值和解析错误日志，数据库写入率低于100%时检查是否被拒绝"))
+	log.Println("===============================")
+}
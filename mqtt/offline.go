@@ -0,0 +1,329 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 离线缓存场景命令行参数
+var (
+	offlineFlag   = flag.Bool("offline", false, "离线缓存场景：部分设备离线若干循环并在本地缓存数据，重连后一次性补发全部缓存")
+	offlineStart  = flag.Int("offline-start", 0, "设备在第几个循环离线(默认为总循环数的三分之一)")
+	offlineCycles = flag.Int("offline-cycles", 0, "离线持续的循环数(默认为总循环数的三分之一)")
+	offlineRatio  = flag.Float64("offline-ratio", 0, "参与离线的设备比例(%)，默认10")
+)
+
+// maxOfflineSamples 测试结束后到数据库核对的离线设备数上限
+const maxOfflineSamples = 20
+
+// offlinePhase 设备在某个循环所处的离线阶段
+type offlinePhase int
+
+const (
+	offlineNone   offlinePhase = iota // 在线，正常发送
+	offlineBuffer                     // 离线，只缓存本循环的数据
+	offlineFlush                      // 恢复在线，先补发缓存再正常发送
+)
+
+// offlineDevice 参与离线缓存场景的设备
+type offlineDevice struct {
+	username string
+	topic    string // 遥测主题(多租户分组时为所在组的主题)
+	start    uint64 // 离线的第一个循环
+	end      uint64 // 恢复在线的循环
+	offline  bool
+
+	backlog [][]byte // 离线期间缓存的消息，按生成顺序
+	stamps  []int64  // 缓存消息的计划发送时间(毫秒)
+
+	sample  bool    // 是否在测试结束后核对入库结果
+	flushed []int64 // 补发成功的消息时间戳(仅抽样设备)
+	last    []byte  // 最后一条发送成功的实时消息(仅抽样设备)
+}
+
+// offlineStats 离线缓存场景统计
+var offlineStats struct {
+	devices         uint64    // 离线的设备数
+	buffered        uint64    // 缓存的消息数
+	flushed         uint64    // 补发成功的消息数
+	failed          uint64    // 补发失败的消息数
+	reconnectFailed uint64    // 恢复在线时重连失败的设备数
+	flushTime       Histogram // 单个设备补发全部缓存的耗时
+	latency         Histogram // 补发消息的发布耗时
+
+	mu         sync.Mutex
+	firstFlush time.Time // 第一个设备开始补发的时间
+	lastFlush  time.Time // 最后一个设备补发完成的时间
+	samples    []*offlineDevice
+}
+
+// newOfflineDevice 按token哈希选择参与离线的设备，未选中时返回nil
+func newOfflineDevice(username, topic string) *offlineDevice {
+	cfg := AppConfig.Offline
+	if !cfg.Enabled || !hashSelected(username, cfg.Ratio) {
+		return nil
+	}
+	d := &offlineDevice{
+		username: username,
+		topic:    topic,
+		start:    uint64(cfg.StartCycle),
+		end:      uint64(cfg.StartCycle + cfg.Cycles),
+	}
+
+	offlineStats.mu.Lock()
+	if len(offlineStats.samples) < maxOfflineSamples {
+		d.sample = true
+		offlineStats.samples = append(offlineStats.samples, d)
+	}
+	offlineStats.mu.Unlock()
+	return d
+}
+
+// phase 返回设备在本循环的离线阶段
+//
+// 按状态而不是精确的循环号切换，设备因发布阻塞错过离线或恢复的那个循环时仍能正确进入下一阶段。
+func (d *offlineDevice) phase(cycle uint64) offlinePhase {
+	if cycle >= d.start && cycle < d.end {
+		return offlineBuffer
+	}
+	if d.offline {
+		return offlineFlush
+	}
+	return offlineNone
+}
+
+// buffer 离线期间生成本循环的数据并缓存，首次进入时断开连接
+//
+// 缓存的消息加入计划发送时间戳(time_key)，平台据此按原始时间写入历史数据。
+func (d *offlineDevice) buffer(client DeviceClient, generator PayloadGenerator, slot sendSlot) error {
+	if !d.offline {
+		d.offline = true
+		atomic.AddUint64(&offlineStats.devices, 1)
+		client.Disconnect()
+	}
+
+	payload, err := generator.Next()
+	if err != nil {
+		return err
+	}
+	ts := slot.at.UnixMilli()
+	d.backlog = append(d.backlog, withTimestamp(payload, AppConfig.Offline.TimeKey, ts))
+	d.stamps = append(d.stamps, ts)
+	generator.Done()
+	atomic.AddUint64(&offlineStats.buffered, 1)
+	return nil
+}
+
+// flush 重连并以最快速度按顺序补发全部缓存，补发的消息计入遥测消息数和数据点数
+func (d *offlineDevice) flush(ctx context.Context, client DeviceClient, qos byte, points int, stats *DeviceStats) {
+	d.offline = false
+	backlog, stamps := d.backlog, d.stamps
+	d.backlog, d.stamps, d.last = nil, nil, nil
+
+	if err := client.Connect(); err != nil {
+		atomic.AddUint64(&offlineStats.reconnectFailed, 1)
+		atomic.AddUint64(&offlineStats.failed, uint64(len(backlog)))
+		logErrorf(T("设备 %s 恢复在线时重连失败，丢弃 %d 条缓存消息: %v"), d.username, len(backlog), err)
+		for range backlog {
+			runStats.RecordPublish(telemetryStream, qos, clientBroker(client), points, err)
+		}
+		return
+	}
+
+	start := time.Now()
+	for i, payload := range backlog {
+		if ctx.Err() != nil {
+			break
+		}
+		publishStart := time.Now()
+		err := publishWithRetry(ctx, client, d.topic, qos, payload)
+		publishElapsed := time.Since(publishStart)
+		stats.recordPublish(publishElapsed, err)
+		runStats.RecordPublish(telemetryStream, qos, clientBroker(client), points, err)
+		if err != nil {
+			atomic.AddUint64(&offlineStats.failed, 1)
+			continue
+		}
+		atomic.AddUint64(&offlineStats.flushed, 1)
+		offlineStats.latency.Record(publishElapsed)
+		if d.sample {
+			d.flushed = append(d.flushed, stamps[i])
+		}
+	}
+	end := time.Now()
+	offlineStats.flushTime.Record(end.Sub(start))
+
+	offlineStats.mu.Lock()
+	if offlineStats.firstFlush.IsZero() || start.Before(offlineStats.firstFlush) {
+		offlineStats.firstFlush = start
+	}
+	if end.After(offlineStats.lastFlush) {
+		offlineStats.lastFlush = end
+	}
+	offlineStats.mu.Unlock()
+}
+
+// recordLive 记录发送成功的实时消息，用于核对平台的当前值
+func (d *offlineDevice) recordLive(payload []byte) {
+	if d.sample {
+		d.last = append(d.last[:0], payload...)
+	}
+}
+
+// withTimestamp 在JSON对象消息的开头加入毫秒时间戳字段，返回新的切片
+func withTimestamp(payload []byte, key string, ts int64) []byte {
+	if key == "" || len(payload) < 2 || payload[0] != '{' {
+		return append([]byte(nil), payload...)
+	}
+	quoted, _ := json.Marshal(key)
+	buf := make([]byte, 0, len(payload)+len(quoted)+16)
+	buf = append(buf, '{')
+	buf = append(buf, quoted...)
+	buf = append(buf, ':')
+	buf = strconv.AppendInt(buf, ts, 10)
+	if len(payload) > 2 {
+		buf = append(buf, ',')
+	}
+	return append(buf, payload[1:]...)
+}
+
+// reportOffline 输出离线缓存补发统计，并到数据库核对抽样设备的入库结果(未启用时不输出)
+func reportOffline() {
+	cfg := AppConfig.Offline
+	if !cfg.Enabled {
+		return
+	}
+	devices := atomic.LoadUint64(&offlineStats.devices)
+	log.Println(T("\n========== 离线缓存补发 =========="))
+	if devices == 0 {
+		log.Printf(T("测试未进行到第 %d 个循环，没有设备离线"), cfg.StartCycle)
+		log.Println("===============================")
+		return
+	}
+
+	buffered := atomic.LoadUint64(&offlineStats.buffered)
+	flushed, failed := atomic.LoadUint64(&offlineStats.flushed), atomic.LoadUint64(&offlineStats.failed)
+	log.Printf(T("离线设备: %d, 离线循环: 第 %d-%d 个"), devices, cfg.StartCycle, cfg.StartCycle+cfg.Cycles-1)
+	log.Printf(T("缓存消息: %d, 补发成功 %d (%.2f%%), 补发失败 %d"), buffered, flushed, percent(flushed, buffered), failed)
+	if pending := buffered - min(flushed+failed, buffered); pending > 0 {
+		log.Printf(T("未补发: %d 条(测试在设备恢复在线前结束)"), pending)
+	}
+	if n := atomic.LoadUint64(&offlineStats.reconnectFailed); n > 0 {
+		log.Printf(T("恢复在线时重连失败的设备: %d"), n)
+	}
+	if offlineStats.flushTime.Count() > 0 {
+		log.Printf(T("单设备补发耗时: p50=%v, p99=%v, 最大=%v"),
+			offlineStats.flushTime.Quantile(0.50), offlineStats.flushTime.Quantile(0.99), offlineStats.flushTime.Max())
+		log.Printf(T("补发消息发布耗时: p50=%v, p99=%v, 最大=%v"),
+			offlineStats.latency.Quantile(0.50), offlineStats.latency.Quantile(0.99), offlineStats.latency.Max())
+		offlineStats.mu.Lock()
+		window := offlineStats.lastFlush.Sub(offlineStats.firstFlush)
+		offlineStats.mu.Unlock()
+		log.Printf(T("补发突发: %v 内补发 %d 条, %.2f 条/秒(入库情况见该时段的监控输出)"),
+			window.Round(time.Millisecond), flushed, ratePerSecond(float64(flushed), window))
+	}
+	verifyOffline()
+	log.Println("===============================")
+}
+
+// verifyOffline 核对抽样设备的补发数据是否按原始时间戳入库，以及当前值是否为最后一条实时消息
+//
+// 平台乱序处理补发数据或把历史数据当作最新数据时，当前值会回退为某条缓存消息的值。
+func verifyOffline() {
+	var samples []*offlineDevice
+	offlineStats.mu.Lock()
+	for _, d := range offlineStats.samples {
+		if len(d.flushed) > 0 && len(d.last) > 0 {
+			samples = append(samples, d)
+		}
+	}
+	offlineStats.mu.Unlock()
+	if len(samples) == 0 {
+		return
+	}
+
+	db, err := openReadDatabase()
+	if err != nil {
+		log.Printf(T("离线缓存核对: %v"), err)
+		return
+	}
+	defer db.Close()
+
+	ingestSettle()
+	waitReplicaReplay(db)
+	var stored, expected, current, mismatched int
+	for _, d := range samples {
+		key := sensorKeys(d.username, 1)[0]
+		result, err := verifyOfflineDevice(db, d, key)
+		if err != nil {
+			log.Printf(T("离线缓存核对: 设备 %s: %v"), d.username, err)
+			continue
+		}
+		stored += result.stored
+		expected += len(d.flushed)
+		if result.currentOK {
+			current++
+		} else {
+			mismatched++
+			log.Printf(T("离线缓存核对: 设备 %s 的 %s 当前值为 %v，最后发送的值为 %v"),
+				d.username, key, result.current, result.sent)
+		}
+	}
+	if expected == 0 {
+		return
+	}
+	log.Printf(T("抽样核对 %d 个设备(各设备的第一个数据点):"), current+mismatched)
+	log.Printf(T("- 补发数据按原始时间戳入库: %d/%d (%.2f%%)，偏低说明平台丢弃了补发数据或以接收时间入库"),
+		stored, expected, percent(uint64(stored), uint64(expected)))
+	log.Printf(T("- 当前值为最后一条实时消息: %d/%d"), current, current+mismatched)
+}
+
+// offlineCheck 单个设备的核对结果
+type offlineCheck struct {
+	stored    int
+	currentOK bool
+	current   float64
+	sent      float64
+}
+
+// verifyOfflineDevice 查询单个设备补发时间范围内的历史数据点数和当前值
+func verifyOfflineDevice(db *sql.DB, d *offlineDevice, key string) (offlineCheck, error) {
+	var check offlineCheck
+	sent, err := sentValues(d.last)
+	if err != nil {
+		return check, err
+	}
+	check.sent = sent[key]
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	deviceID, err := deviceIDByToken(ctx, db, d.username)
+	if err != nil {
+		return check, err
+	}
+
+	first, last := d.flushed[0], d.flushed[len(d.flushed)-1]
+	err = db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM telemetry_datas WHERE device_id = $1 AND key = $2 AND ts BETWEEN $3 AND $4`,
+		deviceID, key, first, last).Scan(&check.stored)
+	if err != nil {
+		return check, fmt.Errorf(T("查询历史数据失败: %w"), err)
+	}
+
+	current, err := currentValues(ctx, db, deviceID)
+	if err != nil {
+		return check, err
+	}
+	check.current = current[key]
+	check.currentOK = sameValue(check.current, check.sent)
+	return check, nil
+}
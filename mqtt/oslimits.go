@@ -0,0 +1,106 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// 系统限制相关命令行参数
+var capClients = flag.Bool("cap-clients", false, "系统限制不足时自动减少设备数量，而不是拒绝启动")
+
+// fileLimitReserve 除设备连接外预留的文件描述符(数据库连接、日志文件等)
+const fileLimitReserve = 64
+
+// tuneOSLimits 启动时检测并尽量放宽影响连接数的系统限制
+//
+// 文件描述符软上限会自动提高到硬上限以内；本地端口范围和监听队列长度只能由管理员修改，
+// 这里只做检测。启用 --cap-clients 时按实际允许的连接数减少设备数量。
+func tuneOSLimits() {
+	clients := uint64(AppConfig.Device.ClientNumber)
+
+	soft, hard, ok := openFileLimit()
+	if ok {
+		if raised, err := raiseFileLimit(clients + fileLimitReserve); err != nil {
+			log.Printf(T("警告: 提高文件描述符上限失败: %v"), err)
+		} else if raised > soft {
+			log.Printf(T("已将文件描述符软上限从 %d 提高到 %d (硬上限 %d)"), soft, raised, hard)
+			soft = raised
+		}
+	}
+
+	// 同一台机器连接同一个Broker地址时，每个连接占用一个本地端口
+	ports := localPortCount()
+
+	// Broker在本机时，连接风暴可能超出监听队列长度导致SYN被丢弃
+	if somaxconn := readSysctl("net/core/somaxconn"); somaxconn > 0 && somaxconn < clients && brokerIsLocal() {
+		log.Printf(T("警告: net.core.somaxconn=%d 小于设备数量，本机Broker在集中连接时可能丢弃连接请求，可执行 sysctl -w net.core.somaxconn=%d"),
+			somaxconn, clients)
+	}
+
+	if !*capClients {
+		return
+	}
+	allowed := clients
+	if ok && soft < allowed+fileLimitReserve {
+		allowed = 0
+		if soft > fileLimitReserve {
+			allowed = soft - fileLimitReserve
+		}
+	}
+	if ports > 0 && ports < allowed {
+		allowed = ports
+	}
+	if allowed < clients {
+		log.Printf(T("警告: 受系统限制(文件描述符上限 %d, 本地端口 %d)，设备数量从 %d 减少到 %d"),
+			soft, ports, clients, allowed)
+		AppConfig.Device.ClientNumber = int(allowed)
+	}
+}
+
+// localPortCount 返回可用于主动连接的本地端口数量，未知时返回0
+func localPortCount() uint64 {
+	data, err := os.ReadFile("/proc/sys/net/ipv4/ip_local_port_range")
+	if err != nil {
+		return 0
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) != 2 {
+		return 0
+	}
+	low, err1 := strconv.ParseUint(fields[0], 10, 32)
+	high, err2 := strconv.ParseUint(fields[1], 10, 32)
+	if err1 != nil || err2 != nil || high < low {
+		return 0
+	}
+	return high - low + 1
+}
+
+// readSysctl 读取/proc/sys下的整数参数，不存在时返回0
+func readSysctl(name string) uint64 {
+	data, err := os.ReadFile("/proc/sys/" + name)
+	if err != nil {
+		return 0
+	}
+	value, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return value
+}
+
+// brokerIsLocal Broker地址是否指向本机
+func brokerIsLocal() bool {
+	host, _, err := net.SplitHostPort(brokerAddress(AppConfig.MQTT.Server))
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin
+
+package main
+
+// openFileLimit 当前平台不检查文件数上限
+func openFileLimit() (soft, hard uint64, ok bool) {
+	return 0, 0, false
+}
+
+// raiseFileLimit 当前平台无需调整文件数上限
+func raiseFileLimit(need uint64) (uint64, error) {
+	return 0, nil
+}
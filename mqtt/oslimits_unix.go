@@ -0,0 +1,34 @@
+//go:build linux || darwin
+
+package main
+
+import "syscall"
+
+// openFileLimit 返回进程可打开文件数的软上限和硬上限
+func openFileLimit() (soft, hard uint64, ok bool) {
+	var rlimit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rlimit); err != nil {
+		return 0, 0, false
+	}
+	return uint64(rlimit.Cur), uint64(rlimit.Max), true
+}
+
+// raiseFileLimit 将软上限提高到need(不超过硬上限)，返回调整后的软上限
+func raiseFileLimit(need uint64) (uint64, error) {
+	var rlimit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rlimit); err != nil {
+		return 0, err
+	}
+	if uint64(rlimit.Cur) >= need {
+		return uint64(rlimit.Cur), nil
+	}
+	target := need
+	if target > uint64(rlimit.Max) {
+		target = uint64(rlimit.Max)
+	}
+	rlimit.Cur = target
+	if err := syscall.Setrlimit(syscall.RLIMIT_NOFILE, &rlimit); err != nil {
+		return 0, err
+	}
+	return target, nil
+}
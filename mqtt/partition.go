@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// partitionUpperBound 从范围分区的边界表达式中提取上界，如 FOR VALUES FROM ('0') TO ('1700000000000')
+var partitionUpperBound = regexp.MustCompile(`TO \('?(-?\d+)'?\)`)
+
+// partitionState 最近一次统计的分区，统计的分区变化时输出日志
+var partitionState struct {
+	mu     sync.Mutex
+	seen   bool
+	logged string
+}
+
+// activePartitions 返回telemetry_datas中可能包含ts不早于since的数据的分区或chunk
+//
+// TimescaleDB按chunk的时间范围筛选；原生分区表按范围分区的上界筛选，无法解析上界的分区
+// (默认分区、MAXVALUE、非范围分区)都保留。partitioned表示表是否分区。
+func activePartitions(ctx context.Context, db *sql.DB, since int64) (partitions []string, partitioned bool, err error) {
+	var hypertable bool
+	err = db.QueryRowContext(ctx, `SELECT to_regclass('timescaledb_information.hypertables') IS NOT NULL`).Scan(&hypertable)
+	if err == nil && hypertable {
+		err = db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM timescaledb_information.hypertables
+			WHERE hypertable_name = 'telemetry_datas')`).Scan(&hypertable)
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf(T("查询分区信息失败: %w"), err)
+	}
+	if hypertable {
+		partitions, err = queryPartitions(ctx, db, `SELECT chunk_schema, chunk_name FROM timescaledb_information.chunks
+			WHERE hypertable_name = 'telemetry_datas' AND (range_end_integer IS NULL OR range_end_integer > $1)
+			ORDER BY range_start_integer`, since)
+		return partitions, true, err
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT n.nspname, c.relname, COALESCE(pg_get_expr(c.relpartbound, c.oid), '')
+		FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE i.inhparent = 'telemetry_datas'::regclass`)
+	if err != nil {
+		return nil, false, fmt.Errorf(T("查询分区信息失败: %w"), err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		partitioned = true
+		var schema, name, bound string
+		if err := rows.Scan(&schema, &name, &bound); err != nil {
+			return nil, false, fmt.Errorf(T("查询分区信息失败: %w"), err)
+		}
+		if m := partitionUpperBound.FindStringSubmatch(bound); m != nil {
+			if upper, err := strconv.ParseInt(m[1], 10, 64); err == nil && upper <= since {
+				continue
+			}
+		}
+		partitions = append(partitions, pgx.Identifier{schema, name}.Sanitize())
+	}
+	return partitions, partitioned, rows.Err()
+}
+
+// queryPartitions 执行返回schema和表名的查询，返回带引号的完整表名
+func queryPartitions(ctx context.Context, db *sql.DB, query string, args ...any) ([]string, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf(T("查询分区信息失败: %w"), err)
+	}
+	defer rows.Close()
+	var partitions []string
+	for rows.Next() {
+		var schema, name string
+		if err := rows.Scan(&schema, &name); err != nil {
+			return nil, fmt.Errorf(T("查询分区信息失败: %w"), err)
+		}
+		partitions = append(partitions, pgx.Identifier{schema, name}.Sanitize())
+	}
+	return partitions, rows.Err()
+}
+
+// countPartitions 只在与测试时间窗口相关的分区上统计ts不早于since的行数
+//
+// 数TB的安装上即使按ts过滤，查询父表时规划器也要检查全部分区的元数据，参数化查询还可能
+// 退化为扫描全部分区。每个间隔重新列出分区(测试期间可能创建新分区)，只查询相关的分区，
+// 使每个间隔计数在大库上也可行。表未分区时退回按时间窗口查询父表。
+func countPartitions(ctx context.Context, db *sql.DB, since int64) (int64, error) {
+	partitions, partitioned, err := activePartitions(ctx, db, since)
+	if err != nil {
+		return 0, err
+	}
+	logPartitions(partitions, partitioned)
+	if !partitioned {
+		var count int64
+		err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM telemetry_datas WHERE ts >= $1", since).Scan(&count)
+		return count, err
+	}
+	parts := make([]string, len(partitions))
+	for i, p := range partitions {
+		parts[i] = fmt.Sprintf("(SELECT COUNT(*) FROM %s WHERE ts >= $1)", p)
+	}
+	if len(parts) == 0 {
+		return 0, nil
+	}
+	var count int64
+	err = db.QueryRowContext(ctx, "SELECT "+strings.Join(parts, " + "), since).Scan(&count)
+	return count, err
+}
+
+// logPartitions 统计的分区变化时输出日志
+func logPartitions(partitions []string, partitioned bool) {
+	list := strings.Join(partitions, ", ")
+	partitionState.mu.Lock()
+	defer partitionState.mu.Unlock()
+	if partitionState.seen && list == partitionState.logged {
+		return
+	}
+	partitionState.seen, partitionState.logged = true, list
+	if !partitioned {
+		log.Println(T("监控模块: telemetry_datas 未分区，按时间窗口统计全表"))
+		return
+	}
+	log.Printf(T("监控模块: 按分区统计入库数，当前统计 %d 个分区: %s"), len(partitions), list)
+}
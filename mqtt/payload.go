@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+
+	"github.com/brianvoe/gofakeit/v7"
+)
+
+// SensorPoint 单个数据点
+type SensorPoint struct {
+	Key   string  // 数据点名称
+	Value float64 // 数据点数值
+	field []byte  // 预编码的 "key": 前缀
+}
+
+// SensorData 表示设备上报的传感器数据结构（基于切片，按固定顺序编码）
+type SensorData []SensorPoint
+
+// newSensorData 创建包含指定键的数据对象，键名只编码一次
+func newSensorData(keys []string) SensorData {
+	data := make(SensorData, len(keys))
+	for i, key := range keys {
+		quoted, _ := json.Marshal(key)
+		data[i] = SensorPoint{Key: key, field: append(quoted, ':')}
+	}
+	return data
+}
+
+// AppendJSON 将数据编码为JSON对象追加到buf，输出与encoding/json一致
+func (d SensorData) AppendJSON(buf []byte) ([]byte, error) {
+	buf = append(buf, '{')
+	for i := range d {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, d[i].field...)
+
+		var err error
+		if buf, err = appendJSONFloat(buf, d[i].Value); err != nil {
+			return buf, fmt.Errorf(T("数据点 %s: %w"), d[i].Key, err)
+		}
+	}
+	return append(buf, '}'), nil
+}
+
+// appendJSONFloat 按encoding/json的规则编码float64
+func appendJSONFloat(buf []byte, f float64) ([]byte, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return buf, fmt.Errorf(T("不支持的数值: %v"), f)
+	}
+
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		format = 'e'
+	}
+	buf = strconv.AppendFloat(buf, f, format, -1, 64)
+	if format == 'e' {
+		// 与encoding/json一致，将 e-07 规范为 e-7
+		n := len(buf)
+		if n >= 4 && buf[n-4] == 'e' && buf[n-3] == '-' && buf[n-2] == '0' {
+			buf[n-2] = buf[n-1]
+			buf = buf[:n-1]
+		}
+	}
+	return buf, nil
+}
+
+// payloadPool 复用消息编码缓冲区，减少发送热路径上的内存分配
+var payloadPool = sync.Pool{
+	New: func() any {
+		buf := make([]byte, 0, 512)
+		return &buf
+	},
+}
+
+// getPayloadBuffer 从池中获取一个空缓冲区
+func getPayloadBuffer() *[]byte {
+	buf := payloadPool.Get().(*[]byte)
+	*buf = (*buf)[:0]
+	return buf
+}
+
+// putPayloadBuffer 归还缓冲区，调用方须确保已不再引用其中的数据
+func putPayloadBuffer(buf *[]byte) {
+	payloadPool.Put(buf)
+}
+
+// PayloadGenerator 生成设备每次发送的消息内容
+type PayloadGenerator interface {
+	// Next 生成下一条消息，返回的数据在调用Done之前有效
+	Next() ([]byte, error)
+	// Done 消息发布完成后调用，释放Next返回的数据
+	Done()
+	// Points 每条消息包含的数据点数
+	Points() int
+}
+
+// newPayloadGenerator 根据配置的消息生成模式创建每条消息包含points个数据点的生成器，index为设备序号
+func newPayloadGenerator(index int, username string, points int, faker *gofakeit.Faker) PayloadGenerator {
+	if gpsEnabled() {
+		return newGPSGenerator(index, faker)
+	}
+	if len(AppConfig.Data.Fields) > 0 {
+		return newExprGenerator(index, faker)
+	}
+	if profile, ok := payloadProfiles[AppConfig.Data.Profile]; ok {
+		return newProfileGenerator(profile, points, faker)
+	}
+	keys := sensorKeys(username, points)
+	if countersEnabled() {
+		return newCounterGenerator(keys, faker)
+	}
+	if numericEnabled() {
+		return &numericGenerator{jsonGenerator{data: newSensorData(keys), faker: faker}}
+	}
+	if AppConfig.Data.PayloadMode == "template" {
+		return newTemplateGenerator(keys, faker)
+	}
+	return &jsonGenerator{data: newSensorData(keys), faker: faker}
+}
+
+// keyTemplateInUse 数据点名称是否按数据点名称模板生成(未使用GPS轨迹、表达式数据点和网关消息格式)
+func keyTemplateInUse() bool {
+	return !gpsEnabled() && len(AppConfig.Data.Fields) == 0 && AppConfig.Data.Profile == ""
+}
+
+// jsonGenerator 每次重新生成数据并编码为JSON
+type jsonGenerator struct {
+	data  SensorData
+	faker *gofakeit.Faker
+	buf   *[]byte
+}
+
+func (g *jsonGenerator) Next() ([]byte, error) {
+	updateSensorData(g.data, g.faker)
+
+	g.buf = getPayloadBuffer()
+	payload, err := g.data.AppendJSON(*g.buf)
+	if err != nil {
+		g.Done()
+		return nil, err
+	}
+	*g.buf = payload
+	return payload, nil
+}
+
+func (g *jsonGenerator) Done() {
+	if g.buf != nil {
+		putPayloadBuffer(g.buf)
+		g.buf = nil
+	}
+}
+
+func (g *jsonGenerator) Points() int {
+	return len(g.data)
+}
+
+// 模板模式下数值保留的小数位数
+const templateDecimals = 3
+
+// templateGenerator 预先生成消息模板，每次只原地改写数值部分，适合纯Broker容量测试
+type templateGenerator struct {
+	buf     []byte
+	slots   []int // 每个数值字段在buf中的起始位置
+	width   int   // 数值字段的固定宽度
+	scratch []byte
+	faker   *gofakeit.Faker
+}
+
+// newTemplateGenerator 按数值范围确定固定字段宽度并生成模板
+func newTemplateGenerator(keys []string, faker *gofakeit.Faker) *templateGenerator {
+	width := len(strconv.FormatFloat(AppConfig.Data.MinValue, 'f', templateDecimals, 64))
+	if w := len(strconv.FormatFloat(AppConfig.Data.MaxValue, 'f', templateDecimals, 64)); w > width {
+		width = w
+	}
+
+	g := &templateGenerator{width: width, faker: faker, slots: make([]int, len(keys))}
+	g.buf = append(g.buf, '{')
+	for i, point := range newSensorData(keys) {
+		if i > 0 {
+			g.buf = append(g.buf, ',')
+		}
+		g.buf = append(g.buf, point.field...)
+		g.slots[i] = len(g.buf)
+		for j := 0; j < width; j++ {
+			g.buf = append(g.buf, '0')
+		}
+	}
+	g.buf = append(g.buf, '}')
+	return g
+}
+
+func (g *templateGenerator) Next() ([]byte, error) {
+	for _, slot := range g.slots {
+		value := g.faker.Float64Range(AppConfig.Data.MinValue, AppConfig.Data.MaxValue)
+		g.scratch = strconv.AppendFloat(g.scratch[:0], value, 'f', templateDecimals, 64)
+
+		// 数值右对齐，左侧用空格填充(JSON允许值前有空白)
+		field := g.buf[slot : slot+g.width]
+		pad := g.width - len(g.scratch)
+		for j := 0; j < pad; j++ {
+			field[j] = ' '
+		}
+		copy(field[pad:], g.scratch)
+	}
+	return g.buf, nil
+}
+
+func (g *templateGenerator) Done() {}
+
+func (g *templateGenerator) Points() int {
+	return len(g.slots)
+}
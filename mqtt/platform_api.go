@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// 平台API命令行参数
+var (
+	apiURL = flag.String("api-url", "", "平台API地址(如 http://127.0.0.1:9999)，属性下发等测试通过它调用平台接口")
+	apiKey = flag.String("api-key", "", "平台API密钥，作为x-api-key请求头")
+)
+
+// apiTransport 调用平台API共用的连接池
+var apiTransport = &http.Transport{
+	Proxy:               http.ProxyFromEnvironment,
+	MaxIdleConnsPerHost: 1000,
+	IdleConnTimeout:     90 * time.Second,
+}
+
+// apiResult ThingsPanel接口的响应体，HTTP状态码为200时业务错误在code中
+type apiResult struct {
+	Code    *int   `json:"code"`
+	Message string `json:"message"`
+}
+
+// apiCall 以JSON请求体调用平台API，返回HTTP状态码(请求失败时为0)和响应体
+//
+// 非2xx状态码，以及响应体中code不是0或200的业务错误都作为错误返回。
+func apiCall(ctx context.Context, method, path string, body []byte) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(AppConfig.API.URL, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range AppConfig.API.Headers {
+		req.Header.Set(k, v)
+	}
+	client := &http.Client{Transport: apiTransport, Timeout: AppConfig.API.Timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return resp.StatusCode, nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return resp.StatusCode, data, fmt.Errorf(T("接口返回状态码 %d"), resp.StatusCode)
+	}
+	var result apiResult
+	if json.Unmarshal(data, &result) == nil && result.Code != nil && *result.Code != 0 && *result.Code != 200 {
+		return resp.StatusCode, data, fmt.Errorf(T("接口返回错误 %d: %s"), *result.Code, result.Message)
+	}
+	return resp.StatusCode, data, nil
+}
+
+// apiEnabled 是否配置了平台API地址
+func apiEnabled() bool {
+	return AppConfig.API.URL != ""
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"time"
+)
+
+// 启动前检查相关命令行参数
+var (
+	skipPreflight    = flag.Bool("skip-preflight", false, "跳过启动前检查")
+	preflightPublish = flag.Bool("preflight-publish", false, "启动前检查时用第一个token向遥测主题发布一条QoS1空消息({})，检查发布权限")
+)
+
+// preflightCheck 一项启动前检查，失败时附带处理建议
+type preflightCheck struct {
+	name string
+	run  func() (hint string, err error)
+}
+
+// runPreflight 在启动设备前逐项检查配置和环境，全部通过返回true
+//
+// 配置错误如果等到设备启动后才暴露，会变成成千上万行相同的错误日志，
+// 因此这里用一个探测连接提前发现问题，并给出可操作的提示。
+func runPreflight(tokens *TokenList) bool {
+	checks := []preflightCheck{
+		{T("设备token数量"), func() (string, error) { return checkTokenCount(tokens) }},
+		{T("文件描述符上限"), checkFileLimit},
+		{T("本地端口范围"), checkLocalPorts},
+	}
+	// 使用本地接收端时不连接Broker，使用本地Broker或本地接收端时不需要数据库
+	switch {
+	case provisionEnabled():
+		checks = append(checks, preflightCheck{T("动态注册"), func() (string, error) { return checkProvisioning(tokens.At(0)) }})
+	case !sinkEnabled():
+		checks = append(checks, preflightCheck{T("MQTT服务器"), func() (string, error) { return checkBrokerPublish(tokens.At(0)) }})
+	}
+	if !localBrokerEnabled() && !sinkEnabled() {
+		checks = append(checks, preflightCheck{T("数据库"), checkDatabaseReachable},
+			preflightCheck{T("遥测表结构"), checkTelemetrySchema})
+	}
+	if replicaEnabled() {
+		checks = append(checks, preflightCheck{T("只读副本"), checkReplicaReachable})
+	}
+	for _, family := range requiredFamilies() {
+		checks = append(checks, preflightCheck{fmt.Sprintf(T("IP%s连通性"), family),
+			func() (string, error) { return checkBrokerFamily(family) }})
+	}
+
+	log.Println(T("启动前检查:"))
+	passed := true
+	for _, check := range checks {
+		hint, err := check.run()
+		if err != nil {
+			passed = false
+			log.Printf(T(" [失败] %s: %v"), check.name, err)
+			if hint != "" {
+				log.Printf(T("        建议: %s"), hint)
+			}
+			continue
+		}
+		log.Printf(T(" [通过] %s"), check.name)
+	}
+	if !passed {
+		log.Println(T("启动前检查未通过，可使用 --skip-preflight 跳过检查"))
+	}
+	return passed
+}
+
+// checkTokenCount 检查token文件中的设备数量是否满足配置
+func checkTokenCount(tokens *TokenList) (string, error) {
+	if tokens.Len() < AppConfig.Device.ClientNumber {
+		return fmt.Sprintf(T("使用create_device再创建 %d 个设备，或将 --clients 减少到 %d"),
+				AppConfig.Device.ClientNumber-tokens.Len(), tokens.Len()),
+			fmt.Errorf(T("token文件 %s 只有 %d 个设备，少于请求的 %d 个"),
+				AppConfig.Device.TokenFile, tokens.Len(), AppConfig.Device.ClientNumber)
+	}
+	return "", nil
+}
+
+// checkFileLimit 检查进程可打开的文件数是否足够(每个设备一个连接，另留出余量)
+func checkFileLimit() (string, error) {
+	limit, hard, ok := openFileLimit()
+	if !ok {
+		return "", nil
+	}
+	need := uint64(AppConfig.Device.ClientNumber) + fileLimitReserve
+	if limit < need {
+		return fmt.Sprintf(T("硬上限为 %d，需由管理员在 /etc/security/limits.conf 或 systemd 的 LimitNOFILE 中提高到 %d 以上，或使用 --cap-clients 自动减少设备数量"), hard, need),
+			fmt.Errorf(T("当前上限 %d，%d 个设备至少需要 %d"), limit, AppConfig.Device.ClientNumber, need)
+	}
+	return "", nil
+}
+
+// checkLocalPorts 检查本地端口范围是否足够建立全部连接
+func checkLocalPorts() (string, error) {
+	ports := localPortCount()
+	if ports > 0 && ports < uint64(AppConfig.Device.ClientNumber) {
+		return T("执行 sysctl -w net.ipv4.ip_local_port_range='1024 65535'，超过该数量时需从多台机器或多个源IP发起测试，或使用 --cap-clients"),
+			fmt.Errorf(T("本地端口范围只有 %d 个端口，少于 %d 个设备"), ports, AppConfig.Device.ClientNumber)
+	}
+	return "", nil
+}
+
+// checkBrokerPublish 用第一个token连接服务器，启用 --preflight-publish 时再发布一条QoS1探测消息
+//
+// 探测消息是发到真实遥测主题的空JSON对象({})，平台会把它当作第一个设备的一条遥测处理，
+// 因此默认只检查连接和认证(已能发现token来自其他实例等问题)。发布被ACL拒绝时，
+// 大多数Broker会断开连接或不返回PUBACK，这里据此判断。
+func checkBrokerPublish(username string) (string, error) {
+	address := brokerAddress(AppConfig.MQTT.Server)
+	conn, err := net.DialTimeout("tcp", address, 5*time.Second)
+	if err != nil {
+		return fmt.Sprintf(T("确认 %s 地址和端口正确，且Broker已启动"), address), err
+	}
+	conn.Close()
+
+	client := newLiteClient(username+"_preflight", username, AppConfig.MQTT.Server, nil)
+	if err := client.Connect(); err != nil {
+		return T("确认token文件来自当前ThingsPanel实例，且设备未被删除"),
+			fmt.Errorf(T("使用第一个token认证失败: %w"), err)
+	}
+	defer client.Disconnect()
+
+	if !*preflightPublish {
+		return "", nil
+	}
+	// 与第一个设备实际使用的主题一致(多租户分组时为该组的主题)
+	topic := tenantGroupOf(0).telemetryTopic(username)
+	if err := client.Publish(topic, 1, []byte("{}")); err != nil {
+		return fmt.Sprintf(T("检查Broker的ACL是否允许设备向 %s 发布"), topic),
+			fmt.Errorf(T("发布到主题 %s 失败: %w"), topic, err)
+	}
+	return "", nil
+}
+
+// checkBrokerFamily 检查能否通过指定地址族连接服务器
+func checkBrokerFamily(family string) (string, error) {
+	address := brokerAddress(AppConfig.MQTT.Server)
+	if servers := brokerTargets.servers[family]; len(servers) > 0 {
+		address = brokerAddress(servers[0])
+	}
+	d := net.Dialer{Timeout: 5 * time.Second, Resolver: brokerResolver}
+	conn, err := d.Dial(familyNetwork(family), address)
+	if err != nil {
+		return fmt.Sprintf(T("确认Broker域名有IP%s解析记录、Broker监听了IP%s地址，且本机有可用的IP%s路由"), family, family, family), err
+	}
+	conn.Close()
+	return "", nil
+}
+
+// checkDatabaseReachable 检查监控使用的数据库能否连接
+func checkDatabaseReachable() (string, error) {
+	db, err := openDatabase()
+	if err != nil {
+		return T("检查 --db-host、--db-user、--db-pass 和 --db-name 参数(或 --db-dsn)"), err
+	}
+	db.Close()
+	return "", nil
+}
+
+// telemetryColumns 监控、统计和核对查询用到的遥测表的列
+var telemetryColumns = []struct {
+	table   string
+	columns []string
+}{
+	{"telemetry_datas", []string{"device_id", "key", "ts", "number_v", "string_v"}},
+	{"telemetry_current_datas", []string{"device_id", "key", "number_v"}},
+}
+
+// checkTelemetrySchema 检查遥测表中是否有本工具查询的列
+//
+// 平台版本改变遥测表结构后，依赖这些列的统计和核对会在测试结束时才失败，
+// 这里提前列出差异。
+func checkTelemetrySchema() (string, error) {
+	db, err := openDatabase()
+	if err != nil {
+		return "", err
+	}
+	defer db.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var drift []string
+	for _, t := range telemetryColumns {
+		rows, err := db.QueryContext(ctx, `SELECT column_name FROM information_schema.columns
+			WHERE table_schema = current_schema() AND table_name = $1`, t.table)
+		if err != nil {
+			return "", err
+		}
+		existing := make(map[string]bool)
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				rows.Close()
+				return "", err
+			}
+			existing[name] = true
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return "", err
+		}
+		if len(existing) == 0 {
+			drift = append(drift, fmt.Sprintf(T("%s: 表不存在"), t.table))
+			continue
+		}
+		var missing []string
+		for _, c := range t.columns {
+			if !existing[c] {
+				missing = append(missing, c)
+			}
+		}
+		if len(missing) > 0 {
+			drift = append(drift, fmt.Sprintf(T("%s: 缺少列 %s"), t.table, strings.Join(missing, ", ")))
+		}
+	}
+	if len(drift) > 0 {
+		return T("确认 --db-name 指向ThingsPanel数据库；平台版本改变了遥测表结构时需要同步修改本工具的查询"),
+			fmt.Errorf(T("遥测表结构与本工具不一致: %s"), strings.Join(drift, "; "))
+	}
+	return "", nil
+}
+
+// checkReplicaReachable 检查只读副本能否连接且确实处于恢复(复制)状态
+func checkReplicaReachable() (string, error) {
+	db, err := openReadDatabase()
+	if err != nil {
+		return T("检查 --db-replica 参数，副本使用与主库相同的账号和数据库名"), err
+	}
+	defer db.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	lag, err := replicaLag(ctx, db)
+	if err != nil {
+		return T("--db-replica 应指向流复制的只读副本，而不是主库"), err
+	}
+	log.Printf(T("只读副本最后重放的事务距今: %v"), lag.Round(time.Millisecond))
+	return "", nil
+}
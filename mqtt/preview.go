@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// preview子命令命令行参数
+var (
+	previewCount    = flag.Int("preview-count", 3, "preview: 每种消息输出的示例数量")
+	previewProfiles = flag.String("preview-profiles", "", "preview: 额外预览的网关消息格式，逗号分隔，all为全部内置格式(默认只预览配置的消息格式)")
+)
+
+// previewPrinter 按发布时的原样输出示例消息，并统计不是合法JSON的消息数
+type previewPrinter struct {
+	invalid int
+}
+
+// show 输出一条示例消息，前一行以#开头说明消息类型、主题和数据点数
+func (p *previewPrinter) show(kind, topic string, points int, payload []byte) {
+	header := fmt.Sprintf("# %s topic=%s bytes=%d", kind, topic, len(payload))
+	if points > 0 {
+		header += fmt.Sprintf(" points=%d", points)
+	}
+	fmt.Println(header)
+	fmt.Println(string(payload))
+	if !json.Valid(payload) {
+		p.invalid++
+		fmt.Println(T("# 警告: 不是合法的JSON"))
+	}
+}
+
+// runPreview preview子命令：按当前配置生成示例消息并原样输出，不连接MQTT服务器
+//
+// 使用token文件中第一个设备的token和随机数流，按正式运行时的顺序生成设备信息和每个
+// 循环的遥测、额外数据流消息，内容与该设备实际发送的一致(依赖时间的{ts}和GPS位置除外)。
+// 示例消息输出到标准输出，便于对照平台期望的格式检查或保存后用其他工具校验。
+func runPreview() {
+	if *previewCount <= 0 {
+		log.Fatalf(T("示例数量必须大于0: %d"), *previewCount)
+	}
+	profiles, err := previewProfileNames()
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if err := initStrings(); err != nil {
+		log.Fatalf("%v", err)
+	}
+	if err := initGPS(); err != nil {
+		log.Fatalf("%v", err)
+	}
+	username := previewUsername()
+	p := &previewPrinter{}
+
+	// 和正式运行一样，设备信息在遥测之前使用设备的随机数流
+	faker := newDeviceFaker(0)
+	if deviceInfoStats != nil {
+		payload, err := deviceInfoPayload(username, faker)
+		if err != nil {
+			log.Fatalf(T("序列化设备信息失败: %v"), err)
+		}
+		p.show(deviceInfoStream, strings.ReplaceAll(AppConfig.DeviceInfo.Topic, "{username}", username), 0, payload)
+	}
+
+	generator := newPayloadGenerator(0, username, currentMessagePoints(), faker)
+	strs := newStringFields(faker)
+	streams := newStreamPublishers(username)
+	for i := 0; i < *previewCount; i++ {
+		payload, err := generator.Next()
+		if err != nil {
+			log.Fatalf(T("序列化数据失败: %v"), err)
+		}
+		payload, extra := strs.append(payload)
+		p.show(telemetryStream, AppConfig.MQTT.Topic, generator.Points()+extra, payload)
+		generator.Done()
+		for _, stream := range streams {
+			p.show(stream.stats.Name, stream.topic, 0, stream.Next(faker))
+		}
+	}
+
+	for _, name := range profiles {
+		faker := newDeviceFaker(0)
+		generator := newProfileGenerator(payloadProfiles[name], currentMessagePoints(), faker)
+		for i := 0; i < *previewCount; i++ {
+			payload, err := generator.Next()
+			if err != nil {
+				log.Fatalf(T("序列化数据失败: %v"), err)
+			}
+			p.show(telemetryStream+"/"+name, AppConfig.MQTT.Topic, generator.Points(), payload)
+			generator.Done()
+		}
+	}
+
+	if heartbeatStats != nil {
+		faker := newDeviceFaker(0)
+		publisher := &streamPublisher{
+			topic:    strings.ReplaceAll(AppConfig.Heartbeat.Topic, "{username}", username),
+			segments: parseStreamTemplate(strings.ReplaceAll(AppConfig.Heartbeat.Payload, "{username}", username)),
+		}
+		for i := 0; i < *previewCount; i++ {
+			p.show(heartbeatStream, publisher.topic, 0, publisher.Next(faker))
+		}
+	}
+
+	if p.invalid > 0 {
+		log.Printf(T("预览完成: %d 条示例消息不是合法的JSON，平台可能无法解析"), p.invalid)
+	}
+}
+
+// previewUsername 预览使用的设备token，读取token文件失败时使用占位名称
+func previewUsername() string {
+	tokens, err := loadTokens(AppConfig.Device.TokenFile, 1, false)
+	if err != nil {
+		log.Printf(T("警告: %v，预览使用占位token"), err)
+		return "preview-device"
+	}
+	if tokens.Len() == 0 {
+		log.Printf(T("警告: token文件 %s 中没有设备，预览使用占位token"), AppConfig.Device.TokenFile)
+		return "preview-device"
+	}
+	return tokens.At(0)
+}
+
+// previewProfileNames 解析需要额外预览的网关消息格式
+func previewProfileNames() ([]string, error) {
+	if *previewProfiles == "" {
+		return nil, nil
+	}
+	if *previewProfiles == "all" {
+		names := make([]string, 0, len(payloadProfiles))
+		for name := range payloadProfiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return names, nil
+	}
+	var names []string
+	for _, name := range strings.Split(*previewProfiles, ",") {
+		name = strings.TrimSpace(name)
+		if _, ok := payloadProfiles[name]; !ok {
+			return nil, fmt.Errorf(T("不支持的网关消息格式: %s (可选: %s)"), name, profileNames())
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 认证缓存预热命令行参数
+var (
+	primeAuth = flag.Bool("prime-auth", false, "正式测试前以低速率逐个认证全部设备一次，预热平台的认证缓存(测量热认证下的连接性能)")
+	primeRate = flag.Int("prime-rate", 0, "预热阶段每秒认证的设备数")
+)
+
+// primeResult 认证缓存预热结果
+var primeResult struct {
+	done    bool
+	ok      uint64
+	failed  uint64
+	elapsed time.Duration
+	latency Histogram
+}
+
+// primeAuthCache 以固定速率逐个连接并立即断开，使每个凭证在正式测试前被平台认证一次
+//
+// 预热连接不计入连接统计。不预热时测量的是冷认证(平台首次查询凭证)下的连接性能。
+func primeAuthCache(tokens *TokenList) {
+	cfg := AppConfig.Prime
+	count := min(tokens.Len(), AppConfig.Device.ClientNumber)
+	log.Printf(T("开始预热认证缓存: %d 个设备, %d 个/秒"), count, cfg.Rate)
+
+	ticker := time.NewTicker(time.Second / time.Duration(cfg.Rate))
+	defer ticker.Stop()
+
+	sem := make(chan struct{}, cfg.Concurrency)
+	var wg sync.WaitGroup
+	start := time.Now()
+	lastLog := start
+	for i := 0; i < count; i++ {
+		<-ticker.C
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(username string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			client := newLiteClient(username+"_prime", username, AppConfig.MQTT.Server, nil)
+			connectStart := time.Now()
+			if err := client.Connect(); err != nil {
+				atomic.AddUint64(&primeResult.failed, 1)
+				return
+			}
+			primeResult.latency.Record(time.Since(connectStart))
+			atomic.AddUint64(&primeResult.ok, 1)
+			client.Disconnect()
+		}(tokens.At(i))
+
+		if time.Since(lastLog) >= AppConfig.Monitor.LogInterval {
+			lastLog = time.Now()
+			log.Printf(T("预热进度: %d/%d"), i+1, count)
+		}
+	}
+	wg.Wait()
+
+	primeResult.done = true
+	primeResult.elapsed = time.Since(start)
+	log.Printf(T("认证缓存预热完成: 成功 %d, 失败 %d, 耗时 %v, 认证耗时 p50=%v, p99=%v"),
+		primeResult.ok, primeResult.failed, primeResult.elapsed.Round(time.Second),
+		primeResult.latency.Quantile(0.50), primeResult.latency.Quantile(0.99))
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/brianvoe/gofakeit/v7"
+)
+
+// profileField 网关消息中的单个数据点
+type profileField struct {
+	name     string  // 数据点名称(寄存器地址或数据标识)
+	min, max float64 // 数值范围，累计量为每条消息的增量范围
+	decimals int     // 保留的小数位数，0为整数(原始寄存器值)
+	counter  bool    // 累计量(如电能示值)，数值只增不减
+}
+
+// payloadProfile 工业网关的消息格式，寄存器表或数据标识展开为平铺的数据点
+//
+// 协议插件解析网关上报的数据时，实际负载是按寄存器地址或数据标识命名的整数和定点数，
+// 而不是hum1..humN这样的同类浮点数。每条消息的数据点数超过一个从站(电表)的字段数时，
+// 按从站地址重复字段表，如 s1_40001, s1_40002, ..., s2_40001。
+type payloadProfile struct {
+	unit   string // 从站或电表的名称前缀
+	fields []profileField
+}
+
+// payloadProfiles 内置的网关消息格式
+var payloadProfiles = map[string]payloadProfile{
+	// Modbus RTU/TCP网关: 保持寄存器(4xxxx)为原始16位值，输入寄存器(3xxxx)为按比例换算后的测量值，
+	// 线圈(0xxxx)和离散输入(1xxxx)为0/1
+	"modbus": {unit: "s", fields: []profileField{
+		{name: "00001", min: 0, max: 1},
+		{name: "00002", min: 0, max: 1},
+		{name: "10001", min: 0, max: 1},
+		{name: "10002", min: 0, max: 1},
+		{name: "30001", min: 180, max: 250, decimals: 1},   // 电压(V)
+		{name: "30002", min: 0, max: 100, decimals: 2},     // 电流(A)
+		{name: "30003", min: -20, max: 80, decimals: 1},    // 温度(℃)
+		{name: "30004", min: 0, max: 100, decimals: 1},     // 湿度(%RH)
+		{name: "30005", min: 0, max: 1.6, decimals: 3},     // 压力(MPa)
+		{name: "30006", min: 49.5, max: 50.5, decimals: 2}, // 频率(Hz)
+		{name: "40001", min: 0, max: 65535},
+		{name: "40002", min: 0, max: 65535},
+		{name: "40003", min: 0, max: 4000}, // 转速设定(rpm)
+		{name: "40004", min: 0, max: 3},    // 运行模式
+		{name: "40005", min: 0, max: 65535},
+		{name: "40006", min: 0, max: 65535},
+		{name: "40007", min: 0, max: 1000, counter: true}, // 运行时长计数
+		{name: "40008", min: 0, max: 255},                 // 故障码
+	}},
+	// DL/T 645-2007多功能电能表: 以数据标识(DI)命名，精度与规约的数据格式一致
+	"dlt645": {unit: "m", fields: []profileField{
+		{name: "00000000", min: 0, max: 0.05, decimals: 2, counter: true}, // 组合有功总电能(kWh)
+		{name: "00010000", min: 0, max: 0.05, decimals: 2, counter: true}, // 正向有功总电能(kWh)
+		{name: "00020000", min: 0, max: 0.01, decimals: 2, counter: true}, // 反向有功总电能(kWh)
+		{name: "02010100", min: 210, max: 235, decimals: 1},               // A相电压(V)
+		{name: "02010200", min: 210, max: 235, decimals: 1},               // B相电压(V)
+		{name: "02010300", min: 210, max: 235, decimals: 1},               // C相电压(V)
+		{name: "02020100", min: 0, max: 60, decimals: 3},                  // A相电流(A)
+		{name: "02020200", min: 0, max: 60, decimals: 3},                  // B相电流(A)
+		{name: "02020300", min: 0, max: 60, decimals: 3},                  // C相电流(A)
+		{name: "02030000", min: 0, max: 40, decimals: 4},                  // 总有功功率(kW)
+		{name: "02040000", min: -10, max: 10, decimals: 4},                // 总无功功率(kvar)
+		{name: "02060000", min: 0.8, max: 1, decimals: 3},                 // 总功率因数
+		{name: "02800002", min: 49.9, max: 50.1, decimals: 2},             // 电网频率(Hz)
+		{name: "02800007", min: 20, max: 60, decimals: 1},                 // 表内温度(℃)
+	}},
+}
+
+// profileNames 内置网关消息格式的名称列表，用于帮助和错误信息
+func profileNames() string {
+	names := make([]string, 0, len(payloadProfiles))
+	for name := range payloadProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// layout 返回每条消息包含points个数据点时各数据点的名称和字段定义
+func (p payloadProfile) layout(points int) ([]string, []profileField) {
+	keys := make([]string, points)
+	fields := make([]profileField, points)
+	for i := range keys {
+		f := p.fields[i%len(p.fields)]
+		keys[i] = fmt.Sprintf("%s%d_%s", p.unit, i/len(p.fields)+1, f.name)
+		fields[i] = f
+	}
+	return keys, fields
+}
+
+// profileGenerator 按网关消息格式生成数据，每个数据点使用各自的数值范围和精度
+type profileGenerator struct {
+	jsonGenerator
+	fields []profileField
+}
+
+// newProfileGenerator 创建按网关消息格式生成每条消息points个数据点的生成器
+func newProfileGenerator(profile payloadProfile, points int, faker *gofakeit.Faker) *profileGenerator {
+	keys, fields := profile.layout(points)
+	g := &profileGenerator{jsonGenerator: jsonGenerator{data: newSensorData(keys), faker: faker}, fields: fields}
+	for i, f := range fields {
+		if f.counter {
+			// 累计量从一个随机的示值开始，避免所有设备的示值相同
+			g.data[i].Value = round(faker.Float64Range(0, 100000), f.decimals)
+		}
+	}
+	return g
+}
+
+func (g *profileGenerator) Next() ([]byte, error) {
+	for i, f := range g.fields {
+		v := round(g.faker.Float64Range(f.min, f.max), f.decimals)
+		if f.counter {
+			v = round(g.data[i].Value+v, f.decimals)
+		}
+		g.data[i].Value = v
+	}
+
+	g.buf = getPayloadBuffer()
+	payload, err := g.data.AppendJSON(*g.buf)
+	if err != nil {
+		g.Done()
+		return nil, err
+	}
+	*g.buf = payload
+	return payload, nil
+}
+
+// round 保留decimals位小数
+func round(v float64, decimals int) float64 {
+	scale := math.Pow10(decimals)
+	return math.Round(v*scale) / scale
+}
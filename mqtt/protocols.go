@@ -0,0 +1,109 @@
+package main
+
+import (
+	"log"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+// protocolStats 按上报协议统计的遥测消息
+type protocolStats struct {
+	name    string
+	devices atomic.Uint64 // 连接成功(HTTP为开始上报)的设备数
+	sent    atomic.Uint64 // 发送成功的消息数
+	failed  atomic.Uint64 // 发送失败的消息数
+	latency Histogram     // 发送成功的耗时，HTTP为请求到响应的耗时
+}
+
+// 各上报协议的统计
+var (
+	protocolMQTT = &protocolStats{name: "MQTT"}
+	protocolHTTP = &protocolStats{name: "HTTP"}
+)
+
+// mixedProtocols 是否为多协议混合运行
+func mixedProtocols() bool {
+	return httpDevicesEnabled() || dashboardsEnabled()
+}
+
+// protocolOf 设备客户端对应的协议统计
+func protocolOf(client DeviceClient) *protocolStats {
+	if _, ok := client.(*httpClient); ok {
+		return protocolHTTP
+	}
+	return protocolMQTT
+}
+
+// recordProtocolDevice 记录一个上线的设备
+func recordProtocolDevice(client DeviceClient) {
+	protocolOf(client).devices.Add(1)
+}
+
+// recordProtocol 记录一条遥测消息的发送结果
+func recordProtocol(client DeviceClient, elapsed time.Duration, err error) {
+	p := protocolOf(client)
+	if err != nil {
+		p.failed.Add(1)
+		return
+	}
+	p.sent.Add(1)
+	p.latency.Record(elapsed)
+}
+
+// reportProtocols 在同一份报告中输出各协议设备和WebSocket看板的结果(非多协议混合时不输出)
+//
+// 各协议共用同一个时间线(循环、速率、场景)，入库统计不区分协议；这里按协议拆分发送结果，
+// 对比同一负载下不同接入方式的成功率和耗时，以及设备上报对看板实时推送的影响。
+func reportProtocols(duration time.Duration) {
+	if !mixedProtocols() {
+		return
+	}
+	log.Println(T("\n========== 多协议混合 =========="))
+	for _, p := range []*protocolStats{protocolMQTT, protocolHTTP} {
+		sent, failed := p.sent.Load(), p.failed.Load()
+		if p.devices.Load() == 0 && sent+failed == 0 {
+			continue
+		}
+		log.Printf(T("%s 设备: %d 个, 发送成功 %d 条 (%.1f 条/秒), 失败 %d 条 (%.2f%%)"),
+			p.name, p.devices.Load(), sent, ratePerSecond(float64(sent), duration), failed, percent(failed, sent+failed))
+		if p.latency.Count() > 0 {
+			log.Printf(T("  发送耗时: p50=%v, p99=%v, 最大=%v"),
+				p.latency.Quantile(0.50).Round(time.Microsecond), p.latency.Quantile(0.99).Round(time.Microsecond),
+				p.latency.Max().Round(time.Microsecond))
+		}
+	}
+
+	httpStatus.mu.Lock()
+	codes := make([]int, 0, len(httpStatus.counts))
+	for code := range httpStatus.counts {
+		codes = append(codes, code)
+	}
+	slices.Sort(codes)
+	for _, code := range codes {
+		if code == 0 {
+			log.Printf(T("  请求失败(连接错误或超时): %d 次"), httpStatus.counts[code])
+		} else {
+			log.Printf(T("  状态码 %d: %d 次"), code, httpStatus.counts[code])
+		}
+	}
+	httpStatus.mu.Unlock()
+
+	if dashboardsEnabled() {
+		s := &dashboardStats
+		received := s.received.Load()
+		log.Printf(T("WebSocket看板: %d 个, 连接 %d 次, 连接失败 %d 次, 中途断开 %d 次"),
+			AppConfig.Dashboards.Count, s.connects.Load(), s.failures.Load(), s.disconnects.Load())
+		log.Printf(T("  收到推送 %d 条 (%.1f 条/秒), 共 %.1f KB"),
+			received, ratePerSecond(float64(received), duration), float64(s.bytes.Load())/1024)
+		if s.interval.Count() > 0 {
+			log.Printf(T("  推送间隔: p50=%v, p99=%v, 最大=%v"),
+				s.interval.Quantile(0.50).Round(time.Millisecond), s.interval.Quantile(0.99).Round(time.Millisecond),
+				s.interval.Max().Round(time.Millisecond))
+		}
+		if received == 0 {
+			log.Println(T("  警告: 看板没有收到任何推送，请检查看板地址和订阅消息"))
+		}
+	}
+	log.Println("===============================")
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// 动态注册命令行参数
+var (
+	provisionFlag   = flag.Bool("provision", false, "动态注册模式：设备连接前通过平台的一型一密动态注册接口获取凭证，而不是使用预先创建的token，用于压测注册接口本身")
+	provisionSecret = flag.String("provision-secret", "", "动态注册模式：设备配置的一型一密密钥(template secret)")
+)
+
+// 动态注册接口的默认配置，对应ThingsPanel的设备动态认证接口
+const (
+	defaultProvisionPath = "/api/v1/device/auth"
+	defaultProvisionBody = `{"template_secret":"{secret}","device_number":"{device_number}","device_name":"{device_name}"}`
+)
+
+// provisionStats 动态注册统计
+var provisionStats struct {
+	slots     chan struct{} // 同时进行的注册请求数上限
+	requests  atomic.Uint64
+	failed    atomic.Uint64 // 请求失败(网络错误、非2xx状态码或业务错误)
+	malformed atomic.Uint64 // 请求成功但响应中没有凭证
+	waiting   Histogram     // 等待并发名额的耗时
+	latency   Histogram     // 注册请求耗时
+}
+
+// provisionEnabled 是否使用动态注册获取凭证
+func provisionEnabled() bool {
+	return AppConfig.Provision.Enabled
+}
+
+// provisionTokens 动态注册模式下生成n个设备编号作为设备的token列表，并初始化注册请求的并发名额
+//
+// 设备编号在多次运行之间保持不变，平台对已注册的设备编号返回已有设备的凭证，
+// 重复运行不会不断创建新设备。
+func provisionTokens(n int) *TokenList {
+	if AppConfig.Provision.Concurrency > 0 {
+		provisionStats.slots = make(chan struct{}, AppConfig.Provision.Concurrency)
+	}
+	var buf strings.Builder
+	offsets := make([]uint32, 0, 2*n)
+	for i := 1; i <= n; i++ {
+		offsets = append(offsets, uint32(buf.Len()))
+		fmt.Fprintf(&buf, "%s%06d", AppConfig.Provision.Prefix, i)
+		offsets = append(offsets, uint32(buf.Len()))
+	}
+	return &TokenList{data: buf.String(), offsets: offsets}
+}
+
+// provisionCredentials 用设备编号调用动态注册接口，返回设备的MQTT用户名
+func provisionCredentials(ctx context.Context, deviceNumber string) (string, error) {
+	cfg := AppConfig.Provision
+	if provisionStats.slots != nil {
+		start := time.Now()
+		select {
+		case provisionStats.slots <- struct{}{}:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+		defer func() { <-provisionStats.slots }()
+		provisionStats.waiting.Record(time.Since(start))
+	}
+
+	secret, _ := json.Marshal(cfg.Secret)
+	body := strings.NewReplacer(
+		"{secret}", strings.Trim(string(secret), `"`),
+		"{device_number}", deviceNumber,
+		"{device_name}", deviceNumber,
+	).Replace(cfg.Body)
+
+	provisionStats.requests.Add(1)
+	start := time.Now()
+	_, data, err := apiCall(ctx, "POST", cfg.Path, []byte(body))
+	provisionStats.latency.Record(time.Since(start))
+	if err != nil {
+		provisionStats.failed.Add(1)
+		return "", fmt.Errorf(T("动态注册失败: %w"), err)
+	}
+	username, err := provisionedUsername(data)
+	if err != nil {
+		provisionStats.malformed.Add(1)
+		return "", fmt.Errorf(T("动态注册失败: %w"), err)
+	}
+	return username, nil
+}
+
+// provisionedUsername 从注册接口的响应中取出MQTT用户名
+//
+// 响应的data.voucher可以是凭证对象，也可以是凭证的JSON字符串(与devices表的voucher列相同)。
+func provisionedUsername(data []byte) (string, error) {
+	var resp struct {
+		Data struct {
+			Voucher  json.RawMessage `json:"voucher"`
+			Username string          `json:"username"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return "", err
+	}
+	if resp.Data.Username != "" {
+		return resp.Data.Username, nil
+	}
+	voucher := resp.Data.Voucher
+	var encoded string
+	if json.Unmarshal(voucher, &encoded) == nil {
+		voucher = json.RawMessage(encoded)
+	}
+	var credentials struct {
+		Username string `json:"username"`
+	}
+	if json.Unmarshal(voucher, &credentials) != nil || credentials.Username == "" {
+		return "", errors.New(T("响应中没有设备凭证(data.voucher.username)"))
+	}
+	return credentials.Username, nil
+}
+
+// checkProvisioning 启动前检查：注册第一个设备并用获得的凭证连接Broker(使用本地接收端时只注册)
+func checkProvisioning(deviceNumber string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	username, err := provisionCredentials(ctx, deviceNumber)
+	if err != nil {
+		return T("确认 provision.secret 是平台上设备配置的一型一密密钥，且 provision.path 与平台版本一致"), err
+	}
+	if sinkEnabled() {
+		return "", nil
+	}
+	return checkBrokerPublish(username)
+}
+
+// reportProvision 输出动态注册接口的统计(未启用时不输出)
+func reportProvision() {
+	if !provisionEnabled() {
+		return
+	}
+	requests := provisionStats.requests.Load()
+	failed := provisionStats.failed.Load()
+	malformed := provisionStats.malformed.Load()
+	log.Println(T("\n========== 动态注册 =========="))
+	log.Printf(T("注册请求: %d, 失败 %d (%.2f%%), 响应中没有凭证 %d"),
+		requests, failed, percent(failed, requests), malformed)
+	if h := &provisionStats.latency; h.Count() > 0 {
+		log.Printf(T("  注册耗时: p50 %v, p99 %v, 最大 %v"), h.Quantile(0.50), h.Quantile(0.99), h.Max())
+	}
+	if h := &provisionStats.waiting; h.Count() > 0 && h.Max() > 0 {
+		log.Printf(T("  等待并发名额(provision.concurrency=%d): p99 %v, 最大 %v"),
+			AppConfig.Provision.Concurrency, h.Quantile(0.99), h.Max())
+	}
+	log.Println("===============================")
+}
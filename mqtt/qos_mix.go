@@ -0,0 +1,104 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// QoS混合命令行参数
+var qosMixFlag = flag.String("qos-mix", "", "按比例为设备分配QoS，如 0:60,1:35,2:5 (覆盖--qos)")
+
+// qosShare QoS混合中的一项
+type qosShare struct {
+	qos     byte
+	percent float64
+}
+
+// qosMix 解析后的QoS分布，为空时所有设备使用mqtt.qos
+var qosMix []qosShare
+
+// QoSStats 单个QoS等级的发布统计
+type QoSStats struct {
+	Devices  uint64    // 使用该QoS的设备数
+	Sent     uint64    // 发送成功的消息数
+	Failures uint64    // 发送失败的消息数
+	Latency  Histogram // 发布耗时
+}
+
+// qosStats 按QoS等级(0,1,2)统计
+var qosStats [3]QoSStats
+
+// parseQoSMix 解析"QoS:百分比"列表，百分比之和必须为100
+func parseQoSMix(spec string) ([]qosShare, error) {
+	var mix []qosShare
+	var total float64
+	for _, item := range strings.Split(spec, ",") {
+		qosStr, percentStr, ok := strings.Cut(strings.TrimSpace(item), ":")
+		qos, err1 := strconv.Atoi(qosStr)
+		percent, err2 := strconv.ParseFloat(percentStr, 64)
+		if !ok || err1 != nil || err2 != nil || qos < 0 || qos > 2 || percent < 0 {
+			return nil, fmt.Errorf(T("无效的QoS比例: %s"), item)
+		}
+		mix = append(mix, qosShare{qos: byte(qos), percent: percent})
+		total += percent
+	}
+	if math.Abs(total-100) > 0.01 {
+		return nil, fmt.Errorf(T("QoS比例之和应为100，当前为 %.1f"), total)
+	}
+	return mix, nil
+}
+
+// deviceQoS 返回第i个设备使用的QoS
+//
+// 用黄金分割序列把设备序号映射到[0,1)，各QoS的设备交错分布且比例准确，
+// 只测试前一部分设备时也保持相同的分布。
+func deviceQoS(i int) byte {
+	if len(qosMix) == 0 {
+		return byte(AppConfig.MQTT.QoS)
+	}
+	position := math.Mod(float64(i)*0.6180339887498949, 1) * 100
+	for _, share := range qosMix {
+		if position < share.percent {
+			return share.qos
+		}
+		position -= share.percent
+	}
+	return qosMix[len(qosMix)-1].qos
+}
+
+// recordQoS 记录一次发布结果
+func recordQoS(qos byte, latency time.Duration, err error) {
+	s := &qosStats[qos]
+	if err != nil {
+		atomic.AddUint64(&s.Failures, 1)
+		return
+	}
+	atomic.AddUint64(&s.Sent, 1)
+	s.Latency.Record(latency)
+}
+
+// reportQoSMix 按QoS等级输出设备数、失败率和发布耗时(未配置QoS混合时不输出)
+func reportQoSMix() {
+	if len(qosMix) == 0 {
+		return
+	}
+	log.Println(T("\n========== QoS分布统计 =========="))
+	for qos := range qosStats {
+		s := &qosStats[qos]
+		devices := atomic.LoadUint64(&s.Devices)
+		if devices == 0 {
+			continue
+		}
+		sent, failed := atomic.LoadUint64(&s.Sent), atomic.LoadUint64(&s.Failures)
+		log.Printf(T("QoS%d: 设备=%d, 成功=%d, 失败=%d (%.2f%%), p50=%v, p99=%v, 最大=%v"),
+			qos, devices, sent, failed, percent(failed, sent+failed),
+			s.Latency.Quantile(0.50), s.Latency.Quantile(0.99), s.Latency.Max())
+	}
+	log.Println("===============================")
+}
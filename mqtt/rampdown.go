@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 逐步断开命令行参数
+var rampDownRate = flag.Int("ramp-down-rate", 0, "测试结束时每秒断开的设备数，测量平台标记离线和处理剩余写入的耗时，0为同时断开")
+
+// rampDown 逐步断开阶段的状态
+var rampDown struct {
+	active  atomic.Bool   // 测试正常结束后开始逐步断开，此前退出的设备直接断开
+	permits chan struct{} // 每个断开许可允许一个设备断开
+	started time.Time
+	last    atomic.Int64 // 最后一个设备断开的时间(UnixNano)
+	count   atomic.Uint64
+
+	mu      sync.Mutex
+	samples []*rampDownSample
+}
+
+// rampDownSample 抽样设备的断开时间和平台标记离线的时间
+type rampDownSample struct {
+	username     string
+	disconnected time.Time
+	offline      time.Duration // 从断开到平台标记离线，未标记时为0
+}
+
+// rampDownResult 逐步断开阶段的测量结果
+var rampDownResult struct {
+	duration   time.Duration // 断开全部设备用时
+	offlineAll time.Duration // 最后一个设备断开后，抽样设备全部被标记离线的用时
+	offlineP99 time.Duration // 单设备从断开到被标记离线的p99
+	pending    int           // 超时仍未被标记离线的抽样设备数
+	flushed    int64         // 最后一个设备断开后新增的入库数据点数
+	flushTime  time.Duration // 最后一个设备断开后入库数不再增长的用时
+}
+
+// rampDownEnabled 是否启用逐步断开
+func rampDownEnabled() bool {
+	return AppConfig.RampDown.Rate > 0
+}
+
+// beginRampDown 测试正常结束、取消设备之前调用，之后退出的设备等待断开许可
+func beginRampDown() {
+	if !rampDownEnabled() {
+		return
+	}
+	rampDown.permits = make(chan struct{})
+	rampDown.active.Store(true)
+}
+
+// rampDownDisconnect 设备退出时调用：等待断开许可后断开连接，并记录断开时间
+func rampDownDisconnect(client DeviceClient, username string) {
+	if !rampDown.active.Load() {
+		client.Disconnect()
+		return
+	}
+	<-rampDown.permits
+	client.Disconnect()
+	now := time.Now()
+	rampDown.last.Store(now.UnixNano())
+	rampDown.count.Add(1)
+
+	rampDown.mu.Lock()
+	if len(rampDown.samples) < AppConfig.RampDown.Samples {
+		rampDown.samples = append(rampDown.samples, &rampDownSample{username: username, disconnected: now})
+	}
+	rampDown.mu.Unlock()
+}
+
+// runRampDown 按配置的速率发放断开许可，直到ctx结束(全部设备已退出)
+//
+// 大量设备同时断开曾在生产环境引发设备状态表的写入风暴，逐步断开可以测量平台处理断开的能力。
+func runRampDown(ctx context.Context) {
+	annotate("stage", T("开始逐步断开设备"))
+	rampDown.started = time.Now()
+	ticker := time.NewTicker(time.Second / time.Duration(AppConfig.RampDown.Rate))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case rampDown.permits <- struct{}{}:
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// measureRampDown 全部设备断开后，轮询抽样设备的在线状态和入库数，直到全部离线且入库数稳定或超时
+func measureRampDown() {
+	if !rampDown.active.Load() || rampDown.count.Load() == 0 {
+		return
+	}
+	last := time.Unix(0, rampDown.last.Load())
+	rampDownResult.duration = last.Sub(rampDown.started)
+
+	// 离线耗时在主库上测量，不使用只读副本，避免复制延迟计入测量结果
+	db, err := openDatabase()
+	if err != nil {
+		log.Printf(T("逐步断开: %v"), err)
+		return
+	}
+	defer db.Close()
+
+	cfg := AppConfig.RampDown
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	defer cancel()
+
+	ids := make(map[*rampDownSample]string)
+	for _, s := range rampDown.samples {
+		if id, err := deviceIDByToken(ctx, db, s.username); err == nil {
+			ids[s] = id
+		}
+	}
+
+	var lastCount int64 = -1
+	var lastGrowth time.Time
+	stable := 0
+	ticker := time.NewTicker(cfg.PollInterval)
+	defer ticker.Stop()
+	for ctx.Err() == nil {
+		now := time.Now()
+		pending := 0
+		for s, id := range ids {
+			if s.offline > 0 {
+				continue
+			}
+			var online int
+			if err := db.QueryRowContext(ctx, `SELECT is_online FROM devices WHERE id = $1`, id).Scan(&online); err != nil {
+				pending++
+				continue
+			}
+			if online == 0 {
+				s.offline = now.Sub(s.disconnected)
+				continue
+			}
+			pending++
+		}
+		if pending == 0 && rampDownResult.offlineAll == 0 {
+			rampDownResult.offlineAll = now.Sub(last)
+		}
+
+		// 入库数连续3次轮询不变视为剩余写入已处理完
+		if stable < 3 {
+			if count, err := countTelemetry(ctx, db); err == nil {
+				if lastCount < 0 {
+					lastGrowth = last
+				} else if count > lastCount {
+					rampDownResult.flushed += count - lastCount
+					lastGrowth = now
+					stable = 0
+				} else {
+					stable++
+				}
+				lastCount = count
+			}
+		}
+		rampDownResult.pending = pending
+
+		if pending == 0 && stable >= 3 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+		case <-ticker.C:
+		}
+	}
+	if ctx.Err() != nil {
+		log.Printf(T("逐步断开: 等待平台处理断开超时(%v)"), cfg.Timeout)
+	}
+	rampDownResult.flushTime = max(lastGrowth.Sub(last), 0)
+	rampDownResult.offlineP99 = rampDownOfflineP99(ids)
+}
+
+// rampDownOfflineP99 已被标记离线的抽样设备从断开到离线耗时的p99
+func rampDownOfflineP99(ids map[*rampDownSample]string) time.Duration {
+	var h Histogram
+	for s := range ids {
+		if s.offline > 0 {
+			h.Record(s.offline)
+		}
+	}
+	return h.Quantile(0.99)
+}
+
+// reportRampDown 输出逐步断开阶段的测量结果(未启用时不输出)
+func reportRampDown() {
+	if !rampDown.active.Load() || rampDown.count.Load() == 0 {
+		return
+	}
+	r := rampDownResult
+	log.Println(T("\n========== 逐步断开 =========="))
+	log.Printf(T("断开速率: %d 个/秒, 断开 %d 个设备用时 %v"),
+		AppConfig.RampDown.Rate, rampDown.count.Load(), r.duration.Round(time.Millisecond))
+	if r.pending > 0 {
+		log.Printf(T("平台标记离线(抽样 %d 个设备): %d 个设备超时仍未被标记离线, 已离线设备p99=%v"),
+			len(rampDown.samples), r.pending, r.offlineP99)
+	} else {
+		log.Printf(T("平台标记离线(抽样 %d 个设备): 最后一个设备断开后全部离线用时 %v, 单设备p99=%v"),
+			len(rampDown.samples), r.offlineAll.Round(time.Millisecond), r.offlineP99)
+	}
+	log.Printf(T("剩余写入: 最后一个设备断开后入库 %d 个数据点, 用时 %v"), r.flushed, r.flushTime.Round(time.Millisecond))
+	log.Println("===============================")
+}
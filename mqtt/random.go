@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+)
+
+// 随机数种子命令行参数
+var seed = flag.Int64("seed", 0, "随机数种子(0表示按当前时间生成)，相同种子可复现测试数据")
+
+// initRandom 确定本次运行的随机数种子并初始化全局随机数生成器
+func initRandom() {
+	if AppConfig.Test.Seed == 0 {
+		AppConfig.Test.Seed = time.Now().UnixNano()
+	}
+	gofakeit.Seed(AppConfig.Test.Seed)
+	log.Printf(T("随机数种子: %d (使用 --seed %d 可复现本次运行)"), AppConfig.Test.Seed, AppConfig.Test.Seed)
+}
+
+// newRand 基于运行种子创建独立的随机数流，stream用于区分不同用途
+func newRand(stream uint64) *rand.Rand {
+	return rand.New(rand.NewPCG(uint64(AppConfig.Test.Seed), stream))
+}
+
+// fakerRandStream 设备数据生成器随机数流的起始编号，与其他用途的随机数流错开
+const fakerRandStream = 2 << 32
+
+// newDeviceFaker 为指定序号的设备创建独立的数据生成器，保证并发下结果可复现
+func newDeviceFaker(index int) *gofakeit.Faker {
+	return gofakeit.NewFaker(newRand(fakerRandStream+uint64(index)), false)
+}
+
+// lockedRand 多个goroutine共享的随机数流
+type lockedRand struct {
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// newLockedRand 基于运行种子创建可并发使用的随机数流
+func newLockedRand(stream uint64) *lockedRand {
+	return &lockedRand{rng: newRand(stream)}
+}
+
+// Float64 返回[0,1)内的随机数
+func (r *lockedRand) Float64() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rng.Float64()
+}
+
+// IntN 返回[0,n)内的随机整数
+func (r *lockedRand) IntN(n int) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rng.IntN(n)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 断线重连命令行参数
+var (
+	reconnectMode = flag.String("reconnect", "", "断线重连方式(backoff: 指数退避加随机抖动, paho: paho内置退避, off: 不重连)")
+	reconnectMax  = flag.Duration("reconnect-max", 0, "重连等待时间上限(默认5s)")
+)
+
+// reconnectStats 设备断线重连统计
+var reconnectStats struct {
+	lost      atomic.Uint64 // 连接断开次数
+	attempts  atomic.Uint64 // 重连尝试次数
+	succeeded atomic.Uint64 // 重连成功次数
+	failed    atomic.Uint64 // 重连失败次数
+	outage    Histogram     // 从断开到重连成功的时长
+}
+
+// reconnectRandStream 重连抖动随机数流的起始编号，与其他用途的随机数流错开
+const reconnectRandStream = 3 << 32
+
+// newReconnectRand 为设备连接创建重连抖动随机数流，相同种子下重连时间可复现
+func newReconnectRand(index int) *rand.Rand {
+	return newRand(reconnectRandStream + uint64(index))
+}
+
+// reconnectState 单个设备连接的重连状态
+//
+// paho的连接断开回调和重连回调在不同的goroutine中调用且先后不定，两者都可能最先发现断开。
+type reconnectState struct {
+	mu         sync.Mutex
+	lostAt     time.Time     // 本次断开的时间，连接正常时为零值
+	attempting bool          // 已开始重连且尚未成功
+	delay      time.Duration // 下一次重连前的等待时间(backoff方式)
+	rng        *rand.Rand    // 重连抖动随机数流，在锁内使用
+}
+
+// markLost 记录一次断开(已记录时忽略)，调用方持有锁
+func (s *reconnectState) markLost(now time.Time) {
+	if !s.lostAt.IsZero() {
+		return
+	}
+	s.lostAt = now
+	s.delay = AppConfig.Reconnect.Initial
+	reconnectStats.lost.Add(1)
+}
+
+// connectionLost 连接断开时调用
+func (s *reconnectState) connectionLost() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.markLost(time.Now())
+}
+
+// beforeAttempt 每次重连尝试前调用，返回尝试前需要等待的时间
+//
+// 上一次尝试之后又开始新的尝试说明上一次失败。backoff方式下等待时间从initial开始按multiplier
+// 增长到max_interval，并加上随机抖动，同时断线的设备不会在同一时刻集中重连。
+func (s *reconnectState) beforeAttempt() time.Duration {
+	cfg := AppConfig.Reconnect
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.markLost(time.Now())
+	if s.attempting {
+		reconnectStats.failed.Add(1)
+	}
+	s.attempting = true
+	reconnectStats.attempts.Add(1)
+
+	if cfg.Mode != "backoff" {
+		return 0
+	}
+	wait := jittered(s.delay, cfg.Jitter, s.rng)
+	s.delay = min(time.Duration(float64(s.delay)*cfg.Multiplier), cfg.MaxInterval)
+	return wait
+}
+
+// connected 连接建立时调用(含首次连接，首次连接不计入重连)
+func (s *reconnectState) connected() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.attempting {
+		return
+	}
+	reconnectStats.succeeded.Add(1)
+	reconnectStats.outage.Record(time.Since(s.lostAt))
+	s.attempting = false
+	s.lostAt = time.Time{}
+}
+
+// recordReconnectResult 记录一次同步完成的重连(轻量客户端在发布前重连)
+func recordReconnectResult(lostAt time.Time, err error) {
+	reconnectStats.attempts.Add(1)
+	if err != nil {
+		reconnectStats.failed.Add(1)
+		return
+	}
+	reconnectStats.succeeded.Add(1)
+	reconnectStats.outage.Record(time.Since(lostAt))
+}
+
+// reportReconnects 输出断线重连统计(没有断线时不输出)
+func reportReconnects() {
+	lost := reconnectStats.lost.Load()
+	if lost == 0 && reconnectStats.attempts.Load() == 0 {
+		return
+	}
+	succeeded := reconnectStats.succeeded.Load()
+	cfg := AppConfig.Reconnect
+	log.Println(T("\n========== 断线重连 =========="))
+	log.Printf(T("重连方式: %s, 首次等待=%v, 上限=%v, 倍数=%.1f, 抖动=%.0f%%"),
+		cfg.Mode, cfg.Initial, cfg.MaxInterval, cfg.Multiplier, cfg.Jitter*100)
+	log.Printf(T("断开 %d 次, 重连尝试 %d 次: 成功 %d, 失败 %d, 测试结束时未恢复 %d"),
+		lost, reconnectStats.attempts.Load(), succeeded, reconnectStats.failed.Load(), lost-min(succeeded, lost))
+	if succeeded > 0 {
+		o := &reconnectStats.outage
+		log.Printf(T("断开到重连成功: p50=%v, p99=%v, 最大=%v"), o.Quantile(0.50), o.Quantile(0.99), o.Max())
+	}
+	log.Println("===============================")
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"log"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 图表尺寸(像素)
+const (
+	chartWidth   = 860
+	chartHeight  = 260
+	chartPadLeft = 70
+	chartPadTop  = 30
+	chartPadBot  = 30
+	chartPadR    = 20
+	chartYTicks  = 5
+	chartXTicks  = 6
+)
+
+// ThroughputSample 一个监控间隔的吞吐量和延迟
+type ThroughputSample struct {
+	Time     time.Time
+	SentRate float64       // 发送数据点速率(点/秒)
+	MsgRate  float64       // 发送消息速率(条/秒)
+	DBRate   float64       // 入库速率(点/秒)
+	P50      time.Duration // 本间隔发布耗时p50
+	P99      time.Duration // 本间隔发布耗时p99
+	DBProbe  time.Duration // 本间隔数据库写入探测耗时(未启用探测时为0)
+}
+
+// throughputSamples 监控模块按间隔采集的样本，用于生成HTML报告中的图表
+var throughputSamples struct {
+	mu   sync.Mutex
+	list []ThroughputSample
+}
+
+// recordThroughputSample 记录一个监控间隔的样本
+func recordThroughputSample(s ThroughputSample) {
+	throughputSamples.mu.Lock()
+	throughputSamples.list = append(throughputSamples.list, s)
+	throughputSamples.mu.Unlock()
+}
+
+// chartSeries 折线图中的一条曲线
+type chartSeries struct {
+	Name   string
+	Color  string
+	Values []float64
+}
+
+// chartMark 图表上的竖线标记(标注)
+type chartMark struct {
+	Offset float64 // 相对起点的秒数
+	Text   string
+}
+
+// svgLineChart 生成内嵌的SVG折线图，xs为相对起点的秒数
+func svgLineChart(title, unit string, xs []float64, series []chartSeries, marks []chartMark) template.HTML {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="sans-serif" font-size="11">`,
+		chartWidth, chartHeight)
+	fmt.Fprintf(&b, `<text x="%d" y="16" font-size="13" font-weight="bold">%s</text>`,
+		chartPadLeft, template.HTMLEscapeString(title))
+
+	plotW := float64(chartWidth - chartPadLeft - chartPadR)
+	plotH := float64(chartHeight - chartPadTop - chartPadBot)
+	xMax := 1.0
+	if len(xs) > 0 {
+		xMax = math.Max(xs[len(xs)-1], 1)
+	}
+	yMax := 0.0
+	for _, s := range series {
+		for _, v := range s.Values {
+			yMax = math.Max(yMax, v)
+		}
+	}
+	yMax = niceCeil(yMax)
+	px := func(x float64) float64 { return chartPadLeft + x/xMax*plotW }
+	py := func(y float64) float64 { return chartPadTop + plotH - y/yMax*plotH }
+
+	// 网格和坐标轴刻度
+	for i := 0; i <= chartYTicks; i++ {
+		y := yMax * float64(i) / chartYTicks
+		fmt.Fprintf(&b, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="#e0e0e0"/>`,
+			chartPadLeft, py(y), chartWidth-chartPadR, py(y))
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" text-anchor="end">%s</text>`,
+			chartPadLeft-6, py(y)+4, formatChartValue(y))
+	}
+	for i := 0; i <= chartXTicks; i++ {
+		x := xMax * float64(i) / chartXTicks
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" text-anchor="middle">%v</text>`,
+			px(x), chartHeight-10, (time.Duration(x) * time.Second).Round(time.Second))
+	}
+	fmt.Fprintf(&b, `<text x="4" y="%d">%s</text>`, chartPadTop-8, template.HTMLEscapeString(unit))
+
+	// 标注竖线，鼠标悬停显示内容
+	for _, m := range marks {
+		if m.Offset < 0 || m.Offset > xMax {
+			continue
+		}
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%.1f" stroke="#999" stroke-dasharray="4 3"><title>%s</title></line>`,
+			px(m.Offset), chartPadTop, px(m.Offset), chartPadTop+plotH, template.HTMLEscapeString(m.Text))
+	}
+
+	// 曲线和图例
+	for i, s := range series {
+		points := make([]string, 0, len(s.Values))
+		for j, v := range s.Values {
+			points = append(points, fmt.Sprintf("%.1f,%.1f", px(xs[j]), py(v)))
+		}
+		fmt.Fprintf(&b, `<polyline fill="none" stroke="%s" stroke-width="2" points="%s"/>`, s.Color, strings.Join(points, " "))
+		lx := chartWidth - chartPadR - 150*(len(series)-i)
+		fmt.Fprintf(&b, `<rect x="%d" y="8" width="12" height="3" fill="%s"/><text x="%d" y="14">%s</text>`,
+			lx, s.Color, lx+16, template.HTMLEscapeString(s.Name))
+	}
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+// niceCeil 将坐标轴上限取整为1、2、5乘以10的幂
+func niceCeil(v float64) float64 {
+	if v <= 0 {
+		return 1
+	}
+	p := math.Pow(10, math.Floor(math.Log10(v)))
+	for _, m := range []float64{1, 2, 5, 10} {
+		if v <= m*p {
+			return m * p
+		}
+	}
+	return 10 * p
+}
+
+// formatChartValue 坐标轴刻度值，较大的值用k/M表示
+func formatChartValue(v float64) string {
+	switch {
+	case v >= 1e6:
+		return fmt.Sprintf("%.1fM", v/1e6)
+	case v >= 1e3:
+		return fmt.Sprintf("%.1fk", v/1e3)
+	case v == math.Trunc(v):
+		return fmt.Sprintf("%.0f", v)
+	}
+	return fmt.Sprintf("%.2f", v)
+}
+
+// htmlReportTemplate 自包含的HTML报告，图表为内嵌SVG，不依赖外部资源
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}} {{.Summary.RunID}}</title>
+<style>
+body { font-family: sans-serif; margin: 24px; color: #222; }
+table { border-collapse: collapse; margin-bottom: 24px; }
+td, th { border: 1px solid #ddd; padding: 4px 10px; text-align: left; }
+th { background: #f5f5f5; }
+.chart { margin-bottom: 16px; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<table>
+{{range .Rows}}<tr><th>{{index . 0}}</th><td>{{index . 1}}</td></tr>
+{{end}}</table>
+{{if .Charts}}{{range .Charts}}<div class="chart">{{.}}</div>
+{{end}}{{else}}<p>{{.NoSamples}}</p>{{end}}
+{{if .Stages}}<h2>{{.StageTitle}}</h2>
+<table>
+<tr>{{range .StageHeaders}}<th>{{.}}</th>{{end}}</tr>
+{{range .Stages}}<tr><td>{{.Name}}</td><td>{{.FirstCycle}}-{{.LastCycle}}</td><td>{{.Interval}}</td><td>{{.PointsPerMsg}}</td><td>{{printf "%.1f" .MsgsPerSec}}</td><td>{{printf "%.1f" .PointsPerSec}}</td><td>{{if ge .DBRate 0.0}}{{printf "%.1f" .DBRate}}{{else}}-{{end}}</td><td>{{printf "%.1f / %.1f / %.1f" .P50Ms .P95Ms .P99Ms}}</td><td>{{printf "%.2f%%" .SuccessRate}}</td></tr>
+{{end}}</table>{{end}}
+{{if .Annotations}}<h2>{{.AnnotationTitle}}</h2>
+<table>
+{{range .Annotations}}<tr><td>{{.Time.Format "15:04:05"}}</td><td>{{.Source}}</td><td>{{.Text}}</td></tr>
+{{end}}</table>{{end}}
+</body>
+</html>
+`))
+
+// writeHTMLReport 生成包含汇总指标和时间序列图表的report.html，写入运行目录，返回报告内容
+//
+// 没有运行目录时只在需要发送报告时生成。
+func writeHTMLReport(summary RunSummary) []byte {
+	if runDir == "" && !reportDeliveryEnabled() {
+		return nil
+	}
+	throughputSamples.mu.Lock()
+	samples := append([]ThroughputSample(nil), throughputSamples.list...)
+	throughputSamples.mu.Unlock()
+
+	var charts []template.HTML
+	if len(samples) > 0 {
+		xs := make([]float64, len(samples))
+		sent, db, msgs := make([]float64, len(samples)), make([]float64, len(samples)), make([]float64, len(samples))
+		p50, p99 := make([]float64, len(samples)), make([]float64, len(samples))
+		probe := make([]float64, len(samples))
+		for i, s := range samples {
+			xs[i] = s.Time.Sub(summary.StartedAt).Seconds()
+			sent[i], db[i], msgs[i] = s.SentRate, s.DBRate, s.MsgRate
+			p50[i], p99[i] = durationMs(s.P50), durationMs(s.P99)
+			probe[i] = durationMs(s.DBProbe)
+		}
+		var marks []chartMark
+		for _, a := range annotationsSince(time.Time{}) {
+			marks = append(marks, chartMark{Offset: a.Time.Sub(summary.StartedAt).Seconds(), Text: a.Text})
+		}
+		charts = []template.HTML{
+			svgLineChart(T("数据点速率"), T("点/秒"), xs, []chartSeries{
+				{Name: T("发送"), Color: "#1f77b4", Values: sent},
+				{Name: T("入库"), Color: "#2ca02c", Values: db},
+			}, marks),
+			svgLineChart(T("消息速率"), T("条/秒"), xs, []chartSeries{
+				{Name: T("发送"), Color: "#1f77b4", Values: msgs},
+			}, marks),
+			svgLineChart(T("发布耗时"), "ms", xs, []chartSeries{
+				{Name: "p50", Color: "#ff7f0e", Values: p50},
+				{Name: "p99", Color: "#d62728", Values: p99},
+			}, marks),
+		}
+		if probeEnabled() {
+			charts = append(charts, svgLineChart(T("数据库写入探测耗时"), "ms", xs, []chartSeries{
+				{Name: T("插入"), Color: "#9467bd", Values: probe},
+			}, marks))
+		}
+	}
+
+	rows := [][2]string{
+		{T("运行ID"), summary.RunID},
+		{T("开始时间"), summary.StartedAt.Format("2006-01-02 15:04:05")},
+		{T("测试时长"), summary.Duration.Round(time.Second).String()},
+		{T("设备数"), fmt.Sprintf("%d / %d", summary.Connected, summary.Clients)},
+		{T("循环次数"), fmt.Sprint(summary.Cycles)},
+		{T("发送消息数"), fmt.Sprintf(T("%d (%.1f 条/秒)"), summary.Messages, summary.MsgsPerSec)},
+		{T("发送数据点数"), fmt.Sprintf(T("%d (%.1f 点/秒)"), summary.DataPoints, summary.PointsPerSec)},
+		{T("入库数据点数"), fmt.Sprint(summary.DBWritten)},
+		{T("发布耗时"), fmt.Sprintf("p50=%v, p99=%v, max=%v",
+			publishLatency.Quantile(0.50), publishLatency.Quantile(0.99), publishLatency.Max())},
+	}
+	if reason := abortedReason(); reason != "" {
+		rows = append(rows, [2]string{T("提前终止"), reason})
+	}
+
+	var buf bytes.Buffer
+	err := htmlReportTemplate.Execute(&buf, map[string]any{
+		"Title":      T("MQTT性能测试报告"),
+		"Summary":    summary,
+		"Rows":       rows,
+		"Charts":     charts,
+		"NoSamples":  T("没有监控样本(监控间隔内测试已结束或数据库不可用)，未生成图表"),
+		"StageTitle": T("分阶段统计"),
+		"StageHeaders": []string{T("阶段"), T("循环"), T("发送间隔"), T("点/条"), T("条/秒"), T("点/秒"),
+			T("入库 点/秒"), T("发布耗时 p50/p95/p99 (ms)"), T("成功率")},
+		"Stages":          stageSummaries(),
+		"AnnotationTitle": T("事件时间线"),
+		"Annotations":     annotationsSince(time.Time{}),
+	})
+	if err != nil {
+		log.Printf(T("生成HTML报告失败: %v"), err)
+		return nil
+	}
+	if runDir != "" {
+		writeRunArtifact("report.html", buf.Bytes())
+		log.Printf(T("HTML报告: %s"), runArtifact("report.html"))
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strings"
+	"time"
+)
+
+// 报告发送命令行参数
+var (
+	reportMailTo  = flag.String("report-mail-to", "", "测试结束后把HTML报告和JSON汇总发送到这些邮箱(逗号分隔)，SMTP服务器见配置文件report部分")
+	reportWebhook = flag.String("report-webhook", "", "测试结束后以multipart/form-data上传HTML报告和JSON汇总的HTTP地址")
+)
+
+// reportDeliveryEnabled 是否在测试结束后发送报告
+func reportDeliveryEnabled() bool {
+	return AppConfig.Report.MailTo != "" || AppConfig.Report.Webhook != ""
+}
+
+// mailRecipients 收件人列表
+func mailRecipients() []string {
+	var to []string
+	for _, addr := range strings.Split(AppConfig.Report.MailTo, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			to = append(to, addr)
+		}
+	}
+	return to
+}
+
+// deliverReport 测试结束后通过邮件和/或Webhook发送HTML报告和JSON汇总
+//
+// 在无人值守的夜间测试中结果直接送到团队邮箱。发送失败只记录日志，不影响退出状态。
+func deliverReport(summary RunSummary, html, summaryJSON []byte, interrupted bool) {
+	if !reportDeliveryEnabled() {
+		return
+	}
+	subject := reportSubject(summary, interrupted)
+	if AppConfig.Report.MailTo != "" {
+		if err := sendReportMail(subject, html, summaryJSON); err != nil {
+			log.Printf(T("发送报告邮件失败: %v"), err)
+		} else {
+			log.Printf(T("报告已发送到: %s"), strings.Join(mailRecipients(), ", "))
+		}
+	}
+	if AppConfig.Report.Webhook != "" {
+		if err := uploadReport(summary, subject, html, summaryJSON); err != nil {
+			log.Printf(T("上传报告失败: %v"), err)
+		} else {
+			log.Println(T("报告已上传到Webhook"))
+		}
+	}
+}
+
+// reportSubject 报告标题：前缀、运行ID、主机和关键结果，在收件箱列表中即可区分各次运行
+func reportSubject(summary RunSummary, interrupted bool) string {
+	host, _ := os.Hostname()
+	status := fmt.Sprintf(T("%.0f 点/秒, 设备 %d/%d"), summary.PointsPerSec, summary.Connected, summary.Clients)
+	if reason := abortedReason(); reason != "" {
+		status = T("提前终止: ") + reason
+	} else if interrupted {
+		status += T(", 被中断")
+	}
+	return fmt.Sprintf("%s %s@%s %s", AppConfig.Report.Subject, summary.RunID, host, status)
+}
+
+// buildReportMail 构造邮件：HTML报告作为正文，HTML报告和JSON汇总同时作为附件
+func buildReportMail(from string, to []string, subject string, html, summaryJSON []byte) []byte {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	contentType, content := "text/html; charset=utf-8", html
+	if html == nil {
+		contentType, content = "text/plain; charset=utf-8", summaryJSON
+	}
+	writeMailPart(writer, textproto.MIMEHeader{"Content-Type": {contentType}}, content)
+	if html != nil {
+		writeMailPart(writer, mailAttachment("report.html", "text/html; charset=utf-8"), html)
+	}
+	if summaryJSON != nil {
+		writeMailPart(writer, mailAttachment("summary.json", "application/json"), summaryJSON)
+	}
+	writer.Close()
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", writer.Boundary())
+	msg.Write(body.Bytes())
+	return msg.Bytes()
+}
+
+// mailAttachment 附件的MIME头
+func mailAttachment(name, contentType string) textproto.MIMEHeader {
+	return textproto.MIMEHeader{
+		"Content-Type":        {contentType},
+		"Content-Disposition": {fmt.Sprintf(`attachment; filename="%s"`, name)},
+	}
+}
+
+// writeMailPart 写入一个base64编码的MIME部分，每行76个字符
+func writeMailPart(writer *multipart.Writer, header textproto.MIMEHeader, data []byte) {
+	header.Set("Content-Transfer-Encoding", "base64")
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return
+	}
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		io.WriteString(part, encoded[:76]+"\r\n")
+		encoded = encoded[76:]
+	}
+	io.WriteString(part, encoded+"\r\n")
+}
+
+// sendReportMail 通过SMTP发送报告邮件
+//
+// 465端口直接建立TLS连接，其他端口在服务器支持时使用STARTTLS。整个会话受report.timeout限制，
+// 服务器无响应时不会阻塞程序退出。
+func sendReportMail(subject string, html, summaryJSON []byte) error {
+	cfg := AppConfig.Report
+	host, port, err := net.SplitHostPort(cfg.SMTPHost)
+	if err != nil {
+		return fmt.Errorf(T("SMTP服务器地址格式错误: %w"), err)
+	}
+	dialer := &net.Dialer{Timeout: cfg.Timeout}
+	var conn net.Conn
+	if port == "465" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", cfg.SMTPHost, &tls.Config{ServerName: host})
+	} else {
+		conn, err = dialer.Dial("tcp", cfg.SMTPHost)
+	}
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(cfg.Timeout))
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok && port != "465" {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if cfg.SMTPUser != "" {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return errors.New(T("SMTP服务器不支持登录认证"))
+		}
+		if err := client.Auth(smtp.PlainAuth("", cfg.SMTPUser, cfg.SMTPPass, host)); err != nil {
+			return err
+		}
+	}
+
+	to := mailRecipients()
+	if err := client.Mail(cfg.MailFrom); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err := client.Rcpt(addr); err != nil {
+			return fmt.Errorf(T("收件人 %s: %w"), addr, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(buildReportMail(cfg.MailFrom, to, subject, html, summaryJSON)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// uploadReport 以multipart/form-data上传报告，字段run_id、subject，文件report(report.html)和summary(summary.json)
+func uploadReport(summary RunSummary, subject string, html, summaryJSON []byte) error {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writer.WriteField("run_id", summary.RunID)
+	writer.WriteField("subject", subject)
+	for _, f := range []struct {
+		field, name string
+		data        []byte
+	}{
+		{"report", "report.html", html},
+		{"summary", "summary.json", summaryJSON},
+	} {
+		if f.data == nil {
+			continue
+		}
+		part, err := writer.CreateFormFile(f.field, f.name)
+		if err != nil {
+			return err
+		}
+		part.Write(f.data)
+	}
+	writer.Close()
+
+	client := &http.Client{Timeout: AppConfig.Report.Timeout}
+	resp, err := client.Post(AppConfig.Report.Webhook, writer.FormDataContentType(), &body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf(T("Webhook返回状态码 %d"), resp.StatusCode)
+	}
+	return nil
+}
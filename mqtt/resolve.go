@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// 域名解析命令行参数
+var (
+	resolveMode = flag.String("resolve", "", "Broker域名解析方式(per-connection: 每次连接时解析, once: 启动时解析一次，设备轮流使用解析结果)")
+	pinIPs      = flag.String("pin-ip", "", "只连接指定的Broker IP，逗号分隔，设备轮流使用(不再解析域名)")
+	dnsServers  = flag.String("dns-server", "", "解析Broker域名使用的DNS服务器(host:port)，逗号分隔，为空时使用系统配置")
+	ipFamily    = flag.String("ip-family", "", "连接Broker使用的地址族(any: 不限, v4: 只用IPv4, v6: 只用IPv6, dual: 设备交替使用IPv4和IPv6)")
+)
+
+// dnsTimeout 单次DNS查询的超时时间
+const dnsTimeout = 5 * time.Second
+
+// brokerResolver 设备连接使用的解析器，未配置DNS服务器时为nil(使用系统解析)
+var brokerResolver *net.Resolver
+
+// brokerTargets 解析一次或固定IP时设备轮流使用的服务器地址(已替换为IP)，为空时每次连接解析域名
+var brokerTargets struct {
+	servers map[string][]string // 按地址族分组: v4、v6，any为全部
+	next    atomic.Uint64
+}
+
+// nextFamily dual模式下交替分配地址族的计数
+var nextFamily atomic.Uint64
+
+// addressFamily 返回IP的地址族(v4/v6)
+func addressFamily(ip net.IP) string {
+	if ip.To4() != nil {
+		return "v4"
+	}
+	return "v6"
+}
+
+// familyNetwork 返回地址族对应的拨号网络类型
+func familyNetwork(family string) string {
+	switch family {
+	case "v4":
+		return "tcp4"
+	case "v6":
+		return "tcp6"
+	}
+	return "tcp"
+}
+
+// requiredFamilies 配置的地址族需要连通的地址族，any时不限制
+func requiredFamilies() []string {
+	switch AppConfig.Resolve.Family {
+	case "dual":
+		return []string{"v4", "v6"}
+	case "v4", "v6":
+		return []string{AppConfig.Resolve.Family}
+	}
+	return nil
+}
+
+// dnsResolver 使用配置的DNS服务器的解析器，未配置时返回nil(使用系统解析)
+//
+// 多个DNS服务器按查询轮流使用，Go解析器本身会在失败时重试。
+func dnsResolver() *net.Resolver {
+	servers := AppConfig.Resolve.DNSServers
+	if len(servers) == 0 {
+		return nil
+	}
+	var next atomic.Uint64
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			server := servers[next.Add(1)%uint64(len(servers))]
+			d := net.Dialer{Timeout: dnsTimeout}
+			return d.DialContext(ctx, network, server)
+		},
+	}
+}
+
+// splitServer 拆分服务器地址为协议前缀、主机和端口
+func splitServer(server string) (scheme, host, port string, err error) {
+	rest := server
+	if i := strings.Index(server, "://"); i >= 0 {
+		scheme, rest = server[:i+3], server[i+3:]
+	}
+	host, port, err = net.SplitHostPort(rest)
+	return scheme, host, port, err
+}
+
+// initResolve 按配置的解析方式准备设备连接的服务器地址
+//
+// 负载均衡和DNS的行为直接决定爬坡阶段的连接分布：每次连接解析时由DNS轮询决定分布，
+// 解析一次或固定IP时设备在解析结果之间轮流分配。服务器地址替换为IP后，TLS仍按原域名校验证书。
+func initResolve() error {
+	cfg := AppConfig.Resolve
+	brokerResolver = dnsResolver()
+	if len(cfg.PinIPs) == 0 && cfg.Mode != "once" {
+		// 每次连接解析时由拨号器按设备分配的地址族过滤解析结果
+		return nil
+	}
+	scheme, host, port, err := splitServer(AppConfig.MQTT.Server)
+	if err != nil {
+		return fmt.Errorf(T("解析服务器地址 %s 失败: %w"), AppConfig.MQTT.Server, err)
+	}
+
+	ips := cfg.PinIPs
+	if len(ips) == 0 {
+		resolver := brokerResolver
+		if resolver == nil {
+			resolver = net.DefaultResolver
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), dnsTimeout)
+		defer cancel()
+		ips, err = resolver.LookupHost(ctx, host)
+		if err != nil {
+			return fmt.Errorf(T("解析Broker域名 %s 失败: %w"), host, err)
+		}
+	}
+	brokerTargets.servers = make(map[string][]string)
+	for _, ip := range ips {
+		server := scheme + net.JoinHostPort(ip, port)
+		family := addressFamily(net.ParseIP(ip))
+		brokerTargets.servers[family] = append(brokerTargets.servers[family], server)
+		brokerTargets.servers["any"] = append(brokerTargets.servers["any"], server)
+	}
+	for _, family := range requiredFamilies() {
+		if len(brokerTargets.servers[family]) == 0 {
+			return fmt.Errorf(T("Broker地址 %s 没有可用的IP%s地址 (%s)"), host, family, strings.Join(ips, ", "))
+		}
+	}
+	if tlsState.base != nil && tlsState.base.ServerName == "" && net.ParseIP(host) == nil {
+		tlsState.base.ServerName = host
+	}
+	log.Printf(T("Broker地址 %s 使用 %d 个IP，设备轮流连接: %s"), host, len(ips), strings.Join(ips, ", "))
+	return nil
+}
+
+// nextBrokerTarget 返回下一个设备连接的服务器地址和地址族，dual模式下设备交替使用IPv4和IPv6
+func nextBrokerTarget() (server, family string) {
+	family = AppConfig.Resolve.Family
+	if family == "dual" {
+		family = []string{"v4", "v6"}[(nextFamily.Add(1)-1)%2]
+	}
+	servers := brokerTargets.servers[family]
+	if len(servers) == 0 {
+		return AppConfig.MQTT.Server, family
+	}
+	return servers[(brokerTargets.next.Add(1)-1)%uint64(len(servers))], family
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// RunSummary 单次测试运行的汇总结果
+type RunSummary struct {
+	RunID         string        `json:"run_id"`         // 运行ID
+	StartedAt     time.Time     `json:"started_at"`     // 开始时间
+	Duration      time.Duration `json:"duration"`       // 测试总耗时
+	Clients       int           `json:"clients"`        // 请求的设备数量
+	Connected     uint64        `json:"connected"`      // 成功连接的设备数
+	Cycles        int           `json:"cycles"`         // 测试循环次数
+	DataPoints    uint64        `json:"data_points"`    // 总发送数据点数
+	Messages      uint64        `json:"messages"`       // 总发送消息数
+	PointsPerSec  float64       `json:"points_per_sec"` // 平均数据点速率
+	MsgsPerSec    float64       `json:"msgs_per_sec"`   // 平均消息速率
+	DBWritten     int64         `json:"db_written"`     // 监控期间累计入库数据点数
+	Received      uint64        `json:"received"`       // 收到的下行消息数
+	ConfigPayload []byte        `json:"-"`              // 本次运行的配置(JSON)
+}
+
+// 历史记录子命令参数
+var (
+	historyLimit   = flag.Int("limit", 20, "history: 显示最近的运行记录数量")
+	historyCompare = flag.String("compare", "", "history: 对比的两个运行ID，逗号分隔")
+)
+
+// resultsSchema 结果库schema名，按SQL标识符引用
+func resultsSchema() string {
+	return pgx.Identifier{AppConfig.Results.Schema}.Sanitize()
+}
+
+// resultsTable 结果库schema中的表名，按SQL标识符引用
+func resultsTable(name string) string {
+	return pgx.Identifier{AppConfig.Results.Schema, name}.Sanitize()
+}
+
+// ensureResultsSchema 创建结果库的schema和表（如不存在）
+func ensureResultsSchema(db *sql.DB) error {
+	stmts := []string{
+		fmt.Sprintf(`CREATE SCHEMA IF NOT EXISTS %s`, resultsSchema()),
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+			run_id         text PRIMARY KEY,
+			started_at     timestamptz NOT NULL,
+			duration_ms    bigint NOT NULL,
+			clients        integer NOT NULL,
+			connected      bigint NOT NULL,
+			cycles         integer NOT NULL,
+			data_points    bigint NOT NULL,
+			messages       bigint NOT NULL,
+			points_per_sec double precision NOT NULL,
+			msgs_per_sec   double precision NOT NULL,
+			db_written     bigint NOT NULL,
+			config         jsonb NOT NULL
+		)`, resultsTable("runs")),
+	}
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf(T("创建结果表失败: %w"), err)
+		}
+	}
+	return nil
+}
+
+// saveRunSummary 将本次运行的配置和汇总指标写入结果库
+//
+// 中断恢复(--resume、长稳测试)沿用原运行ID，同一运行ID已有记录时累加各次运行的时长和计数，
+// 开始时间取最早的一次，速率按累计值重新计算，设备数和配置取最近一次。
+func saveRunSummary(summary RunSummary) error {
+	db, err := openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := ensureResultsSchema(db); err != nil {
+		return err
+	}
+
+	_, err = db.Exec(fmt.Sprintf(`INSERT INTO %s AS r (
+		run_id, started_at, duration_ms, clients, connected, cycles,
+		data_points, messages, points_per_sec, msgs_per_sec, db_written, config)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	ON CONFLICT (run_id) DO UPDATE SET
+		started_at     = LEAST(r.started_at, EXCLUDED.started_at),
+		duration_ms    = r.duration_ms + EXCLUDED.duration_ms,
+		clients        = EXCLUDED.clients,
+		connected      = EXCLUDED.connected,
+		cycles         = r.cycles + EXCLUDED.cycles,
+		data_points    = r.data_points + EXCLUDED.data_points,
+		messages       = r.messages + EXCLUDED.messages,
+		points_per_sec = (r.data_points + EXCLUDED.data_points) * 1000.0 / GREATEST(r.duration_ms + EXCLUDED.duration_ms, 1),
+		msgs_per_sec   = (r.messages + EXCLUDED.messages) * 1000.0 / GREATEST(r.duration_ms + EXCLUDED.duration_ms, 1),
+		db_written     = r.db_written + EXCLUDED.db_written,
+		config         = EXCLUDED.config`, resultsTable("runs")),
+		summary.RunID,
+		summary.StartedAt,
+		summary.Duration.Milliseconds(),
+		summary.Clients,
+		summary.Connected,
+		summary.Cycles,
+		summary.DataPoints,
+		summary.Messages,
+		summary.PointsPerSec,
+		summary.MsgsPerSec,
+		summary.DBWritten,
+		string(summary.ConfigPayload),
+	)
+	if err != nil {
+		return fmt.Errorf(T("写入运行结果失败: %w"), err)
+	}
+	return nil
+}
+
+// configSnapshot 将当前配置序列化为JSON（隐藏密码等敏感信息）
+func configSnapshot() []byte {
+	data, err := json.Marshal(redactedConfig())
+	if err != nil {
+		log.Printf(T("警告: 序列化配置失败: %v"), err)
+		return []byte("{}")
+	}
+	return data
+}
+
+// loadRunSummaries 从结果库读取运行记录，ids为空时读取最近limit条
+func loadRunSummaries(db *sql.DB, ids []string, limit int) ([]RunSummary, error) {
+	query := fmt.Sprintf(`SELECT run_id, started_at, duration_ms, clients, connected, cycles,
+		data_points, messages, points_per_sec, msgs_per_sec, db_written, config
+	FROM %s`, resultsTable("runs"))
+
+	var rows *sql.Rows
+	var err error
+	if len(ids) > 0 {
+		// 按 --compare 中的顺序返回，而不是按开始时间
+		rows, err = db.Query(query+` WHERE run_id = ANY(string_to_array($1, ','))
+			ORDER BY array_position(string_to_array($1, ','), run_id)`,
+			strings.Join(ids, ","))
+	} else {
+		rows, err = db.Query(query+` ORDER BY started_at DESC LIMIT $1`, limit)
+	}
+	if err != nil {
+		return nil, fmt.Errorf(T("查询运行记录失败: %w"), err)
+	}
+	defer rows.Close()
+
+	var summaries []RunSummary
+	for rows.Next() {
+		var s RunSummary
+		var durationMs int64
+		if err := rows.Scan(&s.RunID, &s.StartedAt, &durationMs, &s.Clients, &s.Connected, &s.Cycles,
+			&s.DataPoints, &s.Messages, &s.PointsPerSec, &s.MsgsPerSec, &s.DBWritten, &s.ConfigPayload); err != nil {
+			return nil, fmt.Errorf(T("读取运行记录失败: %w"), err)
+		}
+		s.Duration = time.Duration(durationMs) * time.Millisecond
+		summaries = append(summaries, s)
+	}
+	return summaries, rows.Err()
+}
+
+// runHistory history子命令：列出或对比历史运行记录
+func runHistory() {
+	db, err := openDatabase()
+	if err != nil {
+		log.Fatalf(T("连接结果库失败: %v"), err)
+	}
+	defer db.Close()
+
+	if *historyCompare != "" {
+		ids := strings.Split(*historyCompare, ",")
+		if len(ids) != 2 {
+			log.Fatalf(T("--compare 需要两个运行ID，例如: --compare a1b2c3d4,e5f6a7b8"))
+		}
+		summaries, err := loadRunSummaries(db, ids, 0)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		if len(summaries) != 2 {
+			log.Fatalf(T("未找到全部运行记录: %s"), *historyCompare)
+		}
+		printRunComparison(summaries[0], summaries[1])
+		return
+	}
+
+	summaries, err := loadRunSummaries(db, nil, *historyLimit)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if len(summaries) == 0 {
+		log.Println(T("结果库中没有运行记录"))
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, T("运行ID\t开始时间\t耗时\t设备数\t循环\t数据点\t点/秒\t消息/秒\t入库数"))
+	for _, s := range summaries {
+		fmt.Fprintf(w, "%s\t%s\t%v\t%d/%d\t%d\t%d\t%.1f\t%.1f\t%d\n",
+			s.RunID, s.StartedAt.Format("2006-01-02 15:04:05"), s.Duration.Round(time.Second),
+			s.Connected, s.Clients, s.Cycles, s.DataPoints, s.PointsPerSec, s.MsgsPerSec, s.DBWritten)
+	}
+	w.Flush()
+}
+
+// printRunComparison 并排输出两次运行的指标及变化率
+func printRunComparison(a, b RunSummary) {
+	change := func(before, after float64) string {
+		if before == 0 {
+			return "-"
+		}
+		return fmt.Sprintf("%+.1f%%", (after-before)/before*100)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, T("指标\t%s\t%s\t变化\n"), a.RunID, b.RunID)
+	fmt.Fprintf(w, T("开始时间\t%s\t%s\t\n"),
+		a.StartedAt.Format("2006-01-02 15:04:05"), b.StartedAt.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(w, T("耗时\t%v\t%v\t%s\n"), a.Duration.Round(time.Second), b.Duration.Round(time.Second),
+		change(a.Duration.Seconds(), b.Duration.Seconds()))
+	fmt.Fprintf(w, T("连接设备数\t%d\t%d\t%s\n"), a.Connected, b.Connected,
+		change(float64(a.Connected), float64(b.Connected)))
+	fmt.Fprintf(w, T("总发送数据点\t%d\t%d\t%s\n"), a.DataPoints, b.DataPoints,
+		change(float64(a.DataPoints), float64(b.DataPoints)))
+	fmt.Fprintf(w, T("总发送消息\t%d\t%d\t%s\n"), a.Messages, b.Messages,
+		change(float64(a.Messages), float64(b.Messages)))
+	fmt.Fprintf(w, T("数据点速率(点/秒)\t%.1f\t%.1f\t%s\n"), a.PointsPerSec, b.PointsPerSec,
+		change(a.PointsPerSec, b.PointsPerSec))
+	fmt.Fprintf(w, T("消息速率(条/秒)\t%.1f\t%.1f\t%s\n"), a.MsgsPerSec, b.MsgsPerSec,
+		change(a.MsgsPerSec, b.MsgsPerSec))
+	fmt.Fprintf(w, T("入库数据点\t%d\t%d\t%s\n"), a.DBWritten, b.DBWritten,
+		change(float64(a.DBWritten), float64(b.DBWritten)))
+	w.Flush()
+}
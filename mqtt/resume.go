@@ -0,0 +1,148 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// 中断恢复命令行参数
+var resumeFlag = flag.Bool("resume", false, "中断后恢复：定期保存场景进度检查点(当前阶段、已完成循环、每设备偏移)，压测程序崩溃后重新启动时从检查点的下一个循环继续，正常结束后删除检查点")
+
+// DeviceOffset 检查点中单个设备的进度
+type DeviceOffset struct {
+	Messages uint64   `json:"messages"`          // 已发布的遥测消息数(含失败)
+	Streams  []uint64 `json:"streams,omitempty"` // 各额外数据流的{seq}，恢复后继续递增
+}
+
+// deviceOffset 设备进度的运行中计数，由设备goroutine更新、检查点goroutine读取
+type deviceOffset struct {
+	messages atomic.Uint64
+	streams  []atomic.Uint64
+}
+
+// deviceOffsets 全部设备的进度，只在保存检查点时需要
+var deviceOffsets struct {
+	mu      sync.Mutex
+	devices map[string]*deviceOffset
+}
+
+// startCycle 主循环的第一个循环，从检查点恢复时为检查点的下一个循环
+var startCycle = 1
+
+// currentStageName 当前阶段的名称，写入检查点
+var currentStageName atomic.Pointer[string]
+
+// resumeEnabled 是否从检查点恢复场景进度
+func resumeEnabled() bool {
+	return AppConfig.Soak.Resume
+}
+
+// checkpointEnabled 是否定期保存检查点(长稳测试或中断恢复)
+func checkpointEnabled() bool {
+	return AppConfig.Soak.Enabled || resumeEnabled()
+}
+
+// scenarioFingerprint 决定循环含义的场景参数，恢复时与检查点核对，不一致时从检查点的循环继续没有意义
+func scenarioFingerprint() string {
+	return fmt.Sprintf("clients=%d cycles=%d stages=%s", AppConfig.Device.ClientNumber, AppConfig.Test.CycleCount, AppConfig.Test.Stages)
+}
+
+// resumeScenario 根据检查点确定开始的循环，检查点与当前场景不一致或测试已完成时退出
+func resumeScenario(cp *SoakCheckpoint) {
+	if cp.Scenario != "" && cp.Scenario != scenarioFingerprint() {
+		log.Fatalf(T("中断恢复: 检查点的场景 (%s) 与当前配置 (%s) 不一致，请删除 %s 后重新开始"),
+			cp.Scenario, scenarioFingerprint(), AppConfig.Soak.CheckpointFile)
+	}
+	if !AppConfig.Soak.Enabled && !daemonEnabled() && cp.Cycles >= uint64(AppConfig.Test.CycleCount) {
+		log.Fatalf(T("中断恢复: 检查点显示已完成全部 %d 个循环，请删除 %s 后重新开始"),
+			AppConfig.Test.CycleCount, AppConfig.Soak.CheckpointFile)
+	}
+	startCycle = int(cp.Cycles) + 1
+	stage := cp.Stage
+	if stage == "" {
+		stage = "-"
+	}
+	log.Printf(T("中断恢复: 从第 %d 个循环继续 (阶段: %s, %d 个设备的进度)"), startCycle, stage, len(cp.Devices))
+}
+
+// trackDeviceOffset 创建设备的进度计数，从检查点恢复时以检查点中的进度为起点，未启用时返回nil
+func trackDeviceOffset(username string, streams []*streamPublisher) *deviceOffset {
+	if !checkpointEnabled() {
+		return nil
+	}
+	o := &deviceOffset{streams: make([]atomic.Uint64, len(streams))}
+	base := soakBase.Devices[username]
+	o.messages.Store(base.Messages)
+	for i, stream := range streams {
+		if i < len(base.Streams) {
+			stream.seq = base.Streams[i]
+		}
+		o.streams[i].Store(stream.seq)
+	}
+	deviceOffsets.mu.Lock()
+	if deviceOffsets.devices == nil {
+		deviceOffsets.devices = make(map[string]*deviceOffset)
+	}
+	deviceOffsets.devices[username] = o
+	deviceOffsets.mu.Unlock()
+	return o
+}
+
+// recordMessage 记录设备发布了一条遥测消息，nil表示未启用
+func (o *deviceOffset) recordMessage() {
+	if o != nil {
+		o.messages.Add(1)
+	}
+}
+
+// recordStream 记录设备第i个额外数据流的序号，nil表示未启用
+func (o *deviceOffset) recordStream(i int, seq uint64) {
+	if o != nil {
+		o.streams[i].Store(seq)
+	}
+}
+
+// deviceOffsetSnapshot 全部设备的当前进度，本进程没有启动的设备沿用检查点中的进度
+func deviceOffsetSnapshot() map[string]DeviceOffset {
+	deviceOffsets.mu.Lock()
+	defer deviceOffsets.mu.Unlock()
+	if len(deviceOffsets.devices) == 0 && len(soakBase.Devices) == 0 {
+		return nil
+	}
+	devices := make(map[string]DeviceOffset, max(len(deviceOffsets.devices), len(soakBase.Devices)))
+	for username, offset := range soakBase.Devices {
+		devices[username] = offset
+	}
+	for username, o := range deviceOffsets.devices {
+		offset := DeviceOffset{Messages: o.messages.Load()}
+		for i := range o.streams {
+			offset.Streams = append(offset.Streams, o.streams[i].Load())
+		}
+		devices[username] = offset
+	}
+	return devices
+}
+
+// finishResume 测试正常结束后删除检查点，下次运行从头开始；中断或提前终止时保存最终检查点
+func finishResume(runID string, completed bool) {
+	if !resumeEnabled() || AppConfig.Soak.Enabled {
+		return
+	}
+	if completed {
+		if err := os.Remove(AppConfig.Soak.CheckpointFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf(T("中断恢复: 删除检查点失败: %v"), err)
+		}
+		return
+	}
+	cp := currentCheckpoint(runID)
+	if err := saveSoakCheckpoint(AppConfig.Soak.CheckpointFile, cp); err != nil {
+		log.Printf(T("检查点: %v"), err)
+		return
+	}
+	log.Printf(T("中断恢复: 检查点已保存到 %s，使用 --resume 重新启动可从第 %d 个循环继续"), AppConfig.Soak.CheckpointFile, cp.Cycles+1)
+}
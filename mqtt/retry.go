@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 发布重试命令行参数
+var (
+	publishRetries = flag.Int("publish-retries", 0, "每条消息最多发布次数(含首次)，1为不重试")
+	retryBackoff   = flag.Duration("retry-backoff", 0, "首次重试前的等待时间，之后每次翻倍")
+)
+
+// 发布重试统计
+var (
+	retriedCount   uint64 // 重试后发布成功的消息数
+	abandonedCount uint64 // 重试次数用尽后放弃的消息数
+	retryAttempts  uint64 // 重试总次数(不含首次发布)
+)
+
+// retryRandStream 发布重试抖动随机数流的编号，与其他用途的随机数流错开
+const retryRandStream = 5 << 32
+
+// retryRand 所有设备共享的重试抖动随机数流，在确定随机数种子后首次使用时创建
+var retryRand = sync.OnceValue(func() *lockedRand { return newLockedRand(retryRandStream) })
+
+// publishWithRetry 发布消息，失败时按指数退避重试，返回最后一次的错误
+//
+// 第n次重试前等待 backoff*2^(n-1)(不超过max_backoff)，并加上±jitter比例的随机抖动，
+// 避免大量设备在Broker恢复的同一时刻集中重发。测试结束时立即放弃重试。
+func publishWithRetry(ctx context.Context, client DeviceClient, topic string, qos byte, payload []byte) error {
+	cfg := AppConfig.Retry
+	err := client.Publish(topic, qos, payload)
+	if err == nil {
+		return nil
+	}
+
+	backoff := cfg.Backoff
+	for attempt := 2; attempt <= cfg.MaxAttempts; attempt++ {
+		wait := jittered(backoff, cfg.Jitter, retryRand())
+		select {
+		case <-ctx.Done():
+			atomic.AddUint64(&abandonedCount, 1)
+			return err
+		case <-time.After(wait):
+		}
+
+		atomic.AddUint64(&retryAttempts, 1)
+		if err = client.Publish(topic, qos, payload); err == nil {
+			atomic.AddUint64(&retriedCount, 1)
+			return nil
+		}
+		backoff = min(backoff*2, cfg.MaxBackoff)
+	}
+
+	if cfg.MaxAttempts > 1 {
+		atomic.AddUint64(&abandonedCount, 1)
+	}
+	return err
+}
+
+// jitterSource 抖动使用的随机数来源，*rand.Rand和*lockedRand均满足
+type jitterSource interface {
+	Float64() float64
+}
+
+// jittered 在等待时间上加±jitter比例的随机抖动，随机数取自rng以便按种子复现
+func jittered(d time.Duration, jitter float64, rng jitterSource) time.Duration {
+	if jitter <= 0 {
+		return d
+	}
+	return time.Duration(float64(d) * (1 + jitter*(2*rng.Float64()-1)))
+}
+
+// reportRetries 输出发布重试统计(未启用重试时不输出)
+func reportRetries() {
+	if AppConfig.Retry.MaxAttempts <= 1 {
+		return
+	}
+	log.Printf(T("发布重试: 重试后成功 %d 条, 放弃 %d 条, 共重试 %d 次"),
+		atomic.LoadUint64(&retriedCount), atomic.LoadUint64(&abandonedCount), atomic.LoadUint64(&retryAttempts))
+}
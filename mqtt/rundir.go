@@ -0,0 +1,145 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 运行目录相关命令行参数
+var outputDir = flag.String("output-dir", "", "运行产物根目录，每次运行写入 <时间>-<运行ID> 子目录")
+
+// latestLink 指向最近一次运行目录的符号链接名称
+const latestLink = "latest"
+
+// redacted 快照中替换敏感信息的占位符
+const redacted = "******"
+
+// secretFlags 值为敏感信息的命令行参数，快照中隐藏
+var secretFlags = map[string]bool{"db-pass": true, "db-dsn": true, "alert-webhook": true, "report-webhook": true, "provision-secret": true}
+
+// runDir 本次运行的产物目录，未创建时为空
+var runDir string
+
+// setupRunDir 创建本次运行目录，更新latest链接，并将日志同时写入目录中的run.log
+func setupRunDir(runID string, started time.Time) error {
+	name := started.Format("20060102-150405") + "-" + runID
+	dir := filepath.Join(AppConfig.Output.Dir, name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf(T("创建运行目录失败: %w"), err)
+	}
+	runDir = dir
+
+	// latest使用相对链接，整个结果目录可以直接打包拷贝；
+	// Windows上创建符号链接可能需要管理员权限，失败时改为写入目录名
+	latest := filepath.Join(AppConfig.Output.Dir, latestLink)
+	os.Remove(latest)
+	if err := os.Symlink(name, latest); err != nil {
+		if err := os.WriteFile(latest, []byte(name+"\n"), 0644); err != nil {
+			log.Printf(T("警告: 更新 %s 失败: %v"), latest, err)
+		}
+	}
+
+	// 长稳测试的日志按大小轮转，避免单个文件无限增长
+	maxSize := int64(AppConfig.Output.MaxLogSize) << 20
+	logFile, err := newRotatingWriter(runArtifact("run.log"), maxSize, AppConfig.Output.MaxLogFiles)
+	if err != nil {
+		return fmt.Errorf(T("创建日志文件失败: %w"), err)
+	}
+	log.SetOutput(io.MultiWriter(os.Stderr, logFile))
+
+	writeRunArtifact("config.json", configSnapshot())
+	writeRunArtifact("config.yml", resolvedConfigFile(runID))
+	return nil
+}
+
+// redactedURL 隐藏Webhook地址中协议和主机以外的部分
+func redactedURL(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	if u, err := url.Parse(raw); err == nil && u.Host != "" {
+		return u.Scheme + "://" + u.Host + "/" + redacted
+	}
+	return redacted
+}
+
+// redactedConfig 返回隐藏了密码和Webhook地址的配置副本
+//
+// Webhook地址的路径和参数中通常带有机器人的access token，只保留协议和主机。
+func redactedConfig() Config {
+	cfg := AppConfig
+	if cfg.Database.Password != "" {
+		cfg.Database.Password = redacted
+	}
+	if cfg.Database.DSN != "" {
+		cfg.Database.DSN = redacted
+	}
+	if cfg.Consumers.Password != "" {
+		cfg.Consumers.Password = redacted
+	}
+	if cfg.Provision.Secret != "" {
+		cfg.Provision.Secret = redacted
+	}
+	cfg.Alerts.Webhook = redactedURL(cfg.Alerts.Webhook)
+	cfg.Report.Webhook = redactedURL(cfg.Report.Webhook)
+	if cfg.Report.SMTPPass != "" {
+		cfg.Report.SMTPPass = redacted
+	}
+	return cfg
+}
+
+// commandLine 返回本次运行实际设置的命令行参数，敏感参数的值隐藏
+func commandLine() string {
+	var args []string
+	flag.Visit(func(f *flag.Flag) {
+		value := f.Value.String()
+		if secretFlags[f.Name] {
+			value = redacted
+		}
+		args = append(args, fmt.Sprintf("--%s=%q", f.Name, value))
+	})
+	return strings.Join(args, " ")
+}
+
+// resolvedConfigFile 生成合并了配置文件、命令行参数、环境变量(TP_LANG)和默认值之后的完整配置文件
+//
+// 使用 --config 指定该文件即可复现本次运行，无需再回忆当时用了哪些参数；
+// 隐藏的密码需要先填回。随机数种子已写入配置，测试数据与本次运行相同。
+func resolvedConfigFile(runID string) []byte {
+	cfg := redactedConfig()
+	data, err := yaml.Marshal(&cfg)
+	if err != nil {
+		log.Printf(T("警告: 序列化配置失败: %v"), err)
+		return nil
+	}
+	header := fmt.Sprintf(T("# 运行 %s 的完整配置(已合并配置文件、命令行参数、环境变量和默认值)，使用 --config 指定本文件可复现本次运行\n# 密码等敏感信息已隐藏为 %s，复现前需要填回\n# 命令行参数: %s\n\n"),
+		runID, redacted, commandLine())
+	return append([]byte(header), data...)
+}
+
+// runArtifact 返回产物文件路径：相对路径放在本次运行目录下，绝对路径保持不变
+func runArtifact(name string) string {
+	if runDir == "" || filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(runDir, name)
+}
+
+// writeRunArtifact 将数据写入本次运行目录，未创建运行目录时忽略
+func writeRunArtifact(name string, data []byte) {
+	if runDir == "" {
+		return
+	}
+	if err := os.WriteFile(runArtifact(name), data, 0644); err != nil {
+		log.Printf(T("写入 %s 失败: %v"), name, err)
+	}
+}
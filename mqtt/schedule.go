@@ -0,0 +1,99 @@
+package main
+
+import (
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// maxOmittedSamples 单次补记错过循环的样本数上限
+const maxOmittedSamples = 1000
+
+// sendSlot 一个循环的计划发送时间
+type sendSlot struct {
+	cycle uint64
+	at    time.Time
+}
+
+// Schedule 跟踪计划发送时间，用于修正协调遗漏(coordinated omission)
+//
+// 发布阻塞超过计划时间时，只从实际开始发送的时刻计时会低估延迟：被推迟的发送和
+// 错过的循环都不会出现在统计中。修正后的耗时从计划发送时间算起，设备因上一次发布
+// 尚未完成而错过的循环按各自的计划时间补记样本。主循环本身晚于计划触发的时间
+// 计为调度落后，吞吐量应结合该值解读。
+type Schedule struct {
+	slot atomic.Pointer[sendSlot] // 当前循环
+
+	behind     atomic.Int64  // 主循环累计晚于计划触发的时间(纳秒)
+	lateCycles atomic.Uint64 // 晚于计划触发的循环数
+	omitted    atomic.Uint64 // 设备错过的循环数
+
+	corrected Histogram // 从计划发送时间到发布完成的耗时(含补记样本)
+	startLag  Histogram // 设备实际开始发送时间晚于计划时间的时长
+}
+
+// schedule 全局发送计划
+var schedule Schedule
+
+// begin 主循环触发发送前登记本循环的计划时间和实际触发时间
+func (s *Schedule) begin(cycle uint64, planned, now time.Time) {
+	if lag := now.Sub(planned); lag > time.Millisecond {
+		s.behind.Add(int64(lag))
+		s.lateCycles.Add(1)
+	}
+	s.slot.Store(&sendSlot{cycle: cycle, at: planned})
+}
+
+// current 返回当前循环，尚未开始发送时返回零值
+func (s *Schedule) current() sendSlot {
+	if slot := s.slot.Load(); slot != nil {
+		return *slot
+	}
+	return sendSlot{}
+}
+
+// behindSchedule 返回主循环累计落后于计划的时间
+func (s *Schedule) behindSchedule() time.Duration {
+	return time.Duration(s.behind.Load())
+}
+
+// recordStart 记录设备开始发送的时间相对计划时间的延迟
+func (s *Schedule) recordStart(slot sendSlot, start time.Time) {
+	s.startLag.Record(start.Sub(slot.at))
+}
+
+// recordDone 记录发布完成，lastCycle为设备上一次发送的循环，用于补记错过的循环
+func (s *Schedule) recordDone(slot sendSlot, lastCycle uint64, done time.Time) {
+	s.corrected.Record(done.Sub(slot.at))
+	if lastCycle == 0 || slot.cycle <= lastCycle+1 {
+		return
+	}
+
+	// 错过的循环按当前发送间隔向前推算计划时间
+	missed := slot.cycle - lastCycle - 1
+	s.omitted.Add(missed)
+	interval := currentSendInterval()
+	for i := uint64(1); i <= min(missed, maxOmittedSamples); i++ {
+		s.corrected.Record(done.Sub(slot.at.Add(-time.Duration(i) * interval)))
+	}
+}
+
+// Report 对比实测耗时与修正后的耗时，输出调度落后情况
+func (s *Schedule) Report(cycles uint64) {
+	if s.corrected.Count() == 0 {
+		return
+	}
+	log.Println(T("\n========== 调度与协调遗漏 =========="))
+	log.Printf(T("实测发布耗时: p50=%v, p99=%v, 最大=%v"),
+		publishLatency.Quantile(0.50), publishLatency.Quantile(0.99), publishLatency.Max())
+	log.Printf(T("修正后耗时(从计划发送时间算起): p50=%v, p99=%v, 最大=%v"),
+		s.corrected.Quantile(0.50), s.corrected.Quantile(0.99), s.corrected.Max())
+	log.Printf(T("设备开始发送晚于计划: p50=%v, p99=%v, 最大=%v"),
+		s.startLag.Quantile(0.50), s.startLag.Quantile(0.99), s.startLag.Max())
+	log.Printf(T("主循环晚于计划触发: %d/%d 个循环, 累计 %v"),
+		s.lateCycles.Load(), cycles, s.behindSchedule().Round(time.Millisecond))
+	if omitted := s.omitted.Load(); omitted > 0 {
+		log.Printf(T("设备因上一次发布未完成而错过的循环: %d 次(已按计划时间补记耗时样本)"), omitted)
+	}
+	log.Println("===============================")
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 消息接收端命令行参数
+var (
+	sinkMode = flag.String("sink", "", "消息不发送到Broker而由本地接收端处理(null: 直接丢弃, file: 写入本地文件)，用于测量本机的数据生成能力或生成样本数据集")
+	sinkFile = flag.String("sink-file", "", "file接收端的输出文件(默认为运行目录下的sink.jsonl)")
+)
+
+// defaultSinkFile file接收端默认的输出文件名
+const defaultSinkFile = "sink.jsonl"
+
+// sinkStats 接收端统计
+var sinkStats struct {
+	messages atomic.Uint64 // 收到的消息数(含设备信息、心跳等各数据流)
+	bytes    atomic.Uint64 // 收到的消息内容字节数
+}
+
+// sinkOutput file接收端的输出，各设备共用一个带缓冲的文件
+var sinkOutput struct {
+	mu   sync.Mutex
+	file *os.File
+	w    *bufio.Writer
+	err  error // 第一次写入错误，之后不再写入
+}
+
+// sinkEnabled 是否使用本地接收端代替Broker
+func sinkEnabled() bool {
+	return AppConfig.Sink.Mode != ""
+}
+
+// openSink 打开file接收端的输出文件(相对路径位于运行目录下)
+func openSink() error {
+	if AppConfig.Sink.Mode != "file" {
+		return nil
+	}
+	f, err := os.Create(runArtifact(AppConfig.Sink.File))
+	if err != nil {
+		return fmt.Errorf(T("创建接收端输出文件失败: %w"), err)
+	}
+	sinkOutput.file = f
+	sinkOutput.w = bufio.NewWriterSize(f, 1<<20)
+	return nil
+}
+
+// closeSink 刷新并关闭输出文件
+func closeSink() {
+	sinkOutput.mu.Lock()
+	defer sinkOutput.mu.Unlock()
+	if sinkOutput.file == nil {
+		return
+	}
+	if err := sinkOutput.w.Flush(); err != nil && sinkOutput.err == nil {
+		sinkOutput.err = err
+	}
+	sinkOutput.file.Close()
+	sinkOutput.file = nil
+}
+
+// sinkRecord file接收端输出的一行
+type sinkRecord struct {
+	TS      int64           `json:"ts"` // 毫秒时间戳
+	Device  string          `json:"device"`
+	Topic   string          `json:"topic"`
+	Payload json.RawMessage `json:"payload"` // 消息内容，不是JSON时为字符串
+}
+
+// sinkClient 不经过网络的设备客户端，实现DeviceClient以复用完整的发送循环和统计
+//
+// 消息由设备goroutine生成后直接丢弃或写入文件，测得的是本机生成、编码消息的能力，
+// 不受Broker和平台性能的影响。写入文件时各设备共用一把锁，速率会低于丢弃。
+type sinkClient struct {
+	username string
+}
+
+// newSinkClient 创建接收端客户端
+func newSinkClient(username string) *sinkClient {
+	return &sinkClient{username: username}
+}
+
+func (c *sinkClient) Connect() error {
+	return nil
+}
+
+// Publish 记录消息，file接收端每条消息写入一行JSON：时间(毫秒)、设备、主题和消息内容
+func (c *sinkClient) Publish(topic string, qos byte, payload []byte) error {
+	sinkStats.messages.Add(1)
+	sinkStats.bytes.Add(uint64(len(payload)))
+	if AppConfig.Sink.Mode != "file" {
+		return nil
+	}
+
+	out := &sinkOutput
+	out.mu.Lock()
+	defer out.mu.Unlock()
+	if out.file == nil || out.err != nil {
+		return out.err
+	}
+	record := sinkRecord{TS: time.Now().UnixMilli(), Device: c.username, Topic: topic, Payload: payload}
+	if !json.Valid(payload) {
+		record.Payload, _ = json.Marshal(string(payload))
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	if _, err := out.w.Write(line); err != nil {
+		out.err = fmt.Errorf(T("写入接收端输出文件失败: %w"), err)
+		return out.err
+	}
+	return nil
+}
+
+func (c *sinkClient) Subscribe(topic string, qos byte) (byte, error) {
+	return subackFailure, errors.New(T("接收端不支持订阅"))
+}
+
+func (c *sinkClient) Disconnect() {}
+
+// reportSink 关闭输出文件并输出接收端统计(未启用时不输出)
+func reportSink(duration time.Duration) {
+	if !sinkEnabled() {
+		return
+	}
+	closeSink()
+	messages, bytes := sinkStats.messages.Load(), sinkStats.bytes.Load()
+	log.Println(T("\n========== 本地接收端 =========="))
+	log.Printf(T("模式: %s, 收到消息 %d 条 (%.1f 条/秒), %.1f MB (%.1f MB/秒)"),
+		AppConfig.Sink.Mode, messages, ratePerSecond(float64(messages), duration),
+		float64(bytes)/(1<<20), ratePerSecond(float64(bytes)/(1<<20), duration))
+	if AppConfig.Sink.Mode == "file" {
+		if sinkOutput.err != nil {
+			log.Printf(T("输出文件 %s: %v"), runArtifact(AppConfig.Sink.File), sinkOutput.err)
+		} else {
+			log.Printf(T("输出文件: %s"), runArtifact(AppConfig.Sink.File))
+		}
+	}
+	log.Println(T("以上速率为本机生成消息的能力，不经过网络和Broker"))
+	log.Println("===============================")
+}
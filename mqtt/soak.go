@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// SoakCheckpoint 长稳测试和中断恢复的检查点
+type SoakCheckpoint struct {
+	RunID      string        `json:"run_id"`      // 运行ID(恢复后沿用)
+	Cycles     uint64        `json:"cycles"`      // 累计完成的循环次数
+	DataPoints uint64        `json:"data_points"` // 累计发送数据点数
+	Messages   uint64        `json:"messages"`    // 累计发送消息数
+	Elapsed    time.Duration `json:"elapsed"`     // 累计发送时长
+	Restarts   int           `json:"restarts"`    // 从检查点恢复的次数
+	SavedAt    time.Time     `json:"saved_at"`    // 保存时间
+
+	Scenario string                  `json:"scenario,omitempty"` // 场景参数，中断恢复时核对
+	Stage    string                  `json:"stage,omitempty"`    // 保存时所在的阶段(分阶段负载)
+	Devices  map[string]DeviceOffset `json:"devices,omitempty"`  // 设备token -> 设备进度
+}
+
+// 长稳测试相关命令行参数
+var (
+	soakMode           = flag.Bool("soak", false, "长稳测试模式：持续运行直到手动停止")
+	checkpointFile     = flag.String("checkpoint-file", "", "长稳测试检查点文件路径")
+	checkpointInterval = flag.Duration("checkpoint-interval", 0, "长稳测试检查点保存间隔")
+)
+
+// soakBase 从检查点恢复的基准计数，本进程的计数在此基础上累加
+var soakBase SoakCheckpoint
+
+// loadSoakCheckpoint 读取检查点文件，文件不存在时返回nil
+func loadSoakCheckpoint(path string) (*SoakCheckpoint, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf(T("读取检查点文件失败: %w"), err)
+	}
+
+	var cp SoakCheckpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf(T("解析检查点文件失败: %w"), err)
+	}
+	return &cp, nil
+}
+
+// saveSoakCheckpoint 原子地写入检查点文件(先写临时文件再重命名)
+func saveSoakCheckpoint(path string, cp SoakCheckpoint) error {
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return fmt.Errorf(T("序列化检查点失败: %w"), err)
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf(T("写入检查点文件失败: %w"), err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf(T("替换检查点文件失败: %w"), err)
+	}
+	return nil
+}
+
+// currentCheckpoint 合并基准计数与本进程计数，生成当前检查点
+func currentCheckpoint(runID string) SoakCheckpoint {
+	var elapsed time.Duration
+	if first := firstSendTime.Load(); first != nil && first.(*time.Time) != nil {
+		elapsed = time.Since(*first.(*time.Time))
+	}
+
+	var stage string
+	if name := currentStageName.Load(); name != nil {
+		stage = *name
+	}
+	snap := runStats.Snapshot()
+	return SoakCheckpoint{
+		RunID:      runID,
+		Cycles:     soakBase.Cycles + snap.Cycles,
+		DataPoints: soakBase.DataPoints + snap.DataPoints,
+		Messages:   soakBase.Messages + snap.Messages,
+		Elapsed:    soakBase.Elapsed + elapsed,
+		Restarts:   soakBase.Restarts,
+		SavedAt:    time.Now(),
+		Scenario:   scenarioFingerprint(),
+		Stage:      stage,
+		Devices:    deviceOffsetSnapshot(),
+	}
+}
+
+// resumeSoak 从检查点恢复计数(中断恢复时还恢复开始的循环和设备进度)，返回应沿用的运行ID
+func resumeSoak(runID string) string {
+	cp, err := loadSoakCheckpoint(AppConfig.Soak.CheckpointFile)
+	if err != nil {
+		log.Fatalf(T("检查点: %v"), err)
+	}
+	if cp == nil {
+		log.Printf(T("检查点: 未找到检查点 %s，从零开始计数"), AppConfig.Soak.CheckpointFile)
+		return runID
+	}
+
+	soakBase = *cp
+	soakBase.Restarts++
+	log.Printf(T("检查点: 从检查点恢复 (运行ID=%s, 循环=%d, 数据点=%d, 消息=%d, 累计时长=%v, 保存于 %s)"),
+		cp.RunID, cp.Cycles, cp.DataPoints, cp.Messages, cp.Elapsed.Round(time.Second),
+		cp.SavedAt.Format("2006-01-02 15:04:05"))
+	if resumeEnabled() {
+		resumeScenario(cp)
+	}
+	return cp.RunID
+}
+
+// runCheckpointer 定期保存检查点，ctx结束时退出
+//
+// 最终检查点在所有设备停止发送后由主流程保存(正常结束的中断恢复运行则删除检查点)，
+// 这里不再保存，以免与之交错写入或在删除后重新写出过时的检查点。
+func runCheckpointer(ctx context.Context, runID string) {
+	ticker := time.NewTicker(AppConfig.Soak.CheckpointInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := saveSoakCheckpoint(AppConfig.Soak.CheckpointFile, currentCheckpoint(runID)); err != nil {
+				log.Printf(T("检查点: %v"), err)
+			}
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// 分阶段负载命令行参数
+var stagesFlag = flag.String("stages", "", "分阶段负载，如 warmup:100x1s,20k:300x50ms/20 (名称:循环数x发送间隔[/每条消息数据点数])，依次运行并分别统计各阶段(覆盖--cycles和--interval)")
+
+// loadStage 分阶段负载中的一个阶段
+type loadStage struct {
+	name     string
+	cycles   int
+	interval time.Duration
+	points   int // 每条消息的数据点数，0表示使用data_point_count
+	first    int // 阶段的第一个循环(从1开始)
+}
+
+// loadStages 解析后的阶段列表，为空时不分阶段
+var loadStages []loadStage
+
+// messagePoints 当前阶段每条消息的数据点数，0表示使用data_point_count
+var messagePoints atomic.Int64
+
+// stageResult 一个阶段开始和结束时的计数快照
+type stageResult struct {
+	stage   loadStage
+	start   StatsSnapshot
+	end     StatsSnapshot
+	latency *Histogram // 开始时的发布耗时快照，结束后替换为阶段内的分布
+}
+
+// stageResults 已开始的阶段，只在主循环中访问
+var stageResults []*stageResult
+
+// parseStages 解析"名称:循环数x发送间隔[/每条消息数据点数]"列表
+func parseStages(spec string) ([]loadStage, error) {
+	var stages []loadStage
+	first := 1
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		name, rest, ok := strings.Cut(item, ":")
+		cyclesStr, intervalStr, ok2 := strings.Cut(rest, "x")
+		intervalStr, pointsStr, hasPoints := strings.Cut(intervalStr, "/")
+		cycles, err1 := strconv.Atoi(cyclesStr)
+		interval, err2 := time.ParseDuration(intervalStr)
+		points, err3 := 0, error(nil)
+		if hasPoints {
+			if points, err3 = strconv.Atoi(pointsStr); err3 == nil && points <= 0 {
+				err3 = strconv.ErrRange
+			}
+		}
+		if !ok || !ok2 || name == "" || err1 != nil || err2 != nil || err3 != nil || cycles <= 0 || interval <= 0 {
+			return nil, fmt.Errorf(T("无效的阶段: %s (格式: 名称:循环数x发送间隔[/每条消息数据点数])"), item)
+		}
+		stages = append(stages, loadStage{name: name, cycles: cycles, interval: interval, points: points, first: first})
+		first += cycles
+	}
+	return stages, nil
+}
+
+// stageCycles 所有阶段的循环数之和
+func stageCycles(stages []loadStage) int {
+	total := 0
+	for _, s := range stages {
+		total += s.cycles
+	}
+	return total
+}
+
+// currentMessagePoints 返回当前阶段每条消息的数据点数
+func currentMessagePoints() int {
+	if n := messagePoints.Load(); n > 0 {
+		return int(n)
+	}
+	return AppConfig.Data.DataPointCount
+}
+
+// maxMessagePoints 返回各阶段中每条消息的最大数据点数
+func maxMessagePoints() int {
+	n := AppConfig.Data.DataPointCount
+	for _, s := range loadStages {
+		n = max(n, s.points)
+	}
+	return n
+}
+
+// beginStage 在循环开始前调用，到达新阶段的第一个循环时结束上一阶段、切换发送间隔和数据点数并记录起点
+//
+// 阶段边界以循环为准，上一循环仍在发布的消息计入下一阶段。各阶段沿用已建立的设备连接，
+// 切换阶段时不重连，阶段统计中不含连接开销。从检查点恢复时从所在阶段的中间开始，
+// 该阶段的统计只含恢复后的循环。
+func beginStage(cycle int) {
+	for _, stage := range loadStages {
+		if cycle == startCycle && cycle > stage.first && cycle < stage.first+stage.cycles {
+			stage.cycles -= cycle - stage.first
+			stage.first = cycle
+		}
+		if stage.first != cycle {
+			continue
+		}
+		endStage()
+		currentStageName.Store(&stage.name)
+		sendInterval.Store(int64(stage.interval))
+		messagePoints.Store(int64(stage.points))
+		stageResults = append(stageResults, &stageResult{
+			stage:   stage,
+			start:   runStats.Snapshot(),
+			latency: publishLatency.Snapshot(),
+		})
+		annotate("stage", fmt.Sprintf(T("阶段 %s 开始: %d 个循环, 发送间隔 %v, 每条消息 %d 个数据点"),
+			stage.name, stage.cycles, stage.interval, currentMessagePoints()))
+		return
+	}
+}
+
+// endStage 结束当前阶段(如有)，测试结束时也需调用
+func endStage() {
+	if len(stageResults) == 0 {
+		return
+	}
+	current := stageResults[len(stageResults)-1]
+	if !current.end.Time.IsZero() {
+		return
+	}
+	current.end = runStats.Snapshot()
+	current.latency = publishLatency.Snapshot().Sub(current.latency)
+}
+
+// telemetryCounts 快照中主遥测数据流的发送成功和失败数
+func telemetryCounts(snap StatsSnapshot) (sent, failed uint64) {
+	for labels, c := range snap.ByLabel {
+		if labels.Type == telemetryStream {
+			sent += c.Sent
+			failed += c.Failed
+		}
+	}
+	return sent, failed
+}
+
+// stageDBRate 阶段时间范围内监控样本的平均入库速率(点/秒)，没有样本时返回-1
+func stageDBRate(start, end time.Time) float64 {
+	throughputSamples.mu.Lock()
+	defer throughputSamples.mu.Unlock()
+	var total float64
+	var n int
+	for _, s := range throughputSamples.list {
+		if s.Time.After(start) && !s.Time.After(end) {
+			total += s.DBRate
+			n++
+		}
+	}
+	if n == 0 {
+		return -1
+	}
+	return total / float64(n)
+}
+
+// StageSummary 一个阶段的统计结果
+type StageSummary struct {
+	Name         string        `json:"name"`
+	FirstCycle   int           `json:"first_cycle"`
+	LastCycle    int           `json:"last_cycle"`
+	Cycles       int           `json:"cycles"`
+	StartedAt    time.Time     `json:"started_at"` // 阶段边界: 第一个循环开始的时间
+	EndedAt      time.Time     `json:"ended_at"`   // 阶段边界: 下一阶段开始或测试结束的时间
+	PointsPerMsg int           `json:"points_per_msg"`
+	Interval     time.Duration `json:"-"`
+	Duration     time.Duration `json:"-"`
+	IntervalMs   float64       `json:"interval_ms"`
+	DurationS    float64       `json:"duration_s"`
+	Msgs         uint64        `json:"msgs"`
+	Points       uint64        `json:"points"`
+	MsgsPerSec   float64       `json:"msgs_per_sec"`
+	PointsPerSec float64       `json:"points_per_sec"`
+	DBRate       float64       `json:"db_rate"` // 监控采样的平均入库速率(点/秒)，没有样本时为-1
+	P50Ms        float64       `json:"p50_ms"`
+	P95Ms        float64       `json:"p95_ms"`
+	P99Ms        float64       `json:"p99_ms"`
+	MaxMs        float64       `json:"max_ms"`
+	Failed       uint64        `json:"failed"`
+	SuccessRate  float64       `json:"success_rate"`      // 主遥测数据流发布成功率(%)
+	Latency      *Histogram    `json:"latency,omitempty"` // 发布耗时直方图，合并多个worker的汇总时使用
+}
+
+// stageSummaries 计算已开始的各阶段的统计结果
+func stageSummaries() []StageSummary {
+	endStage()
+	summaries := make([]StageSummary, 0, len(stageResults))
+	for _, r := range stageResults {
+		elapsed := r.end.Time.Sub(r.start.Time)
+		sentEnd, failedEnd := telemetryCounts(r.end)
+		sentStart, failedStart := telemetryCounts(r.start)
+		sent, failed := sentEnd-sentStart, failedEnd-failedStart
+		s := StageSummary{
+			Name:        r.stage.name,
+			FirstCycle:  r.stage.first,
+			LastCycle:   r.stage.first + r.stage.cycles - 1,
+			Cycles:      r.stage.cycles,
+			StartedAt:   r.start.Time,
+			EndedAt:     r.end.Time,
+			Interval:    r.stage.interval,
+			Duration:    elapsed,
+			Msgs:        r.end.Messages - r.start.Messages,
+			Points:      r.end.DataPoints - r.start.DataPoints,
+			DBRate:      stageDBRate(r.start.Time, r.end.Time),
+			P50Ms:       durationMs(r.latency.Quantile(0.50)),
+			P95Ms:       durationMs(r.latency.Quantile(0.95)),
+			P99Ms:       durationMs(r.latency.Quantile(0.99)),
+			MaxMs:       durationMs(r.latency.Max()),
+			Failed:      failed,
+			SuccessRate: percent(sent, sent+failed),
+			Latency:     r.latency,
+		}
+		s.IntervalMs, s.DurationS = durationMs(s.Interval), elapsed.Seconds()
+		s.PointsPerMsg = r.stage.points
+		if s.PointsPerMsg == 0 {
+			s.PointsPerMsg = AppConfig.Data.DataPointCount
+		}
+		s.MsgsPerSec = ratePerSecond(float64(s.Msgs), elapsed)
+		s.PointsPerSec = ratePerSecond(float64(s.Points), elapsed)
+		summaries = append(summaries, s)
+	}
+	return summaries
+}
+
+// reportStages 分别输出各阶段的吞吐量、发布耗时分位数和成功率(未分阶段时不输出)
+func reportStages() {
+	if len(stageResults) == 0 {
+		return
+	}
+	log.Println(T("\n========== 分阶段统计 =========="))
+	for _, s := range stageSummaries() {
+		log.Printf(T("阶段 %s (第 %d-%d 个循环, %s-%s, 发送间隔 %v, 每条消息 %d 个数据点, 耗时 %v):"),
+			s.Name, s.FirstCycle, s.LastCycle, s.StartedAt.Format("15:04:05"), s.EndedAt.Format("15:04:05"),
+			s.Interval, s.PointsPerMsg, s.Duration.Round(time.Second))
+		log.Printf(T("  - 吞吐量: %.1f 条/秒, %.1f 点/秒 (消息 %d, 数据点 %d)"),
+			s.MsgsPerSec, s.PointsPerSec, s.Msgs, s.Points)
+		if s.DBRate >= 0 {
+			log.Printf(T("  - 入库速率(监控采样平均): %.1f 点/秒"), s.DBRate)
+		}
+		log.Printf(T("  - 发布耗时: p50=%.1fms, p95=%.1fms, p99=%.1fms, 最大=%.1fms"), s.P50Ms, s.P95Ms, s.P99Ms, s.MaxMs)
+		log.Printf(T("  - 成功率: %.2f%% (失败 %d)"), s.SuccessRate, s.Failed)
+	}
+	log.Println("===============================")
+}
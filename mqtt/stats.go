@@ -0,0 +1,156 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Counter 单调递增计数器，只能通过Stats的Record方法修改
+type Counter struct {
+	v atomic.Uint64
+}
+
+// Load 读取当前值(单个计数器的实时值，不保证与其他计数器一致)
+func (c *Counter) Load() uint64 {
+	return c.v.Load()
+}
+
+// StatsLabels 发布计数的维度
+type StatsLabels struct {
+	Type   string // 消息类型(数据流名称，主遥测数据流为telemetry)
+	QoS    byte   // 发布使用的QoS
+	Broker string // 实际连接的Broker节点地址
+}
+
+// labeledCounters 一组维度上的发布计数
+type labeledCounters struct {
+	sent   Counter
+	failed Counter
+}
+
+// LabeledCounts 快照中一组维度上的发布计数
+type LabeledCounts struct {
+	Sent   uint64
+	Failed uint64
+}
+
+// Stats 测试运行计数
+//
+// 计数器在热路径上原子递增。记录方共享读锁(彼此不阻塞)，Snapshot持有写锁，
+// 因此快照中的各计数相互一致，不会出现消息数已增加而数据点数尚未增加的中间状态。
+// 监控、结果汇总和检查点都通过Snapshot读取，保证口径一致。
+type Stats struct {
+	mu sync.RWMutex
+
+	Connected     Counter // 成功连接(含订阅)的设备数
+	ConnectFailed Counter // 连接失败的设备数
+	Exited        Counter // 已退出的设备goroutine数
+	Messages      Counter // 发送成功的遥测消息数
+	DataPoints    Counter // 发送成功的数据点数
+	PublishFailed Counter // 最终发布失败的消息数(含额外数据流)
+	Cycles        Counter // 已完成的循环次数
+	Received      Counter // 收到的下行消息数
+
+	labeled sync.Map // StatsLabels → *labeledCounters
+}
+
+// StatsSnapshot Stats在某一时刻的一致快照
+type StatsSnapshot struct {
+	Time          time.Time
+	Connected     uint64
+	ConnectFailed uint64
+	Exited        uint64
+	Messages      uint64
+	DataPoints    uint64
+	PublishFailed uint64
+	Cycles        uint64
+	Received      uint64
+	ByLabel       map[StatsLabels]LabeledCounts
+}
+
+// runStats 全局运行计数
+var runStats Stats
+
+// add 在读锁下递增计数器
+func (s *Stats) add(c *Counter, n uint64) {
+	s.mu.RLock()
+	c.v.Add(n)
+	s.mu.RUnlock()
+}
+
+// RecordConnected 设备连接(及订阅)成功
+func (s *Stats) RecordConnected() { s.add(&s.Connected, 1) }
+
+// RecordConnectFailure 设备连接失败
+func (s *Stats) RecordConnectFailure() { s.add(&s.ConnectFailed, 1) }
+
+// RecordExit 设备goroutine退出
+func (s *Stats) RecordExit() { s.add(&s.Exited, 1) }
+
+// RecordCycle 完成一个循环
+func (s *Stats) RecordCycle() { s.add(&s.Cycles, 1) }
+
+// RecordReceived 收到一条下行消息
+func (s *Stats) RecordReceived() { s.add(&s.Received, 1) }
+
+// RecordPublish 记录一次发布的最终结果，只有主遥测数据流计入消息数和数据点数，
+// broker为设备实际连接的节点地址(见clientBroker)
+func (s *Stats) RecordPublish(msgType string, qos byte, broker string, points int, err error) {
+	labels := StatsLabels{Type: msgType, QoS: qos, Broker: broker}
+	l, ok := s.labeled.Load(labels)
+	if !ok {
+		l, _ = s.labeled.LoadOrStore(labels, &labeledCounters{})
+	}
+	counters := l.(*labeledCounters)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if err != nil {
+		counters.failed.v.Add(1)
+		s.PublishFailed.v.Add(1)
+		return
+	}
+	counters.sent.v.Add(1)
+	if msgType == telemetryStream {
+		s.Messages.v.Add(1)
+		s.DataPoints.v.Add(uint64(points))
+	}
+}
+
+// Snapshot 返回所有计数的一致快照
+func (s *Stats) Snapshot() StatsSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snap := StatsSnapshot{
+		Time:          time.Now(),
+		Connected:     s.Connected.Load(),
+		ConnectFailed: s.ConnectFailed.Load(),
+		Exited:        s.Exited.Load(),
+		Messages:      s.Messages.Load(),
+		DataPoints:    s.DataPoints.Load(),
+		PublishFailed: s.PublishFailed.Load(),
+		Cycles:        s.Cycles.Load(),
+		Received:      s.Received.Load(),
+		ByLabel:       make(map[StatsLabels]LabeledCounts),
+	}
+	s.labeled.Range(func(key, value any) bool {
+		c := value.(*labeledCounters)
+		snap.ByLabel[key.(StatsLabels)] = LabeledCounts{Sent: c.sent.Load(), Failed: c.failed.Load()}
+		return true
+	})
+	return snap
+}
+
+// SentByType 按消息类型汇总发送成功的消息数，只有主遥测数据流时返回nil
+func (snap StatsSnapshot) SentByType() map[string]uint64 {
+	counts := make(map[string]uint64)
+	for labels, c := range snap.ByLabel {
+		counts[labels.Type] += c.Sent
+	}
+	if len(counts) < 2 {
+		return nil
+	}
+	return counts
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+)
+
+// StreamConfig 设备在每个周期额外发布的数据流(如属性、事件上报)
+type StreamConfig struct {
+	Name    string `yaml:"name"`    // 数据流名称，用于分别计数(默认为主题)
+	Topic   string `yaml:"topic"`   // 发布主题，{username}替换为设备token
+	Payload string `yaml:"payload"` // 消息模板，支持{username}、{ts}、{seq}、{value}占位符
+}
+
+// telemetryStream 主遥测数据流(mqtt.topic + 传感器数据)的名称
+const telemetryStream = "telemetry"
+
+// StreamStats 单个数据流的发布统计
+type StreamStats struct {
+	Name     string
+	Topic    string
+	Sent     uint64    // 发送成功的消息数
+	Failures uint64    // 发送失败的消息数
+	Latency  Histogram // 发布耗时
+}
+
+// streamStats 各数据流的统计，第0项为主遥测数据流
+var streamStats []*StreamStats
+
+// initStreams 校验额外数据流配置并初始化统计
+func initStreams() {
+	streamStats = []*StreamStats{{Name: telemetryStream, Topic: AppConfig.MQTT.Topic}}
+	seen := map[string]bool{telemetryStream: true}
+	for i := range AppConfig.Streams {
+		s := &AppConfig.Streams[i]
+		if s.Topic == "" {
+			log.Fatalf(T("数据流 %d 未配置主题"), i+1)
+		}
+		if s.Payload == "" {
+			log.Fatalf(T("数据流 %s 未配置消息模板"), s.Topic)
+		}
+		if s.Name == "" {
+			s.Name = s.Topic
+		}
+		if seen[s.Name] {
+			log.Fatalf(T("数据流名称重复: %s"), s.Name)
+		}
+		seen[s.Name] = true
+		streamStats = append(streamStats, &StreamStats{Name: s.Name, Topic: s.Topic})
+	}
+}
+
+// 消息模板中的占位符
+const (
+	slotLiteral = iota
+	slotTimestamp
+	slotSeq
+	slotValue
+)
+
+// templateSegment 消息模板片段：固定文本或动态占位符
+type templateSegment struct {
+	kind int
+	text string
+}
+
+// streamPublisher 设备上的一个额外数据流，消息缓冲区在周期间复用
+type streamPublisher struct {
+	stats    *StreamStats
+	topic    string
+	segments []templateSegment
+	seq      uint64
+	buf      []byte
+}
+
+// newStreamPublishers 为设备创建全部额外数据流，{username}在创建时直接替换
+func newStreamPublishers(username string) []*streamPublisher {
+	publishers := make([]*streamPublisher, len(AppConfig.Streams))
+	for i, s := range AppConfig.Streams {
+		publishers[i] = &streamPublisher{
+			stats:    streamStats[i+1],
+			topic:    strings.ReplaceAll(s.Topic, "{username}", username),
+			segments: parseStreamTemplate(strings.ReplaceAll(s.Payload, "{username}", username)),
+		}
+	}
+	return publishers
+}
+
+// parseStreamTemplate 将模板拆分为固定文本和占位符片段
+func parseStreamTemplate(payload string) []templateSegment {
+	placeholders := map[string]int{"{ts}": slotTimestamp, "{seq}": slotSeq, "{value}": slotValue}
+
+	var segments []templateSegment
+	for payload != "" {
+		start := strings.IndexByte(payload, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(payload[start:], '}')
+		if end < 0 {
+			break
+		}
+		kind, ok := placeholders[payload[start:start+end+1]]
+		if !ok {
+			// 不是占位符(如JSON对象的括号)，按固定文本保留
+			segments = append(segments, templateSegment{kind: slotLiteral, text: payload[:start+1]})
+			payload = payload[start+1:]
+			continue
+		}
+		if start > 0 {
+			segments = append(segments, templateSegment{kind: slotLiteral, text: payload[:start]})
+		}
+		segments = append(segments, templateSegment{kind: kind})
+		payload = payload[start+end+1:]
+	}
+	if payload != "" {
+		segments = append(segments, templateSegment{kind: slotLiteral, text: payload})
+	}
+	return segments
+}
+
+// Next 生成下一条消息，返回的切片在下次调用前有效
+func (p *streamPublisher) Next(faker *gofakeit.Faker) []byte {
+	p.seq++
+	p.buf = p.buf[:0]
+	for _, seg := range p.segments {
+		switch seg.kind {
+		case slotLiteral:
+			p.buf = append(p.buf, seg.text...)
+		case slotTimestamp:
+			p.buf = strconv.AppendInt(p.buf, time.Now().UnixMilli(), 10)
+		case slotSeq:
+			p.buf = strconv.AppendUint(p.buf, p.seq, 10)
+		case slotValue:
+			// 配置范围内的随机数不会是NaN或Inf
+			p.buf, _ = appendJSONFloat(p.buf, faker.Float64Range(AppConfig.Data.MinValue, AppConfig.Data.MaxValue))
+		}
+	}
+	return p.buf
+}
+
+// record 记录一次发布结果
+func (s *StreamStats) record(latency time.Duration, err error) {
+	if err != nil {
+		atomic.AddUint64(&s.Failures, 1)
+		return
+	}
+	atomic.AddUint64(&s.Sent, 1)
+	s.Latency.Record(latency)
+}
+
+// reportStreams 按数据流输出发布统计(只有主遥测数据流时不输出)
+func reportStreams() {
+	if len(streamStats) < 2 {
+		return
+	}
+	log.Println(T("\n========== 数据流统计 =========="))
+	for _, s := range streamStats {
+		log.Printf(T("%s (%s): 成功=%d, 失败=%d, p50=%v, p99=%v"),
+			s.Name, s.Topic, atomic.LoadUint64(&s.Sent), atomic.LoadUint64(&s.Failures),
+			s.Latency.Quantile(0.50), s.Latency.Quantile(0.99))
+	}
+	log.Println("===============================")
+}
+
+// streamSummary 配置中额外数据流的简要描述
+func streamSummary() string {
+	if len(AppConfig.Streams) == 0 {
+		return "-"
+	}
+	names := make([]string, len(AppConfig.Streams))
+	for i, s := range AppConfig.Streams {
+		names[i] = s.Topic
+		if s.Name != s.Topic {
+			names[i] = fmt.Sprintf("%s=%s", s.Name, s.Topic)
+		}
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+)
+
+// 字符串数据点命令行参数
+var (
+	stringFieldCount = flag.Int("string-fields", 0, "每条消息附加的字符串数据点数量，用于测试string_v存储、JSON转义和索引，0为不附加")
+	stringKinds      = flag.String("string-kinds", "", "字符串内容类型，逗号分隔(ascii, cjk, emoji, escape, mixed)，各数据点轮流使用(默认: 全部)")
+)
+
+// stringKindNames 支持的字符串内容类型
+var stringKindNames = []string{"ascii", "cjk", "emoji", "escape", "mixed"}
+
+// stringEmoji 包含多码点组合(ZWJ序列、国旗、肤色修饰)的emoji，拆分或截断时容易产生无效的UTF-8
+var stringEmoji = []string{"😀", "🚀", "🔥", "👍🏽", "👨‍👩‍👧‍👦", "🏳️‍🌈", "🇨🇳", "🇺🇸", "❤️", "🧑‍💻"}
+
+// stringEscapes 需要JSON转义或容易被错误处理的字符
+var stringEscapes = []string{`"`, `\`, "/", "\n", "\r", "\t", "\b", "\f", "\x00", "\x1f", "\u2028", "\u2029", "\ufeff", "</script>", "'", "%s", "${x}"}
+
+// stringKindStats 单类字符串数据点的发送统计
+type stringKindStats struct {
+	sent   atomic.Uint64 // 发送成功的数据点数
+	maxLen atomic.Int64  // 发送成功的最大长度(字符数)
+}
+
+// stringStats 字符串数据点统计
+var stringStats struct {
+	kinds    map[string]*stringKindStats
+	messages atomic.Uint64 // 附加了字符串数据点并发送成功的消息数
+	failed   atomic.Uint64 // 附加了字符串数据点但发送失败的消息数
+
+	mu       sync.Mutex
+	rejected map[byte]uint64 // 按PUBACK原因码统计被服务器拒绝的消息数
+
+	rejectedPoints uint64 // 发送成功但未入库的字符串数据点数，供JSON汇总使用
+	truncated      int    // 入库的最大长度小于发送的最大长度的类型数，供JSON汇总使用
+}
+
+// stringsEnabled 是否附加字符串数据点
+func stringsEnabled() bool {
+	return AppConfig.Strings.Count > 0
+}
+
+// initStrings 解析字符串内容类型并初始化统计
+func initStrings() error {
+	if !stringsEnabled() {
+		return nil
+	}
+	stringStats.kinds = make(map[string]*stringKindStats)
+	stringStats.rejected = make(map[byte]uint64)
+	for _, kind := range stringFieldKinds() {
+		if !slices.Contains(stringKindNames, kind) {
+			return fmt.Errorf(T("不支持的字符串内容类型: %s (可选: %s)"), kind, strings.Join(stringKindNames, ", "))
+		}
+		stringStats.kinds[kind] = &stringKindStats{}
+	}
+	return nil
+}
+
+// stringFieldKinds 配置的字符串内容类型列表(去重)
+func stringFieldKinds() []string {
+	var kinds []string
+	for _, kind := range strings.Split(AppConfig.Strings.Kinds, ",") {
+		if kind = strings.TrimSpace(kind); kind != "" && !slices.Contains(kinds, kind) {
+			kinds = append(kinds, kind)
+		}
+	}
+	return kinds
+}
+
+// stringKeys 字符串数据点的名称，如 str1_cjk，名称中包含内容类型便于在平台上按类型查询
+func stringKeys() []string {
+	kinds := stringFieldKinds()
+	keys := make([]string, AppConfig.Strings.Count)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("str%d_%s", i+1, kinds[i%len(kinds)])
+	}
+	return keys
+}
+
+// stringFields 设备附加到每条消息的字符串数据点
+type stringFields struct {
+	keys    [][]byte // 预编码的 "key": 前缀
+	kinds   []string
+	lengths []int // 本条消息各数据点的长度(字符数)
+	buf     []byte
+	faker   *gofakeit.Faker
+}
+
+// newStringFields 创建设备的字符串数据点生成器，未启用时返回nil
+func newStringFields(faker *gofakeit.Faker) *stringFields {
+	if !stringsEnabled() {
+		return nil
+	}
+	kinds := stringFieldKinds()
+	s := &stringFields{faker: faker, lengths: make([]int, AppConfig.Strings.Count)}
+	for i, key := range stringKeys() {
+		quoted, _ := json.Marshal(key)
+		s.keys = append(s.keys, append(quoted, ':'))
+		s.kinds = append(s.kinds, kinds[i%len(kinds)])
+	}
+	return s
+}
+
+// length 选择字符串长度，配置了平台长度上限时一半的数据点取上限附近的长度
+func (s *stringFields) length() int {
+	cfg := AppConfig.Strings
+	if cfg.LimitLength > 0 && s.faker.IntN(2) == 0 {
+		return max(cfg.LimitLength-1+s.faker.IntN(3), 1)
+	}
+	return cfg.MinLength + s.faker.IntN(cfg.MaxLength-cfg.MinLength+1)
+}
+
+// text 生成指定类型和长度(字符数)的字符串
+func (s *stringFields) text(kind string, n int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		k := kind
+		if k == "mixed" {
+			k = stringKindNames[s.faker.IntN(len(stringKindNames)-1)]
+		}
+		switch k {
+		case "cjk":
+			b.WriteRune(rune(0x4e00 + s.faker.IntN(0x9fa5-0x4e00+1)))
+		case "emoji":
+			b.WriteString(stringEmoji[s.faker.IntN(len(stringEmoji))])
+		case "escape":
+			b.WriteString(stringEscapes[s.faker.IntN(len(stringEscapes))])
+		default:
+			b.WriteByte(byte('a' + s.faker.IntN(26)))
+		}
+	}
+	return b.String()
+}
+
+// append 在消息末尾附加字符串数据点，返回新消息和增加的数据点数，nil表示未启用
+//
+// 长度按字符(emoji和转义序列按一个单位)计，存储时的字节数和码点数可能更多。
+func (s *stringFields) append(payload []byte) ([]byte, int) {
+	if s == nil || len(payload) < 2 || payload[len(payload)-1] != '}' {
+		return payload, 0
+	}
+	s.buf = append(s.buf[:0], payload[:len(payload)-1]...)
+	for i, key := range s.keys {
+		if len(s.buf) > 1 {
+			s.buf = append(s.buf, ',')
+		}
+		s.lengths[i] = s.length()
+		value, _ := json.Marshal(s.text(s.kinds[i], s.lengths[i]))
+		s.buf = append(s.buf, key...)
+		s.buf = append(s.buf, value...)
+	}
+	s.buf = append(s.buf, '}')
+	return s.buf, len(s.keys)
+}
+
+// record 记录本条消息的发送结果，nil表示未启用
+func (s *stringFields) record(err error) {
+	if s == nil {
+		return
+	}
+	if err != nil {
+		stringStats.failed.Add(1)
+		var rejected *publishRejected
+		if errors.As(err, &rejected) {
+			stringStats.mu.Lock()
+			stringStats.rejected[rejected.code]++
+			stringStats.mu.Unlock()
+		}
+		return
+	}
+	stringStats.messages.Add(1)
+	for i, kind := range s.kinds {
+		k := stringStats.kinds[kind]
+		k.sent.Add(1)
+		for n := int64(s.lengths[i]); ; {
+			cur := k.maxLen.Load()
+			if n <= cur || k.maxLen.CompareAndSwap(cur, n) {
+				break
+			}
+		}
+	}
+}
+
+// storedStrings 查询各字符串数据点自since起入库的条数和最大长度(字符数)
+func storedStrings(ctx context.Context, db *sql.DB, since time.Time) (map[string][2]int64, error) {
+	rows, err := db.QueryContext(ctx, `SELECT key, COUNT(*), COALESCE(MAX(char_length(string_v)), 0) FROM telemetry_datas
+		WHERE ts >= $1 AND key = ANY($2) GROUP BY key`, since.Add(-dbClockSkew).UnixMilli(), stringKeys())
+	if err != nil {
+		return nil, fmt.Errorf(T("查询字符串数据点失败: %w"), err)
+	}
+	defer rows.Close()
+	stored := make(map[string][2]int64)
+	for rows.Next() {
+		var key string
+		var count, maxLen int64
+		if err := rows.Scan(&key, &count, &maxLen); err != nil {
+			return nil, fmt.Errorf(T("查询字符串数据点失败: %w"), err)
+		}
+		kind := key[strings.IndexByte(key, '_')+1:]
+		s := stored[kind]
+		stored[kind] = [2]int64{s[0] + count, max(s[1], maxLen)}
+	}
+	return stored, rows.Err()
+}
+
+// reportStrings 按内容类型核对字符串数据点的入库条数和最大长度(未启用时不输出)
+//
+// 入库条数少于发送条数说明平台拒绝或丢弃了这类字符串；入库的最大长度小于发送的最大长度
+// 说明被截断。emoji按一个单位计长度，入库长度按码点计，可能大于发送长度。
+func reportStrings(start time.Time) {
+	if !stringsEnabled() {
+		return
+	}
+	log.Println(T("\n========== 字符串数据点 =========="))
+	defer log.Println("===============================")
+	log.Printf(T("附加字符串的消息: 成功 %d 条, 失败 %d 条"), stringStats.messages.Load(), stringStats.failed.Load())
+	stringStats.mu.Lock()
+	for code, n := range stringStats.rejected {
+		log.Printf(T("  服务器拒绝(原因码 0x%02x): %d 条"), code, n)
+	}
+	stringStats.mu.Unlock()
+
+	db, err := openReadDatabase()
+	if err != nil {
+		log.Printf(T("字符串数据点: %v"), err)
+		return
+	}
+	defer db.Close()
+	ingestSettle()
+	waitReplicaReplay(db)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	stored, err := storedStrings(ctx, db, start)
+	if err != nil {
+		log.Printf(T("字符串数据点: %v"), err)
+		return
+	}
+	for _, kind := range stringFieldKinds() {
+		k := stringStats.kinds[kind]
+		sent, got := k.sent.Load(), stored[kind]
+		line := fmt.Sprintf(T("%s: 发送 %d, 入库 %d, 最大长度 发送 %d / 入库 %d"), kind, sent, got[0], k.maxLen.Load(), got[1])
+		if got[0] < int64(sent) {
+			stringStats.rejectedPoints += sent - uint64(got[0])
+			line += fmt.Sprintf(T(", 未入库 %d"), sent-uint64(got[0]))
+		}
+		if got[0] > 0 && got[1] < k.maxLen.Load() {
+			stringStats.truncated++
+			line += T(", 可能被截断")
+		}
+		log.Println(line)
+	}
+}
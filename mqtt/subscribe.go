@@ -0,0 +1,118 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MQTT SUBACK中表示订阅失败的返回码
+const subackFailure = 0x80
+
+// 订阅相关命令行参数
+var (
+	subscribeTopicsFlag = flag.String("subscribe", "", "设备订阅的主题，逗号分隔，{username}替换为设备token")
+	subscribeQoS        = flag.Int("subscribe-qos", -1, "订阅的服务质量(0,1,2)")
+)
+
+// 订阅统计
+var (
+	subscribeLatency  Histogram // SUBSCRIBE→SUBACK耗时
+	subscribeGranted  uint64    // 被接受的订阅数
+	subscribeRejected uint64    // 被拒绝的订阅数
+	subscribeErrors   uint64    // 订阅请求失败(超时、断开等)次数
+
+	subackCodesMu sync.Mutex
+	subackCodes   = make(map[byte]uint64) // 按返回码统计
+	rejectSamples []string                // 被拒绝订阅的示例(设备:主题)
+)
+
+// 最多保留的拒绝示例数量
+const maxRejectSamples = 5
+
+// subscribeTopics 订阅配置的全部主题，任何订阅被拒绝时返回错误
+func subscribeTopics(client DeviceClient, username string, stats *DeviceStats) error {
+	// HTTP设备和本地接收端没有下行通道
+	switch client.(type) {
+	case *httpClient, *sinkClient:
+		return nil
+	}
+	for _, pattern := range AppConfig.Subscribe.Topics {
+		topic := strings.ReplaceAll(pattern, "{username}", username)
+
+		start := time.Now()
+		code, err := client.Subscribe(topic, byte(AppConfig.Subscribe.QoS))
+		latency := time.Since(start)
+		if err != nil {
+			atomic.AddUint64(&subscribeErrors, 1)
+			stats.recordSubscribe(latency, subackFailure)
+			return fmt.Errorf(T("订阅主题 %s 失败: %w"), topic, err)
+		}
+
+		subscribeLatency.Record(latency)
+		stats.recordSubscribe(latency, code)
+
+		subackCodesMu.Lock()
+		subackCodes[code]++
+		if code == subackFailure && len(rejectSamples) < maxRejectSamples {
+			rejectSamples = append(rejectSamples, username+": "+topic)
+		}
+		subackCodesMu.Unlock()
+
+		if code == subackFailure {
+			atomic.AddUint64(&subscribeRejected, 1)
+			return fmt.Errorf(T("服务器拒绝订阅主题 %s (SUBACK返回码 0x80)"), topic)
+		}
+		atomic.AddUint64(&subscribeGranted, 1)
+	}
+	return nil
+}
+
+// onMessageReceived 收到下行消息时调用，平台下发的属性设置和命令由设备回复
+func onMessageReceived(client DeviceClient, topic string, payload []byte) {
+	runStats.RecordReceived()
+	if !handleAttributeSet(client, topic, payload) {
+		handleCommand(client, topic, payload)
+	}
+}
+
+// reportSubscriptions 输出订阅统计，存在被拒绝的订阅时返回false
+func reportSubscriptions() bool {
+	if len(AppConfig.Subscribe.Topics) == 0 {
+		return true
+	}
+
+	granted := atomic.LoadUint64(&subscribeGranted)
+	rejected := atomic.LoadUint64(&subscribeRejected)
+	errors := atomic.LoadUint64(&subscribeErrors)
+
+	log.Printf(T("\n========== 订阅统计(SUBSCRIBE→SUBACK) =========="))
+	log.Printf(T("订阅主题: %s, QoS: %d"), strings.Join(AppConfig.Subscribe.Topics, ", "), AppConfig.Subscribe.QoS)
+	log.Printf(T("成功: %d, 被拒绝: %d, 请求失败: %d"), granted, rejected, errors)
+	if subscribeLatency.Count() > 0 {
+		log.Printf(T("耗时: 平均 %v, p50 %v, p99 %v, 最大 %v"),
+			subscribeLatency.Mean(), subscribeLatency.Quantile(0.50),
+			subscribeLatency.Quantile(0.99), subscribeLatency.Max())
+	}
+
+	subackCodesMu.Lock()
+	for code, count := range subackCodes {
+		log.Printf(T("  - 返回码 0x%02x: %d"), code, count)
+	}
+	samples := append([]string(nil), rejectSamples...)
+	subackCodesMu.Unlock()
+	log.Printf("==============================")
+
+	if rejected > 0 {
+		log.Printf(T("错误: 服务器拒绝了 %d 个订阅，请检查Broker的ACL配置是否允许设备订阅这些主题，示例:"), rejected)
+		for _, sample := range samples {
+			log.Printf("  - %s", sample)
+		}
+		return false
+	}
+	return true
+}
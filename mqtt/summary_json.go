@@ -0,0 +1,353 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// 单行JSON汇总命令行参数
+var jsonSummary = flag.Bool("json-summary", true, "测试结束时向标准输出打印单行JSON汇总(日志输出到标准错误)")
+
+// jsonSummaryLine 单行JSON汇总的字段，便于shell管道和jq提取
+type jsonSummaryLine struct {
+	RunID        string  `json:"run_id"`
+	Workers      int     `json:"workers,omitempty"` // merge子命令合并的worker数，单次运行时不输出
+	DurationS    float64 `json:"duration_s"`
+	Clients      int     `json:"clients"`
+	Connected    uint64  `json:"connected"`
+	Cycles       int     `json:"cycles"`
+	Msgs         uint64  `json:"msgs"`
+	Points       uint64  `json:"points"`
+	MsgsPerSec   float64 `json:"msgs_per_sec"`
+	PointsPerSec float64 `json:"points_per_sec"`
+	P50Ms        float64 `json:"p50_ms"`
+	P99Ms        float64 `json:"p99_ms"`
+	ConnectP99Ms float64 `json:"connect_p99_ms"`
+	DBWritten    int64   `json:"db_written"`
+	WriteRate    float64 `json:"write_rate"` // 入库率(%)，总入库/总发送
+	Received     uint64  `json:"received"`
+	Interrupted  bool    `json:"interrupted"`
+	Aborted      string  `json:"aborted,omitempty"` // 超出失败预算时的终止原因
+	AuthPrimed   bool    `json:"auth_primed"`       // 是否预热了认证缓存(连接耗时为热认证结果)
+
+	CorrectedP99Ms  float64 `json:"corrected_p99_ms"`  // 从计划发送时间算起的p99耗时(协调遗漏修正)
+	BehindScheduleS float64 `json:"behind_schedule_s"` // 主循环累计晚于计划触发的时间
+
+	SustainablePointsPerSec float64 `json:"sustainable_points_per_sec,omitempty"` // 自适应速率找到的可持续吞吐量
+
+	Streams          map[string]uint64 `json:"streams,omitempty"`           // 各数据流发送成功的消息数
+	HeartbeatDevices uint64            `json:"heartbeat_devices,omitempty"` // 只发送心跳的设备数
+	ConsumerReceived uint64            `json:"consumer_received,omitempty"` // 平台消费者收到的消息数
+
+	Annotations []Annotation `json:"annotations,omitempty"` // 事件时间线标注
+
+	PublishFailed  uint64 `json:"publish_failed"`            // 最终发布失败的消息数
+	PublishRetried uint64 `json:"publish_retried,omitempty"` // 重试后发布成功的消息数
+
+	ErrorsSuppressed uint64 `json:"errors_suppressed,omitempty"` // 聚合后未单独输出的重复错误日志条数
+
+	ConnectionsLost    uint64 `json:"connections_lost,omitempty"`    // 设备连接断开次数
+	ReconnectAttempts  uint64 `json:"reconnect_attempts,omitempty"`  // 重连尝试次数
+	ReconnectSucceeded uint64 `json:"reconnect_succeeded,omitempty"` // 重连成功次数
+	ReconnectFailed    uint64 `json:"reconnect_failed,omitempty"`    // 重连失败次数
+
+	DBProbeP99Ms float64 `json:"db_probe_p99_ms,omitempty"` // 数据库写入探测耗时p99
+
+	RampDownS          float64 `json:"ramp_down_s,omitempty"`              // 逐步断开全部设备用时
+	RampDownOfflineS   float64 `json:"ramp_down_offline_s,omitempty"`      // 最后一个设备断开后抽样设备全部被标记离线的用时
+	RampDownOfflineP99 float64 `json:"ramp_down_offline_p99_ms,omitempty"` // 单设备从断开到被标记离线的p99
+	RampDownFlushS     float64 `json:"ramp_down_flush_s,omitempty"`        // 最后一个设备断开后入库数不再增长的用时
+
+	BrokerNodes []BrokerNodeSummary `json:"broker_nodes,omitempty"` // 按实际连接地址统计的Broker节点分布
+
+	OfflineBuffered   uint64  `json:"offline_buffered,omitempty"`     // 离线缓存场景中缓存的消息数
+	OfflineFlushed    uint64  `json:"offline_flushed,omitempty"`      // 恢复在线后补发成功的消息数
+	OfflineFlushP99Ms float64 `json:"offline_flush_p99_ms,omitempty"` // 单设备补发全部缓存耗时的p99
+
+	CredRotated          int     `json:"cred_rotated,omitempty"`             // 凭证轮换场景中更换凭证的设备数
+	CredRotationNewP99Ms float64 `json:"cred_rotation_new_p99_ms,omitempty"` // 更换到新凭证首次连接成功的耗时p99
+	CredRotationOldP99Ms float64 `json:"cred_rotation_old_p99_ms,omitempty"` // 更换到旧凭证首次被拒绝的耗时p99
+	CredRotationOldValid int     `json:"cred_rotation_old_valid,omitempty"`  // 探测超时时旧凭证仍可连接的设备数
+
+	DevicesDeleted      int     `json:"devices_deleted,omitempty"`       // 设备删除场景中删除的设备数
+	DeleteAuthP99Ms     float64 `json:"delete_auth_p99_ms,omitempty"`    // 删除到凭证首次被拒绝的耗时p99
+	DeleteAuthValid     int     `json:"delete_auth_valid,omitempty"`     // 探测超时时凭证仍可连接的已删除设备数
+	DeleteAcceptedAfter uint64  `json:"delete_accepted_after,omitempty"` // 删除后仍发布成功的消息数
+	DeleteStoredAfter   int64   `json:"delete_stored_after,omitempty"`   // 删除后入库的数据点数
+
+	DevicesAdded          uint64  `json:"devices_added,omitempty"`            // 设备接入场景中测试中途接入的设备数
+	DevicesAddFailed      uint64  `json:"devices_add_failed,omitempty"`       // 接入的设备中连接失败的设备数
+	DeviceOnboardingP99Ms float64 `json:"device_onboarding_p99_ms,omitempty"` // 发现新设备到首次发布成功的耗时p99
+
+	ProvisionRequests uint64  `json:"provision_requests,omitempty"` // 动态注册模式下的注册请求数
+	ProvisionFailed   uint64  `json:"provision_failed,omitempty"`   // 失败或响应中没有凭证的注册请求数
+	ProvisionP99Ms    float64 `json:"provision_p99_ms,omitempty"`   // 注册请求耗时p99
+
+	CurrentChecked  int `json:"current_checked,omitempty"`  // 当前值核对的数据点数
+	CurrentMismatch int `json:"current_mismatch,omitempty"` // 平台当前值与最后发送的值不一致(含缺失)的数据点数
+
+	ChecksumChecked   int `json:"checksum_checked,omitempty"`    // 校验和核对的数据点数
+	ChecksumCountDiff int `json:"checksum_count_diff,omitempty"` // 入库条数与发送条数不同的数据点数
+	ChecksumCorrupt   int `json:"checksum_corrupt,omitempty"`    // 条数相同但数值之和不同的数据点数
+
+	TenantLimit          string `json:"tenant_limit,omitempty"`           // 租户限额验证结论(enforced/not_enforced/inconclusive)
+	TenantOthersDegraded bool   `json:"tenant_others_degraded,omitempty"` // 其他租户的写入率是否低于阈值
+
+	StringsRejected  uint64 `json:"strings_rejected,omitempty"`  // 发送成功但未入库的字符串数据点数
+	StringsTruncated int    `json:"strings_truncated,omitempty"` // 入库的最大长度小于发送的最大长度的字符串类型数
+
+	AnomalyEvents     int     `json:"anomaly_events,omitempty"`       // 实际进行的异常注入次数
+	AnomalyDetected   int     `json:"anomaly_detected,omitempty"`     // 检测到告警的注入次数
+	AlarmLatencyP50Ms float64 `json:"alarm_latency_p50_ms,omitempty"` // 告警检测延迟p50
+	AlarmLatencyP99Ms float64 `json:"alarm_latency_p99_ms,omitempty"` // 告警检测延迟p99
+
+	HTTPSent          uint64 `json:"http_sent,omitempty"`          // HTTP设备发送成功的消息数
+	HTTPFailed        uint64 `json:"http_failed,omitempty"`        // HTTP设备发送失败的消息数
+	DashboardReceived uint64 `json:"dashboard_received,omitempty"` // WebSocket看板收到的推送数
+
+	DashboardLatencyP50Ms float64 `json:"dashboard_latency_p50_ms,omitempty"` // 设备发布到看板收到推送的耗时p50
+	DashboardLatencyP99Ms float64 `json:"dashboard_latency_p99_ms,omitempty"` // 设备发布到看板收到推送的耗时p99
+	DashboardLatencyLost  uint64  `json:"dashboard_latency_lost,omitempty"`   // 看板没有收到的带序号推送数
+
+	AttrSetSent      uint64  `json:"attr_set_sent,omitempty"`       // 通过平台API下发的属性设置请求数
+	AttrSetAPIFailed uint64  `json:"attr_set_api_failed,omitempty"` // API返回失败的请求数
+	AttrSetLost      uint64  `json:"attr_set_lost,omitempty"`       // 设备没有收到的请求数
+	AttrSetAPIP99Ms  float64 `json:"attr_set_api_p99_ms,omitempty"` // API耗时p99
+	AttrSetAckP99Ms  float64 `json:"attr_set_ack_p99_ms,omitempty"` // 下发到设备回复的耗时p99
+
+	CommandSent       uint64  `json:"command_sent,omitempty"`        // 通过平台API下发的命令数
+	CommandSucceeded  uint64  `json:"command_succeeded,omitempty"`   // 接口成功且设备在超时时间内回复的命令数
+	CommandAPITimeout uint64  `json:"command_api_timeout,omitempty"` // 接口超时的命令数
+	CommandAPIFailed  uint64  `json:"command_api_failed,omitempty"`  // 接口返回错误的命令数
+	CommandLost       uint64  `json:"command_lost,omitempty"`        // 设备没有收到的命令数
+	CommandAPIP99Ms   float64 `json:"command_api_p99_ms,omitempty"`  // 接口耗时p99
+	CommandAckP99Ms   float64 `json:"command_ack_p99_ms,omitempty"`  // 下发到设备回复的耗时p99
+
+	HistoryQueries      uint64  `json:"history_queries,omitempty"`       // 读写混合负载成功的查询数
+	HistoryQueryFailed  uint64  `json:"history_query_failed,omitempty"`  // 失败的查询数
+	HistoryQuerySkipped uint64  `json:"history_query_skipped,omitempty"` // 达到并发上限跳过的查询数
+	HistoryQueryP99Ms   float64 `json:"history_query_p99_ms,omitempty"`  // 查询耗时p99
+
+	Stages []StageSummary `json:"stages,omitempty"` // 分阶段负载各阶段的统计
+
+	TenantGroups []TenantGroupSummary `json:"tenant_groups,omitempty"` // 多租户分组各组的统计
+
+	CPUCapHits int `json:"cpu_cap_hits,omitempty"` // 压测工具CPU使用率达到上限的采样数
+
+	Histograms map[string]*Histogram `json:"histograms,omitempty"` // 各耗时的直方图，merge子命令据此合并分位数
+}
+
+// summaryQuantiles 由直方图计算的汇总字段(JSON字段名)，merge子命令合并直方图后按此重新计算
+var summaryQuantiles = []struct {
+	field     string
+	histogram string
+	q         float64
+}{
+	{"p50_ms", "publish", 0.50},
+	{"p99_ms", "publish", 0.99},
+	{"connect_p99_ms", "connect", 0.99},
+	{"corrected_p99_ms", "corrected", 0.99},
+	{"db_probe_p99_ms", "db_probe", 0.99},
+	{"offline_flush_p99_ms", "offline_flush", 0.99},
+	{"cred_rotation_new_p99_ms", "cred_rotation_new", 0.99},
+	{"cred_rotation_old_p99_ms", "cred_rotation_old", 0.99},
+	{"delete_auth_p99_ms", "delete_auth", 0.99},
+	{"device_onboarding_p99_ms", "device_onboarding", 0.99},
+	{"provision_p99_ms", "provision", 0.99},
+	{"alarm_latency_p50_ms", "alarm_latency", 0.50},
+	{"alarm_latency_p99_ms", "alarm_latency", 0.99},
+	{"dashboard_latency_p50_ms", "dashboard_latency", 0.50},
+	{"dashboard_latency_p99_ms", "dashboard_latency", 0.99},
+	{"attr_set_api_p99_ms", "attr_set_api", 0.99},
+	{"attr_set_ack_p99_ms", "attr_set_ack", 0.99},
+	{"command_api_p99_ms", "command_api", 0.99},
+	{"command_ack_p99_ms", "command_ack", 0.99},
+	{"history_query_p99_ms", "history_query", 0.99},
+}
+
+// summaryHistograms 汇总中保存的直方图，只包含有记录的
+func summaryHistograms() map[string]*Histogram {
+	all := map[string]*Histogram{
+		"publish":           &publishLatency,
+		"connect":           &connectLatency.total,
+		"corrected":         &schedule.corrected,
+		"db_probe":          &probeState.latency,
+		"offline_flush":     &offlineStats.flushTime,
+		"cred_rotation_new": &credRotation.newLatency,
+		"cred_rotation_old": &credRotation.oldLatency,
+		"delete_auth":       &fleetShrink.authLatency,
+		"device_onboarding": &fleetGrowth.onboard,
+		"provision":         &provisionStats.latency,
+		"alarm_latency":     &anomalyResult.latency,
+		"dashboard_latency": &glassLatency.latency,
+		"attr_set_api":      &attrSet.apiLatency,
+		"attr_set_ack":      &attrSet.ackLatency,
+		"command_api":       &commandFlood.apiLatency,
+		"command_ack":       &commandFlood.ackLatency,
+		"history_query":     &historyQuery.total,
+	}
+	histograms := make(map[string]*Histogram)
+	for name, h := range all {
+		if h.Count() > 0 {
+			histograms[name] = h.Snapshot()
+		}
+	}
+	return histograms
+}
+
+// durationMs 将耗时转换为毫秒
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// printJSONSummary 向标准输出打印单行JSON汇总，并保存到运行目录的summary.json，返回汇总内容
+func printJSONSummary(summary RunSummary, final StatsSnapshot, interrupted bool) []byte {
+	command := classifyCommands()
+	rotation := classifyCredRotation()
+	shrink := classifyFleetShrink()
+	line := jsonSummaryLine{
+		RunID:        summary.RunID,
+		DurationS:    summary.Duration.Seconds(),
+		Clients:      summary.Clients,
+		Connected:    summary.Connected,
+		Cycles:       summary.Cycles,
+		Msgs:         summary.Messages,
+		Points:       summary.DataPoints,
+		MsgsPerSec:   summary.MsgsPerSec,
+		PointsPerSec: summary.PointsPerSec,
+		P50Ms:        durationMs(publishLatency.Quantile(0.50)),
+		P99Ms:        durationMs(publishLatency.Quantile(0.99)),
+		ConnectP99Ms: durationMs(connectLatency.total.Quantile(0.99)),
+		DBWritten:    summary.DBWritten,
+		Received:     summary.Received,
+		Interrupted:  interrupted,
+		Aborted:      abortedReason(),
+		AuthPrimed:   primeResult.done,
+
+		CorrectedP99Ms:  durationMs(schedule.corrected.Quantile(0.99)),
+		BehindScheduleS: schedule.behindSchedule().Seconds(),
+
+		SustainablePointsPerSec: rateController.Sustainable(),
+		Streams:                 final.SentByType(),
+		HeartbeatDevices:        heartbeatDevices.Load(),
+
+		ConsumerReceived: atomic.LoadUint64(&consumerReceived),
+
+		Annotations: annotationsSince(time.Time{}),
+
+		PublishFailed:  final.PublishFailed,
+		PublishRetried: atomic.LoadUint64(&retriedCount),
+
+		ErrorsSuppressed: errorsSuppressed(),
+
+		ConnectionsLost:    reconnectStats.lost.Load(),
+		ReconnectAttempts:  reconnectStats.attempts.Load(),
+		ReconnectSucceeded: reconnectStats.succeeded.Load(),
+		ReconnectFailed:    reconnectStats.failed.Load(),
+
+		DBProbeP99Ms: durationMs(probeState.latency.Quantile(0.99)),
+
+		RampDownS:          rampDownResult.duration.Seconds(),
+		RampDownOfflineS:   rampDownResult.offlineAll.Seconds(),
+		RampDownOfflineP99: durationMs(rampDownResult.offlineP99),
+		RampDownFlushS:     rampDownResult.flushTime.Seconds(),
+
+		BrokerNodes: brokerNodeSummaries(summary.Duration),
+
+		OfflineBuffered:   atomic.LoadUint64(&offlineStats.buffered),
+		OfflineFlushed:    atomic.LoadUint64(&offlineStats.flushed),
+		OfflineFlushP99Ms: durationMs(offlineStats.flushTime.Quantile(0.99)),
+
+		CredRotated:          rotation.rotated,
+		CredRotationNewP99Ms: durationMs(credRotation.newLatency.Quantile(0.99)),
+		CredRotationOldP99Ms: durationMs(credRotation.oldLatency.Quantile(0.99)),
+		CredRotationOldValid: rotation.oldStillValid,
+
+		DevicesDeleted:      shrink.deleted,
+		DeleteAuthP99Ms:     durationMs(fleetShrink.authLatency.Quantile(0.99)),
+		DeleteAuthValid:     shrink.authValid,
+		DeleteAcceptedAfter: shrink.acceptedAfter,
+		DeleteStoredAfter:   max(fleetShrink.stored, 0),
+
+		DevicesAdded:          fleetGrowth.added.Load(),
+		DevicesAddFailed:      fleetGrowth.connectFailed.Load(),
+		DeviceOnboardingP99Ms: durationMs(fleetGrowth.onboard.Quantile(0.99)),
+
+		ProvisionRequests: provisionStats.requests.Load(),
+		ProvisionFailed:   provisionStats.failed.Load() + provisionStats.malformed.Load(),
+		ProvisionP99Ms:    durationMs(provisionStats.latency.Quantile(0.99)),
+
+		CurrentChecked:  currentResultSummary.checked,
+		CurrentMismatch: currentResultSummary.mismatched + currentResultSummary.missing,
+
+		ChecksumChecked:   checksumResultSummary.checked,
+		ChecksumCountDiff: checksumResultSummary.countDiff,
+		ChecksumCorrupt:   checksumResultSummary.corrupt,
+
+		TenantLimit:          tenantLimit.result,
+		TenantOthersDegraded: tenantLimit.degraded,
+
+		StringsRejected:  stringStats.rejectedPoints,
+		StringsTruncated: stringStats.truncated,
+
+		AnomalyEvents:     anomalyResult.events,
+		AnomalyDetected:   anomalyResult.detected,
+		AlarmLatencyP50Ms: durationMs(anomalyResult.latency.Quantile(0.50)),
+		AlarmLatencyP99Ms: durationMs(anomalyResult.latency.Quantile(0.99)),
+
+		HTTPSent:          protocolHTTP.sent.Load(),
+		HTTPFailed:        protocolHTTP.failed.Load(),
+		DashboardReceived: dashboardStats.received.Load(),
+
+		DashboardLatencyP50Ms: durationMs(glassLatency.latency.Quantile(0.50)),
+		DashboardLatencyP99Ms: durationMs(glassLatency.latency.Quantile(0.99)),
+		DashboardLatencyLost:  glassLatency.expected.Load() - min(glassLatency.received.Load(), glassLatency.expected.Load()),
+
+		AttrSetSent:      attrSet.sent.Load(),
+		AttrSetAPIFailed: attrSet.apiFailed.Load(),
+		AttrSetLost:      attrSet.sent.Load() - attrSet.delivered.Load(),
+		AttrSetAPIP99Ms:  durationMs(attrSet.apiLatency.Quantile(0.99)),
+		AttrSetAckP99Ms:  durationMs(attrSet.ackLatency.Quantile(0.99)),
+
+		CommandSent:       command.sent,
+		CommandSucceeded:  command.succeeded,
+		CommandAPITimeout: command.apiTimeout,
+		CommandAPIFailed:  command.apiFailed,
+		CommandLost:       command.lost,
+		CommandAPIP99Ms:   durationMs(commandFlood.apiLatency.Quantile(0.99)),
+		CommandAckP99Ms:   durationMs(commandFlood.ackLatency.Quantile(0.99)),
+
+		HistoryQueries:      historyQuery.total.Count(),
+		HistoryQueryFailed:  historyQuery.failed.Load(),
+		HistoryQuerySkipped: historyQuery.skipped.Load(),
+		HistoryQueryP99Ms:   durationMs(historyQuery.total.Quantile(0.99)),
+
+		Stages: stageSummaries(),
+
+		TenantGroups: tenantGroupSummaries(summary.Duration),
+
+		CPUCapHits: cpuCapHits(),
+
+		Histograms: summaryHistograms(),
+	}
+	if summary.DataPoints > 0 {
+		line.WriteRate = float64(summary.DBWritten) / float64(summary.DataPoints) * 100
+	}
+
+	data, err := json.Marshal(line)
+	if err != nil {
+		log.Printf(T("序列化JSON汇总失败: %v"), err)
+		return nil
+	}
+	data = append(data, '\n')
+	writeRunArtifact("summary.json", data)
+
+	if *jsonSummary {
+		os.Stdout.Write(data)
+	}
+	return data
+}
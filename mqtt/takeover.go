@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+	"hash/fnv"
+	"log"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+)
+
+// 会话接管场景命令行参数
+var (
+	takeoverFlag  = flag.Bool("takeover", false, "会话接管场景：测试中途用相同的客户端ID为设备再建立一个连接，测量接管耗时和交接期间的消息丢失")
+	takeoverCycle = flag.Int("takeover-cycle", 0, "在第几个循环进行接管(默认为总循环数的一半)")
+	takeoverRatio = flag.Float64("takeover-ratio", 0, "参与接管的设备比例(%)，默认100")
+)
+
+// takeoverStats 会话接管统计
+var takeoverStats struct {
+	attempted uint64    // 参与接管的设备数
+	failed    uint64    // 新连接失败的设备数(继续使用旧连接)
+	lost      uint64    // 接管后首条消息发布失败的设备数
+	latency   Histogram // 新连接CONNECT→CONNACK耗时
+}
+
+// takeoverDue 判断设备是否在本循环进行接管
+//
+// 按token哈希选择设备，同一配置下每次运行选中的设备相同。
+func takeoverDue(cycle uint64, username string) bool {
+	cfg := AppConfig.Takeover
+	if !cfg.Enabled || cycle != uint64(cfg.Cycle) {
+		return false
+	}
+	return hashSelected(username, cfg.Ratio)
+}
+
+// hashSelected 按token哈希判断设备是否落在指定比例(%)内
+func hashSelected(username string, ratio float64) bool {
+	if ratio >= 100 {
+		return true
+	}
+	h := fnv.New32a()
+	h.Write([]byte(username))
+	return float64(h.Sum32()%10000) < ratio*100
+}
+
+// takeoverSession 模拟SIM卡更换或重复烧录：用相同的客户端ID建立新连接，
+// Broker应踢掉旧连接，之后设备改用新连接发送。新连接失败时返回旧连接和false。
+//
+// 旧连接在新连接成功后立即断开，避免paho的自动重连把新连接踢回去。
+func takeoverSession(old DeviceClient, clientID, username string, stats *DeviceStats, rng *rand.Rand) (DeviceClient, bool) {
+	atomic.AddUint64(&takeoverStats.attempted, 1)
+	next := newDeviceClient(clientID, username, stats, rng)
+	start := time.Now()
+	if err := next.Connect(); err != nil {
+		atomic.AddUint64(&takeoverStats.failed, 1)
+		logErrorf(T("设备 %s 会话接管失败: %v"), username, err)
+		return old, false
+	}
+	takeoverStats.latency.Record(time.Since(start))
+	old.Disconnect()
+	return next, true
+}
+
+// recordHandover 记录接管后首条消息的发布结果
+func recordHandover(err error) {
+	if err != nil {
+		atomic.AddUint64(&takeoverStats.lost, 1)
+	}
+}
+
+// reportTakeover 输出会话接管统计(未启用时不输出)
+func reportTakeover() {
+	if !AppConfig.Takeover.Enabled {
+		return
+	}
+	attempted := atomic.LoadUint64(&takeoverStats.attempted)
+	log.Println(T("\n========== 会话接管 =========="))
+	if attempted == 0 {
+		log.Printf(T("测试未进行到第 %d 个循环，未进行接管"), AppConfig.Takeover.Cycle)
+	} else {
+		failed, lost := atomic.LoadUint64(&takeoverStats.failed), atomic.LoadUint64(&takeoverStats.lost)
+		log.Printf(T("第 %d 个循环接管设备: %d, 成功 %d, 失败 %d"),
+			AppConfig.Takeover.Cycle, attempted, attempted-failed, failed)
+		log.Printf(T("接管耗时(新连接CONNECT→CONNACK): p50=%v, p99=%v, 最大=%v"),
+			takeoverStats.latency.Quantile(0.50), takeoverStats.latency.Quantile(0.99), takeoverStats.latency.Max())
+		log.Printf(T("交接期间丢失: %d 个设备接管后首条消息发布失败 (%.2f%%)"), lost, percent(lost, attempted))
+	}
+	log.Println("===============================")
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// TenantGroupConfig 同一次运行中相互独立的一组设备(一个租户)
+type TenantGroupConfig struct {
+	Name      string        `yaml:"name"`       // 分组名称，用于分别统计和报告
+	TokenFile string        `yaml:"token_file"` // 该组的设备token文件
+	Clients   int           `yaml:"clients"`    // 该组的设备数
+	Topic     string        `yaml:"topic"`      // 该组的遥测主题(主题命名空间)，{username}替换为设备token，为空时使用mqtt.topic
+	Interval  time.Duration `yaml:"interval"`   // 该组的发送间隔，须为test.data_interval的整数倍，0为每个循环都发送
+	TenantID  string        `yaml:"tenant_id"`  // 平台上的租户ID，配置后测试结束时按租户统计入库数(可选)
+}
+
+// tenantGroup 一个分组的运行状态和统计
+type tenantGroup struct {
+	TenantGroupConfig
+	first int    // 该组第一个设备的序号
+	every uint64 // 每N个循环发送一次
+
+	connected     atomic.Uint64
+	connectFailed atomic.Uint64
+	sent          atomic.Uint64 // 发送成功的消息数
+	failed        atomic.Uint64 // 发送失败的消息数
+	points        atomic.Uint64 // 发送成功的数据点数
+	latency       Histogram     // 发布耗时
+}
+
+// tenantGroups 配置的分组，按设备序号排列，启动后只读
+var tenantGroups []*tenantGroup
+
+// tenantGroupsEnabled 是否按分组模拟多个独立租户
+func tenantGroupsEnabled() bool {
+	return len(AppConfig.TenantGroups) > 0
+}
+
+// initTenantGroups 校验分组配置，设备总数为各组设备数之和
+func initTenantGroups() {
+	if !tenantGroupsEnabled() {
+		return
+	}
+	if AppConfig.Device.Filter != "" {
+		log.Fatalf(T("多租户分组: 不能与设备筛选同时使用"))
+	}
+	seen := map[string]bool{}
+	tenantGroups = nil
+	first := 0
+	for i, cfg := range AppConfig.TenantGroups {
+		switch {
+		case cfg.Name == "":
+			log.Fatalf(T("多租户分组: 第 %d 组没有名称"), i+1)
+		case seen[cfg.Name]:
+			log.Fatalf(T("多租户分组: 名称 %s 重复"), cfg.Name)
+		case cfg.Clients <= 0:
+			log.Fatalf(T("多租户分组: %s 的设备数必须大于0"), cfg.Name)
+		case cfg.TokenFile == "" && !localBrokerEnabled() && !sinkEnabled():
+			log.Fatalf(T("多租户分组: %s 没有配置token文件"), cfg.Name)
+		case cfg.Interval < 0 || cfg.Interval%AppConfig.Test.DataInterval != 0:
+			log.Fatalf(T("多租户分组: %s 的发送间隔 %v 不是 test.data_interval (%v) 的整数倍"), cfg.Name, cfg.Interval, AppConfig.Test.DataInterval)
+		}
+		seen[cfg.Name] = true
+		if cfg.Topic == "" {
+			cfg.Topic = AppConfig.MQTT.Topic
+		}
+		g := &tenantGroup{TenantGroupConfig: cfg, first: first, every: max(uint64(cfg.Interval/AppConfig.Test.DataInterval), 1)}
+		tenantGroups = append(tenantGroups, g)
+		first += cfg.Clients
+	}
+	AppConfig.Device.ClientNumber = first
+}
+
+// loadTenantGroupTokens 依次读取各组的token文件，合并为一个列表，设备序号按分组连续排列
+//
+// 本地Broker和本地接收端不校验设备，token文件不存在时按组名生成。某组的token不足时
+// 该组的设备数减少为实际数量。
+func loadTenantGroupTokens() (*TokenList, error) {
+	var buf strings.Builder
+	var offsets []uint32
+	first := 0
+	for _, g := range tenantGroups {
+		tokens, err := loadTokens(g.TokenFile, g.Clients, AppConfig.Device.TokenMmap)
+		if err != nil && (localBrokerEnabled() || sinkEnabled()) && (g.TokenFile == "" || errors.Is(err, os.ErrNotExist)) {
+			tokens, err = tenantGroupTokens(g.Name, g.Clients), nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf(T("多租户分组 %s: %w"), g.Name, err)
+		}
+		if tokens.Len() < g.Clients {
+			log.Printf(T("警告: 多租户分组 %s: 可用设备数量(%d)少于请求数量(%d)"), g.Name, tokens.Len(), g.Clients)
+			g.Clients = tokens.Len()
+		}
+		g.first = first
+		first += g.Clients
+		for i := 0; i < g.Clients; i++ {
+			offsets = append(offsets, uint32(buf.Len()))
+			buf.WriteString(tokens.At(i))
+			offsets = append(offsets, uint32(buf.Len()))
+		}
+	}
+	AppConfig.Device.ClientNumber = first
+	return &TokenList{data: buf.String(), offsets: offsets}, nil
+}
+
+// tenantGroupTokens 为分组生成n个设备token，各组的token不重复
+func tenantGroupTokens(name string, n int) *TokenList {
+	var buf strings.Builder
+	offsets := make([]uint32, 0, 2*n)
+	for i := 1; i <= n; i++ {
+		offsets = append(offsets, uint32(buf.Len()))
+		fmt.Fprintf(&buf, "%s%s-%06d", localBrokerTokenPrefix, name, i)
+		offsets = append(offsets, uint32(buf.Len()))
+	}
+	return &TokenList{data: buf.String(), offsets: offsets}
+}
+
+// tenantGroupOf 返回第index个设备所属的分组，未启用时返回nil
+func tenantGroupOf(index int) *tenantGroup {
+	for _, g := range tenantGroups {
+		if index < g.first+g.Clients {
+			return g
+		}
+	}
+	return nil
+}
+
+// telemetryTopic 设备发布遥测数据的主题，nil表示未启用分组，使用mqtt.topic
+func (g *tenantGroup) telemetryTopic(username string) string {
+	if g == nil {
+		return AppConfig.MQTT.Topic
+	}
+	return strings.ReplaceAll(g.Topic, "{username}", username)
+}
+
+// due 该组在本循环是否发送，nil表示未启用
+func (g *tenantGroup) due(cycle uint64) bool {
+	return g == nil || cycle%g.every == 0
+}
+
+// recordConnect 记录设备的连接结果，nil表示未启用
+func (g *tenantGroup) recordConnect(err error) {
+	if g == nil {
+		return
+	}
+	if err != nil {
+		g.connectFailed.Add(1)
+		return
+	}
+	g.connected.Add(1)
+}
+
+// record 记录一次遥测发布结果，nil表示未启用
+func (g *tenantGroup) record(points int, latency time.Duration, err error) {
+	if g == nil {
+		return
+	}
+	if err != nil {
+		g.failed.Add(1)
+		return
+	}
+	g.sent.Add(1)
+	g.points.Add(uint64(points))
+	g.latency.Record(latency)
+}
+
+// TenantGroupSummary JSON汇总中一个分组的统计
+type TenantGroupSummary struct {
+	Name          string  `json:"name"`
+	Devices       int     `json:"devices"`
+	Connected     uint64  `json:"connected"`
+	ConnectFailed uint64  `json:"connect_failed"`
+	Msgs          uint64  `json:"msgs"`
+	Points        uint64  `json:"points"`
+	Failed        uint64  `json:"failed"`
+	PointsPerSec  float64 `json:"points_per_sec"`
+	P50Ms         float64 `json:"p50_ms"`
+	P99Ms         float64 `json:"p99_ms"`
+	Stored        int64   `json:"stored"` // 入库数据点数，未配置tenant_id或未查询时为-1
+
+	Latency *Histogram `json:"latency,omitempty"` // 发布耗时直方图，合并多个worker的汇总时使用
+}
+
+// tenantGroupStored 各分组的入库数据点数，由reportTenantGroups查询
+var tenantGroupStored map[string]int64
+
+// tenantGroupSummaries 各分组的统计
+func tenantGroupSummaries(elapsed time.Duration) []TenantGroupSummary {
+	var summaries []TenantGroupSummary
+	for _, g := range tenantGroups {
+		stored := int64(-1)
+		if tenantGroupStored != nil && g.TenantID != "" {
+			stored = tenantGroupStored[g.TenantID]
+		}
+		summaries = append(summaries, TenantGroupSummary{
+			Name:          g.Name,
+			Devices:       g.Clients,
+			Connected:     g.connected.Load(),
+			ConnectFailed: g.connectFailed.Load(),
+			Msgs:          g.sent.Load(),
+			Points:        g.points.Load(),
+			Failed:        g.failed.Load(),
+			PointsPerSec:  ratePerSecond(float64(g.points.Load()), elapsed),
+			P50Ms:         durationMs(g.latency.Quantile(0.50)),
+			P99Ms:         durationMs(g.latency.Quantile(0.99)),
+			Stored:        stored,
+			Latency:       g.latency.Snapshot(),
+		})
+	}
+	return summaries
+}
+
+// reportTenantGroups 分别输出各分组的发送、发布耗时和入库统计(未启用时不输出)
+//
+// 各组共用同一个平台，一个组的负载升高时其他组的发布耗时和写入率是否保持不变，
+// 用于验证平台的租户隔离(noisy neighbor)。配置了tenant_id且连接数据库时按租户统计入库数，
+// 同一租户ID配置在多个组时入库数为这些组的合计。
+func reportTenantGroups(start time.Time, elapsed time.Duration) {
+	if !tenantGroupsEnabled() {
+		return
+	}
+	var tenants []string
+	for _, g := range tenantGroups {
+		if g.TenantID != "" {
+			tenants = append(tenants, g.TenantID)
+		}
+	}
+	if len(tenants) > 0 && !localBrokerEnabled() && !sinkEnabled() {
+		if err := queryTenantGroupStored(tenants, start); err != nil {
+			log.Printf(T("多租户分组: %v"), err)
+		}
+	}
+
+	log.Println(T("\n========== 多租户分组 =========="))
+	for i, s := range tenantGroupSummaries(elapsed) {
+		g := tenantGroups[i]
+		log.Printf(T("%s: 设备 %d (连接成功 %d, 失败 %d), 主题 %s, 发送间隔 %v"),
+			s.Name, s.Devices, s.Connected, s.ConnectFailed, g.Topic, time.Duration(g.every)*AppConfig.Test.DataInterval)
+		log.Printf(T("  发送: %d 条消息, %d 个数据点 (%.1f 点/秒), 失败 %d 条 (%.2f%%), 发布耗时 p50 %v, p99 %v"),
+			s.Msgs, s.Points, s.PointsPerSec, s.Failed, percent(s.Failed, s.Msgs+s.Failed),
+			g.latency.Quantile(0.50), g.latency.Quantile(0.99))
+		if s.Stored >= 0 && s.Points > 0 {
+			log.Printf(T("  入库(租户 %s): %d 个数据点, 写入率 %.1f%%"), g.TenantID, s.Stored, float64(s.Stored)/float64(s.Points)*100)
+		}
+	}
+	log.Println("===============================")
+}
+
+// queryTenantGroupStored 查询各分组所属租户自start起的入库数
+func queryTenantGroupStored(tenants []string, start time.Time) error {
+	db, err := openReadDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	ingestSettle()
+	waitReplicaReplay(db)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	tenantGroupStored, err = storedByTenant(ctx, db, tenants, start)
+	return err
+}
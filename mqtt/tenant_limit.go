@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 租户限额验证命令行参数
+var (
+	limitTenant = flag.String("limit-tenant", "", "租户限额验证: 被限额的租户ID，测试结束时核对该租户的入库速率不超过限额、其他租户不受影响")
+	tenantQuota = flag.Float64("tenant-quota", 0, "租户限额验证: 该租户在平台上配置的入库限额(数据点/秒)")
+)
+
+// tenantLookupBatch 按token查询设备所属租户时每次查询的token数
+const tenantLookupBatch = 5000
+
+// tenantCounters 单个租户的发布统计
+type tenantCounters struct {
+	devices  atomic.Uint64
+	points   atomic.Uint64 // 发送成功的数据点数
+	sent     atomic.Uint64 // 发送成功的消息数
+	failed   atomic.Uint64 // 发送失败的消息数
+	mu       sync.Mutex
+	rejected map[byte]uint64 // 按PUBACK原因码统计被服务器拒绝的消息数
+}
+
+// record 记录一次遥测发布结果，nil表示未启用租户限额验证
+func (c *tenantCounters) record(points int, err error) {
+	if c == nil {
+		return
+	}
+	if err == nil {
+		c.sent.Add(1)
+		c.points.Add(uint64(points))
+		return
+	}
+	c.failed.Add(1)
+	var rejected *publishRejected
+	if errors.As(err, &rejected) {
+		c.mu.Lock()
+		c.rejected[rejected.code]++
+		c.mu.Unlock()
+	}
+}
+
+// tenantLimit 租户限额验证的状态，启动后只读
+var tenantLimit struct {
+	byToken  map[string]*tenantCounters // 设备token到所属租户统计
+	byTenant map[string]*tenantCounters
+	result   string // 验证结论(enforced/not_enforced/inconclusive)
+	degraded bool   // 其他租户的写入率是否低于阈值
+}
+
+// tenantLimitEnabled 是否启用租户限额验证
+func tenantLimitEnabled() bool {
+	return AppConfig.TenantLimit.Tenant != ""
+}
+
+// initTenantLimit 查询参与测试的设备所属的租户
+//
+// 被限额租户和其他租户的设备在同一次运行中混合发送，token文件中应同时包含两类设备，
+// 被限额租户的设备数和发送速率需要使其发送速率超过限额。
+func initTenantLimit(tokens *TokenList) error {
+	if !tenantLimitEnabled() {
+		return nil
+	}
+	db, err := openDatabase()
+	if err != nil {
+		return fmt.Errorf(T("租户限额验证: %w"), err)
+	}
+	defer db.Close()
+
+	tenantLimit.byToken = make(map[string]*tenantCounters, tokens.Len())
+	tenantLimit.byTenant = make(map[string]*tenantCounters)
+	for start := 0; start < tokens.Len(); start += tenantLookupBatch {
+		batch := make([]string, 0, tenantLookupBatch)
+		for i := start; i < min(start+tenantLookupBatch, tokens.Len()); i++ {
+			batch = append(batch, tokens.At(i))
+		}
+		if err := lookupTenants(db, batch); err != nil {
+			return err
+		}
+	}
+
+	limited := tenantLimit.byTenant[AppConfig.TenantLimit.Tenant]
+	if limited == nil {
+		return fmt.Errorf(T("租户限额验证: token文件中没有租户 %s 的设备"), AppConfig.TenantLimit.Tenant)
+	}
+	if len(tenantLimit.byTenant) < 2 {
+		log.Println(T("警告: 租户限额验证: 参与测试的设备都属于被限额租户，无法验证其他租户不受影响"))
+	}
+	if missing := tokens.Len() - len(tenantLimit.byToken); missing > 0 {
+		log.Printf(T("警告: 租户限额验证: %d 个token在数据库中找不到设备，不计入租户统计"), missing)
+	}
+	log.Printf(T("租户限额验证: 被限额租户 %s 有 %d 个设备，其他 %d 个租户"),
+		AppConfig.TenantLimit.Tenant, limited.devices.Load(), len(tenantLimit.byTenant)-1)
+	return nil
+}
+
+// lookupTenants 查询一批token对应设备的租户
+func lookupTenants(db *sql.DB, batch []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	rows, err := db.QueryContext(ctx, `SELECT voucher::json->>'username', tenant_id FROM devices
+		WHERE voucher::json->>'username' = ANY($1)`, batch)
+	if err != nil {
+		return fmt.Errorf(T("查询设备所属租户失败: %w"), err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var username, tenant string
+		if err := rows.Scan(&username, &tenant); err != nil {
+			return fmt.Errorf(T("查询设备所属租户失败: %w"), err)
+		}
+		c := tenantLimit.byTenant[tenant]
+		if c == nil {
+			c = &tenantCounters{rejected: make(map[byte]uint64)}
+			tenantLimit.byTenant[tenant] = c
+		}
+		if _, ok := tenantLimit.byToken[username]; !ok {
+			c.devices.Add(1)
+		}
+		tenantLimit.byToken[username] = c
+	}
+	return rows.Err()
+}
+
+// tenantOf 返回设备所属租户的统计，未启用或找不到设备时返回nil
+func tenantOf(username string) *tenantCounters {
+	return tenantLimit.byToken[username]
+}
+
+// storedByTenant 查询各租户自since起入库的数据点数
+func storedByTenant(ctx context.Context, db *sql.DB, tenants []string, since time.Time) (map[string]int64, error) {
+	rows, err := db.QueryContext(ctx, `SELECT d.tenant_id, COUNT(*) FROM telemetry_datas t
+		JOIN devices d ON d.id = t.device_id
+		WHERE t.ts >= $1 AND d.tenant_id = ANY($2) GROUP BY d.tenant_id`,
+		since.Add(-dbClockSkew).UnixMilli(), tenants)
+	if err != nil {
+		return nil, fmt.Errorf(T("查询租户入库数失败: %w"), err)
+	}
+	defer rows.Close()
+	stored := make(map[string]int64)
+	for rows.Next() {
+		var tenant string
+		var count int64
+		if err := rows.Scan(&tenant, &count); err != nil {
+			return nil, fmt.Errorf(T("查询租户入库数失败: %w"), err)
+		}
+		stored[tenant] = count
+	}
+	return stored, rows.Err()
+}
+
+// rejectedSummary 按原因码汇总被拒绝的消息数，如 "0x97=120"
+func (c *tenantCounters) rejectedSummary() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	codes := make([]int, 0, len(c.rejected))
+	for code := range c.rejected {
+		codes = append(codes, int(code))
+	}
+	sort.Ints(codes)
+	summary := ""
+	for i, code := range codes {
+		if i > 0 {
+			summary += ", "
+		}
+		summary += fmt.Sprintf("0x%02x=%d", code, c.rejected[byte(code)])
+	}
+	if summary == "" {
+		return "-"
+	}
+	return summary
+}
+
+// reportTenantLimit 核对被限额租户的入库速率不超过限额，且其他租户的写入率不受影响
+//
+// 限额通过Broker拒绝(MQTT 5 PUBACK原因码、断开连接)或平台丢弃超额数据实现，
+// 两种方式都体现为该租户的入库速率被压在限额附近；其他租户的写入率低于阈值说明
+// 限流影响到了同一平台上的其他租户。
+func reportTenantLimit(start time.Time, elapsed time.Duration) {
+	if !tenantLimitEnabled() || len(tenantLimit.byTenant) == 0 {
+		return
+	}
+	log.Println(T("\n========== 租户限额验证 =========="))
+	defer log.Println("===============================")
+
+	db, err := openReadDatabase()
+	if err != nil {
+		log.Printf(T("租户限额验证: %v"), err)
+		return
+	}
+	defer db.Close()
+	ingestSettle()
+	waitReplicaReplay(db)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	tenants := make([]string, 0, len(tenantLimit.byTenant))
+	for tenant := range tenantLimit.byTenant {
+		tenants = append(tenants, tenant)
+	}
+	sort.Strings(tenants)
+	stored, err := storedByTenant(ctx, db, tenants, start)
+	if err != nil {
+		log.Printf(T("租户限额验证: %v"), err)
+		return
+	}
+
+	cfg := AppConfig.TenantLimit
+	seconds := max(elapsed.Seconds(), 1)
+	for _, tenant := range tenants {
+		c := tenantLimit.byTenant[tenant]
+		points := c.points.Load()
+		sentRate, storedRate := float64(points)/seconds, float64(stored[tenant])/seconds
+		log.Printf(T("租户 %s: 设备 %d, 发送 %.1f 点/秒 (成功 %d 条, 失败 %d 条, 拒绝原因码: %s), 入库 %.1f 点/秒"),
+			tenant, c.devices.Load(), sentRate, c.sent.Load(), c.failed.Load(), c.rejectedSummary(), storedRate)
+
+		if tenant == cfg.Tenant {
+			offered := float64(points+c.failed.Load()*uint64(AppConfig.Data.DataPointCount)) / seconds
+			limit := cfg.Quota * (1 + cfg.Tolerance/100)
+			switch {
+			case offered <= cfg.Quota:
+				tenantLimit.result = "inconclusive"
+				log.Printf(T("  结论: 无法验证，发送速率 %.1f 点/秒未超过限额 %.0f 点/秒，需增加该租户的设备数或发送频率"), offered, cfg.Quota)
+			case storedRate <= limit:
+				tenantLimit.result = "enforced"
+				log.Printf(T("  结论: 限额生效，入库速率 %.1f 点/秒未超过限额 %.0f 点/秒(容差 %.0f%%)"), storedRate, cfg.Quota, cfg.Tolerance)
+			default:
+				tenantLimit.result = "not_enforced"
+				log.Printf(T("  结论: 限额未生效，入库速率 %.1f 点/秒超过限额 %.0f 点/秒(容差 %.0f%%)"), storedRate, cfg.Quota, cfg.Tolerance)
+			}
+			continue
+		}
+		if points == 0 {
+			continue
+		}
+		ratio := float64(stored[tenant]) / float64(points) * 100
+		if ratio < cfg.MinWriteRatio {
+			tenantLimit.degraded = true
+			log.Printf(T("  结论: 受到影响，写入率 %.1f%% 低于 %.0f%%"), ratio, cfg.MinWriteRatio)
+		} else {
+			log.Printf(T("  结论: 未受影响，写入率 %.1f%%"), min(ratio, 100))
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 资源限制命令行参数
+var (
+	maxProcs  = flag.Int("max-procs", 0, "限制使用的CPU核数(GOMAXPROCS)，与Broker等服务共用机器时使用，0为不限制")
+	niceLevel = flag.Int("nice", 0, "降低进程调度优先级(1-19，仅Linux/macOS)")
+	cpuLimit  = flag.Float64("cpu-limit", 0, "CPU使用率上限(%，100为一个核)，超出时推迟发送，0为不限制")
+	gcPercent = flag.Int("gc-percent", 0, "GC触发比例(GOGC)，调低可减少内存占用但会增加CPU开销，0为不修改")
+)
+
+// cpuSampleInterval CPU使用率的采样间隔
+const cpuSampleInterval = time.Second
+
+// cpuCapThreshold 使用率达到上限的该比例即视为触顶
+const cpuCapThreshold = 0.95
+
+// maxPace 限速时发送间隔最多拉长的倍数
+const maxPace = 20
+
+// cpuStats CPU使用率采样和限速统计
+var cpuStats struct {
+	mu       sync.Mutex
+	samples  int
+	sum      float64 // 各采样的使用率之和(%)
+	peak     float64
+	capHits  int           // 使用率触顶的采样数
+	firstHit time.Time     // 第一次触顶的时间
+	paced    time.Duration // 因限速累计推迟发送的时间
+
+	pace atomic.Uint64 // 发送间隔倍数(float64位模式)，1为不限速
+}
+
+// applyThrottle 按配置限制本进程的CPU核数、调度优先级和GC频率，在启动设备前调用
+func applyThrottle() {
+	cfg := AppConfig.Throttle
+	if cfg.MaxProcs > 0 {
+		runtime.GOMAXPROCS(cfg.MaxProcs)
+	}
+	if cfg.Nice > 0 {
+		if err := setNice(cfg.Nice); err != nil {
+			log.Printf(T("警告: 设置调度优先级失败: %v"), err)
+		}
+	}
+	if cfg.GCPercent > 0 {
+		debug.SetGCPercent(cfg.GCPercent)
+	}
+	cpuStats.pace.Store(math.Float64bits(1))
+}
+
+// cpuCap 返回CPU使用率上限(%)，未限制时返回0
+//
+// 同时配置了cpu_limit和max_procs时取较小者。
+func cpuCap() float64 {
+	limit := AppConfig.Throttle.CPULimit
+	if procs := AppConfig.Throttle.MaxProcs; procs > 0 && (limit <= 0 || float64(procs*100) < limit) {
+		limit = float64(procs * 100)
+	}
+	return limit
+}
+
+// monitorCPU 定期采集进程CPU使用率，超过cpu_limit时按比例拉长发送间隔
+//
+// 限速倍数按使用率与上限之比累乘调整，使用率回落后逐步恢复，避免在上限附近来回振荡。
+func monitorCPU(ctx context.Context) {
+	last, ok := processCPUTime()
+	if !ok {
+		if cpuCap() > 0 {
+			log.Println(T("警告: 当前平台无法采集进程CPU使用率，不会报告是否达到CPU上限"))
+		}
+		return
+	}
+	lastTime := time.Now()
+	ticker := time.NewTicker(cpuSampleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		now := time.Now()
+		used, _ := processCPUTime()
+		usage := float64(used-last) / float64(now.Sub(lastTime)) * 100
+		last, lastTime = used, now
+		recordCPUSample(now, usage)
+	}
+}
+
+// recordCPUSample 记录一次CPU使用率采样并调整限速倍数
+func recordCPUSample(now time.Time, usage float64) {
+	capacity := cpuCap()
+	cpuStats.mu.Lock()
+	defer cpuStats.mu.Unlock()
+
+	cpuStats.samples++
+	cpuStats.sum += usage
+	cpuStats.peak = max(cpuStats.peak, usage)
+	if capacity > 0 && usage >= capacity*cpuCapThreshold {
+		cpuStats.capHits++
+		if cpuStats.firstHit.IsZero() {
+			cpuStats.firstHit = now
+			annotate("throttle", fmt.Sprintf(T("CPU使用率 %.0f%% 达到上限 %.0f%%"), usage, capacity))
+		}
+	}
+
+	if limit := AppConfig.Throttle.CPULimit; limit > 0 && usage > 0 {
+		pace := math.Float64frombits(cpuStats.pace.Load()) * usage / limit
+		cpuStats.pace.Store(math.Float64bits(min(max(pace, 1), maxPace)))
+	}
+}
+
+// throttleDelay 返回CPU限速要求在发送间隔之外额外等待的时间
+func throttleDelay(interval time.Duration) time.Duration {
+	pace := math.Float64frombits(cpuStats.pace.Load())
+	if pace <= 1 {
+		return 0
+	}
+	delay := time.Duration(float64(interval) * (pace - 1))
+	cpuStats.mu.Lock()
+	cpuStats.paced += delay
+	cpuStats.mu.Unlock()
+	return delay
+}
+
+// cpuCapHits 返回CPU使用率触顶的采样数
+func cpuCapHits() int {
+	cpuStats.mu.Lock()
+	defer cpuStats.mu.Unlock()
+	return cpuStats.capHits
+}
+
+// reportThrottle 输出CPU使用率和限速情况(未配置资源限制且未采样时不输出)
+func reportThrottle() {
+	cfg := AppConfig.Throttle
+	cpuStats.mu.Lock()
+	defer cpuStats.mu.Unlock()
+	if cpuStats.samples == 0 || (cfg.MaxProcs <= 0 && cfg.CPULimit <= 0 && cfg.Nice <= 0 && cfg.GCPercent <= 0) {
+		return
+	}
+
+	log.Println(T("\n========== 资源限制 =========="))
+	log.Printf(T("GOMAXPROCS=%d, 调度优先级=%d, GC比例=%d"), runtime.GOMAXPROCS(0), cfg.Nice, cfg.GCPercent)
+	log.Printf(T("CPU使用率(100%%为一个核): 平均 %.0f%%, 峰值 %.0f%%"),
+		cpuStats.sum/float64(cpuStats.samples), cpuStats.peak)
+	if capacity := cpuCap(); capacity > 0 {
+		log.Printf(T("CPU上限 %.0f%%: %d/%d 次采样达到上限"), capacity, cpuStats.capHits, cpuStats.samples)
+		if cpuStats.capHits > 0 {
+			log.Printf(T("警告: 压测工具自身的CPU达到上限(首次在 %s)，吞吐量和耗时可能受工具本身限制而非平台"),
+				cpuStats.firstHit.Format("15:04:05"))
+		}
+	}
+	if cpuStats.paced > 0 {
+		log.Printf(T("因CPU限速累计推迟发送: %v"), cpuStats.paced.Round(time.Millisecond))
+	}
+	log.Println("===============================")
+}
@@ -0,0 +1,18 @@
+//go:build !linux && !darwin && !windows
+
+package main
+
+import (
+	"errors"
+	"time"
+)
+
+// processCPUTime 当前平台不采集进程CPU时间
+func processCPUTime() (time.Duration, bool) {
+	return 0, false
+}
+
+// setNice 当前平台不支持调整调度优先级
+func setNice(level int) error {
+	return errors.ErrUnsupported
+}
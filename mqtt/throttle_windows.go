@@ -0,0 +1,29 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"syscall"
+	"time"
+)
+
+// processCPUTime 返回进程累计使用的CPU时间(用户态+内核态)
+func processCPUTime() (time.Duration, bool) {
+	process, err := syscall.GetCurrentProcess()
+	if err != nil {
+		return 0, false
+	}
+	var creation, exit, kernel, user syscall.Filetime
+	if err := syscall.GetProcessTimes(process, &creation, &exit, &kernel, &user); err != nil {
+		return 0, false
+	}
+	// Filetime以100纳秒为单位
+	ticks := func(ft syscall.Filetime) int64 { return int64(ft.HighDateTime)<<32 | int64(ft.LowDateTime) }
+	return time.Duration((ticks(kernel) + ticks(user)) * 100), true
+}
+
+// setNice Windows没有nice值，不支持调整调度优先级
+func setNice(level int) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"unsafe"
+)
+
+// 设备token加载相关命令行参数
+var (
+	tokenMmap       = flag.Bool("token-mmap", false, "使用mmap映射设备token文件(仅类Unix系统)")
+	duplicateTokens = flag.String("duplicate-tokens", "", "重复token的处理方式(dedupe: 去重, error: 报错退出, allow: 保留，用于客户端抢占测试)")
+)
+
+// 重复token示例的最大数量
+const maxDuplicateSamples = 5
+
+// errMmapUnsupported 当前平台不支持mmap
+var errMmapUnsupported = errors.New(T("当前平台不支持mmap"))
+
+// TokenList 设备token列表，所有token共享同一块只读内存，避免逐个分配字符串
+type TokenList struct {
+	data    string   // 所有token所在的只读数据
+	offsets []uint32 // 第i个token位于 data[offsets[2i]:offsets[2i+1]]
+}
+
+// Len 返回token数量
+func (t *TokenList) Len() int {
+	return len(t.offsets) / 2
+}
+
+// At 返回第i个token，返回值与列表共享内存，无额外分配
+func (t *TokenList) At(i int) string {
+	return t.data[t.offsets[2*i]:t.offsets[2*i+1]]
+}
+
+// duplicates 返回重复出现的token下标(每个token第一次出现的位置除外)
+func (t *TokenList) duplicates() []int {
+	seen := make(map[string]struct{}, t.Len())
+	var dups []int
+	for i := 0; i < t.Len(); i++ {
+		token := t.At(i)
+		if _, ok := seen[token]; ok {
+			dups = append(dups, i)
+			continue
+		}
+		seen[token] = struct{}{}
+	}
+	return dups
+}
+
+// remove 删除指定下标(升序)的token，只调整偏移量，不复制数据
+func (t *TokenList) remove(indexes []int) {
+	kept := t.offsets[:0]
+	next := 0
+	for i := 0; i < t.Len(); i++ {
+		if next < len(indexes) && indexes[next] == i {
+			next++
+			continue
+		}
+		kept = append(kept, t.offsets[2*i], t.offsets[2*i+1])
+	}
+	t.offsets = kept
+}
+
+// truncate 只保留前n个token
+func (t *TokenList) truncate(n int) {
+	if n >= 0 && n < t.Len() {
+		t.offsets = t.offsets[:2*n]
+	}
+}
+
+// applyDuplicatePolicy 按配置处理重复token
+//
+// 相同token的设备会不断互相挤掉连接，表现为大量断线重连，容易被误判为Broker不稳定。
+func applyDuplicatePolicy(tokens *TokenList) error {
+	dups := tokens.duplicates()
+	if len(dups) == 0 {
+		return nil
+	}
+
+	samples := make([]string, 0, maxDuplicateSamples)
+	for _, i := range dups {
+		if len(samples) == maxDuplicateSamples {
+			break
+		}
+		if token := tokens.At(i); !slices.Contains(samples, token) {
+			samples = append(samples, token)
+		}
+	}
+	sample := strings.Join(samples, ", ")
+
+	switch AppConfig.Device.DuplicateTokens {
+	case "error":
+		return fmt.Errorf(T("token文件中有 %d 个重复token(如 %s)，可使用 --duplicate-tokens=dedupe 去重"), len(dups), sample)
+	case "allow":
+		log.Printf(T("警告: 保留 %d 个重复token(如 %s)，相同token的设备会互相抢占连接"), len(dups), sample)
+	default:
+		tokens.remove(dups)
+		log.Printf(T("已去除 %d 个重复token(如 %s)，剩余 %d 个"), len(dups), sample, tokens.Len())
+	}
+	return nil
+}
+
+// loadTokens 加载设备token文件，limit>0时只读取前limit个有效token
+//
+// 重复token不保留时(--duplicate-tokens不为allow)只统计不重复的token，
+// 去重后仍有limit个token可用。
+func loadTokens(path string, limit int, useMmap bool) (*TokenList, error) {
+	var tokens *TokenList
+	var err error
+
+	distinct := AppConfig.Device.DuplicateTokens != "allow"
+	if useMmap {
+		tokens, err = loadTokensMmap(path, limit, distinct)
+		if err == errMmapUnsupported {
+			log.Printf(T("警告: %v，改为流式读取token文件"), err)
+			useMmap = false
+		}
+	}
+	if !useMmap {
+		tokens, err = loadTokensStream(path, limit, distinct)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if tokens.Len() == 0 {
+		return nil, fmt.Errorf(T("文件为空或不包含有效设备token"))
+	}
+	return tokens, nil
+}
+
+// tokenCounter 统计已读取的token数量，用于判断是否达到读取上限
+type tokenCounter struct {
+	seen map[string]struct{} // 已出现的token，为nil时重复token也计数
+	n    int
+}
+
+// newTokenCounter 创建计数器，distinct为true时重复token不计数
+func newTokenCounter(distinct bool) *tokenCounter {
+	if distinct {
+		return &tokenCounter{seen: make(map[string]struct{})}
+	}
+	return &tokenCounter{}
+}
+
+// add 记录读取到的一个token
+func (c *tokenCounter) add(token []byte) {
+	if c.seen != nil {
+		if _, ok := c.seen[string(token)]; ok {
+			return
+		}
+		c.seen[string(token)] = struct{}{}
+	}
+	c.n++
+}
+
+// loadTokensStream 逐行读取token，拼接到同一块缓冲区中
+func loadTokensStream(path string, limit int, distinct bool) (*TokenList, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf(T("打开文件失败: %w"), err)
+	}
+	defer file.Close()
+
+	var buf strings.Builder
+	var offsets []uint32
+	count := newTokenCounter(distinct && limit > 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() && (limit <= 0 || count.n < limit) {
+		line := bytes.TrimRight(scanner.Bytes(), "\r")
+		if len(line) == 0 { // 忽略空行
+			continue
+		}
+		offsets = append(offsets, uint32(buf.Len()))
+		buf.Write(line)
+		offsets = append(offsets, uint32(buf.Len()))
+		count.add(line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf(T("读取文件内容失败: %w"), err)
+	}
+
+	// strings.Builder.String 不复制底层数据
+	return &TokenList{data: buf.String(), offsets: offsets}, nil
+}
+
+// loadTokensMmap 将token文件映射到内存，token直接引用映射区域
+func loadTokensMmap(path string, limit int, distinct bool) (*TokenList, error) {
+	data, err := mmapFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return &TokenList{}, nil
+	}
+
+	var offsets []uint32
+	count := newTokenCounter(distinct && limit > 0)
+	start := 0
+	for start < len(data) && (limit <= 0 || count.n < limit) {
+		end := bytes.IndexByte(data[start:], '\n')
+		if end < 0 {
+			end = len(data)
+		} else {
+			end += start
+		}
+
+		lineEnd := end
+		if lineEnd > start && data[lineEnd-1] == '\r' {
+			lineEnd--
+		}
+		if lineEnd > start { // 忽略空行
+			offsets = append(offsets, uint32(start), uint32(lineEnd))
+			count.add(data[start:lineEnd])
+		}
+		start = end + 1
+	}
+
+	// 映射区域在进程生命周期内保持有效且只读，可安全地视为字符串
+	return &TokenList{data: unsafe.String(&data[0], len(data)), offsets: offsets}, nil
+}
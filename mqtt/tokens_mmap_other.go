@@ -0,0 +1,8 @@
+//go:build !unix
+
+package main
+
+// mmapFile 当前平台不支持mmap
+func mmapFile(path string) ([]byte, error) {
+	return nil, errMmapUnsupported
+}
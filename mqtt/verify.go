@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 当前值核对命令行参数
+var verifyCurrent = flag.Int("verify-current", 0, "测试结束后核对N个抽样设备在telemetry_current_datas中的当前值是否为最后发送的值，0为不核对")
+
+// maxMismatchLines 当前值核对最多输出的不一致明细行数
+const maxMismatchLines = 10
+
+// currentSample 抽样设备最后一条发送成功的遥测消息
+type currentSample struct {
+	username string
+	last     []byte
+}
+
+// record 记录发送成功的遥测消息，nil表示设备未被抽样
+func (s *currentSample) record(payload []byte) {
+	if s != nil {
+		s.last = append(s.last[:0], payload...)
+	}
+}
+
+// currentSamples 参与当前值核对的设备
+var currentSamples struct {
+	mu   sync.Mutex
+	list []*currentSample
+}
+
+// newCurrentSample 前 verify.current_samples 个启动的设备参与核对，其余设备返回nil
+func newCurrentSample(username string) *currentSample {
+	currentSamples.mu.Lock()
+	defer currentSamples.mu.Unlock()
+	if len(currentSamples.list) >= AppConfig.Verify.CurrentSamples {
+		return nil
+	}
+	s := &currentSample{username: username}
+	currentSamples.list = append(currentSamples.list, s)
+	return s
+}
+
+// currentResult 当前值核对结果
+type currentResult struct {
+	devices    int
+	checked    int // 核对的数据点数
+	mismatched int // 平台当前值与最后发送的值不一致
+	missing    int // 平台没有该数据点的当前值
+}
+
+// currentResultSummary 最近一次核对的结果，供JSON汇总使用
+var currentResultSummary currentResult
+
+// ingestSettle 核对入库结果前只等待一次平台处理完最后一批消息
+var ingestSettle = sync.OnceFunc(func() {
+	time.Sleep(AppConfig.Verify.Settle)
+})
+
+// deviceIDByToken 按设备凭证中的username查询设备ID
+func deviceIDByToken(ctx context.Context, db *sql.DB, username string) (string, error) {
+	var deviceID string
+	err := db.QueryRowContext(ctx,
+		`SELECT id FROM devices WHERE voucher::json->>'username' = $1`, username).Scan(&deviceID)
+	if err != nil {
+		return "", fmt.Errorf(T("查询设备ID失败: %w"), err)
+	}
+	return deviceID, nil
+}
+
+// currentValues 查询设备在telemetry_current_datas中各数值型数据点的当前值
+func currentValues(ctx context.Context, db *sql.DB, deviceID string) (map[string]float64, error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT key, number_v FROM telemetry_current_datas WHERE device_id = $1`, deviceID)
+	if err != nil {
+		return nil, fmt.Errorf(T("查询当前值失败: %w"), err)
+	}
+	defer rows.Close()
+
+	values := make(map[string]float64)
+	for rows.Next() {
+		var key string
+		var value sql.NullFloat64
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, fmt.Errorf(T("查询当前值失败: %w"), err)
+		}
+		if value.Valid {
+			values[key] = value.Float64
+		}
+	}
+	return values, rows.Err()
+}
+
+// sentValues 解析消息中的数值型数据点
+func sentValues(payload []byte) (map[string]float64, error) {
+	var fields map[string]any
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return nil, fmt.Errorf(T("解析最后发送的消息失败: %w"), err)
+	}
+	values := make(map[string]float64, len(fields))
+	for key, v := range fields {
+		if f, ok := v.(float64); ok {
+			values[key] = f
+		}
+	}
+	return values, nil
+}
+
+// sameValue 比较平台保存的数值与发送的数值(允许浮点存储误差)
+func sameValue(stored, sent float64) bool {
+	return math.Abs(stored-sent) <= 1e-9*math.Max(1, math.Abs(sent))
+}
+
+// checkCurrentValues 核对单个设备的各数据点，返回不一致的明细
+func checkCurrentValues(ctx context.Context, db *sql.DB, s *currentSample, result *currentResult) ([]string, error) {
+	sent, err := sentValues(s.last)
+	if err != nil {
+		return nil, err
+	}
+	deviceID, err := deviceIDByToken(ctx, db, s.username)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := currentValues(ctx, db, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	result.devices++
+
+	keys := make([]string, 0, len(sent))
+	for key := range sent {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var details []string
+	for _, key := range keys {
+		result.checked++
+		value, ok := stored[key]
+		switch {
+		case !ok:
+			result.missing++
+			details = append(details, fmt.Sprintf(T("设备 %s 数据点 %s: 平台没有当前值, 最后发送 %v"), s.username, key, sent[key]))
+		case !sameValue(value, sent[key]):
+			result.mismatched++
+			details = append(details, fmt.Sprintf(T("设备 %s 数据点 %s: 平台当前值 %v, 最后发送 %v"), s.username, key, value, sent[key]))
+		}
+	}
+	return details, nil
+}
+
+// reportCurrentValues 核对抽样设备每个数据点的平台当前值是否为工具最后发送的值
+//
+// 高负载下最新值缓存与历史数据不一致是平台出现过的问题。QoS 0下最后一条消息可能在
+// 传输中丢失，此时的不一致应结合发布失败数和入库率判断。
+func reportCurrentValues() {
+	if AppConfig.Verify.CurrentSamples <= 0 {
+		return
+	}
+	currentSamples.mu.Lock()
+	var samples []*currentSample
+	for _, s := range currentSamples.list {
+		if len(s.last) > 0 {
+			samples = append(samples, s)
+		}
+	}
+	currentSamples.mu.Unlock()
+
+	log.Println(T("\n========== 当前值核对 =========="))
+	defer log.Println("===============================")
+	if len(samples) == 0 {
+		log.Println(T("抽样设备没有发送成功的消息，跳过核对"))
+		return
+	}
+
+	db, err := openReadDatabase()
+	if err != nil {
+		log.Printf(T("当前值核对: %v"), err)
+		return
+	}
+	defer db.Close()
+	ingestSettle()
+	waitReplicaReplay(db)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	var result currentResult
+	var details []string
+	for _, s := range samples {
+		lines, err := checkCurrentValues(ctx, db, s, &result)
+		if err != nil {
+			log.Printf(T("当前值核对: 设备 %s: %v"), s.username, err)
+			continue
+		}
+		details = append(details, lines...)
+	}
+	currentResultSummary = result
+
+	log.Printf(T("抽样设备: %d, 核对数据点: %d, 一致 %d, 不一致 %d, 缺失 %d"),
+		result.devices, result.checked, result.checked-result.mismatched-result.missing, result.mismatched, result.missing)
+	for i, line := range details {
+		if i == maxMismatchLines {
+			log.Printf(T("... 另有 %d 条不一致未列出"), len(details)-maxMismatchLines)
+			break
+		}
+		log.Println(line)
+	}
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// wizard 交互式配置向导
+type wizard struct {
+	reader *bufio.Reader
+}
+
+// ask 提示输入，直接回车时使用默认值
+func (w *wizard) ask(label, def string) string {
+	if def != "" {
+		fmt.Printf("%s [%s]: ", label, def)
+	} else {
+		fmt.Printf("%s: ", label)
+	}
+	line, _ := w.reader.ReadString('\n')
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return def
+	}
+	return line
+}
+
+// askInt 提示输入正整数
+func (w *wizard) askInt(label string, def int) int {
+	for {
+		value, err := strconv.Atoi(w.ask(label, strconv.Itoa(def)))
+		if err == nil && value > 0 {
+			return value
+		}
+		fmt.Println(T("请输入正整数"))
+	}
+}
+
+// askFloat 提示输入正数
+func (w *wizard) askFloat(label string, def float64) float64 {
+	for {
+		value, err := strconv.ParseFloat(w.ask(label, strconv.FormatFloat(def, 'f', -1, 64)), 64)
+		if err == nil && value > 0 {
+			return value
+		}
+		fmt.Println(T("请输入正数"))
+	}
+}
+
+// confirm 提示确认(y/N)
+func (w *wizard) confirm(label string) bool {
+	answer := strings.ToLower(w.ask(label+" (y/N)", ""))
+	return answer == "y" || answer == "yes"
+}
+
+// validate 执行校验，失败时询问是重新输入还是保留当前值，返回true表示继续下一步
+func (w *wizard) validate(name string, check func() error) bool {
+	fmt.Printf(T("正在检查%s..."), name)
+	if err := check(); err != nil {
+		fmt.Printf(T(" 失败: %v\n"), err)
+		return w.confirm(T("仍然使用该配置?"))
+	}
+	fmt.Println(T(" 正常"))
+	return true
+}
+
+// runInit init子命令：交互式生成配置文件
+func runInit() {
+	// 已有配置文件时以其内容作为默认值
+	if data, err := os.ReadFile(*configFile); err == nil {
+		if err := yaml.Unmarshal(data, &AppConfig); err != nil {
+			fmt.Printf(T("警告: 解析已有配置文件失败，使用默认值: %v\n"), err)
+		}
+	}
+	applyWizardDefaults()
+
+	w := &wizard{reader: bufio.NewReader(os.Stdin)}
+	fmt.Println(T("ThingsPanel性能测试配置向导，直接回车使用方括号中的默认值"))
+
+	// MQTT服务器和设备
+	fmt.Println(T("\n[1/4] MQTT服务器"))
+	for {
+		AppConfig.MQTT.Server = w.ask(T("MQTT服务器地址"), AppConfig.MQTT.Server)
+		AppConfig.Device.TokenFile = w.ask(T("设备token文件路径"), AppConfig.Device.TokenFile)
+		if w.validate(T("MQTT服务器"), checkBroker) {
+			break
+		}
+	}
+
+	// 负载参数
+	fmt.Println(T("\n[2/4] 负载参数"))
+	AppConfig.Device.ClientNumber = w.askInt(T("模拟连接的设备数量"), AppConfig.Device.ClientNumber)
+	rate := w.askFloat(T("每台设备每秒发送消息数"), float64(time.Second)/float64(AppConfig.Test.DataInterval))
+	AppConfig.Test.DataInterval = time.Duration(float64(time.Second) / rate)
+	AppConfig.Test.CycleCount = w.askInt(T("测试循环次数"), AppConfig.Test.CycleCount)
+	AppConfig.Data.DataPointCount = w.askInt(T("每条消息包含的数据点数量"), AppConfig.Data.DataPointCount)
+	fmt.Printf(T("预计总发送速率: %.1f 消息/秒, %.1f 点/秒\n"),
+		rate*float64(AppConfig.Device.ClientNumber),
+		rate*float64(AppConfig.Device.ClientNumber*AppConfig.Data.DataPointCount))
+
+	// 数据库
+	fmt.Println(T("\n[3/4] 数据库"))
+	for {
+		AppConfig.Database.Host = w.ask(T("数据库服务器地址和端口"), AppConfig.Database.Host)
+		AppConfig.Database.User = w.ask(T("数据库用户名"), AppConfig.Database.User)
+		AppConfig.Database.Password = w.ask(T("数据库密码"), AppConfig.Database.Password)
+		AppConfig.Database.Name = w.ask(T("数据库名称"), AppConfig.Database.Name)
+		if w.validate(T("数据库"), checkDatabase) {
+			break
+		}
+	}
+
+	// 租户(用于创建设备)
+	fmt.Println(T("\n[4/4] 租户"))
+	tenant := w.ask(T("租户ID"), "")
+	if tenant != "" {
+		w.validate(T("租户"), func() error { return checkTenant(tenant) })
+	}
+
+	// 写入配置文件
+	if _, err := os.Stat(*configFile); err == nil && !w.confirm(fmt.Sprintf(T("配置文件 %s 已存在，是否覆盖?"), *configFile)) {
+		fmt.Println(T("已取消，未写入配置文件"))
+		return
+	}
+	data, err := yaml.Marshal(&AppConfig)
+	if err != nil {
+		fmt.Printf(T("生成配置失败: %v\n"), err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(*configFile, data, 0644); err != nil {
+		fmt.Printf(T("写入配置文件失败: %v\n"), err)
+		os.Exit(1)
+	}
+
+	fmt.Printf(T("\n配置已写入 %s\n"), *configFile)
+	fmt.Println(T("下一步:"))
+	if tenant != "" {
+		// 设备创建工具从生成的配置文件读取数据库配置(含密码)，命令中不出现密码
+		path, err := filepath.Abs(*configFile)
+		if err != nil {
+			path = *configFile
+		}
+		fmt.Printf(T("  1. 创建设备: cd ../create_device && go run . --config %s --tenant %s --count %d\n"),
+			path, tenant, AppConfig.Device.ClientNumber)
+		fmt.Println(T("  2. 开始测试: go run ."))
+	} else {
+		fmt.Println(T("  开始测试: go run ."))
+	}
+}
+
+// applyWizardDefaults 为未配置的项设置向导默认值
+func applyWizardDefaults() {
+	if AppConfig.MQTT.Server == "" {
+		AppConfig.MQTT.Server = "127.0.0.1:1883"
+	}
+	if AppConfig.MQTT.Topic == "" {
+		AppConfig.MQTT.Topic = "devices/telemetry"
+	}
+	if AppConfig.Device.TokenFile == "" {
+		AppConfig.Device.TokenFile = "../create_device/device_username.txt"
+	}
+	if AppConfig.Device.ClientNumber <= 0 {
+		AppConfig.Device.ClientNumber = 10
+	}
+	if AppConfig.Test.DataInterval <= 0 {
+		AppConfig.Test.DataInterval = time.Second
+	}
+	if AppConfig.Test.CycleCount <= 0 {
+		AppConfig.Test.CycleCount = 200
+	}
+	if AppConfig.Test.ConnectWaitTime <= 0 {
+		AppConfig.Test.ConnectWaitTime = 3 * time.Second
+	}
+	if AppConfig.Data.DataPointCount <= 0 {
+		AppConfig.Data.DataPointCount = 10
+	}
+	if AppConfig.Data.MaxValue <= AppConfig.Data.MinValue {
+		AppConfig.Data.MinValue, AppConfig.Data.MaxValue = 1, 10
+	}
+	if AppConfig.Database.Host == "" {
+		AppConfig.Database.Host = "127.0.0.1:5432"
+	}
+	if AppConfig.Database.User == "" {
+		AppConfig.Database.User = "postgres"
+	}
+	if AppConfig.Database.Name == "" {
+		AppConfig.Database.Name = "thingspanel"
+	}
+	if AppConfig.Database.SSLMode == "" {
+		AppConfig.Database.SSLMode = "disable"
+	}
+	if AppConfig.Monitor.LogInterval <= 0 {
+		AppConfig.Monitor.LogInterval = 10 * time.Second
+	}
+}
+
+// checkBroker 检查MQTT服务器是否可达，token文件存在时用第一个token尝试认证
+func checkBroker() error {
+	conn, err := net.DialTimeout("tcp", brokerAddress(AppConfig.MQTT.Server), 5*time.Second)
+	if err != nil {
+		return err
+	}
+	conn.Close()
+
+	tokens, err := loadTokens(AppConfig.Device.TokenFile, 1, false)
+	if err != nil {
+		fmt.Printf(T(" (跳过认证检查: %v)"), err)
+		return nil
+	}
+	username := tokens.At(0)
+	client := newLiteClient(username+"_init", username, AppConfig.MQTT.Server, nil)
+	if err := client.Connect(); err != nil {
+		return fmt.Errorf(T("使用第一个token认证失败: %w"), err)
+	}
+	client.Disconnect()
+	return nil
+}
+
+// checkDatabase 检查数据库连接及遥测数据表是否存在
+func checkDatabase() error {
+	db, err := openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var table *string
+	if err := db.QueryRowContext(ctx, "SELECT to_regclass('telemetry_datas')::text").Scan(&table); err != nil {
+		return err
+	}
+	if table == nil {
+		return errors.New(T("数据库中不存在telemetry_datas表，请确认是ThingsPanel数据库"))
+	}
+	return nil
+}
+
+// checkTenant 检查租户下已有的设备数量
+func checkTenant(tenant string) error {
+	db, err := openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var count int64
+	if err := db.QueryRow("SELECT COUNT(*) FROM devices WHERE tenant_id = $1", tenant).Scan(&count); err != nil {
+		return err
+	}
+	fmt.Printf(T(" (该租户已有 %d 个设备)"), count)
+	return nil
+}